@@ -0,0 +1,250 @@
+package genai
+
+import (
+	"encoding/csv"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TurnRecord is one turn of a conversation for offline analysis
+type TurnRecord struct {
+	ConversationID   string
+	Turn             int
+	Provider         string
+	Model            string
+	UserChars        int
+	ResponseChars    int
+	ToolCalls        int
+	ToolErrors       int
+	Tools            []string
+	PromptTokens     int
+	CompletionTokens int
+	CachedTokens     int
+	ReasoningTokens  int
+	TotalTokens      int
+	Requests         int
+	// Duration and ToolDuration are only known for recorded turns, not transcripts
+	Duration     time.Duration
+	ToolDuration time.Duration
+	Canceled     bool
+	Error        string
+}
+
+// ToolRecord is one tool call of a turn
+type ToolRecord struct {
+	ConversationID string
+	Turn           int
+	Tool           string
+	IsError        bool
+	Duration       time.Duration
+	ArgumentBytes  int
+	ResultBytes    int
+}
+
+// Analytics holds the turns and tool calls of conversations, written as CSV for
+// spreadsheets, pandas or DuckDB
+type Analytics struct {
+	Turns []TurnRecord
+	Tools []ToolRecord
+}
+
+// AddTranscript adds the turns of a stored conversation. Each user message starts a
+// turn, leading exchanges without usage are few-shot examples and left out.
+func (a *Analytics) AddTranscript(t Transcript) {
+	var turns [][]Message
+	for _, msg := range t.Messages[t.conversationStart():] {
+		switch {
+		case msg.Role == RoleSystem:
+		case msg.Role == RoleUser && len(msg.ToolResults()) == 0:
+			turns = append(turns, []Message{msg})
+		case len(turns) > 0:
+			turns[len(turns)-1] = append(turns[len(turns)-1], msg)
+		}
+	}
+	var usage []TurnUsage
+	if t.Usage != nil {
+		usage = t.Usage.Turns
+	}
+
+	for i, messages := range turns {
+		record := TurnRecord{
+			ConversationID: t.ConversationID,
+			Turn:           i + 1,
+			Provider:       t.Provider,
+			Model:          t.Model,
+			UserChars:      len(messages[0].Text()),
+		}
+		if i < len(usage) {
+			record.addUsage(usage[i])
+		}
+		results := make(map[string]ToolResult)
+		for _, msg := range messages {
+			for _, result := range msg.ToolResults() {
+				results[result.ID+"/"+result.Name] = result
+			}
+		}
+		for _, msg := range messages[1:] {
+			if msg.Role != RoleAssistant {
+				continue
+			}
+			if msg.Model != "" {
+				record.Model = msg.Model
+			}
+			record.ResponseChars += len(msg.Text())
+			for _, call := range msg.ToolCalls() {
+				result := results[call.ID+"/"+call.Name]
+				record.addTool(call.Name, result.IsError)
+				a.Tools = append(a.Tools, ToolRecord{
+					ConversationID: t.ConversationID,
+					Turn:           record.Turn,
+					Tool:           call.Name,
+					IsError:        result.IsError,
+					ArgumentBytes:  len(call.argumentsJSON()),
+					ResultBytes:    len(result.Content),
+				})
+			}
+		}
+		a.Turns = append(a.Turns, record)
+	}
+}
+
+func (r *TurnRecord) addUsage(u TurnUsage) {
+	if u.Model != "" {
+		r.Model = u.Model
+	}
+	r.PromptTokens = u.PromptTokens
+	r.CompletionTokens = u.CompletionTokens
+	r.CachedTokens = u.CachedTokens
+	r.ReasoningTokens = u.ReasoningTokens
+	r.TotalTokens = u.TotalTokens
+	r.Requests = u.Requests
+}
+
+func (r *TurnRecord) addTool(name string, isError bool) {
+	r.ToolCalls++
+	if isError {
+		r.ToolErrors++
+	}
+	if !slices.Contains(r.Tools, name) {
+		r.Tools = append(r.Tools, name)
+	}
+}
+
+// WriteTurnsCSV writes one row per turn with a header, durations are in milliseconds
+func (a *Analytics) WriteTurnsCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	writer.Write([]string{
+		"conversation_id", "turn", "provider", "model", "user_chars", "response_chars",
+		"tool_calls", "tool_errors", "tools", "prompt_tokens", "completion_tokens",
+		"cached_tokens", "reasoning_tokens", "total_tokens", "requests", "duration_ms",
+		"tool_duration_ms", "canceled", "error",
+	})
+	for _, r := range a.Turns {
+		writer.Write([]string{
+			r.ConversationID, strconv.Itoa(r.Turn), r.Provider, r.Model,
+			strconv.Itoa(r.UserChars), strconv.Itoa(r.ResponseChars),
+			strconv.Itoa(r.ToolCalls), strconv.Itoa(r.ToolErrors), strings.Join(r.Tools, ";"),
+			strconv.Itoa(r.PromptTokens), strconv.Itoa(r.CompletionTokens),
+			strconv.Itoa(r.CachedTokens), strconv.Itoa(r.ReasoningTokens),
+			strconv.Itoa(r.TotalTokens), strconv.Itoa(r.Requests),
+			strconv.FormatInt(r.Duration.Milliseconds(), 10),
+			strconv.FormatInt(r.ToolDuration.Milliseconds(), 10),
+			strconv.FormatBool(r.Canceled), r.Error,
+		})
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteToolsCSV writes one row per tool call with a header
+func (a *Analytics) WriteToolsCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"conversation_id", "turn", "tool", "is_error", "duration_ms", "argument_bytes", "result_bytes"})
+	for _, r := range a.Tools {
+		writer.Write([]string{
+			r.ConversationID, strconv.Itoa(r.Turn), r.Tool, strconv.FormatBool(r.IsError),
+			strconv.FormatInt(r.Duration.Milliseconds(), 10),
+			strconv.Itoa(r.ArgumentBytes), strconv.Itoa(r.ResultBytes),
+		})
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// AnalyticsRecorder builds analytics from the events of running chats, including the
+// durations transcripts don't have. Pass it every event read from Chat.Events. Events
+// don't carry the user's message, so UserChars is zero.
+type AnalyticsRecorder struct {
+	mu        sync.Mutex
+	analytics Analytics
+	turns     map[*Chat]*recordedTurn
+}
+
+// recordedTurn is a turn that has not completed yet
+type recordedTurn struct {
+	record TurnRecord
+	tools  []ToolRecord
+}
+
+// NewAnalyticsRecorder creates an empty recorder
+func NewAnalyticsRecorder() *AnalyticsRecorder {
+	return &AnalyticsRecorder{turns: make(map[*Chat]*recordedTurn)}
+}
+
+// Observe records an event of chat, the turn is added when it completes
+func (r *AnalyticsRecorder) Observe(chat *Chat, event Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	turn, ok := r.turns[chat]
+	if !ok {
+		turn = &recordedTurn{record: TurnRecord{
+			ConversationID: chat.ID(),
+			Provider:       chat.model.Provider.Provider,
+			Model:          chat.model.ModelName,
+		}}
+		r.turns[chat] = turn
+	}
+	record := &turn.record
+	switch e := event.(type) {
+	case ToolCallFinished:
+		record.addTool(e.Call.Name, e.Result.IsError)
+		record.ToolDuration += e.Duration
+		turn.tools = append(turn.tools, ToolRecord{
+			ConversationID: record.ConversationID,
+			Tool:           e.Call.Name,
+			IsError:        e.Result.IsError,
+			Duration:       e.Duration,
+			ArgumentBytes:  len(e.Call.argumentsJSON()),
+			ResultBytes:    len(e.Result.Content),
+		})
+	case UsageEvent:
+		record.Turn = e.Turn.Turn
+		record.addUsage(e.Turn)
+	case ErrorEvent:
+		record.Error = e.Err.Error()
+	case TurnComplete:
+		record.ResponseChars = len(e.Text)
+		record.Canceled = e.Canceled
+		record.Duration = e.Duration
+		for i := range turn.tools {
+			turn.tools[i].Turn = record.Turn
+		}
+		r.analytics.Turns = append(r.analytics.Turns, *record)
+		r.analytics.Tools = append(r.analytics.Tools, turn.tools...)
+		delete(r.turns, chat)
+	}
+}
+
+// Analytics returns the turns completed so far
+func (r *AnalyticsRecorder) Analytics() Analytics {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return Analytics{
+		Turns: slices.Clone(r.analytics.Turns),
+		Tools: slices.Clone(r.analytics.Tools),
+	}
+}
@@ -0,0 +1,118 @@
+package genai
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// maxAttachmentSize limits images downloaded for providers that need inline data
+const maxAttachmentSize = 20 << 20
+
+// ChatMessage is a user message with attachments, sent on Chat.Messages
+type ChatMessage struct {
+	Text        string
+	Attachments []Part
+}
+
+// message converts the chat message to a user message
+func (c ChatMessage) message() Message {
+	msg := Message{Role: RoleUser}
+	if c.Text != "" {
+		msg.Parts = append(msg.Parts, Part{Type: TextPart, Text: c.Text})
+	}
+	msg.Parts = append(msg.Parts, c.Attachments...)
+	return msg
+}
+
+// NewImageFromFile reads an image attachment from disk
+func NewImageFromFile(path string) (Part, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Part{}, fmt.Errorf("failed to read image: %w", err)
+	}
+	mimeType := mime.TypeByExtension(strings.ToLower(filepath.Ext(path)))
+	if mimeType == "" {
+		mimeType = http.DetectContentType(data)
+	}
+	if !strings.HasPrefix(mimeType, "image/") {
+		return Part{}, fmt.Errorf("%s is not an image: %s", path, mimeType)
+	}
+	return NewImagePart(mimeType, data), nil
+}
+
+// NewImageFromURL references an image by URL. OpenAI fetches the image itself, it is
+// downloaded and sent inline for Ollama and Gemini.
+func NewImageFromURL(url string) Part {
+	mimeType := mime.TypeByExtension(strings.ToLower(filepath.Ext(strings.SplitN(url, "?", 2)[0])))
+	return Part{Type: ImagePart, MIMEType: mimeType, URL: url}
+}
+
+// resolveAttachments downloads images the provider can not fetch by URL
+func (m *Model) resolveAttachments(ctx context.Context, msg Message) (Message, error) {
+	if m.Provider.Provider != OLLAMA && m.Provider.Provider != GEMINI {
+		return msg, nil
+	}
+	var resolved []Part
+	for _, part := range msg.Parts {
+		if part.Type == ImagePart && (strings.HasPrefix(part.URL, "http://") || strings.HasPrefix(part.URL, "https://")) {
+			downloaded, err := downloadImage(ctx, m.Provider.Client.http, part.URL)
+			if err != nil {
+				return msg, err
+			}
+			part = downloaded
+		}
+		resolved = append(resolved, part)
+	}
+	msg.Parts = resolved
+	return msg, nil
+}
+
+// downloadImage fetches an image with the provider's client, so its proxy, TLS config
+// and headers apply, or http.DefaultClient when client is nil
+func downloadImage(ctx context.Context, client *http.Client, url string) (Part, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return Part{}, fmt.Errorf("invalid image URL: %w", err)
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return Part{}, fmt.Errorf("failed to download image: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return Part{}, fmt.Errorf("failed to download image %s: status code: %d", url, resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxAttachmentSize+1))
+	if err != nil {
+		return Part{}, fmt.Errorf("failed to download image: %w", err)
+	}
+	if len(data) > maxAttachmentSize {
+		return Part{}, fmt.Errorf("image %s is larger than %d bytes", url, maxAttachmentSize)
+	}
+	mimeType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if !strings.HasPrefix(mimeType, "image/") {
+		mimeType = http.DetectContentType(data)
+	}
+	return NewImagePart(mimeType, data), nil
+}
+
+// withoutURLImages drops images that are only referenced by an http(s) URL
+func withoutURLImages(parts []Part) []Part {
+	var kept []Part
+	for _, part := range parts {
+		if part.Type == ImagePart && len(part.Data) == 0 && (strings.HasPrefix(part.URL, "http://") || strings.HasPrefix(part.URL, "https://")) {
+			continue
+		}
+		kept = append(kept, part)
+	}
+	return kept
+}
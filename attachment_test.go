@@ -0,0 +1,49 @@
+package genai
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr"
+)
+
+func TestResolveAttachments(t *testing.T) {
+	var headers []string
+	images := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers = append(headers, r.Header.Get("X-Gateway-Key"))
+		w.Header().Set("Content-Type", "image/png")
+		if r.URL.Path == "/large.png" {
+			w.Write(bytes.Repeat([]byte{0}, maxAttachmentSize+1))
+			return
+		}
+		w.Write([]byte("\x89PNG\r\n\x1a\n"))
+	}))
+	defer images.Close()
+	p, err := NewProvider(OLLAMA, ProviderOptions{BaseURL: "http://ollama.invalid", Headers: map[string]string{"X-Gateway-Key": "secret"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	model := NewModel(p, ModelOptions{ModelName: "test"}, logr.Discard())
+
+	msg := ChatMessage{Text: "What is this?", Attachments: []Part{{Type: ImagePart, URL: images.URL + "/cat.png"}}}
+	resolved, err := model.resolveAttachments(context.Background(), msg.message())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if image := resolved.Parts[1]; image.MIMEType != "image/png" || len(image.Data) != 8 {
+		t.Errorf("image %s with %d bytes", image.MIMEType, len(image.Data))
+	}
+	// the download goes through the provider's client and its headers
+	if len(headers) != 1 || headers[0] != "secret" {
+		t.Errorf("headers %v, want the provider's", headers)
+	}
+
+	msg.Attachments[0].URL = images.URL + "/large.png"
+	if _, err := model.resolveAttachments(context.Background(), msg.message()); err == nil || !strings.Contains(err.Error(), "larger than") {
+		t.Errorf("large image error %v", err)
+	}
+}
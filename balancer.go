@@ -0,0 +1,181 @@
+package genai
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// BalanceStrategy selects the endpoint used for each request
+type BalanceStrategy string
+
+const (
+	RoundRobin  BalanceStrategy = "round_robin"
+	LeastLoaded BalanceStrategy = "least_loaded"
+
+	// rateLimitCooldown is how long an endpoint is skipped after it was rate limited
+	rateLimitCooldown = 30 * time.Second
+)
+
+// Endpoint is an additional API key and base URL for a provider
+type Endpoint struct {
+	APIKey  string
+	BaseURL string
+}
+
+// EndpointStats reports the traffic sent to one endpoint
+type EndpointStats struct {
+	BaseURL     string    `json:"baseURL"`
+	Requests    int64     `json:"requests"`
+	InFlight    int64     `json:"inFlight"`
+	Failures    int64     `json:"failures"`
+	RateLimited int64     `json:"rateLimited"`
+	CooldownEnd time.Time `json:"cooldownEnd,omitempty"`
+}
+
+type endpoint struct {
+	baseURL     string
+	client      *Client
+	requests    atomic.Int64
+	inFlight    atomic.Int64
+	failures    atomic.Int64
+	rateLimited atomic.Int64
+	// cooldownEnd is a unix nano timestamp, zero when the endpoint is usable
+	cooldownEnd atomic.Int64
+}
+
+// balancer spreads requests over the endpoints of a provider
+type balancer struct {
+	strategy  BalanceStrategy
+	endpoints []*endpoint
+	next      atomic.Uint64
+}
+
+// initEndpoints creates a client for each additional endpoint. The provider's own
+// APIKey and BaseURL are always the first endpoint.
+func (p *Provider) initEndpoints(strategy BalanceStrategy, endpoints []Endpoint) error {
+	if strategy == "" {
+		strategy = RoundRobin
+	}
+	if strategy != RoundRobin && strategy != LeastLoaded {
+		return fmt.Errorf("unsupported balance strategy: %s", strategy)
+	}
+	p.balancer = &balancer{
+		strategy:  strategy,
+		endpoints: []*endpoint{{baseURL: p.BaseURL, client: p.Client}},
+	}
+	for _, e := range endpoints {
+		ep := *p
+		ep.APIKey = e.APIKey
+		ep.BaseURL = e.BaseURL
+		client, err := NewClient(&ep)
+		if err != nil {
+			return fmt.Errorf("failed to create client for endpoint %s: %w", e.BaseURL, err)
+		}
+		if client.OpenAI != nil {
+			client.OpenAI.balancer = p.balancer
+		}
+		p.balancer.endpoints = append(p.balancer.endpoints, &endpoint{baseURL: e.BaseURL, client: client})
+	}
+	if p.Client.OpenAI != nil {
+		p.Client.OpenAI.balancer = p.balancer
+	}
+	return nil
+}
+
+// EndpointStats returns the per endpoint request counters
+func (p *Provider) EndpointStats() []EndpointStats {
+	if p.balancer == nil {
+		return nil
+	}
+	var stats []EndpointStats
+	for _, e := range p.balancer.endpoints {
+		s := EndpointStats{
+			BaseURL:     e.baseURL,
+			Requests:    e.requests.Load(),
+			InFlight:    e.inFlight.Load(),
+			Failures:    e.failures.Load(),
+			RateLimited: e.rateLimited.Load(),
+		}
+		if end := e.cooldownEnd.Load(); end > time.Now().UnixNano() {
+			s.CooldownEnd = time.Unix(0, end)
+		}
+		stats = append(stats, s)
+	}
+	return stats
+}
+
+// pick selects the next endpoint, skipping endpoints that are cooling down
+// unless every endpoint is
+func (b *balancer) pick() *endpoint {
+	if len(b.endpoints) == 1 {
+		return b.endpoints[0]
+	}
+	now := time.Now().UnixNano()
+	start := int(b.next.Add(1) - 1)
+	var best, soonest *endpoint
+	for i := range b.endpoints {
+		e := b.endpoints[(start+i)%len(b.endpoints)]
+		if soonest == nil || e.cooldownEnd.Load() < soonest.cooldownEnd.Load() {
+			soonest = e
+		}
+		if e.cooldownEnd.Load() > now {
+			continue
+		}
+		if b.strategy == RoundRobin {
+			return e
+		}
+		if best == nil || e.inFlight.Load() < best.inFlight.Load() {
+			best = e
+		}
+	}
+	if best == nil {
+		return soonest
+	}
+	return best
+}
+
+// done records the outcome of a request sent to the endpoint
+func (e *endpoint) done(err error) {
+	e.inFlight.Add(-1)
+	if err == nil {
+		return
+	}
+	e.failures.Add(1)
+	if errors.Is(err, ErrRateLimited) {
+		e.rateLimited.Add(1)
+		e.cooldownEnd.Store(time.Now().Add(rateLimitCooldown).UnixNano())
+	}
+}
+
+// balanced runs call with the client of the next endpoint and tracks its load.
+// Errors are classified so rate limits put the endpoint into cooldown.
+func balanced[T any](p *Provider, call func(*Client) (T, error)) (T, error) {
+	if p.balancer == nil {
+		result, err := call(p.Client)
+		return result, wrapProviderError(p.Provider, err)
+	}
+	return balancedCall(p.balancer, p.Provider, call)
+}
+
+// openAIBalanced is balanced for code that only has the OpenAI client
+func openAIBalanced[T any](c *OpenAIClient, call func(*OpenAIClient) (T, error)) (T, error) {
+	if c.balancer == nil {
+		result, err := call(c)
+		return result, wrapProviderError(c.provider, err)
+	}
+	return balancedCall(c.balancer, c.provider, func(client *Client) (T, error) {
+		return call(client.OpenAI)
+	})
+}
+
+func balancedCall[T any](b *balancer, provider string, call func(*Client) (T, error)) (T, error) {
+	e := b.pick()
+	e.requests.Add(1)
+	e.inFlight.Add(1)
+	result, err := call(e.client)
+	err = wrapProviderError(provider, err)
+	e.done(err)
+	return result, err
+}
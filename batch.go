@@ -0,0 +1,79 @@
+package genai
+
+import (
+	"context"
+	"maps"
+	"sync"
+)
+
+// BatchResult is the response to one prompt of GenerateBatch or a BatchJob
+type BatchResult struct {
+	// ID is the id of the BatchRequest
+	ID     string
+	Prompt string
+	Text   string
+	Usage  Usage
+	Err    error
+}
+
+// GenerateBatch runs each prompt like GenerateWithUsage with at most concurrency
+// requests in flight, one when concurrency is below 1. Each prompt is retried
+// according to the provider's RetryPolicy and a prompt that fails has Err set without
+// stopping the others. The results are in the order of prompts and the usage is the
+// total of all of them. When ctx is canceled the prompts that were not started fail
+// with its error.
+func (p *Provider) GenerateBatch(ctx context.Context, modelOptions ModelOptions, prompts []string, concurrency int) ([]BatchResult, Usage) {
+	options := make([]ModelOptions, len(prompts))
+	for i := range options {
+		options[i] = modelOptions
+	}
+	return p.generateAll(ctx, options, prompts, concurrency)
+}
+
+// generateAll runs prompts[i] with options[i] for GenerateBatch
+func (p *Provider) generateAll(ctx context.Context, options []ModelOptions, prompts []string, concurrency int) ([]BatchResult, Usage) {
+	results := make([]BatchResult, len(prompts))
+	for i, prompt := range prompts {
+		results[i].Prompt = prompt
+	}
+	if concurrency > len(prompts) {
+		concurrency = len(prompts)
+	}
+	concurrency = max(concurrency, 1)
+
+	work := make(chan int)
+	var wg sync.WaitGroup
+	for range concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range work {
+				options := options[i]
+				// NewModel fills in default parameters
+				options.Parameters = maps.Clone(options.Parameters)
+				text, usage, err := p.generateWithUsage(ctx, options, prompts[i])
+				results[i].Text, results[i].Usage, results[i].Err = text, usage, err
+			}
+		}()
+	}
+	next := 0
+feed:
+	for ; next < len(prompts); next++ {
+		select {
+		case work <- next:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(work)
+	wg.Wait()
+	for i := next; i < len(prompts); i++ {
+		results[i].Err = context.Cause(ctx)
+	}
+
+	var total Usage
+	for _, result := range results {
+		total.Add(result.Usage)
+	}
+	return results, total
+}
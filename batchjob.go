@@ -0,0 +1,310 @@
+package genai
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/openai/openai-go"
+)
+
+// BatchRequest is one prompt of a batch job. ID identifies its result and defaults to
+// the index of the request.
+type BatchRequest struct {
+	ID     string
+	Prompt string
+}
+
+// BatchJob is a batch of chat completions run asynchronously by OpenAI's Batch API,
+// usually at a lower price than the same requests sent one at a time. Results are
+// available within 24 hours.
+type BatchJob struct {
+	ID           string
+	Status       string
+	InputFileID  string
+	OutputFileID string
+	ErrorFileID  string
+	Total        int
+	Completed    int
+	Failed       int
+
+	client *OpenAIClient
+	// prompts maps request ids to prompts when the job was submitted by this process
+	prompts map[string]string
+	order   []string
+}
+
+// batchLine is a line of the input and output files of a batch
+type batchLine struct {
+	CustomID string `json:"custom_id"`
+	Method   string `json:"method,omitempty"`
+	URL      string `json:"url,omitempty"`
+	Body     any    `json:"body,omitempty"`
+	Response *struct {
+		StatusCode int             `json:"status_code"`
+		Body       json.RawMessage `json:"body"`
+	} `json:"response,omitempty"`
+	Error *struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+// SubmitBatch uploads the requests as a batch file and starts a batch job. Each request
+// is sent like GenerateWithUsage with the system prompt, examples and parameters of
+// modelOptions.
+func (p *Provider) SubmitBatch(ctx context.Context, modelOptions ModelOptions, requests []BatchRequest) (*BatchJob, error) {
+	if p.Provider != OPENAI {
+		return nil, fmt.Errorf("unsupported provider for batch jobs: %s", p.Provider)
+	}
+	if len(requests) == 0 {
+		return nil, errors.New("no requests to submit")
+	}
+	l := p.Log.WithName("batch").WithValues("model", modelOptions.ModelName, "id", uuid.New().String())
+	model := NewModel(p, modelOptions, l)
+	options := model.options()
+
+	job := &BatchJob{client: p.Client.OpenAI, prompts: make(map[string]string, len(requests))}
+	var input bytes.Buffer
+	encoder := json.NewEncoder(&input)
+	for i, request := range requests {
+		id := request.ID
+		if id == "" {
+			id = strconv.Itoa(i)
+		}
+		if _, ok := job.prompts[id]; ok {
+			return nil, fmt.Errorf("duplicate batch request id %q", id)
+		}
+		job.prompts[id] = request.Prompt
+		job.order = append(job.order, id)
+		msg := NewTextMessage(RoleUser, request.Prompt)
+		options.ModelName = model.routedModel([]Message{msg})
+		line := batchLine{
+			CustomID: id,
+			Method:   "POST",
+			URL:      string(openai.BatchNewParamsEndpointV1ChatCompletions),
+			Body:     generateParams(options, msg),
+		}
+		if err := encoder.Encode(line); err != nil {
+			return nil, fmt.Errorf("failed to encode batch request %q: %w", id, err)
+		}
+	}
+	return job, job.submit(ctx, input.Bytes())
+}
+
+// GetBatch returns a batch job submitted earlier, e.g. by another process
+func (p *Provider) GetBatch(ctx context.Context, id string) (*BatchJob, error) {
+	if p.Provider != OPENAI {
+		return nil, fmt.Errorf("unsupported provider for batch jobs: %s", p.Provider)
+	}
+	job := &BatchJob{ID: id, client: p.Client.OpenAI}
+	return job, job.Refresh(ctx)
+}
+
+// submit uploads the input file and creates the batch. Batches don't go through the
+// balancer since the files belong to one account.
+func (j *BatchJob) submit(ctx context.Context, input []byte) error {
+	c := j.client
+	file, err := retry(ctx, c.retry, c.log, c.provider, c.metrics, func() (*openai.FileObject, error) {
+		file, err := c.client.Files.New(ctx, openai.FileNewParams{
+			File:    openai.File(bytes.NewReader(input), "batch.jsonl", "application/jsonl"),
+			Purpose: openai.FilePurposeBatch,
+		})
+		return file, wrapProviderError(c.provider, err)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to upload batch file: %w", err)
+	}
+	j.InputFileID = file.ID
+	batch, err := retry(ctx, c.retry, c.log, c.provider, c.metrics, func() (*openai.Batch, error) {
+		batch, err := c.client.Batches.New(ctx, openai.BatchNewParams{
+			CompletionWindow: openai.BatchNewParamsCompletionWindow24h,
+			Endpoint:         openai.BatchNewParamsEndpointV1ChatCompletions,
+			InputFileID:      file.ID,
+		})
+		return batch, wrapProviderError(c.provider, err)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create batch: %w", err)
+	}
+	j.update(batch)
+	c.log.Info("Submitted batch", "batch", j.ID, "requests", len(j.order))
+	return nil
+}
+
+func (j *BatchJob) update(batch *openai.Batch) {
+	j.ID = batch.ID
+	j.Status = string(batch.Status)
+	j.InputFileID = batch.InputFileID
+	j.OutputFileID = batch.OutputFileID
+	j.ErrorFileID = batch.ErrorFileID
+	j.Total = int(batch.RequestCounts.Total)
+	j.Completed = int(batch.RequestCounts.Completed)
+	j.Failed = int(batch.RequestCounts.Failed)
+}
+
+// Refresh updates the status and request counts of the job
+func (j *BatchJob) Refresh(ctx context.Context) error {
+	c := j.client
+	batch, err := retry(ctx, c.retry, c.log, c.provider, c.metrics, func() (*openai.Batch, error) {
+		batch, err := c.client.Batches.Get(ctx, j.ID)
+		return batch, wrapProviderError(c.provider, err)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get batch %s: %w", j.ID, err)
+	}
+	j.update(batch)
+	return nil
+}
+
+// Done reports whether the job has finished, successfully or not
+func (j *BatchJob) Done() bool {
+	switch openai.BatchStatus(j.Status) {
+	case openai.BatchStatusCompleted, openai.BatchStatusFailed, openai.BatchStatusExpired, openai.BatchStatusCancelled:
+		return true
+	}
+	return false
+}
+
+// Wait polls the job every interval, a minute when zero, until it is done or ctx ends
+func (j *BatchJob) Wait(ctx context.Context, interval time.Duration) error {
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for !j.Done() {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		if err := j.Refresh(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Cancel stops the job, requests that already completed keep their results
+func (j *BatchJob) Cancel(ctx context.Context) error {
+	c := j.client
+	batch, err := retry(ctx, c.retry, c.log, c.provider, c.metrics, func() (*openai.Batch, error) {
+		batch, err := c.client.Batches.Cancel(ctx, j.ID)
+		return batch, wrapProviderError(c.provider, err)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to cancel batch %s: %w", j.ID, err)
+	}
+	j.update(batch)
+	return nil
+}
+
+// Results downloads the results of a finished job. Failed requests have Err set, the
+// usage is the total of the requests that completed. Jobs submitted by this process
+// return the results in the order of the requests with their prompts, others in the
+// order of the output files. Requests without a result, e.g. of an expired job, are
+// left out.
+func (j *BatchJob) Results(ctx context.Context) ([]BatchResult, Usage, error) {
+	if !j.Done() {
+		return nil, Usage{}, fmt.Errorf("batch %s is %s", j.ID, j.Status)
+	}
+	byID := make(map[string]BatchResult)
+	var ids []string
+	for _, fileID := range []string{j.OutputFileID, j.ErrorFileID} {
+		if fileID == "" {
+			continue
+		}
+		lines, err := j.download(ctx, fileID)
+		if err != nil {
+			return nil, Usage{}, err
+		}
+		for _, line := range lines {
+			if _, ok := byID[line.CustomID]; !ok {
+				ids = append(ids, line.CustomID)
+			}
+			byID[line.CustomID] = batchResult(line)
+		}
+	}
+	if j.order != nil {
+		ids = ids[:0]
+		for _, id := range j.order {
+			if _, ok := byID[id]; ok {
+				ids = append(ids, id)
+			}
+		}
+	}
+	results := make([]BatchResult, 0, len(ids))
+	var total Usage
+	for _, id := range ids {
+		result := byID[id]
+		result.Prompt = j.prompts[id]
+		total.Add(result.Usage)
+		results = append(results, result)
+	}
+	return results, total, nil
+}
+
+// download reads the lines of a result file
+func (j *BatchJob) download(ctx context.Context, fileID string) ([]batchLine, error) {
+	c := j.client
+	data, err := retry(ctx, c.retry, c.log, c.provider, c.metrics, func() ([]byte, error) {
+		resp, err := c.client.Files.Content(ctx, fileID)
+		if err != nil {
+			return nil, wrapProviderError(c.provider, err)
+		}
+		defer resp.Body.Close()
+		var buf bytes.Buffer
+		_, err = buf.ReadFrom(resp.Body)
+		return buf.Bytes(), err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to download batch file %s: %w", fileID, err)
+	}
+	var lines []batchLine
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var line batchLine
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			return nil, fmt.Errorf("failed to decode batch file %s: %w", fileID, err)
+		}
+		lines = append(lines, line)
+	}
+	return lines, scanner.Err()
+}
+
+// batchResult converts a line of a result file
+func batchResult(line batchLine) BatchResult {
+	result := BatchResult{ID: line.CustomID}
+	switch {
+	case line.Error != nil:
+		result.Err = fmt.Errorf("%s: %s", line.Error.Code, line.Error.Message)
+	case line.Response == nil:
+		result.Err = errors.New("no response")
+	case line.Response.StatusCode != 200:
+		result.Err = fmt.Errorf("status %d: %s", line.Response.StatusCode, line.Response.Body)
+	default:
+		var completion openai.ChatCompletion
+		if err := json.Unmarshal(line.Response.Body, &completion); err != nil {
+			result.Err = fmt.Errorf("failed to decode response: %w", err)
+			break
+		}
+		result.Usage = openAIUsage(completion.Usage)
+		if len(completion.Choices) == 0 {
+			result.Err = errors.New("no response choices returned")
+			break
+		}
+		result.Text = completion.Choices[0].Message.Content
+	}
+	return result
+}
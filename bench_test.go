@@ -0,0 +1,186 @@
+package genai
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+)
+
+// Performance targets for the chat hot path. These are enforced by the
+// Test*Allocs tests below and can be inspected with
+//
+//	go test -run xxx -bench . -benchmem
+//
+// The HTTP based budgets include the allocations of the in-process test server
+// so they are intentionally loose, they exist to catch regressions such as
+// rebuilding schemas or re-encoding the history several times per turn.
+const (
+	messagesToStringAllocBudget    = 2 * benchHistoryLength
+	openAITurnAllocBudget          = 1500
+	ollamaTurnAllocBudget          = 300
+	compactAllocBudget             = 500
+	benchHistoryLength             = 50
+	benchHistoryMessageContentSize = 512
+)
+
+const openAICompletionBody = `{"id":"chatcmpl-1","object":"chat.completion","created":0,"model":"test",` +
+	`"choices":[{"index":0,"finish_reason":"stop","message":{"role":"assistant","content":"hello"}}]}`
+
+const ollamaChatBody = `{"model":"test","created_at":"2024-01-01T00:00:00Z",` +
+	`"message":{"role":"assistant","content":"hello"},"done":true}`
+
+func benchHistory(n int) []Message {
+	content := make([]byte, benchHistoryMessageContentSize)
+	for i := range content {
+		content[i] = 'a' + byte(i%26)
+	}
+	messages := []Message{NewTextMessage(RoleSystem, "You are a helpful assistant")}
+	for i := 0; i < n; i++ {
+		msg := NewTextMessage(RoleUser, string(content))
+		if i%2 == 1 {
+			msg.Role = RoleAssistant
+		}
+		messages = append(messages, msg)
+	}
+	return messages
+}
+
+func newTestModel(tb testing.TB, providerName string, baseURL string) *Model {
+	tb.Helper()
+	p, err := NewProvider(providerName, ProviderOptions{APIKey: "test", BaseURL: baseURL})
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return NewModel(p, ModelOptions{ModelName: "test"}, logr.Discard())
+}
+
+func newTestChat() *Chat {
+	chat := &Chat{
+		ctx:    context.Background(),
+		Recv:   make(chan string, 1),
+		Logger: logr.Discard(),
+	}
+	return chat
+}
+
+func BenchmarkMessagesToString(b *testing.B) {
+	messages := benchHistory(benchHistoryLength)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		messagesToString(messages, true)
+	}
+}
+
+func BenchmarkProcessOpenAIMessage(b *testing.B) {
+	srv := newTestServer(b, openAICompletionBody)
+	m := newTestModel(b, OPENAI, srv.URL)
+	chat := newTestChat()
+	messages := benchHistory(benchHistoryLength)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		chat.Turns = 0
+		if err := m.openAIClient.processOpenAIMessage(context.Background(), m, chat, messages); err != nil {
+			b.Fatal(err)
+		}
+		<-chat.Recv
+	}
+}
+
+func BenchmarkHandleOllamaResponse(b *testing.B) {
+	srv := newTestServer(b, ollamaChatBody)
+	m := newTestModel(b, OLLAMA, srv.URL)
+	chat := newTestChat()
+	messages := []Message{NewTextMessage(RoleUser, "hello")}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := handleOllamaResponse(context.Background(), m, nil, chat, messages); err != nil {
+			b.Fatal(err)
+		}
+		<-chat.Recv
+	}
+}
+
+func BenchmarkCompact(b *testing.B) {
+	srv := newTestServer(b, openAICompletionBody)
+	m := newTestModel(b, OPENAI, srv.URL)
+	messages := benchHistory(benchHistoryLength)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := compact(m, messages); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// skipUnderRace skips allocation budget tests, the race detector adds allocations
+func skipUnderRace(t *testing.T) {
+	t.Helper()
+	if raceEnabled {
+		t.Skip("allocation budgets are not checked with the race detector")
+	}
+}
+
+func TestMessagesToStringAllocs(t *testing.T) {
+	skipUnderRace(t)
+	messages := benchHistory(benchHistoryLength)
+	allocs := testing.AllocsPerRun(100, func() {
+		messagesToString(messages, true)
+	})
+	if allocs > messagesToStringAllocBudget {
+		t.Errorf("messagesToString allocated %.0f times per call, budget is %d", allocs, messagesToStringAllocBudget)
+	}
+}
+
+func TestProcessOpenAIMessageAllocs(t *testing.T) {
+	skipUnderRace(t)
+	srv := newTestServer(t, openAICompletionBody)
+	m := newTestModel(t, OPENAI, srv.URL)
+	chat := newTestChat()
+	messages := benchHistory(benchHistoryLength)
+	allocs := testing.AllocsPerRun(20, func() {
+		chat.Turns = 0
+		if err := m.openAIClient.processOpenAIMessage(context.Background(), m, chat, messages); err != nil {
+			t.Fatal(err)
+		}
+		<-chat.Recv
+	})
+	if allocs > openAITurnAllocBudget {
+		t.Errorf("processOpenAIMessage allocated %.0f times per turn, budget is %d", allocs, openAITurnAllocBudget)
+	}
+}
+
+func TestHandleOllamaResponseAllocs(t *testing.T) {
+	skipUnderRace(t)
+	srv := newTestServer(t, ollamaChatBody)
+	m := newTestModel(t, OLLAMA, srv.URL)
+	chat := newTestChat()
+	messages := []Message{NewTextMessage(RoleUser, "hello")}
+	allocs := testing.AllocsPerRun(20, func() {
+		if err := handleOllamaResponse(context.Background(), m, nil, chat, messages); err != nil {
+			t.Fatal(err)
+		}
+		<-chat.Recv
+	})
+	if allocs > ollamaTurnAllocBudget {
+		t.Errorf("handleOllamaResponse allocated %.0f times per turn, budget is %d", allocs, ollamaTurnAllocBudget)
+	}
+}
+
+func TestCompactAllocs(t *testing.T) {
+	skipUnderRace(t)
+	srv := newTestServer(t, openAICompletionBody)
+	m := newTestModel(t, OPENAI, srv.URL)
+	messages := benchHistory(benchHistoryLength)
+	allocs := testing.AllocsPerRun(20, func() {
+		if _, err := compact(m, messages); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if allocs > compactAllocBudget {
+		t.Errorf("compact allocated %.0f times, budget is %d", allocs, compactAllocBudget)
+	}
+}
@@ -0,0 +1,168 @@
+package genai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// BestOfOptions configure GenerateBestOf
+type BestOfOptions struct {
+	// N is the number of candidates, 2 when zero
+	N int
+	// Models are used for the candidates in turn, e.g. to compare a local and a hosted
+	// model. The candidates use modelOptions.ModelName when it is empty.
+	Models []string
+	// Concurrency is the number of candidates generated at a time, N when zero
+	Concurrency int
+	// Score rates a response, higher is better. The judge scores the candidates when
+	// it is nil.
+	Score func(ctx context.Context, prompt, response string) (float64, error)
+	// JudgeModel compares the candidates, modelOptions.ModelName when empty
+	JudgeModel string
+	// JudgeCriteria replaces DefaultJudgeCriteria
+	JudgeCriteria string
+}
+
+// DefaultJudgeCriteria is what the judge scores candidates on
+const DefaultJudgeCriteria = "correctness, completeness and clarity"
+
+const judgePrompt = `Score each candidate response to the request from 0 to 10 for %s. Answer with JSON only, in the form {"scores": [score of candidate 1, score of candidate 2, ...]}.
+
+<request>
+%s
+</request>
+`
+
+// Candidate is one of the responses sampled by GenerateBestOf
+type Candidate struct {
+	Model string
+	Text  string
+	Score float64
+	// Err is set when the candidate could not be generated, it is not scored
+	Err error
+}
+
+// BestOfResult is the best response of GenerateBestOf with all candidates and the
+// usage of generating and judging them
+type BestOfResult struct {
+	Best       Candidate
+	Candidates []Candidate
+	Usage      Usage
+}
+
+// GenerateBestOf samples N responses to prompt, concurrently like GenerateBatch, and
+// returns the one with the highest score. Candidates are scored with Score or, when it
+// is nil, compared by a judge model. Failed candidates are left out, it only fails
+// when no candidate could be generated or scored.
+func (p *Provider) GenerateBestOf(ctx context.Context, modelOptions ModelOptions, prompt string, opts BestOfOptions) (BestOfResult, error) {
+	n := opts.N
+	if n <= 0 {
+		n = 2
+	}
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = n
+	}
+	options := make([]ModelOptions, n)
+	prompts := make([]string, n)
+	for i := range options {
+		options[i] = modelOptions
+		if len(opts.Models) > 0 {
+			options[i].ModelName = opts.Models[i%len(opts.Models)]
+		}
+		prompts[i] = prompt
+	}
+	results, usage := p.generateAll(ctx, options, prompts, concurrency)
+
+	result := BestOfResult{Candidates: make([]Candidate, n), Usage: usage}
+	var generated []int
+	var errs []error
+	for i, r := range results {
+		result.Candidates[i] = Candidate{Model: options[i].ModelName, Text: r.Text, Err: r.Err}
+		if r.Err != nil {
+			errs = append(errs, r.Err)
+			continue
+		}
+		generated = append(generated, i)
+	}
+	if len(generated) == 0 {
+		return result, fmt.Errorf("no candidates were generated: %w", errors.Join(errs...))
+	}
+
+	if opts.Score != nil {
+		scored := 0
+		for _, i := range generated {
+			score, err := opts.Score(ctx, prompt, result.Candidates[i].Text)
+			if err != nil {
+				p.Log.Error(err, "Failed to score candidate", "candidate", i)
+				continue
+			}
+			result.Candidates[i].Score = score
+			scored++
+		}
+		if scored == 0 {
+			return result, errors.New("no candidates could be scored")
+		}
+	} else if len(generated) > 1 {
+		scores, judgeUsage, err := p.judge(ctx, modelOptions, prompt, result.Candidates, generated, opts)
+		result.Usage.Add(judgeUsage)
+		if err != nil {
+			return result, fmt.Errorf("failed to judge candidates: %w", err)
+		}
+		for j, i := range generated {
+			result.Candidates[i].Score = scores[j]
+		}
+	}
+
+	best := generated[0]
+	for _, i := range generated[1:] {
+		if result.Candidates[i].Score > result.Candidates[best].Score {
+			best = i
+		}
+	}
+	result.Best = result.Candidates[best]
+	return result, nil
+}
+
+// judge asks the judge model to score the generated candidates
+func (p *Provider) judge(ctx context.Context, modelOptions ModelOptions, prompt string, candidates []Candidate, generated []int, opts BestOfOptions) ([]float64, Usage, error) {
+	criteria := opts.JudgeCriteria
+	if criteria == "" {
+		criteria = DefaultJudgeCriteria
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, judgePrompt, criteria, prompt)
+	for j, i := range generated {
+		fmt.Fprintf(&sb, "\n<candidate %d>\n%s\n</candidate %d>\n", j+1, candidates[i].Text, j+1)
+	}
+	model := opts.JudgeModel
+	if model == "" {
+		model = modelOptions.ModelName
+	}
+	// the judge runs without the system prompt and examples of the candidates
+	response, usage, err := p.generateWithUsage(ctx, ModelOptions{
+		ModelName: model,
+		Timeouts:  modelOptions.Timeouts,
+		Reasoning: ReasoningStrip,
+	}, sb.String())
+	if err != nil {
+		return nil, usage, err
+	}
+	response = strings.TrimSpace(response)
+	if match := fencePattern.FindStringSubmatch(response); match != nil {
+		response = match[1]
+	}
+	var parsed struct {
+		Scores []float64 `json:"scores"`
+	}
+	if err := json.Unmarshal([]byte(response), &parsed); err != nil {
+		return nil, usage, fmt.Errorf("invalid scores %q: %w", response, err)
+	}
+	if len(parsed.Scores) != len(generated) {
+		return nil, usage, fmt.Errorf("got %d scores for %d candidates", len(parsed.Scores), len(generated))
+	}
+	return parsed.Scores, usage, nil
+}
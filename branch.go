@@ -0,0 +1,118 @@
+package genai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+
+	"github.com/jbutlerdev/genai/tools"
+)
+
+// ErrNothingToRegenerate is returned by Regenerate before the first message is sent
+var ErrNothingToRegenerate = errors.New("no message to regenerate")
+
+// Fork starts a new chat with the first at messages of History and the same model,
+// parameters, tools and session. The branches continue independently, each with its
+// own history, usage and conversation id. Fork(len(chat.History())) branches from the
+// current state.
+func (c *Chat) Fork(at int) (*Chat, error) {
+	history := c.History()
+	if at < 0 || at > len(history) {
+		return nil, fmt.Errorf("fork at %d is outside the history of %d messages", at, len(history))
+	}
+	history = history[:at]
+	if pendingToolCalls(history) {
+		return nil, fmt.Errorf("fork at %d separates tool calls from their results", at)
+	}
+
+	m := c.model
+	// updates are applied under updateMu, so the options are read between them
+	c.updateMu.Lock()
+	options := m.options()
+	options.ModelName = m.requestedModel
+	options.Parameters = maps.Clone(m.Parameters)
+	if m.packer != nil {
+		options.ContextPacking = true
+		options.PackRecentTurns = m.packer.recentTurns
+	}
+	var toolsToUse []*tools.Tool
+	for _, tool := range m.Tools {
+		if _, ok := m.localTools[tool.Name]; !ok {
+			toolsToUse = append(toolsToUse, tool)
+		}
+	}
+	c.updateMu.Unlock()
+	options.History = history
+
+	fork := m.Provider.newChat(options, toolsToUse, c.Events != nil)
+	session := m.sessionContext()
+	fork.model.updateSession(func(s *Session) {
+		s.Workdir = session.Workdir
+		s.RepoRoot = session.RepoRoot
+		s.Facts = maps.Clone(session.Facts)
+		s.Env = maps.Clone(session.Env)
+		s.CredentialScope = session.CredentialScope
+	})
+	return fork, nil
+}
+
+// pendingToolCalls reports whether the last tool calls in messages have no results
+func pendingToolCalls(messages []Message) bool {
+	pending := 0
+	for _, msg := range messages {
+		pending += len(msg.ToolCalls())
+		pending -= len(msg.ToolResults())
+		pending = max(pending, 0)
+	}
+	return pending > 0
+}
+
+// Regenerate replaces the last response with a new one, see RegenerateCtx
+func (c *Chat) Regenerate(parameters map[string]any) error {
+	return c.RegenerateCtx(context.Background(), parameters)
+}
+
+// RegenerateCtx removes the last response, including its tool calls, and sends the
+// message it answered again. Parameters such as a higher Temperature are merged into the
+// model parameters first, as with SetParameters, and are kept for later turns. The new
+// response is reported like any other. When it is canceled the previous response is
+// kept.
+func (c *Chat) RegenerateCtx(ctx context.Context, parameters map[string]any) error {
+	if lastUserMessage(c.History()) < 0 {
+		return ErrNothingToRegenerate
+	}
+	if parameters != nil {
+		c.SetParameters(parameters)
+	}
+	select {
+	case c.requests <- chatRequest{ctx: ctx, regenerate: true}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// lastUserMessage returns the index of the last user message, or -1 when there is none
+func lastUserMessage(history []Message) int {
+	for i := len(history) - 1; i >= 0; i-- {
+		if history[i].Role == RoleUser {
+			return i
+		}
+	}
+	return -1
+}
+
+// popResponse removes the last user message and its response from the history and
+// returns the message, it runs on the chat goroutine. The full history is restored if
+// the regenerated turn is canceled.
+func (c *Chat) popResponse(m *Model) (Message, bool) {
+	history := m.History()
+	i := lastUserMessage(history)
+	if i < 0 {
+		return Message{}, false
+	}
+	c.restoreHistory = history
+	m.setHistory(history[:i])
+	return history[i], true
+}
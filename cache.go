@@ -0,0 +1,278 @@
+package genai
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+// ResponseCache stores provider responses under the hash of their request. Set it with
+// ProviderOptions.Cache, only deterministic requests are cached: requests with a
+// temperature of 0 or a fixed seed.
+type ResponseCache interface {
+	// Get returns the response stored under key, false when there is none
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	// Set stores the response under key
+	Set(ctx context.Context, key string, value []byte) error
+}
+
+// CacheStats counts the requests of a provider that could be cached
+type CacheStats struct {
+	Hits   int64 `json:"hits"`
+	Misses int64 `json:"misses"`
+	// Errors are failed reads and writes, the request is sent when the cache fails
+	Errors int64 `json:"errors"`
+	// SemanticHits and SemanticMisses count the prompts looked up in the SemanticCache
+	SemanticHits   int64 `json:"semanticHits,omitempty"`
+	SemanticMisses int64 `json:"semanticMisses,omitempty"`
+}
+
+// responseCache is the cache of a provider with its counters
+type responseCache struct {
+	cache  ResponseCache
+	hits   atomic.Int64
+	misses atomic.Int64
+	errors atomic.Int64
+}
+
+func newResponseCache(cache ResponseCache) *responseCache {
+	if cache == nil {
+		return nil
+	}
+	return &responseCache{cache: cache}
+}
+
+// CacheStats returns the hits and misses of the provider's response and semantic caches
+func (p *Provider) CacheStats() CacheStats {
+	var stats CacheStats
+	if p.cache != nil {
+		stats.Hits = p.cache.hits.Load()
+		stats.Misses = p.cache.misses.Load()
+		stats.Errors = p.cache.errors.Load()
+	}
+	if p.SemanticCache != nil {
+		stats.SemanticHits = p.SemanticCache.hits.Load()
+		stats.SemanticMisses = p.SemanticCache.misses.Load()
+	}
+	return stats
+}
+
+// deterministic reports that the parameters ask for repeatable responses
+func deterministic(parameters map[string]any) bool {
+	if _, ok := parameters[Seed]; ok {
+		return true
+	}
+	temperature, ok := toFloat(parameters[Temperature])
+	return ok && temperature == 0
+}
+
+// cacheKey hashes the request of a provider with the parameters that are not part of it
+func cacheKey(provider string, request any, parameters map[string]any) (string, error) {
+	data, err := json.Marshal(struct {
+		Provider   string         `json:"provider"`
+		Request    any            `json:"request"`
+		Parameters map[string]any `json:"parameters,omitempty"`
+	}{provider, request, parameters})
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// rawJSON is implemented by the OpenAI responses, which keep the JSON they were read from
+type rawJSON interface {
+	RawJSON() string
+}
+
+// cached returns the cached response to request when the parameters are deterministic,
+// otherwise it sends the request and caches the response. hit reports a cached response,
+// its usage must not be recorded. A failing cache is logged and the request is sent.
+func cached[T any](ctx context.Context, c *responseCache, log logr.Logger, provider string, request any, parameters map[string]any, send func() (T, error)) (resp T, hit bool, err error) {
+	if c == nil || !deterministic(parameters) {
+		resp, err = send()
+		return resp, false, err
+	}
+	key, err := cacheKey(provider, request, parameters)
+	if err != nil {
+		c.errors.Add(1)
+		log.Error(err, "Failed to hash request for the response cache")
+		resp, err = send()
+		return resp, false, err
+	}
+	data, ok, err := c.cache.Get(ctx, key)
+	if err != nil {
+		c.errors.Add(1)
+		log.Error(err, "Failed to read the response cache", "key", key)
+	}
+	if ok {
+		decodeErr := json.Unmarshal(data, &resp)
+		if decodeErr == nil {
+			c.hits.Add(1)
+			log.Info("Using cached response", "key", key)
+			return resp, true, nil
+		}
+		c.errors.Add(1)
+		log.Error(decodeErr, "Ignoring invalid cached response", "key", key)
+		var zero T
+		resp = zero
+	}
+	c.misses.Add(1)
+	resp, err = send()
+	if err != nil {
+		return resp, false, err
+	}
+	if raw, ok := any(resp).(rawJSON); ok && raw.RawJSON() != "" {
+		data = []byte(raw.RawJSON())
+	} else if data, err = json.Marshal(resp); err != nil {
+		c.errors.Add(1)
+		log.Error(err, "Failed to encode response for the response cache")
+		return resp, false, nil
+	}
+	if err := c.cache.Set(ctx, key, data); err != nil {
+		c.errors.Add(1)
+		log.Error(err, "Failed to write the response cache", "key", key)
+	}
+	return resp, false, nil
+}
+
+// MemoryCache is a ResponseCache that keeps the most recently used responses in memory
+type MemoryCache struct {
+	size    int
+	mu      sync.Mutex
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+type memoryEntry struct {
+	key   string
+	value []byte
+}
+
+// NewMemoryCache creates a cache of at most size responses, 1000 when size is not positive
+func NewMemoryCache(size int) *MemoryCache {
+	if size <= 0 {
+		size = 1000
+	}
+	return &MemoryCache{size: size, order: list.New(), entries: make(map[string]*list.Element)}
+}
+
+func (c *MemoryCache) Get(_ context.Context, key string) ([]byte, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+	c.order.MoveToFront(e)
+	return e.Value.(*memoryEntry).value, true, nil
+}
+
+func (c *MemoryCache) Set(_ context.Context, key string, value []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[key]; ok {
+		e.Value.(*memoryEntry).value = value
+		c.order.MoveToFront(e)
+		return nil
+	}
+	c.entries[key] = c.order.PushFront(&memoryEntry{key: key, value: value})
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*memoryEntry).key)
+	}
+	return nil
+}
+
+// Len returns the number of cached responses
+func (c *MemoryCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// DiskCache is a ResponseCache that stores each response in a file under a directory,
+// so responses survive restarts and can be shared by processes on the same host
+type DiskCache struct {
+	dir string
+	ttl time.Duration
+}
+
+// NewDiskCache creates the directory of the cache. Responses older than ttl are treated
+// as missing, they never expire when ttl is zero.
+func NewDiskCache(dir string, ttl time.Duration) (*DiskCache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	return &DiskCache{dir: dir, ttl: ttl}, nil
+}
+
+// path spreads the files over subdirectories named after the first byte of the hash
+func (c *DiskCache) path(key string) string {
+	if len(key) < 2 {
+		return filepath.Join(c.dir, key)
+	}
+	return filepath.Join(c.dir, key[:2], key)
+}
+
+func (c *DiskCache) Get(_ context.Context, key string) ([]byte, bool, error) {
+	path := c.path(key)
+	if c.ttl > 0 {
+		info, err := os.Stat(path)
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, false, nil
+		}
+		if err != nil {
+			return nil, false, err
+		}
+		if time.Since(info.ModTime()) > c.ttl {
+			return nil, false, nil
+		}
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return data, true, nil
+}
+
+// Set writes the response to a temporary file first, readers never see a partial response
+func (c *DiskCache) Set(_ context.Context, key string, value []byte) error {
+	path := c.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), ".tmp-")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(value); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	if err := os.Rename(f.Name(), path); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,220 @@
+package genai
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+func TestResponseCache(t *testing.T) {
+	for _, tc := range []struct {
+		provider string
+		body     string
+	}{
+		{OPENAI, openAICompletionBody},
+		{OLLAMA, ollamaGenerateBody},
+		{GEMINI, geminiResponseBody},
+	} {
+		t.Run(tc.provider, func(t *testing.T) {
+			srv := newTestServer(t, tc.body)
+			p, err := NewProvider(tc.provider, ProviderOptions{APIKey: "test", BaseURL: srv.URL, Cache: NewMemoryCache(10)})
+			if err != nil {
+				t.Fatal(err)
+			}
+			p.Log = logr.Discard()
+			p.Retry = RetryPolicy{MaxAttempts: 1}
+			send := func(params map[string]any) Usage {
+				t.Helper()
+				text, usage, err := p.generateWithUsage(context.Background(), ModelOptions{ModelName: "test", Parameters: params}, "hi")
+				if err != nil {
+					t.Fatal(err)
+				}
+				if text != "hello" {
+					t.Fatalf("response %q, want hello", text)
+				}
+				return usage
+			}
+
+			send(map[string]any{Temperature: 0})
+			usage := send(map[string]any{Temperature: 0})
+			if n := len(srv.recorded()); n != 1 {
+				t.Errorf("deterministic requests sent %d times, want 1", n)
+			}
+			if usage.CachedResponses != 1 || usage.Requests != 0 {
+				t.Errorf("usage of the cached response %+v, want one cached response and no request", usage)
+			}
+
+			// a different seed is a different request
+			send(map[string]any{Seed: 1})
+			send(map[string]any{Seed: 2})
+			if n := len(srv.recorded()); n != 3 {
+				t.Errorf("server received %d requests, want 3", n)
+			}
+
+			send(map[string]any{Temperature: 0.7})
+			send(map[string]any{Temperature: 0.7})
+			if n := len(srv.recorded()); n != 5 {
+				t.Errorf("sampled requests were cached, server received %d requests, want 5", n)
+			}
+			if stats := p.CacheStats(); stats.Hits != 1 || stats.Misses != 3 || stats.Errors != 0 {
+				t.Errorf("stats %+v, want 1 hit and 3 misses", stats)
+			}
+		})
+	}
+}
+
+func TestMemoryCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	ctx := context.Background()
+	c := NewMemoryCache(2)
+	c.Set(ctx, "a", []byte("1"))
+	c.Set(ctx, "b", []byte("2"))
+	c.Get(ctx, "a")
+	c.Set(ctx, "c", []byte("3"))
+	if _, ok, _ := c.Get(ctx, "b"); ok {
+		t.Error("b was not evicted")
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, ok, _ := c.Get(ctx, key); !ok {
+			t.Errorf("%s was evicted", key)
+		}
+	}
+	if c.Len() != 2 {
+		t.Errorf("cache holds %d responses, want 2", c.Len())
+	}
+}
+
+func TestDiskCache(t *testing.T) {
+	ctx := context.Background()
+	c, err := NewDiskCache(t.TempDir(), time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, err := c.Get(ctx, "abc"); ok || err != nil {
+		t.Fatalf("Get of a missing key = %v, %v", ok, err)
+	}
+	if err := c.Set(ctx, "abc", []byte("response")); err != nil {
+		t.Fatal(err)
+	}
+	data, ok, err := c.Get(ctx, "abc")
+	if err != nil || !ok || string(data) != "response" {
+		t.Fatalf("Get = %q, %v, %v", data, ok, err)
+	}
+	old := time.Now().Add(-2 * time.Hour)
+	if err := os.Chtimes(c.path("abc"), old, old); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, _ := c.Get(ctx, "abc"); ok {
+		t.Error("expired response was returned")
+	}
+}
+
+// fakeRedis answers GET, SET and AUTH like a Redis server
+type fakeRedis struct {
+	net.Listener
+	mu     sync.Mutex
+	values map[string]string
+	// commands are the commands received, without values
+	commands []string
+}
+
+func newFakeRedis(t *testing.T) *fakeRedis {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &fakeRedis{Listener: l, values: make(map[string]string)}
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go r.serve(conn)
+		}
+	}()
+	return r
+}
+
+func (r *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+	br := bufio.NewReader(conn)
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil {
+			return
+		}
+		n, _ := strconv.Atoi(strings.TrimSpace(line[1:]))
+		args := make([]string, n)
+		for i := range args {
+			line, _ := br.ReadString('\n')
+			size, _ := strconv.Atoi(strings.TrimSpace(line[1:]))
+			data := make([]byte, size+2)
+			if _, err := io.ReadFull(br, data); err != nil {
+				return
+			}
+			args[i] = string(data[:size])
+		}
+		r.mu.Lock()
+		command := args[0]
+		if len(args) > 3 {
+			command = strings.Join(append([]string{args[0]}, args[3:]...), " ")
+		}
+		r.commands = append(r.commands, command)
+		switch args[0] {
+		case "AUTH":
+			fmt.Fprint(conn, "+OK\r\n")
+		case "SET":
+			r.values[args[1]] = args[2]
+			fmt.Fprint(conn, "+OK\r\n")
+		case "GET":
+			if v, ok := r.values[args[1]]; ok {
+				fmt.Fprintf(conn, "$%d\r\n%s\r\n", len(v), v)
+			} else {
+				fmt.Fprint(conn, "$-1\r\n")
+			}
+		default:
+			fmt.Fprintf(conn, "-ERR unknown command '%s'\r\n", args[0])
+		}
+		r.mu.Unlock()
+	}
+}
+
+func TestRedisCache(t *testing.T) {
+	ctx := context.Background()
+	server := newFakeRedis(t)
+	c := NewRedisCache(RedisCacheOptions{Addr: server.Addr().String(), Password: "secret", TTL: time.Minute})
+	defer c.Close()
+
+	if _, ok, err := c.Get(ctx, "abc"); ok || err != nil {
+		t.Fatalf("Get of a missing key = %v, %v", ok, err)
+	}
+	value := "line one\r\nline two"
+	if err := c.Set(ctx, "abc", []byte(value)); err != nil {
+		t.Fatal(err)
+	}
+	data, ok, err := c.Get(ctx, "abc")
+	if err != nil || !ok || string(data) != value {
+		t.Fatalf("Get = %q, %v, %v", data, ok, err)
+	}
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	if _, ok := server.values["genai:abc"]; !ok {
+		t.Errorf("keys %v are not prefixed", server.values)
+	}
+	want := []string{"AUTH", "GET", "SET PX 60000", "GET"}
+	if fmt.Sprint(server.commands) != fmt.Sprint(want) {
+		t.Errorf("commands %v, want %v", server.commands, want)
+	}
+}
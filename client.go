@@ -3,11 +3,11 @@ package genai
 import (
 	"context"
 	"fmt"
+	"net/http"
 
-	gemini "github.com/google/generative-ai-go/genai"
+	"github.com/go-logr/logr"
 	ollama "github.com/ollama/ollama/api"
-	"google.golang.org/api/iterator"
-	"google.golang.org/api/option"
+	gemini "google.golang.org/genai"
 )
 
 type Client struct {
@@ -16,6 +16,9 @@ type Client struct {
 	Gemini   *gemini.Client
 	Ollama   *ollama.Client
 	OpenAI   *OpenAIClient
+	// http is the HTTP client passed to the SDK, nil when it uses its own
+	http *http.Client
+	log  logr.Logger
 }
 
 func NewClient(provider *Provider) (*Client, error) {
@@ -23,17 +26,36 @@ func NewClient(provider *Provider) (*Client, error) {
 	client := &Client{
 		ctx:      ctx,
 		provider: provider.Provider,
+		log:      provider.Log,
 	}
 	switch provider.Provider {
 	case GEMINI:
-		g, err := gemini.NewClient(ctx, option.WithAPIKey(provider.APIKey))
+		config := &gemini.ClientConfig{
+			APIKey:      provider.APIKey,
+			Backend:     gemini.BackendGeminiAPI,
+			HTTPOptions: gemini.HTTPOptions{BaseURL: provider.BaseURL},
+		}
+		if provider.customTransport() {
+			hc, err := provider.httpClient(nil)
+			if err != nil {
+				return nil, err
+			}
+			config.HTTPClient = hc
+			client.http = hc
+		}
+		g, err := gemini.NewClient(ctx, config)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create Gemini client: %v", err)
 		}
 		client.Gemini = g
 	case OLLAMA:
-		client.Ollama = NewOllamaClient(provider.BaseURL)
-	case OPENAI:
+		hc, err := provider.httpClient(nil)
+		if err != nil {
+			return nil, err
+		}
+		client.Ollama = newOllamaClient(provider.BaseURL, hc)
+		client.http = hc
+	case OPENAI, VLLM:
 		o, err := NewOpenAIClient(provider)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create OpenAI client: %v", err)
@@ -43,44 +65,59 @@ func NewClient(provider *Provider) (*Client, error) {
 			o.model = provider.EmbeddingModel
 		}
 		client.OpenAI = o
+		client.http = o.httpClient
 	}
 	return client, nil
 }
 
+// Models returns the available models. Errors are logged, OpenAI compatible providers
+// fall back to a default list and the others return an empty slice. Use
+// ModelsWithError to distinguish "no models" from a failure.
 func (c *Client) Models() []string {
+	switch c.provider {
+	case OPENAI, VLLM:
+		return c.OpenAI.Models()
+	}
+	models, err := c.ModelsWithError()
+	if err != nil {
+		c.log.Error(err, "Failed to get models", "provider", c.provider)
+		return []string{}
+	}
+	return models
+}
+
+// ModelsWithError returns the available models or the error from the provider
+func (c *Client) ModelsWithError() ([]string, error) {
 	switch c.provider {
 	case GEMINI:
 		return c.getGeminiModels()
 	case OLLAMA:
 		return c.getOllamaModels()
-	case OPENAI:
-		return c.OpenAI.Models()
+	case OPENAI, VLLM:
+		return c.OpenAI.ModelsWithError()
 	}
-	return []string{}
+	return nil, fmt.Errorf("unsupported provider: %s", c.provider)
 }
 
-func (c *Client) getGeminiModels() []string {
-	iter := c.Gemini.ListModels(c.ctx)
+func (c *Client) getGeminiModels() ([]string, error) {
 	var geminiModels []string
-	for {
-		model, err := iter.Next()
-		if err == iterator.Done {
-			break
+	for model, err := range c.Gemini.Models.All(c.ctx) {
+		if err != nil {
+			return nil, fmt.Errorf("failed to list Gemini models: %w", wrapProviderError(GEMINI, err))
 		}
 		geminiModels = append(geminiModels, model.Name)
 	}
-	return geminiModels
+	return geminiModels, nil
 }
 
-func (c *Client) getOllamaModels() []string {
+func (c *Client) getOllamaModels() ([]string, error) {
 	models, err := c.Ollama.List(c.ctx)
 	if err != nil {
-		fmt.Printf("failed to get Ollama models: %v", err)
-		return []string{}
+		return nil, fmt.Errorf("failed to get Ollama models: %w", wrapProviderError(OLLAMA, err))
 	}
 	var ollamaModels []string
 	for _, model := range models.Models {
 		ollamaModels = append(ollamaModels, model.Name)
 	}
-	return ollamaModels
+	return ollamaModels, nil
 }
@@ -0,0 +1,30 @@
+package genai
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestModels(t *testing.T) {
+	srv := newTestServer(t, `{"object":"list","data":[]}`)
+	p, err := NewProvider(OPENAI, ProviderOptions{APIKey: "test", BaseURL: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// OpenAI compatible providers fall back to the default models
+	if models := p.Models(); !slices.Contains(models, "gpt-4") {
+		t.Errorf("models %v, want the defaults", models)
+	}
+	if models, err := p.ModelsWithError(); err != nil || len(models) != 0 {
+		t.Errorf("ModelsWithError = %v, %v, want no models", models, err)
+	}
+
+	srv = newTestServer(t, `{"models":[{"name":"llama3"}]}`)
+	p, err = NewProvider(OLLAMA, ProviderOptions{BaseURL: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if models := p.Models(); !slices.Equal(models, []string{"llama3"}) {
+		t.Errorf("models %v, want [llama3]", models)
+	}
+}
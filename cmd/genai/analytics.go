@@ -0,0 +1,89 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jbutlerdev/genai"
+)
+
+func runAnalytics(args []string) error {
+	var input, turnsPath, toolsPath string
+	flags := flag.NewFlagSet("analytics", flag.ContinueOnError)
+	flags.StringVar(&input, "input", "", "transcript exported with Chat.Export, or a directory of them")
+	flags.StringVar(&turnsPath, "turns", "-", "CSV file for the turns, - for stdout")
+	flags.StringVar(&toolsPath, "tools", "", "CSV file for the tool calls")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if input == "" {
+		return fmt.Errorf("-input is required")
+	}
+	files, err := transcriptFiles(input)
+	if err != nil {
+		return err
+	}
+	var analytics genai.Analytics
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		transcript, err := genai.ParseTranscript(data)
+		if err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+		analytics.AddTranscript(transcript)
+	}
+	if err := writeCSV(turnsPath, analytics.WriteTurnsCSV); err != nil {
+		return err
+	}
+	if toolsPath != "" {
+		if err := writeCSV(toolsPath, analytics.WriteToolsCSV); err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(os.Stderr, "exported %d turns and %d tool calls from %d transcripts\n", len(analytics.Turns), len(analytics.Tools), len(files))
+	return nil
+}
+
+// transcriptFiles returns input, or the JSON files in it when it is a directory
+func transcriptFiles(input string) ([]string, error) {
+	info, err := os.Stat(input)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{input}, nil
+	}
+	var files []string
+	err = filepath.WalkDir(input, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && strings.HasSuffix(path, ".json") {
+			files = append(files, path)
+		}
+		return nil
+	})
+	return files, err
+}
+
+func writeCSV(path string, write func(io.Writer) error) error {
+	if path == "-" {
+		return write(os.Stdout)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
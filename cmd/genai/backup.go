@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/jbutlerdev/genai/tools"
+)
+
+// backupFlags select where backups are kept, a local directory or a bucket
+type backupFlags struct {
+	databaseURL string
+	dir         string
+	bucket      string
+	prefix      string
+	endpoint    string
+}
+
+func (f *backupFlags) register(flags *flag.FlagSet) {
+	flags.StringVar(&f.databaseURL, "db", "", "PostgreSQL URL of the memory store")
+	flags.StringVar(&f.dir, "dir", "", "directory holding the backups")
+	flags.StringVar(&f.bucket, "bucket", "", "S3 compatible bucket holding the backups, credentials are read from AWS_* variables")
+	flags.StringVar(&f.prefix, "prefix", "genai-backups", "key prefix of the backups in the bucket")
+	flags.StringVar(&f.endpoint, "endpoint", "", "object storage endpoint, e.g. https://storage.googleapis.com for GCS")
+}
+
+func (f *backupFlags) open() (*tools.MemoryTool, tools.BackupStore, error) {
+	if f.databaseURL == "" {
+		return nil, nil, fmt.Errorf("-db is required")
+	}
+	var store tools.BackupStore
+	switch {
+	case f.dir != "" && f.bucket != "":
+		return nil, nil, fmt.Errorf("-dir and -bucket cannot both be set")
+	case f.dir != "":
+		store = tools.LocalBackupStore{Dir: f.dir}
+	case f.bucket != "":
+		config := tools.S3ConfigFromEnv()
+		config.Bucket = f.bucket
+		if f.endpoint != "" {
+			config.Endpoint = f.endpoint
+		}
+		client, err := tools.NewS3Client(config)
+		if err != nil {
+			return nil, nil, err
+		}
+		store = tools.S3BackupStore{Client: client, Prefix: f.prefix}
+	default:
+		return nil, nil, fmt.Errorf("-dir or -bucket is required")
+	}
+	// backups copy the stored embeddings, so no embedding provider is needed
+	memory, err := tools.NewMemoryTool(tools.MemoryConfig{DatabaseURL: f.databaseURL}, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	return memory, store, nil
+}
+
+func runBackup(args []string) error {
+	var target backupFlags
+	var every time.Duration
+	var keep int
+	flags := flag.NewFlagSet("backup", flag.ContinueOnError)
+	target.register(flags)
+	flags.DurationVar(&every, "every", 0, "keep running and back up at this interval, e.g. 6h")
+	flags.IntVar(&keep, "keep", 0, "number of backups to retain, all when zero")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	memory, store, err := target.open()
+	if err != nil {
+		return err
+	}
+	defer memory.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	report := func(info tools.BackupInfo, err error) {
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "backup failed: %v\n", err)
+			return
+		}
+		fmt.Fprintf(os.Stderr, "wrote %s with %d memories (%d bytes)\n", info.Name, info.Memories, info.Size)
+	}
+	if every > 0 {
+		if err := memory.ScheduleBackups(ctx, store, tools.BackupSchedule{Interval: every, Keep: keep, OnBackup: report}); err != nil {
+			return err
+		}
+		<-ctx.Done()
+		return nil
+	}
+	info, err := memory.Backup(ctx, store)
+	if err != nil {
+		return err
+	}
+	report(info, nil)
+	if keep > 0 {
+		return tools.PruneBackups(ctx, store, keep)
+	}
+	return nil
+}
+
+func runRestore(args []string) error {
+	var target backupFlags
+	var name string
+	var replace bool
+	flags := flag.NewFlagSet("restore", flag.ContinueOnError)
+	target.register(flags)
+	flags.StringVar(&name, "name", "", "backup to restore, the newest when empty")
+	flags.BoolVar(&replace, "replace", false, "delete all memories before restoring")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	memory, store, err := target.open()
+	if err != nil {
+		return err
+	}
+	defer memory.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if name == "" {
+		if name, err = tools.LatestBackup(ctx, store); err != nil {
+			return err
+		}
+	}
+	count, err := memory.RestoreBackup(ctx, store, name, tools.RestoreOptions{Replace: replace})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "restored %d memories from %s\n", count, name)
+	return nil
+}
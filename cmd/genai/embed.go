@@ -0,0 +1,296 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/jbutlerdev/genai"
+	"github.com/jbutlerdev/genai/tools"
+)
+
+// embedRecord is one line of the JSONL output
+type embedRecord struct {
+	ID        string    `json:"id"`
+	File      string    `json:"file"`
+	Chunk     int       `json:"chunk"`
+	Offset    int       `json:"offset"`
+	Content   string    `json:"content"`
+	Embedding []float32 `json:"embedding"`
+}
+
+type embedBatch struct {
+	records []embedRecord
+}
+
+type embedOptions struct {
+	providerFlags
+	input       string
+	out         string
+	databaseURL string
+	namespace   string
+	model       string
+	extensions  string
+	chunkSize   int
+	overlap     int
+	batchSize   int
+	concurrency int
+	rate        float64
+}
+
+func runEmbed(args []string) error {
+	var opts embedOptions
+	flags := flag.NewFlagSet("embed", flag.ContinueOnError)
+	flags.StringVar(&opts.provider, "provider", genai.OPENAI, "provider: openai, gemini, ollama or vllm")
+	flags.StringVar(&opts.apiKey, "api-key", "", "API key, defaults to the provider's API key environment variable")
+	flags.StringVar(&opts.baseURL, "base-url", "", "provider base URL")
+	flags.StringVar(&opts.model, "model", "", "embedding model")
+	flags.StringVar(&opts.input, "input", "", "file or directory to embed")
+	flags.StringVar(&opts.out, "out", "-", "JSONL output file, - for stdout")
+	flags.StringVar(&opts.databaseURL, "db", "", "store chunks in the memory store at this PostgreSQL URL instead of writing JSONL")
+	flags.StringVar(&opts.namespace, "namespace", "default", "memory namespace used with -db")
+	flags.StringVar(&opts.extensions, "ext", "", "comma separated file extensions to include, e.g. .md,.txt, all text files by default")
+	flags.IntVar(&opts.chunkSize, "chunk-size", 2000, "maximum chunk size in bytes")
+	flags.IntVar(&opts.overlap, "overlap", 200, "bytes shared by consecutive chunks")
+	flags.IntVar(&opts.batchSize, "batch", 32, "chunks embedded per request")
+	flags.IntVar(&opts.concurrency, "concurrency", 4, "concurrent embedding requests")
+	flags.Float64Var(&opts.rate, "rate", 0, "maximum embedding requests per second, 0 for no limit")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if opts.input == "" {
+		return fmt.Errorf("-input is required")
+	}
+	if opts.chunkSize <= 0 || opts.overlap < 0 || opts.overlap >= opts.chunkSize {
+		return fmt.Errorf("invalid -chunk-size %d and -overlap %d, overlap must be smaller than chunk-size", opts.chunkSize, opts.overlap)
+	}
+	if opts.batchSize <= 0 || opts.concurrency <= 0 || opts.rate < 0 {
+		return fmt.Errorf("-batch and -concurrency must be positive and -rate not negative")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	provider, err := opts.newProvider(genai.ProviderOptions{EmbeddingModel: opts.model})
+	if err != nil {
+		return fmt.Errorf("failed to create provider: %w", err)
+	}
+
+	batches, err := chunkInput(opts)
+	if err != nil {
+		return err
+	}
+	if len(batches) == 0 {
+		return fmt.Errorf("no text files found in %s", opts.input)
+	}
+
+	var sink func(embedBatch) error
+	if opts.databaseURL != "" {
+		memory, err := tools.NewMemoryTool(tools.MemoryConfig{
+			DatabaseURL:       opts.databaseURL,
+			EmbeddingProvider: opts.provider,
+			EmbeddingModel:    opts.model,
+		}, provider)
+		if err != nil {
+			return err
+		}
+		defer memory.Close()
+		sink = memorySink(ctx, memory, opts.namespace)
+	} else {
+		out := os.Stdout
+		if opts.out != "-" {
+			if out, err = os.Create(opts.out); err != nil {
+				return fmt.Errorf("failed to create output: %w", err)
+			}
+			defer out.Close()
+		}
+		w := bufio.NewWriter(out)
+		defer w.Flush()
+		sink = jsonlSink(ctx, provider, opts.model, w)
+	}
+	return embedBatches(ctx, batches, opts.concurrency, opts.rate, sink)
+}
+
+// chunkInput walks the input and groups the chunks of all text files into batches
+func chunkInput(opts embedOptions) ([]embedBatch, error) {
+	var include map[string]bool
+	if opts.extensions != "" {
+		include = make(map[string]bool)
+		for _, ext := range strings.Split(opts.extensions, ",") {
+			ext = strings.TrimSpace(ext)
+			if !strings.HasPrefix(ext, ".") {
+				ext = "." + ext
+			}
+			include[strings.ToLower(ext)] = true
+		}
+	}
+	var batches []embedBatch
+	var current embedBatch
+	err := filepath.WalkDir(opts.input, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != opts.input && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() || (include != nil && !include[strings.ToLower(filepath.Ext(path))]) {
+			return nil
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if !utf8.Valid(content) || strings.IndexByte(string(content), 0) >= 0 {
+			fmt.Fprintf(os.Stderr, "skipping binary file %s\n", path)
+			return nil
+		}
+		rel, err := filepath.Rel(opts.input, path)
+		if err != nil || rel == "." {
+			rel = filepath.Base(path)
+		}
+		for i, chunk := range tools.ChunkText(string(content), opts.chunkSize, opts.overlap) {
+			current.records = append(current.records, embedRecord{
+				ID:      fmt.Sprintf("%s#%d", filepath.ToSlash(rel), i),
+				File:    filepath.ToSlash(rel),
+				Chunk:   i,
+				Offset:  chunk.Offset,
+				Content: chunk.Content,
+			})
+			if len(current.records) == opts.batchSize {
+				batches = append(batches, current)
+				current = embedBatch{}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read input: %w", err)
+	}
+	if len(current.records) > 0 {
+		batches = append(batches, current)
+	}
+	return batches, nil
+}
+
+// embedBatches runs sink on each batch with at most concurrency batches in flight
+// and at most rate batches started per second
+func embedBatches(ctx context.Context, batches []embedBatch, concurrency int, rate float64, sink func(embedBatch) error) error {
+	var ticker *time.Ticker
+	if rate > 0 {
+		ticker = time.NewTicker(time.Duration(float64(time.Second) / rate))
+		defer ticker.Stop()
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	work := make(chan embedBatch)
+	var wg sync.WaitGroup
+	var once sync.Once
+	var firstErr error
+	var done, chunks int
+	var mu sync.Mutex
+	for range concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for batch := range work {
+				if err := sink(batch); err != nil {
+					once.Do(func() {
+						firstErr = err
+						cancel()
+					})
+					return
+				}
+				mu.Lock()
+				done++
+				chunks += len(batch.records)
+				fmt.Fprintf(os.Stderr, "\rembedded %d/%d batches (%d chunks)", done, len(batches), chunks)
+				mu.Unlock()
+			}
+		}()
+	}
+feed:
+	for _, batch := range batches {
+		if ticker != nil {
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				break feed
+			}
+		}
+		select {
+		case work <- batch:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(work)
+	wg.Wait()
+	fmt.Fprintln(os.Stderr)
+	if firstErr != nil {
+		return firstErr
+	}
+	return ctx.Err()
+}
+
+// jsonlSink embeds each batch and writes one JSON line per chunk
+func jsonlSink(ctx context.Context, provider *genai.Provider, model string, w io.Writer) func(embedBatch) error {
+	var mu sync.Mutex
+	encoder := json.NewEncoder(w)
+	return func(batch embedBatch) error {
+		texts := make([]string, len(batch.records))
+		for i, record := range batch.records {
+			texts[i] = record.Content
+		}
+		embeddings, err := provider.GenerateEmbeddings(ctx, texts, model)
+		if err != nil {
+			return fmt.Errorf("failed to embed %s: %w", batch.records[0].ID, err)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		for i, record := range batch.records {
+			record.Embedding = embeddings[i]
+			if err := encoder.Encode(record); err != nil {
+				return fmt.Errorf("failed to write output: %w", err)
+			}
+		}
+		return nil
+	}
+}
+
+// memorySink stores each batch in the memory store with the same metadata as the
+// ingest_file tool
+func memorySink(ctx context.Context, memory *tools.MemoryTool, namespace string) func(embedBatch) error {
+	return func(batch embedBatch) error {
+		contents := make([]string, len(batch.records))
+		metadata := make([]map[string]interface{}, len(batch.records))
+		for i, record := range batch.records {
+			contents[i] = record.Content
+			metadata[i] = map[string]interface{}{
+				"namespace": namespace,
+				"file":      record.File,
+				"offset":    record.Offset,
+				"length":    len(record.Content),
+				"chunk":     record.Chunk,
+			}
+		}
+		if _, err := memory.StoreBatch(ctx, contents, metadata); err != nil {
+			return fmt.Errorf("failed to store %s: %w", batch.records[0].ID, err)
+		}
+		return nil
+	}
+}
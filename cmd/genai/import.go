@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+
+	"github.com/jbutlerdev/genai"
+	"github.com/jbutlerdev/genai/tools"
+)
+
+func runImport(args []string) error {
+	var providerOpts providerFlags
+	var input, format, databaseURL, namespace, model string
+	var dryRun bool
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	flags.StringVar(&providerOpts.provider, "provider", genai.OPENAI, "provider: openai, gemini, ollama or vllm")
+	flags.StringVar(&providerOpts.apiKey, "api-key", "", "API key, defaults to the provider's API key environment variable")
+	flags.StringVar(&providerOpts.baseURL, "base-url", "", "provider base URL")
+	flags.StringVar(&model, "model", "", "embedding model")
+	flags.StringVar(&input, "input", "", "export file, - for stdin")
+	flags.StringVar(&format, "format", "", "export format: mem0 or langchain, detected when empty")
+	flags.StringVar(&databaseURL, "db", "", "PostgreSQL URL of the memory store")
+	flags.StringVar(&namespace, "namespace", "default", "namespace for memories without one")
+	flags.BoolVar(&dryRun, "dry-run", false, "print the parsed memories instead of storing them")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if input == "" {
+		return fmt.Errorf("-input is required")
+	}
+	var data []byte
+	var err error
+	if input == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(input)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read export: %w", err)
+	}
+	if format == "" {
+		format = tools.DetectMemoryExportFormat(data)
+	}
+	memories, err := tools.ParseMemoryExport(bytes.NewReader(data), format)
+	if err != nil {
+		return err
+	}
+	if dryRun {
+		encoder := json.NewEncoder(os.Stdout)
+		for _, memory := range memories {
+			if err := encoder.Encode(map[string]any{"content": memory.Content, "metadata": memory.Metadata}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if databaseURL == "" {
+		return fmt.Errorf("-db is required unless -dry-run is set")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	provider, err := providerOpts.newProvider(genai.ProviderOptions{EmbeddingModel: model})
+	if err != nil {
+		return fmt.Errorf("failed to create provider: %w", err)
+	}
+	memory, err := tools.NewMemoryTool(tools.MemoryConfig{
+		DatabaseURL:       databaseURL,
+		EmbeddingProvider: providerOpts.provider,
+		EmbeddingModel:    model,
+	}, provider)
+	if err != nil {
+		return err
+	}
+	defer memory.Close()
+	result, err := memory.Import(ctx, memories, format, namespace)
+	fmt.Fprintf(os.Stderr, "imported %d memories, skipped %d empty\n", result.Imported, result.Skipped)
+	return err
+}
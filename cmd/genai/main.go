@@ -0,0 +1,90 @@
+// Command genai exposes parts of the library on the command line.
+//
+// Usage:
+//
+//	genai <command> [flags]
+//
+// Run genai <command> -h for the flags of a command.
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/jbutlerdev/genai"
+)
+
+type command struct {
+	description string
+	run         func(args []string) error
+}
+
+var commands = map[string]command{
+	"analytics": {description: "Export turns, tool calls, tokens and latency of chat transcripts as CSV", run: runAnalytics},
+	"backup":    {description: "Back up the memory store to a directory or bucket, optionally on a schedule", run: runBackup},
+	"embed":     {description: "Chunk and embed a directory of files into JSONL or the memory store", run: runEmbed},
+	"import":    {description: "Import memories exported from mem0 or LangChain into the memory store", run: runImport},
+	"replay":    {description: "Show the requests a chat transcript was built from, turn by turn", run: runReplay},
+	"restore":   {description: "Restore the memory store from a backup", run: runRestore},
+	"tools":     {description: "Document the parameters and examples of the registered tools, or serve the docs over HTTP", run: runTools},
+}
+
+func main() {
+	if len(os.Args) < 2 || os.Args[1] == "-h" || os.Args[1] == "--help" || os.Args[1] == "help" {
+		usage()
+		os.Exit(2)
+	}
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command: %s\n\n", os.Args[1])
+		usage()
+		os.Exit(2)
+	}
+	if err := cmd.run(os.Args[2:]); err != nil {
+		fmt.Fprintf(os.Stderr, "genai %s: %v\n", os.Args[1], err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: genai <command> [flags]")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Commands:")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", name, commands[name].description)
+	}
+}
+
+// providerFlags are the flags shared by commands that talk to a provider
+type providerFlags struct {
+	provider string
+	apiKey   string
+	baseURL  string
+}
+
+// newProvider creates the provider, the API key defaults to <PROVIDER>_API_KEY
+func (f providerFlags) newProvider(options genai.ProviderOptions) (*genai.Provider, error) {
+	options.APIKey = f.apiKey
+	if options.APIKey == "" {
+		options.APIKey = os.Getenv(apiKeyEnv(f.provider))
+	}
+	options.BaseURL = f.baseURL
+	return genai.NewProvider(f.provider, options)
+}
+
+func apiKeyEnv(provider string) string {
+	switch provider {
+	case genai.GEMINI:
+		return "GEMINI_API_KEY"
+	case genai.VLLM:
+		return "VLLM_API_KEY"
+	default:
+		return "OPENAI_API_KEY"
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/jbutlerdev/genai"
+	"github.com/jbutlerdev/genai/tools"
+)
+
+func runReplay(args []string) error {
+	var providerOpts providerFlags
+	var input, model, systemPrompt, toolNames string
+	var numCtx int
+	var send bool
+	flags := flag.NewFlagSet("replay", flag.ContinueOnError)
+	flags.StringVar(&input, "input", "", "transcript exported with Chat.Export")
+	flags.StringVar(&providerOpts.provider, "provider", "", "provider: openai, gemini, ollama or vllm, defaults to the transcript's")
+	flags.StringVar(&providerOpts.apiKey, "api-key", "", "API key, defaults to the provider's API key environment variable")
+	flags.StringVar(&providerOpts.baseURL, "base-url", "", "provider base URL")
+	flags.StringVar(&model, "model", "", "model, defaults to the transcript's")
+	flags.StringVar(&systemPrompt, "system-prompt", "", "system prompt of the chat, used when the transcript has none")
+	flags.StringVar(&toolNames, "tools", "", "comma separated names of the registered tools the chat used")
+	flags.IntVar(&numCtx, "num-ctx", 0, "context size in tokens the history is fitted to, the library default when zero")
+	flags.BoolVar(&send, "send", false, "send each request and show the provider's response next to the recorded one")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if input == "" {
+		return fmt.Errorf("-input is required")
+	}
+	data, err := os.ReadFile(input)
+	if err != nil {
+		return err
+	}
+	transcript, err := genai.ParseTranscript(data)
+	if err != nil {
+		return err
+	}
+	if providerOpts.provider == "" {
+		providerOpts.provider = transcript.Provider
+	}
+	provider, err := providerOpts.newProvider(genai.ProviderOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to create provider: %w", err)
+	}
+	modelOptions := genai.ModelOptions{ModelName: model, SystemPrompt: systemPrompt}
+	if numCtx > 0 {
+		modelOptions.Parameters = map[string]any{genai.NumCtx: numCtx}
+	}
+	var replayOpts genai.ReplayOptions
+	if toolNames != "" {
+		replayOpts.Tools, err = tools.GetTools(strings.Split(toolNames, ","))
+		if err != nil {
+			return err
+		}
+	}
+	replayOpts.Send = send
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	for step, err := range provider.Replay(ctx, modelOptions, transcript, replayOpts) {
+		fmt.Printf("=== turn %d, message %d", step.Turn, step.Index)
+		if step.Compacted {
+			fmt.Printf(", compacted to %d messages", len(step.Messages))
+		}
+		fmt.Println()
+		if len(step.Request) > 0 {
+			var request bytes.Buffer
+			json.Indent(&request, step.Request, "", "  ")
+			fmt.Printf("--- request\n%s\n", request.String())
+		}
+		fmt.Printf("--- recorded\n%s\n", describeMessage(step.Recorded))
+		if step.Response != nil {
+			fmt.Printf("--- response\n%s\n", describeMessage(*step.Response))
+		}
+		if err != nil {
+			fmt.Printf("--- error\n%v\n", err)
+		}
+		fmt.Println()
+	}
+	return ctx.Err()
+}
+
+// describeMessage shows the text and tool calls of a response
+func describeMessage(msg genai.Message) string {
+	var sb strings.Builder
+	sb.WriteString(msg.Text())
+	for _, call := range msg.ToolCalls() {
+		arguments, _ := json.Marshal(call.Arguments)
+		fmt.Fprintf(&sb, "\ntool call %s %s(%s)", call.ID, call.Name, arguments)
+	}
+	return sb.String()
+}
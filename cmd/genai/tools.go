@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/jbutlerdev/genai/tools"
+)
+
+func runTools(args []string) error {
+	var format, output, toolNames, addr string
+	flags := flag.NewFlagSet("tools", flag.ContinueOnError)
+	flags.StringVar(&format, "format", "markdown", "format of the docs: markdown or json")
+	flags.StringVar(&output, "output", "-", "file for the docs, - for stdout")
+	flags.StringVar(&toolNames, "tools", "", "comma separated names of the tools to document, all registered tools when empty")
+	flags.StringVar(&addr, "serve", "", "serve the docs on this address, e.g. :8080, at /tools instead of writing them")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if addr != "" {
+		return serveToolDocs(addr)
+	}
+	var names []string
+	if toolNames != "" {
+		names = strings.Split(toolNames, ",")
+	}
+	docs, err := tools.Docs(names...)
+	if err != nil {
+		return err
+	}
+	var write func(io.Writer) error
+	switch format {
+	case "markdown":
+		write = func(w io.Writer) error { return tools.WriteDocsMarkdown(w, docs) }
+	case "json":
+		write = func(w io.Writer) error {
+			enc := json.NewEncoder(w)
+			enc.SetIndent("", "  ")
+			return enc.Encode(docs)
+		}
+	default:
+		return fmt.Errorf("unknown format %q, use markdown or json", format)
+	}
+	if err := writeCSV(output, write); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "documented %d tools\n", len(docs))
+	return nil
+}
+
+// serveToolDocs serves tools.DocsHandler at /tools until interrupted
+func serveToolDocs(addr string) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	mux := http.NewServeMux()
+	mux.Handle("/tools", tools.DocsHandler())
+	srv := &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdown)
+	}()
+	fmt.Fprintf(os.Stderr, "serving tool docs on %s/tools\n", addr)
+	if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package genai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jbutlerdev/genai/tools"
+)
+
+// Complete runs one turn of a conversation without channels. The last message must be
+// from the user, the messages before it are the history. Tool calls are run until the
+// model answers, up to MaxTurns, and the answer is returned with the usage of the turn.
+func (p *Provider) Complete(ctx context.Context, modelOptions ModelOptions, messages []Message, toolsToUse []*tools.Tool) (Message, Usage, error) {
+	if len(messages) == 0 {
+		return Message{}, Usage{}, errors.New("no messages to complete")
+	}
+	if err := ValidateHistory(messages); err != nil {
+		return Message{}, Usage{}, err
+	}
+	last := messages[len(messages)-1]
+	if last.Role != RoleUser {
+		return Message{}, Usage{}, fmt.Errorf("the last message must be from the user, got %s", last.Role)
+	}
+	modelOptions.History = messages[:len(messages)-1]
+
+	chat := p.newChat(modelOptions, toolsToUse, true)
+	done := make(chan struct{})
+	defer close(done)
+	defer close(chat.Done)
+	if chat.Reasoning != nil {
+		// the reasoning is part of the returned message
+		go func() {
+			for {
+				select {
+				case <-chat.Reasoning:
+				case <-done:
+					return
+				}
+			}
+		}()
+	}
+
+	if err := chat.SendMessageCtx(ctx, ChatMessage{Attachments: last.Parts}); err != nil {
+		return Message{}, Usage{}, err
+	}
+	var turnErr error
+	for event := range chat.Events {
+		switch e := event.(type) {
+		case ErrorEvent:
+			turnErr = e.Err
+		case TurnComplete:
+			usage := chat.Usage()
+			if e.Canceled {
+				return Message{}, usage, context.Cause(ctx)
+			}
+			if turnErr != nil {
+				return Message{}, usage, turnErr
+			}
+			history := chat.History()
+			answer := history[len(history)-1]
+			if answer.Role != RoleAssistant {
+				return Message{}, usage, errors.New("the model did not answer")
+			}
+			return answer, usage, nil
+		}
+	}
+	return Message{}, Usage{}, errors.New("chat ended before the turn completed")
+}
@@ -0,0 +1,238 @@
+package genai
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// DefaultSummaryWords caps the length of a Summarize summary
+const DefaultSummaryWords = 5000
+
+// ContextStrategy shrinks a conversation that no longer fits in the model's context
+// window. Fit is called before a request when the messages are larger than maxTokens,
+// which is the model's NumCtx parameter, and returns the messages to send instead.
+// The result replaces the chat's history once the turn completes.
+type ContextStrategy interface {
+	Fit(m *Model, messages []Message, maxTokens int) ([]Message, error)
+}
+
+// SlidingWindow keeps the system prompt, the few-shot examples and the most recent
+// Turns user turns. Older turns are dropped as with DropOldest when the window alone
+// is still too large.
+type SlidingWindow struct {
+	Turns int
+}
+
+// DropOldest drops whole user turns from the start of the conversation until it
+// fits. The system prompt, the few-shot examples and the current turn are kept.
+type DropOldest struct{}
+
+// Summarize replaces the conversation with a summary generated by the model. It is
+// the default strategy.
+type Summarize struct {
+	// Prompt replaces the summarization instructions, the conversation is appended to it.
+	// The model's SummaryPrompt is used when empty.
+	Prompt string
+	// Words caps the summary, the smaller of DefaultSummaryWords and a third of the
+	// context window when zero
+	Words int
+}
+
+// Hybrid keeps the RecentTurns most recent user turns verbatim and summarizes the turns
+// before them into a single message. Recent turns are dropped as with DropOldest when
+// the result is still too large.
+type Hybrid struct {
+	RecentTurns int
+	// Prompt replaces the summarization instructions, the model's SummaryPrompt is used
+	// when empty
+	Prompt string
+}
+
+// handleContextLength applies the model's context strategy when the messages are
+// larger than NumCtx
+func handleContextLength(m *Model, messages []Message) ([]Message, error) {
+	maxContext, ok := m.Parameters[NumCtx].(int)
+	if !ok {
+		return nil, errors.New("failed to parse num_ctx for model")
+	}
+	contextSize, err := m.contextSize(messages, maxContext)
+	if err != nil {
+		return nil, err
+	}
+	if contextSize <= maxContext {
+		return messages, nil
+	}
+	m.Logger.Info("context length is larger than NumCtx, applying context strategy", "length", strconv.Itoa(contextSize), "strategy", fmt.Sprintf("%T", m.contextStrategy()))
+	return m.contextStrategy().Fit(m, messages, maxContext)
+}
+
+func (m *Model) contextStrategy() ContextStrategy {
+	if m.ContextStrategy != nil {
+		return m.ContextStrategy
+	}
+	if m.packer != nil {
+		return m.packer
+	}
+	return Summarize{}
+}
+
+// contextSize counts the tokens of the messages. Every token is at least one byte, so
+// content no longer than limit is returned without calling the tokenizer.
+func (m *Model) contextSize(messages []Message, limit int) (int, error) {
+	content := messagesToString(messages, true)
+	if len(content) <= limit {
+		return len(content), nil
+	}
+	return m.Provider.CountTokens(m.ModelName, content)
+}
+
+// pinnedMessages returns the number of leading messages every strategy keeps: the
+// system prompt and the few-shot examples when the history starts with them
+func (m *Model) pinnedMessages(messages []Message) int {
+	pinned := 0
+	if len(messages) > 0 && messages[0].Role == RoleSystem {
+		pinned = 1
+	}
+	examples := exampleMessages(m.Examples)
+	if len(messages)-pinned <= len(examples) {
+		return pinned
+	}
+	for i, example := range examples {
+		msg := messages[pinned+i]
+		if msg.Role != example.Role || msg.Text() != example.Text() {
+			return pinned
+		}
+	}
+	return pinned + len(examples)
+}
+
+func (s SlidingWindow) Fit(m *Model, messages []Message, maxTokens int) ([]Message, error) {
+	turns := s.Turns
+	if turns <= 0 {
+		turns = DefaultPackRecentTurns
+	}
+	pinned := m.pinnedMessages(messages)
+	topics := splitTopics(messages[pinned:])
+	if len(topics) > turns {
+		windowed := append([]Message{}, messages[:pinned]...)
+		for _, topic := range topics[len(topics)-turns:] {
+			windowed = append(windowed, topic...)
+		}
+		messages = windowed
+	}
+	return dropOldest(m, messages, pinned, maxTokens)
+}
+
+func (DropOldest) Fit(m *Model, messages []Message, maxTokens int) ([]Message, error) {
+	return dropOldest(m, messages, m.pinnedMessages(messages), maxTokens)
+}
+
+// dropOldest drops the oldest turns after the first pinned messages until the
+// conversation fits
+func dropOldest(m *Model, messages []Message, pinned int, maxTokens int) ([]Message, error) {
+	topics := splitTopics(messages[pinned:])
+	for {
+		size, err := m.contextSize(messages, maxTokens)
+		if err != nil {
+			return nil, err
+		}
+		if size <= maxTokens {
+			return messages, nil
+		}
+		if len(topics) <= 1 {
+			return nil, fmt.Errorf("the current turn does not fit in the context window of %d tokens: %w", maxTokens, ErrContextLengthExceeded)
+		}
+		// estimate how many turns to drop from their share of the content, the
+		// conversation is counted again afterwards since the estimate can be short
+		total := len(messagesToString(messages, true))
+		excess := total - total*maxTokens/size
+		drop := 1
+		for dropped := len(messagesToString(topics[0], true)); dropped < excess && drop < len(topics)-1; drop++ {
+			dropped += len(messagesToString(topics[drop], true))
+		}
+		topics = topics[drop:]
+		kept := append([]Message{}, messages[:pinned]...)
+		for _, topic := range topics {
+			kept = append(kept, topic...)
+		}
+		messages = kept
+	}
+}
+
+func (s Summarize) Fit(m *Model, messages []Message, maxTokens int) ([]Message, error) {
+	prompt := s.Prompt
+	if prompt == "" {
+		prompt = m.utility().prompt
+	}
+	if prompt == "" {
+		words := s.Words
+		if words <= 0 {
+			words = summaryWords(maxTokens / 3)
+		}
+		prompt = fmt.Sprintf("Compact this conversation into %d words or less. Do not include any word counts or summarizing. Just return the summarized content.\n", words)
+	}
+	if len(messages) == 0 {
+		return messages, nil
+	}
+	response, err := m.summarize(prompt + messagesToString(messages, false))
+	if err != nil {
+		return nil, err
+	}
+	responseMessages := []Message{messages[0]}
+	if messages[0].Role == RoleSystem && len(messages) > 1 {
+		responseMessages = append(responseMessages, messages[1])
+	}
+	responseMessages = append(responseMessages, NewTextMessage(RoleUser, response))
+	return responseMessages, nil
+}
+
+// summaryWords caps words at DefaultSummaryWords
+func summaryWords(words int) int {
+	if words > DefaultSummaryWords {
+		return DefaultSummaryWords
+	}
+	return words
+}
+
+// compact summarizes the conversation with the default Summarize strategy
+func compact(m *Model, messages []Message) ([]Message, error) {
+	return Summarize{}.Fit(m, messages, m.Parameters[NumCtx].(int))
+}
+
+func (h Hybrid) Fit(m *Model, messages []Message, maxTokens int) ([]Message, error) {
+	recentTurns := h.RecentTurns
+	if recentTurns <= 0 {
+		recentTurns = DefaultPackRecentTurns
+	}
+	pinned := m.pinnedMessages(messages)
+	topics := splitTopics(messages[pinned:])
+	if len(topics) <= recentTurns {
+		return dropOldest(m, messages, pinned, maxTokens)
+	}
+	var older []Message
+	for _, topic := range topics[:len(topics)-recentTurns] {
+		older = append(older, topic...)
+	}
+	prompt := h.Prompt
+	if prompt == "" {
+		prompt = m.utility().prompt
+	}
+	if prompt == "" {
+		prompt = fmt.Sprintf("Summarize this earlier part of a conversation in %d words or less. Keep names, file paths, decisions and open questions. Just return the summarized content.\n", summaryWords(maxTokens/4))
+	}
+	summary, err := m.summarize(prompt + messagesToString(older, false))
+	if err != nil {
+		return nil, fmt.Errorf("failed to summarize conversation: %w", err)
+	}
+	fitted := append([]Message{}, messages[:pinned]...)
+	// the summary is pinned with the system prompt so it is never dropped
+	fitted = append(fitted,
+		NewTextMessage(RoleUser, "Summary of the earlier conversation:\n"+summary),
+		NewTextMessage(RoleAssistant, "Understood."),
+	)
+	for _, topic := range topics[len(topics)-recentTurns:] {
+		fitted = append(fitted, topic...)
+	}
+	return dropOldest(m, fitted, pinned+2, maxTokens)
+}
@@ -0,0 +1,77 @@
+package genai
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"strings"
+)
+
+// DraftOptions configure two stage generation: Model drafts the response and the
+// generating model verifies it. Approving a draft takes a few output tokens of the
+// stronger model, a draft that needs changes is rewritten by it.
+type DraftOptions struct {
+	// Model writes the draft, e.g. a small local model
+	Model string
+	// Provider runs Model, the generating provider when nil
+	Provider *Provider
+	// VerifyPrompt replaces DefaultVerifyPrompt, it must ask for draftApproved when the
+	// draft needs no changes
+	VerifyPrompt string
+}
+
+// draftApproved is the verifier's answer for a draft that is used as it is
+const draftApproved = "APPROVED"
+
+// DefaultVerifyPrompt is sent to the verifying model with the request and the draft
+const DefaultVerifyPrompt = `Another assistant drafted a response to the request above. Check the draft for mistakes, omissions and instructions it did not follow.
+If the draft is correct and complete, answer with exactly ` + draftApproved + ` and nothing else.
+Otherwise answer with the corrected response in full, without mentioning the draft.`
+
+// generateWithDraft drafts the response to msg with the draft model and has the model
+// verify it. The model answers on its own when the draft fails.
+func (m *Model) generateWithDraft(ctx context.Context, msg Message) (string, error) {
+	provider := m.Draft.Provider
+	if provider == nil {
+		provider = m.Provider
+	}
+	// the drafter gets the same instructions as the model
+	drafter := NewModel(provider, ModelOptions{
+		ModelName:    m.Draft.Model,
+		SystemPrompt: m.SystemPrompt,
+		Parameters:   maps.Clone(m.Parameters),
+		Examples:     m.Examples,
+		Timeouts:     m.Timeouts,
+		Reasoning:    ReasoningStrip,
+	}, m.Logger.WithName("draft"))
+	draft, err := drafter.generateOnce(ctx, msg)
+	usage := drafter.Usage()
+	if usage.responses() {
+		m.addUsage(drafter.ModelName, usage)
+	}
+	if err != nil || strings.TrimSpace(draft) == "" {
+		if ctx.Err() != nil {
+			return "", err
+		}
+		m.Logger.Error(err, "Draft failed, generating without it")
+		return m.generateOnce(ctx, msg)
+	}
+
+	prompt := m.Draft.VerifyPrompt
+	if prompt == "" {
+		prompt = DefaultVerifyPrompt
+	}
+	verify := Message{Role: RoleUser, Parts: append([]Part(nil), msg.Parts...)}
+	verify.Parts = append(verify.Parts, Part{Type: TextPart, Text: fmt.Sprintf("\n\n<draft>\n%s\n</draft>\n\n%s", draft, prompt)})
+	verdict, err := m.generateOnce(ctx, verify)
+	if err != nil {
+		return "", err
+	}
+	if strings.Trim(strings.TrimSpace(verdict), ".") == draftApproved {
+		m.Logger.Info("Draft approved", "draftModel", drafter.ModelName)
+		m.setLogprobs(drafter.Logprobs())
+		return draft, nil
+	}
+	m.Logger.Info("Draft revised", "draftModel", drafter.ModelName)
+	return verdict, nil
+}
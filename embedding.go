@@ -2,6 +2,8 @@ package genai
 
 import (
 	"context"
+	"errors"
+	"fmt"
 )
 
 // EmbeddingProvider defines the interface for generating embeddings
@@ -11,4 +13,19 @@ type EmbeddingProvider interface {
 
 	// GenerateEmbeddings generates embeddings for multiple text inputs
 	GenerateEmbeddings(ctx context.Context, texts []string, model string) ([][]float32, error)
-}
\ No newline at end of file
+}
+// verifyEmbeddingModel probes EmbeddingModel with a short input so a model that
+// does not serve embeddings is reported by NewProvider instead of at first use
+func (p *Provider) verifyEmbeddingModel(ctx context.Context) error {
+	embedding, err := p.GenerateEmbedding(ctx, "ping", p.EmbeddingModel)
+	if err != nil {
+		if errors.Is(err, ErrAuth) || errors.Is(err, ErrRateLimited) || errors.Is(err, ErrUnavailable) {
+			return fmt.Errorf("failed to verify embedding model %s: %w", p.EmbeddingModel, err)
+		}
+		return fmt.Errorf("%w: %s on %s: %w", ErrEmbeddingUnsupported, p.EmbeddingModel, p.Provider, err)
+	}
+	if len(embedding) == 0 {
+		return fmt.Errorf("%w: %s on %s returned an empty embedding", ErrEmbeddingUnsupported, p.EmbeddingModel, p.Provider)
+	}
+	return nil
+}
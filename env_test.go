@@ -0,0 +1,134 @@
+package genai
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jbutlerdev/genai/tools"
+)
+
+func TestChatEnv(t *testing.T) {
+	var got []map[string]any
+	tools.RegisterTool(tools.Tool{
+		Name:        "mock_repos",
+		Description: "List the repositories of the organization",
+		Options:     map[string]string{"org": "${GITHUB_ORG}", "path": "${UNSET}/repos"},
+		Run: func(args map[string]any) (map[string]any, error) {
+			got = append(got, args)
+			return map[string]any{"repos": []string{}}, nil
+		},
+	})
+	t.Cleanup(func() { tools.UnregisterTool("mock_repos") })
+	tool, err := tools.GetTool("mock_repos")
+	if err != nil {
+		t.Fatal(err)
+	}
+	call := MockResponse{ToolCalls: []ToolCall{{Name: "mock_repos", Arguments: map[string]any{}}}}
+	p, err := NewMockProvider(call, MockResponse{Text: "None"}, call, MockResponse{Text: "None"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, org := range []string{"acme", "globex"} {
+		chat := p.ChatEvents(ModelOptions{ModelName: "mock"}, []*tools.Tool{tool})
+		chat.SetEnv("GITHUB_ORG", org)
+		chat.SetEnv("SEARCH_LOCALE", "en-US")
+		chat.SetEnv("SEARCH_LOCALE", "")
+		if _, err := mockTurn(t, chat, "Which repositories are there?"); err != nil {
+			t.Fatalf("turn failed: %v", err)
+		}
+		close(chat.Done)
+	}
+
+	if len(got) != 2 {
+		t.Fatalf("tool ran %d times, want 2", len(got))
+	}
+	for i, org := range []string{"acme", "globex"} {
+		if got[i]["org"] != org {
+			t.Errorf("chat %d: org %v, want %s", i+1, got[i]["org"], org)
+		}
+		if got[i]["path"] != "${UNSET}/repos" {
+			t.Errorf("chat %d: path %v, want the unset reference kept", i+1, got[i]["path"])
+		}
+		env, _ := got[i][tools.EnvArg].(map[string]string)
+		if len(env) != 1 || env["GITHUB_ORG"] != org {
+			t.Errorf("chat %d: env %v", i+1, env)
+		}
+	}
+}
+
+func TestSessionEnvCredentials(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"query":%q}`, r.URL.Query().Get("q"))
+	}))
+	defer srv.Close()
+	t.Setenv("SEARXNG_URL", "")
+	p, err := NewMockProvider()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the search tool reads its endpoint from the conversation instead of the process
+	session := Session{Env: map[string]string{"SEARXNG_URL": srv.URL}}
+	result, err := p.runTool("SearchWeb", map[string]any{"query": "gophers"}, session, p.utility())
+	if err != nil {
+		t.Fatalf("search failed: %v", err)
+	}
+	if results := result.(map[string]any)["results"].(map[string]any); results["query"] != "gophers" {
+		t.Errorf("results %v", results)
+	}
+	if _, err := p.runTool("SearchWeb", map[string]any{"query": "gophers"}, Session{}, p.utility()); err == nil {
+		t.Error("search without SEARXNG_URL succeeded")
+	}
+}
+
+func TestModelSessionArgsIgnored(t *testing.T) {
+	var got map[string]any
+	tools.RegisterTool(tools.Tool{
+		Name:        "mock_secrets",
+		Description: "Read a secret of the organization",
+		Run: func(args map[string]any) (map[string]any, error) {
+			got = args
+			return map[string]any{}, nil
+		},
+	})
+	t.Cleanup(func() { tools.UnregisterTool("mock_secrets") })
+	tool, err := tools.GetTool("mock_secrets")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a model that tries to act as another tenant in another environment
+	call := MockResponse{ToolCalls: []ToolCall{{Name: "mock_secrets", Arguments: map[string]any{
+		"name":                   "GITHUB_TOKEN",
+		tools.CredentialScopeArg: "globex",
+		tools.EnvArg:             map[string]any{"GITHUB_TOKEN": "forged"},
+		tools.ConversationIDArg:  "other",
+		tools.RequestIDArg:       "other",
+	}}}}
+	p, err := NewMockProvider(call, MockResponse{Text: "Done"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	chat := p.ChatEvents(ModelOptions{ModelName: "mock"}, []*tools.Tool{tool})
+	defer close(chat.Done)
+	chat.SetCredentialScope("acme")
+	if _, err := mockTurn(t, chat, "Read the token"); err != nil {
+		t.Fatalf("turn failed: %v", err)
+	}
+
+	if got["name"] != "GITHUB_TOKEN" {
+		t.Errorf("name %v, want the model's argument", got["name"])
+	}
+	if got[tools.CredentialScopeArg] != "acme" {
+		t.Errorf("scope %v, want the session's", got[tools.CredentialScopeArg])
+	}
+	for _, name := range []string{tools.EnvArg, tools.ConversationIDArg, tools.RequestIDArg} {
+		if value, ok := got[name]; ok && value == "other" {
+			t.Errorf("%s %v supplied by the model", name, value)
+		}
+	}
+	if _, ok := got[tools.EnvArg].(map[string]any); ok {
+		t.Errorf("env %v supplied by the model", got[tools.EnvArg])
+	}
+}
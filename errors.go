@@ -0,0 +1,95 @@
+package genai
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	ollama "github.com/ollama/ollama/api"
+	"github.com/openai/openai-go"
+	gemini "google.golang.org/genai"
+)
+
+// Provider failures are wrapped so callers can use errors.Is regardless of the provider
+var (
+	ErrRateLimited           = errors.New("rate limited")
+	ErrAuth                  = errors.New("authentication failed")
+	ErrContextLengthExceeded = errors.New("context length exceeded")
+	ErrContentFiltered       = errors.New("content filtered")
+	ErrModelNotFound         = errors.New("model not found")
+	ErrUnavailable           = errors.New("provider unavailable")
+	// ErrEmbeddingUnsupported is returned by NewProvider when EmbeddingModel does not serve embeddings
+	ErrEmbeddingUnsupported = errors.New("embedding model not supported")
+)
+
+// ProviderError is a classified provider failure. errors.Is matches both the
+// kind (e.g. ErrRateLimited) and the underlying SDK error.
+type ProviderError struct {
+	Provider string
+	Kind     error
+	Err      error
+}
+
+func (e *ProviderError) Error() string {
+	return fmt.Sprintf("%s: %v: %v", e.Provider, e.Kind, e.Err)
+}
+
+func (e *ProviderError) Unwrap() []error {
+	return []error{e.Kind, e.Err}
+}
+
+// wrapProviderError classifies an SDK error, errors that can not be classified are returned as-is
+func wrapProviderError(provider string, err error) error {
+	if err == nil {
+		return nil
+	}
+	var providerErr *ProviderError
+	if errors.As(err, &providerErr) {
+		return err
+	}
+	if kind := classifyError(err); kind != nil {
+		return &ProviderError{Provider: provider, Kind: kind, Err: err}
+	}
+	return err
+}
+
+func classifyError(err error) error {
+	message := strings.ToLower(err.Error())
+	switch {
+	case strings.Contains(message, "context_length_exceeded"),
+		strings.Contains(message, "maximum context length"),
+		strings.Contains(message, "context window"):
+		return ErrContextLengthExceeded
+	case strings.Contains(message, "content_filter"), strings.Contains(message, "content management policy"):
+		return ErrContentFiltered
+	}
+	switch statusCode(err) {
+	case http.StatusTooManyRequests:
+		return ErrRateLimited
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return ErrAuth
+	case http.StatusNotFound:
+		return ErrModelNotFound
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return ErrUnavailable
+	}
+	return nil
+}
+
+// statusCode extracts the HTTP status code from a provider SDK error, 0 if there is none
+func statusCode(err error) int {
+	var openaiErr *openai.Error
+	if errors.As(err, &openaiErr) {
+		return openaiErr.StatusCode
+	}
+	var ollamaErr ollama.StatusError
+	if errors.As(err, &ollamaErr) {
+		return ollamaErr.StatusCode
+	}
+	var geminiErr gemini.APIError
+	if errors.As(err, &geminiErr) {
+		return geminiErr.Code
+	}
+	return 0
+}
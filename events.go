@@ -0,0 +1,115 @@
+package genai
+
+import (
+	"time"
+)
+
+// Event is sent on Chat.Events while a chat generates a response. Use a type switch to
+// handle the events a UI is interested in, each turn ends with TurnComplete.
+type Event interface {
+	event()
+}
+
+// TextDelta is text of the response. Providers that do not stream send the whole
+// response in one delta.
+type TextDelta struct {
+	Text string
+}
+
+// ToolCallStarted is sent before a tool runs
+type ToolCallStarted struct {
+	Call ToolCall
+}
+
+// ToolCallFinished is sent with the result of a tool, Result.IsError is set when the
+// tool failed or was blocked
+type ToolCallFinished struct {
+	Call     ToolCall
+	Result   ToolResult
+	Duration time.Duration
+}
+
+// UsageEvent reports the tokens used by the turn and by the chat so far
+type UsageEvent struct {
+	Turn  TurnUsage
+	Total Usage
+}
+
+// ErrorEvent is sent when generating the response failed, the turn still completes
+type ErrorEvent struct {
+	Err error
+}
+
+// TurnComplete ends the response to a message. Text is the full response, empty when
+// the turn failed or was canceled. Duration is the time the turn took, including tool
+// calls. Logprobs are the tokens of the response when the Logprobs parameter is set,
+// see Model.Logprobs, and Grounding its sources when WebSearch is set, see
+// Model.Grounding. RequestID identifies the turn in logs, tool calls and the requests
+// sent to the provider.
+type TurnComplete struct {
+	Text      string
+	Canceled  bool
+	Duration  time.Duration
+	Logprobs  []TokenLogprob
+	Grounding *Grounding
+	RequestID string
+}
+
+// GuardrailEvent reports a guardrail that annotated, rewrote or blocked the prompt or
+// response of the turn, see ModelOptions.Guardrails
+type GuardrailEvent struct {
+	Guardrail   string
+	Stage       GuardrailStage
+	Action      GuardrailAction
+	Reason      string
+	Annotations map[string]string
+}
+
+func (TextDelta) event()        {}
+func (ToolCallStarted) event()  {}
+func (ToolCallFinished) event() {}
+func (UsageEvent) event()       {}
+func (ErrorEvent) event()       {}
+func (TurnComplete) event()     {}
+func (GuardrailEvent) event()   {}
+
+// emit sends an event to Events, or for chats created with Provider.Chat translates
+// it to the Recv, Errors and GenerationComplete channels
+func (c *Chat) emit(event Event) {
+	if delta, ok := event.(TextDelta); ok && c.model != nil {
+		delta.Text = c.model.postProcess(delta.Text)
+		event = delta
+	}
+	if c.Events != nil {
+		if delta, ok := event.(TextDelta); ok {
+			c.response.WriteString(delta.Text)
+		}
+		c.Events <- event
+		return
+	}
+	switch e := event.(type) {
+	case TextDelta:
+		c.Recv <- e.Text
+	case ErrorEvent:
+		select {
+		case c.Errors <- e.Err:
+		default:
+			c.Logger.Info("Dropped chat error, Errors is not drained", "error", e.Err.Error())
+		}
+	case TurnComplete:
+		if c.GenerationComplete != nil {
+			c.GenerationComplete <- true
+		}
+	}
+}
+
+// toolStarted reports a tool call and returns the time it started
+func (c *Chat) toolStarted(call ToolCall) time.Time {
+	c.emit(ToolCallStarted{Call: call})
+	return time.Now()
+}
+
+// toolFinished reports the result of a tool call started at start
+func (c *Chat) toolFinished(call ToolCall, result ToolResult, start time.Time) {
+	c.emit(ToolCallFinished{Call: call, Result: result, Duration: time.Since(start)})
+}
@@ -62,22 +62,8 @@ func main() {
 		}
 	}
 
-	fmt.Printf("Using database URL: [REDACTED - password hidden]\n")
-	// Hide password in logs for security
-	displayURL := databaseURL
-	if strings.Contains(displayURL, ":") && strings.Contains(displayURL, "@") {
-		parts := strings.SplitN(displayURL, ":", 3)
-		if len(parts) >= 3 {
-			userPart := parts[1][2:] // Skip "//"
-			if strings.Contains(userPart, ":") {
-				userPass := strings.SplitN(userPart, "@", 2)
-				if len(userPass) >= 2 {
-					displayURL = strings.Replace(displayURL, ":"+userPass[0]+"@", ":***@", 1)
-				}
-			}
-		}
-	}
-	fmt.Printf("Connection info: %s\n", displayURL)
+	// the password of the connection string is redacted
+	fmt.Printf("Connection info: %s\n", tools.DefaultLogPolicy.RedactString(databaseURL))
 	fmt.Printf("Using embedding provider: %s\n", embeddingProvider)
 	if configModel != "" {
 		fmt.Printf("Using embedding model from config: %s\n", configModel)
@@ -0,0 +1,107 @@
+package genai
+
+import (
+	"slices"
+
+	"github.com/openai/openai-go"
+	"github.com/openai/openai-go/packages/param"
+	gemini "google.golang.org/genai"
+)
+
+// FunctionCallingMode selects whether the model may, must or must not call tools
+type FunctionCallingMode string
+
+const (
+	// FunctionCallingAuto lets the model decide, the default
+	FunctionCallingAuto FunctionCallingMode = "auto"
+	// FunctionCallingAny makes the model call a tool before it answers. In a chat the
+	// requests following the tool results of a turn use FunctionCallingAuto, so the
+	// model can answer with their results.
+	FunctionCallingAny FunctionCallingMode = "any"
+	// FunctionCallingNone keeps the model from calling tools, it answers with text
+	FunctionCallingNone FunctionCallingMode = "none"
+)
+
+// FunctionCalling controls the tool calls of a model: Gemini's FunctionCallingConfig
+// and OpenAI's tool_choice. Ollama ignores it.
+type FunctionCalling struct {
+	Mode FunctionCallingMode
+	// AllowedFunctions limits the tools FunctionCallingAny may call, all tools when empty
+	AllowedFunctions []string
+}
+
+// functionCalling returns the function calling of the next request with messages, the
+// follow-up to tool results is not forced to call tools again
+func (m *Model) functionCalling(messages []Message) *FunctionCalling {
+	fc := m.FunctionCalling
+	if fc == nil || fc.Mode != FunctionCallingAny {
+		return fc
+	}
+	if len(messages) > 0 && messages[len(messages)-1].Role == RoleTool {
+		return &FunctionCalling{Mode: FunctionCallingAuto}
+	}
+	return fc
+}
+
+// geminiToolConfig converts function calling to Gemini's tool config
+func geminiToolConfig(fc *FunctionCalling) *gemini.ToolConfig {
+	if fc == nil || fc.Mode == "" {
+		return nil
+	}
+	config := &gemini.FunctionCallingConfig{}
+	switch fc.Mode {
+	case FunctionCallingAny:
+		config.Mode = gemini.FunctionCallingConfigModeAny
+		// Gemini only accepts allowed names with ANY
+		config.AllowedFunctionNames = fc.AllowedFunctions
+	case FunctionCallingNone:
+		config.Mode = gemini.FunctionCallingConfigModeNone
+	default:
+		config.Mode = gemini.FunctionCallingConfigModeAuto
+	}
+	return &gemini.ToolConfig{FunctionCallingConfig: config}
+}
+
+// geminiRequestConfig returns the model's config with the function calling of the
+// request with messages and the tools of the turn
+func (m *Model) geminiRequestConfig(messages []Message) *gemini.GenerateContentConfig {
+	fc := m.functionCalling(messages)
+	if (fc == m.FunctionCalling && m.turnTools == nil) || m.Gemini == nil {
+		return m.Gemini
+	}
+	config := *m.Gemini
+	config.ToolConfig = geminiToolConfig(fc)
+	if m.turnTools != nil {
+		config.Tools = m.selectedGeminiTools(config.Tools)
+	}
+	return &config
+}
+
+// applyOpenAIFunctionCalling sets the tool_choice of params. FunctionCallingAny with a
+// single allowed function names it, with several the other tools are left out.
+func applyOpenAIFunctionCalling(params *openai.ChatCompletionNewParams, fc *FunctionCalling) {
+	if fc == nil || fc.Mode == "" || len(params.Tools) == 0 {
+		return
+	}
+	switch fc.Mode {
+	case FunctionCallingNone:
+		params.ToolChoice = openai.ChatCompletionToolChoiceOptionUnionParam{OfAuto: param.NewOpt("none")}
+	case FunctionCallingAny:
+		if len(fc.AllowedFunctions) == 1 {
+			params.ToolChoice = openai.ChatCompletionToolChoiceOptionUnionParam{
+				OfChatCompletionNamedToolChoice: &openai.ChatCompletionNamedToolChoiceParam{
+					Function: openai.ChatCompletionNamedToolChoiceFunctionParam{Name: fc.AllowedFunctions[0]},
+				},
+			}
+			return
+		}
+		if len(fc.AllowedFunctions) > 0 {
+			params.Tools = slices.DeleteFunc(params.Tools, func(tool openai.ChatCompletionToolParam) bool {
+				return !slices.Contains(fc.AllowedFunctions, tool.Function.Name)
+			})
+		}
+		params.ToolChoice = openai.ChatCompletionToolChoiceOptionUnionParam{OfAuto: param.NewOpt("required")}
+	default:
+		params.ToolChoice = openai.ChatCompletionToolChoiceOptionUnionParam{OfAuto: param.NewOpt("auto")}
+	}
+}
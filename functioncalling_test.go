@@ -0,0 +1,105 @@
+package genai
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/jbutlerdev/genai/tools"
+)
+
+func TestFunctionCallingGemini(t *testing.T) {
+	tool, runs := registerWeatherTool(t)
+	srv := newTestServer(t,
+		`{"candidates":[{"content":{"role":"model","parts":[{"functionCall":{"name":"mock_weather","args":{"city":"Paris"}}}]},"finishReason":"STOP"}]}`,
+		geminiResponseBody,
+	)
+	p, err := NewProvider(GEMINI, ProviderOptions{APIKey: "test", BaseURL: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Log = logr.Discard()
+	p.Retry = RetryPolicy{MaxAttempts: 1}
+	chat := p.ChatEvents(ModelOptions{
+		ModelName:       "test",
+		FunctionCalling: &FunctionCalling{Mode: FunctionCallingAny, AllowedFunctions: []string{"mock_weather"}},
+	}, []*tools.Tool{tool})
+	defer close(chat.Done)
+	if _, err := mockTurn(t, chat, "Weather in Paris?"); err != nil {
+		t.Fatalf("turn failed: %v", err)
+	}
+	if runs.Load() != 1 {
+		t.Errorf("tool ran %d times, want 1", runs.Load())
+	}
+
+	chat.SetFunctionCalling(&FunctionCalling{Mode: FunctionCallingNone})
+	if _, err := mockTurn(t, chat, "Thanks"); err != nil {
+		t.Fatalf("turn failed: %v", err)
+	}
+	requests := srv.bodies()
+	if len(requests) != 3 {
+		t.Fatalf("%d requests, want 3", len(requests))
+	}
+	want := []string{
+		`{"functionCallingConfig":{"allowedFunctionNames":["mock_weather"],"mode":"ANY"}}`,
+		// the model answers with the result of the forced call
+		`{"functionCallingConfig":{"mode":"AUTO"}}`,
+		`{"functionCallingConfig":{"mode":"NONE"}}`,
+	}
+	for i, req := range requests {
+		got, _ := json.Marshal(req["toolConfig"])
+		if string(got) != want[i] {
+			t.Errorf("request %d tool config %s, want %s", i+1, got, want[i])
+		}
+	}
+}
+
+func TestFunctionCallingOpenAI(t *testing.T) {
+	tool, _ := registerWeatherTool(t)
+	tools.RegisterTool(tools.Tool{Name: "mock_time", Description: "Get the time", Run: func(map[string]any) (map[string]any, error) {
+		return map[string]any{"time": "noon"}, nil
+	}})
+	t.Cleanup(func() { tools.UnregisterTool("mock_time") })
+	timeTool, err := tools.GetTool("mock_time")
+	if err != nil {
+		t.Fatal(err)
+	}
+	toolset := []*tools.Tool{tool, timeTool}
+
+	for _, tc := range []struct {
+		name    string
+		fc      *FunctionCalling
+		choices []string
+		tools   []string
+	}{
+		{"default", nil, []string{"", ""}, []string{"mock_weather", "mock_time"}},
+		{"named", &FunctionCalling{Mode: FunctionCallingAny, AllowedFunctions: []string{"mock_weather"}}, []string{"mock_weather", "auto"}, []string{"mock_weather", "mock_time"}},
+		{"required", &FunctionCalling{Mode: FunctionCallingAny}, []string{"required", "auto"}, []string{"mock_weather", "mock_time"}},
+		{"allowed", &FunctionCalling{Mode: FunctionCallingAny, AllowedFunctions: []string{"mock_weather", "other"}}, []string{"required", "auto"}, []string{"mock_weather"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			p, err := NewMockProvider(weatherCall("Paris"), MockResponse{Text: "Sunny"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			chat := p.ChatEvents(ModelOptions{ModelName: "mock", FunctionCalling: tc.fc}, toolset)
+			defer close(chat.Done)
+			if _, err := mockTurn(t, chat, "Weather in Paris?"); err != nil {
+				t.Fatalf("turn failed: %v", err)
+			}
+			requests := p.Requests()
+			if len(requests) != 2 {
+				t.Fatalf("%d requests, want 2", len(requests))
+			}
+			for i, req := range requests {
+				if req.ToolChoice != tc.choices[i] {
+					t.Errorf("request %d tool choice %q, want %q", i+1, req.ToolChoice, tc.choices[i])
+				}
+			}
+			if !slices.Equal(requests[0].Tools, tc.tools) {
+				t.Errorf("tools %v, want %v", requests[0].Tools, tc.tools)
+			}
+		})
+	}
+}
@@ -2,112 +2,319 @@ package genai
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"strconv"
 	"strings"
 	"time"
 
-	gemini "github.com/google/generative-ai-go/genai"
+	gemini "google.golang.org/genai"
 )
 
+// defaults for DefaultRetryPolicy
 const (
 	RETRY_COUNT     = 8
 	MAX_RETRY_DELAY = 30 * time.Second
 )
 
-type retryableGeminiCallInput struct {
-	ctx     context.Context
-	model   *Model
-	part    gemini.Part
-	session *gemini.ChatSession
+// geminiConfig builds the request configuration shared by every call of a model
+func geminiConfig(modelOptions ModelOptions) *gemini.GenerateContentConfig {
+	config := &gemini.GenerateContentConfig{}
+	systemPrompt := modelOptions.SystemPrompt
+	// a resumed conversation keeps its own system prompt when none is configured
+	if systemPrompt == "" && len(modelOptions.History) > 0 && modelOptions.History[0].Role == RoleSystem {
+		systemPrompt = modelOptions.History[0].Text()
+	}
+	if systemPrompt != "" {
+		config.SystemInstruction = gemini.NewContentFromText(systemPrompt, gemini.RoleUser)
+	}
+	params := modelOptions.Parameters
+	for k, v := range params {
+		switch k {
+		case Temperature:
+			if f, ok := toFloat(v); ok {
+				config.Temperature = gemini.Ptr(float32(f))
+			}
+		case TopP:
+			if f, ok := toFloat(v); ok {
+				config.TopP = gemini.Ptr(float32(f))
+			}
+		case TopK:
+			if f, ok := toFloat(v); ok {
+				config.TopK = gemini.Ptr(float32(f))
+			}
+		case Seed:
+			if seed, ok := toInt(v); ok {
+				config.Seed = gemini.Ptr(int32(seed))
+			}
+		case NumPredict:
+			if n, ok := toInt(v); ok {
+				config.MaxOutputTokens = int32(n)
+			}
+		case Stop:
+			config.StopSequences = stopSequences(v)
+		case ReasoningEffort:
+			// an explicit budget takes precedence over the effort
+			if _, ok := params[ThinkingBudget]; ok {
+				continue
+			}
+			if effort, ok := v.(string); ok {
+				config.ThinkingConfig = &gemini.ThinkingConfig{ThinkingBudget: gemini.Ptr(effortToBudget(effort))}
+			}
+		case ThinkingBudget:
+			if budget, ok := toInt(v); ok {
+				config.ThinkingConfig = &gemini.ThinkingConfig{ThinkingBudget: gemini.Ptr(int32(budget))}
+			}
+		}
+	}
+	config.ToolConfig = geminiToolConfig(modelOptions.FunctionCalling)
+	config.SafetySettings = geminiSafetySettings(modelOptions.SafetySettings)
+	if tool := geminiSearchTool(modelOptions.WebSearch); tool != nil {
+		config.Tools = append(config.Tools, tool)
+	}
+	if modelOptions.Reasoning == ReasoningCapture {
+		if config.ThinkingConfig == nil {
+			config.ThinkingConfig = &gemini.ThinkingConfig{}
+		}
+		config.ThinkingConfig.IncludeThoughts = true
+	}
+	return config
+}
+
+// effortToBudget maps a reasoning effort to a thinking token budget
+func effortToBudget(effort string) int32 {
+	switch effort {
+	case "low":
+		return 1024
+	case "medium":
+		return 8192
+	default:
+		return 24576
+	}
 }
 
-func retryableGeminiCall(input *retryableGeminiCallInput, attempt int, delay time.Duration) (*gemini.GenerateContentResponse, error) {
-	if attempt > RETRY_COUNT {
-		return nil, fmt.Errorf("failed to get response after %d attempts", RETRY_COUNT)
+func toFloat(v any) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
 	}
-	var resp *gemini.GenerateContentResponse
-	var err error
-	if input.session == nil {
-		resp, err = input.model.Gemini.GenerateContent(input.ctx, input.part)
-	} else {
-		resp, err = input.session.SendMessage(input.ctx, input.part)
+	return 0, false
+}
+
+// geminiGenerate sends the messages to the model, retrying according to the provider's retry policy
+func geminiGenerate(ctx context.Context, m *Model, model string, messages []Message) (*gemini.GenerateContentResponse, error) {
+	contents := toGeminiContents(messages)
+	config := m.geminiRequestConfig(messages)
+	request := geminiRequest{Model: model, Contents: contents, Config: config}
+	done, err := m.startRequest(ctx, model, messages)
+	if err != nil {
+		return nil, err
 	}
+	resp, hit, err := cached(ctx, m.Provider.cache, m.Logger, GEMINI, request, m.Parameters, func() (*gemini.GenerateContentResponse, error) {
+		return retry(ctx, m.Provider.Retry, m.Logger, GEMINI, m.Provider.observer(), func() (*gemini.GenerateContentResponse, error) {
+			return balanced(m.Provider, func(client *Client) (*gemini.GenerateContentResponse, error) {
+				resp, err := client.Gemini.Models.GenerateContent(ctx, model, contents, config)
+				if err != nil {
+					return nil, err
+				}
+				return resp, geminiBlocked(resp)
+			})
+		})
+	})
 	if err != nil {
-		if strings.Contains(err.Error(), "429") || strings.Contains(err.Error(), "503") || strings.Contains(err.Error(), "400") {
-			input.model.Logger.Error(err, "Retryable error", "delay", delay, "attempt", attempt)
-			// rate limit exceeded, wait and retry
-			time.Sleep(delay)
-			delay = min(delay*2, MAX_RETRY_DELAY)
-			return retryableGeminiCall(input, attempt+1, delay)
-		}
-		// non-retryable error
-		return nil, fmt.Errorf("failed to get response: %v", err)
+		done(Message{}, err)
+		return nil, fmt.Errorf("failed to get response: %w", err)
+	}
+	var response Message
+	if len(resp.Candidates) > 0 && resp.Candidates[0].Content != nil {
+		response = fromGeminiContent(resp.Candidates[0].Content)
+	}
+	done(response, nil)
+	if hit {
+		m.recordUsage(model, cachedUsage())
+		return resp, nil
+	}
+	if resp.UsageMetadata != nil {
+		m.Logger.Info("total_token_count", "content", strconv.Itoa(int(resp.UsageMetadata.TotalTokenCount)))
 	}
+	m.recordUsage(model, geminiUsage(resp.UsageMetadata))
 	return resp, nil
 }
 
-func handleGeminiResponse(m *Model, chat *Chat, resp *gemini.GenerateContentResponse) error {
-	m.Logger.Info("total_token_count", "content", strconv.Itoa(int(resp.UsageMetadata.TotalTokenCount)))
-	for _, cand := range resp.Candidates {
-		if cand.Content != nil {
-			for _, part := range cand.Content.Parts {
-				switch p := part.(type) {
-				case gemini.FunctionCall:
-					m.Logger.Info("Handling function call", "name", p.Name, "content", fmt.Sprintf("%v", part))
-					resp, err := handleGeminiFunctionCall(m, &p)
-					if err != nil {
-						m.Logger.Error(err, "failed to handle function call")
-					}
-					if resp == nil {
-						return nil
-					}
-					input := &retryableGeminiCallInput{
-						ctx:     chat.ctx,
-						model:   m,
-						session: m.geminiSession,
-						part:    resp,
-					}
-					m.Logger.Info("Sending function call output", "name", p.Name, "content", fmt.Sprintf("%v", input.part))
-					mresp, err := retryableGeminiCall(input, 0, 1*time.Second)
-					if err != nil {
-						return fmt.Errorf("failed to send message: %v", err)
-					}
-					handleGeminiResponse(m, chat, mresp)
-				case gemini.Text:
-					m.Logger.Info("Handling text", "content", fmt.Sprintf("%v", part))
-					chat.Recv <- fmt.Sprintf("%v", part)
-				default:
-					return fmt.Errorf("unexpected part: %v", part)
+// geminiStream streams the response to the messages to send, the usage of the last chunk
+// covers the whole response
+func geminiStream(ctx context.Context, m *Model, model string, messages []Message, send func(string) bool) error {
+	contents := toGeminiContents(messages)
+	done, err := m.startRequest(ctx, model, messages)
+	if err != nil {
+		return err
+	}
+	send, text := collectResponse(send)
+	var metadata *gemini.GenerateContentResponseUsageMetadata
+	err = streamWithRetry(ctx, m.Provider.Retry, m.Logger, GEMINI, m.Provider.observer(), send, func(send func(string) bool) error {
+		_, err := balanced(m.Provider, func(client *Client) (struct{}, error) {
+			for resp, err := range client.Gemini.Models.GenerateContentStream(ctx, model, contents, m.geminiRequestConfig(messages)) {
+				if err != nil {
+					return struct{}{}, err
+				}
+				if err := geminiBlocked(resp); err != nil {
+					return struct{}{}, err
+				}
+				if resp.UsageMetadata != nil {
+					metadata = resp.UsageMetadata
+				}
+				if text, _ := geminiText(resp); !send(text) {
+					return struct{}{}, errStreamStopped
 				}
 			}
+			return struct{}{}, nil
+		})
+		return err
+	})
+	done(NewTextMessage(RoleAssistant, text.String()), err)
+	m.recordUsage(model, geminiUsage(metadata))
+	return err
+}
+
+func geminiChat(ctx context.Context, m *Model, chat *Chat) error {
+	if len(m.History()) == 0 {
+		m.setHistory(m.initialHistory())
+	}
+	for {
+		msg, turnContext, ok := chat.receive(ctx, m)
+		if !ok {
+			return nil
+		}
+		m.Logger.Info("Sending message", "content", msg.Text())
+		err := chat.runTurn(turnContext, m, msg, func(ctx context.Context) error {
+			return handleGeminiResponse(ctx, m, chat, m.History())
+		})
+		if err != nil {
+			m.Logger.Error(err, "Failed to handle response")
 		}
 	}
-	return nil
 }
 
-func handleGeminiFunctionCall(m *Model, f *gemini.FunctionCall) (gemini.Part, error) {
-	resp, err := m.Provider.RunTool(f.Name, f.Args)
+// handleGeminiResponse sends the messages and runs requested function calls until
+// the model answers with text
+func handleGeminiResponse(ctx context.Context, m *Model, chat *Chat, messages []Message) error {
+	for {
+		fitted, err := handleContextLength(m, messages)
+		if err != nil {
+			return err
+		}
+		messages = fitted
+		turnContext, cancel := context.WithTimeout(ctx, m.timeouts().Chat)
+		resp, err := geminiGenerate(turnContext, m, m.routedModel(messages), messages)
+		cancel()
+		if err != nil {
+			return fmt.Errorf("failed to send message: %w", err)
+		}
+		if len(resp.Candidates) == 0 || resp.Candidates[0].Content == nil {
+			return fmt.Errorf("no response candidates returned")
+		}
+		messages = append(messages, fromGeminiContent(resp.Candidates[0].Content))
+
+		calls := resp.FunctionCalls()
+		if len(calls) == 0 {
+			text, thoughts := geminiText(resp)
+			m.Logger.Info("Handling text", "content", text)
+			m.setGrounding(geminiGrounding(resp))
+			if revised := m.reflect(ctx, messages, text); revised != text {
+				text = revised
+				messages[len(messages)-1] = NewTextMessage(RoleAssistant, revised)
+			}
+			guarded, err := m.guardResponse(ctx, chat, text)
+			if err != nil {
+				return err
+			}
+			if guarded != text {
+				text = guarded
+				messages[len(messages)-1] = NewTextMessage(RoleAssistant, guarded)
+			}
+			m.setHistory(messages)
+			if thoughts != "" && chat.Reasoning != nil {
+				chat.Reasoning <- thoughts
+			}
+			chat.emit(TextDelta{Text: text})
+			return nil
+		}
+		results := Message{Role: RoleTool}
+		seen := make(map[string]ToolResult)
+		for _, call := range calls {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			toolCall := ToolCall{ID: call.ID, Name: call.Name, Arguments: call.Args}
+			// the key is taken before the run, running adds the tool options to the arguments
+			key := toolCallKey(toolCall)
+			if result, ok := seen[key]; ok {
+				// each function call gets a response, a repeated call the one of the first
+				m.Logger.Info("Skipping duplicate tool call", "tool", call.Name)
+				result.ID = call.ID
+				results.Parts = append(results.Parts, Part{Type: ToolResultPart, ToolResult: &result})
+				continue
+			}
+			result, looped, err := m.toolLoop(chat, toolCall)
+			if err != nil {
+				return err
+			}
+			m.Logger.Info("Handling function call", "name", call.Name, "content", fmt.Sprintf("%v", call.Args))
+			started := chat.toolStarted(toolCall)
+			if !looped {
+				result = handleGeminiFunctionCall(m, call)
+			}
+			chat.toolFinished(toolCall, result, started)
+			seen[key] = result
+			m.Logger.Info("Sending function call output", "name", call.Name, "content", result.Content)
+			results.Parts = append(results.Parts, Part{Type: ToolResultPart, ToolResult: &result})
+		}
+		messages = append(messages, results)
+	}
+}
+
+func handleGeminiFunctionCall(m *Model, f *gemini.FunctionCall) ToolResult {
+	if blocked, ok := m.toolBlocked(f.ID, f.Name); ok {
+		return blocked
+	}
+	resp, err := m.runTool(f.Name, f.Args)
 	if err != nil {
 		m.Logger.Error(err, "failed to run tool")
 	}
-	part, ok := resp.(gemini.FunctionResponse)
-	if !ok {
-		return nil, fmt.Errorf("unexpected response type: %v", resp)
+	var response map[string]any
+	switch r := resp.(type) {
+	case gemini.FunctionResponse:
+		response = r.Response
+	case map[string]any:
+		response = r
+	default:
+		response = map[string]any{"content": fmt.Sprintf("%v", resp)}
 	}
-	return part, nil
+	content, marshalErr := json.Marshal(response)
+	if marshalErr != nil {
+		content = []byte(fmt.Sprintf("%v", response))
+	}
+	return m.toolResult(f.ID, f.Name, string(content), err)
 }
 
-func handleGeminiText(resp *gemini.GenerateContentResponse) string {
-	var text string
-	for _, cand := range resp.Candidates {
-		if cand.Content != nil {
-			for _, part := range cand.Content.Parts {
-				text += fmt.Sprintf("%v", part)
-			}
+// geminiText returns the answer and the thought summaries of the first candidate
+func geminiText(resp *gemini.GenerateContentResponse) (text string, thoughts string) {
+	if len(resp.Candidates) == 0 || resp.Candidates[0].Content == nil {
+		return "", ""
+	}
+	var sb, thoughtsSb strings.Builder
+	for _, part := range resp.Candidates[0].Content.Parts {
+		if part.Thought {
+			thoughtsSb.WriteString(part.Text)
+			continue
 		}
+		sb.WriteString(part.Text)
 	}
-	return text
+	return sb.String(), thoughtsSb.String()
 }
 
 func min(a, b time.Duration) time.Duration {
@@ -119,24 +326,11 @@ func min(a, b time.Duration) time.Duration {
 
 // GenerateEmbedding generates an embedding for a single text input using Google's Gemini embedding API
 func geminiGenerateEmbedding(ctx context.Context, client *gemini.Client, text string, model string) ([]float32, error) {
-	// Use gemini-embedding-001 as the default model if none provided
-	if model == "" {
-		model = "gemini-embedding-001"
-	}
-	em := client.EmbeddingModel(model)
-
-	resp, err := em.EmbedContent(ctx, gemini.Text(text))
+	embeddings, err := geminiGenerateEmbeddings(ctx, client, []string{text}, model)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create embedding: %w", err)
-	}
-
-	// Convert []float64 to []float32
-	embedding := make([]float32, len(resp.Embedding.Values))
-	for i, v := range resp.Embedding.Values {
-		embedding[i] = float32(v)
+		return nil, err
 	}
-
-	return embedding, nil
+	return embeddings[0], nil
 }
 
 // GenerateEmbeddings generates embeddings for multiple text inputs using Google's Gemini embedding API
@@ -145,26 +339,22 @@ func geminiGenerateEmbeddings(ctx context.Context, client *gemini.Client, texts
 	if model == "" {
 		model = "gemini-embedding-001"
 	}
-	em := client.EmbeddingModel(model)
-
-	// Create a batch
-	batch := em.NewBatch()
-	for _, text := range texts {
-		batch.AddContent(gemini.Text(text))
+	contents := make([]*gemini.Content, len(texts))
+	for i, text := range texts {
+		contents[i] = gemini.NewContentFromText(text, gemini.RoleUser)
 	}
 
-	resp, err := em.BatchEmbedContents(ctx, batch)
+	resp, err := client.Models.EmbedContent(ctx, model, contents, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create embeddings: %w", err)
+		return nil, fmt.Errorf("failed to create embeddings: %w", wrapProviderError(GEMINI, err))
+	}
+	if len(resp.Embeddings) != len(texts) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(texts), len(resp.Embeddings))
 	}
 
 	embeddings := make([][]float32, len(resp.Embeddings))
 	for i, embedding := range resp.Embeddings {
-		// Convert []float64 to []float32
-		embeddings[i] = make([]float32, len(embedding.Values))
-		for j, v := range embedding.Values {
-			embeddings[i][j] = float32(v)
-		}
+		embeddings[i] = embedding.Values
 	}
 
 	return embeddings, nil
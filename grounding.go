@@ -0,0 +1,169 @@
+package genai
+
+import (
+	"context"
+
+	"github.com/openai/openai-go"
+	gemini "google.golang.org/genai"
+)
+
+// WebSearch grounds responses in a web search run by the provider, an alternative to
+// the SearchWeb tool that needs no SearxNG instance. Gemini uses its Google Search tool,
+// OpenAI sends web_search_options, which only its search models accept. Other
+// providers ignore it.
+type WebSearch struct {
+	// ContextSize is how much search context OpenAI retrieves: low, medium or high.
+	// The provider default is used when empty, Gemini has no such setting.
+	ContextSize string
+}
+
+// Grounding is the web search behind a response
+type Grounding struct {
+	// Queries are the searches the provider ran, only Gemini reports them
+	Queries []string
+	// Sources are the pages the response is grounded in
+	Sources []Source
+	// Citations are the parts of the response supported by the sources
+	Citations []Citation
+	// SearchEntryPoint is the HTML of Gemini's search suggestions, which Google
+	// requires applications to show with grounded responses
+	SearchEntryPoint string
+}
+
+// Source is a web page a response is grounded in
+type Source struct {
+	Title  string
+	URL    string
+	Domain string
+}
+
+// Citation ties a segment of the response to its sources. StartIndex and EndIndex
+// delimit the segment in the response text, in bytes for Gemini and characters for
+// OpenAI.
+type Citation struct {
+	Text       string
+	StartIndex int
+	EndIndex   int
+	// Sources are indexes into Grounding.Sources
+	Sources []int
+}
+
+// Grounding returns the web search behind the last response, nil unless WebSearch is
+// set and the provider searched
+func (m *Model) Grounding() *Grounding {
+	m.groundingMu.Lock()
+	defer m.groundingMu.Unlock()
+	return m.grounding
+}
+
+func (m *Model) setGrounding(grounding *Grounding) {
+	m.groundingMu.Lock()
+	defer m.groundingMu.Unlock()
+	m.grounding = grounding
+}
+
+// GenerateWithGrounding runs a single prompt grounded in a web search and returns the
+// sources of the response, nil when the provider did not search
+func (p *Provider) GenerateWithGrounding(ctx context.Context, modelOptions ModelOptions, prompt string, search WebSearch) (string, *Grounding, error) {
+	modelOptions.WebSearch = &search
+	ctx, end, err := p.life.begin(ctx)
+	if err != nil {
+		return "", nil, err
+	}
+	defer end()
+	ctx, requestID := ensureRequestID(ctx)
+	l := p.Log.WithName("generate").WithValues("model", modelOptions.ModelName, "id", requestID)
+	model := NewModel(p, modelOptions, l)
+	model.setRequestID(requestID)
+	switch p.Provider {
+	case OLLAMA:
+		model.ollamaClient = p.Client.Ollama
+	case OPENAI, VLLM:
+		model.openAIClient = p.Client.OpenAI
+	}
+	text, err := model.generateMessageCtx(ctx, NewTextMessage(RoleUser, prompt))
+	return text, model.Grounding(), err
+}
+
+// geminiSearchTool is the Google Search tool for search, nil without it
+func geminiSearchTool(search *WebSearch) *gemini.Tool {
+	if search == nil {
+		return nil
+	}
+	return &gemini.Tool{GoogleSearch: &gemini.GoogleSearch{}}
+}
+
+// geminiGrounding converts the grounding metadata of the first candidate, nil without it
+func geminiGrounding(resp *gemini.GenerateContentResponse) *Grounding {
+	if len(resp.Candidates) == 0 || resp.Candidates[0].GroundingMetadata == nil {
+		return nil
+	}
+	metadata := resp.Candidates[0].GroundingMetadata
+	grounding := &Grounding{Queries: metadata.WebSearchQueries}
+	for _, chunk := range metadata.GroundingChunks {
+		// indexes of the supports refer to every chunk, those without a page are kept empty
+		var source Source
+		if chunk != nil && chunk.Web != nil {
+			source = Source{Title: chunk.Web.Title, URL: chunk.Web.URI, Domain: chunk.Web.Domain}
+		}
+		grounding.Sources = append(grounding.Sources, source)
+	}
+	for _, support := range metadata.GroundingSupports {
+		if support == nil || support.Segment == nil {
+			continue
+		}
+		citation := Citation{
+			Text:       support.Segment.Text,
+			StartIndex: int(support.Segment.StartIndex),
+			EndIndex:   int(support.Segment.EndIndex),
+		}
+		for _, i := range support.GroundingChunkIndices {
+			citation.Sources = append(citation.Sources, int(i))
+		}
+		grounding.Citations = append(grounding.Citations, citation)
+	}
+	if metadata.SearchEntryPoint != nil {
+		grounding.SearchEntryPoint = metadata.SearchEntryPoint.RenderedContent
+	}
+	if len(grounding.Queries) == 0 && len(grounding.Sources) == 0 && grounding.SearchEntryPoint == "" {
+		return nil
+	}
+	return grounding
+}
+
+// applyOpenAIWebSearch sets the web search options of the request for search
+func applyOpenAIWebSearch(params *openai.ChatCompletionNewParams, search *WebSearch) {
+	if search == nil {
+		return
+	}
+	params.WebSearchOptions = openai.ChatCompletionNewParamsWebSearchOptions{SearchContextSize: search.ContextSize}
+}
+
+// openAIGrounding converts the URL citations of a message, a source per cited page.
+// It is nil without citations.
+func openAIGrounding(message openai.ChatCompletionMessage) *Grounding {
+	var grounding *Grounding
+	content := []rune(message.Content)
+	sources := make(map[string]int)
+	for _, annotation := range message.Annotations {
+		if annotation.Type != "url_citation" {
+			continue
+		}
+		cited := annotation.URLCitation
+		if grounding == nil {
+			grounding = &Grounding{}
+		}
+		i, ok := sources[cited.URL]
+		if !ok {
+			i = len(grounding.Sources)
+			sources[cited.URL] = i
+			grounding.Sources = append(grounding.Sources, Source{Title: cited.Title, URL: cited.URL})
+		}
+		citation := Citation{StartIndex: int(cited.StartIndex), EndIndex: int(cited.EndIndex), Sources: []int{i}}
+		if citation.StartIndex >= 0 && citation.StartIndex <= citation.EndIndex && citation.EndIndex <= len(content) {
+			citation.Text = string(content[citation.StartIndex:citation.EndIndex])
+		}
+		grounding.Citations = append(grounding.Citations, citation)
+	}
+	return grounding
+}
@@ -0,0 +1,89 @@
+package genai
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+func TestGroundingGemini(t *testing.T) {
+	srv := newTestServer(t,
+		`{"candidates":[{"content":{"role":"model","parts":[{"text":"Paris is sunny."}]},"finishReason":"STOP",`+
+			`"groundingMetadata":{"webSearchQueries":["weather paris"],`+
+			`"groundingChunks":[{"web":{"uri":"https://weather.example/paris","title":"Paris weather","domain":"weather.example"}}],`+
+			`"groundingSupports":[{"segment":{"startIndex":0,"endIndex":15,"text":"Paris is sunny."},"groundingChunkIndices":[0]}],`+
+			`"searchEntryPoint":{"renderedContent":"<div>weather paris</div>"}}}]}`,
+	)
+	p, err := NewProvider(GEMINI, ProviderOptions{APIKey: "test", BaseURL: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Log = logr.Discard()
+	p.Retry = RetryPolicy{MaxAttempts: 1}
+	text, grounding, err := p.GenerateWithGrounding(context.Background(), ModelOptions{ModelName: "test"}, "Weather in Paris?", WebSearch{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text != "Paris is sunny." {
+		t.Errorf("text %q, want %q", text, "Paris is sunny.")
+	}
+	if got, want := fmt.Sprint(srv.last()["tools"]), "[map[googleSearch:map[]]]"; got != want {
+		t.Errorf("tools %s, want %s", got, want)
+	}
+	want := &Grounding{
+		Queries:          []string{"weather paris"},
+		Sources:          []Source{{Title: "Paris weather", URL: "https://weather.example/paris", Domain: "weather.example"}},
+		Citations:        []Citation{{Text: "Paris is sunny.", StartIndex: 0, EndIndex: 15, Sources: []int{0}}},
+		SearchEntryPoint: "<div>weather paris</div>",
+	}
+	if got := fmt.Sprintf("%+v", grounding); got != fmt.Sprintf("%+v", want) {
+		t.Errorf("grounding %s, want %+v", got, want)
+	}
+}
+
+func TestGroundingOpenAI(t *testing.T) {
+	srv := newTestServer(t,
+		`{"id":"chatcmpl-1","object":"chat.completion","created":0,"model":"test","choices":[{"index":0,"finish_reason":"stop",`+
+			`"message":{"role":"assistant","content":"Paris is sunny, très chaud.","annotations":[`+
+			`{"type":"url_citation","url_citation":{"start_index":0,"end_index":15,"title":"Paris weather","url":"https://weather.example/paris"}},`+
+			`{"type":"url_citation","url_citation":{"start_index":16,"end_index":26,"title":"Paris weather","url":"https://weather.example/paris"}}]}}]}`,
+	)
+	p, err := NewProvider(OPENAI, ProviderOptions{APIKey: "test", BaseURL: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Log = logr.Discard()
+	p.Retry = RetryPolicy{MaxAttempts: 1}
+	chat := p.ChatEvents(ModelOptions{ModelName: "test", WebSearch: &WebSearch{ContextSize: "low"}}, nil)
+	defer close(chat.Done)
+	go chat.SendCtx(context.Background(), "Weather in Paris?")
+	var complete TurnComplete
+wait:
+	for {
+		select {
+		case event := <-chat.Events:
+			if c, ok := event.(TurnComplete); ok {
+				complete = c
+				break wait
+			}
+		case <-time.After(10 * time.Second):
+			t.Fatal("the turn did not complete")
+		}
+	}
+	if got, want := fmt.Sprint(srv.last()["web_search_options"]), "map[search_context_size:low]"; got != want {
+		t.Errorf("web search options %s, want %s", got, want)
+	}
+	want := &Grounding{
+		Sources: []Source{{Title: "Paris weather", URL: "https://weather.example/paris"}},
+		Citations: []Citation{
+			{Text: "Paris is sunny,", StartIndex: 0, EndIndex: 15, Sources: []int{0}},
+			{Text: "très chaud", StartIndex: 16, EndIndex: 26, Sources: []int{0}},
+		},
+	}
+	if got := fmt.Sprintf("%+v", complete.Grounding); got != fmt.Sprintf("%+v", want) {
+		t.Errorf("grounding %s, want %+v", got, want)
+	}
+}
@@ -0,0 +1,206 @@
+package genai
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+
+	"github.com/jbutlerdev/genai/tools"
+)
+
+// GuardrailStage is the point a guardrail checks a message at
+type GuardrailStage string
+
+const (
+	// GuardInput checks the user's message before it is sent to the model
+	GuardInput GuardrailStage = "input"
+	// GuardOutput checks the model's response before it is delivered
+	GuardOutput GuardrailStage = "output"
+)
+
+// GuardrailAction is the decision of a guardrail about a message
+type GuardrailAction string
+
+const (
+	// GuardrailAllow passes the message on unchanged, the zero value does the same
+	GuardrailAllow GuardrailAction = "allow"
+	// GuardrailAnnotate passes the message on unchanged and reports the annotations
+	GuardrailAnnotate GuardrailAction = "annotate"
+	// GuardrailRewrite replaces the text of the message with GuardrailResult.Text
+	GuardrailRewrite GuardrailAction = "rewrite"
+	// GuardrailBlock stops the message, the request fails with a GuardrailError
+	GuardrailBlock GuardrailAction = "block"
+)
+
+// GuardrailResult is what a guardrail decided about a message
+type GuardrailResult struct {
+	Action GuardrailAction
+	// Text is the rewritten text for GuardrailRewrite
+	Text string
+	// Reason explains the decision, it is reported in GuardrailEvent and GuardrailError
+	Reason string
+	// Annotations label the message, e.g. a category and score of a classifier
+	Annotations map[string]string
+}
+
+// Guardrail validates or transforms the text of prompts and responses. Guardrails run
+// in the order of ModelOptions.Guardrails, each sees the text the previous one passed
+// on. A Check that fails fails the request, so a broken filter does not let messages
+// through unchecked.
+type Guardrail struct {
+	Name string
+	// Stages are the points the guardrail runs at, both when empty
+	Stages []GuardrailStage
+	Check  func(ctx context.Context, stage GuardrailStage, text string) (GuardrailResult, error)
+}
+
+// GuardrailError is returned for a prompt or response a guardrail blocked
+type GuardrailError struct {
+	Guardrail string
+	Stage     GuardrailStage
+	Reason    string
+}
+
+func (e *GuardrailError) Error() string {
+	message := "prompt"
+	if e.Stage == GuardOutput {
+		message = "response"
+	}
+	if e.Reason == "" {
+		return fmt.Sprintf("%s blocked by guardrail %s", message, e.Guardrail)
+	}
+	return fmt.Sprintf("%s blocked by guardrail %s: %s", message, e.Guardrail, e.Reason)
+}
+
+// runsIn reports whether the guardrail checks messages at stage
+func (g Guardrail) runsIn(stage GuardrailStage) bool {
+	return len(g.Stages) == 0 || slices.Contains(g.Stages, stage)
+}
+
+// guarded reports whether any guardrail of the model runs at stage
+func (m *Model) guarded(stage GuardrailStage) bool {
+	for _, g := range m.Guardrails {
+		if g.runsIn(stage) {
+			return true
+		}
+	}
+	return false
+}
+
+// guard runs the guardrails of stage over text and returns the text to pass on. The
+// decisions other than allow are logged and sent to chat as GuardrailEvent, chat is
+// nil for single prompts.
+func (m *Model) guard(ctx context.Context, chat *Chat, stage GuardrailStage, text string) (string, error) {
+	for _, g := range m.Guardrails {
+		if !g.runsIn(stage) {
+			continue
+		}
+		result, err := g.Check(ctx, stage, text)
+		if err != nil {
+			return "", fmt.Errorf("guardrail %s failed: %w", g.Name, err)
+		}
+		if result.Action == "" || result.Action == GuardrailAllow {
+			continue
+		}
+		m.Logger.Info("Guardrail applied", "guardrail", g.Name, "stage", stage, "action", result.Action, "reason", result.Reason)
+		if chat != nil {
+			chat.emit(GuardrailEvent{Guardrail: g.Name, Stage: stage, Action: result.Action, Reason: result.Reason, Annotations: result.Annotations})
+		}
+		switch result.Action {
+		case GuardrailBlock:
+			return "", &GuardrailError{Guardrail: g.Name, Stage: stage, Reason: result.Reason}
+		case GuardrailRewrite:
+			text = result.Text
+		}
+	}
+	return text, nil
+}
+
+// guardPrompt runs the input guardrails over the text parts of msg
+func (m *Model) guardPrompt(ctx context.Context, chat *Chat, msg Message) (Message, error) {
+	if !m.guarded(GuardInput) {
+		return msg, nil
+	}
+	parts := slices.Clone(msg.Parts)
+	for i, part := range parts {
+		if part.Type != TextPart {
+			continue
+		}
+		text, err := m.guard(ctx, chat, GuardInput, part.Text)
+		if err != nil {
+			return msg, err
+		}
+		parts[i].Text = text
+	}
+	msg.Parts = parts
+	return msg, nil
+}
+
+// guardResponse runs the output guardrails over a response
+func (m *Model) guardResponse(ctx context.Context, chat *Chat, response string) (string, error) {
+	return m.guard(ctx, chat, GuardOutput, response)
+}
+
+// RewriteGuardrail returns a guardrail that rewrites messages at stages with process,
+// e.g. MaskProfanity, reporting reason for the messages it changes. Messages it does not
+// change are allowed.
+func RewriteGuardrail(name string, reason string, process PostProcessor, stages ...GuardrailStage) Guardrail {
+	return Guardrail{
+		Name:   name,
+		Stages: stages,
+		Check: func(ctx context.Context, stage GuardrailStage, text string) (GuardrailResult, error) {
+			rewritten, err := process(text)
+			if err != nil || rewritten == text {
+				return GuardrailResult{}, err
+			}
+			return GuardrailResult{Action: GuardrailRewrite, Text: rewritten, Reason: reason}, nil
+		},
+	}
+}
+
+// ProfanityGuardrail masks the given words, DefaultProfanity when none are given, in
+// prompts and responses
+func ProfanityGuardrail(words ...string) Guardrail {
+	return RewriteGuardrail("profanity", "profanity masked", MaskProfanity(words...))
+}
+
+// PIIGuardrail replaces email addresses and international phone numbers with a short
+// hash and redacts secrets such as API keys and passwords, the same way
+// tools.LogPolicy does in logs. It runs on prompts and responses, so the model never
+// receives the data and does not repeat it.
+func PIIGuardrail() Guardrail {
+	policy := tools.LogPolicy{HashPII: true}
+	return RewriteGuardrail("pii", "personal data or secrets removed", func(text string) (string, error) {
+		return policy.RedactString(text), nil
+	})
+}
+
+// DefaultJailbreakPatterns match common attempts to make a model ignore its instructions
+var DefaultJailbreakPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)\b(ignore|disregard|forget)\b.{0,20}\b(previous|prior|above|earlier|system)\b.{0,20}\b(instructions|prompts?|rules)\b`),
+	regexp.MustCompile(`(?i)\b(reveal|print|show|repeat)\b.{0,20}\b(your|the)\b.{0,10}\bsystem prompt\b`),
+	regexp.MustCompile(`(?i)\byou are now\b.{0,20}\b(DAN|jailbroken|unrestricted|unfiltered)\b`),
+	regexp.MustCompile(`(?i)\b(developer|god|jailbreak) mode\b`),
+	regexp.MustCompile(`(?i)\bpretend\b.{0,30}\b(no|without)\b.{0,10}\b(restrictions|rules|guidelines|filters)\b`),
+}
+
+// JailbreakGuardrail blocks prompts matching one of patterns, DefaultJailbreakPatterns
+// when none are given
+func JailbreakGuardrail(patterns ...*regexp.Regexp) Guardrail {
+	if len(patterns) == 0 {
+		patterns = DefaultJailbreakPatterns
+	}
+	return Guardrail{
+		Name:   "jailbreak",
+		Stages: []GuardrailStage{GuardInput},
+		Check: func(ctx context.Context, stage GuardrailStage, text string) (GuardrailResult, error) {
+			for _, pattern := range patterns {
+				if match := pattern.FindString(text); match != "" {
+					return GuardrailResult{Action: GuardrailBlock, Reason: fmt.Sprintf("prompt injection attempt: %q", match)}, nil
+				}
+			}
+			return GuardrailResult{}, nil
+		},
+	}
+}
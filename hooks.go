@@ -0,0 +1,94 @@
+package genai
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// RequestInfo describes a request about to be sent to the model, see
+// ProviderOptions.OnRequest
+type RequestInfo struct {
+	Provider string
+	Model    string
+	// RequestID is the ID of the chat turn or generate call, see WithRequestID
+	RequestID string
+	// Messages are the messages sent, including the history and examples
+	Messages []Message
+}
+
+// ResponseInfo describes a response of the model or the error that ended its request,
+// see ProviderOptions.OnResponse
+type ResponseInfo struct {
+	Provider  string
+	Model     string
+	RequestID string
+	// Message is the response, the text and tool calls of the model
+	Message Message
+	// Duration is the time the request took, including retries
+	Duration time.Duration
+	Err      error
+}
+
+// ToolCallInfo describes a tool call that ran, see ProviderOptions.OnToolCall
+type ToolCallInfo struct {
+	Tool           string
+	Args           map[string]any
+	Result         any
+	Duration       time.Duration
+	Err            error
+	RequestID      string
+	ConversationID string
+}
+
+// UsageInfo reports the tokens of a response, see ProviderOptions.OnUsage
+type UsageInfo struct {
+	Provider  string
+	Model     string
+	RequestID string
+	Usage     Usage
+}
+
+// hooks are the callbacks set with ProviderOptions
+type hooks struct {
+	onRequest  func(RequestInfo) error
+	onResponse func(ResponseInfo)
+	onToolCall func(ToolCallInfo)
+	onUsage    func(UsageInfo)
+}
+
+// startRequest reports a request for messages to OnRequest and returns the function
+// reporting its response to OnResponse. The request must not be sent when OnRequest
+// fails.
+func (m *Model) startRequest(ctx context.Context, model string, messages []Message) (func(Message, error), error) {
+	h := m.Provider.hooks
+	requestID := RequestID(ctx)
+	if h.onRequest != nil {
+		if err := h.onRequest(RequestInfo{Provider: m.Provider.Provider, Model: model, RequestID: requestID, Messages: messages}); err != nil {
+			return nil, err
+		}
+	}
+	started := time.Now()
+	return func(response Message, err error) {
+		if h.onResponse == nil {
+			return
+		}
+		h.onResponse(ResponseInfo{
+			Provider:  m.Provider.Provider,
+			Model:     model,
+			RequestID: requestID,
+			Message:   response,
+			Duration:  time.Since(started),
+			Err:       err,
+		})
+	}, nil
+}
+
+// collectResponse wraps send to keep the text of a streamed response for OnResponse
+func collectResponse(send func(string) bool) (func(string) bool, *strings.Builder) {
+	var text strings.Builder
+	return func(chunk string) bool {
+		text.WriteString(chunk)
+		return send(chunk)
+	}, &text
+}
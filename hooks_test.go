@@ -0,0 +1,124 @@
+package genai
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/jbutlerdev/genai/tools"
+)
+
+func TestProviderHooks(t *testing.T) {
+	tool, _ := registerWeatherTool(t)
+	srv := newTestServer(t,
+		`{"id":"chatcmpl-1","object":"chat.completion","created":0,"model":"test","choices":[{"index":0,"finish_reason":"tool_calls",`+
+			`"message":{"role":"assistant","content":"","tool_calls":[{"id":"call_1","type":"function","function":{"name":"mock_weather","arguments":"{\"city\":\"Paris\"}"}}]}}],`+
+			`"usage":{"prompt_tokens":10,"completion_tokens":5,"total_tokens":15}}`,
+		`{"id":"chatcmpl-2","object":"chat.completion","created":0,"model":"test","choices":[{"index":0,"finish_reason":"stop",`+
+			`"message":{"role":"assistant","content":"Sunny"}}],"usage":{"prompt_tokens":20,"completion_tokens":3,"total_tokens":23}}`,
+	)
+	var mu sync.Mutex
+	var requests []RequestInfo
+	var responses []ResponseInfo
+	var toolCalls []ToolCallInfo
+	var usage []UsageInfo
+	budget := errors.New("over budget")
+	p, err := NewProvider(OPENAI, ProviderOptions{
+		APIKey:  "test",
+		BaseURL: srv.URL,
+		OnRequest: func(info RequestInfo) error {
+			mu.Lock()
+			defer mu.Unlock()
+			requests = append(requests, info)
+			if len(requests) > 2 {
+				return budget
+			}
+			return nil
+		},
+		OnResponse: func(info ResponseInfo) {
+			mu.Lock()
+			defer mu.Unlock()
+			responses = append(responses, info)
+		},
+		OnToolCall: func(info ToolCallInfo) {
+			mu.Lock()
+			defer mu.Unlock()
+			toolCalls = append(toolCalls, info)
+		},
+		OnUsage: func(info UsageInfo) {
+			mu.Lock()
+			defer mu.Unlock()
+			usage = append(usage, info)
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Log = logr.Discard()
+	chat := p.ChatEvents(ModelOptions{ModelName: "test"}, []*tools.Tool{tool})
+	defer close(chat.Done)
+	ctx := WithRequestID(context.Background(), "req-1")
+	go chat.SendCtx(ctx, "Weather in Paris?")
+	for event := range chat.Events {
+		if e, ok := event.(ErrorEvent); ok {
+			t.Fatalf("turn failed: %v", e.Err)
+		}
+		if _, ok := event.(TurnComplete); ok {
+			break
+		}
+	}
+
+	mu.Lock()
+	if len(requests) != 2 || requests[0].Model != "test" || requests[0].RequestID != "req-1" || requests[0].Provider != OPENAI {
+		t.Fatalf("requests %+v", requests)
+	}
+	if last := requests[1].Messages[len(requests[1].Messages)-1]; last.Role != RoleTool {
+		t.Errorf("second request ends with a %s message, want the tool result", last.Role)
+	}
+	if len(responses) != 2 || len(responses[0].Message.ToolCalls()) != 1 || responses[1].Message.Text() != "Sunny" || responses[1].Err != nil {
+		t.Errorf("responses %+v", responses)
+	}
+	if len(toolCalls) != 1 || toolCalls[0].Tool != "mock_weather" || toolCalls[0].Args["city"] != "Paris" || toolCalls[0].RequestID != "req-1" || toolCalls[0].ConversationID != chat.ID() {
+		t.Errorf("tool calls %+v", toolCalls)
+	}
+	if len(usage) != 2 || usage[0].Usage.PromptTokens != 10 || usage[1].Usage.CompletionTokens != 3 || usage[1].RequestID != "req-1" {
+		t.Errorf("usage %+v", usage)
+	}
+	mu.Unlock()
+
+	// a request OnRequest rejects is not sent
+	if _, err := mockTurn(t, chat, "And in Rome?"); !errors.Is(err, budget) {
+		t.Errorf("turn error %v, want %v", err, budget)
+	}
+	if sent := len(srv.recorded()); sent != 2 {
+		t.Errorf("%d requests sent, want 2", sent)
+	}
+}
+
+func TestProviderHooksStream(t *testing.T) {
+	srv := newTestServer(t, `{"model":"test","response":"Hi","done":true,"prompt_eval_count":4,"eval_count":1}`)
+	var responses []ResponseInfo
+	var usage []UsageInfo
+	p, err := NewProvider(OLLAMA, ProviderOptions{
+		BaseURL:    srv.URL,
+		OnResponse: func(info ResponseInfo) { responses = append(responses, info) },
+		OnUsage:    func(info UsageInfo) { usage = append(usage, info) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := WithRequestID(context.Background(), "req-2")
+	for _, err := range p.Stream(ctx, ModelOptions{ModelName: "test"}, "Hello") {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(responses) != 1 || responses[0].Message.Text() != "Hi" || responses[0].RequestID != "req-2" {
+		t.Errorf("responses %+v", responses)
+	}
+	if len(usage) != 1 || usage[0].Usage.PromptTokens != 4 || usage[0].RequestID != "req-2" {
+		t.Errorf("usage %+v", usage)
+	}
+}
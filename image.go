@@ -0,0 +1,169 @@
+package genai
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/openai/openai-go"
+	gemini "google.golang.org/genai"
+)
+
+// default image generation models
+const (
+	DefaultOpenAIImageModel = "dall-e-3"
+	DefaultGeminiImageModel = "imagen-4.0-generate-001"
+)
+
+// ImageOptions configures GenerateImage
+type ImageOptions struct {
+	// Model defaults to DefaultOpenAIImageModel or DefaultGeminiImageModel
+	Model string
+	// Count is the number of images, 1 when zero
+	Count int
+	// Size such as 1024x1024, OpenAI only
+	Size string
+	// Quality such as standard or hd, OpenAI only
+	Quality string
+	// Style vivid or natural, OpenAI only
+	Style string
+	// AspectRatio such as 1:1 or 16:9, Gemini only
+	AspectRatio string
+	// NegativePrompt describes what to leave out of the image, Gemini only
+	NegativePrompt string
+}
+
+// GeneratedImage is an image returned by GenerateImage
+type GeneratedImage struct {
+	Data     []byte
+	MIMEType string
+	// RevisedPrompt is the prompt the provider rewrote the request to, if any
+	RevisedPrompt string
+}
+
+// GenerateImage creates images from a prompt with OpenAI image models or Gemini Imagen
+func (p *Provider) GenerateImage(ctx context.Context, prompt string, opts ImageOptions) ([]GeneratedImage, error) {
+	if prompt == "" {
+		return nil, fmt.Errorf("no prompt for the image")
+	}
+	if opts.Count <= 0 {
+		opts.Count = 1
+	}
+	ctx, cancel := context.WithTimeout(ctx, p.Timeouts.merge(DefaultTimeouts).Generate)
+	defer cancel()
+	switch p.Provider {
+	case OPENAI, VLLM:
+		if opts.Model == "" {
+			opts.Model = DefaultOpenAIImageModel
+		}
+		return p.Client.OpenAI.GenerateImage(ctx, prompt, opts)
+	case GEMINI:
+		if opts.Model == "" {
+			opts.Model = DefaultGeminiImageModel
+		}
+		return geminiGenerateImage(ctx, p, prompt, opts)
+	default:
+		return nil, fmt.Errorf("unsupported provider for image generation: %s", p.Provider)
+	}
+}
+
+// GenerateImage sends the prompt to the image generation endpoint
+func (c *OpenAIClient) GenerateImage(ctx context.Context, prompt string, opts ImageOptions) ([]GeneratedImage, error) {
+	params := openai.ImageGenerateParams{
+		Prompt: prompt,
+		Model:  openai.ImageModel(opts.Model),
+		N:      openai.Int(int64(opts.Count)),
+	}
+	// gpt-image models always return base64 and reject response_format
+	if strings.HasPrefix(opts.Model, "dall-e") {
+		params.ResponseFormat = openai.ImageGenerateParamsResponseFormatB64JSON
+	}
+	if opts.Size != "" {
+		params.Size = openai.ImageGenerateParamsSize(opts.Size)
+	}
+	if opts.Quality != "" {
+		params.Quality = openai.ImageGenerateParamsQuality(opts.Quality)
+	}
+	if opts.Style != "" {
+		params.Style = openai.ImageGenerateParamsStyle(opts.Style)
+	}
+	resp, err := retry(ctx, c.retry, c.log, c.provider, c.metrics, func() (*openai.ImagesResponse, error) {
+		return openAIBalanced(c, func(client *OpenAIClient) (*openai.ImagesResponse, error) {
+			return client.client.Images.Generate(ctx, params)
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate image: %w", err)
+	}
+	images := make([]GeneratedImage, 0, len(resp.Data))
+	for _, image := range resp.Data {
+		if image.B64JSON == "" && image.URL != "" {
+			// compatible servers may ignore response_format and return a URL
+			downloaded, err := downloadImage(ctx, c.httpClient, image.URL)
+			if err != nil {
+				return nil, err
+			}
+			images = append(images, GeneratedImage{Data: downloaded.Data, MIMEType: downloaded.MIMEType, RevisedPrompt: image.RevisedPrompt})
+			continue
+		}
+		if image.B64JSON == "" {
+			return nil, fmt.Errorf("image returned without data")
+		}
+		data, err := base64.StdEncoding.DecodeString(image.B64JSON)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode image: %w", err)
+		}
+		images = append(images, GeneratedImage{
+			Data:          data,
+			MIMEType:      http.DetectContentType(data),
+			RevisedPrompt: image.RevisedPrompt,
+		})
+	}
+	return images, nil
+}
+
+func geminiGenerateImage(ctx context.Context, p *Provider, prompt string, opts ImageOptions) ([]GeneratedImage, error) {
+	config := &gemini.GenerateImagesConfig{
+		NumberOfImages: int32(opts.Count),
+		AspectRatio:    opts.AspectRatio,
+		NegativePrompt: opts.NegativePrompt,
+		// the reason explains images removed by the safety filters
+		IncludeRAIReason: true,
+	}
+	resp, err := retry(ctx, p.Retry, p.Log, GEMINI, p.observer(), func() (*gemini.GenerateImagesResponse, error) {
+		return balanced(p, func(client *Client) (*gemini.GenerateImagesResponse, error) {
+			return client.Gemini.Models.GenerateImages(ctx, opts.Model, prompt, config)
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate image: %w", err)
+	}
+	var images []GeneratedImage
+	var filtered []string
+	for _, image := range resp.GeneratedImages {
+		if image.Image == nil || len(image.Image.ImageBytes) == 0 {
+			if image.RAIFilteredReason != "" {
+				filtered = append(filtered, image.RAIFilteredReason)
+			}
+			continue
+		}
+		mimeType := image.Image.MIMEType
+		if mimeType == "" {
+			mimeType = http.DetectContentType(image.Image.ImageBytes)
+		}
+		images = append(images, GeneratedImage{
+			Data:          image.Image.ImageBytes,
+			MIMEType:      mimeType,
+			RevisedPrompt: image.EnhancedPrompt,
+		})
+	}
+	if len(images) == 0 {
+		if len(filtered) > 0 {
+			return nil, &ProviderError{Provider: GEMINI, Kind: ErrContentFiltered, Err: fmt.Errorf("images blocked: %s", strings.Join(filtered, "; "))}
+		}
+		return nil, fmt.Errorf("no images returned")
+	}
+	return images, nil
+}
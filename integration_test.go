@@ -0,0 +1,377 @@
+//go:build integration
+
+package genai
+
+// The integration tests run multi-turn tool calling through the chat loops of every
+// provider against servers that answer with the canned responses in
+// testdata/integration, one file per provider with the responses of each scenario in
+// order. They check what the model receives, which differs by provider in format but
+// not in meaning: the tool schema, the ids of calls and results, deduplication of
+// repeated calls and the failures of tools.
+//
+//	go test -tags integration -run Integration .
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/jbutlerdev/genai/tools"
+)
+
+// lookupTool is the tool the fixtures call, they also call integration_unknown which
+// is not registered
+const lookupTool = "integration_lookup"
+
+// lookupRuns counts the runs of lookupTool
+var lookupRuns atomic.Int32
+
+func registerLookupTool(t *testing.T) {
+	t.Helper()
+	tools.RegisterTool(tools.Tool{
+		Name:        lookupTool,
+		Description: "Look up the value stored under a key",
+		Parameters: []tools.Parameter{
+			{Name: "key", Type: "string", Description: "The key to look up", Required: true},
+		},
+		Run: func(args map[string]any) (map[string]any, error) {
+			lookupRuns.Add(1)
+			if args["key"] == "alpha" {
+				return map[string]any{"value": 42}, nil
+			}
+			return nil, fmt.Errorf("no value stored under %v", args["key"])
+		},
+	})
+	t.Cleanup(func() { tools.UnregisterTool(lookupTool) })
+}
+
+// recordedResult is a tool result as a provider received it
+type recordedResult struct {
+	ID      string
+	Content string
+}
+
+// integrationProvider decodes the requests of one provider
+type integrationProvider struct {
+	name string
+	// ids reports that the provider identifies calls, repeated calls then get a result each
+	ids bool
+	// toolNames returns the names of the declared tools and the parameters of lookupTool
+	toolNames func(req map[string]any) (names []string, params map[string]any)
+	// callIDs returns the ids of the tool calls sent back in the history
+	callIDs func(req map[string]any) []string
+	// results returns the tool results of the request
+	results func(req map[string]any) []recordedResult
+}
+
+var integrationProviders = []integrationProvider{
+	{
+		name: OPENAI,
+		ids:  true,
+		toolNames: func(req map[string]any) ([]string, map[string]any) {
+			var names []string
+			var params map[string]any
+			for _, tool := range jsonArray(req["tools"]) {
+				fn := jsonObject(jsonObject(tool)["function"])
+				names = append(names, jsonString(fn["name"]))
+				if fn["name"] == lookupTool {
+					params = jsonObject(fn["parameters"])
+				}
+			}
+			return names, params
+		},
+		callIDs: func(req map[string]any) []string {
+			var ids []string
+			for _, msg := range jsonArray(req["messages"]) {
+				for _, call := range jsonArray(jsonObject(msg)["tool_calls"]) {
+					ids = append(ids, jsonString(jsonObject(call)["id"]))
+				}
+			}
+			return ids
+		},
+		results: func(req map[string]any) []recordedResult {
+			var results []recordedResult
+			for _, msg := range jsonArray(req["messages"]) {
+				if m := jsonObject(msg); m["role"] == "tool" {
+					results = append(results, recordedResult{ID: jsonString(m["tool_call_id"]), Content: jsonString(m["content"])})
+				}
+			}
+			return results
+		},
+	},
+	{
+		name: OLLAMA,
+		toolNames: func(req map[string]any) ([]string, map[string]any) {
+			var names []string
+			var params map[string]any
+			for _, tool := range jsonArray(req["tools"]) {
+				fn := jsonObject(jsonObject(tool)["function"])
+				names = append(names, jsonString(fn["name"]))
+				if fn["name"] == lookupTool {
+					params = jsonObject(fn["parameters"])
+				}
+			}
+			return names, params
+		},
+		callIDs: func(map[string]any) []string { return nil },
+		results: func(req map[string]any) []recordedResult {
+			var results []recordedResult
+			for _, msg := range jsonArray(req["messages"]) {
+				if m := jsonObject(msg); m["role"] == "tool" {
+					results = append(results, recordedResult{Content: jsonString(m["content"])})
+				}
+			}
+			return results
+		},
+	},
+	{
+		name: GEMINI,
+		ids:  true,
+		toolNames: func(req map[string]any) ([]string, map[string]any) {
+			var names []string
+			var params map[string]any
+			for _, tool := range jsonArray(req["tools"]) {
+				for _, fn := range jsonArray(jsonObject(tool)["functionDeclarations"]) {
+					f := jsonObject(fn)
+					names = append(names, jsonString(f["name"]))
+					if f["name"] == lookupTool {
+						params = jsonObject(f["parameters"])
+					}
+				}
+			}
+			return names, params
+		},
+		callIDs: func(req map[string]any) []string {
+			var ids []string
+			for _, content := range jsonArray(req["contents"]) {
+				for _, part := range jsonArray(jsonObject(content)["parts"]) {
+					if call := jsonObject(jsonObject(part)["functionCall"]); call != nil {
+						ids = append(ids, jsonString(call["id"]))
+					}
+				}
+			}
+			return ids
+		},
+		results: func(req map[string]any) []recordedResult {
+			var results []recordedResult
+			for _, content := range jsonArray(req["contents"]) {
+				for _, part := range jsonArray(jsonObject(content)["parts"]) {
+					if response := jsonObject(jsonObject(part)["functionResponse"]); response != nil {
+						data, _ := json.Marshal(response["response"])
+						results = append(results, recordedResult{ID: jsonString(response["id"]), Content: string(data)})
+					}
+				}
+			}
+			return results
+		},
+	},
+}
+
+func jsonArray(v any) []any {
+	l, _ := v.([]any)
+	return l
+}
+
+func jsonObject(v any) map[string]any {
+	o, _ := v.(map[string]any)
+	return o
+}
+
+func jsonString(v any) string {
+	s, _ := v.(string)
+	return s
+}
+
+func loadScenarios(t *testing.T, provider string) map[string][]json.RawMessage {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", "integration", provider+".json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var scenarios map[string][]json.RawMessage
+	if err := json.Unmarshal(data, &scenarios); err != nil {
+		t.Fatalf("invalid %s fixtures: %v", provider, err)
+	}
+	return scenarios
+}
+
+// turn is what a chat reported for one message
+type turn struct {
+	text  string
+	tools []ToolCallFinished
+	err   error
+}
+
+// runScenario sends one message through a chat with lookupTool and returns its events
+// and the requests the server received
+func runScenario(t *testing.T, provider string, responses []json.RawMessage) (turn, []map[string]any) {
+	t.Helper()
+	scripted := make([]string, len(responses))
+	for i, response := range responses {
+		scripted[i] = string(response)
+	}
+	srv := newTestServer(t, scripted...)
+	p, err := NewProvider(provider, ProviderOptions{APIKey: "test", BaseURL: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Log = logr.Discard()
+	p.Retry = RetryPolicy{MaxAttempts: 1}
+	tool, err := tools.GetTool(lookupTool)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chat := p.ChatEvents(ModelOptions{ModelName: "test"}, []*tools.Tool{tool})
+	t.Cleanup(func() { close(chat.Done) })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	go chat.SendCtx(ctx, "What is stored under alpha?")
+	var result turn
+	for {
+		select {
+		case event := <-chat.Events:
+			switch e := event.(type) {
+			case ToolCallFinished:
+				result.tools = append(result.tools, e)
+			case ErrorEvent:
+				result.err = errors.Join(result.err, e.Err)
+			case TurnComplete:
+				result.text = e.Text
+				requests := srv.bodies()
+				if len(requests) > len(responses) {
+					t.Errorf("%d requests for %d responses", len(requests), len(responses))
+				}
+				return result, requests
+			}
+		case <-ctx.Done():
+			t.Fatal("the turn did not complete")
+		}
+	}
+}
+
+func TestIntegrationToolCalling(t *testing.T) {
+	registerLookupTool(t)
+	for _, provider := range integrationProviders {
+		scenarios := loadScenarios(t, provider.name)
+		t.Run(provider.name, func(t *testing.T) {
+			t.Run("tool_call", func(t *testing.T) {
+				lookupRuns.Store(0)
+				result, requests := runScenario(t, provider.name, scenarios["tool_call"])
+				checkTurn(t, result, requests, 2, "alpha is 42")
+				if runs := lookupRuns.Load(); runs != 1 {
+					t.Errorf("tool ran %d times, want 1", runs)
+				}
+
+				names, params := provider.toolNames(requests[0])
+				if len(names) != 1 || names[0] != lookupTool {
+					t.Errorf("declared tools %v, want [%s]", names, lookupTool)
+				}
+				key := jsonObject(jsonObject(params["properties"])["key"])
+				if !strings.EqualFold(jsonString(key["type"]), "string") || jsonString(key["description"]) == "" {
+					t.Errorf("parameter key = %v, want a described string", key)
+				}
+				if required := jsonArray(params["required"]); len(required) != 1 || required[0] != "key" {
+					t.Errorf("required parameters %v, want [key]", required)
+				}
+
+				results := provider.results(requests[1])
+				if len(results) != 1 || !strings.Contains(results[0].Content, `{"value":42}`) {
+					t.Fatalf("tool results %+v, want the JSON result of the lookup", results)
+				}
+				if provider.ids {
+					if ids := provider.callIDs(requests[1]); len(ids) != 1 || ids[0] != results[0].ID {
+						t.Errorf("call ids %v do not match result id %q", ids, results[0].ID)
+					}
+				}
+				if len(result.tools) != 1 || result.tools[0].Result.IsError {
+					t.Errorf("tool events %+v, want one successful call", result.tools)
+				}
+			})
+
+			t.Run("duplicate_calls", func(t *testing.T) {
+				lookupRuns.Store(0)
+				result, requests := runScenario(t, provider.name, scenarios["duplicate_calls"])
+				checkTurn(t, result, requests, 2, "alpha is 42")
+				if runs := lookupRuns.Load(); runs != 1 {
+					t.Errorf("repeated call ran the tool %d times, want 1", runs)
+				}
+				results := provider.results(requests[1])
+				want := 1
+				if provider.ids {
+					// every call id must be answered
+					want = 2
+				}
+				if len(results) != want {
+					t.Fatalf("got %d tool results, want %d: %+v", len(results), want, results)
+				}
+				ids := provider.callIDs(requests[1])
+				for i, r := range results {
+					if !strings.Contains(r.Content, `{"value":42}`) {
+						t.Errorf("result %d = %q, want the result of the first call", i, r.Content)
+					}
+					if provider.ids && (i >= len(ids) || r.ID != ids[i]) {
+						t.Errorf("result %d has id %q, calls %v", i, r.ID, ids)
+					}
+				}
+			})
+
+			t.Run("tool_error", func(t *testing.T) {
+				result, requests := runScenario(t, provider.name, scenarios["tool_error"])
+				checkTurn(t, result, requests, 2, "missing is not known")
+				checkFailure(t, provider.results(requests[1]), "no value stored under missing")
+				if len(result.tools) != 1 || !result.tools[0].Result.IsError {
+					t.Errorf("tool events %+v, want one failed call", result.tools)
+				}
+			})
+
+			t.Run("unknown_tool", func(t *testing.T) {
+				result, requests := runScenario(t, provider.name, scenarios["unknown_tool"])
+				checkTurn(t, result, requests, 2, "that tool is not available")
+				checkFailure(t, provider.results(requests[1]), tools.ErrToolNotFound.Error())
+			})
+		})
+	}
+}
+
+// checkTurn checks that the turn completed with text after the expected requests
+func checkTurn(t *testing.T, result turn, requests []map[string]any, wantRequests int, wantText string) {
+	t.Helper()
+	if result.err != nil {
+		t.Fatalf("turn failed: %v", result.err)
+	}
+	if len(requests) != wantRequests {
+		t.Fatalf("server received %d requests, want %d", len(requests), wantRequests)
+	}
+	if result.text != wantText {
+		t.Errorf("response %q, want %q", result.text, wantText)
+	}
+}
+
+// checkFailure checks that the only tool result is a ToolFailure with the error
+func checkFailure(t *testing.T, results []recordedResult, wantError string) {
+	t.Helper()
+	if len(results) != 1 {
+		t.Fatalf("got %d tool results, want 1: %+v", len(results), results)
+	}
+	content := results[0].Content
+	// Ollama prefixes the result with the tool name
+	if i := strings.Index(content, "{"); i > 0 {
+		content = content[i:]
+	}
+	var failure ToolFailure
+	if err := json.Unmarshal([]byte(content), &failure); err != nil {
+		t.Fatalf("tool result %q is not a ToolFailure: %v", results[0].Content, err)
+	}
+	if !strings.Contains(failure.Error, wantError) || failure.Hint == "" {
+		t.Errorf("failure %+v, want error %q with a hint", failure, wantError)
+	}
+}
@@ -0,0 +1,101 @@
+package genai
+
+import (
+	"context"
+	"maps"
+	"math"
+
+	"github.com/openai/openai-go"
+)
+
+// TokenLogprob is a generated token with its log probability and the most likely
+// alternatives at its position, which include the token itself when it was among them
+type TokenLogprob struct {
+	Token        string
+	Logprob      float64
+	Alternatives []TokenAlternative
+}
+
+// TokenAlternative is a token the model could have generated instead
+type TokenAlternative struct {
+	Token   string
+	Logprob float64
+}
+
+// Probability is the probability of the token between 0 and 1
+func (t TokenLogprob) Probability() float64 {
+	return math.Exp(t.Logprob)
+}
+
+// Confidence is the geometric mean of the probabilities of the tokens, a length
+// independent score between 0 and 1 that is low when the model was unsure of its
+// response. It is 0 without tokens.
+func Confidence(tokens []TokenLogprob) float64 {
+	if len(tokens) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, t := range tokens {
+		sum += t.Logprob
+	}
+	return math.Exp(sum / float64(len(tokens)))
+}
+
+// Logprobs returns the tokens of the last response with their log probabilities, as
+// generated before any PostProcessors ran. It is nil unless the Logprobs or TopLogprobs
+// parameter is set on an OpenAI compatible provider.
+func (m *Model) Logprobs() []TokenLogprob {
+	m.logprobsMu.Lock()
+	defer m.logprobsMu.Unlock()
+	return m.logprobs
+}
+
+func (m *Model) setLogprobs(tokens []TokenLogprob) {
+	m.logprobsMu.Lock()
+	defer m.logprobsMu.Unlock()
+	m.logprobs = tokens
+}
+
+// GenerateWithLogprobs runs a single prompt and returns the log probabilities of the
+// response tokens with topLogprobs alternatives each. Only OpenAI compatible providers
+// return them, the others return nil tokens.
+func (p *Provider) GenerateWithLogprobs(ctx context.Context, modelOptions ModelOptions, prompt string, topLogprobs int) (string, []TokenLogprob, error) {
+	modelOptions.Parameters = maps.Clone(modelOptions.Parameters)
+	if modelOptions.Parameters == nil {
+		modelOptions.Parameters = make(map[string]any)
+	}
+	modelOptions.Parameters[Logprobs] = true
+	modelOptions.Parameters[TopLogprobs] = topLogprobs
+	ctx, end, err := p.life.begin(ctx)
+	if err != nil {
+		return "", nil, err
+	}
+	defer end()
+	ctx, requestID := ensureRequestID(ctx)
+	l := p.Log.WithName("generate").WithValues("model", modelOptions.ModelName, "id", requestID)
+	model := NewModel(p, modelOptions, l)
+	model.setRequestID(requestID)
+	switch p.Provider {
+	case OLLAMA:
+		model.ollamaClient = p.Client.Ollama
+	case OPENAI, VLLM:
+		model.openAIClient = p.Client.OpenAI
+	}
+	text, err := model.generateMessageCtx(ctx, NewTextMessage(RoleUser, prompt))
+	return text, model.Logprobs(), err
+}
+
+// openAILogprobs converts the logprobs of a completion choice, nil when none were requested
+func openAILogprobs(logprobs openai.ChatCompletionChoiceLogprobs) []TokenLogprob {
+	if len(logprobs.Content) == 0 {
+		return nil
+	}
+	tokens := make([]TokenLogprob, len(logprobs.Content))
+	for i, t := range logprobs.Content {
+		tokens[i] = TokenLogprob{Token: t.Token, Logprob: t.Logprob}
+		for _, alt := range t.TopLogprobs {
+			tokens[i].Alternatives = append(tokens[i].Alternatives, TokenAlternative{Token: alt.Token, Logprob: alt.Logprob})
+		}
+	}
+	return tokens
+}
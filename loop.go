@@ -0,0 +1,171 @@
+package genai
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"strings"
+)
+
+const (
+	// DefaultLoopThreshold is the number of repetitions of a tool call sequence that
+	// make a loop
+	DefaultLoopThreshold = 3
+	// DefaultLoopMaxSequence is the longest sequence of tool calls checked for repetitions
+	DefaultLoopMaxSequence = 4
+)
+
+// defaultLoopGuidance is the hint sent with the call that completed a loop
+const defaultLoopGuidance = "You are repeating the same tool calls with the same arguments and getting the same results. " +
+	"Do not call them again. Use the results you already have to answer, or try a different approach."
+
+// ErrLoopDetected is matched by LoopDetectedError
+var ErrLoopDetected = errors.New("tool call loop detected")
+
+// LoopAction is what LoopDetection does when a loop is found
+type LoopAction string
+
+const (
+	// LoopGuide answers the call that completes a loop with a failure telling the model
+	// to change its approach, without running the tool. A second loop in the same turn
+	// ends the turn.
+	LoopGuide LoopAction = "guide"
+	// LoopStop ends the turn with a LoopDetectedError
+	LoopStop LoopAction = "stop"
+)
+
+// LoopDetection breaks tool call loops: a sequence of tool calls with the same names and
+// arguments repeated Threshold times in a row within one turn, e.g. listing a directory
+// and reading the same file over and over.
+type LoopDetection struct {
+	// Threshold is the number of repetitions that make a loop, DefaultLoopThreshold when zero
+	Threshold int
+	// MaxSequence is the longest repeated sequence that is detected, DefaultLoopMaxSequence
+	// when zero
+	MaxSequence int
+	// Action is LoopGuide when empty
+	Action LoopAction
+	// Guidance replaces the hint sent to the model by LoopGuide
+	Guidance string
+}
+
+// ToolLoopDetected is sent when a chat's tool calls repeat, before the call that
+// completed the loop is answered or the turn ends
+type ToolLoopDetected struct {
+	// Sequence are the repeated calls in order
+	Sequence []ToolCall
+	Repeats  int
+	Action   LoopAction
+}
+
+func (ToolLoopDetected) event() {}
+
+// LoopDetectedError ends a turn stuck in a tool call loop
+type LoopDetectedError struct {
+	Sequence []ToolCall
+	Repeats  int
+}
+
+func (e *LoopDetectedError) Error() string {
+	names := make([]string, len(e.Sequence))
+	for i, call := range e.Sequence {
+		names[i] = call.Name
+	}
+	return fmt.Sprintf("%v: %s repeated %d times", ErrLoopDetected, strings.Join(names, ", "), e.Repeats)
+}
+
+func (e *LoopDetectedError) Is(target error) bool {
+	return target == ErrLoopDetected
+}
+
+// toolLoop records a tool call of the current turn and checks whether it completes a
+// loop. It returns the failure to send instead of running the tool for LoopGuide, or
+// the error ending the turn.
+func (m *Model) toolLoop(chat *Chat, call ToolCall) (ToolResult, bool, error) {
+	d := m.LoopDetection
+	if d == nil {
+		return ToolResult{}, false, nil
+	}
+	threshold := d.Threshold
+	if threshold <= 0 {
+		threshold = DefaultLoopThreshold
+	}
+	maxSequence := d.MaxSequence
+	if maxSequence <= 0 {
+		maxSequence = DefaultLoopMaxSequence
+	}
+	// running the tool adds its options to the arguments
+	call.Arguments = maps.Clone(call.Arguments)
+	m.toolMu.Lock()
+	m.toolCalls = append(m.toolCalls, call)
+	m.toolCallKeys = append(m.toolCallKeys, toolCallKey(call))
+	length := repeatedSequence(m.toolCallKeys, threshold, maxSequence)
+	var sequence []ToolCall
+	loops := m.toolLoops
+	if length > 0 {
+		sequence = append(sequence, m.toolCalls[len(m.toolCalls)-length:]...)
+		m.toolLoops++
+		loops = m.toolLoops
+	}
+	m.toolMu.Unlock()
+	if length == 0 {
+		return ToolResult{}, false, nil
+	}
+
+	action := d.Action
+	if action == "" {
+		action = LoopGuide
+	}
+	if loops > 1 {
+		// the model ignored the guidance
+		action = LoopStop
+	}
+	m.Logger.Info("Detected tool call loop", "tool", call.Name, "sequence", len(sequence), "repeats", threshold, "action", action)
+	chat.emit(ToolLoopDetected{Sequence: sequence, Repeats: threshold, Action: action})
+	if action == LoopStop {
+		return ToolResult{}, false, &LoopDetectedError{Sequence: sequence, Repeats: threshold}
+	}
+	guidance := d.Guidance
+	if guidance == "" {
+		guidance = defaultLoopGuidance
+	}
+	failure := ToolFailure{
+		Tool:  call.Name,
+		Error: fmt.Sprintf("%v, the call was not run", ErrLoopDetected),
+		Hint:  guidance,
+	}
+	return ToolResult{ID: call.ID, Name: call.Name, Content: failure.String(), IsError: true}, true, nil
+}
+
+// repeatedSequence returns the length of the shortest sequence of at most maxSequence
+// calls that the calls, identified by toolCallKey, end with threshold times in a row,
+// 0 when there is none
+func repeatedSequence(keys []string, threshold int, maxSequence int) int {
+	for length := 1; length <= maxSequence; length++ {
+		n := length * threshold
+		if n > len(keys) {
+			break
+		}
+		tail := keys[len(keys)-n:]
+		repeated := true
+		for i := length; i < n; i++ {
+			if tail[i] != tail[i%length] {
+				repeated = false
+				break
+			}
+		}
+		if repeated {
+			return length
+		}
+	}
+	return 0
+}
+
+// resetToolLoops starts a new turn
+func (m *Model) resetToolLoops() {
+	m.toolMu.Lock()
+	defer m.toolMu.Unlock()
+	m.toolCalls = nil
+	m.toolCallKeys = nil
+	m.toolLoops = 0
+}
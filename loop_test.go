@@ -0,0 +1,151 @@
+package genai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/jbutlerdev/genai/tools"
+)
+
+const loopToolCallBody = `{"id":"chatcmpl-1","object":"chat.completion","created":0,"model":"test",` +
+	`"choices":[{"index":0,"finish_reason":"tool_calls","message":{"role":"assistant","content":"",` +
+	`"tool_calls":[{"id":"call_%d","type":"function","function":{"name":"loop_tree","arguments":"{\"path\":\".\"}"}}]}}]}`
+
+// loopChat runs one turn of a chat whose model calls loop_tree for the first toolCalls
+// responses and answers afterwards, it returns the runs of the tool and the events
+func loopChat(t *testing.T, detection *LoopDetection, toolCalls int) (int32, []Event) {
+	t.Helper()
+	var runs, requests atomic.Int32
+	tools.RegisterTool(tools.Tool{
+		Name:        "loop_tree",
+		Description: "List a directory",
+		Parameters:  []tools.Parameter{{Name: "path", Type: "string", Description: "Directory", Required: true}},
+		Run: func(map[string]any) (map[string]any, error) {
+			runs.Add(1)
+			return map[string]any{"files": []string{"main.go"}}, nil
+		},
+	})
+	t.Cleanup(func() { tools.UnregisterTool("loop_tree") })
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		n := requests.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		if int(n) <= toolCalls {
+			fmt.Fprintf(w, loopToolCallBody, n)
+			return
+		}
+		fmt.Fprint(w, openAICompletionBody)
+	}))
+	t.Cleanup(srv.Close)
+
+	p, err := NewProvider(OPENAI, ProviderOptions{APIKey: "test", BaseURL: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Log = logr.Discard()
+	p.Retry = RetryPolicy{MaxAttempts: 1}
+	tool, err := tools.GetTool("loop_tree")
+	if err != nil {
+		t.Fatal(err)
+	}
+	chat := p.ChatEvents(ModelOptions{ModelName: "test", LoopDetection: detection}, []*tools.Tool{tool})
+	defer close(chat.Done)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	go chat.SendCtx(ctx, "list the files")
+	var events []Event
+	for {
+		select {
+		case event := <-chat.Events:
+			events = append(events, event)
+			if _, ok := event.(TurnComplete); ok {
+				return runs.Load(), events
+			}
+		case <-ctx.Done():
+			t.Fatal("the turn did not complete")
+		}
+	}
+}
+
+func TestLoopDetectionGuides(t *testing.T) {
+	runs, events := loopChat(t, &LoopDetection{}, 3)
+	if runs != 2 {
+		t.Errorf("tool ran %d times, want 2", runs)
+	}
+	var loops []ToolLoopDetected
+	var results []ToolResult
+	for _, event := range events {
+		switch e := event.(type) {
+		case ToolLoopDetected:
+			loops = append(loops, e)
+		case ToolCallFinished:
+			results = append(results, e.Result)
+		case ErrorEvent:
+			t.Errorf("turn failed: %v", e.Err)
+		case TurnComplete:
+			if e.Text != "hello" {
+				t.Errorf("response %q, want hello", e.Text)
+			}
+		}
+	}
+	if len(loops) != 1 || loops[0].Action != LoopGuide || len(loops[0].Sequence) != 1 || loops[0].Repeats != 3 {
+		t.Fatalf("loop events %+v, want one guided loop of loop_tree", loops)
+	}
+	if len(results) != 3 || !results[2].IsError || !strings.Contains(results[2].Content, "loop detected") {
+		t.Errorf("results %+v, want the third call answered with the loop guidance", results)
+	}
+}
+
+func TestLoopDetectionStops(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		detection *LoopDetection
+		runs      int32
+	}{
+		{"stop", &LoopDetection{Action: LoopStop}, 2},
+		// the model keeps calling after the guidance
+		{"guidance ignored", &LoopDetection{}, 2},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			runs, events := loopChat(t, tc.detection, 100)
+			if runs != tc.runs {
+				t.Errorf("tool ran %d times, want %d", runs, tc.runs)
+			}
+			var turnErr error
+			for _, event := range events {
+				if e, ok := event.(ErrorEvent); ok {
+					turnErr = e.Err
+				}
+			}
+			var loopErr *LoopDetectedError
+			if !errors.Is(turnErr, ErrLoopDetected) || !errors.As(turnErr, &loopErr) || loopErr.Sequence[0].Name != "loop_tree" {
+				t.Errorf("turn error %v, want a LoopDetectedError", turnErr)
+			}
+		})
+	}
+}
+
+func TestRepeatedSequence(t *testing.T) {
+	for _, tc := range []struct {
+		keys []string
+		want int
+	}{
+		{[]string{"a", "a"}, 0},
+		{[]string{"a", "a", "a"}, 1},
+		{[]string{"tree", "read", "tree", "read", "tree", "read"}, 2},
+		{[]string{"tree", "read", "tree", "read", "tree"}, 0},
+		{[]string{"a", "b", "c", "a", "b", "c", "a", "b", "c"}, 3},
+		{[]string{"a", "b", "a", "b", "b", "a", "b"}, 0},
+	} {
+		if got := repeatedSequence(tc.keys, 3, 4); got != tc.want {
+			t.Errorf("repeatedSequence(%v) = %d, want %d", tc.keys, got, tc.want)
+		}
+	}
+}
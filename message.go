@@ -0,0 +1,449 @@
+package genai
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	ollama "github.com/ollama/ollama/api"
+	"github.com/openai/openai-go"
+	gemini "google.golang.org/genai"
+)
+
+// Role is the author of a message
+type Role string
+
+const (
+	RoleSystem    Role = "system"
+	RoleUser      Role = "user"
+	RoleAssistant Role = "assistant"
+	RoleTool      Role = "tool"
+)
+
+// PartType identifies the content held by a Part
+type PartType string
+
+const (
+	TextPart       PartType = "text"
+	ImagePart      PartType = "image"
+	FilePart       PartType = "file"
+	ToolCallPart   PartType = "tool_call"
+	ToolResultPart PartType = "tool_result"
+)
+
+// Message is a provider neutral chat message made of one or more parts
+type Message struct {
+	Role  Role   `json:"role"`
+	Parts []Part `json:"parts"`
+	// Model is the model that generated an assistant message
+	Model string `json:"model,omitempty"`
+}
+
+// Part is a single piece of message content. Only the fields matching Type are set.
+type Part struct {
+	Type PartType `json:"type"`
+	Text string   `json:"text,omitempty"`
+	// MIMEType, Data and URL describe image and file parts
+	MIMEType   string      `json:"mimeType,omitempty"`
+	Data       []byte      `json:"data,omitempty"`
+	URL        string      `json:"url,omitempty"`
+	ToolCall   *ToolCall   `json:"toolCall,omitempty"`
+	ToolResult *ToolResult `json:"toolResult,omitempty"`
+}
+
+// ToolCall is a request from the model to run a tool
+type ToolCall struct {
+	ID        string         `json:"id,omitempty"`
+	Name      string         `json:"name"`
+	Arguments map[string]any `json:"arguments,omitempty"`
+	// RawArguments holds the arguments when the model returned invalid JSON
+	RawArguments string `json:"rawArguments,omitempty"`
+}
+
+// ToolResult is the output of a tool call sent back to the model
+type ToolResult struct {
+	ID      string `json:"id,omitempty"`
+	Name    string `json:"name"`
+	Content string `json:"content"`
+	IsError bool   `json:"isError,omitempty"`
+}
+
+// NewTextMessage creates a message with a single text part
+func NewTextMessage(role Role, text string) Message {
+	return Message{Role: role, Parts: []Part{{Type: TextPart, Text: text}}}
+}
+
+// NewToolResultMessage creates a tool message with a single tool result part
+func NewToolResultMessage(result ToolResult) Message {
+	return Message{Role: RoleTool, Parts: []Part{{Type: ToolResultPart, ToolResult: &result}}}
+}
+
+// NewToolCallMessage creates an assistant message requesting the given tool calls
+func NewToolCallMessage(calls ...ToolCall) Message {
+	message := Message{Role: RoleAssistant}
+	for _, call := range calls {
+		message.Parts = append(message.Parts, Part{Type: ToolCallPart, ToolCall: &call})
+	}
+	return message
+}
+
+// NewImagePart creates an inline image part, use Part{Type: ImagePart, URL: ...} for remote images
+func NewImagePart(mimeType string, data []byte) Part {
+	return Part{Type: ImagePart, MIMEType: mimeType, Data: data}
+}
+
+// NewFilePart creates an inline file part such as a PDF
+func NewFilePart(mimeType string, data []byte) Part {
+	return Part{Type: FilePart, MIMEType: mimeType, Data: data}
+}
+
+// Validate reports messages that can not be sent to a provider, e.g. history that was
+// built by hand or loaded from storage
+func (m Message) Validate() error {
+	switch m.Role {
+	case RoleSystem, RoleUser, RoleAssistant, RoleTool:
+	default:
+		return fmt.Errorf("unknown role %q", m.Role)
+	}
+	for i, part := range m.Parts {
+		switch part.Type {
+		case TextPart:
+		case ImagePart, FilePart:
+			if part.URL == "" && len(part.Data) == 0 {
+				return fmt.Errorf("part %d: %s part has neither data nor a URL", i, part.Type)
+			}
+			if part.URL == "" && part.MIMEType == "" {
+				return fmt.Errorf("part %d: inline %s part has no MIME type", i, part.Type)
+			}
+		case ToolCallPart:
+			if m.Role != RoleAssistant {
+				return fmt.Errorf("part %d: tool calls must be in an assistant message, got %s", i, m.Role)
+			}
+			if part.ToolCall == nil || part.ToolCall.Name == "" {
+				return fmt.Errorf("part %d: tool call has no name", i)
+			}
+		case ToolResultPart:
+			if m.Role != RoleTool {
+				return fmt.Errorf("part %d: tool results must be in a tool message, got %s", i, m.Role)
+			}
+			if part.ToolResult == nil {
+				return fmt.Errorf("part %d: tool result part has no result", i)
+			}
+		default:
+			return fmt.Errorf("part %d: unknown part type %q", i, part.Type)
+		}
+	}
+	return nil
+}
+
+// ValidateHistory validates each message of a conversation
+func ValidateHistory(messages []Message) error {
+	for i, message := range messages {
+		if err := message.Validate(); err != nil {
+			return fmt.Errorf("message %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// Text returns the concatenated text parts of the message
+func (m Message) Text() string {
+	var sb strings.Builder
+	for _, part := range m.Parts {
+		if part.Type == TextPart {
+			sb.WriteString(part.Text)
+		}
+	}
+	return sb.String()
+}
+
+// ToolCalls returns the tool calls requested in the message
+func (m Message) ToolCalls() []ToolCall {
+	var calls []ToolCall
+	for _, part := range m.Parts {
+		if part.Type == ToolCallPart && part.ToolCall != nil {
+			calls = append(calls, *part.ToolCall)
+		}
+	}
+	return calls
+}
+
+// ToolResults returns the tool results carried by the message
+func (m Message) ToolResults() []ToolResult {
+	var results []ToolResult
+	for _, part := range m.Parts {
+		if part.Type == ToolResultPart && part.ToolResult != nil {
+			results = append(results, *part.ToolResult)
+		}
+	}
+	return results
+}
+
+// encodedMessage is the JSON form of a message in messagesToString
+type encodedMessage struct {
+	Role        Role         `json:"role"`
+	Content     string       `json:"content"`
+	ToolCalls   []ToolCall   `json:"toolCalls,omitempty"`
+	ToolResults []ToolResult `json:"toolResults,omitempty"`
+}
+
+// messagesToString encodes the conversation as a JSON array with one object per message
+// holding its role, text, tool calls and tool results. It is used to count tokens and in
+// summarization prompts.
+func messagesToString(messages []Message, includeSystem bool) string {
+	encoded := make([]encodedMessage, 0, len(messages))
+	for _, msg := range messages {
+		if !includeSystem && msg.Role == RoleSystem {
+			continue
+		}
+		encoded = append(encoded, encodedMessage{
+			Role:        msg.Role,
+			Content:     msg.Text(),
+			ToolCalls:   msg.ToolCalls(),
+			ToolResults: msg.ToolResults(),
+		})
+	}
+	var sb strings.Builder
+	encoder := json.NewEncoder(&sb)
+	// the text is read by models and tokenizers, not embedded in HTML
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(encoded); err != nil {
+		// arguments that can not be marshaled are left out
+		sb.Reset()
+		for _, msg := range encoded {
+			for i, call := range msg.ToolCalls {
+				if _, err := json.Marshal(call.Arguments); err != nil {
+					msg.ToolCalls[i].Arguments = nil
+				}
+			}
+		}
+		encoder.Encode(encoded)
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// dataURL encodes inline part data for providers that take images as URLs
+func (p Part) dataURL() string {
+	if p.URL != "" {
+		return p.URL
+	}
+	return fmt.Sprintf("data:%s;base64,%s", p.MIMEType, base64.StdEncoding.EncodeToString(p.Data))
+}
+
+// toOpenAIParams converts messages to OpenAI request messages. Tool results are
+// split into one tool message per result since OpenAI matches them by id.
+func toOpenAIParams(messages []Message) []openai.ChatCompletionMessageParamUnion {
+	var params []openai.ChatCompletionMessageParamUnion
+	for _, msg := range messages {
+		switch msg.Role {
+		case RoleSystem:
+			params = append(params, openai.SystemMessage(msg.Text()))
+		case RoleUser:
+			params = append(params, openAIUserMessage(msg))
+		case RoleAssistant:
+			calls := msg.ToolCalls()
+			if len(calls) == 0 {
+				params = append(params, openai.AssistantMessage(msg.Text()))
+				continue
+			}
+			assistant := openai.ChatCompletionAssistantMessageParam{}
+			if text := msg.Text(); text != "" {
+				assistant.Content.OfString = openai.String(text)
+			}
+			for _, call := range calls {
+				assistant.ToolCalls = append(assistant.ToolCalls, openai.ChatCompletionMessageToolCallParam{
+					ID: call.ID,
+					Function: openai.ChatCompletionMessageToolCallFunctionParam{
+						Name:      call.Name,
+						Arguments: call.argumentsJSON(),
+					},
+				})
+			}
+			params = append(params, openai.ChatCompletionMessageParamUnion{OfAssistant: &assistant})
+		case RoleTool:
+			for _, result := range msg.ToolResults() {
+				params = append(params, openai.ToolMessage(result.Content, result.ID))
+			}
+		}
+	}
+	return params
+}
+
+func openAIUserMessage(msg Message) openai.ChatCompletionMessageParamUnion {
+	if len(msg.Parts) == 1 && msg.Parts[0].Type == TextPart {
+		return openai.UserMessage(msg.Parts[0].Text)
+	}
+	var parts []openai.ChatCompletionContentPartUnionParam
+	for _, part := range msg.Parts {
+		switch part.Type {
+		case TextPart:
+			parts = append(parts, openai.TextContentPart(part.Text))
+		case ImagePart:
+			parts = append(parts, openai.ImageContentPart(openai.ChatCompletionContentPartImageImageURLParam{
+				URL: part.dataURL(),
+			}))
+		case FilePart:
+			parts = append(parts, openai.FileContentPart(openai.ChatCompletionContentPartFileFileParam{
+				FileData: openai.String(part.dataURL()),
+			}))
+		}
+	}
+	return openai.UserMessage(parts)
+}
+
+func (c ToolCall) argumentsJSON() string {
+	if c.Arguments == nil {
+		return c.RawArguments
+	}
+	args, err := json.Marshal(c.Arguments)
+	if err != nil {
+		return c.RawArguments
+	}
+	return string(args)
+}
+
+// fromOpenAIMessage converts an OpenAI response message
+func fromOpenAIMessage(msg openai.ChatCompletionMessage) Message {
+	message := Message{Role: RoleAssistant}
+	if msg.Content != "" {
+		message.Parts = append(message.Parts, Part{Type: TextPart, Text: msg.Content})
+	}
+	for _, call := range msg.ToolCalls {
+		toolCall := &ToolCall{ID: call.ID, Name: call.Function.Name}
+		if err := json.Unmarshal([]byte(call.Function.Arguments), &toolCall.Arguments); err != nil {
+			toolCall.Arguments = nil
+			toolCall.RawArguments = call.Function.Arguments
+		}
+		message.Parts = append(message.Parts, Part{Type: ToolCallPart, ToolCall: toolCall})
+	}
+	return message
+}
+
+// toOllamaMessages converts messages to Ollama chat messages
+func toOllamaMessages(messages []Message) []ollama.Message {
+	var converted []ollama.Message
+	for _, msg := range messages {
+		if msg.Role == RoleTool {
+			for _, result := range msg.ToolResults() {
+				content := result.Content
+				if result.Name != "" {
+					content = fmt.Sprintf("Tool %s returned: %s", result.Name, result.Content)
+				}
+				converted = append(converted, ollama.Message{Role: string(RoleTool), Content: content})
+			}
+			continue
+		}
+		ollamaMsg := ollama.Message{Role: string(msg.Role), Content: msg.Text()}
+		for _, part := range msg.Parts {
+			switch part.Type {
+			case ImagePart:
+				ollamaMsg.Images = append(ollamaMsg.Images, ollama.ImageData(part.Data))
+			case ToolCallPart:
+				ollamaMsg.ToolCalls = append(ollamaMsg.ToolCalls, ollama.ToolCall{
+					Function: ollama.ToolCallFunction{
+						Name:      part.ToolCall.Name,
+						Arguments: part.ToolCall.Arguments,
+					},
+				})
+			}
+		}
+		converted = append(converted, ollamaMsg)
+	}
+	return converted
+}
+
+// fromOllamaMessage converts an Ollama chat message
+func fromOllamaMessage(msg ollama.Message) Message {
+	message := Message{Role: Role(msg.Role)}
+	if msg.Content != "" {
+		message.Parts = append(message.Parts, Part{Type: TextPart, Text: msg.Content})
+	}
+	for _, image := range msg.Images {
+		message.Parts = append(message.Parts, Part{Type: ImagePart, Data: image})
+	}
+	for _, call := range msg.ToolCalls {
+		message.Parts = append(message.Parts, Part{
+			Type:     ToolCallPart,
+			ToolCall: &ToolCall{Name: call.Function.Name, Arguments: call.Function.Arguments},
+		})
+	}
+	return message
+}
+
+// toGeminiContents converts messages to Gemini contents. System messages are
+// skipped since Gemini takes the system prompt as a model level instruction.
+func toGeminiContents(messages []Message) []*gemini.Content {
+	var contents []*gemini.Content
+	for _, msg := range messages {
+		if msg.Role == RoleSystem {
+			continue
+		}
+		role := gemini.RoleUser
+		if msg.Role == RoleAssistant {
+			role = gemini.RoleModel
+		}
+		content := &gemini.Content{Role: role}
+		for _, part := range msg.Parts {
+			if geminiPart := toGeminiPart(part); geminiPart != nil {
+				content.Parts = append(content.Parts, geminiPart)
+			}
+		}
+		contents = append(contents, content)
+	}
+	return contents
+}
+
+func toGeminiPart(part Part) *gemini.Part {
+	switch part.Type {
+	case TextPart:
+		return gemini.NewPartFromText(part.Text)
+	case ImagePart, FilePart:
+		if part.URL != "" {
+			return gemini.NewPartFromURI(part.URL, part.MIMEType)
+		}
+		return gemini.NewPartFromBytes(part.Data, part.MIMEType)
+	case ToolCallPart:
+		return &gemini.Part{FunctionCall: &gemini.FunctionCall{ID: part.ToolCall.ID, Name: part.ToolCall.Name, Args: part.ToolCall.Arguments}}
+	case ToolResultPart:
+		response := map[string]any{}
+		if err := json.Unmarshal([]byte(part.ToolResult.Content), &response); err != nil {
+			response = map[string]any{"content": part.ToolResult.Content}
+		}
+		return &gemini.Part{FunctionResponse: &gemini.FunctionResponse{ID: part.ToolResult.ID, Name: part.ToolResult.Name, Response: response}}
+	}
+	return nil
+}
+
+// fromGeminiContent converts a Gemini content, thought summaries are dropped
+func fromGeminiContent(content *gemini.Content) Message {
+	message := Message{Role: RoleUser}
+	if content.Role == gemini.RoleModel {
+		message.Role = RoleAssistant
+	}
+	for _, p := range content.Parts {
+		switch {
+		case p.Thought:
+			continue
+		case p.FunctionCall != nil:
+			message.Parts = append(message.Parts, Part{
+				Type:     ToolCallPart,
+				ToolCall: &ToolCall{ID: p.FunctionCall.ID, Name: p.FunctionCall.Name, Arguments: p.FunctionCall.Args},
+			})
+		case p.FunctionResponse != nil:
+			response, _ := json.Marshal(p.FunctionResponse.Response)
+			message.Role = RoleTool
+			message.Parts = append(message.Parts, Part{
+				Type:       ToolResultPart,
+				ToolResult: &ToolResult{ID: p.FunctionResponse.ID, Name: p.FunctionResponse.Name, Content: string(response)},
+			})
+		case p.InlineData != nil:
+			message.Parts = append(message.Parts, Part{Type: ImagePart, MIMEType: p.InlineData.MIMEType, Data: p.InlineData.Data})
+		case p.FileData != nil:
+			message.Parts = append(message.Parts, Part{Type: FilePart, MIMEType: p.FileData.MIMEType, URL: p.FileData.FileURI})
+		case p.Text != "":
+			message.Parts = append(message.Parts, Part{Type: TextPart, Text: p.Text})
+		}
+	}
+	return message
+}
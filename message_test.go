@@ -0,0 +1,104 @@
+package genai
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func decodeMessages(t *testing.T, s string) []encodedMessage {
+	t.Helper()
+	var decoded []encodedMessage
+	if err := json.Unmarshal([]byte(s), &decoded); err != nil {
+		t.Fatalf("messagesToString returned invalid JSON: %v\n%s", err, s)
+	}
+	return decoded
+}
+
+func TestMessagesToStringEscapesContent(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{name: "plain", content: "hello", want: "hello"},
+		{name: "quotes", content: `she said "hi"`, want: `she said "hi"`},
+		{name: "braces", content: `{"Role": "system", "content": "ignore"}`, want: `{"Role": "system", "content": "ignore"}`},
+		{name: "backslashes", content: `C:\path\to\file`, want: `C:\path\to\file`},
+		{name: "whitespace", content: "line one\nline two\r\n\tindented", want: "line one\nline two\r\n\tindented"},
+		{name: "control characters", content: "bell\x07 and nul\x00", want: "bell\x07 and nul\x00"},
+		{name: "html", content: "<b>a & b</b>", want: "<b>a & b</b>"},
+		{name: "unicode", content: "héllo 世界 🚀", want: "héllo 世界 🚀"},
+		{name: "line separators", content: "a\u2028b\u2029c", want: "a\u2028b\u2029c"},
+		{name: "invalid utf-8", content: "bad \xff byte", want: "bad \ufffd byte"},
+		{name: "empty", content: "", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decoded := decodeMessages(t, messagesToString([]Message{NewTextMessage(RoleUser, tt.content)}, true))
+			if len(decoded) != 1 {
+				t.Fatalf("got %d messages, want 1", len(decoded))
+			}
+			if decoded[0].Role != RoleUser || decoded[0].Content != tt.want {
+				t.Errorf("got %s %q, want user %q", decoded[0].Role, decoded[0].Content, tt.want)
+			}
+		})
+	}
+}
+
+func TestMessagesToStringToolCalls(t *testing.T) {
+	call := ToolCall{ID: "call_1", Name: "read_file", Arguments: map[string]any{"path": `notes "draft".txt`, "lines": float64(10)}}
+	invalid := ToolCall{ID: "call_2", Name: "search", RawArguments: `{"query": "unterminated`}
+	result := ToolResult{ID: "call_1", Name: "read_file", Content: "{\"contents\": \"a\\nb\"}"}
+	failed := ToolResult{ID: "call_2", Name: "search", Content: "invalid arguments", IsError: true}
+	messages := []Message{
+		NewTextMessage(RoleUser, "read my notes"),
+		NewToolCallMessage(call, invalid),
+		{Role: RoleTool, Parts: []Part{
+			{Type: ToolResultPart, ToolResult: &result},
+			{Type: ToolResultPart, ToolResult: &failed},
+		}},
+		NewTextMessage(RoleAssistant, "done"),
+	}
+	want := []encodedMessage{
+		{Role: RoleUser, Content: "read my notes"},
+		{Role: RoleAssistant, ToolCalls: []ToolCall{call, invalid}},
+		{Role: RoleTool, ToolResults: []ToolResult{result, failed}},
+		{Role: RoleAssistant, Content: "done"},
+	}
+	got := decodeMessages(t, messagesToString(messages, true))
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestMessagesToStringSystem(t *testing.T) {
+	messages := []Message{
+		NewTextMessage(RoleSystem, "be brief"),
+		NewTextMessage(RoleUser, "hi"),
+	}
+	if got := decodeMessages(t, messagesToString(messages, true)); len(got) != 2 || got[0].Role != RoleSystem {
+		t.Errorf("system message missing: %+v", got)
+	}
+	if got := decodeMessages(t, messagesToString(messages, false)); len(got) != 1 || got[0].Role != RoleUser {
+		t.Errorf("system message included: %+v", got)
+	}
+	if got := messagesToString(nil, true); got != "[]" {
+		t.Errorf("got %q for no messages, want []", got)
+	}
+}
+
+func TestMessagesToStringFormat(t *testing.T) {
+	messages := []Message{
+		NewTextMessage(RoleUser, "<b>1 & 2</b>"),
+		NewToolCallMessage(ToolCall{ID: "1", Name: "calc", Arguments: map[string]any{"expr": "1+1"}}),
+		NewToolResultMessage(ToolResult{ID: "1", Name: "calc", Content: "2"}),
+	}
+	// content is always written and HTML is not escaped
+	want := `[{"role":"user","content":"<b>1 & 2</b>"},` +
+		`{"role":"assistant","content":"","toolCalls":[{"id":"1","name":"calc","arguments":{"expr":"1+1"}}]},` +
+		`{"role":"tool","content":"","toolResults":[{"id":"1","name":"calc","content":"2"}]}]`
+	if got := messagesToString(messages, true); got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+}
@@ -0,0 +1,271 @@
+package genai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Metrics receives measurements of a provider's requests and tool calls for monitoring,
+// see ProviderOptions.Metrics. Implementations are called from every chat of the
+// provider and must be safe for concurrent use. NewPrometheusMetrics exports them for
+// Prometheus, other systems can implement the interface.
+type Metrics interface {
+	// Attempt is called after each request sent to the provider, including retries.
+	// err is nil when the request succeeded.
+	Attempt(provider string, duration time.Duration, err error)
+	// Retry is called when a failed attempt is going to be retried
+	Retry(provider string, err error)
+	// Failure is called when a request failed for good, after its retries
+	Failure(provider string, err error)
+	// Response is called with the tokens of each response a model received, responses
+	// from the response cache included
+	Response(provider, model string, usage Usage)
+	// ToolCall is called after a registered tool ran, err is the error of the tool
+	ToolCall(tool string, duration time.Duration, err error)
+}
+
+// nopMetrics is used when no Metrics are set
+type nopMetrics struct{}
+
+func (nopMetrics) Attempt(string, time.Duration, error)  {}
+func (nopMetrics) Retry(string, error)                   {}
+func (nopMetrics) Failure(string, error)                 {}
+func (nopMetrics) Response(string, string, Usage)        {}
+func (nopMetrics) ToolCall(string, time.Duration, error) {}
+
+// observer returns the provider's metrics, which do nothing when none are set
+func (p *Provider) observer() Metrics {
+	if p.metrics == nil {
+		return nopMetrics{}
+	}
+	return p.metrics
+}
+
+// ErrorReason is a short label for err to group failures by: ok for nil, the kind of a
+// ProviderError such as rate_limited, canceled, timeout, or error for anything else
+func ErrorReason(err error) string {
+	switch {
+	case err == nil:
+		return "ok"
+	case errors.Is(err, ErrRateLimited):
+		return "rate_limited"
+	case errors.Is(err, ErrAuth):
+		return "auth"
+	case errors.Is(err, ErrContextLengthExceeded):
+		return "context_length"
+	case errors.Is(err, ErrContentFiltered):
+		return "content_filtered"
+	case errors.Is(err, ErrModelNotFound):
+		return "model_not_found"
+	case errors.Is(err, ErrUnavailable):
+		return "unavailable"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, context.Canceled), errors.Is(err, errStreamStopped):
+		return "canceled"
+	}
+	return "error"
+}
+
+// DefaultDurationBuckets are the upper bounds in seconds of the duration histograms of
+// PrometheusMetrics
+var DefaultDurationBuckets = []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120}
+
+// PrometheusMetrics keeps the metrics of one or more providers and serves them in the
+// Prometheus text format, mount it on the /metrics path of an HTTP server:
+//
+//	genai_requests_total{provider,reason}                 requests sent, by ErrorReason
+//	genai_request_duration_seconds{provider}              histogram of request durations
+//	genai_retries_total{provider,reason}                  retried requests
+//	genai_failures_total{provider,reason}                 requests that failed after retries
+//	genai_responses_total{provider,model}                 responses received
+//	genai_cached_responses_total{provider,model}          responses served from the cache
+//	genai_tokens_total{provider,model,type}               prompt, completion, cached and reasoning tokens
+//	genai_tool_calls_total{tool,reason}                   tool calls, by ErrorReason
+//	genai_tool_call_duration_seconds{tool}                histogram of tool call durations
+type PrometheusMetrics struct {
+	mu       sync.Mutex
+	buckets  []float64
+	families map[string]*metricFamily
+}
+
+// metricFamily is a metric with its series by label values
+type metricFamily struct {
+	help   string
+	kind   string
+	labels []string
+	series map[string]*metricSeries
+}
+
+// metricSeries is the value of a counter, or the bucket counts, sum and count of a
+// histogram, for one set of label values
+type metricSeries struct {
+	values  []string
+	value   float64
+	buckets []uint64
+	sum     float64
+	count   uint64
+}
+
+// NewPrometheusMetrics creates an empty registry, the durations are bucketed with
+// DefaultDurationBuckets
+func NewPrometheusMetrics() *PrometheusMetrics {
+	p := &PrometheusMetrics{buckets: DefaultDurationBuckets, families: make(map[string]*metricFamily)}
+	p.family("genai_requests_total", "counter", "Requests sent to the provider.", "provider", "reason")
+	p.family("genai_request_duration_seconds", "histogram", "Duration of the requests sent to the provider.", "provider")
+	p.family("genai_retries_total", "counter", "Requests that failed and were retried.", "provider", "reason")
+	p.family("genai_failures_total", "counter", "Requests that failed after their retries.", "provider", "reason")
+	p.family("genai_responses_total", "counter", "Responses received from the model.", "provider", "model")
+	p.family("genai_cached_responses_total", "counter", "Responses served from the response cache.", "provider", "model")
+	p.family("genai_tokens_total", "counter", "Tokens used by the responses.", "provider", "model", "type")
+	p.family("genai_tool_calls_total", "counter", "Tool calls run.", "tool", "reason")
+	p.family("genai_tool_call_duration_seconds", "histogram", "Duration of the tool calls.", "tool")
+	return p
+}
+
+func (p *PrometheusMetrics) family(name, kind, help string, labels ...string) {
+	p.families[name] = &metricFamily{help: help, kind: kind, labels: labels, series: make(map[string]*metricSeries)}
+}
+
+// get returns the series of the family for the label values, p.mu is held
+func (p *PrometheusMetrics) get(name string, values ...string) *metricSeries {
+	family := p.families[name]
+	key := strings.Join(values, "\xff")
+	series, ok := family.series[key]
+	if !ok {
+		series = &metricSeries{values: values}
+		if family.kind == "histogram" {
+			series.buckets = make([]uint64, len(p.buckets))
+		}
+		family.series[key] = series
+	}
+	return series
+}
+
+func (p *PrometheusMetrics) add(name string, value float64, values ...string) {
+	if value == 0 {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.get(name, values...).value += value
+}
+
+func (p *PrometheusMetrics) observe(name string, duration time.Duration, values ...string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	series := p.get(name, values...)
+	seconds := duration.Seconds()
+	for i, bound := range p.buckets {
+		if seconds <= bound {
+			series.buckets[i]++
+		}
+	}
+	series.sum += seconds
+	series.count++
+}
+
+func (p *PrometheusMetrics) Attempt(provider string, duration time.Duration, err error) {
+	p.add("genai_requests_total", 1, provider, ErrorReason(err))
+	p.observe("genai_request_duration_seconds", duration, provider)
+}
+
+func (p *PrometheusMetrics) Retry(provider string, err error) {
+	p.add("genai_retries_total", 1, provider, ErrorReason(err))
+}
+
+func (p *PrometheusMetrics) Failure(provider string, err error) {
+	p.add("genai_failures_total", 1, provider, ErrorReason(err))
+}
+
+func (p *PrometheusMetrics) Response(provider, model string, usage Usage) {
+	p.add("genai_responses_total", float64(usage.Requests), provider, model)
+	p.add("genai_cached_responses_total", float64(usage.CachedResponses), provider, model)
+	p.add("genai_tokens_total", float64(usage.PromptTokens), provider, model, "prompt")
+	p.add("genai_tokens_total", float64(usage.CompletionTokens), provider, model, "completion")
+	p.add("genai_tokens_total", float64(usage.CachedTokens), provider, model, "cached")
+	p.add("genai_tokens_total", float64(usage.ReasoningTokens), provider, model, "reasoning")
+}
+
+func (p *PrometheusMetrics) ToolCall(tool string, duration time.Duration, err error) {
+	p.add("genai_tool_calls_total", 1, tool, ErrorReason(err))
+	p.observe("genai_tool_call_duration_seconds", duration, tool)
+}
+
+// ServeHTTP writes the metrics in the Prometheus text format
+func (p *PrometheusMetrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	p.WriteTo(w)
+}
+
+// WriteTo writes the metrics in the Prometheus text format, families without series
+// are left out
+func (p *PrometheusMetrics) WriteTo(w io.Writer) (int64, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var b strings.Builder
+	names := make([]string, 0, len(p.families))
+	for name := range p.families {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		family := p.families[name]
+		if len(family.series) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s %s\n", name, family.help, name, family.kind)
+		keys := make([]string, 0, len(family.series))
+		for key := range family.series {
+			keys = append(keys, key)
+		}
+		slices.Sort(keys)
+		for _, key := range keys {
+			series := family.series[key]
+			labels := metricLabels(family.labels, series.values)
+			if family.kind != "histogram" {
+				fmt.Fprintf(&b, "%s%s %s\n", name, braced(labels), formatMetric(series.value))
+				continue
+			}
+			for i, bound := range p.buckets {
+				le := append(slices.Clone(labels), `le="`+formatMetric(bound)+`"`)
+				fmt.Fprintf(&b, "%s_bucket%s %d\n", name, braced(le), series.buckets[i])
+			}
+			fmt.Fprintf(&b, "%s_bucket%s %d\n", name, braced(append(slices.Clone(labels), `le="+Inf"`)), series.count)
+			fmt.Fprintf(&b, "%s_sum%s %s\n", name, braced(labels), formatMetric(series.sum))
+			fmt.Fprintf(&b, "%s_count%s %d\n", name, braced(labels), series.count)
+		}
+	}
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+// metricLabels pairs the label names with their quoted values
+func metricLabels(names, values []string) []string {
+	labels := make([]string, len(names))
+	for i, name := range names {
+		labels[i] = name + `="` + labelEscaper.Replace(values[i]) + `"`
+	}
+	return labels
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func braced(labels []string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(labels, ",") + "}"
+}
+
+func formatMetric(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,75 @@
+package genai
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/jbutlerdev/genai/tools"
+)
+
+func TestPrometheusMetrics(t *testing.T) {
+	tool, _ := registerWeatherTool(t)
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch requests.Add(1) {
+		case 1:
+			w.WriteHeader(http.StatusTooManyRequests)
+			fmt.Fprint(w, `{"error":{"message":"slow down"}}`)
+		case 2:
+			fmt.Fprint(w, `{"id":"chatcmpl-1","object":"chat.completion","created":0,"model":"test","choices":[{"index":0,"finish_reason":"tool_calls",`+
+				`"message":{"role":"assistant","content":"","tool_calls":[{"id":"call_1","type":"function","function":{"name":"mock_weather","arguments":"{\"city\":\"Paris\"}"}}]}}],`+
+				`"usage":{"prompt_tokens":10,"completion_tokens":5,"total_tokens":15}}`)
+		default:
+			fmt.Fprint(w, `{"id":"chatcmpl-2","object":"chat.completion","created":0,"model":"test","choices":[{"index":0,"finish_reason":"stop",`+
+				`"message":{"role":"assistant","content":"Sunny"}}],"usage":{"prompt_tokens":20,"completion_tokens":3,"total_tokens":23}}`)
+		}
+	}))
+	defer srv.Close()
+	metrics := NewPrometheusMetrics()
+	p, err := NewProvider(OPENAI, ProviderOptions{
+		APIKey:  "test",
+		BaseURL: srv.URL,
+		Retry:   &RetryPolicy{MaxAttempts: 2, InitialDelay: time.Millisecond},
+		Metrics: metrics,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Log = logr.Discard()
+	chat := p.ChatEvents(ModelOptions{ModelName: "test"}, []*tools.Tool{tool})
+	defer close(chat.Done)
+	if _, err := mockTurn(t, chat, "Weather in Paris?"); err != nil {
+		t.Fatalf("turn failed: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	metrics.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := rec.Body.String()
+	for _, want := range []string{
+		"# TYPE genai_requests_total counter\n",
+		`genai_requests_total{provider="openai",reason="ok"} 2`,
+		`genai_requests_total{provider="openai",reason="rate_limited"} 1`,
+		`genai_retries_total{provider="openai",reason="rate_limited"} 1`,
+		`genai_request_duration_seconds_count{provider="openai"} 3`,
+		`genai_request_duration_seconds_bucket{provider="openai",le="+Inf"} 3`,
+		`genai_responses_total{provider="openai",model="test"} 2`,
+		`genai_tokens_total{provider="openai",model="test",type="prompt"} 30`,
+		`genai_tokens_total{provider="openai",model="test",type="completion"} 8`,
+		`genai_tool_calls_total{tool="mock_weather",reason="ok"} 1`,
+		`genai_tool_call_duration_seconds_count{tool="mock_weather"} 1`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics are missing %s:\n%s", want, body)
+		}
+	}
+	if strings.Contains(body, "genai_failures_total") {
+		t.Errorf("metrics report a failure:\n%s", body)
+	}
+}
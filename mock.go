@@ -0,0 +1,374 @@
+package genai
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"net/http"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// MockEmbeddingDims is the size of the embeddings of a MockProvider
+const MockEmbeddingDims = 64
+
+// MockResponse is a scripted response of a MockProvider
+type MockResponse struct {
+	// Text is the content of the response
+	Text string
+	// ToolCalls are requested from the chat, calls without ID are numbered
+	ToolCalls []ToolCall
+	// Error fails the request with a client error instead
+	Error string
+}
+
+// MockRequest is a request a MockProvider received
+type MockRequest struct {
+	Model    string
+	Messages []Message
+	// Tools are the names of the tools the model was given
+	Tools []string
+	// ToolChoice is the tool_choice of the request, auto, none, required or the name of
+	// a function, empty when it was not set
+	ToolChoice string
+}
+
+// MockProvider is an OpenAI provider answering from a script instead of a server, so
+// agent logic can be tested without API keys or network access. Each chat completion
+// takes the next response of the script and fails when there is none left. Embeddings
+// are computed from the words of the text, texts sharing words are similar.
+//
+//	p, _ := genai.NewMockProvider(
+//		genai.MockResponse{ToolCalls: []genai.ToolCall{{Name: "readFile", Arguments: map[string]any{"path": "go.mod"}}}},
+//		genai.MockResponse{Text: "The module is example.com/app"},
+//	)
+//	chat := p.Chat(genai.ModelOptions{ModelName: "mock"}, tools)
+type MockProvider struct {
+	*Provider
+	// Embed replaces the embeddings computed from the words of a text
+	Embed func(text string) []float32
+
+	mu       sync.Mutex
+	script   []MockResponse
+	requests []MockRequest
+	calls    int
+}
+
+// NewMockProvider creates a provider answering with responses in order, requests are
+// not retried
+func NewMockProvider(responses ...MockResponse) (*MockProvider, error) {
+	m := &MockProvider{script: responses}
+	p, err := NewProvider(OPENAI, ProviderOptions{
+		Name:       "mock",
+		APIKey:     "mock",
+		BaseURL:    "http://mock.invalid/v1",
+		HTTPClient: &http.Client{Transport: mockTransport{m}},
+		Retry:      &RetryPolicy{MaxAttempts: 1},
+	})
+	if err != nil {
+		return nil, err
+	}
+	m.Provider = p
+	return m, nil
+}
+
+// Script appends responses to the script
+func (m *MockProvider) Script(responses ...MockResponse) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.script = append(m.script, responses...)
+}
+
+// Pending returns the number of responses left in the script
+func (m *MockProvider) Pending() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.script)
+}
+
+// Requests returns the requests received so far
+func (m *MockProvider) Requests() []MockRequest {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]MockRequest(nil), m.requests...)
+}
+
+// next records a request and takes the next response of the script
+func (m *MockProvider) next(req MockRequest) (MockResponse, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.requests = append(m.requests, req)
+	if len(m.script) == 0 {
+		return MockResponse{}, false
+	}
+	resp := m.script[0]
+	m.script = m.script[1:]
+	return resp, true
+}
+
+// mockTransport serves the OpenAI chat completions API from the script of a MockProvider
+type mockTransport struct {
+	mock *MockProvider
+}
+
+// mockChatRequest is the part of an OpenAI chat completion request the mock reads
+type mockChatRequest struct {
+	Model    string `json:"model"`
+	Stream   bool   `json:"stream"`
+	Messages []struct {
+		Role       string          `json:"role"`
+		Content    json.RawMessage `json:"content"`
+		ToolCallID string          `json:"tool_call_id"`
+		ToolCalls  []struct {
+			ID       string `json:"id"`
+			Function struct {
+				Name      string `json:"name"`
+				Arguments string `json:"arguments"`
+			} `json:"function"`
+		} `json:"tool_calls"`
+	} `json:"messages"`
+	Tools []struct {
+		Function struct {
+			Name string `json:"name"`
+		} `json:"function"`
+	} `json:"tools"`
+	ToolChoice json.RawMessage `json:"tool_choice"`
+}
+
+func (t mockTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		defer req.Body.Close()
+	}
+	switch {
+	case strings.HasSuffix(req.URL.Path, "/chat/completions"):
+		return t.chat(req)
+	case strings.HasSuffix(req.URL.Path, "/embeddings"):
+		return t.embeddings(req)
+	}
+	return mockResponse(req, http.StatusNotFound, "application/json",
+		mockError(fmt.Sprintf("the mock provider does not serve %s", req.URL.Path))), nil
+}
+
+// chat answers a chat completion with the next response of the script
+func (t mockTransport) chat(req *http.Request) (*http.Response, error) {
+	var chatReq mockChatRequest
+	if err := json.NewDecoder(req.Body).Decode(&chatReq); err != nil {
+		return mockResponse(req, http.StatusBadRequest, "application/json", mockError(err.Error())), nil
+	}
+	received := chatReq.messages()
+	resp, ok := t.mock.next(received)
+	if !ok {
+		return mockResponse(req, http.StatusBadRequest, "application/json", mockError("the mock script has no response left")), nil
+	}
+	if resp.Error != "" {
+		return mockResponse(req, http.StatusBadRequest, "application/json", mockError(resp.Error)), nil
+	}
+
+	prompt := 0
+	for _, msg := range received.Messages {
+		prompt += estimateTokens(msg.Text())
+	}
+	completion := estimateTokens(resp.Text)
+	usage := map[string]any{
+		"prompt_tokens":     prompt,
+		"completion_tokens": completion,
+		"total_tokens":      prompt + completion,
+	}
+	message := map[string]any{"role": "assistant", "content": resp.Text}
+	finish := "stop"
+	if len(resp.ToolCalls) > 0 {
+		finish = "tool_calls"
+		message["tool_calls"] = t.mock.toolCalls(resp.ToolCalls)
+	}
+	id := fmt.Sprintf("mock-%d", len(t.mock.Requests()))
+	if chatReq.Stream {
+		var body bytes.Buffer
+		for _, chunk := range []map[string]any{
+			{"id": id, "object": "chat.completion.chunk", "model": chatReq.Model,
+				"choices": []any{map[string]any{"index": 0, "delta": message}}},
+			{"id": id, "object": "chat.completion.chunk", "model": chatReq.Model,
+				"choices": []any{map[string]any{"index": 0, "delta": map[string]any{}, "finish_reason": finish}}, "usage": usage},
+		} {
+			data, _ := json.Marshal(chunk)
+			fmt.Fprintf(&body, "data: %s\n\n", data)
+		}
+		body.WriteString("data: [DONE]\n\n")
+		return mockResponse(req, http.StatusOK, "text/event-stream", body.Bytes()), nil
+	}
+	data, err := json.Marshal(map[string]any{
+		"id":      id,
+		"object":  "chat.completion",
+		"model":   chatReq.Model,
+		"choices": []any{map[string]any{"index": 0, "finish_reason": finish, "message": message}},
+		"usage":   usage,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return mockResponse(req, http.StatusOK, "application/json", data), nil
+}
+
+// embeddings answers an embeddings request with the embeddings of the inputs
+func (t mockTransport) embeddings(req *http.Request) (*http.Response, error) {
+	var embedReq struct {
+		Model string          `json:"model"`
+		Input json.RawMessage `json:"input"`
+	}
+	if err := json.NewDecoder(req.Body).Decode(&embedReq); err != nil {
+		return mockResponse(req, http.StatusBadRequest, "application/json", mockError(err.Error())), nil
+	}
+	var inputs []string
+	if err := json.Unmarshal(embedReq.Input, &inputs); err != nil {
+		var input string
+		if err := json.Unmarshal(embedReq.Input, &input); err != nil {
+			return mockResponse(req, http.StatusBadRequest, "application/json", mockError("input must be a string or an array of strings")), nil
+		}
+		inputs = []string{input}
+	}
+	embed := t.mock.Embed
+	if embed == nil {
+		embed = mockEmbedding
+	}
+	data := make([]any, len(inputs))
+	tokens := 0
+	for i, input := range inputs {
+		data[i] = map[string]any{"object": "embedding", "index": i, "embedding": embed(input)}
+		tokens += estimateTokens(input)
+	}
+	body, err := json.Marshal(map[string]any{
+		"object": "list",
+		"model":  embedReq.Model,
+		"data":   data,
+		"usage":  map[string]any{"prompt_tokens": tokens, "total_tokens": tokens},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return mockResponse(req, http.StatusOK, "application/json", body), nil
+}
+
+// mockEmbedding hashes the words of text into a normalized vector of MockEmbeddingDims
+func mockEmbedding(text string) []float32 {
+	embedding := make([]float32, MockEmbeddingDims)
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	for _, word := range words {
+		h := fnv.New32a()
+		h.Write([]byte(word))
+		embedding[h.Sum32()%MockEmbeddingDims]++
+	}
+	var norm float64
+	for _, v := range embedding {
+		norm += float64(v * v)
+	}
+	if norm == 0 {
+		return embedding
+	}
+	norm = math.Sqrt(norm)
+	for i := range embedding {
+		embedding[i] = float32(float64(embedding[i]) / norm)
+	}
+	return embedding
+}
+
+// toolCalls renders scripted tool calls in the OpenAI format, numbering calls without ID
+func (m *MockProvider) toolCalls(calls []ToolCall) []any {
+	rendered := make([]any, len(calls))
+	for i, call := range calls {
+		id := call.ID
+		if id == "" {
+			m.mu.Lock()
+			m.calls++
+			id = fmt.Sprintf("call_%d", m.calls)
+			m.mu.Unlock()
+		}
+		rendered[i] = map[string]any{
+			"id":       id,
+			"type":     "function",
+			"function": map[string]any{"name": call.Name, "arguments": call.argumentsJSON()},
+		}
+	}
+	return rendered
+}
+
+// messages converts the request to the messages of a MockRequest
+func (r mockChatRequest) messages() MockRequest {
+	req := MockRequest{Model: r.Model}
+	if json.Unmarshal(r.ToolChoice, &req.ToolChoice) != nil {
+		var named struct {
+			Function struct {
+				Name string `json:"name"`
+			} `json:"function"`
+		}
+		if json.Unmarshal(r.ToolChoice, &named) == nil {
+			req.ToolChoice = named.Function.Name
+		}
+	}
+	for _, tool := range r.Tools {
+		req.Tools = append(req.Tools, tool.Function.Name)
+	}
+	for _, msg := range r.Messages {
+		text := mockContent(msg.Content)
+		switch Role(msg.Role) {
+		case RoleTool:
+			req.Messages = append(req.Messages, NewToolResultMessage(ToolResult{ID: msg.ToolCallID, Content: text}))
+		case RoleAssistant:
+			message := Message{Role: RoleAssistant}
+			if text != "" {
+				message.Parts = append(message.Parts, Part{Type: TextPart, Text: text})
+			}
+			for _, call := range msg.ToolCalls {
+				toolCall := ToolCall{ID: call.ID, Name: call.Function.Name}
+				if err := json.Unmarshal([]byte(call.Function.Arguments), &toolCall.Arguments); err != nil {
+					toolCall.RawArguments = call.Function.Arguments
+				}
+				message.Parts = append(message.Parts, Part{Type: ToolCallPart, ToolCall: &toolCall})
+			}
+			req.Messages = append(req.Messages, message)
+		default:
+			req.Messages = append(req.Messages, NewTextMessage(Role(msg.Role), text))
+		}
+	}
+	return req
+}
+
+// mockContent reads message content sent as a string or as an array of parts
+func mockContent(content json.RawMessage) string {
+	var text string
+	if json.Unmarshal(content, &text) == nil {
+		return text
+	}
+	var parts []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	}
+	if json.Unmarshal(content, &parts) != nil {
+		return ""
+	}
+	var texts []string
+	for _, part := range parts {
+		if part.Type == "text" {
+			texts = append(texts, part.Text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+func mockError(message string) []byte {
+	data, _ := json.Marshal(map[string]any{
+		"error": map[string]any{"message": message, "type": "invalid_request_error"},
+	})
+	return data
+}
+
+func mockResponse(req *http.Request, status int, contentType string, body []byte) *http.Response {
+	return RecordedResponse{
+		Status:  status,
+		Headers: map[string]string{"Content-Type": contentType},
+		Body:    string(body),
+	}.httpResponse(req)
+}
@@ -0,0 +1,237 @@
+package genai
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/jbutlerdev/genai/tools"
+)
+
+// mockTurn sends prompt to a chat and waits for the response or the error of the turn
+func mockTurn(t *testing.T, chat *Chat, prompt string) (string, error) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	go chat.SendCtx(ctx, prompt)
+	var turnErr error
+	for {
+		select {
+		case event := <-chat.Events:
+			switch e := event.(type) {
+			case ErrorEvent:
+				turnErr = e.Err
+			case TurnComplete:
+				return e.Text, turnErr
+			}
+		case <-ctx.Done():
+			t.Fatal("the turn did not complete")
+		}
+	}
+}
+
+// registerWeatherTool registers mock_weather, which counts its runs
+func registerWeatherTool(t *testing.T) (*tools.Tool, *atomic.Int32) {
+	t.Helper()
+	var runs atomic.Int32
+	tools.RegisterTool(tools.Tool{
+		Name:        "mock_weather",
+		Description: "Get the weather of a city",
+		Parameters:  []tools.Parameter{{Name: "city", Type: "string", Description: "City", Required: true}},
+		Run: func(args map[string]any) (map[string]any, error) {
+			runs.Add(1)
+			return map[string]any{"city": args["city"], "forecast": "sunny"}, nil
+		},
+	})
+	t.Cleanup(func() { tools.UnregisterTool("mock_weather") })
+	tool, err := tools.GetTool("mock_weather")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tool, &runs
+}
+
+// weatherCall is a scripted call of mock_weather
+func weatherCall(city string) MockResponse {
+	return MockResponse{ToolCalls: []ToolCall{{Name: "mock_weather", Arguments: map[string]any{"city": city}}}}
+}
+
+func TestMockProviderChat(t *testing.T) {
+	tool, runs := registerWeatherTool(t)
+	p, err := NewMockProvider(
+		weatherCall("Paris"),
+		MockResponse{Text: "It is sunny in Paris"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chat := p.ChatEvents(ModelOptions{ModelName: "mock", SystemPrompt: "Be brief"}, []*tools.Tool{tool})
+	defer close(chat.Done)
+	text, err := mockTurn(t, chat, "Weather in Paris?")
+	if err != nil {
+		t.Fatalf("turn failed: %v", err)
+	}
+	if text != "It is sunny in Paris" {
+		t.Errorf("response %q", text)
+	}
+	if runs.Load() != 1 {
+		t.Errorf("tool ran %d times, want 1", runs.Load())
+	}
+	if p.Pending() != 0 {
+		t.Errorf("%d responses left", p.Pending())
+	}
+
+	requests := p.Requests()
+	if len(requests) != 2 {
+		t.Fatalf("%d requests, want 2", len(requests))
+	}
+	first := requests[0]
+	if len(first.Tools) != 1 || first.Tools[0] != "mock_weather" {
+		t.Errorf("tools %v, want mock_weather", first.Tools)
+	}
+	if first.Messages[0].Role != RoleSystem || first.Messages[len(first.Messages)-1].Text() != "Weather in Paris?" {
+		t.Errorf("first request %+v", first.Messages)
+	}
+	last := requests[1].Messages
+	calls := last[len(last)-2].ToolCalls()
+	results := last[len(last)-1].ToolResults()
+	if len(calls) != 1 || calls[0].Arguments["city"] != "Paris" {
+		t.Errorf("tool calls %+v", calls)
+	}
+	if len(results) != 1 || results[0].ID != calls[0].ID || !strings.Contains(results[0].Content, "sunny") {
+		t.Errorf("tool results %+v", results)
+	}
+}
+
+func TestMockProviderGenerate(t *testing.T) {
+	p, err := NewMockProvider(MockResponse{Text: "one"}, MockResponse{Error: "overloaded"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Log = logr.Discard()
+	opts := ModelOptions{ModelName: "mock"}
+	text, usage, err := p.GenerateWithUsage(opts, "first")
+	if err != nil || text != "one" {
+		t.Fatalf("Generate = %q, %v", text, err)
+	}
+	if usage.Requests != 1 {
+		t.Errorf("usage %+v, want one request", usage)
+	}
+	if _, err := p.Generate(opts, "second"); err == nil || !strings.Contains(err.Error(), "overloaded") {
+		t.Errorf("scripted error returned %v", err)
+	}
+	if _, err := p.Generate(opts, "third"); err == nil {
+		t.Error("request after the end of the script succeeded")
+	}
+
+	p.Script(MockResponse{Text: "streamed"})
+	var streamed strings.Builder
+	for chunk, err := range p.Stream(context.Background(), opts, "fourth") {
+		if err != nil {
+			t.Fatal(err)
+		}
+		streamed.WriteString(chunk.Text)
+	}
+	if streamed.String() != "streamed" {
+		t.Errorf("streamed %q", streamed.String())
+	}
+}
+
+func TestMockProviderMaxTurns(t *testing.T) {
+	tool, runs := registerWeatherTool(t)
+	p, err := NewMockProvider(weatherCall("Paris"), weatherCall("Lyon"), weatherCall("Nice"), MockResponse{Text: "Sunny everywhere"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	chat := p.ChatEvents(ModelOptions{ModelName: "mock", MaxTurns: 2}, []*tools.Tool{tool})
+	defer close(chat.Done)
+	text, err := mockTurn(t, chat, "Weather in France?")
+	if err != nil {
+		t.Fatalf("turn failed: %v", err)
+	}
+	if text != "Sunny everywhere" {
+		t.Errorf("response %q", text)
+	}
+	if runs.Load() != 3 {
+		t.Errorf("tool ran %d times, want 3", runs.Load())
+	}
+	requests := p.Requests()
+	if len(requests) != 4 {
+		t.Fatalf("%d requests, want 4", len(requests))
+	}
+	// the request after the last turn has to be answered without tools
+	if final := requests[len(requests)-1]; final.ToolChoice != "none" {
+		t.Errorf("final request has tool choice %q, want none", final.ToolChoice)
+	}
+	if choice := requests[0].ToolChoice; choice != "" {
+		t.Errorf("first request has tool choice %q", choice)
+	}
+}
+
+func TestMockProviderCompaction(t *testing.T) {
+	p, err := NewMockProvider(MockResponse{Text: "The user asked about Paris"}, MockResponse{Text: "Lyon is nice too"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	history := []Message{
+		NewTextMessage(RoleUser, strings.Repeat("Tell me about Paris. ", 20)),
+		NewTextMessage(RoleAssistant, strings.Repeat("Paris is the capital of France. ", 20)),
+	}
+	chat := p.ChatEvents(ModelOptions{
+		ModelName:    "mock",
+		SystemPrompt: "Be brief",
+		History:      history,
+		Parameters:   map[string]any{NumCtx: 200},
+	}, nil)
+	defer close(chat.Done)
+	text, err := mockTurn(t, chat, "And Lyon?")
+	if err != nil {
+		t.Fatalf("turn failed: %v", err)
+	}
+	if text != "Lyon is nice too" {
+		t.Errorf("response %q", text)
+	}
+	requests := p.Requests()
+	if len(requests) != 2 {
+		t.Fatalf("%d requests, want the summary and the response", len(requests))
+	}
+	if summary := requests[0].Messages[len(requests[0].Messages)-1].Text(); !strings.Contains(summary, "Paris is the capital") {
+		t.Errorf("summary request %q does not hold the conversation", summary)
+	}
+	var sent []string
+	for _, msg := range requests[1].Messages {
+		sent = append(sent, msg.Text())
+	}
+	if strings.Contains(strings.Join(sent, "\n"), "Paris is the capital") || !strings.Contains(strings.Join(sent, "\n"), "The user asked about Paris") {
+		t.Errorf("compacted request %q, want the summary instead of the history", sent)
+	}
+}
+
+func TestMockProviderEmbeddings(t *testing.T) {
+	p, err := NewMockProvider()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	embeddings, err := p.GenerateEmbeddings(ctx, []string{"capital of France", "France capital city", "weather forecast"}, "mock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(embeddings) != 3 || len(embeddings[0]) != MockEmbeddingDims {
+		t.Fatalf("embeddings %v", embeddings)
+	}
+	similar := cosineSimilarity(embeddings[0], embeddings[1])
+	if different := cosineSimilarity(embeddings[0], embeddings[2]); similar <= different {
+		t.Errorf("similarity of related texts %f, unrelated %f", similar, different)
+	}
+	again, err := p.GenerateEmbedding(ctx, "capital of France", "mock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cosineSimilarity(again, embeddings[0]) < 0.999 {
+		t.Error("embeddings are not deterministic")
+	}
+}
@@ -3,14 +3,14 @@ package genai
 import (
 	"context"
 	"fmt"
-	"time"
+	"maps"
+	"sync"
 
 	"github.com/go-logr/logr"
 	"github.com/jbutlerdev/genai/tools"
 	ollama "github.com/ollama/ollama/api"
 
-	gemini "github.com/google/generative-ai-go/genai"
-	"github.com/openai/openai-go"
+	gemini "google.golang.org/genai"
 )
 
 const (
@@ -22,35 +22,187 @@ const (
 	RepeatPenalty = "repeat_penalty"
 	Temperature   = "temperature"
 	Seed          = "seed"
-	Stop          = "stop"
 	NumPredict    = "num_predict"
 	TopK          = "top_k"
 	TopP          = "top_p"
 	MinP          = "min_p"
 
+	// Stop is a string or a list of strings, generation ends before any of them
+	Stop = "stop"
+
+	// LogitBias maps token ids to a bias from -100 to 100 and BannedTokens lists token
+	// ids the model must not generate. Token ids depend on the model's tokenizer, both
+	// are only sent to OpenAI compatible providers.
+	LogitBias    = "logit_bias"
+	BannedTokens = "banned_tokens"
+
+	// Logprobs returns the log probability of each generated token and TopLogprobs the
+	// number of most likely alternatives at each position, up to 20. Only OpenAI
+	// compatible providers return them, see Model.Logprobs.
+	Logprobs    = "logprobs"
+	TopLogprobs = "top_logprobs"
+
+	// ReasoningEffort is "low", "medium" or "high". ThinkingBudget is a token budget for
+	// models with extended thinking, it is mapped to an effort when only effort is supported.
+	ReasoningEffort = "reasoning_effort"
+	ThinkingBudget  = "thinking_budget"
+
+	// Ollama runtime options. KeepAlive accepts a time.Duration, a duration string
+	// such as "10m" or a number of seconds, negative values keep the model loaded.
+	KeepAlive = "keep_alive"
+	NumGPU    = "num_gpu"
+	NumThread = "num_thread"
+	MainGPU   = "main_gpu"
+
+	// vLLM specific sampling parameters
+	RepetitionPenalty = "repetition_penalty"
+	GuidedJSON        = "guided_json"
+	BestOf            = "best_of"
+
 	DefaultMaxTurns = 100
 )
 
 type ModelOptions struct {
-	ModelName    string
+	ModelName string
+	// SystemPrompt is applied to Generate and Chat for every provider
 	SystemPrompt string
 	Parameters   map[string]any
 	MaxTurns     int
+	// ContextPacking replaces the middle of a long conversation with per-topic
+	// summaries instead of compacting the whole conversation at once
+	ContextPacking bool
+	// PackRecentTurns is the number of recent user turns kept verbatim when packing
+	PackRecentTurns int
+	// ContextStrategy shrinks conversations larger than the NumCtx parameter, Summarize
+	// when nil. It takes precedence over ContextPacking.
+	ContextStrategy ContextStrategy
+	// UtilityModel and SummaryPrompt override the provider's for this model
+	UtilityModel  string
+	SummaryPrompt string
+	// Examples are few-shot user/assistant pairs sent before the conversation
+	Examples []Example
+	// Timeouts override the provider's timeouts for this model
+	Timeouts Timeouts
+	// Reasoning strips or captures <think> blocks emitted by local reasoning models
+	Reasoning ReasoningMode
+	// MaxToolFailures is the number of consecutive failed calls to the same tool
+	// allowed in one turn, DefaultMaxToolFailures when zero
+	MaxToolFailures int
+	// History resumes an earlier conversation, e.g. one saved from Chat.History. The
+	// system prompt is added when the history does not start with a system message.
+	History []Message
+	// Router picks the model for each turn of a chat from the user's message
+	Router *TurnRouter
+	// Draft has a cheaper model write the response to Generate, which the model
+	// verifies and corrects. Chats ignore it.
+	Draft *DraftOptions
+	// Reflection has a critic review each chat response, which the model revises
+	// before it is delivered
+	Reflection *ReflectionOptions
+	// PostProcessors rewrite chat responses in order before they are delivered, the
+	// history keeps the model's response
+	PostProcessors []PostProcessor
+	// Guardrails check and rewrite the user's messages before they are sent and the
+	// responses before they are delivered, they can block either
+	Guardrails []Guardrail
+	// LoopDetection breaks chats that repeat the same tool calls, off when nil
+	LoopDetection *LoopDetection
+	// ReadBudget limits the file and web tool output a chat receives, off when nil
+	ReadBudget *ReadBudget
+	// FunctionCalling forces or restricts tool calls on Gemini and OpenAI compatible
+	// providers
+	FunctionCalling *FunctionCalling
+	// SafetySettings override the thresholds of Gemini's safety filters
+	SafetySettings []SafetySetting
+	// WebSearch grounds responses in a web search run by the provider, see Grounding
+	WebSearch *WebSearch
+	// ToolSelection sends each turn only the tools relevant to the user's message
+	ToolSelection *ToolSelection
+}
+
+// Example is a single few-shot exchange
+type Example struct {
+	Input  string `json:"input"`
+	Output string `json:"output"`
+}
+
+// exampleMessages converts few-shot examples to user/assistant message pairs
+func exampleMessages(examples []Example) []Message {
+	messages := make([]Message, 0, len(examples)*2)
+	for _, example := range examples {
+		messages = append(messages,
+			NewTextMessage(RoleUser, example.Input),
+			NewTextMessage(RoleAssistant, example.Output),
+		)
+	}
+	return messages
 }
 
 type Model struct {
-	Provider      *Provider
-	Gemini        *gemini.GenerativeModel
-	geminiSession *gemini.ChatSession
-	ollamaClient  *ollama.Client
-	ollamaModel   string
-	openAIModel   string
-	openAIClient  *OpenAIClient
-	Tools         []*tools.Tool
-	Logger        logr.Logger
-	SystemPrompt  string
-	Parameters    map[string]any
-	MaxTurns      int
+	Provider     *Provider
+	ModelName    string
+	Gemini       *gemini.GenerateContentConfig
+	ollamaClient *ollama.Client
+	ollamaModel  string
+	openAIModel  string
+	openAIClient *OpenAIClient
+	Tools        []*tools.Tool
+	Logger       logr.Logger
+	SystemPrompt string
+	Parameters   map[string]any
+	MaxTurns     int
+	Examples     []Example
+	packer       *contextPacker
+	// ContextStrategy shrinks the conversation when it outgrows NumCtx
+	ContextStrategy ContextStrategy
+	UtilityModel    string
+	SummaryPrompt   string
+	localTools      map[string]*tools.Tool
+	history         []Message
+	historyMu       sync.Mutex
+
+	// requestedModel is the name or alias the model was created with, used for routing
+	requestedModel string
+	Timeouts       Timeouts
+	Reasoning      ReasoningMode
+	Router         *TurnRouter
+	Draft          *DraftOptions
+	Reflection     *ReflectionOptions
+	// turnModel is the name or alias Router picked for the current turn
+	turnModel string
+	// ToolSelection picks turnTools, the tools sent in the current turn, nil for all
+	ToolSelection *ToolSelection
+	turnTools     map[string]bool
+
+	session   Session
+	sessionMu sync.Mutex
+
+	MaxToolFailures int
+	PostProcessors  []PostProcessor
+	Guardrails      []Guardrail
+	LoopDetection   *LoopDetection
+	ReadBudget      *ReadBudget
+	FunctionCalling *FunctionCalling
+	SafetySettings  []SafetySetting
+	WebSearch       *WebSearch
+	toolFailures    map[string]int
+	// toolCalls are the tool calls of the current turn, toolCallKeys their keys and
+	// toolLoops the loops found in them
+	toolCalls    []ToolCall
+	toolCallKeys []string
+	toolLoops    int
+	// reads is the ReadBudget spent by the chat
+	reads  readState
+	toolMu sync.Mutex
+
+	usage   Usage
+	usageMu sync.Mutex
+
+	logprobs   []TokenLogprob
+	logprobsMu sync.Mutex
+
+	grounding   *Grounding
+	groundingMu sync.Mutex
 }
 
 func NewModel(provider *Provider, modelOptions ModelOptions, log logr.Logger) *Model {
@@ -59,32 +211,154 @@ func NewModel(provider *Provider, modelOptions ModelOptions, log logr.Logger) *M
 	}
 	if _, ok := modelOptions.Parameters[NumCtx]; !ok {
 		modelOptions.Parameters[NumCtx] = 32768
+		// Gemini has no num_ctx option, its budget defaults to the model's context window
+		if info, ok := LookupModelInfo(provider.ResolveModel(modelOptions.ModelName)); ok && provider.Provider == GEMINI {
+			modelOptions.Parameters[NumCtx] = info.ContextWindow
+		}
 	}
 	if modelOptions.MaxTurns == 0 {
 		modelOptions.MaxTurns = DefaultMaxTurns
 	}
+	requested := modelOptions.ModelName
+	modelOptions.ModelName = provider.ResolveModel(requested)
 	m := &Model{
 		Provider:     provider,
+		ModelName:    modelOptions.ModelName,
 		Logger:       log,
 		SystemPrompt: modelOptions.SystemPrompt,
 		Parameters:   modelOptions.Parameters,
 		MaxTurns:     modelOptions.MaxTurns,
+		Examples:     modelOptions.Examples,
+	}
+	m.requestedModel = requested
+	m.Timeouts = modelOptions.Timeouts
+	m.Reasoning = modelOptions.Reasoning
+	m.MaxToolFailures = modelOptions.MaxToolFailures
+	m.ContextStrategy = modelOptions.ContextStrategy
+	m.UtilityModel = modelOptions.UtilityModel
+	m.SummaryPrompt = modelOptions.SummaryPrompt
+	m.PostProcessors = modelOptions.PostProcessors
+	m.Guardrails = modelOptions.Guardrails
+	m.Router = modelOptions.Router
+	m.Draft = modelOptions.Draft
+	m.Reflection = modelOptions.Reflection
+	m.LoopDetection = modelOptions.LoopDetection
+	m.ReadBudget = modelOptions.ReadBudget
+	m.FunctionCalling = modelOptions.FunctionCalling
+	m.SafetySettings = modelOptions.SafetySettings
+	m.WebSearch = modelOptions.WebSearch
+	m.ToolSelection = modelOptions.ToolSelection
+	if len(modelOptions.History) > 0 {
+		if err := ValidateHistory(modelOptions.History); err != nil {
+			log.Error(err, "Ignoring invalid history")
+		} else {
+			m.setHistory(m.seedHistory(modelOptions.History))
+		}
 	}
 	switch provider.Provider {
 	case GEMINI:
-		m.Gemini = provider.Client.Gemini.GenerativeModel(modelOptions.ModelName)
-		if modelOptions.SystemPrompt != "" {
-			m.Gemini.SystemInstruction = gemini.NewUserContent(gemini.Text(modelOptions.SystemPrompt))
-		}
+		m.Gemini = geminiConfig(modelOptions)
 	case OLLAMA:
 		m.ollamaModel = modelOptions.ModelName
-	case OPENAI:
+	case OPENAI, VLLM:
 		m.openAIModel = modelOptions.ModelName
 		m.openAIClient = provider.Client.OpenAI
+		if modelOptions.ContextPacking && modelOptions.ContextStrategy == nil {
+			m.packer = newContextPacker(modelOptions.PackRecentTurns)
+			m.addLocalTool(m.packer.tool())
+		}
 	}
 	return m
 }
 
+// addLocalTool adds a tool that only exists for this model and is not part of the tool registry
+func (m *Model) addLocalTool(tool *tools.Tool) {
+	if m.localTools == nil {
+		m.localTools = make(map[string]*tools.Tool)
+	}
+	m.localTools[tool.Name] = tool
+	m.Tools = append(m.Tools, tool)
+}
+
+// initialHistory is the history a new conversation starts with
+func (m *Model) initialHistory() []Message {
+	var history []Message
+	if m.SystemPrompt != "" {
+		history = append(history, NewTextMessage(RoleSystem, m.SystemPrompt))
+	}
+	return append(history, exampleMessages(m.Examples)...)
+}
+
+// seedHistory copies a resumed conversation and adds the system prompt when it has none.
+// The examples are added after the system prompt unless the history starts with them,
+// as histories saved from a chat do.
+func (m *Model) seedHistory(history []Message) []Message {
+	var seeded []Message
+	if history[0].Role == RoleSystem {
+		seeded, history = append(seeded, history[0]), history[1:]
+	} else if m.SystemPrompt != "" {
+		seeded = append(seeded, NewTextMessage(RoleSystem, m.SystemPrompt))
+	}
+	if examples := exampleMessages(m.Examples); !startsWith(history, examples) {
+		seeded = append(seeded, examples...)
+	}
+	return append(seeded, history...)
+}
+
+// startsWith reports whether the first messages of history have the roles and text of
+// prefix
+func startsWith(history, prefix []Message) bool {
+	if len(history) < len(prefix) {
+		return false
+	}
+	for i, msg := range prefix {
+		if history[i].Role != msg.Role || history[i].Text() != msg.Text() {
+			return false
+		}
+	}
+	return true
+}
+
+// History returns a copy of the conversation history
+func (m *Model) History() []Message {
+	m.historyMu.Lock()
+	defer m.historyMu.Unlock()
+	history := make([]Message, len(m.history))
+	copy(history, m.history)
+	return history
+}
+
+func (m *Model) setHistory(history []Message) {
+	m.historyMu.Lock()
+	defer m.historyMu.Unlock()
+	m.history = history
+}
+
+func (m *Model) appendHistory(messages ...Message) {
+	m.historyMu.Lock()
+	defer m.historyMu.Unlock()
+	m.history = append(m.history, messages...)
+}
+
+// runTool runs a model local tool if one exists, otherwise the registered tool. The
+// session arguments come from the conversation, never from the model's tool call.
+func (m *Model) runTool(toolName string, args map[string]any) (any, error) {
+	tools.StripSessionArgs(args)
+	if tool, ok := m.localTools[toolName]; ok {
+		return tool.Run(args)
+	}
+	if page, ok, err := m.readPage(toolName, args); ok {
+		return page, err
+	}
+	// running the tool adds its options to the arguments
+	key := maps.Clone(args)
+	result, err := m.Provider.runTool(toolName, args, m.sessionContext(), m.utility())
+	if err != nil {
+		return result, err
+	}
+	return m.budgetRead(toolName, key, result)
+}
+
 func (m *Model) AddTool(toolsToAdd ...*tools.Tool) error {
 	for _, tool := range toolsToAdd {
 		switch m.Provider.Provider {
@@ -94,46 +368,122 @@ func (m *Model) AddTool(toolsToAdd ...*tools.Tool) error {
 				return err
 			}
 			m.Gemini.Tools = append(m.Gemini.Tools, geminiTool)
+			m.Tools = append(m.Tools, tool)
 		case OLLAMA:
 			m.Tools = append(m.Tools, tool)
-		case OPENAI:
+		case OPENAI, VLLM:
 			m.Tools = append(m.Tools, tool)
 		}
 	}
 	return nil
 }
 
-func (m *Model) generate(prompt string, modelOptions ModelOptions) (string, error) {
+// options returns the ModelOptions the model was configured with
+func (m *Model) options() ModelOptions {
+	return ModelOptions{
+		ModelName:    m.ModelName,
+		SystemPrompt: m.SystemPrompt,
+		Parameters:   m.Parameters,
+		MaxTurns:     m.MaxTurns,
+		Examples:     m.Examples,
+		Timeouts:     m.Timeouts,
+		Reasoning:    m.Reasoning,
+
+		MaxToolFailures: m.MaxToolFailures,
+		ContextStrategy: m.ContextStrategy,
+		UtilityModel:    m.UtilityModel,
+		SummaryPrompt:   m.SummaryPrompt,
+		PostProcessors:  m.PostProcessors,
+		Guardrails:      m.Guardrails,
+		Router:          m.Router,
+		Draft:           m.Draft,
+		Reflection:      m.Reflection,
+		LoopDetection:   m.LoopDetection,
+		ReadBudget:      m.ReadBudget,
+		FunctionCalling: m.FunctionCalling,
+		SafetySettings:  m.SafetySettings,
+		WebSearch:       m.WebSearch,
+		ToolSelection:   m.ToolSelection,
+	}
+}
+
+// generate runs a single prompt with the model's system prompt and parameters
+func (m *Model) generate(prompt string) (string, error) {
+	return m.generateMessage(NewTextMessage(RoleUser, prompt))
+}
+
+// generateMessage runs a single user message, which may include images
+func (m *Model) generateMessage(msg Message) (string, error) {
+	return m.generateMessageCtx(context.Background(), msg)
+}
+
+// generateMessageCtx is generateMessage canceled with ctx
+func (m *Model) generateMessageCtx(ctx context.Context, msg Message) (string, error) {
+	msg, err := m.guardPrompt(ctx, nil, msg)
+	if err != nil {
+		return "", err
+	}
+	generate := m.generateOnce
+	if m.Draft != nil {
+		generate = m.generateWithDraft
+	}
+	response, err := m.semanticGenerate(ctx, msg, generate)
+	if err != nil {
+		return response, err
+	}
+	return m.guardResponse(ctx, nil, response)
+}
+
+// generateOnce sends a single request for msg to the model
+func (m *Model) generateOnce(ctx context.Context, msg Message) (string, error) {
+	prompt := msg.Text()
 	switch m.Provider.Provider {
 	case GEMINI:
-		input := &retryableGeminiCallInput{
-			ctx:   context.Background(),
-			model: m,
-			part:  gemini.Text(prompt),
-		}
 		m.Logger.Info("Generating content", "content", prompt)
-		resp, err := retryableGeminiCall(input, 0, 1*time.Second)
+		messages := append(exampleMessages(m.Examples), msg)
+		ctx, cancel := context.WithTimeout(ctx, m.timeouts().Generate)
+		defer cancel()
+		resp, err := geminiGenerate(ctx, m, m.routedModel(messages), messages)
 		if err != nil {
-			return "", fmt.Errorf("failed to generate content: %v", err)
+			return "", fmt.Errorf("failed to generate content: %w", err)
 		}
-		response := handleGeminiText(resp)
-		m.Logger.Info("Generated content", "content", response)
+		m.setGrounding(geminiGrounding(resp))
+		response, thoughts := geminiText(resp)
+		m.Logger.Info("Generated content", "content", response, "reasoning", thoughts)
 		return response, nil
 	case OLLAMA:
 		m.Logger.Info("Generating content with Ollama", "content", prompt)
-		resp, err := ollamaGenerate(m, prompt)
+		resp, err := ollamaGenerate(ctx, m, msg)
 		if err != nil {
-			return "", fmt.Errorf("failed to generate content with Ollama: %v", err)
+			return "", fmt.Errorf("failed to generate content with Ollama: %w", err)
 		}
-		m.Logger.Info("Generated content", "content", resp)
+		reasoning, resp := m.processReasoning(resp)
+		m.Logger.Info("Generated content", "content", resp, "reasoning", reasoning)
 		return resp, nil
-	case OPENAI:
+	case OPENAI, VLLM:
 		m.Logger.Info("Generating content with OpenAI", "content", prompt)
-		resp, err := m.openAIClient.Generate(context.Background(), modelOptions, m.SystemPrompt, prompt)
+		options := m.options()
+		options.ModelName = m.routedModel([]Message{msg})
+		done, err := m.startRequest(ctx, options.ModelName, []Message{msg})
 		if err != nil {
-			return "", fmt.Errorf("failed to generate content with OpenAI: %v", err)
+			return "", fmt.Errorf("failed to generate content with OpenAI: %w", err)
 		}
-		m.Logger.Info("Generated content", "content", resp)
+		choice, usage, err := m.openAIClient.generateMessage(ctx, options, msg)
+		if err != nil {
+			done(Message{}, err)
+		} else {
+			done(fromOpenAIMessage(choice.Message), nil)
+		}
+		if usage.responses() {
+			m.recordUsage(options.ModelName, usage)
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to generate content with OpenAI: %w", err)
+		}
+		m.setLogprobs(openAILogprobs(choice.Logprobs))
+		m.setGrounding(openAIGrounding(choice.Message))
+		reasoning, resp := m.processReasoning(choice.Message.Content)
+		m.Logger.Info("Generated content", "content", resp, "reasoning", reasoning)
 		return resp, nil
 	default:
 		return "", fmt.Errorf("unsupported provider: %s", m.Provider.Provider)
@@ -144,42 +494,12 @@ func (m *Model) chat(ctx context.Context, chat *Chat) error {
 	m.Logger.Info("Starting chat")
 	switch m.Provider.Provider {
 	case GEMINI:
-		m.geminiSession = m.Gemini.StartChat()
-		for {
-			select {
-			case msg := <-chat.Send:
-				m.Logger.Info("Sending message", "content", msg)
-				input := &retryableGeminiCallInput{
-					ctx:     ctx,
-					model:   m,
-					session: m.geminiSession,
-					part:    gemini.Text(msg),
-				}
-				res, err := retryableGeminiCall(input, 0, 1*time.Second)
-				if err != nil {
-					m.Logger.Error(err, "Failed to send message")
-					break
-				}
-				err = handleGeminiResponse(m, chat, res)
-				if err != nil {
-					m.Logger.Error(err, "Failed to handle response")
-				}
-			case <-chat.Done:
-				return nil
-			}
-			chat.GenerationComplete <- true
-		}
+		return geminiChat(ctx, m, chat)
 	case OLLAMA:
 		return ollamaChat(m, chat)
-	case OPENAI:
-		// Initialize messages array
-		messages := []openai.ChatCompletionMessage{}
-
-		// Pass tools to OpenAI client
-		m.openAIClient.Tools = m.Tools
-
-		// Delegate to OpenAI client's Chat method
-		return m.openAIClient.Chat(ctx, m, chat, messages)
+	case OPENAI, VLLM:
+		// The client is shared by the provider's chats, the model carries the tools
+		return m.openAIClient.Chat(ctx, m, chat)
 	default:
 		return fmt.Errorf("unsupported provider: %s", m.Provider.Provider)
 	}
@@ -0,0 +1,145 @@
+package genai
+
+import (
+	"fmt"
+	"strings"
+
+	ollama "github.com/ollama/ollama/api"
+)
+
+// ModelInfo describes the capabilities of a model
+type ModelInfo struct {
+	Name          string `json:"name"`
+	ContextWindow int    `json:"contextWindow"`
+	Tools         bool   `json:"tools"`
+	Vision        bool   `json:"vision"`
+	// EmbeddingDims is only set for embedding models
+	EmbeddingDims int `json:"embeddingDims,omitempty"`
+}
+
+// knownModels is used when a provider does not return capability metadata.
+// Entries are matched by prefix so versioned model names resolve to their family.
+var knownModels = []ModelInfo{
+	{Name: "gpt-4o-mini", ContextWindow: 128000, Tools: true, Vision: true},
+	{Name: "gpt-4o", ContextWindow: 128000, Tools: true, Vision: true},
+	{Name: "gpt-4.1", ContextWindow: 1047576, Tools: true, Vision: true},
+	{Name: "gpt-4-turbo", ContextWindow: 128000, Tools: true, Vision: true},
+	{Name: "gpt-4", ContextWindow: 8192, Tools: true},
+	{Name: "gpt-3.5-turbo", ContextWindow: 16385, Tools: true},
+	{Name: "o1", ContextWindow: 200000, Tools: true, Vision: true},
+	{Name: "o3", ContextWindow: 200000, Tools: true, Vision: true},
+	{Name: "o4-mini", ContextWindow: 200000, Tools: true, Vision: true},
+	{Name: "text-embedding-3-small", ContextWindow: 8191, EmbeddingDims: 1536},
+	{Name: "text-embedding-3-large", ContextWindow: 8191, EmbeddingDims: 3072},
+	{Name: "text-embedding-ada-002", ContextWindow: 8191, EmbeddingDims: 1536},
+	{Name: "gemini-embedding-001", ContextWindow: 2048, EmbeddingDims: 3072},
+	{Name: "text-embedding-004", ContextWindow: 2048, EmbeddingDims: 768},
+	{Name: "gemini-", ContextWindow: 1048576, Tools: true, Vision: true},
+	{Name: "all-minilm", ContextWindow: 512, EmbeddingDims: 384},
+	{Name: "nomic-embed-text", ContextWindow: 8192, EmbeddingDims: 768},
+	{Name: "llama3", ContextWindow: 131072, Tools: true},
+	{Name: "qwen3", ContextWindow: 40960, Tools: true},
+	{Name: "qwen2.5", ContextWindow: 32768, Tools: true},
+	{Name: "mistral", ContextWindow: 32768, Tools: true},
+	{Name: "llava", ContextWindow: 4096, Vision: true},
+}
+
+// LookupModelInfo returns the built-in capability metadata for a model.
+// Provider prefixes such as "models/" or "openai/" are ignored.
+func LookupModelInfo(name string) (ModelInfo, bool) {
+	short := name[strings.LastIndex(name, "/")+1:]
+	for _, info := range knownModels {
+		if strings.HasPrefix(short, info.Name) {
+			info.Name = name
+			return info, true
+		}
+	}
+	return ModelInfo{Name: name}, false
+}
+
+func (p *Provider) ModelsInfo() ([]ModelInfo, error) {
+	return p.Client.ModelsInfo()
+}
+
+func (c *Client) ModelsInfo() ([]ModelInfo, error) {
+	switch c.provider {
+	case GEMINI:
+		return c.getGeminiModelsInfo()
+	case OLLAMA:
+		return c.getOllamaModelsInfo()
+	case OPENAI, VLLM:
+		names, err := c.OpenAI.ModelsWithError()
+		if err != nil {
+			return nil, err
+		}
+		var infos []ModelInfo
+		for _, name := range names {
+			info, _ := LookupModelInfo(name)
+			infos = append(infos, info)
+		}
+		return infos, nil
+	}
+	return nil, fmt.Errorf("unsupported provider: %s", c.provider)
+}
+
+func (c *Client) getGeminiModelsInfo() ([]ModelInfo, error) {
+	var infos []ModelInfo
+	for model, err := range c.Gemini.Models.All(c.ctx) {
+		if err != nil {
+			return nil, fmt.Errorf("failed to list Gemini models: %w", err)
+		}
+		info, _ := LookupModelInfo(model.Name)
+		info.ContextWindow = int(model.InputTokenLimit)
+		for _, method := range model.SupportedActions {
+			if method == "embedContent" && info.EmbeddingDims == 0 {
+				// the API does not report dimensions, assume the default output size
+				info.EmbeddingDims = 768
+			}
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
+func (c *Client) getOllamaModelsInfo() ([]ModelInfo, error) {
+	models, err := c.Ollama.List(c.ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Ollama models: %w", err)
+	}
+	var infos []ModelInfo
+	for _, model := range models.Models {
+		info, _ := LookupModelInfo(model.Name)
+		show, err := c.Ollama.Show(c.ctx, &ollama.ShowRequest{Model: model.Name})
+		if err != nil {
+			// keep the built-in metadata if the model can not be inspected
+			infos = append(infos, info)
+			continue
+		}
+		applyOllamaModelInfo(&info, show)
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
+// applyOllamaModelInfo reads the GGUF metadata returned by /api/show.
+// Keys are prefixed with the model architecture, e.g. "llama.context_length".
+func applyOllamaModelInfo(info *ModelInfo, show *ollama.ShowResponse) {
+	for key, value := range show.ModelInfo {
+		n, ok := value.(float64)
+		if !ok {
+			continue
+		}
+		switch {
+		case strings.HasSuffix(key, ".context_length"):
+			info.ContextWindow = int(n)
+		case strings.HasSuffix(key, ".embedding_length") && strings.Contains(show.Details.Family, "bert"):
+			info.EmbeddingDims = int(n)
+		}
+	}
+	if len(show.ProjectorInfo) > 0 {
+		info.Vision = true
+	}
+	if strings.Contains(show.Template, ".Tools") {
+		info.Tools = true
+	}
+}
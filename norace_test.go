@@ -0,0 +1,5 @@
+//go:build !race
+
+package genai
+
+const raceEnabled = false
@@ -2,14 +2,13 @@ package genai
 
 import (
 	"context"
-	"crypto/sha256"
 	"encoding/json"
 	"fmt"
 	"html"
-	"log"
 	"net/http"
 	"net/url"
 	"regexp"
+	"slices"
 	"strings"
 	"time"
 
@@ -18,15 +17,15 @@ import (
 	ollama "github.com/ollama/ollama/api"
 )
 
-const (
-	ollamaTimeout = 1 * time.Hour
-)
-
 var stream = false
 
 var toolCallRegex = regexp.MustCompile(`\{"name":\s*"[^"]*",\s*"arguments":`)
 
 func NewOllamaClient(baseURL string) *ollama.Client {
+	return newOllamaClient(baseURL, &http.Client{})
+}
+
+func newOllamaClient(baseURL string, httpClient *http.Client) *ollama.Client {
 	if baseURL == "" {
 		baseURL = "http://localhost:11434"
 	}
@@ -34,69 +33,280 @@ func NewOllamaClient(baseURL string) *ollama.Client {
 	if err != nil {
 		panic(err)
 	}
-	return ollama.NewClient(url, &http.Client{})
+	return ollama.NewClient(url, httpClient)
 }
 
-func ollamaGenerate(m *Model, prompt string) (string, error) {
+// ollamaUnsupported are the parameters Ollama has no option for
+var ollamaUnsupported = []string{ReasoningEffort, ThinkingBudget, LogitBias, BannedTokens, Logprobs, TopLogprobs}
+
+// ollamaOptions splits keep_alive out of the parameters since Ollama takes it as a
+// request field rather than a model option, converts stop to the list Ollama requires
+// and drops the unsupported parameters. The parameters are only copied when needed.
+func ollamaOptions(params map[string]any, logger logr.Logger) (map[string]any, *ollama.Duration) {
+	var unsupported []string
+	for _, key := range ollamaUnsupported {
+		if _, ok := params[key]; ok {
+			unsupported = append(unsupported, key)
+		}
+	}
+	stop, hasStop := params[Stop]
+	value, ok := params[KeepAlive]
+	if !ok && !hasStop && len(unsupported) == 0 {
+		return params, nil
+	}
+	if len(unsupported) > 0 {
+		logger.Info("Parameters not supported by Ollama are ignored", "parameters", unsupported)
+	}
+	options := make(map[string]any, len(params))
+	for key, v := range params {
+		if key != KeepAlive && key != Stop && !slices.Contains(ollamaUnsupported, key) {
+			options[key] = v
+		}
+	}
+	if hasStop {
+		if sequences := stopSequences(stop); len(sequences) > 0 {
+			options[Stop] = sequences
+		} else {
+			logger.Info("Invalid stop, it must be a string or a list of strings", "value", stop)
+		}
+	}
+	if !ok {
+		return options, nil
+	}
+	var keepAlive time.Duration
+	switch v := value.(type) {
+	case time.Duration:
+		keepAlive = v
+	case string:
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			logger.Error(err, "Invalid keep_alive, using the server default", "value", v)
+			return options, nil
+		}
+		keepAlive = d
+	case int:
+		keepAlive = time.Duration(v) * time.Second
+	case float64:
+		keepAlive = time.Duration(v * float64(time.Second))
+	default:
+		logger.Info("Invalid keep_alive type, using the server default", "value", v)
+		return options, nil
+	}
+	return options, &ollama.Duration{Duration: keepAlive}
+}
+
+func ollamaGenerate(ctx context.Context, m *Model, msg Message) (string, error) {
+	if len(m.Examples) > 0 {
+		return ollamaGenerateWithExamples(ctx, m, msg)
+	}
 	stream := false
+	options, keepAlive := ollamaOptions(m.Parameters, m.Logger)
 	req := ollama.GenerateRequest{
-		Model:   m.ollamaModel,
-		Prompt:  prompt,
-		Stream:  &stream,
-		Options: m.Parameters,
+		Model:     m.routedModel([]Message{msg}),
+		Prompt:    msg.Text(),
+		Stream:    &stream,
+		Options:   options,
+		KeepAlive: keepAlive,
 	}
 	if m.SystemPrompt != "" {
 		req.System = m.SystemPrompt
 	}
-
-	var respString string
-
-	respFunc := func(resp ollama.GenerateResponse) error {
-		printUsage(resp.Metrics, m.Logger)
-		respString = resp.Response
-		return nil
+	for _, part := range msg.Parts {
+		if part.Type == ImagePart {
+			req.Images = append(req.Images, ollama.ImageData(part.Data))
+		}
 	}
 
-	generateContext, cancel := context.WithTimeout(context.Background(), ollamaTimeout)
+	done, err := m.startRequest(ctx, req.Model, []Message{msg})
+	if err != nil {
+		return "", err
+	}
+	generateContext, cancel := context.WithTimeout(ctx, m.timeouts().Generate)
 	defer cancel()
-	err := m.Provider.Client.Ollama.Generate(generateContext, &req, respFunc)
+	resp, hit, err := cached(generateContext, m.Provider.cache, m.Logger, OLLAMA, req, m.Parameters, func() (ollama.GenerateResponse, error) {
+		var last ollama.GenerateResponse
+		_, err := retry(generateContext, m.Provider.Retry, m.Logger, OLLAMA, m.Provider.observer(), func() (struct{}, error) {
+			return balanced(m.Provider, func(client *Client) (struct{}, error) {
+				return struct{}{}, client.Ollama.Generate(generateContext, &req, func(resp ollama.GenerateResponse) error {
+					last = resp
+					return nil
+				})
+			})
+		})
+		return last, err
+	})
 	if err != nil {
+		done(Message{}, err)
 		return "", err
 	}
-	return respString, nil
+	done(NewTextMessage(RoleAssistant, resp.Response), nil)
+	if hit {
+		m.recordUsage(req.Model, cachedUsage())
+	} else {
+		printUsage(resp.Metrics, m.Logger)
+		m.recordUsage(req.Model, ollamaUsage(resp.Metrics))
+	}
+	return resp.Response, nil
 }
 
-func ollamaChat(model *Model, chat *Chat) error {
-	messages := []ollama.Message{}
-	if model.SystemPrompt != "" {
-		messages = append(messages, ollama.Message{Role: "system", Content: model.SystemPrompt})
+// ollamaStream streams the response to a single message to send, using the chat
+// endpoint when there are examples like ollamaGenerate
+func ollamaStream(ctx context.Context, m *Model, msg Message, send func(string) bool) error {
+	stream := true
+	options, keepAlive := ollamaOptions(m.Parameters, m.Logger)
+	var model string
+	messages := []Message{msg}
+	var run func(client *Client, send func(string) bool) error
+	if len(m.Examples) > 0 {
+		messages = append(m.initialHistory(), msg)
+		model = m.routedModel(messages)
+		req := &ollama.ChatRequest{
+			Model:     model,
+			Messages:  toOllamaMessages(messages),
+			Stream:    &stream,
+			Options:   options,
+			KeepAlive: keepAlive,
+		}
+		run = func(client *Client, send func(string) bool) error {
+			return client.Ollama.Chat(ctx, req, func(resp ollama.ChatResponse) error {
+				if resp.Done {
+					m.recordUsage(model, ollamaUsage(resp.Metrics))
+				}
+				if !send(resp.Message.Content) {
+					return errStreamStopped
+				}
+				return nil
+			})
+		}
+	} else {
+		model = m.routedModel([]Message{msg})
+		req := &ollama.GenerateRequest{
+			Model:     model,
+			Prompt:    msg.Text(),
+			System:    m.SystemPrompt,
+			Stream:    &stream,
+			Options:   options,
+			KeepAlive: keepAlive,
+		}
+		for _, part := range msg.Parts {
+			if part.Type == ImagePart {
+				req.Images = append(req.Images, ollama.ImageData(part.Data))
+			}
+		}
+		run = func(client *Client, send func(string) bool) error {
+			return client.Ollama.Generate(ctx, req, func(resp ollama.GenerateResponse) error {
+				if resp.Done {
+					m.recordUsage(model, ollamaUsage(resp.Metrics))
+				}
+				if !send(resp.Response) {
+					return errStreamStopped
+				}
+				return nil
+			})
+		}
 	}
-	for {
-		select {
-		case msg := <-chat.Send:
-			messages = append(messages, ollama.Message{Role: "user", Content: msg})
+	done, err := m.startRequest(ctx, model, messages)
+	if err != nil {
+		return err
+	}
+	send, text := collectResponse(send)
+	err = streamWithRetry(ctx, m.Provider.Retry, m.Logger, OLLAMA, m.Provider.observer(), send, func(send func(string) bool) error {
+		_, err := balanced(m.Provider, func(client *Client) (struct{}, error) {
+			return struct{}{}, run(client, send)
+		})
+		return err
+	})
+	done(NewTextMessage(RoleAssistant, text.String()), err)
+	return err
+}
 
-			// Convert tools to Ollama format
-			var ollamaTools []ollama.Tool
+// ollamaGenerateWithExamples uses the chat endpoint since the generate endpoint
+// only accepts a single prompt
+func ollamaGenerateWithExamples(ctx context.Context, m *Model, msg Message) (string, error) {
+	messages := append(m.initialHistory(), msg)
+	options, keepAlive := ollamaOptions(m.Parameters, m.Logger)
+	req := &ollama.ChatRequest{
+		Model:     m.routedModel(messages),
+		Messages:  toOllamaMessages(messages),
+		Stream:    &stream,
+		Options:   options,
+		KeepAlive: keepAlive,
+	}
 
-			for _, tool := range model.Tools {
-				ollamaTool, err := tools.GetOllamaTool(tool.Name)
-				if err != nil {
-					model.Logger.Error(err, "Failed to get Ollama tool", "tool", tool.Name)
-					continue
-				}
-				ollamaTools = append(ollamaTools, *ollamaTool)
-			}
+	generateContext, cancel := context.WithTimeout(ctx, m.timeouts().Generate)
+	defer cancel()
+	resp, err := ollamaChatOnce(generateContext, m, req, messages)
+	if err != nil {
+		return "", err
+	}
+	return resp.Message.Content, nil
+}
 
-			err := handleOllamaResponse(model, ollamaTools, chat, messages)
-			if err != nil {
-				model.Logger.Error(err, "Failed to handle ollama response")
-			}
+// ollamaChatOnce sends a chat request for messages that is not streamed, or returns its
+// cached response, and records the usage
+func ollamaChatOnce(ctx context.Context, m *Model, req *ollama.ChatRequest, messages []Message) (ollama.ChatResponse, error) {
+	done, err := m.startRequest(ctx, req.Model, messages)
+	if err != nil {
+		return ollama.ChatResponse{}, err
+	}
+	resp, hit, err := cached(ctx, m.Provider.cache, m.Logger, OLLAMA, req, m.Parameters, func() (ollama.ChatResponse, error) {
+		var last ollama.ChatResponse
+		_, err := retry(ctx, m.Provider.Retry, m.Logger, OLLAMA, m.Provider.observer(), func() (struct{}, error) {
+			return balanced(m.Provider, func(client *Client) (struct{}, error) {
+				return struct{}{}, client.Ollama.Chat(ctx, req, func(resp ollama.ChatResponse) error {
+					last = resp
+					return nil
+				})
+			})
+		})
+		return last, err
+	})
+	if err != nil {
+		done(Message{}, err)
+		return resp, err
+	}
+	done(fromOllamaMessage(resp.Message), nil)
+	if hit {
+		m.recordUsage(req.Model, cachedUsage())
+		return resp, nil
+	}
+	printUsage(resp.Metrics, m.Logger)
+	m.recordUsage(req.Model, ollamaUsage(resp.Metrics))
+	return resp, nil
+}
 
-		case <-chat.Done:
+// ollamaTools converts the tools of the turn, tools that can not be converted are left out
+func (m *Model) ollamaTools() []ollama.Tool {
+	var ollamaTools []ollama.Tool
+	for _, tool := range m.Tools {
+		if !m.toolSelected(tool.Name) {
+			continue
+		}
+		ollamaTool, err := tools.GetOllamaTool(tool.Name)
+		if err != nil {
+			m.Logger.Error(err, "Failed to get Ollama tool", "tool", tool.Name)
+			continue
+		}
+		ollamaTools = append(ollamaTools, *ollamaTool)
+	}
+	return ollamaTools
+}
+
+func ollamaChat(model *Model, chat *Chat) error {
+	if len(model.History()) == 0 {
+		model.setHistory(model.initialHistory())
+	}
+	for {
+		msg, turnContext, ok := chat.receive(chat.ctx, model)
+		if !ok {
 			return nil
 		}
-		chat.GenerationComplete <- true
+		err := chat.runTurn(turnContext, model, msg, func(ctx context.Context) error {
+			return handleOllamaResponse(ctx, model, model.ollamaTools(), chat, model.History())
+		})
+		if err != nil {
+			model.Logger.Error(err, "Failed to handle ollama response")
+		}
 	}
 }
 
@@ -110,80 +320,115 @@ func printUsage(resp ollama.Metrics, logger logr.Logger) {
 	logger.Info("token usage", "content", usageString)
 }
 
-func handleOllamaResponse(model *Model, tools []ollama.Tool, chat *Chat, messages []ollama.Message) error {
+// ollamaChatRequest builds the chat request for the messages
+func ollamaChatRequest(model *Model, tools []ollama.Tool, messages []Message) *ollama.ChatRequest {
+	options, keepAlive := ollamaOptions(model.Parameters, model.Logger)
+	return &ollama.ChatRequest{
+		Model:     model.routedModel(messages),
+		Messages:  toOllamaMessages(messages),
+		Tools:     tools,
+		Stream:    &stream,
+		Options:   options,
+		KeepAlive: keepAlive,
+	}
+}
+
+func handleOllamaResponse(ctx context.Context, model *Model, tools []ollama.Tool, chat *Chat, messages []Message) error {
+	messages, err := handleContextLength(model, messages)
+	if err != nil {
+		return err
+	}
 	lastMessage := messages[len(messages)-1]
-	if lastMessage.Role == "tool" {
-		model.Logger.Info("Sending function call output", "content", lastMessage.Content)
+	if lastMessage.Role == RoleTool {
+		model.Logger.Info("Sending function call output", "content", lastMessage.ToolResults())
 	} else {
-		model.Logger.Info("Sending message to Ollama", "content", lastMessage.Content)
+		model.Logger.Info("Sending message to Ollama", "content", lastMessage.Text())
 	}
-	respFunc := func(resp ollama.ChatResponse) error {
-		printUsage(resp.Metrics, model.Logger)
-		messages = append(messages, resp.Message)
-		return nil
-	}
-
-	chatContext, cancel := context.WithTimeout(context.Background(), ollamaTimeout)
+	chatContext, cancel := context.WithTimeout(ctx, model.timeouts().Chat)
 	defer cancel()
-	err := model.Provider.Client.Ollama.Chat(chatContext, &ollama.ChatRequest{
-		Model:    model.ollamaModel,
-		Messages: messages,
-		Tools:    tools,
-		Stream:   &stream,
-		Options:  model.Parameters,
-	}, respFunc)
+	req := ollamaChatRequest(model, tools, messages)
+	resp, err := ollamaChatOnce(chatContext, model, req, messages)
 	if err != nil {
 		model.Logger.Error(err, "Failed to send message to Ollama")
 		return err
 	}
-	lastMessage = messages[len(messages)-1]
-	if len(lastMessage.ToolCalls) < 1 {
-		lastMessage, err = unmarshalToolCall(lastMessage, model.Logger)
+	respMessage := resp.Message
+	messages = append(messages, fromOllamaMessage(resp.Message))
+	if len(respMessage.ToolCalls) < 1 {
+		respMessage, err = unmarshalToolCall(respMessage, model.Logger)
 		if err != nil {
 			// if we hit this case it means the model returned a message that we believe to be a tool call but it can not be unmarshalled.
 			// there is an edge case here where it could be json, and not a tool call, but we will ignore that for now.
-			model.Logger.Info("Received invalid tool call", "content", html.EscapeString(lastMessage.Content))
+			model.Logger.Info("Received invalid tool call", "content", html.EscapeString(respMessage.Content))
 			model.Logger.Error(err, "Failed to unmarshal tool call, sending error back to Ollama")
-			errorMsg := ollama.Message{Role: "tool", Content: fmt.Sprintf("error: you provided an invalid tool call: %s", err.Error())}
-			messages = append(messages, errorMsg)
-			err = handleOllamaResponse(model, tools, chat, messages)
-			return err
+			messages = append(messages, NewToolResultMessage(ToolResult{
+				Content: ToolFailure{
+					Error:     fmt.Sprintf("invalid tool call: %s", err.Error()),
+					Hint:      "Call tools with a JSON object containing name and arguments.",
+					Retryable: true,
+				}.String(),
+				IsError: true,
+			}))
+			return handleOllamaResponse(ctx, model, tools, chat, messages)
 		}
 	}
 	// Handle tool calls if any
-	if len(lastMessage.ToolCalls) > 0 {
-		toolCalls := map[[32]byte]bool{}
-		for _, toolCall := range lastMessage.ToolCalls {
-			funcJson, err := json.Marshal(toolCall.Function)
-			if err != nil {
-				model.Logger.Error(err, "Failed to marshal tool call arguments", "tool", toolCall.Function.Name)
-			}
-			hash := hashToolCall(funcJson)
-			if toolCalls[hash] {
-				model.Logger.Info("Skipping duplicate tool call", "hash", hash)
+	if len(respMessage.ToolCalls) > 0 {
+		seen := make(map[string]bool)
+		for _, toolCall := range respMessage.ToolCalls {
+			call := ToolCall{Name: toolCall.Function.Name, Arguments: toolCall.Function.Arguments}
+			// Ollama calls have no ids, a repeated call is answered once
+			key := toolCallKey(call)
+			if seen[key] {
+				model.Logger.Info("Skipping duplicate tool call", "tool", call.Name)
 				continue
 			}
-			toolCalls[hash] = true
-			model.Logger.Info("Handling function call", "name", toolCall.Function.Name, "content", string(funcJson))
-			result, err := model.Provider.RunTool(toolCall.Function.Name, toolCall.Function.Arguments)
+			seen[key] = true
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			toolResult, blocked, err := model.toolLoop(chat, call)
 			if err != nil {
-				model.Logger.Error(err, "Failed to run tool", "tool", toolCall.Function.Name)
+				return err
 			}
-			// Add tool result to chat
-			resultMsg := fmt.Sprintf("Tool %s returned: %v", toolCall.Function.Name, result)
-			model.Logger.Info("Tool result", "content", resultMsg)
-			toolResultMessage := ollama.Message{Role: "tool", Content: resultMsg}
-			messages = append(messages, toolResultMessage)
+			model.Logger.Info("Handling function call", "name", call.Name, "content", call.argumentsJSON())
+			started := chat.toolStarted(call)
+			if !blocked {
+				toolResult, blocked = model.toolBlocked("", toolCall.Function.Name)
+			}
+			if !blocked {
+				result, err := model.runTool(toolCall.Function.Name, toolCall.Function.Arguments)
+				if err != nil {
+					model.Logger.Error(err, "Failed to run tool", "tool", toolCall.Function.Name)
+				}
+				// Add tool result to chat
+				toolResult = model.toolResult("", toolCall.Function.Name, formatToolResult(result), err)
+			}
+			chat.toolFinished(call, toolResult, started)
+			model.Logger.Info("Tool result", "content", toolResult.Content)
+			messages = append(messages, NewToolResultMessage(toolResult))
 		}
 		// send response
-		err = handleOllamaResponse(model, tools, chat, messages)
-		if err != nil {
-			model.Logger.Error(err, "Failed to handle tool result")
-		}
+		return handleOllamaResponse(ctx, model, tools, chat, messages)
 	} else {
 		// send response
-		model.Logger.Info("Received response from Ollama", "content", html.EscapeString(lastMessage.Content))
-		chat.Recv <- lastMessage.Content
+		model.Logger.Info("Received response from Ollama", "content", html.EscapeString(respMessage.Content))
+		response := respMessage.Content
+		if revised := model.reflect(ctx, messages, response); revised != response {
+			response = revised
+			messages[len(messages)-1] = NewTextMessage(RoleAssistant, revised)
+		}
+		guarded, err := model.guardResponse(ctx, chat, response)
+		if err != nil {
+			return err
+		}
+		if guarded != response {
+			response = guarded
+			messages[len(messages)-1] = NewTextMessage(RoleAssistant, guarded)
+		}
+		messages[len(messages)-1] = model.stripReasoning(messages[len(messages)-1])
+		model.setHistory(messages)
+		model.sendResponse(chat, response)
 	}
 	return nil
 }
@@ -210,17 +455,16 @@ func unmarshalToolCall(message ollama.Message, logger logr.Logger) (ollama.Messa
 		toolString = fixQuotes(toolString)
 		err = json.Unmarshal([]byte(toolString), &toolCall)
 		if err != nil {
-			log.Printf("Failed to unmarshal tool call, attempted string: %s: %s", toolString, err.Error())
+			logger.Error(err, "Failed to unmarshal tool call", "content", toolString)
 			return message, fmt.Errorf("failed to unmarshal tool call: %w", err)
 		} else {
 			logger.Info("Fixed quotes and unmarshalled tool call", "content", toolString)
-			log.Printf("Fixed quotes and unmarshalled tool call: %s", toolString)
 		}
 	}
 	message.ToolCalls = append(message.ToolCalls, ollama.ToolCall{
 		Function: toolCall,
 	})
-	log.Printf("Added tool call to message: %v", toolString)
+	logger.Info("Added tool call to message", "content", toolString)
 	return message, nil
 }
 
@@ -261,10 +505,6 @@ func runeContains(arr []rune, i rune) bool {
 	return false
 }
 
-func hashToolCall(toolCall []byte) [32]byte {
-	return sha256.Sum256(toolCall)
-}
-
 // GenerateEmbedding generates an embedding for a single text input using Ollama's embedding API
 func ollamaGenerateEmbedding(ctx context.Context, client *ollama.Client, text string, model string) ([]float32, error) {
 	// Use all-minilm as the default embedding model if not specified
@@ -279,7 +519,7 @@ func ollamaGenerateEmbedding(ctx context.Context, client *ollama.Client, text st
 
 	resp, err := client.Embeddings(ctx, req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate embedding: %w", err)
+		return nil, fmt.Errorf("failed to generate embedding: %w", wrapProviderError(OLLAMA, err))
 	}
 
 	// Convert []float64 to []float32
@@ -305,7 +545,7 @@ func ollamaGenerateEmbeddings(ctx context.Context, client *ollama.Client, texts
 
 	resp, err := client.Embed(ctx, req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate embeddings: %w", err)
+		return nil, fmt.Errorf("failed to generate embeddings: %w", wrapProviderError(OLLAMA, err))
 	}
 
 	return resp.Embeddings, nil
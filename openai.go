@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net/http"
 	"strconv"
 	"strings"
 	"time"
@@ -15,38 +16,50 @@ import (
 	"github.com/openai/openai-go/option"
 	"github.com/openai/openai-go/packages/param"
 	"github.com/openai/openai-go/shared"
-	"github.com/tiktoken-go/tokenizer"
-)
-
-const (
-	openaiTimeout = 1 * time.Hour
 )
 
 type OpenAIClient struct {
-	client  openai.Client
-	log     logr.Logger
-	Tools   []*tools.Tool
-	enc     tokenizer.Codec
-	model   string
-	baseURL string
+	client   openai.Client
+	log      logr.Logger
+	model    string
+	baseURL  string
+	provider string
+	retry    RetryPolicy
+	balancer *balancer
+	timeouts Timeouts
+	cache    *responseCache
+	metrics  Metrics
+	// httpClient is set when a transport option is set, the SDK uses its default otherwise
+	httpClient *http.Client
 }
 
 func NewOpenAIClient(provider *Provider) (*OpenAIClient, error) {
 	options := []option.RequestOption{
 		option.WithAPIKey(provider.APIKey),
+		// retries are handled by the provider's RetryPolicy
+		option.WithMaxRetries(0),
 	}
 	if provider.BaseURL != "" {
 		provider.Log.Info("setting base URL", "baseURL", provider.BaseURL)
-		options = append(options, option.WithBaseURL(provider.BaseURL))
+		// WithBaseURL adds a missing trailing slash to its URL on every request, which
+		// races when the client is used concurrently
+		baseURL := provider.BaseURL
+		if !strings.HasSuffix(baseURL, "/") {
+			baseURL += "/"
+		}
+		options = append(options, option.WithBaseURL(baseURL))
+	}
+	var hc *http.Client
+	if provider.customTransport() {
+		var err error
+		hc, err = provider.httpClient(nil)
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, option.WithHTTPClient(hc))
 	}
 	client := openai.NewClient(options...)
 
-	c, err := tokenizer.Get(tokenizer.Cl100kBase)
-	if err != nil {
-		provider.Log.Error(err, "Failed to create tokenizer")
-		return nil, err
-	}
-	
 	// Set default model for embeddings
 	model := "text-embedding-3-small"
 	// If provider has a model specified, use it
@@ -55,15 +68,20 @@ func NewOpenAIClient(provider *Provider) (*OpenAIClient, error) {
 	}
 	
 	return &OpenAIClient{
-		client:  client,
-		log:     provider.Log,
-		Tools:   make([]*tools.Tool, 0),
-		enc:     c,
-		model:   model,
-		baseURL: provider.BaseURL,
+		client:   client,
+		log:      provider.Log,
+		model:    model,
+		baseURL:  provider.BaseURL,
+		provider: provider.Provider,
+		retry:    provider.Retry,
+		timeouts: provider.Timeouts,
+		cache:    provider.cache,
+		metrics:  provider.observer(),
+		httpClient: hc,
 	}, nil
 }
 
+// Models returns the available models, falling back to a default list on failure
 func (c *OpenAIClient) Models() []string {
 	// Default models to return as fallback
 	defaultModels := []string{
@@ -72,15 +90,9 @@ func (c *OpenAIClient) Models() []string {
 		"gpt-3.5-turbo",
 	}
 
-	// List all models
-	var allModels []string
-	pager := c.client.Models.ListAutoPaging(context.Background())
-	for pager.Next() {
-		model := pager.Current()
-		allModels = append(allModels, model.ID)
-	}
-	if pager.Err() != nil {
-		c.log.Error(pager.Err(), "failed to list models")
+	allModels, err := c.ModelsWithError()
+	if err != nil {
+		c.log.Error(err, "failed to list models")
 		return defaultModels
 	}
 
@@ -92,6 +104,20 @@ func (c *OpenAIClient) Models() []string {
 	return allModels
 }
 
+// ModelsWithError lists all models without falling back to defaults
+func (c *OpenAIClient) ModelsWithError() ([]string, error) {
+	var allModels []string
+	pager := c.client.Models.ListAutoPaging(context.Background())
+	for pager.Next() {
+		model := pager.Current()
+		allModels = append(allModels, model.ID)
+	}
+	if pager.Err() != nil {
+		return nil, fmt.Errorf("failed to list models: %w", wrapProviderError(c.provider, pager.Err()))
+	}
+	return allModels, nil
+}
+
 func newParams(model string, messages []openai.ChatCompletionMessageParamUnion, params map[string]any) openai.ChatCompletionNewParams {
 	messageParams := openai.ChatCompletionNewParams{
 		Model:    model,
@@ -138,34 +164,282 @@ func newParams(model string, messages []openai.ChatCompletionMessageParamUnion,
 				topP = 1.0
 			}
 			messageParams.TopP = param.Opt[float64]{Value: topP}
+		case Stop:
+			if stop := stopSequences(v); len(stop) > 0 {
+				messageParams.Stop = openai.ChatCompletionNewParamsStopUnion{OfChatCompletionNewsStopArray: stop}
+			}
+		case LogitBias, BannedTokens:
+			// both are merged into one map, setting it twice gives the same result
+			messageParams.LogitBias = logitBias(params)
+		case Logprobs:
+			// top_logprobs turns the logprobs on
+			if n, ok := toInt(params[TopLogprobs]); ok && n > 0 {
+				continue
+			}
+			if enabled, ok := v.(bool); ok {
+				messageParams.Logprobs = param.Opt[bool]{Value: enabled}
+			}
+		case TopLogprobs:
+			// alternatives are only returned with the logprobs
+			if n, ok := toInt(v); ok && n > 0 {
+				messageParams.Logprobs = param.Opt[bool]{Value: true}
+				messageParams.TopLogprobs = param.Opt[int64]{Value: int64(n)}
+			}
+		case ReasoningEffort:
+			if effort, ok := v.(string); ok {
+				messageParams.ReasoningEffort = shared.ReasoningEffort(effort)
+			}
+		case ThinkingBudget:
+			// an explicit effort takes precedence over the budget
+			if _, ok := params[ReasoningEffort]; ok {
+				continue
+			}
+			if budget, ok := toInt(v); ok && budget > 0 {
+				messageParams.ReasoningEffort = budgetToEffort(budget)
+			}
 		}
 	}
 	return messageParams
 }
 
-func (c *OpenAIClient) Generate(ctx context.Context, modelOptions ModelOptions, systemPrompt string, prompt string) (string, error) {
-	messages := []openai.ChatCompletionMessageParamUnion{}
-	if systemPrompt != "" {
-		messages = append(messages, openai.SystemMessage(systemPrompt))
+// budgetToEffort maps a thinking token budget to the closest reasoning effort
+func budgetToEffort(budget int) shared.ReasoningEffort {
+	switch {
+	case budget <= 2048:
+		return shared.ReasoningEffortLow
+	case budget <= 8192:
+		return shared.ReasoningEffortMedium
+	default:
+		return shared.ReasoningEffortHigh
 	}
-	messages = append(messages, openai.UserMessage(prompt))
-	params := newParams(modelOptions.ModelName, messages, modelOptions.Parameters)
+}
+
+func toInt(v any) (int, bool) {
+	switch n := v.(type) {
+	case int:
+		return n, true
+	case int64:
+		return int(n), true
+	case float64:
+		return int(n), true
+	}
+	return 0, false
+}
 
-	generateContext, cancel := context.WithTimeout(ctx, openaiTimeout)
+// stopSequences converts the stop parameter, a string or a list of strings, and returns
+// nil for other values
+func stopSequences(v any) []string {
+	switch stop := v.(type) {
+	case string:
+		if stop != "" {
+			return []string{stop}
+		}
+	case []string:
+		return stop
+	case []any:
+		// JSON decodes lists to []any
+		sequences := make([]string, 0, len(stop))
+		for _, s := range stop {
+			str, ok := s.(string)
+			if !ok {
+				return nil
+			}
+			sequences = append(sequences, str)
+		}
+		return sequences
+	}
+	return nil
+}
+
+// bannedTokenBias is the logit bias that keeps a token from being generated
+const bannedTokenBias = -100
+
+// logitBias merges the logit_bias and banned_tokens parameters into the token ids and
+// biases sent to OpenAI, banned tokens override their bias
+func logitBias(params map[string]any) map[string]int64 {
+	bias := make(map[string]int64)
+	switch b := params[LogitBias].(type) {
+	case map[string]int:
+		for token, v := range b {
+			bias[token] = int64(v)
+		}
+	case map[int]int:
+		for token, v := range b {
+			bias[strconv.Itoa(token)] = int64(v)
+		}
+	case map[string]any:
+		for token, v := range b {
+			if n, ok := toInt(v); ok {
+				bias[token] = int64(n)
+			}
+		}
+	}
+	switch tokens := params[BannedTokens].(type) {
+	case []int:
+		for _, token := range tokens {
+			bias[strconv.Itoa(token)] = bannedTokenBias
+		}
+	case []any:
+		for _, token := range tokens {
+			if n, ok := toInt(token); ok {
+				bias[strconv.Itoa(n)] = bannedTokenBias
+			}
+		}
+	}
+	if len(bias) == 0 {
+		return nil
+	}
+	return bias
+}
+
+// vllmParams are passed through to vLLM as-is since the OpenAI params do not model them
+var vllmParams = []string{MinP, RepetitionPenalty, GuidedJSON, BestOf, TopK}
+
+// requestOptions returns the per-request options for parameters that newParams can not express
+func (c *OpenAIClient) requestOptions(model string, params map[string]any) []option.RequestOption {
+	var opts []option.RequestOption
+	// Anthropic's OpenAI compatible endpoint takes the thinking budget as an extra field
+	if budget, ok := toInt(params[ThinkingBudget]); ok && budget > 0 && strings.HasPrefix(model, "claude") {
+		opts = append(opts,
+			option.WithJSONSet("thinking", map[string]any{"type": "enabled", "budget_tokens": budget}),
+			option.WithJSONDel("reasoning_effort"),
+		)
+	}
+	if c.provider != VLLM {
+		return opts
+	}
+	for _, key := range vllmParams {
+		if v, ok := params[key]; ok {
+			opts = append(opts, option.WithJSONSet(key, v))
+		}
+	}
+	return opts
+}
+
+// completeFor is complete for a request of m with messages, reported to the provider's
+// OnRequest and OnResponse hooks
+func (c *OpenAIClient) completeFor(ctx context.Context, m *Model, params openai.ChatCompletionNewParams, messages []Message) (*openai.ChatCompletion, Usage, error) {
+	done, err := m.startRequest(ctx, params.Model, messages)
+	if err != nil {
+		return nil, Usage{}, err
+	}
+	resp, usage, err := c.complete(ctx, params, m.Parameters)
+	var response Message
+	if err == nil && len(resp.Choices) > 0 {
+		response = fromOpenAIMessage(resp.Choices[0].Message)
+	}
+	done(response, err)
+	return resp, usage, err
+}
+
+// complete sends a chat completion request using the client's retry policy and returns
+// the usage of the request
+func (c *OpenAIClient) complete(ctx context.Context, params openai.ChatCompletionNewParams, parameters map[string]any) (*openai.ChatCompletion, Usage, error) {
+	resp, hit, err := cached(ctx, c.cache, c.log, c.provider, params, parameters, func() (*openai.ChatCompletion, error) {
+		return retry(ctx, c.retry, c.log, c.provider, c.metrics, func() (*openai.ChatCompletion, error) {
+			return openAIBalanced(c, func(client *OpenAIClient) (*openai.ChatCompletion, error) {
+				return client.client.Chat.Completions.New(ctx, params, c.requestOptions(params.Model, parameters)...)
+			})
+		})
+	})
+	if err != nil {
+		return nil, Usage{}, err
+	}
+	if hit {
+		return resp, cachedUsage(), nil
+	}
+	return resp, openAIUsage(resp.Usage), nil
+}
+
+// Generate runs a single prompt, modelOptions.SystemPrompt is sent as the system message
+// followed by any few-shot examples
+func (c *OpenAIClient) Generate(ctx context.Context, modelOptions ModelOptions, prompt string) (string, error) {
+	return c.GenerateMessage(ctx, modelOptions, NewTextMessage(RoleUser, prompt))
+}
+
+// GenerateMessage runs a single user message, which may include images
+func (c *OpenAIClient) GenerateMessage(ctx context.Context, modelOptions ModelOptions, msg Message) (string, error) {
+	text, _, err := c.GenerateMessageWithUsage(ctx, modelOptions, msg)
+	return text, err
+}
+
+// GenerateMessageWithUsage runs a single user message and reports the tokens used
+func (c *OpenAIClient) GenerateMessageWithUsage(ctx context.Context, modelOptions ModelOptions, msg Message) (string, Usage, error) {
+	choice, usage, err := c.generateMessage(ctx, modelOptions, msg)
+	return choice.Message.Content, usage, err
+}
+
+// generateMessage is GenerateMessageWithUsage with the whole choice of the response,
+// which carries its logprobs and citations
+func (c *OpenAIClient) generateMessage(ctx context.Context, modelOptions ModelOptions, msg Message) (openai.ChatCompletionChoice, Usage, error) {
+	params := generateParams(modelOptions, msg)
+
+	generateContext, cancel := context.WithTimeout(ctx, modelOptions.Timeouts.merge(c.timeouts).merge(DefaultTimeouts).Generate)
 	defer cancel()
-	resp, err := c.client.Chat.Completions.New(generateContext, params)
+	resp, usage, err := c.complete(generateContext, params, modelOptions.Parameters)
 	if err != nil {
-		return "", fmt.Errorf("failed to create chat completion: %w", err)
+		return openai.ChatCompletionChoice{}, Usage{}, fmt.Errorf("failed to create chat completion: %w", err)
 	}
 
 	if len(resp.Choices) == 0 {
-		return "", fmt.Errorf("no response choices returned")
+		return openai.ChatCompletionChoice{}, usage, fmt.Errorf("no response choices returned")
 	}
+	return resp.Choices[0], usage, nil
+}
 
-	return resp.Choices[0].Message.Content, nil
+// generateParams builds the request for a single user message with the system prompt
+// and few-shot examples
+func generateParams(modelOptions ModelOptions, msg Message) openai.ChatCompletionNewParams {
+	messages := []openai.ChatCompletionMessageParamUnion{}
+	if modelOptions.SystemPrompt != "" {
+		messages = append(messages, openai.SystemMessage(modelOptions.SystemPrompt))
+	}
+	messages = append(messages, toOpenAIParams(exampleMessages(modelOptions.Examples))...)
+	messages = append(messages, openAIUserMessage(msg))
+	params := newParams(modelOptions.ModelName, messages, modelOptions.Parameters)
+	applyOpenAIWebSearch(&params, modelOptions.WebSearch)
+	return params
+}
+
+// streamMessage streams the response to a single user message to send and reports the
+// tokens used, which servers only include when the stream runs to the end
+func (c *OpenAIClient) streamMessage(ctx context.Context, modelOptions ModelOptions, msg Message, send func(string) bool) (Usage, error) {
+	params := generateParams(modelOptions, msg)
+	params.StreamOptions = openai.ChatCompletionStreamOptionsParam{IncludeUsage: openai.Bool(true)}
+	usage := Usage{Requests: 1}
+	err := streamWithRetry(ctx, c.retry, c.log, c.provider, c.metrics, send, func(send func(string) bool) error {
+		_, err := openAIBalanced(c, func(client *OpenAIClient) (struct{}, error) {
+			stream := client.client.Chat.Completions.NewStreaming(ctx, params, c.requestOptions(params.Model, modelOptions.Parameters)...)
+			defer stream.Close()
+			for stream.Next() {
+				chunk := stream.Current()
+				if chunk.JSON.Usage.IsPresent() {
+					usage = openAIUsage(chunk.Usage)
+				}
+				if len(chunk.Choices) > 0 && !send(chunk.Choices[0].Delta.Content) {
+					return struct{}{}, errStreamStopped
+				}
+			}
+			return struct{}{}, stream.Err()
+		})
+		return err
+	})
+	return usage, err
 }
 
+// ConvertToolToFunction converts a tool to an OpenAI function definition.
+// Registered tools are cached, tools that only exist on a model are converted every time.
 func (c *OpenAIClient) ConvertToolToFunction(tool *tools.Tool) openai.FunctionDefinition {
+	if _, err := tools.GetTool(tool.Name); err != nil {
+		return convertToolToFunction(tool)
+	}
+	fn, _ := tools.CachedSchema(tool.Name, OPENAI, func() (any, error) {
+		return convertToolToFunction(tool), nil
+	})
+	return fn.(openai.FunctionDefinition)
+}
+
+func convertToolToFunction(tool *tools.Tool) openai.FunctionDefinition {
 	params := make(map[string]interface{})
 	required := make([]string, 0)
 	properties := make(map[string]interface{})
@@ -202,110 +476,50 @@ func (c *OpenAIClient) ConvertToolToFunction(tool *tools.Tool) openai.FunctionDe
 	}
 }
 
-func (c *OpenAIClient) Chat(ctx context.Context, m *Model, chat *Chat, messages []openai.ChatCompletionMessage) error {
-	if m.SystemPrompt != "" && len(messages) == 0 {
-		messages = append(messages, openai.ChatCompletionMessage{
-			Role:    "system",
-			Content: m.SystemPrompt,
-		})
+func (c *OpenAIClient) Chat(ctx context.Context, m *Model, chat *Chat) error {
+	if len(m.History()) == 0 {
+		m.setHistory(m.initialHistory())
 	}
 
 	for {
-		select {
-		case newMessage := <-chat.Send:
-			messages = append(messages, openai.ChatCompletionMessage{
-				Role:    "user",
-				Content: newMessage,
-			})
-			chat.Logger.Info("Sending message to OpenAI", "content", newMessage)
-
-			// Process this message and any subsequent tool calls
-			if err := c.processOpenAIMessage(ctx, m, chat, messages); err != nil {
-				chat.Logger.Error(err, "Failed to process message")
-			}
-
-		case <-chat.Done:
+		newMessage, turnContext, ok := chat.receive(ctx, m)
+		if !ok {
 			return nil
 		}
-		chat.GenerationComplete <- true
-	}
-}
+		chat.Logger.Info("Sending message to OpenAI", "content", newMessage.Text())
 
-// transform messages array into string
-func messagesToString(messages []openai.ChatCompletionMessage, includeSystem bool) string {
-	content := ""
-	for _, msg := range messages {
-		if !includeSystem && msg.Role == "system" {
-			continue
+		// Process this message and any subsequent tool calls
+		err := chat.runTurn(turnContext, m, newMessage, func(ctx context.Context) error {
+			return c.processOpenAIMessage(ctx, m, chat, m.History())
+		})
+		if err != nil {
+			chat.Logger.Error(err, "Failed to process message")
 		}
-		content += fmt.Sprintf("{\"Role\": \"%s\", \"content\": \"%s\"}", msg.Role, msg.Content)
-		// need to add other fields
-	}
-	return content
-}
-
-// handle message size
-// if context grows larger than model NumCtx then shrink it
-func handleContextLength(m *Model, messages []openai.ChatCompletionMessage, c tokenizer.Codec) ([]openai.ChatCompletionMessage, error) {
-	maxContext, ok := m.Parameters[NumCtx].(int)
-	if !ok {
-		return nil, errors.New("failed to parse num_ctx for model")
-	}
-	content := messagesToString(messages, true)
-	contextSize, err := c.Count(content)
-	if err != nil {
-		return nil, err
-	}
-	if contextSize > maxContext {
-		m.Logger.Info("context length is larger than NumCtx, compacting...", "length", strconv.Itoa(contextSize))
-		return compact(m, messages)
-	}
-	return messages, nil
-}
-
-// compact messages
-func compact(m *Model, messages []openai.ChatCompletionMessage) ([]openai.ChatCompletionMessage, error) {
-	prompt := "Compact this conversation into 5000 words or less. Do not include any word counts or summarizing. Just return the summarized content.\n"
-	prompt += messagesToString(messages, false)
-	modelOptions := ModelOptions{
-		ModelName:    m.openAIModel,
-		SystemPrompt: m.SystemPrompt,
-		Parameters:   m.Parameters,
-		MaxTurns:     m.MaxTurns,
-	}
-	response, err := m.generate(prompt, modelOptions)
-	if err != nil {
-		return nil, err
 	}
-	responseMessages := []openai.ChatCompletionMessage{messages[0]}
-	if messages[0].Role == "system" {
-		responseMessages = append(responseMessages, messages[1])
-	}
-	compactMessage := openai.ChatCompletionMessage{
-		Role:    "user",
-		Content: response,
-	}
-	responseMessages = append(responseMessages, compactMessage)
-	return responseMessages, nil
 }
 
 // executeToolCall executes a single tool call with its own 5-minute timeout context
-func (c *OpenAIClient) executeToolCall(ctx context.Context, m *Model, chat *Chat, toolCall openai.ChatCompletionMessageToolCall) (string, error) {
+func (c *OpenAIClient) executeToolCall(ctx context.Context, m *Model, chat *Chat, toolCall ToolCall) (string, error) {
 	// Create a context with 5-minute timeout for this specific tool call
 	toolCtx, cancel := context.WithTimeout(ctx, 5*time.Minute)
 	defer cancel()
+	// the turn was canceled while an earlier tool was running
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
 
-	funcJson, err := json.MarshalIndent(toolCall.Function, "", "  ")
+	funcJson, err := json.MarshalIndent(toolCall, "", "  ")
 	if err != nil {
-		chat.Logger.Error(err, "Failed to marshal tool call arguments", "tool", toolCall.Function.Name)
+		chat.Logger.Error(err, "Failed to marshal tool call arguments", "tool", toolCall.Name)
 	}
-	chat.Logger.Info("Handling function call", "name", toolCall.Function.Name, "content", string(funcJson))
+	chat.Logger.Info("Handling function call", "name", toolCall.Name, "content", string(funcJson))
 
-	// Parse arguments to map
-	var argsMap map[string]interface{}
-	err = json.Unmarshal([]byte(toolCall.Function.Arguments), &argsMap)
-	if err != nil {
-		return "", fmt.Errorf("failed to parse tool arguments: %w", err)
+	if toolCall.Arguments == nil && toolCall.RawArguments != "" {
+		return "", tools.NewToolError(fmt.Errorf("failed to parse tool arguments: %s", toolCall.RawArguments), "Send the arguments as a valid JSON object.", true)
+	}
+	argsMap := toolCall.Arguments
+	if argsMap == nil {
+		argsMap = make(map[string]any)
 	}
 
 	// Create a channel to receive the result
@@ -317,204 +531,184 @@ func (c *OpenAIClient) executeToolCall(ctx context.Context, m *Model, chat *Chat
 
 	// Execute the tool in a goroutine
 	go func() {
-		result, err := m.Provider.RunTool(toolCall.Function.Name, argsMap)
+		result, err := m.runTool(toolCall.Name, argsMap)
 		resultChan <- toolResult{result: result, err: err}
 	}()
 
 	// Wait for either the tool to complete or the context to timeout
 	select {
 	case <-toolCtx.Done():
+		if ctx.Err() != nil {
+			return "", fmt.Errorf("tool call %s canceled: %w", toolCall.Name, ctx.Err())
+		}
 		// Context timed out
-		return "", fmt.Errorf("tool call %s timed out after 5 minutes: %w", toolCall.Function.Name, toolCtx.Err())
+		return "", fmt.Errorf("tool call %s timed out after 5 minutes: %w", toolCall.Name, toolCtx.Err())
 	case res := <-resultChan:
 		// Tool completed
 		if res.err != nil {
 			return "", fmt.Errorf("tool execution failed: %w", res.err)
 		}
-		return fmt.Sprintf("%v", res.result), nil
+		return formatToolResult(res.result), nil
 	}
 }
 
-// processToolCalls handles executing multiple tool calls
-func (c *OpenAIClient) processToolCalls(ctx context.Context, m *Model, chat *Chat, toolCalls []openai.ChatCompletionMessageToolCall, messages []openai.ChatCompletionMessage, toolCallIDs map[int]string) (bool, []openai.ChatCompletionMessage, error) {
-	toolCallsProcessed := false
-	var toolResponses []openai.ChatCompletionMessage
-
-	// Process each tool call
+// processToolCalls handles executing multiple tool calls and returns one tool message per
+// call, or the error of a tool call loop that ends the turn
+func (c *OpenAIClient) processToolCalls(ctx context.Context, m *Model, chat *Chat, toolCalls []ToolCall) ([]Message, error) {
+	var toolResponses []Message
+	seen := make(map[string]ToolResult)
 	for _, toolCall := range toolCalls {
-		if toolCall.Type == "function" {
+		// the key is taken before the run, running adds the tool options to the arguments
+		key := toolCallKey(toolCall)
+		if result, ok := seen[key]; ok {
+			// every call id needs a result, a repeated call gets the result of the first
+			chat.Logger.Info("Skipping duplicate tool call", "tool", toolCall.Name)
+			result.ID = toolCall.ID
+			toolResponses = append(toolResponses, NewToolResultMessage(result))
+			continue
+		}
+		result, blocked, err := m.toolLoop(chat, toolCall)
+		if err != nil {
+			return nil, err
+		}
+		started := chat.toolStarted(toolCall)
+		if !blocked {
+			result, blocked = m.toolBlocked(toolCall.ID, toolCall.Name)
+		}
+		if !blocked {
 			// Execute the tool with its own timeout
 			resultStr, err := c.executeToolCall(ctx, m, chat, toolCall)
 			if err != nil {
-				chat.Logger.Error(err, "Failed to execute tool call", "tool", toolCall.Function.Name)
+				chat.Logger.Error(err, "Failed to execute tool call", "tool", toolCall.Name)
 			}
-
-			// Add the tool result as a custom message - we'll handle this specially when converting to params
-			toolResponse := openai.ChatCompletionMessage{
-				Role:    "tool",
-				Content: resultStr,
-				// We'll use the toolCall.ID when converting to a parameter
-			}
-			toolResponses = append(toolResponses, toolResponse)
-			// Also track the ID for this response to use later
-			nextIndex := len(messages) + len(toolResponses) - 1
-			toolCallIDs[nextIndex] = toolCall.ID
-			toolCallsProcessed = true
+			result = m.toolResult(toolCall.ID, toolCall.Name, resultStr, err)
 		}
+		chat.toolFinished(toolCall, result, started)
+		seen[key] = result
+		toolResponses = append(toolResponses, NewToolResultMessage(result))
 	}
-
-	return toolCallsProcessed, toolResponses, nil
+	return toolResponses, nil
 }
 
-func messagesToParamUnion(chat *Chat, messages []openai.ChatCompletionMessage, toolCallIDs map[int]string) []openai.ChatCompletionMessageParamUnion {
-	// Create parameters for OpenAI API
-	var paramMessages []openai.ChatCompletionMessageParamUnion
-	for i, msg := range messages {
-		switch msg.Role {
-		case "user":
-			paramMessages = append(paramMessages, openai.UserMessage(msg.Content))
-		case "assistant":
-			// If there are tool calls, we need to preserve them in the message history
-			if len(msg.ToolCalls) > 0 {
-				// We need to use the raw ToParam() method to preserve tool calls
-				paramMessages = append(paramMessages, msg.ToParam())
-
-				// Save tool call IDs for subsequent tool responses
-				for j, toolCall := range msg.ToolCalls {
-					if i+1+j < len(messages) && messages[i+1+j].Role == "tool" {
-						toolCallIDs[i+1+j] = toolCall.ID
-					}
-				}
-			} else {
-				paramMessages = append(paramMessages, openai.AssistantMessage(msg.Content))
-			}
-		case "system":
-			paramMessages = append(paramMessages, openai.SystemMessage(msg.Content))
-		case "tool":
-			// For tool messages, we need to use ToolMessage with the proper tool_call_id
-			if id, ok := toolCallIDs[i]; ok {
-				paramMessages = append(paramMessages, openai.ToolMessage(msg.Content, id))
-			} else {
-				// Fallback - try to find a matching tool call in the previous message
-				foundID := ""
-				if i > 0 && messages[i-1].Role == "assistant" && len(messages[i-1].ToolCalls) > 0 {
-					// Just use the first tool call ID as a fallback
-					foundID = messages[i-1].ToolCalls[0].ID
-					chat.Logger.Info("Using fallback tool call ID from previous message", "index", i, "id", foundID)
-				}
-
-				if foundID != "" {
-					paramMessages = append(paramMessages, openai.ToolMessage(msg.Content, foundID))
-				} else {
-					// Last resort fallback - not ideal but better than nothing
-					chat.Logger.Info("No tool call ID found, using AssistantMessage as fallback", "index", i)
-					paramMessages = append(paramMessages, openai.AssistantMessage(msg.Content))
-				}
-			}
-		}
-	}
-	return paramMessages
-}
-
-func (c *OpenAIClient) handleTurns(ctx context.Context, m *Model, chat *Chat, messages openai.ChatCompletionNewParams) (bool, error) {
+func (c *OpenAIClient) handleTurns(ctx context.Context, m *Model, chat *Chat, params openai.ChatCompletionNewParams, messages []Message) (bool, error) {
 	chat.Turns++
 	if m.MaxTurns > 0 && chat.Turns > m.MaxTurns {
-		processContext, cancel := context.WithTimeout(ctx, openaiTimeout)
+		// the model has to answer, otherwise every further tool call would be followed
+		// by another final request
+		if len(params.Tools) > 0 {
+			params.ToolChoice = openai.ChatCompletionToolChoiceOptionUnionParam{OfAuto: param.NewOpt("none")}
+		}
+		processContext, cancel := context.WithTimeout(ctx, m.timeouts().Chat)
 		defer cancel()
-		resp, err := c.client.Chat.Completions.New(processContext, messages)
+		resp, usage, err := c.completeFor(processContext, m, params, messages)
 		if err != nil {
 			return true, fmt.Errorf("failed to generate final chat message: %w", err)
 		}
-		return true, c.handleResponse(ctx, resp, m, chat, nil, nil)
+		m.recordUsage(params.Model, usage)
+		return true, c.handleResponse(ctx, resp, m, chat, messages)
 	}
 	return false, nil
 }
 
-func (c *OpenAIClient) handleResponse(ctx context.Context, resp *openai.ChatCompletion, m *Model, chat *Chat, messages []openai.ChatCompletionMessage, toolCallIDs map[int]string) error {
+func (c *OpenAIClient) handleResponse(ctx context.Context, resp *openai.ChatCompletion, m *Model, chat *Chat, messages []Message) error {
 	if len(resp.Choices) == 0 {
 		return fmt.Errorf("no response choices returned")
 	}
 
 	choice := resp.Choices[0]
+	assistantMsg := fromOpenAIMessage(choice.Message)
 
 	// Handle tool calls if present
-	if len(choice.Message.ToolCalls) > 0 {
-		// Save the assistant's response with tool calls
-		assistantMsg := openai.ChatCompletionMessage{
-			Role:      "assistant",
-			Content:   choice.Message.Content,
-			ToolCalls: choice.Message.ToolCalls,
-		}
+	if toolCalls := assistantMsg.ToolCalls(); len(toolCalls) > 0 {
+		// Save the assistant's response with tool calls followed by the tool results
 		messages = append(messages, assistantMsg)
-
-		// Process tool calls with the new function
-		toolCallsProcessed, toolResponses, err := c.processToolCalls(ctx, m, chat, choice.Message.ToolCalls, messages, toolCallIDs)
+		toolResponses, err := c.processToolCalls(ctx, m, chat, toolCalls)
 		if err != nil {
-			return fmt.Errorf("failed to process tool calls: %w", err)
+			return err
 		}
-
-		// Add all tool responses to the conversation history
 		messages = append(messages, toolResponses...)
-
-		// If we processed any tool calls, recursively process the updated messages
-		if toolCallsProcessed {
-			return c.processOpenAIMessage(ctx, m, chat, messages)
-		}
+		return c.processOpenAIMessage(ctx, m, chat, messages)
 	}
 
 	// Handle text response
 	response := choice.Message.Content
 	chat.Logger.Info("Handling text", "content", response)
-	
+
 	// Check if the response contains invalid tool call markers
 	if strings.Contains(response, "<tool_call>") {
 		chat.Logger.Info("Detected invalid tool call in response")
-		
+
 		// Add the assistant's invalid message to history (but don't send to user)
-		messages = append(messages, openai.ChatCompletionMessage{
-			Role:    "assistant",
-			Content: response,
-		})
-		
+		messages = append(messages, assistantMsg)
+
 		// Add an error message about invalid tool call
 		invalidToolCallMsg := "Error: Invalid tool call format detected. Please use the proper tool calling mechanism instead of embedding tool calls in text."
-		messages = append(messages, openai.ChatCompletionMessage{
-			Role:    "user",
-			Content: invalidToolCallMsg,
-		})
-		
+		messages = append(messages, NewTextMessage(RoleUser, invalidToolCallMsg))
+
 		// Process the error message to get a corrected response
 		return c.processOpenAIMessage(ctx, m, chat, messages)
 	}
-	
-	// Send the response to the chat
-	chat.Recv <- response
+
+	// a revision by the critic sets the logprobs of the revised response
+	m.setLogprobs(openAILogprobs(choice.Logprobs))
+	m.setGrounding(openAIGrounding(choice.Message))
+	if revised := m.reflect(ctx, messages, response); revised != response {
+		response = revised
+		assistantMsg = NewTextMessage(RoleAssistant, revised)
+	}
+	guarded, err := m.guardResponse(ctx, chat, response)
+	if err != nil {
+		return err
+	}
+	if guarded != response {
+		response = guarded
+		assistantMsg = NewTextMessage(RoleAssistant, guarded)
+	}
+
+	// Keep the completed turn and send the response to the chat
+	m.setHistory(append(messages, m.stripReasoning(assistantMsg)))
+	m.sendResponse(chat, response)
 	return nil
 }
 
-// processOpenAIMessage handles a message (user input or tool response) and any subsequent tool calls
-func (c *OpenAIClient) processOpenAIMessage(ctx context.Context, m *Model, chat *Chat, messages []openai.ChatCompletionMessage) error {
-	// Map to track tool_call_id for tool messages
-	toolCallIDs := make(map[int]string)
+// chatParams builds the chat completion request for the messages with the model's tools
+func (c *OpenAIClient) chatParams(m *Model, messages []Message) openai.ChatCompletionNewParams {
+	params := newParams(m.routedModel(messages), toOpenAIParams(messages), m.Parameters)
+	// Tools belong to the model so chats sharing the client keep their own toolsets
+	for _, tool := range m.Tools {
+		if !m.toolSelected(tool.Name) {
+			continue
+		}
+		fn := c.ConvertToolToFunction(tool)
+		params.Tools = append(params.Tools, openai.ChatCompletionToolParam{
+			Type: "function",
+			Function: shared.FunctionDefinitionParam{
+				Name:        fn.Name,
+				Parameters:  fn.Parameters,
+				Description: param.NewOpt(fn.Description),
+			},
+		})
+	}
+	applyOpenAIFunctionCalling(&params, m.functionCalling(messages))
+	applyOpenAIWebSearch(&params, m.WebSearch)
+	return params
+}
 
+// processOpenAIMessage handles a message (user input or tool response) and any subsequent tool calls
+func (c *OpenAIClient) processOpenAIMessage(ctx context.Context, m *Model, chat *Chat, messages []Message) error {
 	// validate context length
 	var err error
 	if m.Parameters == nil {
 		return errors.New("nil Parameters attached to model")
 	}
-	messages, err = handleContextLength(m, messages, c.enc)
+	messages, err = handleContextLength(m, messages)
 	if err != nil {
 		return err
 	}
 
-	paramMessages := messagesToParamUnion(chat, messages, toolCallIDs)
-
-	params := openai.ChatCompletionNewParams{
-		Model:    m.openAIModel,
-		Messages: paramMessages,
-	}
+	params := c.chatParams(m, messages)
 
-	done, err := c.handleTurns(ctx, m, chat, params)
+	done, err := c.handleTurns(ctx, m, chat, params, messages)
 	if err != nil {
 		chat.Logger.Error(err, "error occurred when calculating max turns")
 	}
@@ -522,32 +716,16 @@ func (c *OpenAIClient) processOpenAIMessage(ctx context.Context, m *Model, chat
 		return err
 	}
 
-	// Add tools if any
-	if len(c.Tools) > 0 {
-		var tools []openai.ChatCompletionToolParam
-		for _, tool := range c.Tools {
-			fn := c.ConvertToolToFunction(tool)
-			tools = append(tools, openai.ChatCompletionToolParam{
-				Type: "function",
-				Function: shared.FunctionDefinitionParam{
-					Name:        fn.Name,
-					Parameters:  fn.Parameters,
-					Description: param.NewOpt(fn.Description),
-				},
-			})
-		}
-		params.Tools = tools
-	}
-
 	// Get response
-	processContext, cancel := context.WithTimeout(ctx, openaiTimeout)
+	processContext, cancel := context.WithTimeout(ctx, m.timeouts().Chat)
 	defer cancel()
-	resp, err := c.client.Chat.Completions.New(processContext, params)
+	resp, usage, err := c.completeFor(processContext, m, params, messages)
 	if err != nil {
 		return fmt.Errorf("failed to send message: %w", err)
 	}
+	m.recordUsage(params.Model, usage)
 
-	return c.handleResponse(ctx, resp, m, chat, messages, toolCallIDs)
+	return c.handleResponse(ctx, resp, m, chat, messages)
 }
 
 // GenerateEmbedding generates an embedding for a single text input using OpenAI's embedding API
@@ -567,7 +745,11 @@ func (c *OpenAIClient) GenerateEmbedding(ctx context.Context, text string, model
 		Model: openai.EmbeddingModel(model),
 	}
 
-	resp, err := c.client.Embeddings.New(ctx, params)
+	resp, err := retry(ctx, c.retry, c.log, c.provider, c.metrics, func() (*openai.CreateEmbeddingResponse, error) {
+		return openAIBalanced(c, func(client *OpenAIClient) (*openai.CreateEmbeddingResponse, error) {
+			return client.client.Embeddings.New(ctx, params)
+		})
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to create embedding: %w", err)
 	}
@@ -602,7 +784,11 @@ func (c *OpenAIClient) GenerateEmbeddings(ctx context.Context, texts []string, m
 		Model: openai.EmbeddingModel(model),
 	}
 
-	resp, err := c.client.Embeddings.New(ctx, params)
+	resp, err := retry(ctx, c.retry, c.log, c.provider, c.metrics, func() (*openai.CreateEmbeddingResponse, error) {
+		return openAIBalanced(c, func(client *OpenAIClient) (*openai.CreateEmbeddingResponse, error) {
+			return client.client.Embeddings.New(ctx, params)
+		})
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to create embeddings: %w", err)
 	}
@@ -0,0 +1,203 @@
+package genai
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/jbutlerdev/genai/tools"
+)
+
+const (
+	// DefaultPackRecentTurns is the number of user turns kept verbatim when packing
+	DefaultPackRecentTurns = 4
+
+	expandSummaryToolName = "expand_summary"
+)
+
+// packedSummaryHeader starts the message that replaces the packed topics
+var packedSummaryHeader = "Earlier parts of this conversation have been summarized by topic. " +
+	fmt.Sprintf("Call the %s tool with a topic id to see the original messages.\n", expandSummaryToolName)
+
+// topicSummary is a cached summary of one slice of the conversation history
+type topicSummary struct {
+	id       string
+	summary  string
+	messages []Message
+}
+
+// contextPacker keeps the pinned messages and the most recent turns verbatim and
+// replaces the middle of the conversation with per-topic summaries.
+// Summaries are generated lazily the first time a topic has to be packed and
+// cached so that later turns do not summarize the same history again.
+type contextPacker struct {
+	mu          sync.Mutex
+	recentTurns int
+	summaries   map[string]*topicSummary
+}
+
+func newContextPacker(recentTurns int) *contextPacker {
+	if recentTurns <= 0 {
+		recentTurns = DefaultPackRecentTurns
+	}
+	return &contextPacker{
+		recentTurns: recentTurns,
+		summaries:   make(map[string]*topicSummary),
+	}
+}
+
+// tool returns the tool the model can call to re-expand a summarized topic
+func (p *contextPacker) tool() *tools.Tool {
+	return &tools.Tool{
+		Name:        expandSummaryToolName,
+		Description: "Expand a summarized topic from earlier in the conversation back into the original messages",
+		Parameters: []tools.Parameter{
+			{
+				Name:        "id",
+				Type:        "string",
+				Description: "The id of the summarized topic to expand",
+				Required:    true,
+			},
+		},
+		Options: map[string]string{},
+		Run:     p.expand,
+	}
+}
+
+// expand returns the original messages of a summarized topic
+func (p *contextPacker) expand(args map[string]any) (map[string]any, error) {
+	id, ok := args["id"].(string)
+	if !ok {
+		return map[string]any{
+			"success": false,
+			"error":   fmt.Sprintf("expected string: %v", args["id"]),
+		}, fmt.Errorf("expected string: %v", args["id"])
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, topic := range p.summaries {
+		if topic.id == id {
+			return map[string]any{
+				"success":  true,
+				"messages": messagesToString(topic.messages, false),
+			}, nil
+		}
+	}
+	return map[string]any{
+		"success": false,
+		"error":   fmt.Sprintf("unknown topic: %s", id),
+	}, fmt.Errorf("unknown topic: %s", id)
+}
+
+// splitTopics groups messages into topics, each starting with a user message
+func splitTopics(messages []Message) [][]Message {
+	var topics [][]Message
+	for _, msg := range messages {
+		if msg.Role == RoleUser || len(topics) == 0 {
+			topics = append(topics, []Message{})
+		}
+		topics[len(topics)-1] = append(topics[len(topics)-1], msg)
+	}
+	return topics
+}
+
+func topicKey(messages []Message) string {
+	hash := sha256.Sum256([]byte(messagesToString(messages, true)))
+	return hex.EncodeToString(hash[:])
+}
+
+// summarize returns the cached summary for a topic, generating it if needed
+func (p *contextPacker) summarize(m *Model, messages []Message) (*topicSummary, error) {
+	key := topicKey(messages)
+	p.mu.Lock()
+	topic, ok := p.summaries[key]
+	p.mu.Unlock()
+	if ok {
+		return topic, nil
+	}
+
+	prompt := m.utility().prompt
+	if prompt == "" {
+		prompt = "Summarize this part of a conversation in a few sentences. Keep names, file paths, decisions and open questions. Just return the summarized content.\n"
+	}
+	prompt += messagesToString(messages, false)
+	// summaries are generated without the chat's system prompt
+	model := m.utility().model
+	if model == "" {
+		model = m.ModelName
+	}
+	summarizer := NewModel(m.Provider, ModelOptions{
+		ModelName:  model,
+		Parameters: m.Parameters,
+		MaxTurns:   m.MaxTurns,
+	}, m.Logger)
+	response, err := summarizer.generate(prompt)
+	if err != nil {
+		return nil, err
+	}
+	topic = &topicSummary{
+		id:       "topic-" + key[:8],
+		summary:  response,
+		messages: messages,
+	}
+	p.mu.Lock()
+	p.summaries[key] = topic
+	p.mu.Unlock()
+	return topic, nil
+}
+
+// packedSummary returns the topic lines of a summary message left by an earlier packing
+// at the start of messages, the message and its acknowledgement are never packed again
+func packedSummary(messages []Message) (string, bool) {
+	if len(messages) < 2 || messages[0].Role != RoleUser || messages[1].Role != RoleAssistant {
+		return "", false
+	}
+	lines, ok := strings.CutPrefix(messages[0].Text(), packedSummaryHeader)
+	return lines, ok
+}
+
+// Fit keeps the pinned messages and the most recent turns and replaces everything in
+// between with a single message listing the topic summaries. Topics summarized by an
+// earlier call stay in the summary message. Recent turns are dropped as with DropOldest
+// when the result is still too large.
+func (p *contextPacker) Fit(m *Model, messages []Message, maxTokens int) ([]Message, error) {
+	pinned := m.pinnedMessages(messages)
+	earlier, repacking := packedSummary(messages[pinned:])
+	history := messages[pinned:]
+	if repacking {
+		history = history[2:]
+	}
+
+	topics := splitTopics(history)
+	if len(topics) <= p.recentTurns {
+		if repacking {
+			return dropOldest(m, messages, pinned+2, maxTokens)
+		}
+		// nothing left to pack, fall back to full compaction
+		return Summarize{}.Fit(m, messages, maxTokens)
+	}
+	middle := topics[:len(topics)-p.recentTurns]
+	recent := topics[len(topics)-p.recentTurns:]
+
+	var sb strings.Builder
+	sb.WriteString(packedSummaryHeader)
+	sb.WriteString(earlier)
+	for _, topicMessages := range middle {
+		topic, err := p.summarize(m, topicMessages)
+		if err != nil {
+			return nil, fmt.Errorf("failed to summarize topic: %w", err)
+		}
+		sb.WriteString(fmt.Sprintf("\n[%s] %s\n", topic.id, topic.summary))
+	}
+	packed := append([]Message{}, messages[:pinned]...)
+	packed = append(packed,
+		NewTextMessage(RoleUser, sb.String()),
+		NewTextMessage(RoleAssistant, "Understood."),
+	)
+	for _, topicMessages := range recent {
+		packed = append(packed, topicMessages...)
+	}
+	return dropOldest(m, packed, pinned+2, maxTokens)
+}
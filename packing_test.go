@@ -0,0 +1,78 @@
+package genai
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr"
+)
+
+// topicMessages returns a user/assistant pair for each prompt
+func topicMessages(prompts ...string) []Message {
+	var messages []Message
+	for _, prompt := range prompts {
+		messages = append(messages,
+			NewTextMessage(RoleUser, prompt),
+			NewTextMessage(RoleAssistant, "answer to "+prompt),
+		)
+	}
+	return messages
+}
+
+func TestContextPackerRepack(t *testing.T) {
+	p, err := NewMockProvider(
+		MockResponse{Text: "first summary"},
+		MockResponse{Text: "second summary"},
+		MockResponse{Text: "third summary"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := NewModel(p.Provider, ModelOptions{
+		ModelName: "test",
+		Examples:  []Example{{Input: "example", Output: "example answer"}},
+	}, logr.Discard())
+	packer := newContextPacker(1)
+
+	messages := []Message{NewTextMessage(RoleSystem, "Be brief")}
+	messages = append(messages, exampleMessages(m.Examples)...)
+	messages = append(messages, topicMessages("one", "two", "three")...)
+	packed, err := packer.Fit(m, messages, 100000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// system prompt, example pair, summary and acknowledgement, the recent turn
+	if len(packed) != 7 {
+		t.Fatalf("packed %d messages", len(packed))
+	}
+	if packed[1].Text() != "example" || packed[2].Text() != "example answer" {
+		t.Errorf("examples were not pinned: %q %q", packed[1].Text(), packed[2].Text())
+	}
+	if p.Pending() != 1 {
+		t.Fatalf("%d summaries pending after the first packing", p.Pending())
+	}
+
+	packed = append(packed, topicMessages("four")...)
+	repacked, err := packer.Fit(m, packed, 100000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Pending() != 0 {
+		t.Errorf("the new topic was not summarized")
+	}
+	if len(repacked) != 7 {
+		t.Fatalf("repacked %d messages", len(repacked))
+	}
+	summary := repacked[3].Text()
+	if !strings.HasPrefix(summary, packedSummaryHeader) || strings.Count(summary, packedSummaryHeader) != 1 {
+		t.Errorf("the summary message was packed again: %q", summary)
+	}
+	for _, want := range []string{"first summary", "second summary", "third summary"} {
+		if !strings.Contains(summary, want) {
+			t.Errorf("summary message is missing %q: %q", want, summary)
+		}
+	}
+	if repacked[5].Text() != "four" {
+		t.Errorf("recent turn %q", repacked[5].Text())
+	}
+}
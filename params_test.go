@@ -0,0 +1,138 @@
+package genai
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/go-logr/logr"
+)
+
+const geminiResponseBody = `{"candidates":[{"content":{"role":"model","parts":[{"text":"hello"}]},"finishReason":"STOP"}]}`
+
+const ollamaGenerateBody = `{"model":"test","created_at":"2024-01-01T00:00:00Z","response":"hello","done":true}`
+
+// generate sends one prompt with params through the provider
+func generate(t *testing.T, providerName, baseURL string, params map[string]any) {
+	t.Helper()
+	p, err := NewProvider(providerName, ProviderOptions{APIKey: "test", BaseURL: baseURL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Log = logr.Discard()
+	p.Retry = RetryPolicy{MaxAttempts: 1}
+	if _, _, err := p.generateWithUsage(context.Background(), ModelOptions{ModelName: "test", Parameters: params}, "hi"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// jsonParams decodes params like parameters read from a config file
+func jsonParams(t *testing.T, s string) map[string]any {
+	t.Helper()
+	var params map[string]any
+	if err := json.Unmarshal([]byte(s), &params); err != nil {
+		t.Fatal(err)
+	}
+	return params
+}
+
+func TestStopAndLogitBiasOpenAI(t *testing.T) {
+	tests := []struct {
+		name     string
+		params   map[string]any
+		wantStop any
+		wantBias any
+	}{
+		{
+			name:     "string stop",
+			params:   map[string]any{Stop: "END"},
+			wantStop: []any{"END"},
+		},
+		{
+			name:     "stop list and bias",
+			params:   map[string]any{Stop: []string{"END", "\n\n"}, LogitBias: map[string]int{"50256": 5}},
+			wantStop: []any{"END", "\n\n"},
+			wantBias: map[string]any{"50256": float64(5)},
+		},
+		{
+			name:     "banned tokens override bias",
+			params:   map[string]any{LogitBias: map[int]int{42: 10, 7: -5}, BannedTokens: []int{42, 1234}},
+			wantBias: map[string]any{"42": float64(-100), "7": float64(-5), "1234": float64(-100)},
+		},
+		{
+			name:     "json",
+			params:   jsonParams(t, `{"stop": ["END"], "logit_bias": {"50256": -20}, "banned_tokens": [99]}`),
+			wantStop: []any{"END"},
+			wantBias: map[string]any{"50256": float64(-20), "99": float64(-100)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newTestServer(t, openAICompletionBody)
+			generate(t, OPENAI, srv.URL, tt.params)
+			req := srv.last()
+			if got := req["stop"]; !reflect.DeepEqual(got, tt.wantStop) {
+				t.Errorf("stop = %#v, want %#v", got, tt.wantStop)
+			}
+			if got := req["logit_bias"]; !reflect.DeepEqual(got, tt.wantBias) {
+				t.Errorf("logit_bias = %#v, want %#v", got, tt.wantBias)
+			}
+		})
+	}
+}
+
+func TestStopOllama(t *testing.T) {
+	tests := []struct {
+		name   string
+		params map[string]any
+		want   any
+	}{
+		{name: "string", params: map[string]any{Stop: "END"}, want: []any{"END"}},
+		{name: "list", params: map[string]any{Stop: []string{"END", "STOP"}}, want: []any{"END", "STOP"}},
+		{name: "json", params: jsonParams(t, `{"stop": ["END"]}`), want: []any{"END"}},
+		{name: "invalid", params: map[string]any{Stop: 3}, want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newTestServer(t, ollamaGenerateBody)
+			params := map[string]any{LogitBias: map[string]int{"1": 1}, BannedTokens: []int{2}}
+			for k, v := range tt.params {
+				params[k] = v
+			}
+			generate(t, OLLAMA, srv.URL, params)
+			options, _ := srv.last()["options"].(map[string]any)
+			if got := options["stop"]; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("stop = %#v, want %#v", got, tt.want)
+			}
+			// the logit bias parameters are dropped rather than left for Ollama to warn about
+			for _, key := range []string{LogitBias, BannedTokens} {
+				if _, ok := options[key]; ok {
+					t.Errorf("%s was sent to Ollama", key)
+				}
+			}
+		})
+	}
+}
+
+func TestStopGemini(t *testing.T) {
+	tests := []struct {
+		name   string
+		params map[string]any
+		want   any
+	}{
+		{name: "string", params: map[string]any{Stop: "END"}, want: []any{"END"}},
+		{name: "list", params: map[string]any{Stop: []string{"END", "STOP"}}, want: []any{"END", "STOP"}},
+		{name: "json", params: jsonParams(t, `{"stop": ["END"]}`), want: []any{"END"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newTestServer(t, geminiResponseBody)
+			generate(t, GEMINI, srv.URL, tt.params)
+			config, _ := srv.last()["generationConfig"].(map[string]any)
+			if got := config["stopSequences"]; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("stopSequences = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package genai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+
+	gemini "google.golang.org/genai"
+)
+
+// PingFailure describes why a provider could not be reached
+type PingFailure string
+
+const (
+	PingAuth    PingFailure = "auth"
+	PingNetwork PingFailure = "network"
+	PingQuota   PingFailure = "quota"
+	PingUnknown PingFailure = "unknown"
+)
+
+// PingError is returned by Provider.Ping when the provider is not usable
+type PingError struct {
+	Provider string
+	Kind     PingFailure
+	Err      error
+}
+
+func (e *PingError) Error() string {
+	return fmt.Sprintf("%s provider %s failure: %v", e.Provider, e.Kind, e.Err)
+}
+
+func (e *PingError) Unwrap() error {
+	return e.Err
+}
+
+// Ping verifies that the provider is reachable and that the credentials are accepted
+// by listing models. It is cheap enough to call before starting a long agent run.
+func (p *Provider) Ping(ctx context.Context) error {
+	var err error
+	switch p.Provider {
+	case GEMINI:
+		_, err = p.Client.Gemini.Models.List(ctx, &gemini.ListModelsConfig{PageSize: 1})
+	case OLLAMA:
+		if err = p.Client.Ollama.Heartbeat(ctx); err == nil {
+			_, err = p.Client.Ollama.List(ctx)
+		}
+	case OPENAI, VLLM:
+		_, err = p.Client.OpenAI.client.Models.List(ctx)
+	default:
+		err = fmt.Errorf("unsupported provider: %s", p.Provider)
+	}
+	if err != nil {
+		err = wrapProviderError(p.Provider, err)
+		return &PingError{Provider: p.Provider, Kind: classifyPingError(err), Err: err}
+	}
+	return nil
+}
+
+func classifyPingError(err error) PingFailure {
+	switch {
+	case errors.Is(err, ErrAuth):
+		return PingAuth
+	case errors.Is(err, ErrRateLimited):
+		return PingQuota
+	}
+	var netErr net.Error
+	var urlErr *url.Error
+	if statusCode(err) == 0 && (errors.As(err, &netErr) || errors.As(err, &urlErr)) {
+		return PingNetwork
+	}
+	return PingUnknown
+}
@@ -0,0 +1,209 @@
+package genai
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// PostProcessor rewrites a chat response before it is delivered on Recv or Events. A
+// processor that fails is skipped and its error is logged.
+type PostProcessor func(text string) (string, error)
+
+// postProcess runs the model's post-processors over a response
+func (m *Model) postProcess(text string) string {
+	for _, process := range m.PostProcessors {
+		processed, err := process(text)
+		if err != nil {
+			m.Logger.Error(err, "Post-processor failed, delivering the response without it")
+			continue
+		}
+		text = processed
+	}
+	return text
+}
+
+var (
+	blankLinesRegex = regexp.MustCompile(`\n{3,}`)
+	headingRegex    = regexp.MustCompile(`^(#{1,6})([^#\s])`)
+)
+
+// NormalizeMarkdown cleans up the markdown of a response: line endings are converted to
+// \n, trailing whitespace and runs of blank lines outside code blocks are removed,
+// headings get a space after the #s and an unterminated code block is closed.
+func NormalizeMarkdown(text string) (string, error) {
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	inCode := false
+	var sb strings.Builder
+	var prose []string
+	flush := func() {
+		sb.WriteString(blankLinesRegex.ReplaceAllString(strings.Join(prose, "\n"), "\n\n"))
+		prose = prose[:0]
+	}
+	for _, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			// fences belong to the surrounding prose so blank lines around them are collapsed
+			prose = append(prose, strings.TrimRight(line, " \t"))
+			if !inCode {
+				flush()
+				sb.WriteString("\n")
+			}
+			inCode = !inCode
+			continue
+		}
+		if inCode {
+			sb.WriteString(line + "\n")
+			continue
+		}
+		line = strings.TrimRight(line, " \t")
+		prose = append(prose, headingRegex.ReplaceAllString(line, "$1 $2"))
+	}
+	flush()
+	normalized := sb.String()
+	if inCode {
+		normalized += "```"
+	}
+	return strings.TrimSpace(normalized), nil
+}
+
+var codeBlockRegex = regexp.MustCompile("(?ms)^[ \t]*```[ \t]*([\\w+#.-]*)[^\n]*\n(.*?)^[ \t]*```[ \t]*$")
+
+// ExtractCodeBlocks returns a post-processor that replaces a response with the contents
+// of its fenced code blocks, separated by blank lines. An empty language keeps every
+// block, otherwise only blocks tagged with one of the languages are kept. Responses
+// without matching blocks are delivered unchanged.
+func ExtractCodeBlocks(languages ...string) PostProcessor {
+	return func(text string) (string, error) {
+		var blocks []string
+		for _, match := range codeBlockRegex.FindAllStringSubmatch(text, -1) {
+			if len(languages) > 0 && !containsFold(languages, match[1]) {
+				continue
+			}
+			blocks = append(blocks, strings.TrimRight(match[2], "\n"))
+		}
+		if len(blocks) == 0 {
+			return text, nil
+		}
+		return strings.Join(blocks, "\n\n"), nil
+	}
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	markdownLinkRegex = regexp.MustCompile(`\[([^\]]*)\]\((https?://[^\s)]+)\)`)
+	bareURLRegex      = regexp.MustCompile(`https?://[^\s<>()\[\]"'` + "`" + `]+[^\s<>()\[\]"'.,;:!?` + "`" + `]`)
+)
+
+// maxCheckedLinks is the number of links ValidateLinks checks in a response
+const maxCheckedLinks = 20
+
+// ValidateLinks returns a post-processor that checks the links in a response, which
+// models often make up. Markdown links that can't be reached are replaced by their text
+// and bare URLs are marked as unreachable. A nil client uses one with a 5 second timeout.
+func ValidateLinks(client *http.Client) PostProcessor {
+	if client == nil {
+		client = &http.Client{Timeout: 5 * time.Second}
+	}
+	return func(text string) (string, error) {
+		reachable := make(map[string]bool)
+		check := func(url string) bool {
+			ok, checked := reachable[url]
+			if !checked {
+				if len(reachable) >= maxCheckedLinks {
+					return true
+				}
+				ok = linkReachable(client, url)
+				reachable[url] = ok
+			}
+			return ok
+		}
+		text = markdownLinkRegex.ReplaceAllStringFunc(text, func(link string) string {
+			match := markdownLinkRegex.FindStringSubmatch(link)
+			if check(match[2]) {
+				return link
+			}
+			return match[1]
+		})
+		// markdown links are skipped so their URLs are not marked twice
+		var sb strings.Builder
+		last := 0
+		links := markdownLinkRegex.FindAllStringIndex(text, -1)
+		for _, loc := range bareURLRegex.FindAllStringIndex(text, -1) {
+			if insideAny(loc, links) {
+				continue
+			}
+			sb.WriteString(text[last:loc[1]])
+			if !check(text[loc[0]:loc[1]]) {
+				sb.WriteString(" (unreachable)")
+			}
+			last = loc[1]
+		}
+		sb.WriteString(text[last:])
+		return sb.String(), nil
+	}
+}
+
+func insideAny(loc []int, spans [][]int) bool {
+	for _, span := range spans {
+		if loc[0] >= span[0] && loc[1] <= span[1] {
+			return true
+		}
+	}
+	return false
+}
+
+// linkReachable reports whether url answers with a status below 400, servers that
+// don't allow HEAD are asked with GET
+func linkReachable(client *http.Client, url string) bool {
+	for _, method := range []string{http.MethodHead, http.MethodGet} {
+		req, err := http.NewRequestWithContext(context.Background(), method, url, nil)
+		if err != nil {
+			return false
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return false
+		}
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusMethodNotAllowed || resp.StatusCode == http.StatusNotImplemented {
+			continue
+		}
+		return resp.StatusCode < 400
+	}
+	return false
+}
+
+// DefaultProfanity is the word list MaskProfanity uses when it is given none
+var DefaultProfanity = []string{
+	"fuck", "fucking", "fucked", "shit", "shitty", "bitch", "bastard", "asshole",
+	"damn", "crap", "dick", "piss", "cunt", "motherfucker", "bullshit",
+}
+
+// MaskProfanity returns a post-processor that replaces the letters of the given words
+// after the first with asterisks. Words are matched whole and ignoring case.
+func MaskProfanity(words ...string) PostProcessor {
+	if len(words) == 0 {
+		words = DefaultProfanity
+	}
+	quoted := make([]string, len(words))
+	for i, word := range words {
+		quoted[i] = regexp.QuoteMeta(word)
+	}
+	wordsRegex := regexp.MustCompile(`(?i)\b(` + strings.Join(quoted, "|") + `)\b`)
+	return func(text string) (string, error) {
+		return wordsRegex.ReplaceAllStringFunc(text, func(word string) string {
+			runes := []rune(word)
+			return string(runes[0]) + strings.Repeat("*", len(runes)-1)
+		}), nil
+	}
+}
@@ -2,7 +2,13 @@ package genai
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/go-logr/logr"
 	"github.com/google/uuid"
@@ -17,6 +23,8 @@ const (
 	ANTHROPIC = "anthropic"
 	OPENAI    = "openai"
 	OLLAMA    = "ollama"
+	// VLLM is an OpenAI compatible server that accepts additional sampling parameters
+	VLLM = "vllm"
 )
 
 type Provider struct {
@@ -27,7 +35,44 @@ type Provider struct {
 	Client        *Client
 	Model         *Model
 	EmbeddingModel string
+	// SpeechModel is used by Speak, DefaultSpeechModel when empty
+	SpeechModel string `json:"speechModel,omitempty"`
 	Log           logr.Logger
+	// Retry is applied to chat, generate and embedding calls
+	Retry    RetryPolicy `json:"-"`
+	Timeouts Timeouts    `json:"-"`
+	// Aliases map names such as "fast" to model ids
+	Aliases map[string]string `json:"aliases,omitempty"`
+	// Routes switch requests to another model based on their estimated size.
+	// They are applied to every request.
+	Routes []RoutingRule `json:"routes,omitempty"`
+	// HTTPClient, ProxyURL, TLSConfig and Headers configure the transport of the provider SDK
+	HTTPClient *http.Client      `json:"-"`
+	ProxyURL   string            `json:"proxyURL,omitempty"`
+	TLSConfig  *tls.Config       `json:"-"`
+	Headers    map[string]string `json:"headers,omitempty"`
+	// RequestIDHeader is the header request IDs are sent in, see ProviderOptions
+	RequestIDHeader string `json:"requestIDHeader,omitempty"`
+	// TokenCounter counts tokens for CountTokens and context length checks
+	TokenCounter TokenCounter `json:"-"`
+	// UtilityModel generates compaction summaries and summaries of tool results
+	UtilityModel string `json:"utilityModel,omitempty"`
+	// SummaryPrompt replaces the instructions used to summarize conversations and tool results
+	SummaryPrompt string `json:"summaryPrompt,omitempty"`
+	// SemanticCache answers prompts that mean the same as an earlier one with its response
+	SemanticCache *SemanticCache `json:"-"`
+
+	balancer *balancer
+	// cache is set with ProviderOptions.Cache
+	cache *responseCache
+	// metrics is set with ProviderOptions.Metrics
+	metrics Metrics
+	// hooks are set with the On* fields of ProviderOptions
+	hooks hooks
+	// life tracks the chats and in-flight work for Shutdown
+	life *lifecycle
+	// checkpoints is set with ProviderOptions.Checkpoints
+	checkpoints tools.ConversationStore
 }
 
 type ProviderOptions struct {
@@ -35,52 +80,182 @@ type ProviderOptions struct {
 	APIKey        string
 	BaseURL       string
 	EmbeddingModel string
+	SpeechModel    string
 	Log           logr.Logger
+	// LogPolicy is applied to Log, redacting secrets, truncating content and hashing
+	// personal data before they are logged. Loggers assigned to Provider.Log later are
+	// used as they are, wrap them with tools.NewPolicyLogger.
+	LogPolicy *tools.LogPolicy
+	// Retry overrides DefaultRetryPolicy, unset fields keep their defaults
+	Retry   *RetryPolicy
+	Aliases map[string]string
+	Routes  []RoutingRule
+	// Endpoints are used in addition to APIKey and BaseURL, requests are spread
+	// over all of them according to Balance (RoundRobin by default)
+	Endpoints []Endpoint
+	Balance   BalanceStrategy
+	// HTTPClient is used as the base client, ProxyURL and TLSConfig are applied to a
+	// copy of its transport and Headers are sent with every request
+	HTTPClient *http.Client
+	ProxyURL   string
+	TLSConfig  *tls.Config
+	Headers    map[string]string
+	// RequestIDHeader is the header the ID of each chat turn and generate call is sent
+	// in, OpenAIRequestIDHeader for OpenAI and DefaultRequestIDHeader otherwise. Set it
+	// to "-" to not send request IDs.
+	RequestIDHeader string
+	// Timeouts override DefaultTimeouts, ModelOptions.Timeouts override them per model
+	Timeouts Timeouts
+	// SkipEmbeddingCheck disables the probe NewProvider sends when EmbeddingModel is set
+	SkipEmbeddingCheck bool
+	// TokenCounter replaces the provider's default tokenizer
+	TokenCounter TokenCounter
+	// UtilityModel is a model or alias for compaction and tool result summaries. Compaction
+	// uses the chat's own model and tool results DefaultSummaryModel when it is empty.
+	UtilityModel string
+	// SummaryPrompt replaces the summarization instructions, the content is appended to it
+	SummaryPrompt string
+	// Cache returns stored responses for deterministic requests, with a temperature of 0
+	// or a fixed seed, instead of sending them again. See NewMemoryCache, NewDiskCache
+	// and NewRedisCache.
+	Cache ResponseCache
+	// SemanticCache serves the response to a similar earlier prompt for Generate requests
+	SemanticCache *SemanticCache
+	// Metrics receives the requests, tokens, retries, failures and tool calls of the
+	// provider, see NewPrometheusMetrics
+	Metrics Metrics
+	// OnRequest is called before each request to the model. An error fails the request
+	// without sending it, e.g. when a tenant is over its budget.
+	OnRequest func(RequestInfo) error
+	// OnResponse is called with each response of the model, or the error of its request
+	OnResponse func(ResponseInfo)
+	// OnToolCall is called after each tool call with its arguments and result
+	OnToolCall func(ToolCallInfo)
+	// OnUsage is called with the tokens of each response, including cached responses
+	OnUsage func(UsageInfo)
+	// Checkpoints receives the transcript of each open conversation on Shutdown, under
+	// CheckpointKey. Conversations are not saved when it is nil.
+	Checkpoints tools.ConversationStore
 }
 
 type Chat struct {
 	ctx                context.Context
 	Send               chan string
+	// Messages sends a message with attachments such as images, use it instead of Send
+	Messages chan ChatMessage
+	// Events receives the text, tool calls, usage and errors of each response for chats
+	// created with ChatEvents. It must be drained.
+	Events chan Event
+	// Recv and GenerationComplete receive the response and the end of each turn for chats
+	// created with Chat, they are nil for chats created with ChatEvents.
+	//
+	// Deprecated: use ChatEvents and Events, which also report tool calls and errors.
 	Recv               chan string
 	GenerationComplete chan bool
-	Done               chan bool
+	// Errors receives the error of each failed turn of chats created with Chat, before
+	// GenerationComplete. It is buffered and errors are dropped when it is not drained.
+	Errors chan error
+	Done   chan bool
 	Logger             logr.Logger
 	Turns              int
+	model              *Model
+	// Reasoning receives the reasoning of each response when ModelOptions.Reasoning is
+	// ReasoningCapture, before the response is sent on Recv. It must be drained.
+	Reasoning chan string
+	// requests carries the messages sent with SendCtx and SendMessageCtx
+	requests   chan chatRequest
+	turnMu     sync.Mutex
+	cancelTurn context.CancelFunc
+	// response collects the text deltas of the current turn for TurnComplete
+	response strings.Builder
+	// updates are changes to the model queued between turns, see reconfigure.go
+	updates  []func(m *Model)
+	updateMu sync.Mutex
+	// restoreHistory is the history before a regenerated response was removed
+	restoreHistory []Message
+}
+
+// chatErrorBuffer is the number of turn errors Chat.Errors holds
+const chatErrorBuffer = 8
+
+// chatRequest is a message sent with its own context
+type chatRequest struct {
+	ctx context.Context
+	msg ChatMessage
+	// regenerate sends the last user message again instead of msg
+	regenerate bool
 }
 
-// NewProvider creates a new provider with a default logr.Discard() logger
+// NewProvider creates a new provider logging to options.Log, or with a default
+// logr.Discard() logger when it is unset
 func NewProvider(provider string, options ProviderOptions) (*Provider, error) {
-	p := &Provider{
-		Provider:       provider,
-		Name:           options.Name,
-		APIKey:         options.APIKey,
-		BaseURL:        options.BaseURL,
-		EmbeddingModel: options.EmbeddingModel,
-		Log:            logr.Discard(),
-	}
-	client, err := NewClient(p)
-	if err != nil {
-		return nil, err
+	if options.Log.GetSink() == nil {
+		options.Log = logr.Discard()
 	}
-	p.Client = client
-	return p, nil
+	return newProvider(provider, options)
 }
 
 // NewProviderWithLog creates a new provider with a custom logr.Logger
 func NewProviderWithLog(provider string, options ProviderOptions) (*Provider, error) {
+	return newProvider(provider, options)
+}
+
+// newProvider creates a provider logging to options.Log
+func newProvider(provider string, options ProviderOptions) (*Provider, error) {
 	p := &Provider{
 		Provider:       provider,
 		Name:           options.Name,
 		APIKey:         options.APIKey,
 		BaseURL:        options.BaseURL,
 		EmbeddingModel: options.EmbeddingModel,
+		SpeechModel:    options.SpeechModel,
 		Log:            options.Log,
+		Retry:          DefaultRetryPolicy,
+		Aliases:        options.Aliases,
+		Routes:         options.Routes,
+		HTTPClient:     options.HTTPClient,
+		ProxyURL:       options.ProxyURL,
+		TLSConfig:      options.TLSConfig,
+		Headers:        options.Headers,
+		Timeouts:       options.Timeouts.merge(DefaultTimeouts),
+		UtilityModel:   options.UtilityModel,
+		SummaryPrompt:  options.SummaryPrompt,
+		SemanticCache:  options.SemanticCache,
+		cache:          newResponseCache(options.Cache),
+		metrics:        options.Metrics,
 	}
+	if options.Retry != nil {
+		p.Retry = options.Retry.withDefaults()
+	}
+	if options.LogPolicy != nil {
+		p.Log = tools.NewPolicyLogger(p.Log, *options.LogPolicy)
+	}
+	p.RequestIDHeader = options.RequestIDHeader
+	p.hooks = hooks{
+		onRequest:  options.OnRequest,
+		onResponse: options.OnResponse,
+		onToolCall: options.OnToolCall,
+		onUsage:    options.OnUsage,
+	}
+	p.life = newLifecycle()
+	p.checkpoints = options.Checkpoints
 	client, err := NewClient(p)
 	if err != nil {
 		return nil, err
 	}
 	p.Client = client
+	p.TokenCounter = options.TokenCounter
+	if p.TokenCounter == nil {
+		p.TokenCounter = defaultTokenCounter(p)
+	}
+	if err := p.initEndpoints(options.Balance, options.Endpoints); err != nil {
+		return nil, err
+	}
+	if p.EmbeddingModel != "" && !options.SkipEmbeddingCheck {
+		if err := p.verifyEmbeddingModel(client.ctx); err != nil {
+			return nil, err
+		}
+	}
 	return p, nil
 }
 
@@ -88,49 +263,300 @@ func (p *Provider) Models() []string {
 	return p.Client.Models()
 }
 
+// ModelsWithError returns the available models or the error that prevented listing them
+func (p *Provider) ModelsWithError() ([]string, error) {
+	return p.Client.ModelsWithError()
+}
+
+// Chat starts a chat whose responses are sent on Recv, followed by GenerationComplete
 func (p *Provider) Chat(modelOptions ModelOptions, toolsToUse []*tools.Tool) *Chat {
-	l := p.Log.WithName("chat").WithValues("model", modelOptions.ModelName, "id", uuid.New().String())
+	return p.newChat(modelOptions, toolsToUse, false)
+}
+
+// ChatEvents starts a chat that reports its responses, tool calls, usage and errors on
+// Events
+func (p *Provider) ChatEvents(modelOptions ModelOptions, toolsToUse []*tools.Tool) *Chat {
+	return p.newChat(modelOptions, toolsToUse, true)
+}
+
+func (p *Provider) newChat(modelOptions ModelOptions, toolsToUse []*tools.Tool, events bool) *Chat {
+	id := uuid.New().String()
+	l := p.Log.WithName("chat").WithValues("model", modelOptions.ModelName, "id", id)
 	chat := &Chat{
-		ctx:                p.Client.ctx,
-		Send:               make(chan string),
-		Recv:               make(chan string),
-		GenerationComplete: make(chan bool),
-		Done:               make(chan bool),
-		Logger:             l,
+		ctx:      p.Client.ctx,
+		Send:     make(chan string),
+		Messages: make(chan ChatMessage),
+		Done:     make(chan bool),
+		Logger:   l,
+		requests: make(chan chatRequest),
+	}
+	if events {
+		chat.Events = make(chan Event, 16)
+	} else {
+		chat.Recv = make(chan string)
+		chat.GenerationComplete = make(chan bool)
+		chat.Errors = make(chan error, chatErrorBuffer)
 	}
 	model := NewModel(p, modelOptions, l)
 	for _, tool := range toolsToUse {
 		model.AddTool(tool)
 	}
-	go model.chat(chat.ctx, chat)
+	chat.model = model
+	model.session.ConversationID = id
+	if model.Reasoning == ReasoningCapture {
+		chat.Reasoning = make(chan string, 1)
+	}
+	p.life.addChat(chat)
+	go func() {
+		model.chat(chat.ctx, chat)
+		p.life.removeChat(chat)
+	}()
 
 	return chat
 }
 
+// History returns the conversation so far as provider neutral messages
+func (c *Chat) History() []Message {
+	return c.model.History()
+}
+
+// SendCtx sends a message like Send. Generating the response, including any tool calls,
+// is aborted when ctx is done. It returns ctx.Err() if ctx ends before the chat accepts
+// the message.
+func (c *Chat) SendCtx(ctx context.Context, text string) error {
+	return c.SendMessageCtx(ctx, ChatMessage{Text: text})
+}
+
+// SendMessageCtx sends a message with attachments like Messages, aborting the response
+// when ctx is done. It returns ErrShuttingDown after Provider.Shutdown.
+func (c *Chat) SendMessageCtx(ctx context.Context, msg ChatMessage) error {
+	closed := c.model.Provider.life.closed
+	select {
+	case <-closed:
+		return ErrShuttingDown
+	default:
+	}
+	select {
+	case c.requests <- chatRequest{ctx: ctx, msg: msg}:
+		return nil
+	case <-closed:
+		return ErrShuttingDown
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Cancel aborts the response being generated. Requests to the provider are canceled and
+// no further tool calls are started, a tool that is already running is left to finish in
+// the background. The message is removed from the history and the turn completes without
+// a response. Cancel does nothing when no response is being generated.
+func (c *Chat) Cancel() {
+	c.turnMu.Lock()
+	defer c.turnMu.Unlock()
+	if c.cancelTurn != nil {
+		c.cancelTurn()
+	}
+}
+
+// receive waits for the next user message and returns it with the context of its turn,
+// it returns false when the chat is done
+func (c *Chat) receive(ctx context.Context, m *Model) (Message, context.Context, bool) {
+	var msg ChatMessage
+	select {
+	case text := <-c.Send:
+		return NewTextMessage(RoleUser, text), ctx, true
+	case msg = <-c.Messages:
+	case request := <-c.requests:
+		if request.regenerate {
+			if last, ok := c.popResponse(m); ok {
+				return last, request.ctx, true
+			}
+			c.Logger.Info("Nothing to regenerate")
+			return c.receive(ctx, m)
+		}
+		ctx, msg = request.ctx, request.msg
+	case <-c.Done:
+		return Message{}, nil, false
+	case <-m.Provider.life.closed:
+		return Message{}, nil, false
+	}
+	resolved, err := m.resolveAttachments(ctx, msg.message())
+	if err != nil {
+		// tell the model so it can explain the missing image instead of failing the turn
+		m.Logger.Error(err, "Failed to load attachments")
+		resolved = msg.message()
+		resolved.Parts = append(withoutURLImages(resolved.Parts), Part{Type: TextPart, Text: fmt.Sprintf("[attachment could not be loaded: %v]", err)})
+	}
+	return resolved, ctx, true
+}
+
+// runTurn adds msg to the history and generates the response with a context that Cancel
+// aborts. A canceled turn is removed from the history so the conversation continues as
+// if the message was never sent. The turn ends with its usage and TurnComplete. After
+// Provider.Shutdown the turn fails with ErrShuttingDown without being added, a turn whose
+// message or response a guardrail blocked is removed like a canceled one.
+func (c *Chat) runTurn(ctx context.Context, m *Model, msg Message, generate func(ctx context.Context) error) error {
+	start := time.Now()
+	ctx, end, err := m.Provider.life.begin(ctx)
+	if err != nil {
+		c.emit(ErrorEvent{Err: err})
+		c.emit(TurnComplete{Duration: time.Since(start), RequestID: RequestID(ctx)})
+		return err
+	}
+	defer end()
+	turnContext, cancel := context.WithCancel(ctx)
+	c.turnMu.Lock()
+	c.cancelTurn = cancel
+	c.turnMu.Unlock()
+	defer func() {
+		c.turnMu.Lock()
+		c.cancelTurn = nil
+		c.turnMu.Unlock()
+		cancel()
+	}()
+
+	turnContext, requestID := ensureRequestID(turnContext)
+	// the logs of the turn carry its request ID
+	logger := m.Logger
+	m.Logger = logger.WithValues("requestID", requestID)
+	defer func() { m.Logger = logger }()
+
+	c.applyUpdates(m)
+	history := m.History()
+	if c.restoreHistory != nil {
+		history, c.restoreHistory = c.restoreHistory, nil
+	}
+	msg, err = m.guardPrompt(turnContext, c, msg)
+	if err != nil {
+		m.setHistory(history)
+		c.emit(ErrorEvent{Err: err})
+		c.emit(TurnComplete{Duration: time.Since(start), RequestID: requestID})
+		return err
+	}
+	m.appendHistory(msg)
+	m.resetToolFailures()
+	m.resetToolLoops()
+	m.startUsageTurn(requestID)
+	m.setRequestID(requestID)
+	m.setLogprobs(nil)
+	m.setGrounding(nil)
+	m.routeTurn(turnContext, msg)
+	m.selectTools(turnContext, msg)
+	c.response.Reset()
+	err = generate(turnContext)
+	canceled := turnContext.Err() != nil
+	if canceled {
+		m.setHistory(history)
+		m.Logger.Info("Generation canceled", "reason", context.Cause(turnContext))
+		err = nil
+	} else if err != nil {
+		var blocked *GuardrailError
+		if errors.As(err, &blocked) {
+			// the blocked exchange is dropped so later turns don't see it
+			m.setHistory(history)
+		}
+		c.emit(ErrorEvent{Err: err})
+	}
+	usage := m.Usage()
+	if !canceled {
+		m.tagResponses(usage.Turns[len(usage.Turns)-1].Model)
+	}
+	c.emit(UsageEvent{Turn: usage.Turns[len(usage.Turns)-1], Total: usage})
+	complete := TurnComplete{Canceled: canceled, Duration: time.Since(start), RequestID: requestID}
+	if !canceled && err == nil {
+		complete.Text = c.response.String()
+		complete.Logprobs = m.Logprobs()
+		complete.Grounding = m.Grounding()
+	}
+	c.emit(complete)
+	return err
+}
+
 func (p *Provider) Generate(modelOptions ModelOptions, prompt string) (string, error) {
+	text, _, err := p.GenerateWithUsage(modelOptions, prompt)
+	return text, err
+}
+
+// GenerateWithUsage runs a single prompt and reports the tokens used
+func (p *Provider) GenerateWithUsage(modelOptions ModelOptions, prompt string) (string, Usage, error) {
+	return p.generateWithUsage(context.Background(), modelOptions, prompt)
+}
+
+// generateWithUsage is GenerateWithUsage canceled with ctx
+func (p *Provider) generateWithUsage(ctx context.Context, modelOptions ModelOptions, prompt string) (string, Usage, error) {
+	ctx, end, err := p.life.begin(ctx)
+	if err != nil {
+		return "", Usage{}, err
+	}
+	defer end()
+	ctx, requestID := ensureRequestID(ctx)
+	l := p.Log.WithName("generate").WithValues("model", modelOptions.ModelName, "id", requestID)
+	model := NewModel(p, modelOptions, l)
+	model.setRequestID(requestID)
+	switch p.Provider {
+	case OLLAMA:
+		model.ollamaClient = p.Client.Ollama
+	case OPENAI, VLLM:
+		model.openAIClient = p.Client.OpenAI
+	}
+	text, err := model.generateMessageCtx(ctx, NewTextMessage(RoleUser, prompt))
+	return text, model.Usage(), err
+}
+
+// GenerateMessage runs a single prompt with attachments such as images
+func (p *Provider) GenerateMessage(modelOptions ModelOptions, msg ChatMessage) (string, error) {
+	ctx, end, err := p.life.begin(p.Client.ctx)
+	if err != nil {
+		return "", err
+	}
+	defer end()
 	l := p.Log.WithName("generate").WithValues("model", modelOptions.ModelName, "id", uuid.New().String())
 	model := NewModel(p, modelOptions, l)
 	switch p.Provider {
 	case OLLAMA:
 		model.ollamaClient = p.Client.Ollama
-	case OPENAI:
+	case OPENAI, VLLM:
 		model.openAIClient = p.Client.OpenAI
 	}
-	return model.generate(prompt, modelOptions)
+	resolveCtx, cancel := context.WithTimeout(ctx, model.timeouts().Generate)
+	defer cancel()
+	resolved, err := model.resolveAttachments(resolveCtx, msg.message())
+	if err != nil {
+		return "", err
+	}
+	if err := resolved.Validate(); err != nil {
+		return "", err
+	}
+	return model.generateMessageCtx(ctx, resolved)
 }
 
 func (p *Provider) RunTool(toolName string, args map[string]any) (any, error) {
+	_, end, err := p.life.begin(p.Client.ctx)
+	if err != nil {
+		return err.Error(), err
+	}
+	defer end()
+	return p.runTool(toolName, args, Session{}, p.utility())
+}
+
+// runTool runs a registered tool with the conversation's session context applied
+func (p *Provider) runTool(toolName string, args map[string]any, session Session, utility utilitySettings) (any, error) {
 	tool, err := tools.GetTool(toolName)
 	if err != nil {
 		return err.Error(), err
 	}
+	if args == nil {
+		args = make(map[string]any)
+	}
 	for key, value := range tool.Options {
-		args[key] = value
+		args[key] = tools.ResolveOption(value, session.Env)
 	}
+	session.apply(args)
 	if DEBUG {
 		p.Log.Info("Running tool", "toolName", toolName, "args", args)
 	}
 	var result any
+	started := time.Now()
 	switch p.Provider {
 	case GEMINI:
 		result, err = tools.RunGeminiTool(toolName, args)
@@ -140,38 +566,58 @@ func (p *Provider) RunTool(toolName string, args map[string]any) (any, error) {
 		} else {
 			err = fmt.Errorf("tool %s does not have a run function", toolName)
 		}
-	case OPENAI:
+	case OPENAI, VLLM:
 		if tool.Run != nil {
 			result, err = tool.Run(args)
 		} else {
 			err = fmt.Errorf("tool %s does not have a run function", toolName)
 		}
 	}
+	duration := time.Since(started)
+	p.observer().ToolCall(toolName, duration, err)
+	if p.hooks.onToolCall != nil {
+		p.hooks.onToolCall(ToolCallInfo{
+			Tool:           toolName,
+			Args:           args,
+			Result:         result,
+			Duration:       duration,
+			Err:            err,
+			RequestID:      session.RequestID,
+			ConversationID: session.ConversationID,
+		})
+	}
 	if DEBUG {
 		p.Log.Info("Tool result", "result", result)
 	}
-	if tool.Summarize {
-		return p.Generate(ModelOptions{
-			ModelName: "llamacpp/qwen3-30b-a3b",
-			Parameters: map[string]any{
-				NumPredict: 5000,
-			},
-		}, fmt.Sprintf(`Summarize these tool results in 5000 words or less. Your summarization must be shorter than the provided value\n
-				If there appears to be an error, just return the error with no additional information\n
-				Do not provide any reference to the word count or the fact that you summarized. Simply return your content.\n\n%s`, result))
+	if tool.Summarize && err == nil {
+		return p.summarizeToolResult(args, result, utility)
 	}
 	return result, err
 }
 
-// GenerateEmbedding generates an embedding for a single text input using the appropriate provider
+// GenerateEmbedding generates an embedding for a single text input using the appropriate provider.
+// An empty model uses the provider's EmbeddingModel.
 func (p *Provider) GenerateEmbedding(ctx context.Context, text string, model string) ([]float32, error) {
+	if model == "" {
+		model = p.EmbeddingModel
+	}
+	ctx, cancel := context.WithTimeout(ctx, p.Timeouts.merge(DefaultTimeouts).Embedding)
+	defer cancel()
 	switch p.Provider {
 	case GEMINI:
-		return geminiGenerateEmbedding(ctx, p.Client.Gemini, text, model)
-	case OPENAI:
+		return retry(ctx, p.Retry, p.Log, GEMINI, p.observer(), func() ([]float32, error) {
+			return balanced(p, func(client *Client) ([]float32, error) {
+				return geminiGenerateEmbedding(ctx, client.Gemini, text, model)
+			})
+		})
+	case OPENAI, VLLM:
 		return p.Client.OpenAI.GenerateEmbedding(ctx, text, model)
 	case OLLAMA:
-		return ollamaGenerateEmbedding(ctx, p.Client.Ollama, text, model)
+		return retry(ctx, p.Retry, p.Log, OLLAMA, p.observer(), func() ([]float32, error) {
+			return balanced(p, func(client *Client) ([]float32, error) {
+				return ollamaGenerateEmbedding(ctx, client.Ollama, text, model)
+			})
+		})
 	default:
 		return nil, fmt.Errorf("unsupported provider for embeddings: %s", p.Provider)
 	}
@@ -179,13 +625,26 @@ func (p *Provider) GenerateEmbedding(ctx context.Context, text string, model str
 
 // GenerateEmbeddings generates embeddings for multiple text inputs using the appropriate provider
 func (p *Provider) GenerateEmbeddings(ctx context.Context, texts []string, model string) ([][]float32, error) {
+	if model == "" {
+		model = p.EmbeddingModel
+	}
+	ctx, cancel := context.WithTimeout(ctx, p.Timeouts.merge(DefaultTimeouts).Embedding)
+	defer cancel()
 	switch p.Provider {
 	case GEMINI:
-		return geminiGenerateEmbeddings(ctx, p.Client.Gemini, texts, model)
-	case OPENAI:
+		return retry(ctx, p.Retry, p.Log, GEMINI, p.observer(), func() ([][]float32, error) {
+			return balanced(p, func(client *Client) ([][]float32, error) {
+				return geminiGenerateEmbeddings(ctx, client.Gemini, texts, model)
+			})
+		})
+	case OPENAI, VLLM:
 		return p.Client.OpenAI.GenerateEmbeddings(ctx, texts, model)
 	case OLLAMA:
-		return ollamaGenerateEmbeddings(ctx, p.Client.Ollama, texts, model)
+		return retry(ctx, p.Retry, p.Log, OLLAMA, p.observer(), func() ([][]float32, error) {
+			return balanced(p, func(client *Client) ([][]float32, error) {
+				return ollamaGenerateEmbeddings(ctx, client.Ollama, texts, model)
+			})
+		})
 	default:
 		return nil, fmt.Errorf("unsupported provider for embeddings: %s", p.Provider)
 	}
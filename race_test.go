@@ -0,0 +1,6 @@
+//go:build race
+
+package genai
+
+// raceEnabled is set when the tests run with the race detector, which allocates
+const raceEnabled = true
@@ -0,0 +1,238 @@
+package genai
+
+import (
+	"fmt"
+	"maps"
+	"strconv"
+	"unicode/utf8"
+
+	gemini "google.golang.org/genai"
+
+	"github.com/jbutlerdev/genai/tools"
+)
+
+const (
+	// DefaultReadBudgetBytes is the size of the file and web tool results a chat may
+	// receive before ReadBudget steps in
+	DefaultReadBudgetBytes = 256 * 1024
+	// DefaultReadPageBytes is the size of a page once the budget is exhausted
+	DefaultReadPageBytes = 16 * 1024
+	// ReadCursorArg is the argument a model passes back to read the next page of a
+	// result paginated by ReadBudget
+	ReadCursorArg = "read_cursor"
+)
+
+// DefaultReadBudgetTools are the tools that read files and web pages
+var DefaultReadBudgetTools = []string{
+	"readFile",
+	"listFiles",
+	"tree",
+	"SearchWeb",
+	"RetrievePage",
+	tools.ObjectReadToolName,
+	tools.IngestFileToolName,
+}
+
+// ReadBudgetAction is what ReadBudget does with results once the budget is exhausted
+type ReadBudgetAction string
+
+const (
+	// ReadPaginate returns results a page at a time, the model reads on by calling the
+	// tool again with ReadCursorArg
+	ReadPaginate ReadBudgetAction = "paginate"
+	// ReadSummarize replaces results with a summary from the utility model
+	ReadSummarize ReadBudgetAction = "summarize"
+)
+
+// ReadBudget limits the output of file and web tools a chat receives, so a few large
+// files or pages can't crowd the conversation out of the context window. Results are
+// passed through until the budget is used up, later results are paginated or
+// summarized.
+type ReadBudget struct {
+	// MaxBytes is the total size of the results, DefaultReadBudgetBytes when zero
+	MaxBytes int
+	// MaxTokens additionally limits the estimated tokens of the results, no limit when zero
+	MaxTokens int
+	// Action is ReadPaginate when empty
+	Action ReadBudgetAction
+	// PageBytes is the size of a page, DefaultReadPageBytes when zero
+	PageBytes int
+	// Tools are the budgeted tools, DefaultReadBudgetTools when empty
+	Tools []string
+}
+
+// readState is the budget spent by a chat and the results being read a page at a time
+type readState struct {
+	bytes  int
+	tokens int
+	// pages holds the rendered results by tool call key
+	pages map[string]string
+}
+
+func (b *ReadBudget) maxBytes() int {
+	if b.MaxBytes > 0 {
+		return b.MaxBytes
+	}
+	return DefaultReadBudgetBytes
+}
+
+func (b *ReadBudget) pageBytes() int {
+	if b.PageBytes > 0 {
+		return b.PageBytes
+	}
+	return DefaultReadPageBytes
+}
+
+func (b *ReadBudget) budgeted(toolName string) bool {
+	names := b.Tools
+	if len(names) == 0 {
+		names = DefaultReadBudgetTools
+	}
+	for _, name := range names {
+		if name == toolName {
+			return true
+		}
+	}
+	return false
+}
+
+// exceeded reports that content does not fit in what is left of the budget
+func (b *ReadBudget) exceeded(state *readState, content string) bool {
+	if state.bytes+len(content) > b.maxBytes() {
+		return true
+	}
+	return b.MaxTokens > 0 && state.tokens+estimateTokens(content) > b.MaxTokens
+}
+
+// readBudgetKey identifies a budgeted call without its cursor, the calls for the
+// pages of a result share it
+func readBudgetKey(toolName string, args map[string]any) string {
+	args = maps.Clone(args)
+	delete(args, ReadCursorArg)
+	return toolCallKey(ToolCall{Name: toolName, Arguments: args})
+}
+
+// readPage serves the next page of a paginated result without running the tool
+func (m *Model) readPage(toolName string, args map[string]any) (any, bool, error) {
+	if m.ReadBudget == nil || !m.ReadBudget.budgeted(toolName) {
+		return nil, false, nil
+	}
+	cursor, ok := args[ReadCursorArg].(string)
+	if !ok || cursor == "" {
+		return nil, false, nil
+	}
+	m.toolMu.Lock()
+	content, ok := m.reads.pages[readBudgetKey(toolName, args)]
+	m.toolMu.Unlock()
+	if !ok {
+		return nil, true, &tools.ToolError{
+			Err:  fmt.Errorf("unknown %s: %s", ReadCursorArg, cursor),
+			Hint: fmt.Sprintf("Pass the %s of the previous page with the same arguments.", ReadCursorArg),
+		}
+	}
+	offset, err := strconv.Atoi(cursor)
+	if err != nil || offset < 0 || offset > len(content) {
+		return nil, true, &tools.ToolError{
+			Err:  fmt.Errorf("invalid %s: %s", ReadCursorArg, cursor),
+			Hint: fmt.Sprintf("Pass the %s of the previous page with the same arguments.", ReadCursorArg),
+		}
+	}
+	return m.paginate(toolName, args, content, offset), true, nil
+}
+
+// budgetRead counts a result of a budgeted tool against the chat's budget and replaces
+// it with its first page or a summary when it does not fit
+func (m *Model) budgetRead(toolName string, args map[string]any, result any) (any, error) {
+	b := m.ReadBudget
+	if b == nil || !b.budgeted(toolName) {
+		return result, nil
+	}
+	content := formatToolResult(readResponse(result))
+	m.toolMu.Lock()
+	exceeded := b.exceeded(&m.reads, content)
+	if !exceeded {
+		m.spendRead(content)
+	}
+	m.toolMu.Unlock()
+	if !exceeded {
+		return result, nil
+	}
+	action := b.Action
+	if action == "" {
+		action = ReadPaginate
+	}
+	m.Logger.Info("Read budget exhausted", "tool", toolName, "bytes", len(content), "action", action)
+	if len(content) <= b.pageBytes() {
+		// small results are sent as they are
+		m.toolMu.Lock()
+		m.spendRead(content)
+		m.toolMu.Unlock()
+		return result, nil
+	}
+	if action == ReadSummarize {
+		summary, err := m.Provider.summarizeToolResult(args, readResponse(result), m.utility())
+		m.toolMu.Lock()
+		m.spendRead(formatToolResult(summary))
+		m.toolMu.Unlock()
+		return summary, err
+	}
+	return m.paginate(toolName, args, content, 0), nil
+}
+
+// paginate returns the page of content starting at offset and keeps content for the
+// following pages
+func (m *Model) paginate(toolName string, args map[string]any, content string, offset int) map[string]any {
+	end := offset + m.ReadBudget.pageBytes()
+	if end > len(content) {
+		end = len(content)
+	}
+	// pages end on a rune boundary
+	for end < len(content) && end > offset && !utf8.RuneStart(content[end]) {
+		end--
+	}
+	page := content[offset:end]
+	key := readBudgetKey(toolName, args)
+	m.toolMu.Lock()
+	defer m.toolMu.Unlock()
+	m.spendRead(page)
+	result := map[string]any{
+		"content": page,
+		"offset":  offset,
+		"size":    len(content),
+	}
+	if end == len(content) {
+		delete(m.reads.pages, key)
+		return result
+	}
+	if m.reads.pages == nil {
+		m.reads.pages = make(map[string]string)
+	}
+	m.reads.pages[key] = content
+	next := strconv.Itoa(end)
+	result[tools.NextCursorKey] = next
+	result["note"] = fmt.Sprintf("The read budget of this conversation is used up, results are returned a page at a time. "+
+		"Only read on if you need the rest: call %s again with the same arguments and %s set to %q.", toolName, ReadCursorArg, next)
+	return result
+}
+
+// spendRead counts content against the budget, the caller holds toolMu
+func (m *Model) spendRead(content string) {
+	m.reads.bytes += len(content)
+	m.reads.tokens += estimateTokens(content)
+}
+
+// readResponse unwraps the response of a Gemini tool
+func readResponse(result any) any {
+	if r, ok := result.(gemini.FunctionResponse); ok {
+		return r.Response
+	}
+	return result
+}
+
+// ReadBudgetUsed returns the bytes and estimated tokens of file and web tool results
+// the chat has received
+func (m *Model) ReadBudgetUsed() (bytes int, tokens int) {
+	m.toolMu.Lock()
+	defer m.toolMu.Unlock()
+	return m.reads.bytes, m.reads.tokens
+}
@@ -0,0 +1,101 @@
+package genai
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/jbutlerdev/genai/tools"
+)
+
+// budgetModel returns a model with the read budget whose budget_read tool returns the
+// content of its size argument
+func budgetModel(t *testing.T, budget *ReadBudget) *Model {
+	t.Helper()
+	tools.RegisterTool(tools.Tool{
+		Name:        "budget_read",
+		Description: "Read a file",
+		Parameters:  []tools.Parameter{{Name: "size", Type: "number", Description: "Size of the file", Required: true}},
+		Run: func(args map[string]any) (map[string]any, error) {
+			size, _ := toFloat(args["size"])
+			return map[string]any{"content": strings.Repeat("a", int(size))}, nil
+		},
+	})
+	t.Cleanup(func() { tools.UnregisterTool("budget_read") })
+	srv := newTestServer(t, openAICompletionBody)
+	p, err := NewProvider(OPENAI, ProviderOptions{APIKey: "test", BaseURL: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Log = logr.Discard()
+	p.Retry = RetryPolicy{MaxAttempts: 1}
+	budget.Tools = []string{"budget_read"}
+	return NewModel(p, ModelOptions{ModelName: "test", ReadBudget: budget}, logr.Discard())
+}
+
+func readTool(t *testing.T, m *Model, args map[string]any) map[string]any {
+	t.Helper()
+	result, err := m.runTool("budget_read", args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result.(map[string]any)
+}
+
+func TestReadBudgetPaginates(t *testing.T) {
+	m := budgetModel(t, &ReadBudget{MaxBytes: 100, PageBytes: 40})
+	first := readTool(t, m, map[string]any{"size": 50})
+	if len(first["content"].(string)) != 50 {
+		t.Fatalf("result within the budget was changed: %v", first)
+	}
+
+	var content string
+	args := map[string]any{"size": 90}
+	for pages := 1; ; pages++ {
+		result := readTool(t, m, args)
+		content += result["content"].(string)
+		next, ok := result[tools.NextCursorKey].(string)
+		if !ok {
+			if pages != 3 {
+				t.Errorf("read %d pages, want 3", pages)
+			}
+			break
+		}
+		if pages > 3 {
+			t.Fatal("pagination does not end")
+		}
+		args = map[string]any{"size": 90, ReadCursorArg: next}
+	}
+	if full := formatToolResult(map[string]any{"content": strings.Repeat("a", 90)}); content != full {
+		t.Errorf("pages join to %q, want %q", content, full)
+	}
+	want := len(formatToolResult(first)) + len(content)
+	if bytes, _ := m.ReadBudgetUsed(); bytes != want {
+		t.Errorf("budget used %d bytes, want %d", bytes, want)
+	}
+
+	// the pages of a finished result are gone
+	if _, err := m.runTool("budget_read", map[string]any{"size": 90, ReadCursorArg: "40"}); err == nil {
+		t.Error("stale cursor was accepted")
+	}
+}
+
+func TestReadBudgetSummarizes(t *testing.T) {
+	m := budgetModel(t, &ReadBudget{MaxBytes: 100, PageBytes: 40, Action: ReadSummarize})
+	result := readTool(t, m, map[string]any{"size": 200})
+	if result["summary"] != "hello" {
+		t.Errorf("result %v, want the summary", result)
+	}
+	// small results are not summarized once the budget is used up
+	if result := readTool(t, m, map[string]any{"size": 10}); result["content"] != strings.Repeat("a", 10) {
+		t.Errorf("small result %v was changed", result)
+	}
+}
+
+func TestReadBudgetTokens(t *testing.T) {
+	m := budgetModel(t, &ReadBudget{MaxTokens: 10, PageBytes: 20})
+	result := readTool(t, m, map[string]any{"size": 100})
+	if _, ok := result[tools.NextCursorKey]; !ok {
+		t.Errorf("result %v over the token budget was not paginated", result)
+	}
+}
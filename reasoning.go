@@ -0,0 +1,91 @@
+package genai
+
+import "strings"
+
+// ReasoningMode controls what happens to the <think> blocks reasoning models such as
+// Qwen and DeepSeek emit before their answer
+type ReasoningMode string
+
+const (
+	// ReasoningKeep leaves responses unchanged
+	ReasoningKeep ReasoningMode = ""
+	// ReasoningStrip removes reasoning from responses and history
+	ReasoningStrip ReasoningMode = "strip"
+	// ReasoningCapture removes reasoning like ReasoningStrip and sends it on Chat.Reasoning
+	ReasoningCapture ReasoningMode = "capture"
+
+	thinkOpen  = "<think>"
+	thinkClose = "</think>"
+)
+
+// splitThinking separates <think> blocks from the answer. A closing tag without an
+// opening tag means the chat template opened the block, an unterminated block means
+// the response was cut off while reasoning.
+func splitThinking(text string) (reasoning string, content string) {
+	var thoughts []string
+	if end := strings.Index(text, thinkClose); end >= 0 {
+		if start := strings.Index(text, thinkOpen); start < 0 || start > end {
+			thoughts = append(thoughts, strings.TrimSpace(text[:end]))
+			text = text[end+len(thinkClose):]
+		}
+	}
+	var sb strings.Builder
+	for {
+		start := strings.Index(text, thinkOpen)
+		if start < 0 {
+			sb.WriteString(text)
+			break
+		}
+		sb.WriteString(text[:start])
+		text = text[start+len(thinkOpen):]
+		end := strings.Index(text, thinkClose)
+		if end < 0 {
+			thoughts = append(thoughts, strings.TrimSpace(text))
+			break
+		}
+		thoughts = append(thoughts, strings.TrimSpace(text[:end]))
+		text = text[end+len(thinkClose):]
+	}
+	if len(thoughts) == 0 {
+		return "", sb.String()
+	}
+	return strings.Join(thoughts, "\n\n"), strings.TrimSpace(sb.String())
+}
+
+// processReasoning applies the model's ReasoningMode to a response
+func (m *Model) processReasoning(text string) (reasoning string, content string) {
+	if m.Reasoning == ReasoningKeep {
+		return "", text
+	}
+	return splitThinking(text)
+}
+
+// stripReasoning removes reasoning from the text parts of a message so it is not
+// sent back to the model with the history
+func (m *Model) stripReasoning(msg Message) Message {
+	if m.Reasoning == ReasoningKeep {
+		return msg
+	}
+	parts := make([]Part, 0, len(msg.Parts))
+	for _, part := range msg.Parts {
+		if part.Type == TextPart {
+			_, part.Text = splitThinking(part.Text)
+			if part.Text == "" {
+				continue
+			}
+		}
+		parts = append(parts, part)
+	}
+	msg.Parts = parts
+	return msg
+}
+
+// sendResponse delivers a final response to the chat, routing any reasoning to
+// Chat.Reasoning in capture mode
+func (m *Model) sendResponse(chat *Chat, response string) {
+	reasoning, content := m.processReasoning(response)
+	if reasoning != "" && m.Reasoning == ReasoningCapture && chat.Reasoning != nil {
+		chat.Reasoning <- reasoning
+	}
+	chat.emit(TextDelta{Text: content})
+}
@@ -0,0 +1,126 @@
+package genai
+
+import (
+	"maps"
+
+	"github.com/jbutlerdev/genai/tools"
+	gemini "google.golang.org/genai"
+)
+
+// Updates made with these methods are applied before the next message is sent, a turn
+// that is being generated finishes with the previous configuration. The history is kept.
+
+// SetSystemPrompt replaces the system prompt of the conversation, an empty prompt
+// removes it
+func (c *Chat) SetSystemPrompt(prompt string) {
+	c.update(func(m *Model) {
+		m.SystemPrompt = prompt
+		history := m.History()
+		if len(history) > 0 && history[0].Role == RoleSystem {
+			history = history[1:]
+		}
+		if prompt != "" {
+			history = append([]Message{NewTextMessage(RoleSystem, prompt)}, history...)
+		}
+		m.setHistory(history)
+		if m.Gemini != nil {
+			m.Gemini.SystemInstruction = nil
+			if prompt != "" {
+				m.Gemini.SystemInstruction = gemini.NewContentFromText(prompt, gemini.RoleUser)
+			}
+		}
+	})
+}
+
+// SetParameters merges parameters such as Temperature into the model parameters, a nil
+// value removes the parameter
+func (c *Chat) SetParameters(parameters map[string]any) {
+	c.update(func(m *Model) {
+		// the map may be shared with the ModelOptions the chat was created with
+		merged := maps.Clone(m.Parameters)
+		if merged == nil {
+			merged = make(map[string]any)
+		}
+		for key, value := range parameters {
+			if value == nil {
+				delete(merged, key)
+			} else {
+				merged[key] = value
+			}
+		}
+		m.Parameters = merged
+		if m.Gemini != nil {
+			config := geminiConfig(m.options())
+			config.SystemInstruction = m.Gemini.SystemInstruction
+			config.Tools = m.Gemini.Tools
+			m.Gemini = config
+		}
+	})
+}
+
+// SetTemperature sets the sampling temperature of the conversation
+func (c *Chat) SetTemperature(temperature float64) {
+	c.SetParameters(map[string]any{Temperature: temperature})
+}
+
+// SetTools replaces the tools the model can call. Tools that are not registered are
+// rejected without changing the toolset.
+func (c *Chat) SetTools(toolsToUse []*tools.Tool) error {
+	var geminiTools []*gemini.Tool
+	for _, tool := range toolsToUse {
+		if _, err := tools.GetTool(tool.Name); err != nil {
+			return err
+		}
+		if c.model.Provider.Provider == GEMINI {
+			geminiTool, err := tools.GetGeminiTool(tool.Name)
+			if err != nil {
+				return err
+			}
+			geminiTools = append(geminiTools, geminiTool)
+		}
+	}
+	c.update(func(m *Model) {
+		m.Tools = nil
+		// tools such as context packing's belong to the model and are kept
+		for _, tool := range m.localTools {
+			m.Tools = append(m.Tools, tool)
+		}
+		m.Tools = append(m.Tools, toolsToUse...)
+		if m.Gemini != nil {
+			m.Gemini.Tools = geminiTools
+			// the search tool comes from WebSearch rather than the toolset
+			if tool := geminiSearchTool(m.WebSearch); tool != nil {
+				m.Gemini.Tools = append(m.Gemini.Tools, tool)
+			}
+		}
+	})
+	return nil
+}
+
+// SetFunctionCalling forces or restricts the tool calls of the conversation, nil lets
+// the model decide
+func (c *Chat) SetFunctionCalling(fc *FunctionCalling) {
+	c.update(func(m *Model) {
+		m.FunctionCalling = fc
+		if m.Gemini != nil {
+			m.Gemini.ToolConfig = geminiToolConfig(fc)
+		}
+	})
+}
+
+// update queues a change to the model for the chat goroutine to apply between turns
+func (c *Chat) update(fn func(m *Model)) {
+	c.updateMu.Lock()
+	defer c.updateMu.Unlock()
+	c.updates = append(c.updates, fn)
+}
+
+// applyUpdates applies the queued changes, it runs on the chat goroutine before a turn
+func (c *Chat) applyUpdates(m *Model) {
+	c.updateMu.Lock()
+	defer c.updateMu.Unlock()
+	for _, fn := range c.updates {
+		fn(m)
+	}
+	c.updates = nil
+}
@@ -0,0 +1,43 @@
+package genai
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/jbutlerdev/genai/tools"
+)
+
+func TestSetToolsOllama(t *testing.T) {
+	weather, _ := registerWeatherTool(t)
+	translate := registerTools(t, map[string]string{"mock_translate": "Translate text into another language"})[0]
+	srv := newTestServer(t, ollamaChatBody)
+	p, err := NewProvider(OLLAMA, ProviderOptions{BaseURL: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Log = logr.Discard()
+	p.Retry = RetryPolicy{MaxAttempts: 1}
+	chat := p.ChatEvents(ModelOptions{ModelName: "test"}, []*tools.Tool{weather})
+	defer close(chat.Done)
+	if _, err := mockTurn(t, chat, "What is the weather in Paris?"); err != nil {
+		t.Fatalf("turn failed: %v", err)
+	}
+	if err := chat.SetTools([]*tools.Tool{translate}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mockTurn(t, chat, "Translate this text into French"); err != nil {
+		t.Fatalf("turn failed: %v", err)
+	}
+
+	requests := srv.bodies()
+	if len(requests) != 2 {
+		t.Fatalf("%d requests, want 2", len(requests))
+	}
+	want := [][]string{{"mock_weather"}, {"mock_translate"}}
+	for i, req := range requests {
+		if got := requestTools(req); !slices.Equal(got, want[i]) {
+			t.Errorf("request %d tools %v, want %v", i+1, got, want[i])
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package genai
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RedisCacheOptions configure a RedisCache
+type RedisCacheOptions struct {
+	// Addr is the host and port of the server, localhost:6379 when empty
+	Addr     string
+	Username string
+	Password string
+	DB       int
+	// Prefix is prepended to the keys, "genai:" when empty
+	Prefix string
+	// TTL expires the responses, they are kept until Redis evicts them when zero
+	TTL time.Duration
+	// Timeout limits connecting and each command when the context has no deadline,
+	// 5 seconds when zero
+	Timeout time.Duration
+	// TLSConfig connects with TLS when set
+	TLSConfig *tls.Config
+}
+
+// RedisCache is a ResponseCache shared through a Redis server. It uses a single
+// connection that is opened on the first command and reopened after a failure.
+type RedisCache struct {
+	opts RedisCacheOptions
+	mu   sync.Mutex
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+// NewRedisCache creates the cache, the server is connected on the first command
+func NewRedisCache(opts RedisCacheOptions) *RedisCache {
+	if opts.Addr == "" {
+		opts.Addr = "localhost:6379"
+	}
+	if opts.Prefix == "" {
+		opts.Prefix = "genai:"
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 5 * time.Second
+	}
+	return &RedisCache{opts: opts}
+}
+
+func (c *RedisCache) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	reply, err := c.do(ctx, "GET", c.opts.Prefix+key)
+	if err != nil {
+		return nil, false, err
+	}
+	if reply == nil {
+		return nil, false, nil
+	}
+	return reply, true, nil
+}
+
+func (c *RedisCache) Set(ctx context.Context, key string, value []byte) error {
+	args := []string{"SET", c.opts.Prefix + key, string(value)}
+	if c.opts.TTL > 0 {
+		args = append(args, "PX", strconv.FormatInt(c.opts.TTL.Milliseconds(), 10))
+	}
+	_, err := c.do(ctx, args...)
+	return err
+}
+
+// Close closes the connection to the server
+func (c *RedisCache) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn == nil {
+		return nil
+	}
+	err := c.conn.Close()
+	c.conn = nil
+	return err
+}
+
+// do sends a command and returns its bulk string reply, nil for a missing value
+func (c *RedisCache) do(ctx context.Context, args ...string) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(c.opts.Timeout)
+	}
+	if c.conn == nil {
+		if err := c.connect(ctx, deadline); err != nil {
+			return nil, err
+		}
+	}
+	c.conn.SetDeadline(deadline)
+	reply, err := c.command(args...)
+	var redisErr redisError
+	if err != nil && !errors.As(err, &redisErr) {
+		// the connection is in an unknown state after a network error
+		c.conn.Close()
+		c.conn = nil
+	}
+	return reply, err
+}
+
+// connect opens the connection, authenticates and selects the database
+func (c *RedisCache) connect(ctx context.Context, deadline time.Time) error {
+	dialer := &net.Dialer{Deadline: deadline}
+	var conn net.Conn
+	var err error
+	if c.opts.TLSConfig != nil {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: c.opts.TLSConfig}).DialContext(ctx, "tcp", c.opts.Addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", c.opts.Addr)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to connect to redis: %w", err)
+	}
+	conn.SetDeadline(deadline)
+	c.conn = conn
+	c.r = bufio.NewReader(conn)
+	if c.opts.Password != "" {
+		args := []string{"AUTH", c.opts.Password}
+		if c.opts.Username != "" {
+			args = []string{"AUTH", c.opts.Username, c.opts.Password}
+		}
+		if _, err := c.command(args...); err != nil {
+			c.conn.Close()
+			c.conn = nil
+			return fmt.Errorf("failed to authenticate to redis: %w", err)
+		}
+	}
+	if c.opts.DB != 0 {
+		if _, err := c.command("SELECT", strconv.Itoa(c.opts.DB)); err != nil {
+			c.conn.Close()
+			c.conn = nil
+			return fmt.Errorf("failed to select redis database %d: %w", c.opts.DB, err)
+		}
+	}
+	return nil
+}
+
+// redisError is an error reply of the server, the connection stays usable
+type redisError string
+
+func (e redisError) Error() string {
+	return "redis: " + string(e)
+}
+
+// command writes the arguments as a RESP array and reads the reply
+func (c *RedisCache) command(args ...string) ([]byte, error) {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&sb, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err := io.WriteString(c.conn, sb.String()); err != nil {
+		return nil, err
+	}
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, errors.New("redis: empty reply")
+	}
+	switch line[0] {
+	case '+', ':':
+		return []byte(line[1:]), nil
+	case '-':
+		return nil, redisError(line[1:])
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("redis: invalid bulk length %q", line)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		data := make([]byte, n+2)
+		if _, err := io.ReadFull(c.r, data); err != nil {
+			return nil, err
+		}
+		return data[:n], nil
+	}
+	return nil, fmt.Errorf("redis: unexpected reply %q", line)
+}
@@ -0,0 +1,98 @@
+package genai
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// ReflectionOptions configure a review of each chat response before it is delivered. A
+// critic model checks the response and the model revises it until the critic approves
+// or MaxIterations revisions were made.
+type ReflectionOptions struct {
+	// CriticModel reviews the responses, the chat's model when empty
+	CriticModel string
+	// CriticPrompt replaces DefaultCriticPrompt, it must ask for reflectionApproved when
+	// the response needs no changes
+	CriticPrompt string
+	// MaxIterations is the number of revisions of one response, 1 when zero
+	MaxIterations int
+}
+
+// reflectionApproved is the critic's answer for a response that needs no changes
+const reflectionApproved = "APPROVED"
+
+// DefaultCriticPrompt asks the critic to review a response to the user's request
+const DefaultCriticPrompt = `Review the assistant's response to the user's request for factual mistakes, missing parts of the request and unclear explanations.
+If the response needs no changes, answer with exactly ` + reflectionApproved + ` and nothing else.
+Otherwise list the problems to fix, briefly and without rewriting the response.`
+
+const revisePrompt = `<request>
+%s
+</request>
+
+<response>
+%s
+</response>
+
+<critique>
+%s
+</critique>
+
+Revise the response to the request so it addresses the critique. Answer with the revised response only.`
+
+// reflect reviews the response to the last user message of messages and returns the
+// response to deliver. Failed reviews and revisions keep the response.
+func (m *Model) reflect(ctx context.Context, messages []Message, response string) string {
+	if m.Reflection == nil {
+		return response
+	}
+	var request string
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role == RoleUser {
+			request = messages[i].Text()
+			break
+		}
+	}
+	criticModel := m.Reflection.CriticModel
+	if criticModel == "" {
+		criticModel = m.requestedModel
+	}
+	prompt := m.Reflection.CriticPrompt
+	if prompt == "" {
+		prompt = DefaultCriticPrompt
+	}
+	iterations := max(m.Reflection.MaxIterations, 1)
+
+	for i := 0; i < iterations; i++ {
+		_, content := splitThinking(response)
+		// the critic runs without the chat's system prompt and examples
+		critic := NewModel(m.Provider, ModelOptions{
+			ModelName:    criticModel,
+			SystemPrompt: prompt,
+			Timeouts:     m.Timeouts,
+			Reasoning:    ReasoningStrip,
+		}, m.Logger.WithName("critic"))
+		critique, err := critic.generateOnce(ctx, NewTextMessage(RoleUser, fmt.Sprintf("<request>\n%s\n</request>\n\n<response>\n%s\n</response>", request, content)))
+		if usage := critic.Usage(); usage.responses() {
+			m.recordHelperUsage(usage)
+		}
+		if err != nil {
+			m.Logger.Error(err, "Failed to review the response")
+			return response
+		}
+		critique = strings.TrimSpace(critique)
+		if strings.Trim(critique, ".") == reflectionApproved {
+			m.Logger.Info("Response approved", "iteration", i+1)
+			return response
+		}
+		m.Logger.Info("Revising response", "iteration", i+1, "critique", critique)
+		revised, err := m.generateOnce(ctx, NewTextMessage(RoleUser, fmt.Sprintf(revisePrompt, request, content, critique)))
+		if err != nil || strings.TrimSpace(revised) == "" {
+			m.Logger.Error(err, "Failed to revise the response")
+			return response
+		}
+		response = revised
+	}
+	return response
+}
@@ -0,0 +1,176 @@
+package genai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"iter"
+	"reflect"
+
+	"github.com/google/uuid"
+	"github.com/jbutlerdev/genai/tools"
+	gemini "google.golang.org/genai"
+)
+
+// ReplayOptions configure Provider.Replay
+type ReplayOptions struct {
+	// Tools are added to the requests like the tools of the recorded chat, transcripts
+	// don't include the tool definitions
+	Tools []*tools.Tool
+	// Send sends each request and sets the provider's response on the step. The replay
+	// continues with the recorded responses either way.
+	Send bool
+}
+
+// ReplayStep is the request that produced one response of a replayed transcript
+type ReplayStep struct {
+	// Turn is the user turn of the request, starting at 1
+	Turn int
+	// Index is the position of the recorded response in the transcript's messages
+	Index int
+	// Messages are the messages of the request after the context strategy ran
+	Messages []Message
+	// Compacted reports that the context strategy changed the messages
+	Compacted bool
+	// Request is the JSON body sent to the provider. Per request options, such as the
+	// vLLM sampling parameters, are added by the client and not included.
+	Request json.RawMessage
+	// Recorded is the response in the transcript
+	Recorded Message
+	// Response is the provider's response when ReplayOptions.Send is set
+	Response *Message
+}
+
+// geminiRequest shows a Gemini request, the SDK passes its parts as arguments
+type geminiRequest struct {
+	Model    string                        `json:"model"`
+	Contents []*gemini.Content             `json:"contents"`
+	Config   *gemini.GenerateContentConfig `json:"config,omitempty"`
+}
+
+// Replay rebuilds the request behind each assistant message of a transcript, turn by
+// turn, the way a chat with modelOptions and the provider would. The history is fitted
+// to the context and carried on like in the chat, so the steps show the compacted
+// messages and tool call ids the model received. Summaries and routing use the
+// provider, a mock provider replays without network access. Replay stops when the
+// caller stops ranging, a failed step is yielded with its error and the replay goes on.
+func (p *Provider) Replay(ctx context.Context, modelOptions ModelOptions, transcript Transcript, opts ReplayOptions) iter.Seq2[ReplayStep, error] {
+	return func(yield func(ReplayStep, error) bool) {
+		if modelOptions.ModelName == "" {
+			modelOptions.ModelName = transcript.Model
+		}
+		id := transcript.ConversationID
+		if id == "" {
+			id = uuid.New().String()
+		}
+		l := p.Log.WithName("replay").WithValues("model", modelOptions.ModelName, "id", id)
+		model := NewModel(p, modelOptions, l)
+		switch p.Provider {
+		case OLLAMA:
+			model.ollamaClient = p.Client.Ollama
+		case OPENAI, VLLM:
+			model.openAIClient = p.Client.OpenAI
+		}
+		if err := model.AddTool(opts.Tools...); err != nil {
+			yield(ReplayStep{}, fmt.Errorf("failed to add tools: %w", err))
+			return
+		}
+
+		// the system prompt and few-shot examples were not generated, they start the history
+		start := transcript.conversationStart()
+		history := append([]Message(nil), transcript.Messages[:start]...)
+		turn := 0
+		for i := start; i < len(transcript.Messages); i++ {
+			msg := transcript.Messages[i]
+			if msg.Role == RoleUser && len(msg.ToolResults()) == 0 {
+				turn++
+				model.routeTurn(ctx, msg)
+			}
+			if msg.Role != RoleAssistant {
+				history = append(history, msg)
+				continue
+			}
+			step := ReplayStep{Turn: turn, Index: i, Recorded: msg}
+			fitted, err := handleContextLength(model, history)
+			if err != nil {
+				if !yield(step, fmt.Errorf("failed to fit the context: %w", err)) {
+					return
+				}
+				history = append(history, msg)
+				continue
+			}
+			step.Messages = fitted
+			step.Compacted = !reflect.DeepEqual(fitted, history)
+			err = model.replayRequest(ctx, &step, opts.Send)
+			if !yield(step, err) {
+				return
+			}
+			// the chat keeps the fitted history for the following requests
+			history = append(fitted, msg)
+		}
+	}
+}
+
+// replayRequest sets the request of the step's messages and sends it when send is set
+func (m *Model) replayRequest(ctx context.Context, step *ReplayStep, send bool) error {
+	var request any
+	var sendRequest func(ctx context.Context) (Message, error)
+	switch m.Provider.Provider {
+	case OPENAI, VLLM:
+		params := m.openAIClient.chatParams(m, step.Messages)
+		request = params
+		sendRequest = func(ctx context.Context) (Message, error) {
+			resp, usage, err := m.openAIClient.completeFor(ctx, m, params, step.Messages)
+			if err != nil {
+				return Message{}, err
+			}
+			m.recordUsage(params.Model, usage)
+			if len(resp.Choices) == 0 {
+				return Message{}, errors.New("no response choices returned")
+			}
+			return fromOpenAIMessage(resp.Choices[0].Message), nil
+		}
+	case OLLAMA:
+		req := ollamaChatRequest(m, m.ollamaTools(), step.Messages)
+		request = req
+		sendRequest = func(ctx context.Context) (Message, error) {
+			resp, err := ollamaChatOnce(ctx, m, req, step.Messages)
+			if err != nil {
+				return Message{}, err
+			}
+			return fromOllamaMessage(resp.Message), nil
+		}
+	case GEMINI:
+		model := m.routedModel(step.Messages)
+		request = geminiRequest{Model: model, Contents: toGeminiContents(step.Messages), Config: m.geminiRequestConfig(step.Messages)}
+		sendRequest = func(ctx context.Context) (Message, error) {
+			resp, err := geminiGenerate(ctx, m, model, step.Messages)
+			if err != nil {
+				return Message{}, err
+			}
+			if len(resp.Candidates) == 0 || resp.Candidates[0].Content == nil {
+				return Message{}, errors.New("no response candidates returned")
+			}
+			return fromGeminiContent(resp.Candidates[0].Content), nil
+		}
+	default:
+		return fmt.Errorf("unsupported provider: %s", m.Provider.Provider)
+	}
+	data, err := json.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("failed to encode request: %w", err)
+	}
+	step.Request = data
+	if !send {
+		return nil
+	}
+	sendContext, cancel := context.WithTimeout(ctx, m.timeouts().Chat)
+	defer cancel()
+	response, err := sendRequest(sendContext)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	step.Response = &response
+	return nil
+}
@@ -0,0 +1,82 @@
+package genai
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+// Headers the request ID is sent in, see ProviderOptions.RequestIDHeader
+const (
+	// OpenAIRequestIDHeader is the header OpenAI records with a request and returns in
+	// its errors, so support requests can refer to it
+	OpenAIRequestIDHeader = "X-Client-Request-Id"
+	// DefaultRequestIDHeader is sent to the other providers and is picked up by vLLM and
+	// most proxies
+	DefaultRequestIDHeader = "X-Request-Id"
+)
+
+type requestIDKey struct{}
+
+// WithRequestID returns a context carrying id as the request ID. A chat turn sent with
+// the context uses id instead of generating one, so a turn can be correlated with the
+// request of the caller that started it.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the request ID carried by ctx, empty when there is none
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// ensureRequestID returns ctx with a request ID, generating one when ctx has none
+func ensureRequestID(ctx context.Context) (context.Context, string) {
+	if id := RequestID(ctx); id != "" {
+		return ctx, id
+	}
+	id := uuid.New().String()
+	return WithRequestID(ctx, id), id
+}
+
+// requestIDHeader is the header the provider sends request IDs in, empty when disabled
+func (p *Provider) requestIDHeader() string {
+	switch {
+	case p.RequestIDHeader == "-":
+		return ""
+	case p.RequestIDHeader != "":
+		return p.RequestIDHeader
+	case p.Provider == OPENAI:
+		return OpenAIRequestIDHeader
+	}
+	return DefaultRequestIDHeader
+}
+
+// requestIDTransport sends the request ID of the request's context in header
+type requestIDTransport struct {
+	base   http.RoundTripper
+	header string
+}
+
+func (t *requestIDTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	id := RequestID(req.Context())
+	if id == "" || req.Header.Get(t.header) != "" {
+		return t.base.RoundTrip(req)
+	}
+	req = req.Clone(req.Context())
+	req.Header.Set(t.header, id)
+	return t.base.RoundTrip(req)
+}
+
+// CloseIdleConnections closes the idle connections of the base transport
+func (t *requestIDTransport) CloseIdleConnections() {
+	closeIdle(t.base)
+}
+
+// setRequestID makes id the request ID of the chat's current turn. It is passed to
+// tools in the session and reported with the turn's usage.
+func (m *Model) setRequestID(id string) {
+	m.updateSession(func(s *Session) { s.RequestID = id })
+}
@@ -0,0 +1,114 @@
+package genai
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/jbutlerdev/genai/tools"
+)
+
+func TestRequestID(t *testing.T) {
+	var toolRequestID string
+	tools.RegisterTool(tools.Tool{
+		Name:        "mock_lookup",
+		Description: "Look up a record",
+		Run: func(args map[string]any) (map[string]any, error) {
+			toolRequestID, _ = args[tools.RequestIDArg].(string)
+			return map[string]any{"found": true}, nil
+		},
+	})
+	t.Cleanup(func() { tools.UnregisterTool("mock_lookup") })
+	tool, err := tools.GetTool("mock_lookup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := newTestServer(t,
+		`{"id":"chatcmpl-1","object":"chat.completion","created":0,"model":"test","choices":[{"index":0,"finish_reason":"tool_calls",`+
+			`"message":{"role":"assistant","content":"","tool_calls":[{"id":"call_1","type":"function","function":{"name":"mock_lookup","arguments":"{}"}}]}}]}`,
+		`{"id":"chatcmpl-2","object":"chat.completion","created":0,"model":"test","choices":[{"index":0,"finish_reason":"stop",`+
+			`"message":{"role":"assistant","content":"Found it"}}]}`,
+	)
+	p, err := NewProvider(OPENAI, ProviderOptions{APIKey: "test", BaseURL: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Log = logr.Discard()
+	chat := p.ChatEvents(ModelOptions{ModelName: "test"}, []*tools.Tool{tool})
+	defer close(chat.Done)
+
+	turn := func(ctx context.Context) (TurnComplete, UsageEvent) {
+		t.Helper()
+		ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		defer cancel()
+		go chat.SendCtx(ctx, "Find the record")
+		var usage UsageEvent
+		for {
+			select {
+			case event := <-chat.Events:
+				switch e := event.(type) {
+				case ErrorEvent:
+					t.Fatalf("turn failed: %v", e.Err)
+				case UsageEvent:
+					usage = e
+				case TurnComplete:
+					return e, usage
+				}
+			case <-ctx.Done():
+				t.Fatal("the turn did not complete")
+			}
+		}
+	}
+
+	complete, usage := turn(WithRequestID(context.Background(), "req-1"))
+	if complete.RequestID != "req-1" || usage.Turn.RequestID != "req-1" {
+		t.Errorf("turn request ID %q, usage %q, want req-1", complete.RequestID, usage.Turn.RequestID)
+	}
+	if toolRequestID != "req-1" {
+		t.Errorf("tool got request ID %q, want req-1", toolRequestID)
+	}
+	if got := chat.Session().RequestID; got != "req-1" {
+		t.Errorf("session request ID %q, want req-1", got)
+	}
+	headers := srv.headers(OpenAIRequestIDHeader)
+	if len(headers) != 2 || headers[0] != "req-1" || headers[1] != "req-1" {
+		t.Errorf("request ID headers %v, want req-1 for both requests", headers)
+	}
+
+	// a turn without a request ID gets a new one
+	complete, _ = turn(context.Background())
+	headers = srv.headers(OpenAIRequestIDHeader)
+	if complete.RequestID == "" || complete.RequestID == "req-1" || headers[len(headers)-1] != complete.RequestID {
+		t.Errorf("generated request ID %q, sent %q", complete.RequestID, headers[len(headers)-1])
+	}
+}
+
+func TestRequestIDHeader(t *testing.T) {
+	srv := newTestServer(t, `{"model":"test","message":{"role":"assistant","content":"Hi"},"done":true}`)
+	p, err := NewProvider(OLLAMA, ProviderOptions{BaseURL: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Retry = RetryPolicy{MaxAttempts: 1}
+	if _, _, err := p.generateWithUsage(WithRequestID(context.Background(), "req-2"), ModelOptions{ModelName: "test"}, "hi"); err != nil {
+		t.Fatal(err)
+	}
+
+	disabled := newTestServer(t, `{"model":"test","message":{"role":"assistant","content":"Hi"},"done":true}`)
+	p, err = NewProvider(OLLAMA, ProviderOptions{BaseURL: disabled.URL, RequestIDHeader: "-"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Retry = RetryPolicy{MaxAttempts: 1}
+	if _, _, err := p.generateWithUsage(WithRequestID(context.Background(), "req-3"), ModelOptions{ModelName: "test"}, "hi"); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := srv.headers(DefaultRequestIDHeader); len(got) != 1 || got[0] != "req-2" {
+		t.Errorf("request ID headers %v, want req-2", got)
+	}
+	if got := disabled.headers(DefaultRequestIDHeader); len(got) != 1 || got[0] != "" {
+		t.Errorf("request ID headers %v, want none", got)
+	}
+}
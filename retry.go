@@ -0,0 +1,103 @@
+package genai
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+// RetryPolicy controls how failed provider calls are retried
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts including the first one, 1 disables retries
+	MaxAttempts  int
+	InitialDelay time.Duration
+	MaxDelay     time.Duration
+	// Jitter is the fraction of each delay that is randomized, between 0 and 1
+	Jitter float64
+	// RetryOn lists the error kinds that are retried, e.g. ErrRateLimited
+	RetryOn []error
+}
+
+// DefaultRetryPolicy retries rate limits and unavailable providers with exponential backoff
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:  RETRY_COUNT,
+	InitialDelay: 1 * time.Second,
+	MaxDelay:     MAX_RETRY_DELAY,
+	Jitter:       0.2,
+	RetryOn:      []error{ErrRateLimited, ErrUnavailable},
+}
+
+// withDefaults fills unset fields from DefaultRetryPolicy
+func (r RetryPolicy) withDefaults() RetryPolicy {
+	if r.MaxAttempts <= 0 {
+		r.MaxAttempts = DefaultRetryPolicy.MaxAttempts
+	}
+	if r.InitialDelay <= 0 {
+		r.InitialDelay = DefaultRetryPolicy.InitialDelay
+	}
+	if r.MaxDelay <= 0 {
+		r.MaxDelay = DefaultRetryPolicy.MaxDelay
+	}
+	if r.RetryOn == nil {
+		r.RetryOn = DefaultRetryPolicy.RetryOn
+	}
+	return r
+}
+
+func (r RetryPolicy) retryable(err error) bool {
+	for _, kind := range r.RetryOn {
+		if errors.Is(err, kind) {
+			return true
+		}
+	}
+	return false
+}
+
+// backoff returns the delay before the given retry, starting at 1
+func (r RetryPolicy) backoff(retry int) time.Duration {
+	delay := r.InitialDelay
+	for i := 1; i < retry && delay < r.MaxDelay; i++ {
+		delay *= 2
+	}
+	delay = min(delay, r.MaxDelay)
+	if r.Jitter > 0 {
+		spread := time.Duration(float64(delay) * r.Jitter)
+		delay = delay - spread + time.Duration(rand.Int64N(int64(2*spread)+1))
+	}
+	return delay
+}
+
+// retry runs call until it succeeds, fails with an error the policy does not
+// retry, or runs out of attempts. Errors are classified with wrapProviderError.
+// Every attempt, retry and failure is reported to metrics.
+func retry[T any](ctx context.Context, policy RetryPolicy, logger logr.Logger, provider string, metrics Metrics, call func() (T, error)) (T, error) {
+	policy = policy.withDefaults()
+	var result T
+	var err error
+	for attempt := 1; ; attempt++ {
+		started := time.Now()
+		result, err = call()
+		err = wrapProviderError(provider, err)
+		metrics.Attempt(provider, time.Since(started), err)
+		if err == nil {
+			return result, nil
+		}
+		if attempt >= policy.MaxAttempts || !policy.retryable(err) {
+			metrics.Failure(provider, err)
+			return result, err
+		}
+		delay := policy.backoff(attempt)
+		logger.Error(err, "Retryable error", "delay", delay, "attempt", attempt)
+		metrics.Retry(provider, err)
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			err = errors.Join(err, ctx.Err())
+			metrics.Failure(provider, err)
+			return result, err
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package genai
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// TurnRouter picks the model for each turn of a chat from the user's message. Routes are
+// checked in order and the first that matches answers the turn, the chat's model
+// answers when none do. The provider's Routes still apply to the picked model.
+type TurnRouter struct {
+	Routes []TurnRoute `json:"routes"`
+	// ClassifierModel labels messages with one of the routes' intents. The utility model
+	// is used when it is empty, the chat's model when neither is set.
+	ClassifierModel string `json:"classifierModel,omitempty"`
+}
+
+// TurnRoute sends the messages that match every condition that is set to Model
+type TurnRoute struct {
+	Model string `json:"model"`
+	// MinTokens and MaxTokens bound the estimated length of the message, zero is unbounded
+	MinTokens int `json:"minTokens,omitempty"`
+	MaxTokens int `json:"maxTokens,omitempty"`
+	// Code matches messages that contain source code
+	Code bool `json:"code,omitempty"`
+	// Intents match when the classifier labels the message with one of them, e.g.
+	// "math" or "small talk"
+	Intents []string `json:"intents,omitempty"`
+}
+
+const classifyPrompt = `Classify the intent of the user's message. Answer with exactly one of these labels and nothing else: %s, other.
+
+Message:
+%s`
+
+// routeTurn sets the model for the turn answering msg
+func (m *Model) routeTurn(ctx context.Context, msg Message) {
+	m.turnModel = ""
+	if m.Router == nil || len(m.Router.Routes) == 0 {
+		return
+	}
+	text := msg.Text()
+	tokens := estimateTokens(text)
+	code := containsCode(text)
+	var intent string
+	classified := false
+	for _, route := range m.Router.Routes {
+		if route.MinTokens > 0 && tokens < route.MinTokens {
+			continue
+		}
+		if route.MaxTokens > 0 && tokens > route.MaxTokens {
+			continue
+		}
+		if route.Code && !code {
+			continue
+		}
+		if len(route.Intents) > 0 {
+			// the classifier is only asked once a route needs it
+			if !classified {
+				intent = m.classifyIntent(ctx, text)
+				classified = true
+			}
+			if !slices.ContainsFunc(route.Intents, func(i string) bool { return strings.EqualFold(i, intent) }) {
+				continue
+			}
+		}
+		m.Logger.Info("Routing turn", "model", route.Model, "tokens", tokens, "code", code, "intent", intent)
+		m.turnModel = route.Model
+		return
+	}
+}
+
+// classifyIntent labels text with one of the intents of the router's routes, it returns
+// an empty string when the classifier fails or answers with another label
+func (m *Model) classifyIntent(ctx context.Context, text string) string {
+	var intents []string
+	for _, route := range m.Router.Routes {
+		for _, intent := range route.Intents {
+			if !slices.Contains(intents, intent) {
+				intents = append(intents, intent)
+			}
+		}
+	}
+	model := m.Router.ClassifierModel
+	if model == "" {
+		model = m.utility().model
+	}
+	if model == "" {
+		model = m.requestedModel
+	}
+	// the classifier runs without the chat's system prompt and examples
+	classifier := NewModel(m.Provider, ModelOptions{
+		ModelName: model,
+		Timeouts:  m.Timeouts,
+		Reasoning: ReasoningStrip,
+	}, m.Logger)
+	answer, err := classifier.generateMessageCtx(ctx, NewTextMessage(RoleUser, fmt.Sprintf(classifyPrompt, strings.Join(intents, ", "), text)))
+	if err != nil {
+		m.Logger.Error(err, "Failed to classify the message intent")
+		return ""
+	}
+	answer = strings.Trim(strings.TrimSpace(answer), `."'`)
+	for _, intent := range intents {
+		if strings.EqualFold(answer, intent) {
+			return intent
+		}
+	}
+	return ""
+}
+
+var codeLineRegex = regexp.MustCompile(`(?m)^\s*(func|def|class|import|package|public|private|return|const|let|var|#include)\b|[;{}]\s*$`)
+
+// containsCode reports whether text has a fenced code block or several lines that
+// look like source code
+func containsCode(text string) bool {
+	if strings.Contains(text, "```") {
+		return true
+	}
+	return len(codeLineRegex.FindAllStringIndex(text, 3)) >= 2
+}
+
+// tagResponses records model on the assistant messages answering the last user message
+func (m *Model) tagResponses(model string) {
+	if model == "" {
+		return
+	}
+	m.historyMu.Lock()
+	defer m.historyMu.Unlock()
+	for i := len(m.history) - 1; i >= 0 && m.history[i].Role != RoleUser; i-- {
+		if m.history[i].Role == RoleAssistant && m.history[i].Model == "" {
+			m.history[i].Model = model
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package genai
+
+// RoutingRule sends a request to Model when it is estimated to be at least MinTokens long.
+// From restricts the rule to a model name or alias, an empty From matches every model.
+type RoutingRule struct {
+	From      string `json:"from,omitempty"`
+	MinTokens int    `json:"minTokens"`
+	Model     string `json:"model"`
+}
+
+// ResolveModel returns the model an alias refers to. Aliases may point at other
+// aliases, names that are not aliases are returned unchanged.
+func (p *Provider) ResolveModel(name string) string {
+	// bound the lookups so an alias cycle can not loop forever
+	for i := 0; i <= len(p.Aliases); i++ {
+		target, ok := p.Aliases[name]
+		if !ok {
+			return name
+		}
+		name = target
+	}
+	return name
+}
+
+// routeModel picks the model for a request of the given size. When several rules
+// match, the one with the largest MinTokens wins.
+func (p *Provider) routeModel(name string, tokens int) string {
+	resolved := p.ResolveModel(name)
+	var match *RoutingRule
+	for i, rule := range p.Routes {
+		if rule.From != "" && rule.From != name && p.ResolveModel(rule.From) != resolved {
+			continue
+		}
+		if tokens < rule.MinTokens {
+			continue
+		}
+		if match == nil || rule.MinTokens > match.MinTokens {
+			match = &p.Routes[i]
+		}
+	}
+	if match == nil {
+		return resolved
+	}
+	return p.ResolveModel(match.Model)
+}
+
+// estimateTokens is a provider independent approximation of the token count
+func estimateTokens(text string) int {
+	return len(text) / 4
+}
+
+// routedModel returns the model to use for the next request with the given messages,
+// starting from the model the Router picked for the turn
+func (m *Model) routedModel(messages []Message) string {
+	requested, model := m.requestedModel, m.ModelName
+	if m.turnModel != "" {
+		requested, model = m.turnModel, m.Provider.ResolveModel(m.turnModel)
+	}
+	if len(m.Provider.Routes) == 0 {
+		return model
+	}
+	return m.Provider.routeModel(requested, estimateTokens(messagesToString(messages, true)))
+}
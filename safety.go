@@ -0,0 +1,143 @@
+package genai
+
+import (
+	"fmt"
+	"strings"
+
+	gemini "google.golang.org/genai"
+)
+
+// HarmCategory is a category of harmful content rated by Gemini's safety filters
+type HarmCategory string
+
+const (
+	HarmHateSpeech       HarmCategory = "HARM_CATEGORY_HATE_SPEECH"
+	HarmDangerousContent HarmCategory = "HARM_CATEGORY_DANGEROUS_CONTENT"
+	HarmHarassment       HarmCategory = "HARM_CATEGORY_HARASSMENT"
+	HarmSexuallyExplicit HarmCategory = "HARM_CATEGORY_SEXUALLY_EXPLICIT"
+	HarmCivicIntegrity   HarmCategory = "HARM_CATEGORY_CIVIC_INTEGRITY"
+)
+
+// HarmThreshold is the probability of harm from which content is blocked
+type HarmThreshold string
+
+const (
+	BlockLowAndAbove    HarmThreshold = "BLOCK_LOW_AND_ABOVE"
+	BlockMediumAndAbove HarmThreshold = "BLOCK_MEDIUM_AND_ABOVE"
+	BlockOnlyHigh       HarmThreshold = "BLOCK_ONLY_HIGH"
+	// BlockNone rates the content without blocking it
+	BlockNone HarmThreshold = "BLOCK_NONE"
+	// BlockOff turns the filter off
+	BlockOff HarmThreshold = "OFF"
+)
+
+// SafetySetting sets the threshold of a Gemini safety filter
+type SafetySetting struct {
+	Category  HarmCategory
+	Threshold HarmThreshold
+}
+
+// SafetyRating is the rating of a prompt or response in a harm category
+type SafetyRating struct {
+	Category HarmCategory `json:"category"`
+	// Probability is NEGLIGIBLE, LOW, MEDIUM or HIGH
+	Probability string `json:"probability,omitempty"`
+	Blocked     bool   `json:"blocked,omitempty"`
+}
+
+// ContentFilteredError describes a prompt or response blocked by the provider's safety
+// filters. It matches ErrContentFiltered and is wrapped in a ProviderError.
+type ContentFilteredError struct {
+	// Prompt reports a blocked prompt, the response was blocked otherwise
+	Prompt bool
+	// Reason is the block or finish reason, e.g. SAFETY or PROHIBITED_CONTENT
+	Reason  string
+	Message string
+	Ratings []SafetyRating
+}
+
+func (e *ContentFilteredError) Error() string {
+	subject := "response"
+	if e.Prompt {
+		subject = "prompt"
+	}
+	msg := fmt.Sprintf("%s blocked: %s", subject, e.Reason)
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	var categories []string
+	for _, rating := range e.Ratings {
+		if rating.Blocked {
+			categories = append(categories, string(rating.Category))
+		}
+	}
+	if len(categories) > 0 {
+		msg += fmt.Sprintf(" (%s)", strings.Join(categories, ", "))
+	}
+	return msg
+}
+
+func (e *ContentFilteredError) Is(target error) bool {
+	return target == ErrContentFiltered
+}
+
+// geminiSafetySettings converts the safety settings of the model options
+func geminiSafetySettings(settings []SafetySetting) []*gemini.SafetySetting {
+	if len(settings) == 0 {
+		return nil
+	}
+	converted := make([]*gemini.SafetySetting, len(settings))
+	for i, setting := range settings {
+		converted[i] = &gemini.SafetySetting{
+			Category:  gemini.HarmCategory(setting.Category),
+			Threshold: gemini.HarmBlockThreshold(setting.Threshold),
+		}
+	}
+	return converted
+}
+
+// geminiBlockedFinish are the finish reasons of responses stopped by a filter
+var geminiBlockedFinish = map[gemini.FinishReason]bool{
+	gemini.FinishReasonSafety:            true,
+	gemini.FinishReasonRecitation:        true,
+	gemini.FinishReasonBlocklist:         true,
+	gemini.FinishReasonProhibitedContent: true,
+	gemini.FinishReasonSPII:              true,
+	gemini.FinishReasonImageSafety:       true,
+}
+
+// geminiBlocked reports a prompt or response blocked by the safety filters
+func geminiBlocked(resp *gemini.GenerateContentResponse) error {
+	if feedback := resp.PromptFeedback; feedback != nil && feedback.BlockReason != "" {
+		return &ProviderError{Provider: GEMINI, Kind: ErrContentFiltered, Err: &ContentFilteredError{
+			Prompt:  true,
+			Reason:  string(feedback.BlockReason),
+			Message: feedback.BlockReasonMessage,
+			Ratings: geminiSafetyRatings(feedback.SafetyRatings),
+		}}
+	}
+	if len(resp.Candidates) > 0 && geminiBlockedFinish[resp.Candidates[0].FinishReason] {
+		candidate := resp.Candidates[0]
+		return &ProviderError{Provider: GEMINI, Kind: ErrContentFiltered, Err: &ContentFilteredError{
+			Reason:  string(candidate.FinishReason),
+			Message: candidate.FinishMessage,
+			Ratings: geminiSafetyRatings(candidate.SafetyRatings),
+		}}
+	}
+	return nil
+}
+
+func geminiSafetyRatings(ratings []*gemini.SafetyRating) []SafetyRating {
+	var converted []SafetyRating
+	for _, rating := range ratings {
+		if rating == nil {
+			continue
+		}
+		converted = append(converted, SafetyRating{
+			Category:    HarmCategory(rating.Category),
+			Probability: string(rating.Probability),
+			Blocked:     rating.Blocked,
+		})
+	}
+	return converted
+}
@@ -0,0 +1,62 @@
+package genai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/go-logr/logr"
+)
+
+func TestGeminiSafety(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		body string
+		want ContentFilteredError
+	}{
+		{
+			"prompt",
+			`{"promptFeedback":{"blockReason":"SAFETY","blockReasonMessage":"unsafe prompt","safetyRatings":[` +
+				`{"category":"HARM_CATEGORY_DANGEROUS_CONTENT","probability":"HIGH","blocked":true},` +
+				`{"category":"HARM_CATEGORY_HARASSMENT","probability":"NEGLIGIBLE"}]}}`,
+			ContentFilteredError{Prompt: true, Reason: "SAFETY", Message: "unsafe prompt", Ratings: []SafetyRating{
+				{Category: HarmDangerousContent, Probability: "HIGH", Blocked: true},
+				{Category: HarmHarassment, Probability: "NEGLIGIBLE"},
+			}},
+		},
+		{
+			"response",
+			`{"candidates":[{"finishReason":"PROHIBITED_CONTENT","safetyRatings":[]}]}`,
+			ContentFilteredError{Reason: "PROHIBITED_CONTENT"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := newTestServer(t, tc.body)
+			p, err := NewProvider(GEMINI, ProviderOptions{APIKey: "test", BaseURL: srv.URL})
+			if err != nil {
+				t.Fatal(err)
+			}
+			p.Log = logr.Discard()
+			p.Retry = RetryPolicy{MaxAttempts: 1}
+			_, _, err = p.generateWithUsage(context.Background(), ModelOptions{
+				ModelName: "test",
+				SafetySettings: []SafetySetting{
+					{Category: HarmDangerousContent, Threshold: BlockOnlyHigh},
+					{Category: HarmHarassment, Threshold: BlockOff},
+				},
+			}, "hi")
+			var filtered *ContentFilteredError
+			if !errors.Is(err, ErrContentFiltered) || !errors.As(err, &filtered) {
+				t.Fatalf("error %v, want a ContentFilteredError", err)
+			}
+			if got, want := fmt.Sprintf("%+v", *filtered), fmt.Sprintf("%+v", tc.want); got != want {
+				t.Errorf("error %s, want %s", got, want)
+			}
+			settings := fmt.Sprint(srv.last()["safetySettings"])
+			if want := "[map[category:HARM_CATEGORY_DANGEROUS_CONTENT threshold:BLOCK_ONLY_HIGH] map[category:HARM_CATEGORY_HARASSMENT threshold:OFF]]"; settings != want {
+				t.Errorf("safety settings %s, want %s", settings, want)
+			}
+		})
+	}
+}
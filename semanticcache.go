@@ -0,0 +1,267 @@
+package genai
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jbutlerdev/genai/tools"
+	_ "github.com/lib/pq"
+	"github.com/pgvector/pgvector-go"
+)
+
+// DefaultSemanticThreshold is the cosine similarity above which a cached answer is served
+const DefaultSemanticThreshold = 0.95
+
+// SemanticCache answers text prompts with the response to an earlier prompt that means
+// the same, found by the similarity of their embeddings. Prompts only match prompts sent
+// with the same model, system prompt, examples and parameters. It applies to Generate and
+// GenerateMessage without attachments, chats are not cached.
+type SemanticCache struct {
+	// Store keeps the prompts and responses, see NewMemorySemanticStore and
+	// NewPostgresSemanticStore
+	Store SemanticStore
+	// Embedder embeds the prompts, the provider itself when nil
+	Embedder tools.EmbeddingProvider
+	// EmbeddingModel is passed to the embedder, which uses its default when empty
+	EmbeddingModel string
+	// Threshold is the minimum cosine similarity of a match, DefaultSemanticThreshold when zero
+	Threshold float64
+	// MaxAge ignores answers cached longer ago when set
+	MaxAge time.Duration
+
+	hits   atomic.Int64
+	misses atomic.Int64
+}
+
+// SemanticEntry is a cached prompt with its response
+type SemanticEntry struct {
+	Prompt    string    `json:"prompt"`
+	Response  string    `json:"response"`
+	Embedding []float32 `json:"embedding"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// SemanticMatch is the entry closest to a prompt
+type SemanticMatch struct {
+	SemanticEntry
+	Similarity float64 `json:"similarity"`
+}
+
+// SemanticStore keeps the entries of a SemanticCache. Scope identifies the model and
+// options the response was generated with, entries only match within their scope.
+type SemanticStore interface {
+	// Nearest returns the entry of scope most similar to embedding created after since,
+	// false when there is none
+	Nearest(ctx context.Context, scope string, embedding []float32, since time.Time) (SemanticMatch, bool, error)
+	// Add stores an entry
+	Add(ctx context.Context, scope string, entry SemanticEntry) error
+}
+
+// semanticScope identifies the requests whose responses can answer each other
+func (m *Model) semanticScope() (string, error) {
+	return cacheKey(m.Provider.Provider, struct {
+		Model        string    `json:"model"`
+		SystemPrompt string    `json:"systemPrompt,omitempty"`
+		Examples     []Example `json:"examples,omitempty"`
+	}{m.ModelName, m.SystemPrompt, m.Examples}, m.Parameters)
+}
+
+// semanticGenerate answers a text prompt from the provider's semantic cache, or generates
+// the response and adds it. Failures of the cache are logged and the prompt is generated.
+func (m *Model) semanticGenerate(ctx context.Context, msg Message, generate func(context.Context, Message) (string, error)) (string, error) {
+	c := m.Provider.SemanticCache
+	prompt := msg.Text()
+	if c == nil || c.Store == nil || prompt == "" || len(msg.Parts) != 1 {
+		return generate(ctx, msg)
+	}
+	scope, err := m.semanticScope()
+	if err != nil {
+		m.Logger.Error(err, "Failed to hash the semantic cache scope")
+		return generate(ctx, msg)
+	}
+	var embedder tools.EmbeddingProvider = m.Provider
+	if c.Embedder != nil {
+		embedder = c.Embedder
+	}
+	embedding, err := embedder.GenerateEmbedding(ctx, prompt, c.EmbeddingModel)
+	if err != nil {
+		m.Logger.Error(err, "Failed to embed prompt for the semantic cache")
+		return generate(ctx, msg)
+	}
+	var since time.Time
+	if c.MaxAge > 0 {
+		since = time.Now().Add(-c.MaxAge)
+	}
+	threshold := c.Threshold
+	if threshold == 0 {
+		threshold = DefaultSemanticThreshold
+	}
+	match, ok, err := c.Store.Nearest(ctx, scope, embedding, since)
+	if err != nil {
+		m.Logger.Error(err, "Failed to search the semantic cache")
+	}
+	if ok && match.Similarity >= threshold {
+		c.hits.Add(1)
+		m.Logger.Info("Using semantically cached response", "prompt", match.Prompt, "similarity", match.Similarity)
+		m.recordUsage(m.ModelName, cachedUsage())
+		return match.Response, nil
+	}
+	c.misses.Add(1)
+	response, err := generate(ctx, msg)
+	if err != nil || response == "" {
+		return response, err
+	}
+	entry := SemanticEntry{Prompt: prompt, Response: response, Embedding: embedding, CreatedAt: time.Now()}
+	if err := c.Store.Add(ctx, scope, entry); err != nil {
+		m.Logger.Error(err, "Failed to add response to the semantic cache")
+	}
+	return response, nil
+}
+
+// cosineSimilarity returns the cosine of the angle between a and b, 0 when their lengths differ
+func cosineSimilarity(a, b []float32) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
+
+// MemorySemanticStore is a SemanticStore in memory that compares the prompt with every
+// entry of its scope, for caches of up to a few thousand prompts
+type MemorySemanticStore struct {
+	size    int
+	mu      sync.Mutex
+	entries map[string][]SemanticEntry
+}
+
+// NewMemorySemanticStore creates a store of at most size entries per scope, the oldest
+// are dropped first. The size is 1000 when not positive.
+func NewMemorySemanticStore(size int) *MemorySemanticStore {
+	if size <= 0 {
+		size = 1000
+	}
+	return &MemorySemanticStore{size: size, entries: make(map[string][]SemanticEntry)}
+}
+
+func (s *MemorySemanticStore) Nearest(_ context.Context, scope string, embedding []float32, since time.Time) (SemanticMatch, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var best SemanticMatch
+	found := false
+	for _, entry := range s.entries[scope] {
+		if entry.CreatedAt.Before(since) {
+			continue
+		}
+		if similarity := cosineSimilarity(embedding, entry.Embedding); !found || similarity > best.Similarity {
+			best = SemanticMatch{SemanticEntry: entry, Similarity: similarity}
+			found = true
+		}
+	}
+	return best, found, nil
+}
+
+func (s *MemorySemanticStore) Add(_ context.Context, scope string, entry SemanticEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entries := append(s.entries[scope], entry)
+	if len(entries) > s.size {
+		entries = entries[len(entries)-s.size:]
+	}
+	s.entries[scope] = entries
+	return nil
+}
+
+// PostgresSemanticStore is a SemanticStore in a PostgreSQL table with a pgvector HNSW
+// index, like the memory tool's store
+type PostgresSemanticStore struct {
+	db *sql.DB
+}
+
+// NewPostgresSemanticStore connects to the database and creates the semantic_cache table
+// for embeddings of dimensions, 1536 when zero. The table keeps the dimensions it was
+// created with.
+func NewPostgresSemanticStore(ctx context.Context, databaseURL string, dimensions int) (*PostgresSemanticStore, error) {
+	if dimensions <= 0 {
+		dimensions = 1536
+	}
+	db, err := sql.Open("postgres", databaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to ping database: %w", err)
+	}
+	queries := []string{
+		"CREATE EXTENSION IF NOT EXISTS vector",
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS semantic_cache (
+			id BIGSERIAL PRIMARY KEY,
+			scope TEXT NOT NULL,
+			prompt TEXT NOT NULL,
+			response TEXT NOT NULL,
+			embedding VECTOR(%d) NOT NULL,
+			created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+		)`, dimensions),
+		"CREATE INDEX IF NOT EXISTS idx_semantic_cache_scope ON semantic_cache (scope, created_at)",
+		"CREATE INDEX IF NOT EXISTS idx_semantic_cache_embedding ON semantic_cache USING hnsw (embedding vector_cosine_ops)",
+	}
+	for _, query := range queries {
+		if _, err := db.ExecContext(ctx, query); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("failed to initialize semantic cache schema: %w", err)
+		}
+	}
+	return &PostgresSemanticStore{db: db}, nil
+}
+
+func (s *PostgresSemanticStore) Nearest(ctx context.Context, scope string, embedding []float32, since time.Time) (SemanticMatch, bool, error) {
+	var match SemanticMatch
+	var stored pgvector.Vector
+	err := s.db.QueryRowContext(ctx, `
+		SELECT prompt, response, embedding, created_at, 1 - (embedding <=> $1) AS similarity
+		FROM semantic_cache
+		WHERE scope = $2 AND created_at >= $3
+		ORDER BY embedding <=> $1
+		LIMIT 1`,
+		pgvector.NewVector(embedding), scope, since,
+	).Scan(&match.Prompt, &match.Response, &stored, &match.CreatedAt, &match.Similarity)
+	if errors.Is(err, sql.ErrNoRows) {
+		return SemanticMatch{}, false, nil
+	}
+	if err != nil {
+		return SemanticMatch{}, false, fmt.Errorf("failed to search semantic cache: %w", err)
+	}
+	match.Embedding = stored.Slice()
+	return match, true, nil
+}
+
+func (s *PostgresSemanticStore) Add(ctx context.Context, scope string, entry SemanticEntry) error {
+	_, err := s.db.ExecContext(ctx,
+		"INSERT INTO semantic_cache (scope, prompt, response, embedding, created_at) VALUES ($1, $2, $3, $4, $5)",
+		scope, entry.Prompt, entry.Response, pgvector.NewVector(entry.Embedding), entry.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to add to semantic cache: %w", err)
+	}
+	return nil
+}
+
+// Close closes the database connection
+func (s *PostgresSemanticStore) Close() error {
+	return s.db.Close()
+}
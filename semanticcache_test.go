@@ -0,0 +1,83 @@
+package genai
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr"
+)
+
+// topicEmbedder embeds texts by the topics they mention
+type topicEmbedder struct {
+	topics []string
+}
+
+func (e topicEmbedder) GenerateEmbedding(_ context.Context, text string, _ string) ([]float32, error) {
+	embedding := make([]float32, len(e.topics)+1)
+	// a constant component keeps texts without topics comparable
+	embedding[len(e.topics)] = 0.1
+	for i, topic := range e.topics {
+		if strings.Contains(strings.ToLower(text), topic) {
+			embedding[i] = 1
+		}
+	}
+	return embedding, nil
+}
+
+func (e topicEmbedder) GenerateEmbeddings(ctx context.Context, texts []string, model string) ([][]float32, error) {
+	embeddings := make([][]float32, len(texts))
+	for i, text := range texts {
+		embeddings[i], _ = e.GenerateEmbedding(ctx, text, model)
+	}
+	return embeddings, nil
+}
+
+func TestSemanticCache(t *testing.T) {
+	srv := newTestServer(t, openAICompletionBody)
+	cache := &SemanticCache{
+		Store:    NewMemorySemanticStore(10),
+		Embedder: topicEmbedder{topics: []string{"capital", "france", "weather"}},
+	}
+	p, err := NewProvider(OPENAI, ProviderOptions{APIKey: "test", BaseURL: srv.URL, SemanticCache: cache})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Log = logr.Discard()
+	p.Retry = RetryPolicy{MaxAttempts: 1}
+	send := func(opts ModelOptions, prompt string) Usage {
+		t.Helper()
+		opts.ModelName = "test"
+		text, usage, err := p.generateWithUsage(context.Background(), opts, prompt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if text != "hello" {
+			t.Fatalf("response %q, want hello", text)
+		}
+		return usage
+	}
+
+	send(ModelOptions{}, "What is the capital of France?")
+	usage := send(ModelOptions{}, "capital of france, please")
+	if n := len(srv.recorded()); n != 1 {
+		t.Errorf("similar prompt was sent, server received %d requests, want 1", n)
+	}
+	if usage.CachedResponses != 1 || usage.Requests != 0 {
+		t.Errorf("usage %+v, want one cached response", usage)
+	}
+
+	send(ModelOptions{}, "How is the weather in France?")
+	if n := len(srv.recorded()); n != 2 {
+		t.Errorf("different prompt was answered from the cache, server received %d requests, want 2", n)
+	}
+
+	// the same prompt with another system prompt is another request
+	send(ModelOptions{SystemPrompt: "Answer in French"}, "What is the capital of France?")
+	if n := len(srv.recorded()); n != 3 {
+		t.Errorf("prompt with another system prompt was answered from the cache, server received %d requests, want 3", n)
+	}
+	if stats := p.CacheStats(); stats.SemanticHits != 1 || stats.SemanticMisses != 3 {
+		t.Errorf("stats %+v, want 1 semantic hit and 3 misses", stats)
+	}
+}
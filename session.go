@@ -0,0 +1,131 @@
+package genai
+
+import (
+	"context"
+	"maps"
+
+	"github.com/jbutlerdev/genai/tools"
+)
+
+// Session is the conversation scoped context tools run in. It replaces the static
+// basePath option of file and git tools so one process can serve conversations
+// working in different directories.
+type Session struct {
+	Workdir  string
+	RepoRoot string
+	// Facts are free form details about the environment passed to tools
+	Facts map[string]string
+	// Env are environment variables of the conversation, such as GITHUB_TOKEN or
+	// SLACK_CHANNEL. Tools read them before the process environment and ${NAME} in tool
+	// options is replaced by them, so one process can serve tenants with different
+	// settings.
+	Env map[string]string
+	// ConversationID scopes tool state such as the scratchpad, see tools.ConversationStore
+	ConversationID string
+	// CredentialScope selects the credentials tools use, e.g. the user or tenant the
+	// conversation runs for, see tools.CredentialsProvider
+	CredentialScope string
+	// RequestID identifies the current turn, it is set by the chat at the start of each
+	// turn, see WithRequestID
+	RequestID string
+}
+
+// SetWorkdir sets the directory file tools resolve paths against for this conversation
+func (c *Chat) SetWorkdir(dir string) {
+	c.model.updateSession(func(s *Session) { s.Workdir = dir })
+}
+
+// SetRepoRoot sets the repository git tools operate on for this conversation
+func (c *Chat) SetRepoRoot(dir string) {
+	c.model.updateSession(func(s *Session) { s.RepoRoot = dir })
+}
+
+// SetFact records a fact about the environment that is passed to tools
+func (c *Chat) SetFact(key string, value string) {
+	c.model.updateSession(func(s *Session) {
+		if s.Facts == nil {
+			s.Facts = make(map[string]string)
+		}
+		s.Facts[key] = value
+	})
+}
+
+// SetEnv sets an environment variable of the conversation, an empty value removes it
+func (c *Chat) SetEnv(name string, value string) {
+	c.model.updateSession(func(s *Session) {
+		if value == "" {
+			delete(s.Env, name)
+			return
+		}
+		if s.Env == nil {
+			s.Env = make(map[string]string)
+		}
+		s.Env[name] = value
+	})
+}
+
+// SetConversationID sets the id conversation scoped tool state is stored under. Use it
+// to resume the state of an earlier conversation, by default each chat has a new id.
+func (c *Chat) SetConversationID(id string) {
+	c.model.updateSession(func(s *Session) { s.ConversationID = id })
+}
+
+// SetCredentialScope sets whose credentials tools use in this conversation
+func (c *Chat) SetCredentialScope(scope string) {
+	c.model.updateSession(func(s *Session) { s.CredentialScope = scope })
+}
+
+// ID returns the conversation id
+func (c *Chat) ID() string {
+	return c.model.sessionContext().ConversationID
+}
+
+// Tasks returns the conversation's task list kept by the task tools
+func (c *Chat) Tasks(ctx context.Context) ([]tools.Task, error) {
+	return tools.ListTasks(ctx, c.ID())
+}
+
+// Session returns a copy of the conversation's session context
+func (c *Chat) Session() Session {
+	return c.model.sessionContext()
+}
+
+func (m *Model) updateSession(update func(*Session)) {
+	m.sessionMu.Lock()
+	defer m.sessionMu.Unlock()
+	update(&m.session)
+}
+
+func (m *Model) sessionContext() Session {
+	m.sessionMu.Lock()
+	defer m.sessionMu.Unlock()
+	session := m.session
+	session.Facts = maps.Clone(m.session.Facts)
+	session.Env = maps.Clone(m.session.Env)
+	return session
+}
+
+// apply adds the session context to tool arguments, overriding static tool options
+func (s Session) apply(args map[string]any) {
+	if s.Workdir != "" {
+		args[tools.BasePathArg] = s.Workdir
+	}
+	if s.RepoRoot != "" {
+		args[tools.RepoRootArg] = s.RepoRoot
+	}
+	if len(s.Facts) > 0 {
+		args[tools.SessionFactsArg] = s.Facts
+	}
+	if len(s.Env) > 0 {
+		args[tools.EnvArg] = s.Env
+	}
+	if s.ConversationID != "" {
+		args[tools.ConversationIDArg] = s.ConversationID
+	}
+	if s.CredentialScope != "" {
+		args[tools.CredentialScopeArg] = s.CredentialScope
+	}
+	if s.RequestID != "" {
+		args[tools.RequestIDArg] = s.RequestID
+	}
+}
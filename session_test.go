@@ -0,0 +1,88 @@
+package genai
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/jbutlerdev/genai/tools"
+)
+
+func TestCommandToolBasePath(t *testing.T) {
+	tool, err := tools.NewCommandTool(tools.CommandToolConfig{Name: "mock_pwd", Command: "pwd"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tools.RegisterTool(tool)
+	t.Cleanup(func() { tools.UnregisterTool("mock_pwd") })
+	p, err := NewMockProvider()
+	if err != nil {
+		t.Fatal(err)
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	workdir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{wd, workdir} {
+		chat := p.ChatEvents(ModelOptions{ModelName: "mock"}, []*tools.Tool{&tool})
+		if want != wd {
+			chat.SetWorkdir(want)
+		}
+		// the model can not move the command out of the conversation's directory
+		result, err := chat.model.runTool("mock_pwd", map[string]any{tools.BasePathArg: "/"})
+		close(chat.Done)
+		if err != nil {
+			t.Fatalf("pwd failed: %v", err)
+		}
+		if dir := result.(map[string]any)["output"]; dir != want {
+			t.Errorf("ran in %v, want %s", dir, want)
+		}
+	}
+}
+
+func TestModelRepoRootIgnored(t *testing.T) {
+	// newRepo creates a repository with a changed notes.txt
+	newRepo := func() string {
+		dir := t.TempDir()
+		if _, err := git.PlainInit(dir, false); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("draft"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return dir
+	}
+	tool, err := tools.GetTool("revertFile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := NewMockProvider()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, setRoot := range []func(*Chat, string){(*Chat).SetRepoRoot, (*Chat).SetWorkdir} {
+		repo, other := newRepo(), newRepo()
+		chat := p.ChatEvents(ModelOptions{ModelName: "mock"}, []*tools.Tool{tool})
+		setRoot(chat, repo)
+		_, err := chat.model.runTool("revertFile", map[string]any{
+			"file":            "notes.txt",
+			tools.RepoRootArg: other,
+			tools.BasePathArg: other,
+		})
+		close(chat.Done)
+		if err != nil {
+			t.Fatalf("revert failed: %v", err)
+		}
+		if _, err := os.Stat(filepath.Join(repo, "notes.txt")); !os.IsNotExist(err) {
+			t.Errorf("the conversation's repository was not reverted: %v", err)
+		}
+		if _, err := os.Stat(filepath.Join(other, "notes.txt")); err != nil {
+			t.Errorf("the repository the model supplied was changed: %v", err)
+		}
+	}
+}
@@ -0,0 +1,197 @@
+package genai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+)
+
+// ErrShuttingDown is returned for turns and requests started after Provider.Shutdown
+var ErrShuttingDown = errors.New("provider is shutting down")
+
+// CheckpointKey is the key Shutdown stores the transcript of each conversation under in
+// ProviderOptions.Checkpoints, restore it with Chat.Import
+const CheckpointKey = "transcript"
+
+// lifecycle tracks the chats and in-flight work of a provider so Shutdown can drain them
+type lifecycle struct {
+	mu      sync.Mutex
+	closing bool
+	// closed is closed when Shutdown starts, chats stop waiting for messages
+	closed chan struct{}
+	active sync.WaitGroup
+	// cancels abort the in-flight turns and requests when the deadline passes
+	cancels map[int]context.CancelCauseFunc
+	next    int
+	chats   map[*Chat]struct{}
+}
+
+func newLifecycle() *lifecycle {
+	return &lifecycle{
+		closed:  make(chan struct{}),
+		cancels: make(map[int]context.CancelCauseFunc),
+		chats:   make(map[*Chat]struct{}),
+	}
+}
+
+// begin registers a turn or request, it returns ctx canceled by Shutdown and the
+// function that ends it. It fails with ErrShuttingDown once Shutdown started.
+func (l *lifecycle) begin(ctx context.Context) (context.Context, func(), error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closing {
+		return ctx, nil, ErrShuttingDown
+	}
+	ctx, cancel := context.WithCancelCause(ctx)
+	l.next++
+	id := l.next
+	l.cancels[id] = cancel
+	l.active.Add(1)
+	return ctx, func() {
+		l.mu.Lock()
+		delete(l.cancels, id)
+		l.mu.Unlock()
+		cancel(nil)
+		l.active.Done()
+	}, nil
+}
+
+func (l *lifecycle) addChat(chat *Chat) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.chats[chat] = struct{}{}
+}
+
+func (l *lifecycle) removeChat(chat *Chat) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.chats, chat)
+}
+
+// close stops accepting new work and returns the open chats, false when Shutdown
+// already started
+func (l *lifecycle) close() ([]*Chat, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closing {
+		return nil, false
+	}
+	l.closing = true
+	close(l.closed)
+	chats := make([]*Chat, 0, len(l.chats))
+	for chat := range l.chats {
+		chats = append(chats, chat)
+	}
+	return chats, true
+}
+
+// cancel aborts the work still in flight
+func (l *lifecycle) cancel() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, cancel := range l.cancels {
+		cancel(ErrShuttingDown)
+	}
+}
+
+// Shutdown stops the provider. New turns and requests fail with ErrShuttingDown and
+// chats stop receiving messages. It waits for the turns, generate calls and tool calls
+// in flight until ctx is done and cancels the ones still running after that, a tool
+// that is already running is left to finish in the background. The conversations are
+// then saved to ProviderOptions.Checkpoints, and the response cache, the semantic cache
+// store and idle connections are closed. The database of the memory tools is shared by
+// the process and stays open, close it with tools.CloseMemoryTool.
+//
+// Shutdown returns ctx.Err() when the deadline passed before the work drained, joined
+// with any error saving checkpoints or closing stores. Calling it again returns
+// ErrShuttingDown.
+func (p *Provider) Shutdown(ctx context.Context) error {
+	chats, ok := p.life.close()
+	if !ok {
+		return ErrShuttingDown
+	}
+	p.Log.Info("Shutting down", "chats", len(chats))
+	var errs []error
+	drained := make(chan struct{})
+	go func() {
+		p.life.active.Wait()
+		close(drained)
+	}()
+	select {
+	case <-drained:
+	case <-ctx.Done():
+		p.Log.Info("Shutdown deadline passed, canceling in-flight requests")
+		p.life.cancel()
+		errs = append(errs, ctx.Err())
+	}
+
+	// the deadline may have passed, saving and closing are not canceled with it
+	ctx = context.WithoutCancel(ctx)
+	if p.checkpoints != nil {
+		for _, chat := range chats {
+			if err := p.checkpoint(ctx, chat); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	if p.cache != nil {
+		if closer, ok := p.cache.cache.(io.Closer); ok {
+			if err := closer.Close(); err != nil {
+				errs = append(errs, fmt.Errorf("failed to close response cache: %w", err))
+			}
+		}
+	}
+	if p.SemanticCache != nil {
+		if closer, ok := p.SemanticCache.Store.(io.Closer); ok {
+			if err := closer.Close(); err != nil {
+				errs = append(errs, fmt.Errorf("failed to close semantic cache store: %w", err))
+			}
+		}
+	}
+	for _, client := range p.clients() {
+		client.closeIdleConnections()
+	}
+	return errors.Join(errs...)
+}
+
+// checkpoint saves the transcript of chat to the provider's checkpoint store
+func (p *Provider) checkpoint(ctx context.Context, chat *Chat) error {
+	data, err := chat.Export()
+	if err != nil {
+		return fmt.Errorf("failed to checkpoint conversation %s: %w", chat.ID(), err)
+	}
+	if err := p.checkpoints.Set(ctx, chat.ID(), CheckpointKey, string(data)); err != nil {
+		return fmt.Errorf("failed to checkpoint conversation %s: %w", chat.ID(), err)
+	}
+	return nil
+}
+
+// clients returns the client of each endpoint of the provider
+func (p *Provider) clients() []*Client {
+	if p.balancer == nil {
+		return []*Client{p.Client}
+	}
+	clients := make([]*Client, 0, len(p.balancer.endpoints))
+	for _, e := range p.balancer.endpoints {
+		clients = append(clients, e.client)
+	}
+	return clients
+}
+
+// closeIdleConnections closes the idle connections of the HTTP client the SDK uses, the
+// Gemini SDK keeps its own client unless a transport option is set
+func (c *Client) closeIdleConnections() {
+	if c.http != nil {
+		c.http.CloseIdleConnections()
+	}
+}
+
+// closeIdle closes the idle connections of transport when it supports it
+func closeIdle(transport http.RoundTripper) {
+	if closer, ok := transport.(interface{ CloseIdleConnections() }); ok {
+		closer.CloseIdleConnections()
+	}
+}
@@ -0,0 +1,222 @@
+package genai
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/jbutlerdev/genai/tools"
+)
+
+const openAIHelloBody = `{"id":"chatcmpl-1","object":"chat.completion","created":0,"model":"test","choices":[{"index":0,"finish_reason":"stop",` +
+	`"message":{"role":"assistant","content":"Hello"}}]}`
+
+func waitTurn(t *testing.T, chat *Chat) TurnComplete {
+	t.Helper()
+	timeout := time.After(10 * time.Second)
+	for {
+		select {
+		case event := <-chat.Events:
+			if complete, ok := event.(TurnComplete); ok {
+				return complete
+			}
+		case <-timeout:
+			t.Fatal("the turn did not complete")
+		}
+	}
+}
+
+func TestShutdown(t *testing.T) {
+	srv := newTestServer(t, openAIHelloBody)
+	started, release := srv.hold()
+	store := tools.NewInMemoryConversationStore()
+	p, err := NewProvider(OPENAI, ProviderOptions{
+		APIKey:      "test",
+		BaseURL:     srv.URL,
+		Retry:       &RetryPolicy{MaxAttempts: 1},
+		Checkpoints: store,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Log = logr.Discard()
+	chat := p.ChatEvents(ModelOptions{ModelName: "test"}, nil)
+	defer close(chat.Done)
+	if err := chat.SendCtx(context.Background(), "Hi"); err != nil {
+		t.Fatal(err)
+	}
+	<-started
+
+	shutdown := make(chan error, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		shutdown <- p.Shutdown(ctx)
+	}()
+	// new turns and requests are refused while the turn in flight drains
+	deadline := time.Now().Add(5 * time.Second)
+	for chat.SendCtx(context.Background(), "Still there?") != ErrShuttingDown {
+		if time.Now().After(deadline) {
+			t.Fatal("the chat still accepts messages")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if _, err := p.Generate(ModelOptions{ModelName: "test"}, "Hi"); !errors.Is(err, ErrShuttingDown) {
+		t.Errorf("generate error %v, want %v", err, ErrShuttingDown)
+	}
+	select {
+	case err := <-shutdown:
+		t.Fatalf("shutdown returned %v before the turn completed", err)
+	default:
+	}
+
+	close(release)
+	if complete := waitTurn(t, chat); complete.Text != "Hello" || complete.Canceled {
+		t.Errorf("turn %+v, want it completed", complete)
+	}
+	if err := <-shutdown; err != nil {
+		t.Fatalf("shutdown failed: %v", err)
+	}
+	data, ok, err := store.Get(context.Background(), chat.ID(), CheckpointKey)
+	if err != nil || !ok {
+		t.Fatalf("no checkpoint: %v", err)
+	}
+	transcript, err := ParseTranscript([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(transcript.Messages) != 2 || transcript.Messages[1].Text() != "Hello" {
+		t.Errorf("checkpoint messages %+v", transcript.Messages)
+	}
+	if err := p.Shutdown(context.Background()); !errors.Is(err, ErrShuttingDown) {
+		t.Errorf("second shutdown %v, want %v", err, ErrShuttingDown)
+	}
+}
+
+func TestShutdownDeadline(t *testing.T) {
+	srv := newTestServer(t, openAIHelloBody)
+	started, _ := srv.hold()
+	p, err := NewProvider(OPENAI, ProviderOptions{APIKey: "test", BaseURL: srv.URL, Retry: &RetryPolicy{MaxAttempts: 1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Log = logr.Discard()
+	chat := p.ChatEvents(ModelOptions{ModelName: "test"}, nil)
+	defer close(chat.Done)
+	if err := chat.SendCtx(context.Background(), "Hi"); err != nil {
+		t.Fatal(err)
+	}
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := p.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("shutdown error %v, want %v", err, context.DeadlineExceeded)
+	}
+	if complete := waitTurn(t, chat); !complete.Canceled {
+		t.Errorf("turn %+v, want it canceled", complete)
+	}
+	if len(chat.History()) != 0 {
+		t.Errorf("history %+v, want the canceled message removed", chat.History())
+	}
+}
+
+func TestShutdownCancelsGenerateMessage(t *testing.T) {
+	srv := newTestServer(t, openAIHelloBody)
+	started, _ := srv.hold()
+	p, err := NewProvider(OPENAI, ProviderOptions{APIKey: "test", BaseURL: srv.URL, Retry: &RetryPolicy{MaxAttempts: 1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Log = logr.Discard()
+	generated := make(chan error, 1)
+	go func() {
+		_, err := p.GenerateMessage(ModelOptions{ModelName: "test"}, ChatMessage{Text: "Hi"})
+		generated <- err
+	}()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := p.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("shutdown error %v, want %v", err, context.DeadlineExceeded)
+	}
+	select {
+	case err := <-generated:
+		if err == nil {
+			t.Error("the generate call was not canceled")
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("the generate call did not return")
+	}
+}
+
+// fakeDriver is a database/sql driver whose connections accept every statement and
+// count how many were closed
+type fakeDriver struct {
+	closed atomic.Int32
+}
+
+type fakeConn struct {
+	driver *fakeDriver
+}
+
+func (d *fakeDriver) Open(string) (driver.Conn, error) {
+	return fakeConn{driver: d}, nil
+}
+
+func (c fakeConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("not supported")
+}
+
+func (c fakeConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("not supported")
+}
+
+func (c fakeConn) ExecContext(context.Context, string, []driver.NamedValue) (driver.Result, error) {
+	return driver.RowsAffected(0), nil
+}
+
+func (c fakeConn) Close() error {
+	c.driver.closed.Add(1)
+	return nil
+}
+
+var shutdownDriver = &fakeDriver{}
+
+func init() {
+	sql.Register("genai-shutdown-test", shutdownDriver)
+}
+
+func TestShutdownKeepsMemoryTool(t *testing.T) {
+	db, err := sql.Open("genai-shutdown-test", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tools.InitializeMemoryToolWithDB(db, tools.MemoryConfig{}, nil); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { tools.CloseMemoryTool() })
+	p, err := NewProvider(OPENAI, ProviderOptions{APIKey: "test", BaseURL: newTestServer(t, openAIHelloBody).URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Shutdown(context.Background()); err != nil {
+		t.Fatalf("shutdown failed: %v", err)
+	}
+	// the memory tools are shared with the other providers of the process
+	if err := db.Ping(); err != nil {
+		t.Errorf("the memory tool database was closed: %v", err)
+	}
+	if err := tools.CloseMemoryTool(); err != nil {
+		t.Fatal(err)
+	}
+	if shutdownDriver.closed.Load() == 0 {
+		t.Error("no connection of the memory tool was closed")
+	}
+}
@@ -0,0 +1,95 @@
+package genai
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/openai/openai-go"
+)
+
+const (
+	// DefaultSpeechModel is used when Provider.SpeechModel is empty
+	DefaultSpeechModel = "tts-1"
+	// DefaultVoice is used when Speak is called without a voice
+	DefaultVoice = "alloy"
+)
+
+// SpeechOptions configures SpeakWithOptions
+type SpeechOptions struct {
+	// Voice defaults to DefaultVoice, e.g. alloy, echo, fable, onyx, nova or shimmer
+	Voice string
+	// Model overrides Provider.SpeechModel
+	Model string
+	// Format of the audio: mp3 (default), opus, aac, flac, wav or pcm
+	Format string
+	// Speed from 0.25 to 4.0, 1.0 when zero
+	Speed float64
+	// Instructions control the tone of voice, only supported by newer models
+	Instructions string
+}
+
+// Speak converts text to speech and returns the audio as mp3
+func (p *Provider) Speak(ctx context.Context, text string, voice string) ([]byte, error) {
+	return p.SpeakWithOptions(ctx, text, SpeechOptions{Voice: voice})
+}
+
+// SpeakWithOptions converts text to speech with OpenAI TTS or a compatible server
+func (p *Provider) SpeakWithOptions(ctx context.Context, text string, opts SpeechOptions) ([]byte, error) {
+	if text == "" {
+		return nil, fmt.Errorf("no text to speak")
+	}
+	if opts.Model == "" {
+		opts.Model = p.SpeechModel
+	}
+	if opts.Model == "" {
+		opts.Model = DefaultSpeechModel
+	}
+	if opts.Voice == "" {
+		opts.Voice = DefaultVoice
+	}
+	ctx, cancel := context.WithTimeout(ctx, p.Timeouts.merge(DefaultTimeouts).Generate)
+	defer cancel()
+	switch p.Provider {
+	case OPENAI, VLLM:
+		return p.Client.OpenAI.Speak(ctx, text, opts)
+	default:
+		return nil, fmt.Errorf("unsupported provider for speech: %s", p.Provider)
+	}
+}
+
+// Speak sends text to the speech endpoint and returns the audio
+func (c *OpenAIClient) Speak(ctx context.Context, text string, opts SpeechOptions) ([]byte, error) {
+	params := openai.AudioSpeechNewParams{
+		Input: text,
+		Model: opts.Model,
+		Voice: openai.AudioSpeechNewParamsVoice(opts.Voice),
+	}
+	if opts.Format != "" {
+		params.ResponseFormat = openai.AudioSpeechNewParamsResponseFormat(opts.Format)
+	}
+	if opts.Speed != 0 {
+		params.Speed = openai.Float(opts.Speed)
+	}
+	if opts.Instructions != "" {
+		params.Instructions = openai.String(opts.Instructions)
+	}
+	audio, err := retry(ctx, c.retry, c.log, c.provider, c.metrics, func() ([]byte, error) {
+		return openAIBalanced(c, func(client *OpenAIClient) ([]byte, error) {
+			resp, err := client.client.Audio.Speech.New(ctx, params)
+			if err != nil {
+				return nil, err
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				return nil, fmt.Errorf("status code: %d", resp.StatusCode)
+			}
+			return io.ReadAll(resp.Body)
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate speech: %w", err)
+	}
+	return audio, nil
+}
@@ -0,0 +1,223 @@
+package genai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"strings"
+
+	"github.com/go-logr/logr"
+)
+
+// Chunk is a piece of a streamed response. The last chunk has no text and carries the
+// usage of the request.
+type Chunk struct {
+	Text  string
+	Usage *Usage
+}
+
+// errStreamStopped ends a stream whose consumer stopped ranging over it
+var errStreamStopped = errors.New("stream stopped")
+
+// Stream runs a single prompt like Generate and yields the response as it is generated.
+// Breaking out of the loop cancels the request, an error is yielded once and ends the
+// stream. With output guardrails the response is held back until they checked it and
+// yielded in one chunk.
+//
+//	for chunk, err := range provider.Stream(ctx, opts, "Tell me a story") {
+//		if err != nil {
+//			return err
+//		}
+//		fmt.Print(chunk.Text)
+//	}
+func (p *Provider) Stream(ctx context.Context, modelOptions ModelOptions, prompt string) iter.Seq2[Chunk, error] {
+	return func(yield func(Chunk, error) bool) {
+		ctx, end, err := p.life.begin(ctx)
+		if err != nil {
+			yield(Chunk{}, err)
+			return
+		}
+		defer end()
+		ctx, requestID := ensureRequestID(ctx)
+		l := p.Log.WithName("stream").WithValues("model", modelOptions.ModelName, "id", requestID)
+		model := NewModel(p, modelOptions, l)
+		model.setRequestID(requestID)
+		switch p.Provider {
+		case OLLAMA:
+			model.ollamaClient = p.Client.Ollama
+		case OPENAI, VLLM:
+			model.openAIClient = p.Client.OpenAI
+		}
+		streamContext, cancel := context.WithTimeout(ctx, model.timeouts().Generate)
+		defer cancel()
+
+		msg, err := model.guardPrompt(streamContext, nil, NewTextMessage(RoleUser, prompt))
+		if err != nil {
+			yield(Chunk{}, err)
+			return
+		}
+		// output guardrails check the whole response, it is held back and yielded in one
+		// chunk once they passed it
+		guarded := model.guarded(GuardOutput)
+		var held strings.Builder
+		stopped := false
+		var filter thinkFilter
+		send := func(text string) bool {
+			if model.Reasoning != ReasoningKeep {
+				text = filter.write(text)
+			}
+			if guarded {
+				held.WriteString(text)
+				return true
+			}
+			if text == "" || stopped {
+				return !stopped
+			}
+			stopped = !yield(Chunk{Text: text}, nil)
+			return !stopped
+		}
+		err = model.stream(streamContext, msg, send)
+		if stopped {
+			return
+		}
+		if err != nil {
+			yield(Chunk{}, err)
+			return
+		}
+		text := filter.flush()
+		if guarded {
+			text, err = model.guardResponse(streamContext, nil, held.String()+text)
+			if err != nil {
+				yield(Chunk{}, err)
+				return
+			}
+		}
+		if text != "" && !yield(Chunk{Text: text}, nil) {
+			return
+		}
+		usage := model.Usage()
+		yield(Chunk{Usage: &usage}, nil)
+	}
+}
+
+// stream runs a single user message and passes the response to send as it arrives
+func (m *Model) stream(ctx context.Context, msg Message, send func(string) bool) error {
+	m.Logger.Info("Streaming content", "content", msg.Text())
+	switch m.Provider.Provider {
+	case GEMINI:
+		messages := append(exampleMessages(m.Examples), msg)
+		if err := geminiStream(ctx, m, m.routedModel(messages), messages, send); err != nil {
+			return fmt.Errorf("failed to stream content: %w", err)
+		}
+		return nil
+	case OLLAMA:
+		if err := ollamaStream(ctx, m, msg, send); err != nil {
+			return fmt.Errorf("failed to stream content with Ollama: %w", err)
+		}
+		return nil
+	case OPENAI, VLLM:
+		options := m.options()
+		options.ModelName = m.routedModel([]Message{msg})
+		done, err := m.startRequest(ctx, options.ModelName, []Message{msg})
+		if err != nil {
+			return fmt.Errorf("failed to stream content with OpenAI: %w", err)
+		}
+		send, text := collectResponse(send)
+		usage, err := m.openAIClient.streamMessage(ctx, options, msg, send)
+		done(NewTextMessage(RoleAssistant, text.String()), err)
+		m.recordUsage(options.ModelName, usage)
+		if err != nil {
+			return fmt.Errorf("failed to stream content with OpenAI: %w", err)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported provider: %s", m.Provider.Provider)
+	}
+}
+
+// streamWithRetry retries run according to the policy until the first text is sent.
+// Errors after that are returned as they are, the text that was sent can't be taken back.
+func streamWithRetry(ctx context.Context, policy RetryPolicy, logger logr.Logger, provider string, metrics Metrics, send func(string) bool, run func(send func(string) bool) error) error {
+	started := false
+	track := func(text string) bool {
+		if text != "" {
+			started = true
+		}
+		return send(text)
+	}
+	var streamErr error
+	_, err := retry(ctx, policy, logger, provider, metrics, func() (struct{}, error) {
+		err := run(track)
+		if err != nil && started {
+			streamErr = err
+			return struct{}{}, nil
+		}
+		return struct{}{}, err
+	})
+	if streamErr != nil {
+		streamErr = wrapProviderError(provider, streamErr)
+		metrics.Failure(provider, streamErr)
+		return streamErr
+	}
+	return err
+}
+
+// thinkFilter removes <think> blocks from streamed text. The end of a chunk that could
+// be the start of a tag is held back until the next chunk. Unlike splitThinking, a
+// block opened by the chat template can't be recognized before its closing tag arrives.
+type thinkFilter struct {
+	thinking bool
+	pending  string
+}
+
+func (f *thinkFilter) write(text string) string {
+	text = f.pending + text
+	f.pending = ""
+	var sb strings.Builder
+	for text != "" {
+		tag := thinkOpen
+		if f.thinking {
+			tag = thinkClose
+		}
+		if i := strings.Index(text, tag); i >= 0 {
+			if !f.thinking {
+				sb.WriteString(text[:i])
+			}
+			text = text[i+len(tag):]
+			f.thinking = !f.thinking
+			continue
+		}
+		keep := partialTag(text, tag)
+		if !f.thinking {
+			sb.WriteString(text[:len(text)-keep])
+		}
+		f.pending = text[len(text)-keep:]
+		break
+	}
+	return sb.String()
+}
+
+// flush returns the text held back at the end of the stream
+func (f *thinkFilter) flush() string {
+	pending := f.pending
+	f.pending = ""
+	if f.thinking {
+		return ""
+	}
+	return pending
+}
+
+// partialTag returns the length of the longest suffix of text that starts tag
+func partialTag(text, tag string) int {
+	n := len(tag) - 1
+	if len(text) < n {
+		n = len(text)
+	}
+	for ; n > 0; n-- {
+		if strings.HasSuffix(text, tag[:n]) {
+			return n
+		}
+	}
+	return 0
+}
@@ -0,0 +1,182 @@
+package genai
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	// DefaultSummaryModel summarizes the results of tools marked with Summarize when no
+	// utility model is configured
+	DefaultSummaryModel = "llamacpp/qwen3-30b-a3b"
+	// maxSummaryQuotes limits the quotes kept in a summary's appendix
+	maxSummaryQuotes = 8
+)
+
+// sourceKeys are argument and result fields that identify where content came from
+var sourceKeys = map[string]bool{
+	"url":    true,
+	"link":   true,
+	"source": true,
+	"path":   true,
+	"file":   true,
+	"id":     true,
+}
+
+var fencePattern = regexp.MustCompile("(?s)^```(?:json)?\\s*(.*?)\\s*```$")
+
+// defaultToolSummaryPrompt is used for tool results when no SummaryPrompt is configured
+const defaultToolSummaryPrompt = `Summarize these tool results in 5000 words or less. Your summarization must be shorter than the provided value\n
+			If there appears to be an error, just return the error with no additional information\n
+			Do not provide any reference to the word count or the fact that you summarized.\n`
+
+// utilitySettings select the model and prompt used for compaction and tool result
+// summaries
+type utilitySettings struct {
+	model  string
+	prompt string
+}
+
+func (p *Provider) utility() utilitySettings {
+	return utilitySettings{model: p.UtilityModel, prompt: p.SummaryPrompt}
+}
+
+// utility returns the model's settings, falling back to the provider's
+func (m *Model) utility() utilitySettings {
+	utility := m.Provider.utility()
+	if m.UtilityModel != "" {
+		utility.model = m.UtilityModel
+	}
+	if m.SummaryPrompt != "" {
+		utility.prompt = m.SummaryPrompt
+	}
+	return utility
+}
+
+// summarize generates a summary for compaction with the utility model, or with the
+// model itself when none is configured
+func (m *Model) summarize(prompt string) (string, error) {
+	model := m.utility().model
+	if model == "" {
+		return m.generate(prompt)
+	}
+	// the utility model runs without the chat's system prompt and examples
+	summarizer := NewModel(m.Provider, ModelOptions{
+		ModelName:  model,
+		Parameters: m.Parameters,
+		Timeouts:   m.Timeouts,
+	}, m.Logger)
+	return summarizer.generate(prompt)
+}
+
+// summarizeToolResult condenses a large tool result. Sources and verbatim quotes are
+// returned next to the summary so answers built on it can still cite the original.
+func (p *Provider) summarizeToolResult(args map[string]any, result any, utility utilitySettings) (any, error) {
+	content := fmt.Sprintf("%v", result)
+	sources := citationSources(args, result)
+	model := utility.model
+	if model == "" {
+		model = DefaultSummaryModel
+	}
+	prompt := utility.prompt
+	if prompt == "" {
+		prompt = defaultToolSummaryPrompt
+	}
+	summary, err := p.Generate(ModelOptions{
+		ModelName: model,
+		Parameters: map[string]any{
+			NumPredict: 5000,
+		},
+	}, fmt.Sprintf(`%s
+			Also copy up to %d short quotes that support the most important facts, word for word from the results.\n
+			Respond with JSON only: {"summary": "...", "quotes": ["..."]}\n\n%s`, prompt, maxSummaryQuotes, content))
+	if err != nil {
+		return map[string]any{
+			"success": false,
+			"error":   err.Error(),
+			"sources": sources,
+		}, err
+	}
+	text, quotes := parseSummary(summary)
+	return map[string]any{
+		"success": true,
+		"summary": text,
+		"sources": sources,
+		"quotes":  verifiedQuotes(quotes, content),
+	}, nil
+}
+
+// parseSummary reads the summary JSON, falling back to the raw text when the model
+// did not follow the format
+func parseSummary(response string) (string, []string) {
+	response = strings.TrimSpace(response)
+	if match := fencePattern.FindStringSubmatch(response); match != nil {
+		response = match[1]
+	}
+	var parsed struct {
+		Summary string   `json:"summary"`
+		Quotes  []string `json:"quotes"`
+	}
+	if err := json.Unmarshal([]byte(response), &parsed); err != nil || parsed.Summary == "" {
+		return response, nil
+	}
+	return parsed.Summary, parsed.Quotes
+}
+
+// verifiedQuotes keeps quotes that appear in the content, models tend to paraphrase
+func verifiedQuotes(quotes []string, content string) []string {
+	normalized := normalizeQuote(content)
+	verified := []string{}
+	for _, quote := range quotes {
+		quote = strings.TrimSpace(quote)
+		if quote == "" || !strings.Contains(normalized, normalizeQuote(quote)) {
+			continue
+		}
+		verified = append(verified, quote)
+		if len(verified) == maxSummaryQuotes {
+			break
+		}
+	}
+	return verified
+}
+
+func normalizeQuote(s string) string {
+	return strings.ToLower(strings.Join(strings.Fields(s), " "))
+}
+
+// citationSources collects source identifiers from the tool arguments and result
+func citationSources(args map[string]any, result any) []string {
+	seen := make(map[string]bool)
+	collectSources(args, seen)
+	collectSources(result, seen)
+	sources := make([]string, 0, len(seen))
+	for source := range seen {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+	return sources
+}
+
+func collectSources(value any, seen map[string]bool) {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, field := range v {
+			if s, ok := field.(string); ok && sourceKeys[strings.ToLower(key)] && s != "" {
+				seen[s] = true
+				continue
+			}
+			collectSources(field, seen)
+		}
+	case []any:
+		for _, item := range v {
+			collectSources(item, seen)
+		}
+	case []map[string]any:
+		for _, item := range v {
+			collectSources(item, seen)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package genai
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// testServer answers requests with scripted responses in order, repeating the last one,
+// and records the requests it receives
+type testServer struct {
+	*httptest.Server
+	tb        testing.TB
+	responses []string
+	stop      chan struct{}
+
+	mu       sync.Mutex
+	requests []recordedRequest
+	started  chan struct{}
+	release  chan struct{}
+}
+
+// recordedRequest is a request as the test server received it
+type recordedRequest struct {
+	path   string
+	header http.Header
+	body   []byte
+}
+
+func newTestServer(tb testing.TB, responses ...string) *testServer {
+	tb.Helper()
+	s := &testServer{tb: tb, responses: responses, stop: make(chan struct{})}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	tb.Cleanup(s.Close)
+	tb.Cleanup(func() { close(s.stop) })
+	return s
+}
+
+func (s *testServer) serve(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	s.mu.Lock()
+	s.requests = append(s.requests, recordedRequest{path: r.URL.Path, header: r.Header.Clone(), body: body})
+	response := s.responses[len(s.responses)-1]
+	if len(s.requests) < len(s.responses) {
+		response = s.responses[len(s.requests)-1]
+	}
+	started, release := s.started, s.release
+	s.mu.Unlock()
+	if started != nil {
+		started <- struct{}{}
+		select {
+		case <-release:
+		case <-r.Context().Done():
+			return
+		case <-s.stop:
+			return
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	io.WriteString(w, response)
+}
+
+// hold makes the server answer only once release is closed, requests that are canceled
+// or still waiting at the end of the test are not answered. started receives each
+// request as it arrives.
+func (s *testServer) hold() (started chan struct{}, release chan struct{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.started = make(chan struct{}, 4)
+	s.release = make(chan struct{})
+	return s.started, s.release
+}
+
+func (s *testServer) recorded() []recordedRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]recordedRequest(nil), s.requests...)
+}
+
+// bodies decodes the JSON bodies of the requests
+func (s *testServer) bodies() []map[string]any {
+	var bodies []map[string]any
+	for _, req := range s.recorded() {
+		var body map[string]any
+		if err := json.Unmarshal(req.body, &body); err != nil {
+			s.tb.Errorf("request to %s is not JSON: %v", req.path, err)
+		}
+		bodies = append(bodies, body)
+	}
+	return bodies
+}
+
+// last returns the JSON body of the last request
+func (s *testServer) last() map[string]any {
+	bodies := s.bodies()
+	if len(bodies) == 0 {
+		return nil
+	}
+	return bodies[len(bodies)-1]
+}
+
+// headers returns the value of a header in each request
+func (s *testServer) headers(name string) []string {
+	var values []string
+	for _, req := range s.recorded() {
+		values = append(values, req.header.Get(name))
+	}
+	return values
+}
@@ -0,0 +1,37 @@
+package genai
+
+import "time"
+
+// Timeouts bound the duration of provider requests. Zero fields use the default.
+type Timeouts struct {
+	// Chat bounds a single chat turn request, tool calls are not included
+	Chat      time.Duration
+	Generate  time.Duration
+	Embedding time.Duration
+}
+
+// DefaultTimeouts are used for fields left unset in ProviderOptions.Timeouts
+var DefaultTimeouts = Timeouts{
+	Chat:      1 * time.Hour,
+	Generate:  1 * time.Hour,
+	Embedding: 1 * time.Hour,
+}
+
+// merge returns t with zero fields taken from fallback
+func (t Timeouts) merge(fallback Timeouts) Timeouts {
+	if t.Chat <= 0 {
+		t.Chat = fallback.Chat
+	}
+	if t.Generate <= 0 {
+		t.Generate = fallback.Generate
+	}
+	if t.Embedding <= 0 {
+		t.Embedding = fallback.Embedding
+	}
+	return t
+}
+
+// timeouts returns the model's timeouts, ModelOptions.Timeouts override the provider's
+func (m *Model) timeouts() Timeouts {
+	return m.Timeouts.merge(m.Provider.Timeouts).merge(DefaultTimeouts)
+}
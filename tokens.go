@@ -0,0 +1,144 @@
+package genai
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	ollama "github.com/ollama/ollama/api"
+	"github.com/tiktoken-go/tokenizer"
+	gemini "google.golang.org/genai"
+)
+
+// TokenCounter counts the tokens of text with the tokenizer of a model
+type TokenCounter interface {
+	CountTokens(ctx context.Context, model string, text string) (int, error)
+}
+
+// CountTokens counts the tokens of text for a model or alias. OpenAI models are counted
+// locally, Gemini models with the countTokens API and Ollama models with the tokenizer
+// family reported by /api/show.
+func (p *Provider) CountTokens(model string, text string) (int, error) {
+	ctx, cancel := context.WithTimeout(p.Client.ctx, p.Timeouts.merge(DefaultTimeouts).Generate)
+	defer cancel()
+	return p.tokenCounter().CountTokens(ctx, p.ResolveModel(model), text)
+}
+
+func (p *Provider) tokenCounter() TokenCounter {
+	if p.TokenCounter != nil {
+		return p.TokenCounter
+	}
+	return TiktokenCounter{}
+}
+
+// defaultTokenCounter picks the counter for the provider
+func defaultTokenCounter(p *Provider) TokenCounter {
+	switch p.Provider {
+	case GEMINI:
+		return &geminiTokenCounter{provider: p}
+	case OLLAMA:
+		return &ollamaTokenCounter{provider: p}
+	default:
+		return TiktokenCounter{}
+	}
+}
+
+// TiktokenCounter counts with the tiktoken encoding of OpenAI models. Models it does not
+// know, such as local models served by vLLM, are counted with cl100k_base.
+type TiktokenCounter struct{}
+
+func (TiktokenCounter) CountTokens(ctx context.Context, model string, text string) (int, error) {
+	codec, err := codecFor(EncodingForModel(model))
+	if err != nil {
+		return 0, err
+	}
+	return codec.Count(text)
+}
+
+// o200kPrefixes are the OpenAI model families that use o200k_base
+var o200kPrefixes = []string{"gpt-4o", "gpt-4.1", "gpt-4.5", "gpt-5", "chatgpt-4o", "o1", "o3", "o4"}
+
+// EncodingForModel returns the tiktoken encoding used by an OpenAI model
+func EncodingForModel(model string) tokenizer.Encoding {
+	short := model[strings.LastIndex(model, "/")+1:]
+	for _, prefix := range o200kPrefixes {
+		if strings.HasPrefix(short, prefix) {
+			return tokenizer.O200kBase
+		}
+	}
+	return tokenizer.Cl100kBase
+}
+
+var codecs sync.Map
+
+// codecFor returns a shared codec, building one loads the encoding's vocabulary
+func codecFor(encoding tokenizer.Encoding) (tokenizer.Codec, error) {
+	if codec, ok := codecs.Load(encoding); ok {
+		return codec.(tokenizer.Codec), nil
+	}
+	codec, err := tokenizer.Get(encoding)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load tokenizer %s: %w", encoding, err)
+	}
+	actual, _ := codecs.LoadOrStore(encoding, codec)
+	return actual.(tokenizer.Codec), nil
+}
+
+// geminiTokenCounter calls the countTokens API
+type geminiTokenCounter struct {
+	provider *Provider
+}
+
+func (c *geminiTokenCounter) CountTokens(ctx context.Context, model string, text string) (int, error) {
+	if text == "" {
+		return 0, nil
+	}
+	contents := []*gemini.Content{gemini.NewContentFromText(text, gemini.RoleUser)}
+	resp, err := retry(ctx, c.provider.Retry, c.provider.Log, GEMINI, c.provider.observer(), func() (*gemini.CountTokensResponse, error) {
+		return balanced(c.provider, func(client *Client) (*gemini.CountTokensResponse, error) {
+			return client.Gemini.Models.CountTokens(ctx, model, contents, nil)
+		})
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to count tokens: %w", err)
+	}
+	return int(resp.TotalTokens), nil
+}
+
+// ollamaTokenCounter counts with the tiktoken encoding closest to the model's tokenizer.
+// Ollama has no tokenize endpoint, so the tokenizer is read from /api/show once per model.
+type ollamaTokenCounter struct {
+	provider  *Provider
+	encodings sync.Map
+}
+
+func (c *ollamaTokenCounter) CountTokens(ctx context.Context, model string, text string) (int, error) {
+	encoding, ok := c.encodings.Load(model)
+	if !ok {
+		show, err := retry(ctx, c.provider.Retry, c.provider.Log, OLLAMA, c.provider.observer(), func() (*ollama.ShowResponse, error) {
+			return balanced(c.provider, func(client *Client) (*ollama.ShowResponse, error) {
+				return client.Ollama.Show(ctx, &ollama.ShowRequest{Model: model})
+			})
+		})
+		if err != nil {
+			return 0, fmt.Errorf("failed to inspect model %s: %w", model, err)
+		}
+		encoding, _ = c.encodings.LoadOrStore(model, ollamaEncoding(show))
+	}
+	codec, err := codecFor(encoding.(tokenizer.Encoding))
+	if err != nil {
+		return 0, err
+	}
+	return codec.Count(text)
+}
+
+// ollamaEncoding maps the GGUF pre-tokenizer to a tiktoken encoding
+func ollamaEncoding(show *ollama.ShowResponse) tokenizer.Encoding {
+	pre, _ := show.ModelInfo["tokenizer.ggml.pre"].(string)
+	switch pre {
+	case "gpt-4o", "llama4":
+		return tokenizer.O200kBase
+	}
+	return tokenizer.Cl100kBase
+}
@@ -0,0 +1,135 @@
+package genai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/jbutlerdev/genai/tools"
+)
+
+// DefaultMaxToolFailures is the number of consecutive failed calls to a tool allowed
+// in one turn before the tool is no longer run
+const DefaultMaxToolFailures = 3
+
+// ToolFailure is the feedback a model receives when a tool call fails. It is sent as
+// the JSON content of the tool result for every provider.
+type ToolFailure struct {
+	Tool      string `json:"tool"`
+	Error     string `json:"error"`
+	Hint      string `json:"hint,omitempty"`
+	Retryable bool   `json:"retryable"`
+}
+
+// String renders the failure as JSON
+func (f ToolFailure) String() string {
+	content, err := json.Marshal(f)
+	if err != nil {
+		return fmt.Sprintf("error: %s", f.Error)
+	}
+	return string(content)
+}
+
+// newToolFailure describes err with a hint on how the model can recover
+func newToolFailure(toolName string, err error) ToolFailure {
+	failure := ToolFailure{Tool: toolName, Error: err.Error(), Retryable: true}
+	var toolErr *tools.ToolError
+	switch {
+	case errors.As(err, &toolErr):
+		failure.Hint = toolErr.Hint
+		failure.Retryable = toolErr.Retryable
+	case errors.Is(err, tools.ErrToolNotFound):
+		failure.Hint = "Only call the tools you were given."
+		failure.Retryable = false
+	case errors.Is(err, context.DeadlineExceeded):
+		failure.Hint = "The tool timed out, try a smaller request."
+	default:
+		failure.Hint = "Check the arguments against the tool's parameters before calling it again."
+	}
+	return failure
+}
+
+// formatToolResult renders the result of a tool for the model, text as it is and other
+// values as JSON
+func formatToolResult(result any) string {
+	switch r := result.(type) {
+	case nil:
+		return ""
+	case string:
+		return r
+	}
+	content, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Sprintf("%v", result)
+	}
+	return string(content)
+}
+
+// toolCallKey identifies calls of the same tool with the same arguments, a call
+// repeated in one response is run once
+func toolCallKey(call ToolCall) string {
+	return call.Name + "\x00" + call.argumentsJSON()
+}
+
+// toolResult builds the result sent to the model for a tool call, content is the
+// rendering of a successful result
+func (m *Model) toolResult(id string, name string, content string, err error) ToolResult {
+	if err == nil {
+		m.toolSucceeded(name)
+		return ToolResult{ID: id, Name: name, Content: content}
+	}
+	failure := newToolFailure(name, err)
+	if failures := m.toolFailed(name); failures >= m.maxToolFailures() {
+		failure.Retryable = false
+		failure.Hint = fmt.Sprintf("%s failed %d times in a row and will not be run again this turn. Continue without it.", name, failures)
+	}
+	return ToolResult{ID: id, Name: name, Content: failure.String(), IsError: true}
+}
+
+// toolBlocked returns a failure result when the tool failed too often this turn
+func (m *Model) toolBlocked(id string, name string) (ToolResult, bool) {
+	m.toolMu.Lock()
+	failures := m.toolFailures[name]
+	m.toolMu.Unlock()
+	if failures < m.maxToolFailures() {
+		return ToolResult{}, false
+	}
+	m.Logger.Info("Skipping tool after consecutive failures", "name", name, "failures", failures)
+	failure := ToolFailure{
+		Tool:  name,
+		Error: "tool disabled after repeated failures",
+		Hint:  fmt.Sprintf("%s failed %d times in a row and will not be run again this turn. Continue without it.", name, failures),
+	}
+	return ToolResult{ID: id, Name: name, Content: failure.String(), IsError: true}, true
+}
+
+func (m *Model) maxToolFailures() int {
+	if m.MaxToolFailures > 0 {
+		return m.MaxToolFailures
+	}
+	return DefaultMaxToolFailures
+}
+
+func (m *Model) toolFailed(name string) int {
+	m.toolMu.Lock()
+	defer m.toolMu.Unlock()
+	if m.toolFailures == nil {
+		m.toolFailures = make(map[string]int)
+	}
+	m.toolFailures[name]++
+	return m.toolFailures[name]
+}
+
+func (m *Model) toolSucceeded(name string) {
+	m.toolMu.Lock()
+	defer m.toolMu.Unlock()
+	delete(m.toolFailures, name)
+}
+
+// resetToolFailures starts a new turn
+func (m *Model) resetToolFailures() {
+	m.toolMu.Lock()
+	defer m.toolMu.Unlock()
+	m.toolFailures = nil
+}
@@ -0,0 +1,21 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// DecodeArgs decodes tool arguments into a struct using its json tags, so tools can
+// work with typed fields instead of asserting map values. Arguments added by the
+// session and tool options are ignored unless the struct has a field for them.
+func DecodeArgs[T any](args map[string]any) (T, error) {
+	var typed T
+	data, err := json.Marshal(args)
+	if err != nil {
+		return typed, fmt.Errorf("failed to encode arguments: %w", err)
+	}
+	if err := json.Unmarshal(data, &typed); err != nil {
+		return typed, NewToolError(fmt.Errorf("invalid arguments: %w", err), "Check the argument types against the tool's parameters.", true)
+	}
+	return typed, nil
+}
@@ -0,0 +1,473 @@
+package tools
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+	"unicode"
+)
+
+const (
+	CalculatorToolName = "calculate"
+
+	// calcPrec is the binary precision of intermediate results, about 150 decimal digits
+	calcPrec          = 512
+	defaultCalcDigits = 30
+	maxCalcDigits     = 100
+	// maxCalcExponent bounds integer powers so an expression can not exhaust memory
+	maxCalcExponent = 100000
+
+	calcPi = "3.14159265358979323846264338327950288419716939937510582097494459230781640628620899862803482534211706798214808651328230664709384460955058223172535940812848111745028410270193852110555964462294895493038196"
+	calcE  = "2.71828182845904523536028747135266249775724709369995957496696762772407663035354759457138217852516642742746639193200305992181741359662904357290033429526059563073813232862794349076323382988075319525101901"
+)
+
+var calculatorTools = map[string]Tool{
+	CalculatorToolName: calculatorTool,
+}
+
+var calculatorTool = Tool{
+	Name:        CalculatorToolName,
+	Description: "Evaluate a math expression with arbitrary precision instead of doing arithmetic in text. Supports + - * / % ^, parentheses, the constants pi and e and the functions sqrt, abs, floor, ceil, round, exp, ln, log, log2, sin, cos, tan, asin, acos, atan, min and max.",
+	Parameters: []Parameter{
+		{
+			Name:        "expression",
+			Type:        "string",
+			Description: "The expression to evaluate, e.g. \"(1.5 + 2) * 3^2 / sqrt(2)\"",
+			Required:    true,
+		},
+		{
+			Name:        "precision",
+			Type:        "integer",
+			Description: "The number of significant digits in the result, defaults to 30",
+			Required:    false,
+		},
+	},
+	Options: map[string]string{},
+	Run:     Calculate,
+	Examples: []Example{
+		{Description: "Compound interest on 1000 at 5% over 10 years", Args: map[string]any{"expression": "1000 * (1 + 0.05)^10", "precision": 10}},
+	},
+}
+
+func Calculate(args map[string]any) (map[string]any, error) {
+	expression, ok := args["expression"].(string)
+	if !ok {
+		return map[string]any{
+			"success": false,
+			"error":   fmt.Sprintf("expected string: %v", args["expression"]),
+		}, fmt.Errorf("expected string: %v", args["expression"])
+	}
+	digits := min(max(intArg(args["precision"], defaultCalcDigits), 1), maxCalcDigits)
+	result, err := Evaluate(expression)
+	if err != nil {
+		return map[string]any{
+			"success": false,
+			"error":   err.Error(),
+		}, err
+	}
+	return map[string]any{
+		"success":    true,
+		"expression": expression,
+		"result":     formatCalcResult(result, digits),
+	}, nil
+}
+
+// Evaluate parses and evaluates a math expression
+func Evaluate(expression string) (*big.Float, error) {
+	p := &calcParser{input: []rune(expression)}
+	result, err := p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	if p.pos < len(p.input) {
+		return nil, fmt.Errorf("unexpected %q at position %d", string(p.input[p.pos]), p.pos+1)
+	}
+	return result, nil
+}
+
+// formatCalcResult prints integers exactly and other values with digits significant digits
+func formatCalcResult(f *big.Float, digits int) string {
+	if f.IsInt() {
+		if i, _ := f.Int(nil); len(i.String()) <= maxCalcDigits {
+			return i.String()
+		}
+	}
+	return f.Text('g', digits)
+}
+
+// calcParser is a recursive descent parser, each level handles one precedence:
+// expression = term {("+"|"-") term}
+// term       = unary {("*"|"/"|"%") unary}
+// unary      = ("+"|"-") unary | power
+// power      = primary ["^" unary]
+// primary    = number | constant | function "(" args ")" | "(" expression ")"
+type calcParser struct {
+	input []rune
+	pos   int
+}
+
+func newCalcFloat() *big.Float {
+	return new(big.Float).SetPrec(calcPrec)
+}
+
+func calcConstant(digits string) *big.Float {
+	value, _, _ := newCalcFloat().Parse(digits, 10)
+	return value
+}
+
+func (p *calcParser) skipSpace() {
+	for p.pos < len(p.input) && unicode.IsSpace(p.input[p.pos]) {
+		p.pos++
+	}
+}
+
+// accept consumes r if it is the next non space character
+func (p *calcParser) accept(r rune) bool {
+	p.skipSpace()
+	if p.pos < len(p.input) && p.input[p.pos] == r {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *calcParser) parseExpression() (*big.Float, error) {
+	left, err := p.parseTerm()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		switch {
+		case p.accept('+'):
+			right, err := p.parseTerm()
+			if err != nil {
+				return nil, err
+			}
+			if left, err = calcFinite(newCalcFloat().Add(left, right)); err != nil {
+				return nil, err
+			}
+		case p.accept('-'):
+			right, err := p.parseTerm()
+			if err != nil {
+				return nil, err
+			}
+			if left, err = calcFinite(newCalcFloat().Sub(left, right)); err != nil {
+				return nil, err
+			}
+		default:
+			return left, nil
+		}
+	}
+}
+
+func (p *calcParser) parseTerm() (*big.Float, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		var op rune
+		switch {
+		case p.accept('*'):
+			op = '*'
+		case p.accept('/'):
+			op = '/'
+		case p.accept('%'):
+			op = '%'
+		default:
+			return left, nil
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		switch op {
+		case '*':
+			if left, err = calcFinite(newCalcFloat().Mul(left, right)); err != nil {
+				return nil, err
+			}
+		case '/':
+			if right.Sign() == 0 {
+				return nil, fmt.Errorf("division by zero")
+			}
+			if left, err = calcFinite(newCalcFloat().Quo(left, right)); err != nil {
+				return nil, err
+			}
+		case '%':
+			if left, err = calcMod(left, right); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+func (p *calcParser) parseUnary() (*big.Float, error) {
+	if p.accept('-') {
+		value, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return newCalcFloat().Neg(value), nil
+	}
+	if p.accept('+') {
+		return p.parseUnary()
+	}
+	return p.parsePower()
+}
+
+func (p *calcParser) parsePower() (*big.Float, error) {
+	base, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	if !p.accept('^') {
+		return base, nil
+	}
+	// right associative, 2^3^2 is 2^9
+	exponent, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	return calcPow(base, exponent)
+}
+
+func (p *calcParser) parsePrimary() (*big.Float, error) {
+	p.skipSpace()
+	if p.pos >= len(p.input) {
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+	r := p.input[p.pos]
+	switch {
+	case r == '(':
+		p.pos++
+		value, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		if !p.accept(')') {
+			return nil, fmt.Errorf("missing closing parenthesis")
+		}
+		return value, nil
+	case unicode.IsDigit(r) || r == '.':
+		return p.parseNumber()
+	case unicode.IsLetter(r):
+		return p.parseIdentifier()
+	}
+	return nil, fmt.Errorf("unexpected %q at position %d", string(r), p.pos+1)
+}
+
+func (p *calcParser) parseNumber() (*big.Float, error) {
+	start := p.pos
+	for p.pos < len(p.input) && (unicode.IsDigit(p.input[p.pos]) || p.input[p.pos] == '.' || p.input[p.pos] == '_') {
+		p.pos++
+	}
+	// scientific notation such as 1.5e-3
+	if p.pos < len(p.input) && (p.input[p.pos] == 'e' || p.input[p.pos] == 'E') {
+		end := p.pos + 1
+		if end < len(p.input) && (p.input[end] == '+' || p.input[end] == '-') {
+			end++
+		}
+		if end < len(p.input) && unicode.IsDigit(p.input[end]) {
+			for end < len(p.input) && unicode.IsDigit(p.input[end]) {
+				end++
+			}
+			p.pos = end
+		}
+	}
+	text := strings.ReplaceAll(string(p.input[start:p.pos]), "_", "")
+	value, _, err := newCalcFloat().Parse(text, 10)
+	if err != nil {
+		return nil, fmt.Errorf("invalid number %q", text)
+	}
+	return value, nil
+}
+
+func (p *calcParser) parseIdentifier() (*big.Float, error) {
+	start := p.pos
+	for p.pos < len(p.input) && (unicode.IsLetter(p.input[p.pos]) || unicode.IsDigit(p.input[p.pos])) {
+		p.pos++
+	}
+	name := strings.ToLower(string(p.input[start:p.pos]))
+	switch name {
+	case "pi":
+		return calcConstant(calcPi), nil
+	case "e":
+		return calcConstant(calcE), nil
+	}
+	if !p.accept('(') {
+		return nil, fmt.Errorf("unknown constant %q", name)
+	}
+	var args []*big.Float
+	if !p.accept(')') {
+		for {
+			arg, err := p.parseExpression()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+			if p.accept(')') {
+				break
+			}
+			if !p.accept(',') {
+				return nil, fmt.Errorf("expected , or ) in arguments of %s", name)
+			}
+		}
+	}
+	return calcFunction(name, args)
+}
+
+func calcFunction(name string, args []*big.Float) (*big.Float, error) {
+	switch name {
+	case "min", "max":
+		if len(args) == 0 {
+			return nil, fmt.Errorf("%s expects at least one argument", name)
+		}
+		result := args[0]
+		for _, arg := range args[1:] {
+			if (name == "min" && arg.Cmp(result) < 0) || (name == "max" && arg.Cmp(result) > 0) {
+				result = arg
+			}
+		}
+		return result, nil
+	}
+	if len(args) != 1 {
+		return nil, fmt.Errorf("%s expects 1 argument, got %d", name, len(args))
+	}
+	x := args[0]
+	switch name {
+	case "sqrt":
+		if x.Sign() < 0 {
+			return nil, fmt.Errorf("sqrt of a negative number")
+		}
+		return newCalcFloat().Sqrt(x), nil
+	case "abs":
+		return newCalcFloat().Abs(x), nil
+	case "floor", "ceil", "round":
+		return calcRound(name, x), nil
+	}
+	f, _ := x.Float64()
+	var result float64
+	switch name {
+	case "exp":
+		result = math.Exp(f)
+	case "ln":
+		result = math.Log(f)
+	case "log":
+		result = math.Log10(f)
+	case "log2":
+		result = math.Log2(f)
+	case "sin":
+		result = math.Sin(f)
+	case "cos":
+		result = math.Cos(f)
+	case "tan":
+		result = math.Tan(f)
+	case "asin":
+		result = math.Asin(f)
+	case "acos":
+		result = math.Acos(f)
+	case "atan":
+		result = math.Atan(f)
+	default:
+		return nil, fmt.Errorf("unknown function %q", name)
+	}
+	if math.IsNaN(result) || math.IsInf(result, 0) {
+		return nil, fmt.Errorf("%s(%s) is undefined", name, x.Text('g', 10))
+	}
+	return newCalcFloat().SetFloat64(result), nil
+}
+
+// calcRound rounds to an integer, round uses half away from zero
+func calcRound(name string, x *big.Float) *big.Float {
+	i, accuracy := x.Int(nil)
+	result := newCalcFloat().SetInt(i)
+	if accuracy == big.Exact {
+		return result
+	}
+	one := newCalcFloat().SetInt64(1)
+	switch name {
+	case "floor":
+		if x.Sign() < 0 {
+			result.Sub(result, one)
+		}
+	case "ceil":
+		if x.Sign() > 0 {
+			result.Add(result, one)
+		}
+	case "round":
+		frac := newCalcFloat().Sub(x, result)
+		half := newCalcFloat().SetFloat64(0.5)
+		if frac.Abs(frac).Cmp(half) >= 0 {
+			if x.Sign() > 0 {
+				result.Add(result, one)
+			} else {
+				result.Sub(result, one)
+			}
+		}
+	}
+	return result
+}
+
+// calcFinite rejects results beyond the exponent range of big.Float, arithmetic on an
+// infinity panics in math/big
+func calcFinite(f *big.Float) (*big.Float, error) {
+	if f.IsInf() {
+		return nil, fmt.Errorf("overflow")
+	}
+	return f, nil
+}
+
+func calcMod(x *big.Float, y *big.Float) (*big.Float, error) {
+	if y.Sign() == 0 {
+		return nil, fmt.Errorf("modulo by zero")
+	}
+	if x.IsInt() && y.IsInt() {
+		a, _ := x.Int(nil)
+		b, _ := y.Int(nil)
+		return newCalcFloat().SetInt(new(big.Int).Rem(a, b)), nil
+	}
+	// x - y*trunc(x/y)
+	quotient, err := calcFinite(newCalcFloat().Quo(x, y))
+	if err != nil {
+		return nil, err
+	}
+	q, _ := quotient.Int(nil)
+	return newCalcFloat().Sub(x, newCalcFloat().Mul(y, newCalcFloat().SetInt(q))), nil
+}
+
+// calcPow is exact for integer exponents and uses float64 otherwise
+func calcPow(base *big.Float, exponent *big.Float) (*big.Float, error) {
+	if exponent.IsInt() {
+		e, _ := exponent.Int64()
+		if e > maxCalcExponent || e < -maxCalcExponent {
+			return nil, fmt.Errorf("exponent %d is too large", e)
+		}
+		if base.Sign() == 0 && e < 0 {
+			return nil, fmt.Errorf("division by zero")
+		}
+		result := newCalcFloat().SetInt64(1)
+		b := newCalcFloat().Set(base)
+		for n := max(e, -e); n > 0; n >>= 1 {
+			if n&1 == 1 {
+				result.Mul(result, b)
+			}
+			b.Mul(b, b)
+		}
+		if result.IsInf() {
+			return nil, fmt.Errorf("overflow")
+		}
+		if e < 0 {
+			result.Quo(newCalcFloat().SetInt64(1), result)
+		}
+		return result, nil
+	}
+	b, _ := base.Float64()
+	e, _ := exponent.Float64()
+	result := math.Pow(b, e)
+	if math.IsNaN(result) || math.IsInf(result, 0) {
+		return nil, fmt.Errorf("%s^%s is undefined", base.Text('g', 10), exponent.Text('g', 10))
+	}
+	return newCalcFloat().SetFloat64(result), nil
+}
@@ -0,0 +1,80 @@
+package tools
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCalculate(t *testing.T) {
+	tests := []struct {
+		expression string
+		precision  int
+		want       string
+	}{
+		{"1 + 2 * 3", 0, "7"},
+		{"(1 + 2) * 3", 0, "9"},
+		{"10 - 4 - 3", 0, "3"},
+		{"2^3^2", 0, "512"},
+		{"-2^2", 0, "-4"},
+		{"(-2)^2", 0, "4"},
+		{"2^-1", 0, "0.5"},
+		{"7 % 3", 0, "1"},
+		{"-7 % 3", 0, "-1"},
+		{"5.5 % 2", 0, "1.5"},
+		{"2 + 3 % 2 * 4", 0, "6"},
+		{"1 / 3", 5, "0.33333"},
+		{"round(2.5)", 0, "3"},
+		{"round(-2.5)", 0, "-3"},
+		{"floor(-1.2)", 0, "-2"},
+		{"ceil(1.2)", 0, "2"},
+		{"max(1, 4, 2)", 0, "4"},
+		{"sqrt(16)", 0, "4"},
+	}
+	for _, test := range tests {
+		args := map[string]any{"expression": test.expression}
+		if test.precision > 0 {
+			args["precision"] = test.precision
+		}
+		result, err := Calculate(args)
+		if err != nil {
+			t.Errorf("%s: %v", test.expression, err)
+			continue
+		}
+		if result["result"] != test.want {
+			t.Errorf("%s = %v, want %s", test.expression, result["result"], test.want)
+		}
+	}
+}
+
+func TestCalculateErrors(t *testing.T) {
+	tests := []struct {
+		expression string
+		want       string
+	}{
+		{"1 / 0", "division by zero"},
+		{"0^-1", "division by zero"},
+		{"1 % 0", "modulo by zero"},
+		{"(10^100000)^100000", "overflow"},
+		{"(10^100000)^100000 - (10^100000)^100000", "overflow"},
+		{"(10^100000)^100000 * 0", "overflow"},
+		{"(10^100000)^-100000", "overflow"},
+		{"2^1000001", "too large"},
+		{"sqrt(-1)", "negative"},
+		{"(1 + 2", "missing closing parenthesis"},
+		{"1 +", "unexpected end"},
+		{"foo(1)", "unknown function"},
+	}
+	for _, test := range tests {
+		result, err := Calculate(map[string]any{"expression": test.expression})
+		if err == nil {
+			t.Errorf("%s: expected an error, got %v", test.expression, result["result"])
+			continue
+		}
+		if !strings.Contains(err.Error(), test.want) {
+			t.Errorf("%s: error %q, want %q", test.expression, err, test.want)
+		}
+		if result["success"] != false {
+			t.Errorf("%s: success %v", test.expression, result["success"])
+		}
+	}
+}
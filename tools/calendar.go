@@ -0,0 +1,794 @@
+package tools
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+const (
+	ListCalendarEventsToolName  = "list_calendar_events"
+	CreateCalendarEventToolName = "create_calendar_event"
+
+	// Credentials read when the calendar is not configured with ConfigureCalendar.
+	// CalDAV is used when CALDAV_URL is set, Google Calendar otherwise.
+	GoogleCalendarIDEnv           = "GOOGLE_CALENDAR_ID"
+	GoogleCalendarAccessTokenEnv  = "GOOGLE_CALENDAR_ACCESS_TOKEN"
+	GoogleCalendarClientIDEnv     = "GOOGLE_CALENDAR_CLIENT_ID"
+	GoogleCalendarClientSecretEnv = "GOOGLE_CALENDAR_CLIENT_SECRET"
+	GoogleCalendarRefreshTokenEnv = "GOOGLE_CALENDAR_REFRESH_TOKEN"
+	CalDAVURLEnv                  = "CALDAV_URL"
+	CalDAVUsernameEnv             = "CALDAV_USERNAME"
+	CalDAVPasswordEnv             = "CALDAV_PASSWORD"
+
+	// maxCalendarEvents is the number of events fetched for one list call
+	maxCalendarEvents = 500
+	calendarPageSize  = 50
+)
+
+var (
+	googleCalendarAPIURL = "https://www.googleapis.com/calendar/v3"
+	googleTokenURL       = "https://oauth2.googleapis.com/token"
+)
+
+// CalendarConfig configures the calendar tools. Set CalDAVURL to use a CalDAV server,
+// otherwise events are read from and written to Google Calendar.
+type CalendarConfig struct {
+	// GoogleCalendarID defaults to the primary calendar
+	GoogleCalendarID string
+	// GoogleAccessToken is used as is, for tokens managed by the application
+	GoogleAccessToken string
+	// GoogleClientID, GoogleClientSecret and GoogleRefreshToken are exchanged for
+	// access tokens, which are refreshed when they expire
+	GoogleClientID     string
+	GoogleClientSecret string
+	GoogleRefreshToken string
+	// GoogleTokenSource overrides the other Google credentials
+	GoogleTokenSource oauth2.TokenSource
+
+	// CalDAVURL is the URL of the calendar collection, e.g.
+	// https://dav.example.com/calendars/user/personal/
+	CalDAVURL      string
+	CalDAVUsername string
+	CalDAVPassword string
+}
+
+var (
+	calendarConfig *CalendarConfig
+	// calendarBackends holds a backend per credential scope, or a single one under the
+	// empty scope when the calendar was configured with ConfigureCalendar
+	calendarBackends = map[string]calendarClient{}
+	calendarConfigMu sync.Mutex
+)
+
+// ConfigureCalendar replaces the configuration read from the credentials provider
+func ConfigureCalendar(config CalendarConfig) {
+	calendarConfigMu.Lock()
+	defer calendarConfigMu.Unlock()
+	calendarConfig = &config
+	calendarBackends = map[string]calendarClient{}
+}
+
+// calendarEvent is the event representation shared by the backends
+type calendarEvent struct {
+	ID          string
+	Title       string
+	Start       time.Time
+	End         time.Time
+	AllDay      bool
+	Location    string
+	Description string
+	Attendees   []string
+	URL         string
+}
+
+type calendarClient interface {
+	listEvents(ctx context.Context, start time.Time, end time.Time, query string) ([]calendarEvent, error)
+	createEvent(ctx context.Context, event calendarEvent) (calendarEvent, error)
+}
+
+// getCalendar returns the backend of the credential scope in args, created once so
+// refreshed Google tokens are reused
+func getCalendar(args map[string]any) (calendarClient, error) {
+	calendarConfigMu.Lock()
+	defer calendarConfigMu.Unlock()
+	scope := ""
+	if calendarConfig == nil {
+		scope, _ = args[CredentialScopeArg].(string)
+	}
+	if backend, ok := calendarBackends[scope]; ok {
+		return backend, nil
+	}
+	config := calendarConfig
+	if config == nil {
+		creds := credentialsFor(args)
+		config = &CalendarConfig{
+			GoogleCalendarID:   creds.get(GoogleCalendarIDEnv),
+			GoogleAccessToken:  creds.get(GoogleCalendarAccessTokenEnv),
+			GoogleClientID:     creds.get(GoogleCalendarClientIDEnv),
+			GoogleClientSecret: creds.get(GoogleCalendarClientSecretEnv),
+			GoogleRefreshToken: creds.get(GoogleCalendarRefreshTokenEnv),
+			CalDAVURL:          creds.get(CalDAVURLEnv),
+			CalDAVUsername:     creds.get(CalDAVUsernameEnv),
+			CalDAVPassword:     creds.get(CalDAVPasswordEnv),
+		}
+		if creds.err != nil {
+			return nil, creds.err
+		}
+	}
+	if config.CalDAVURL != "" {
+		backend := &caldavCalendar{
+			url:      strings.TrimRight(config.CalDAVURL, "/") + "/",
+			username: config.CalDAVUsername,
+			password: config.CalDAVPassword,
+		}
+		calendarBackends[scope] = backend
+		return backend, nil
+	}
+	tokens := config.GoogleTokenSource
+	switch {
+	case tokens != nil:
+	case config.GoogleRefreshToken != "":
+		oauthConfig := &oauth2.Config{
+			ClientID:     config.GoogleClientID,
+			ClientSecret: config.GoogleClientSecret,
+			Endpoint:     oauth2.Endpoint{TokenURL: googleTokenURL},
+		}
+		tokens = oauthConfig.TokenSource(context.Background(), &oauth2.Token{RefreshToken: config.GoogleRefreshToken})
+	case config.GoogleAccessToken != "":
+		tokens = oauth2.StaticTokenSource(&oauth2.Token{AccessToken: config.GoogleAccessToken})
+	default:
+		return nil, fmt.Errorf("calendar is not configured, set %s or %s", CalDAVURLEnv, GoogleCalendarRefreshTokenEnv)
+	}
+	calendarID := config.GoogleCalendarID
+	if calendarID == "" {
+		calendarID = "primary"
+	}
+	backend := &googleCalendar{calendarID: calendarID, tokens: oauth2.ReuseTokenSource(nil, tokens)}
+	calendarBackends[scope] = backend
+	return backend, nil
+}
+
+var calendarTools = map[string]Tool{
+	ListCalendarEventsToolName:  listCalendarEventsTool,
+	CreateCalendarEventToolName: createCalendarEventTool,
+}
+
+var listCalendarEventsTool = Tool{
+	Name:        ListCalendarEventsToolName,
+	Description: "List calendar events between two times, recurring events are expanded",
+	Parameters: []Parameter{
+		{
+			Name:        "start",
+			Type:        "string",
+			Description: "Start of the range as RFC 3339 or YYYY-MM-DD, defaults to now",
+			Required:    false,
+		},
+		{
+			Name:        "end",
+			Type:        "string",
+			Description: "End of the range as RFC 3339 or YYYY-MM-DD, defaults to 7 days after start",
+			Required:    false,
+		},
+		{
+			Name:        "query",
+			Type:        "string",
+			Description: "Only return events whose title, description or location contain this text",
+			Required:    false,
+		},
+		timezoneParameter,
+	},
+	Options:   map[string]string{},
+	Run:       ListCalendarEvents,
+	Paginated: true,
+}
+
+var createCalendarEventTool = Tool{
+	Name:        CreateCalendarEventToolName,
+	Description: "Create a calendar event",
+	Parameters: []Parameter{
+		{
+			Name:        "title",
+			Type:        "string",
+			Description: "The title of the event",
+			Required:    true,
+		},
+		{
+			Name:        "start",
+			Type:        "string",
+			Description: "Start as RFC 3339, or YYYY-MM-DD for an all day event",
+			Required:    true,
+		},
+		{
+			Name:        "end",
+			Type:        "string",
+			Description: "End in the same format as start, defaults to one hour after start or the end of an all day event",
+			Required:    false,
+		},
+		{
+			Name:        "description",
+			Type:        "string",
+			Description: "Notes for the event",
+			Required:    false,
+		},
+		{
+			Name:        "location",
+			Type:        "string",
+			Description: "Where the event takes place",
+			Required:    false,
+		},
+		{
+			Name:        "attendees",
+			Type:        "stringArray",
+			Description: "Email addresses to invite",
+			Required:    false,
+		},
+		timezoneParameter,
+	},
+	Options: map[string]string{},
+	Run:     CreateCalendarEvent,
+}
+
+type createCalendarEventArgs struct {
+	Title       string   `json:"title"`
+	Start       string   `json:"start"`
+	End         string   `json:"end"`
+	Description string   `json:"description"`
+	Location    string   `json:"location"`
+	Attendees   []string `json:"attendees"`
+}
+
+func ListCalendarEvents(args map[string]any) (map[string]any, error) {
+	loc, err := timezoneArg(args)
+	var start, end time.Time
+	if err == nil {
+		start, err = dateArg(args, "start", loc)
+	}
+	if err == nil {
+		end = start.AddDate(0, 0, 7)
+		if value, _ := args["end"].(string); value != "" {
+			end, err = dateArg(args, "end", loc)
+		}
+	}
+	if err == nil && !end.After(start) {
+		err = NewToolError(fmt.Errorf("end must be after start"), "", true)
+	}
+	var client calendarClient
+	if err == nil {
+		client, err = getCalendar(args)
+	}
+	var events []calendarEvent
+	if err == nil {
+		query, _ := args["query"].(string)
+		ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+		defer cancel()
+		events, err = client.listEvents(ctx, start, end, query)
+	}
+	var page []calendarEvent
+	next := ""
+	if err == nil {
+		sort.SliceStable(events, func(i, j int) bool { return events[i].Start.Before(events[j].Start) })
+		page, next, err = Paginate(events, args, calendarPageSize)
+	}
+	if err != nil {
+		return map[string]any{
+			"success": false,
+			"error":   err.Error(),
+		}, err
+	}
+	results := make([]map[string]any, len(page))
+	for i, event := range page {
+		results[i] = event.toMap(loc)
+	}
+	return setNextCursor(map[string]any{
+		"success": true,
+		"events":  results,
+		"total":   len(events),
+	}, next), nil
+}
+
+func CreateCalendarEvent(args map[string]any) (map[string]any, error) {
+	typed, err := DecodeArgs[createCalendarEventArgs](args)
+	if err == nil && (typed.Title == "" || typed.Start == "") {
+		err = NewToolError(fmt.Errorf("title and start are required"), "", true)
+	}
+	var loc *time.Location
+	if err == nil {
+		loc, err = timezoneArg(args)
+	}
+	event := calendarEvent{
+		Title:       typed.Title,
+		Description: typed.Description,
+		Location:    typed.Location,
+		Attendees:   typed.Attendees,
+	}
+	if err == nil {
+		event.AllDay = len(typed.Start) == len(time.DateOnly)
+		event.Start, err = dateArg(args, "start", loc)
+	}
+	if err == nil {
+		switch {
+		case typed.End != "":
+			event.End, err = dateArg(args, "end", loc)
+			// an all day event ending on a date includes that day
+			if event.AllDay && len(typed.End) == len(time.DateOnly) {
+				event.End = event.End.AddDate(0, 0, 1)
+			}
+		case event.AllDay:
+			event.End = event.Start.AddDate(0, 0, 1)
+		default:
+			event.End = event.Start.Add(time.Hour)
+		}
+	}
+	if err == nil && !event.End.After(event.Start) {
+		err = NewToolError(fmt.Errorf("end must be after start"), "", true)
+	}
+	var client calendarClient
+	if err == nil {
+		client, err = getCalendar(args)
+	}
+	if err == nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+		defer cancel()
+		event, err = client.createEvent(ctx, event)
+	}
+	if err != nil {
+		return map[string]any{
+			"success": false,
+			"error":   err.Error(),
+		}, err
+	}
+	return map[string]any{
+		"success": true,
+		"event":   event.toMap(loc),
+	}, nil
+}
+
+func (e calendarEvent) toMap(loc *time.Location) map[string]any {
+	result := map[string]any{
+		"id":    e.ID,
+		"title": e.Title,
+	}
+	if e.AllDay {
+		result["all_day"] = true
+		result["start"] = e.Start.Format(time.DateOnly)
+		// all day events end at the start of the next day
+		result["end"] = e.End.AddDate(0, 0, -1).Format(time.DateOnly)
+	} else {
+		result["start"] = e.Start.In(loc).Format(time.RFC3339)
+		result["end"] = e.End.In(loc).Format(time.RFC3339)
+	}
+	if e.Location != "" {
+		result["location"] = e.Location
+	}
+	if e.Description != "" {
+		result["description"] = e.Description
+	}
+	if len(e.Attendees) > 0 {
+		result["attendees"] = e.Attendees
+	}
+	if e.URL != "" {
+		result["url"] = e.URL
+	}
+	return result
+}
+
+func (e calendarEvent) matches(query string) bool {
+	query = strings.ToLower(query)
+	return strings.Contains(strings.ToLower(e.Title), query) ||
+		strings.Contains(strings.ToLower(e.Description), query) ||
+		strings.Contains(strings.ToLower(e.Location), query)
+}
+
+// googleCalendar uses the Google Calendar v3 REST API
+type googleCalendar struct {
+	calendarID string
+	tokens     oauth2.TokenSource
+}
+
+type googleEventTime struct {
+	DateTime string `json:"dateTime,omitempty"`
+	Date     string `json:"date,omitempty"`
+}
+
+type googleEvent struct {
+	ID          string          `json:"id,omitempty"`
+	Summary     string          `json:"summary"`
+	Description string          `json:"description,omitempty"`
+	Location    string          `json:"location,omitempty"`
+	HTMLLink    string          `json:"htmlLink,omitempty"`
+	Start       googleEventTime `json:"start"`
+	End         googleEventTime `json:"end"`
+	Attendees   []struct {
+		Email string `json:"email"`
+	} `json:"attendees,omitempty"`
+}
+
+func (g *googleCalendar) authorization() (string, error) {
+	token, err := g.tokens.Token()
+	if err != nil {
+		return "", fmt.Errorf("failed to get a Google access token: %w", err)
+	}
+	return "Bearer " + token.AccessToken, nil
+}
+
+func (g *googleCalendar) eventsURL() string {
+	return googleCalendarAPIURL + "/calendars/" + url.PathEscape(g.calendarID) + "/events"
+}
+
+func (g *googleCalendar) listEvents(ctx context.Context, start time.Time, end time.Time, query string) ([]calendarEvent, error) {
+	authorization, err := g.authorization()
+	if err != nil {
+		return nil, err
+	}
+	params := url.Values{
+		"timeMin":      {start.Format(time.RFC3339)},
+		"timeMax":      {end.Format(time.RFC3339)},
+		"singleEvents": {"true"},
+		"orderBy":      {"startTime"},
+		"maxResults":   {"250"},
+	}
+	if query != "" {
+		params.Set("q", query)
+	}
+	var events []calendarEvent
+	for len(events) < maxCalendarEvents {
+		var resp struct {
+			Items         []googleEvent `json:"items"`
+			NextPageToken string        `json:"nextPageToken"`
+		}
+		if err := requestJSON(ctx, http.MethodGet, g.eventsURL()+"?"+params.Encode(), authorization, nil, &resp); err != nil {
+			return nil, fmt.Errorf("failed to list events: %w", err)
+		}
+		for _, item := range resp.Items {
+			event, err := item.event()
+			if err != nil {
+				return nil, err
+			}
+			events = append(events, event)
+		}
+		if resp.NextPageToken == "" {
+			break
+		}
+		params.Set("pageToken", resp.NextPageToken)
+	}
+	return events, nil
+}
+
+func (g *googleCalendar) createEvent(ctx context.Context, event calendarEvent) (calendarEvent, error) {
+	authorization, err := g.authorization()
+	if err != nil {
+		return calendarEvent{}, err
+	}
+	body := googleEvent{
+		Summary:     event.Title,
+		Description: event.Description,
+		Location:    event.Location,
+	}
+	if event.AllDay {
+		body.Start.Date = event.Start.Format(time.DateOnly)
+		body.End.Date = event.End.Format(time.DateOnly)
+	} else {
+		body.Start.DateTime = event.Start.Format(time.RFC3339)
+		body.End.DateTime = event.End.Format(time.RFC3339)
+	}
+	for _, attendee := range event.Attendees {
+		body.Attendees = append(body.Attendees, struct {
+			Email string `json:"email"`
+		}{Email: attendee})
+	}
+	var created googleEvent
+	if err := postJSON(ctx, g.eventsURL(), authorization, body, &created); err != nil {
+		return calendarEvent{}, fmt.Errorf("failed to create event: %w", err)
+	}
+	return created.event()
+}
+
+func (e googleEvent) event() (calendarEvent, error) {
+	event := calendarEvent{
+		ID:          e.ID,
+		Title:       e.Summary,
+		Description: e.Description,
+		Location:    e.Location,
+		URL:         e.HTMLLink,
+		AllDay:      e.Start.Date != "",
+	}
+	for _, attendee := range e.Attendees {
+		event.Attendees = append(event.Attendees, attendee.Email)
+	}
+	var err error
+	if event.AllDay {
+		event.Start, err = time.Parse(time.DateOnly, e.Start.Date)
+		if err == nil {
+			event.End, err = time.Parse(time.DateOnly, e.End.Date)
+		}
+	} else {
+		event.Start, err = time.Parse(time.RFC3339, e.Start.DateTime)
+		if err == nil {
+			event.End, err = time.Parse(time.RFC3339, e.End.DateTime)
+		}
+	}
+	if err != nil {
+		return calendarEvent{}, fmt.Errorf("invalid time in event %s: %w", e.ID, err)
+	}
+	return event, nil
+}
+
+// caldavCalendar reads and writes iCalendar events in a CalDAV collection
+type caldavCalendar struct {
+	url      string
+	username string
+	password string
+}
+
+const caldavTimeLayout = "20060102T150405Z"
+
+func (c *caldavCalendar) request(ctx context.Context, method string, target string, contentType string, body []byte, headers map[string]string) (*http.Response, []byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, target, bytes.NewReader(body))
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	if c.username != "" {
+		req.SetBasicAuth(c.username, c.password)
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 16<<20))
+	if resp.StatusCode/100 != 2 {
+		return nil, nil, fmt.Errorf("status code %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody[:min(len(respBody), 1024)])))
+	}
+	return resp, respBody, nil
+}
+
+type caldavMultistatus struct {
+	Responses []struct {
+		Href     string `xml:"href"`
+		Propstat []struct {
+			Prop struct {
+				CalendarData string `xml:"calendar-data"`
+			} `xml:"prop"`
+		} `xml:"propstat"`
+	} `xml:"response"`
+}
+
+func (c *caldavCalendar) listEvents(ctx context.Context, start time.Time, end time.Time, query string) ([]calendarEvent, error) {
+	timeRange := fmt.Sprintf(`start="%s" end="%s"`, start.UTC().Format(caldavTimeLayout), end.UTC().Format(caldavTimeLayout))
+	// expand asks the server to return each occurrence of recurring events
+	report := `<?xml version="1.0" encoding="utf-8"?>
+<C:calendar-query xmlns:D="DAV:" xmlns:C="urn:ietf:params:xml:ns:caldav">
+  <D:prop>
+    <C:calendar-data><C:expand ` + timeRange + `/></C:calendar-data>
+  </D:prop>
+  <C:filter>
+    <C:comp-filter name="VCALENDAR">
+      <C:comp-filter name="VEVENT">
+        <C:time-range ` + timeRange + `/>
+      </C:comp-filter>
+    </C:comp-filter>
+  </C:filter>
+</C:calendar-query>`
+	_, body, err := c.request(ctx, "REPORT", c.url, "application/xml; charset=utf-8", []byte(report), map[string]string{"Depth": "1"})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list events: %w", err)
+	}
+	var multistatus caldavMultistatus
+	if err := xml.Unmarshal(body, &multistatus); err != nil {
+		return nil, fmt.Errorf("failed to decode CalDAV response: %w", err)
+	}
+	var events []calendarEvent
+	for _, response := range multistatus.Responses {
+		for _, propstat := range response.Propstat {
+			if propstat.Prop.CalendarData == "" {
+				continue
+			}
+			for _, event := range parseICalendar(propstat.Prop.CalendarData) {
+				if query != "" && !event.matches(query) {
+					continue
+				}
+				event.URL = c.resolve(response.Href)
+				events = append(events, event)
+				if len(events) >= maxCalendarEvents {
+					return events, nil
+				}
+			}
+		}
+	}
+	return events, nil
+}
+
+func (c *caldavCalendar) createEvent(ctx context.Context, event calendarEvent) (calendarEvent, error) {
+	uid := make([]byte, 16)
+	if _, err := rand.Read(uid); err != nil {
+		return calendarEvent{}, err
+	}
+	event.ID = hex.EncodeToString(uid)
+	target := c.url + event.ID + ".ics"
+	// If-None-Match prevents overwriting an existing event
+	_, _, err := c.request(ctx, http.MethodPut, target, "text/calendar; charset=utf-8", []byte(formatICalendar(event, time.Now())), map[string]string{"If-None-Match": "*"})
+	if err != nil {
+		return calendarEvent{}, fmt.Errorf("failed to create event: %w", err)
+	}
+	event.URL = target
+	return event, nil
+}
+
+func (c *caldavCalendar) resolve(href string) string {
+	base, err := url.Parse(c.url)
+	if err != nil {
+		return href
+	}
+	ref, err := url.Parse(href)
+	if err != nil {
+		return href
+	}
+	return base.ResolveReference(ref).String()
+}
+
+// parseICalendar returns the VEVENTs of an iCalendar object. Only the properties the
+// tools return are read.
+func parseICalendar(data string) []calendarEvent {
+	var events []calendarEvent
+	var event *calendarEvent
+	for _, line := range unfoldICalendar(data) {
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		name, params, _ := strings.Cut(name, ";")
+		switch strings.ToUpper(name) {
+		case "BEGIN":
+			if value == "VEVENT" {
+				event = &calendarEvent{}
+			}
+		case "END":
+			if value == "VEVENT" && event != nil {
+				if event.End.IsZero() {
+					event.End = event.Start
+					if event.AllDay {
+						event.End = event.Start.AddDate(0, 0, 1)
+					}
+				}
+				events = append(events, *event)
+				event = nil
+			}
+		}
+		if event == nil {
+			continue
+		}
+		switch strings.ToUpper(name) {
+		case "UID":
+			event.ID = value
+		case "SUMMARY":
+			event.Title = unescapeICalendar(value)
+		case "DESCRIPTION":
+			event.Description = unescapeICalendar(value)
+		case "LOCATION":
+			event.Location = unescapeICalendar(value)
+		case "ATTENDEE":
+			if email, ok := strings.CutPrefix(strings.ToLower(value), "mailto:"); ok {
+				event.Attendees = append(event.Attendees, email)
+			}
+		case "DTSTART":
+			event.Start, event.AllDay = parseICalendarTime(value, params)
+		case "DTEND":
+			event.End, _ = parseICalendarTime(value, params)
+		}
+	}
+	return events
+}
+
+// unfoldICalendar joins continuation lines, which start with a space or tab
+func unfoldICalendar(data string) []string {
+	var lines []string
+	scanner := bufio.NewScanner(strings.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// parseICalendarTime parses UTC, floating, TZID and date values
+func parseICalendarTime(value string, params string) (time.Time, bool) {
+	loc := time.Local
+	for _, param := range strings.Split(params, ";") {
+		key, paramValue, _ := strings.Cut(param, "=")
+		switch strings.ToUpper(key) {
+		case "VALUE":
+			if strings.EqualFold(paramValue, "DATE") {
+				t, _ := time.Parse("20060102", value)
+				return t, true
+			}
+		case "TZID":
+			if tz, err := time.LoadLocation(strings.Trim(paramValue, `"`)); err == nil {
+				loc = tz
+			}
+		}
+	}
+	if len(value) == len("20060102") {
+		t, _ := time.Parse("20060102", value)
+		return t, true
+	}
+	if strings.HasSuffix(value, "Z") {
+		t, _ := time.Parse(caldavTimeLayout, value)
+		return t, false
+	}
+	t, _ := time.ParseInLocation("20060102T150405", value, loc)
+	return t, false
+}
+
+var icalendarUnescaper = strings.NewReplacer(`\n`, "\n", `\N`, "\n", `\,`, ",", `\;`, ";", `\\`, `\`)
+
+var icalendarEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, ",", `\,`, ";", `\;`, "\r", "")
+
+func unescapeICalendar(value string) string {
+	return icalendarUnescaper.Replace(value)
+}
+
+// formatICalendar writes an event as an iCalendar object
+func formatICalendar(event calendarEvent, now time.Time) string {
+	var sb strings.Builder
+	write := func(line string) {
+		// lines are folded at 75 bytes without splitting UTF-8 sequences
+		for len(line) > 75 {
+			cut := 75
+			for cut > 0 && line[cut]&0xC0 == 0x80 {
+				cut--
+			}
+			sb.WriteString(line[:cut] + "\r\n")
+			line = " " + line[cut:]
+		}
+		sb.WriteString(line + "\r\n")
+	}
+	write("BEGIN:VCALENDAR")
+	write("VERSION:2.0")
+	write("PRODID:-//jbutlerdev//genai//EN")
+	write("BEGIN:VEVENT")
+	write("UID:" + event.ID)
+	write("DTSTAMP:" + now.UTC().Format(caldavTimeLayout))
+	if event.AllDay {
+		write("DTSTART;VALUE=DATE:" + event.Start.Format("20060102"))
+		write("DTEND;VALUE=DATE:" + event.End.Format("20060102"))
+	} else {
+		write("DTSTART:" + event.Start.UTC().Format(caldavTimeLayout))
+		write("DTEND:" + event.End.UTC().Format(caldavTimeLayout))
+	}
+	write("SUMMARY:" + icalendarEscaper.Replace(event.Title))
+	if event.Description != "" {
+		write("DESCRIPTION:" + icalendarEscaper.Replace(event.Description))
+	}
+	if event.Location != "" {
+		write("LOCATION:" + icalendarEscaper.Replace(event.Location))
+	}
+	for _, attendee := range event.Attendees {
+		write("ATTENDEE;RSVP=TRUE:mailto:" + attendee)
+	}
+	write("END:VEVENT")
+	write("END:VCALENDAR")
+	return sb.String()
+}
@@ -0,0 +1,244 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// useCalendar configures the calendar tools for a test and points the Google URLs at srv
+func useCalendar(t *testing.T, config CalendarConfig, srv *httptest.Server) {
+	apiURL, tokenURL := googleCalendarAPIURL, googleTokenURL
+	googleCalendarAPIURL, googleTokenURL = srv.URL+"/calendar/v3", srv.URL+"/token"
+	ConfigureCalendar(config)
+	t.Cleanup(func() {
+		googleCalendarAPIURL, googleTokenURL = apiURL, tokenURL
+		calendarConfigMu.Lock()
+		defer calendarConfigMu.Unlock()
+		calendarConfig = nil
+		calendarBackends = map[string]calendarClient{}
+	})
+}
+
+func TestGoogleCalendar(t *testing.T) {
+	var mu sync.Mutex
+	var tokens int
+	var queries []string
+	var created map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/token" {
+			r.ParseForm()
+			if r.Form.Get("grant_type") != "refresh_token" || r.Form.Get("refresh_token") != "refresh" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			tokens++
+			fmt.Fprint(w, `{"access_token":"access","token_type":"Bearer","expires_in":3600}`)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer access" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path != "/calendar/v3/calendars/team@example.com/events" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.Method == http.MethodPost {
+			json.NewDecoder(r.Body).Decode(&created)
+			created["id"] = "evt-3"
+			json.NewEncoder(w).Encode(created)
+			return
+		}
+		queries = append(queries, r.URL.RawQuery)
+		if r.URL.Query().Get("pageToken") == "" {
+			fmt.Fprint(w, `{"items":[{"id":"evt-2","summary":"Standup","start":{"dateTime":"2024-01-15T09:30:00Z"},"end":{"dateTime":"2024-01-15T09:45:00Z"},
+				"attendees":[{"email":"ana@example.com"}]}],"nextPageToken":"p2"}`)
+			return
+		}
+		fmt.Fprint(w, `{"items":[{"id":"evt-1","summary":"Offsite","location":"Lisbon","start":{"date":"2024-01-15"},"end":{"date":"2024-01-17"}}]}`)
+	}))
+	defer srv.Close()
+	useCalendar(t, CalendarConfig{GoogleCalendarID: "team@example.com", GoogleClientID: "client", GoogleClientSecret: "secret", GoogleRefreshToken: "refresh"}, srv)
+
+	result, err := ListCalendarEvents(map[string]any{"start": "2024-01-15", "end": "2024-01-16", "timezone": "UTC"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	events := result["events"].([]map[string]any)
+	if len(events) != 2 || result["total"] != 2 {
+		t.Fatalf("events %v", events)
+	}
+	// events are sorted by start, all day events end on their last day
+	if events[0]["title"] != "Offsite" || events[0]["all_day"] != true || events[0]["end"] != "2024-01-16" {
+		t.Errorf("all day event %v", events[0])
+	}
+	if events[1]["start"] != "2024-01-15T09:30:00Z" || events[1]["attendees"].([]string)[0] != "ana@example.com" {
+		t.Errorf("event %v", events[1])
+	}
+	if len(queries) != 2 || !strings.Contains(queries[0], "timeMin=2024-01-15T00%3A00%3A00Z") || !strings.Contains(queries[0], "singleEvents=true") || !strings.Contains(queries[1], "pageToken=p2") {
+		t.Errorf("queries %v", queries)
+	}
+
+	result, err = CreateCalendarEvent(map[string]any{"title": "Planning", "start": "2024-01-18", "end": "2024-01-19", "attendees": []any{"ana@example.com"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if start := created["start"].(map[string]any); start["date"] != "2024-01-18" {
+		t.Errorf("created start %v", start)
+	}
+	// the end date of an all day event is exclusive in the API
+	if end := created["end"].(map[string]any); end["date"] != "2024-01-20" {
+		t.Errorf("created end %v", end)
+	}
+	if event := result["event"].(map[string]any); event["id"] != "evt-3" || event["end"] != "2024-01-19" {
+		t.Errorf("event %v", event)
+	}
+	if tokens != 1 {
+		t.Errorf("refreshed the token %d times, want it reused", tokens)
+	}
+
+	if _, err := CreateCalendarEvent(map[string]any{"title": "Backwards", "start": "2024-01-18T10:00:00Z", "end": "2024-01-18T09:00:00Z"}); err == nil {
+		t.Error("created an event that ends before it starts")
+	}
+}
+
+func TestCalDAVCalendar(t *testing.T) {
+	if _, err := time.LoadLocation("Europe/Lisbon"); err != nil {
+		t.Skipf("time zone data is not available: %v", err)
+	}
+	var put string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, password, _ := r.BasicAuth(); user != "ana" || password != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		switch r.Method {
+		case "REPORT":
+			if r.Header.Get("Depth") != "1" || !strings.Contains(string(body), `<C:time-range start="20240115T000000Z" end="20240122T000000Z"/>`) {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			w.WriteHeader(http.StatusMultiStatus)
+			fmt.Fprint(w, `<?xml version="1.0" encoding="utf-8"?>
+<D:multistatus xmlns:D="DAV:" xmlns:C="urn:ietf:params:xml:ns:caldav">
+  <D:response>
+    <D:href>/calendars/ana/personal/review.ics</D:href>
+    <D:propstat><D:prop><C:calendar-data>BEGIN:VCALENDAR&#13;
+BEGIN:VEVENT&#13;
+UID:review&#13;
+SUMMARY:Design review\, round 2&#13;
+DESCRIPTION:Bring the mockups\nand notes&#13;
+DTSTART;TZID=Europe/Lisbon:20240116T140000&#13;
+DTEND;TZID=Europe/Lisbon:20240116T150000&#13;
+ATTENDEE;CN=Ben:mailto:Ben@Example.com&#13;
+LOCATION:Room 4,&#13;
+  2nd floor&#13;
+END:VEVENT&#13;
+END:VCALENDAR&#13;
+</C:calendar-data></D:prop></D:propstat>
+  </D:response>
+  <D:response>
+    <D:href>/calendars/ana/personal/lunch.ics</D:href>
+    <D:propstat><D:prop><C:calendar-data>BEGIN:VCALENDAR
+BEGIN:VEVENT
+UID:lunch
+SUMMARY:Lunch
+DTSTART:20240115T120000Z
+DTEND:20240115T130000Z
+END:VEVENT
+END:VCALENDAR
+</C:calendar-data></D:prop></D:propstat>
+  </D:response>
+</D:multistatus>`)
+		case http.MethodPut:
+			if r.Header.Get("If-None-Match") != "*" || r.Header.Get("Content-Type") != "text/calendar; charset=utf-8" {
+				w.WriteHeader(http.StatusPreconditionFailed)
+				return
+			}
+			put = string(body)
+			w.WriteHeader(http.StatusCreated)
+		}
+	}))
+	defer srv.Close()
+	useCalendar(t, CalendarConfig{CalDAVURL: srv.URL + "/calendars/ana/personal", CalDAVUsername: "ana", CalDAVPassword: "secret"}, srv)
+
+	result, err := ListCalendarEvents(map[string]any{"start": "2024-01-15", "timezone": "Europe/Lisbon"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	events := result["events"].([]map[string]any)
+	if len(events) != 2 || events[0]["title"] != "Lunch" {
+		t.Fatalf("events %v", events)
+	}
+	review := events[1]
+	if review["title"] != "Design review, round 2" || review["description"] != "Bring the mockups\nand notes" || review["location"] != "Room 4, 2nd floor" {
+		t.Errorf("review %v", review)
+	}
+	if review["start"] != "2024-01-16T14:00:00Z" || review["url"] != srv.URL+"/calendars/ana/personal/review.ics" {
+		t.Errorf("review %v", review)
+	}
+	if attendees := review["attendees"].([]string); len(attendees) != 1 || attendees[0] != "ben@example.com" {
+		t.Errorf("attendees %v", attendees)
+	}
+	result, err = ListCalendarEvents(map[string]any{"start": "2024-01-15", "query": "MOCKUPS"})
+	if err != nil || result["total"] != 1 {
+		t.Errorf("query matched %v, %v", result["total"], err)
+	}
+
+	result, err = CreateCalendarEvent(map[string]any{"title": "1:1; notes", "start": "2024-01-17T10:00:00+01:00"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	event := result["event"].(map[string]any)
+	if !strings.HasPrefix(event["url"].(string), srv.URL+"/calendars/ana/personal/") || !strings.HasSuffix(event["url"].(string), ".ics") {
+		t.Errorf("event url %v", event["url"])
+	}
+	for _, want := range []string{"SUMMARY:1:1\\; notes\r\n", "DTSTART:20240117T090000Z\r\n", "DTEND:20240117T100000Z\r\n", "UID:" + event["id"].(string) + "\r\n"} {
+		if !strings.Contains(put, want) {
+			t.Errorf("event is missing %q:\n%s", want, put)
+		}
+	}
+}
+
+func TestFormatICalendar(t *testing.T) {
+	event := calendarEvent{
+		ID:          "abc",
+		Title:       "Quarterly planning",
+		Description: strings.Repeat("é", 60) + "\nagenda, goals; owners",
+		Start:       time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+		End:         time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC),
+		AllDay:      true,
+		Attendees:   []string{"ana@example.com"},
+	}
+	data := formatICalendar(event, time.Date(2024, 2, 1, 8, 0, 0, 0, time.UTC))
+	for _, line := range strings.Split(data, "\r\n") {
+		if len(line) > 75 {
+			t.Errorf("line of %d bytes: %q", len(line), line)
+		}
+	}
+	for _, want := range []string{"DTSTART;VALUE=DATE:20240301\r\n", "DTEND;VALUE=DATE:20240302\r\n", "DTSTAMP:20240201T080000Z\r\n"} {
+		if !strings.Contains(data, want) {
+			t.Errorf("missing %q:\n%s", want, data)
+		}
+	}
+	parsed := parseICalendar(data)
+	if len(parsed) != 1 {
+		t.Fatalf("parsed %+v", parsed)
+	}
+	got := parsed[0]
+	if got.ID != "abc" || got.Title != event.Title || got.Description != event.Description || !got.AllDay ||
+		!got.Start.Equal(event.Start) || !got.End.Equal(event.End) || len(got.Attendees) != 1 {
+		t.Errorf("round trip %+v, want %+v", got, event)
+	}
+}
@@ -0,0 +1,441 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	defaultCommandTimeout = 30 * time.Second
+	// commandOutputLimit is the number of bytes of stdout and stderr kept
+	commandOutputLimit = 64 << 10
+	// commandMaxMatches bounds the records parsed from the output with a regex
+	commandMaxMatches = 500
+)
+
+// CommandToolConfig wraps a CLI command as a tool. Commands are run directly, without a
+// shell, in the conversation's base path with only PATH, HOME and the variables listed
+// in Env set, taken from the conversation's environment when it sets them.
+//
+//	tools:
+//	  - name: go_vet
+//	    description: Report suspicious constructs in Go packages
+//	    command: go
+//	    args: [vet, "{{packages}}"]
+//	    parameters:
+//	      - {name: packages, type: stringArray, description: Package patterns, required: true}
+//	    output: {format: regex, regex: '^(?P<file>[^:]+):(?P<line>\d+):\d+: (?P<message>.*)$'}
+type CommandToolConfig struct {
+	Name        string `yaml:"name"`
+	Description string `yaml:"description"`
+	Command     string `yaml:"command"`
+	// Args are passed to the command, {{name}} is replaced by the value of a parameter.
+	// An arg that is only a placeholder is left out when the parameter is not set and
+	// expands to one arg per value for stringArray parameters.
+	Args       []string           `yaml:"args"`
+	Parameters []CommandParameter `yaml:"parameters"`
+	// Env lists the environment variables passed to the command
+	Env            []string      `yaml:"env"`
+	TimeoutSeconds int           `yaml:"timeoutSeconds"`
+	Output         CommandOutput `yaml:"output"`
+	// ExitCodes are the exit codes that mean success, 0 when empty. Tools such as grep
+	// exit with 1 when nothing matched.
+	ExitCodes []int `yaml:"exitCodes"`
+}
+
+// CommandParameter is a typed parameter of a command tool
+type CommandParameter struct {
+	Name        string `yaml:"name"`
+	Type        string `yaml:"type"`
+	Description string `yaml:"description"`
+	Required    bool   `yaml:"required"`
+	// Flag passes the parameter as an option after Args, e.g. "-n" or "--limit=".
+	// Boolean parameters add the flag when true. Flags ending with = are joined with
+	// the value.
+	Flag string `yaml:"flag"`
+	// Pattern is a regular expression string values must match
+	Pattern string `yaml:"pattern"`
+	// Enum lists the allowed values
+	Enum []string `yaml:"enum"`
+	// Path values are resolved in the base path and may not leave it
+	Path bool `yaml:"path"`
+	// AllowDash allows values starting with -, which are rejected by default so a value
+	// cannot be read as an option
+	AllowDash bool `yaml:"allowDash"`
+
+	pattern *regexp.Regexp
+}
+
+// CommandOutput describes how the output of a command is returned
+type CommandOutput struct {
+	// Format is text, json, jsonLines or regex, text by default
+	Format string `yaml:"format"`
+	// Regex extracts a record with its named groups from each matching line
+	Regex string `yaml:"regex"`
+}
+
+var placeholderPattern = regexp.MustCompile(`\{\{\s*([a-zA-Z0-9_]+)\s*\}\}`)
+
+// RegisterCommandTools registers the command tools of a YAML file and returns their names
+func RegisterCommandTools(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read command tools: %w", err)
+	}
+	tools, err := CommandTools(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	names := make([]string, 0, len(tools))
+	for _, tool := range tools {
+		RegisterTool(tool)
+		names = append(names, tool.Name)
+	}
+	return names, nil
+}
+
+// CommandTools builds tools from a YAML document with a list of CommandToolConfig
+// under tools
+func CommandTools(data []byte) ([]Tool, error) {
+	var file struct {
+		Tools []CommandToolConfig `yaml:"tools"`
+	}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse command tools: %w", err)
+	}
+	tools := make([]Tool, 0, len(file.Tools))
+	for _, config := range file.Tools {
+		tool, err := NewCommandTool(config)
+		if err != nil {
+			return nil, err
+		}
+		tools = append(tools, tool)
+	}
+	return tools, nil
+}
+
+// NewCommandTool validates a command tool configuration and returns the tool
+func NewCommandTool(config CommandToolConfig) (Tool, error) {
+	if config.Name == "" || config.Command == "" {
+		return Tool{}, errors.New("command tools need a name and a command")
+	}
+	var output *regexp.Regexp
+	switch config.Output.Format {
+	case "", "text", "json", "jsonLines":
+	case "regex":
+		var err error
+		if output, err = regexp.Compile(config.Output.Regex); err != nil {
+			return Tool{}, fmt.Errorf("tool %s: invalid output regex: %w", config.Name, err)
+		}
+	default:
+		return Tool{}, fmt.Errorf("tool %s: unknown output format %s", config.Name, config.Output.Format)
+	}
+	// the parameters are completed with their defaults and compiled patterns
+	config.Parameters = slices.Clone(config.Parameters)
+	known := make(map[string]bool)
+	parameters := make([]Parameter, 0, len(config.Parameters))
+	for i := range config.Parameters {
+		param := &config.Parameters[i]
+		switch param.Type {
+		case "":
+			param.Type = "string"
+		case "string", "stringArray", "integer", "number", "boolean":
+		default:
+			return Tool{}, fmt.Errorf("tool %s: parameter %s has unsupported type %s", config.Name, param.Name, param.Type)
+		}
+		if param.Pattern != "" {
+			pattern, err := regexp.Compile("^(?:" + param.Pattern + ")$")
+			if err != nil {
+				return Tool{}, fmt.Errorf("tool %s: parameter %s: invalid pattern: %w", config.Name, param.Name, err)
+			}
+			param.pattern = pattern
+		}
+		description := param.Description
+		if len(param.Enum) > 0 {
+			description = strings.TrimSpace(fmt.Sprintf("%s One of %s.", description, strings.Join(param.Enum, ", ")))
+		}
+		known[param.Name] = true
+		parameters = append(parameters, Parameter{Name: param.Name, Type: param.Type, Description: description, Required: param.Required})
+	}
+	for _, arg := range config.Args {
+		for _, match := range placeholderPattern.FindAllStringSubmatch(arg, -1) {
+			if !known[match[1]] {
+				return Tool{}, fmt.Errorf("tool %s: args use undefined parameter %s", config.Name, match[1])
+			}
+		}
+	}
+	timeout := defaultCommandTimeout
+	if config.TimeoutSeconds > 0 {
+		timeout = time.Duration(config.TimeoutSeconds) * time.Second
+	}
+	description := config.Description
+	if description == "" {
+		description = "Run " + config.Command
+	}
+	return Tool{
+		Name:        config.Name,
+		Description: description,
+		Parameters:  parameters,
+		Options:     map[string]string{BasePathArg: "."},
+		Run: func(args map[string]any) (map[string]any, error) {
+			return runCommandTool(config, output, timeout, args)
+		},
+	}, nil
+}
+
+func runCommandTool(config CommandToolConfig, output *regexp.Regexp, timeout time.Duration, args map[string]any) (map[string]any, error) {
+	basePath, _ := args[BasePathArg].(string)
+	values := make(map[string][]string)
+	var flags []string
+	for _, param := range config.Parameters {
+		value, ok := args[param.Name]
+		if !ok || value == nil || value == "" {
+			if param.Required {
+				err := NewToolError(fmt.Errorf("%s is required", param.Name), "", true)
+				return map[string]any{"success": false, "error": err.Error()}, err
+			}
+			continue
+		}
+		strs, err := commandValues(param, value, basePath)
+		if err != nil {
+			err = NewToolError(err, "Check the argument against the parameter's description.", true)
+			return map[string]any{"success": false, "error": err.Error()}, err
+		}
+		if param.Flag != "" {
+			if param.Type == "boolean" {
+				if strs[0] == "true" {
+					flags = append(flags, param.Flag)
+				}
+				continue
+			}
+			for _, s := range strs {
+				if strings.HasSuffix(param.Flag, "=") {
+					flags = append(flags, param.Flag+s)
+				} else {
+					flags = append(flags, param.Flag, s)
+				}
+			}
+			continue
+		}
+		values[param.Name] = strs
+	}
+
+	var argv []string
+	for _, arg := range config.Args {
+		if match := placeholderPattern.FindStringSubmatch(arg); match != nil && match[0] == arg {
+			argv = append(argv, values[match[1]]...)
+			continue
+		}
+		argv = append(argv, placeholderPattern.ReplaceAllStringFunc(arg, func(placeholder string) string {
+			name := placeholderPattern.FindStringSubmatch(placeholder)[1]
+			return strings.Join(values[name], ",")
+		}))
+	}
+	argv = append(argv, flags...)
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, config.Command, argv...)
+	cmd.Dir = basePath
+	conversationEnv, _ := args[EnvArg].(map[string]string)
+	cmd.Env = commandEnv(config.Env, conversationEnv)
+	stdout := &limitedBuffer{limit: commandOutputLimit}
+	stderr := &limitedBuffer{limit: commandOutputLimit}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	err := cmd.Run()
+	exitCode := 0
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		exitCode = exitErr.ExitCode()
+		err = nil
+	}
+	if ctx.Err() != nil {
+		err = fmt.Errorf("%s timed out after %s", config.Name, timeout)
+	}
+	if err != nil {
+		return map[string]any{"success": false, "error": err.Error()}, err
+	}
+	okCodes := config.ExitCodes
+	if len(okCodes) == 0 {
+		okCodes = []int{0}
+	}
+	result := map[string]any{"success": true, "exitCode": exitCode}
+	if stdout.truncated || stderr.truncated {
+		result["truncated"] = true
+	}
+	if !slices.Contains(okCodes, exitCode) {
+		message := strings.TrimSpace(stderr.String())
+		if message == "" {
+			message = strings.TrimSpace(stdout.String())
+		}
+		err := NewToolError(fmt.Errorf("%s exited with code %d: %s", config.Command, exitCode, message), "", true)
+		result["success"] = false
+		result["error"] = err.Error()
+		return result, err
+	}
+	if parsed, err := parseCommandOutput(config.Output.Format, output, stdout.Bytes()); err != nil {
+		result["output"] = stdout.String()
+		result["parseError"] = err.Error()
+	} else {
+		result["output"] = parsed
+	}
+	if message := strings.TrimSpace(stderr.String()); message != "" {
+		result["stderr"] = message
+	}
+	return result, nil
+}
+
+// commandValues validates an argument and returns it as command line strings
+func commandValues(param CommandParameter, value any, basePath string) ([]string, error) {
+	var strs []string
+	switch param.Type {
+	case "stringArray":
+		switch v := value.(type) {
+		case []any:
+			for _, item := range v {
+				strs = append(strs, fmt.Sprint(item))
+			}
+		case []string:
+			strs = v
+		case string:
+			strs = []string{v}
+		default:
+			return nil, fmt.Errorf("%s must be a list of strings", param.Name)
+		}
+	case "integer":
+		n, ok := value.(float64)
+		if i, isInt := value.(int); isInt {
+			n, ok = float64(i), true
+		}
+		if !ok || n != float64(int64(n)) {
+			return nil, fmt.Errorf("%s must be an integer", param.Name)
+		}
+		strs = []string{strconv.FormatInt(int64(n), 10)}
+	case "number":
+		n, ok := value.(float64)
+		if !ok {
+			return nil, fmt.Errorf("%s must be a number", param.Name)
+		}
+		strs = []string{strconv.FormatFloat(n, 'f', -1, 64)}
+	case "boolean":
+		b, ok := value.(bool)
+		if !ok {
+			return nil, fmt.Errorf("%s must be true or false", param.Name)
+		}
+		return []string{strconv.FormatBool(b)}, nil
+	default:
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s must be a string", param.Name)
+		}
+		strs = []string{s}
+	}
+	for i, s := range strs {
+		if len(param.Enum) > 0 && !slices.Contains(param.Enum, s) {
+			return nil, fmt.Errorf("%s must be one of %s", param.Name, strings.Join(param.Enum, ", "))
+		}
+		if param.pattern != nil && !param.pattern.MatchString(s) {
+			return nil, fmt.Errorf("%s does not match %s", param.Name, param.Pattern)
+		}
+		if strings.HasPrefix(s, "-") && !param.AllowDash && param.Type != "integer" && param.Type != "number" {
+			return nil, fmt.Errorf("%s may not start with -", param.Name)
+		}
+		if param.Path {
+			path, err := sandboxPath(basePath, s)
+			if err != nil {
+				return nil, err
+			}
+			strs[i] = path
+		}
+	}
+	return strs, nil
+}
+
+// commandEnv returns the environment of a command, PATH and HOME and the listed
+// variables, read from the conversation's environment before the process environment
+func commandEnv(names []string, conversationEnv map[string]string) []string {
+	env := make([]string, 0, len(names)+2)
+	for _, name := range append([]string{"PATH", "HOME"}, names...) {
+		if value, ok := conversationEnv[name]; ok {
+			env = append(env, name+"="+value)
+		} else if value, ok := os.LookupEnv(name); ok {
+			env = append(env, name+"="+value)
+		}
+	}
+	return env
+}
+
+func parseCommandOutput(format string, pattern *regexp.Regexp, out []byte) (any, error) {
+	switch format {
+	case "json":
+		var value any
+		if err := json.Unmarshal(out, &value); err != nil {
+			return nil, fmt.Errorf("output is not JSON: %w", err)
+		}
+		return value, nil
+	case "jsonLines":
+		var values []any
+		decoder := json.NewDecoder(bytes.NewReader(out))
+		for decoder.More() {
+			var value any
+			if err := decoder.Decode(&value); err != nil {
+				return nil, fmt.Errorf("output is not JSON lines: %w", err)
+			}
+			values = append(values, value)
+		}
+		return values, nil
+	case "regex":
+		records := []map[string]string{}
+		names := pattern.SubexpNames()
+		for _, line := range strings.Split(string(out), "\n") {
+			match := pattern.FindStringSubmatch(line)
+			if match == nil {
+				continue
+			}
+			record := make(map[string]string)
+			for i, name := range names {
+				if name != "" {
+					record[name] = match[i]
+				}
+			}
+			records = append(records, record)
+			if len(records) == commandMaxMatches {
+				break
+			}
+		}
+		return records, nil
+	}
+	return strings.TrimRight(string(out), "\n"), nil
+}
+
+// limitedBuffer keeps the first limit bytes written and reports whether more were dropped
+type limitedBuffer struct {
+	bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if room := b.limit - b.Len(); room < len(p) {
+		b.truncated = true
+		if room > 0 {
+			b.Buffer.Write(p[:room])
+		}
+		// the command keeps running, its output past the limit is dropped
+		return len(p), nil
+	}
+	return b.Buffer.Write(p)
+}
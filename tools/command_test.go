@@ -0,0 +1,179 @@
+package tools
+
+import (
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestCommandTools(t *testing.T) {
+	tools, err := CommandTools([]byte(`
+tools:
+  - name: list
+    command: printf
+    args: ["%s|", "{{items}}", "x{{mode}}"]
+    parameters:
+      - {name: items, type: stringArray, description: Items}
+      - {name: mode, enum: [fast, slow], description: Mode}
+      - {name: count, type: integer, flag: "--count="}
+      - {name: verbose, type: boolean, flag: -v}
+      - {name: file, path: true, flag: -f}
+  - name: env
+    command: sh
+    args: [-c, 'echo "$GENAI_TEST_ALLOWED-$GENAI_TEST_HIDDEN"']
+    env: [GENAI_TEST_ALLOWED]
+  - name: exit
+    command: sh
+    args: [-c, 'echo "no match" >&2; exit {{code}}']
+    parameters:
+      - {name: code, type: integer, required: true}
+    exitCodes: [0, 1]
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tools) != 3 || !strings.Contains(tools[0].Parameters[1].Description, "One of fast, slow") {
+		t.Fatalf("tools %+v", tools)
+	}
+	dir := t.TempDir()
+	run := func(tool Tool, args map[string]any) (map[string]any, error) {
+		args[BasePathArg] = dir
+		return tool.Run(args)
+	}
+
+	// a placeholder arg expands to one arg per value and is left out when not set,
+	// flags come after the args
+	result, err := run(tools[0], map[string]any{"items": []any{"a b", "c"}, "mode": "fast", "count": 3.0, "verbose": true, "file": "notes.txt"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "a b|c|xfast|--count=3|-v|-f|" + filepath.Join(dir, "notes.txt") + "|"
+	if result["output"] != want {
+		t.Errorf("output %q, want %q", result["output"], want)
+	}
+	result, err = run(tools[0], map[string]any{"verbose": false})
+	if err != nil || result["output"] != "x|" {
+		t.Errorf("output %q, %v", result["output"], err)
+	}
+	for _, args := range []map[string]any{
+		{"mode": "medium"},
+		{"items": []any{"--delete"}},
+		{"count": 1.5},
+		{"file": "../outside"},
+		{"verbose": "yes"},
+	} {
+		if _, err := run(tools[0], args); err == nil {
+			t.Errorf("ran with invalid arguments %v", args)
+		}
+	}
+
+	t.Setenv("GENAI_TEST_ALLOWED", "process")
+	t.Setenv("GENAI_TEST_HIDDEN", "secret")
+	result, err = run(tools[1], map[string]any{})
+	if err != nil || result["output"] != "process-" {
+		t.Errorf("environment %q, %v", result["output"], err)
+	}
+	// the conversation's environment wins over the process environment
+	result, err = run(tools[1], map[string]any{EnvArg: map[string]string{"GENAI_TEST_ALLOWED": "conversation", "GENAI_TEST_HIDDEN": "conversation"}})
+	if err != nil || result["output"] != "conversation-" {
+		t.Errorf("environment %q, %v", result["output"], err)
+	}
+
+	result, err = run(tools[2], map[string]any{"code": 1.0})
+	if err != nil || result["exitCode"] != 1 || result["stderr"] != "no match" {
+		t.Errorf("allowed exit code %v, %v", result, err)
+	}
+	result, err = run(tools[2], map[string]any{"code": 2.0})
+	if err == nil || result["success"] != false || !strings.Contains(err.Error(), "exited with code 2: no match") {
+		t.Errorf("failing exit code %v, %v", result, err)
+	}
+	if _, err := run(tools[2], map[string]any{}); err == nil || !strings.Contains(err.Error(), "code is required") {
+		t.Errorf("missing parameter error %v", err)
+	}
+}
+
+func TestNewCommandToolErrors(t *testing.T) {
+	tests := []struct {
+		config CommandToolConfig
+		want   string
+	}{
+		{CommandToolConfig{Name: "x"}, "need a name and a command"},
+		{CommandToolConfig{Name: "x", Command: "ls", Output: CommandOutput{Format: "xml"}}, "unknown output format"},
+		{CommandToolConfig{Name: "x", Command: "ls", Output: CommandOutput{Format: "regex", Regex: "("}}, "invalid output regex"},
+		{CommandToolConfig{Name: "x", Command: "ls", Parameters: []CommandParameter{{Name: "p", Type: "object"}}}, "unsupported type"},
+		{CommandToolConfig{Name: "x", Command: "ls", Parameters: []CommandParameter{{Name: "p", Pattern: "["}}}, "invalid pattern"},
+		{CommandToolConfig{Name: "x", Command: "ls", Args: []string{"{{missing}}"}}, "undefined parameter missing"},
+	}
+	for _, tt := range tests {
+		if _, err := NewCommandTool(tt.config); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("NewCommandTool(%+v) error %v, want %q", tt.config, err, tt.want)
+		}
+	}
+}
+
+func TestCommandValues(t *testing.T) {
+	pattern := CommandParameter{Name: "branch", Pattern: `[a-z0-9/-]+`, pattern: regexp.MustCompile(`^(?:[a-z0-9/-]+)$`)}
+	if got, err := commandValues(pattern, "feature/x-1", ""); err != nil || !reflect.DeepEqual(got, []string{"feature/x-1"}) {
+		t.Errorf("branch %v, %v", got, err)
+	}
+	// the pattern is anchored
+	if _, err := commandValues(pattern, "main; rm -rf /", ""); err == nil {
+		t.Error("accepted a value that only partly matches the pattern")
+	}
+	if got, err := commandValues(CommandParameter{Name: "offset", Type: "integer"}, -5.0, ""); err != nil || got[0] != "-5" {
+		t.Errorf("negative integer %v, %v", got, err)
+	}
+	if got, err := commandValues(CommandParameter{Name: "ratio", Type: "number"}, 0.25, ""); err != nil || got[0] != "0.25" {
+		t.Errorf("number %v, %v", got, err)
+	}
+	if _, err := commandValues(CommandParameter{Name: "range", AllowDash: true}, "-3..5", ""); err != nil {
+		t.Errorf("allowed dash %v", err)
+	}
+	if _, err := commandValues(CommandParameter{Name: "name"}, 5.0, ""); err == nil {
+		t.Error("accepted a number for a string")
+	}
+}
+
+func TestParseCommandOutput(t *testing.T) {
+	value, err := parseCommandOutput("json", nil, []byte(`{"ok":true}`))
+	if err != nil || !reflect.DeepEqual(value, map[string]any{"ok": true}) {
+		t.Errorf("json %v, %v", value, err)
+	}
+	if _, err := parseCommandOutput("json", nil, []byte("not json")); err == nil {
+		t.Error("parsed invalid JSON")
+	}
+	value, err = parseCommandOutput("jsonLines", nil, []byte("{\"n\":1}\n{\"n\":2}\n"))
+	if err != nil || !reflect.DeepEqual(value, []any{map[string]any{"n": 1.0}, map[string]any{"n": 2.0}}) {
+		t.Errorf("jsonLines %v, %v", value, err)
+	}
+	pattern := regexp.MustCompile(`^(?P<file>[^:]+):(?P<line>\d+): (?P<message>.*)$`)
+	value, err = parseCommandOutput("regex", pattern, []byte("# pkg\nmain.go:12: unreachable code\nutil.go:3: unused result\n"))
+	want := []map[string]string{
+		{"file": "main.go", "line": "12", "message": "unreachable code"},
+		{"file": "util.go", "line": "3", "message": "unused result"},
+	}
+	if err != nil || !reflect.DeepEqual(value, want) {
+		t.Errorf("regex %v, %v", value, err)
+	}
+	many := strings.Repeat("a.go:1: x\n", commandMaxMatches+5)
+	if value, _ := parseCommandOutput("regex", pattern, []byte(many)); len(value.([]map[string]string)) != commandMaxMatches {
+		t.Errorf("regex records %d, want %d", len(value.([]map[string]string)), commandMaxMatches)
+	}
+	if value, _ := parseCommandOutput("", nil, []byte("done\n\n")); value != "done" {
+		t.Errorf("text %q", value)
+	}
+}
+
+func TestLimitedBuffer(t *testing.T) {
+	buffer := &limitedBuffer{limit: 5}
+	for _, chunk := range []string{"abc", "defg", "h"} {
+		if n, err := buffer.Write([]byte(chunk)); n != len(chunk) || err != nil {
+			t.Fatalf("write %q: %d, %v", chunk, n, err)
+		}
+	}
+	if buffer.String() != "abcde" || !buffer.truncated {
+		t.Errorf("buffer %q, truncated %v", buffer.String(), buffer.truncated)
+	}
+}
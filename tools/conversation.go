@@ -0,0 +1,200 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// ConversationStore holds key/value state scoped to one conversation. Tools find the
+// conversation in the ConversationIDArg argument.
+type ConversationStore interface {
+	Get(ctx context.Context, conversationID string, key string) (string, bool, error)
+	Set(ctx context.Context, conversationID string, key string, value string) error
+	Delete(ctx context.Context, conversationID string, key string) error
+	// List returns the entries whose key starts with prefix
+	List(ctx context.Context, conversationID string, prefix string) (map[string]string, error)
+}
+
+var (
+	conversationStore   ConversationStore = NewInMemoryConversationStore()
+	conversationStoreMu sync.RWMutex
+)
+
+// SetConversationStore replaces the store used by conversation scoped tools, the
+// default keeps state in memory for the life of the process
+func SetConversationStore(store ConversationStore) {
+	conversationStoreMu.Lock()
+	defer conversationStoreMu.Unlock()
+	conversationStore = store
+}
+
+func getConversationStore() ConversationStore {
+	conversationStoreMu.RLock()
+	defer conversationStoreMu.RUnlock()
+	return conversationStore
+}
+
+// InMemoryConversationStore is a ConversationStore that does not survive restarts
+type InMemoryConversationStore struct {
+	mu            sync.Mutex
+	conversations map[string]map[string]string
+}
+
+func NewInMemoryConversationStore() *InMemoryConversationStore {
+	return &InMemoryConversationStore{conversations: make(map[string]map[string]string)}
+}
+
+func (s *InMemoryConversationStore) Get(ctx context.Context, conversationID string, key string) (string, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	value, ok := s.conversations[conversationID][key]
+	return value, ok, nil
+}
+
+func (s *InMemoryConversationStore) Set(ctx context.Context, conversationID string, key string, value string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conversations[conversationID] == nil {
+		s.conversations[conversationID] = make(map[string]string)
+	}
+	s.conversations[conversationID][key] = value
+	return nil
+}
+
+func (s *InMemoryConversationStore) Delete(ctx context.Context, conversationID string, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.conversations[conversationID], key)
+	return nil
+}
+
+func (s *InMemoryConversationStore) List(ctx context.Context, conversationID string, prefix string) (map[string]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return filterPrefix(s.conversations[conversationID], prefix), nil
+}
+
+// FileConversationStore keeps each conversation in a JSON file in a directory
+type FileConversationStore struct {
+	mu  sync.Mutex
+	dir string
+}
+
+// conversationIDPattern keeps conversation ids safe to use as file names
+var conversationIDPattern = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+func NewFileConversationStore(dir string) (*FileConversationStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create conversation store directory: %w", err)
+	}
+	return &FileConversationStore{dir: dir}, nil
+}
+
+func (s *FileConversationStore) path(conversationID string) (string, error) {
+	if !conversationIDPattern.MatchString(conversationID) || conversationID == "." || conversationID == ".." {
+		return "", fmt.Errorf("invalid conversation id: %q", conversationID)
+	}
+	return filepath.Join(s.dir, conversationID+".json"), nil
+}
+
+func (s *FileConversationStore) load(conversationID string) (map[string]string, error) {
+	path, err := s.path(conversationID)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return make(map[string]string), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read conversation %s: %w", conversationID, err)
+	}
+	entries := make(map[string]string)
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("failed to decode conversation %s: %w", conversationID, err)
+	}
+	return entries, nil
+}
+
+func (s *FileConversationStore) save(conversationID string, entries map[string]string) error {
+	path, err := s.path(conversationID)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode conversation %s: %w", conversationID, err)
+	}
+	// write to a temporary file first so a crash does not leave a truncated file
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write conversation %s: %w", conversationID, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write conversation %s: %w", conversationID, err)
+	}
+	return nil
+}
+
+func (s *FileConversationStore) Get(ctx context.Context, conversationID string, key string) (string, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entries, err := s.load(conversationID)
+	if err != nil {
+		return "", false, err
+	}
+	value, ok := entries[key]
+	return value, ok, nil
+}
+
+func (s *FileConversationStore) Set(ctx context.Context, conversationID string, key string, value string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entries, err := s.load(conversationID)
+	if err != nil {
+		return err
+	}
+	entries[key] = value
+	return s.save(conversationID, entries)
+}
+
+func (s *FileConversationStore) Delete(ctx context.Context, conversationID string, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entries, err := s.load(conversationID)
+	if err != nil {
+		return err
+	}
+	if _, ok := entries[key]; !ok {
+		return nil
+	}
+	delete(entries, key)
+	return s.save(conversationID, entries)
+}
+
+func (s *FileConversationStore) List(ctx context.Context, conversationID string, prefix string) (map[string]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entries, err := s.load(conversationID)
+	if err != nil {
+		return nil, err
+	}
+	return filterPrefix(entries, prefix), nil
+}
+
+func filterPrefix(entries map[string]string, prefix string) map[string]string {
+	filtered := make(map[string]string)
+	for key, value := range entries {
+		if strings.HasPrefix(key, prefix) {
+			filtered[key] = value
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,214 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CredentialsProvider supplies the secrets and endpoints tools read, such as
+// GITHUB_TOKEN or SEARXNG_URL. The scope identifies whose credentials are needed, it
+// is the conversation's credential scope, e.g. a user or tenant id, and empty for tools
+// called outside a scoped conversation.
+type CredentialsProvider interface {
+	// Credential returns the value of a credential and whether it exists
+	Credential(ctx context.Context, scope string, name string) (string, bool, error)
+}
+
+var credentialsProvider CredentialsProvider = EnvCredentials{}
+
+// SetCredentialsProvider sets the provider tools read credentials from, the environment
+// by default
+func SetCredentialsProvider(provider CredentialsProvider) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if provider == nil {
+		provider = EnvCredentials{}
+	}
+	credentialsProvider = provider
+}
+
+func getCredentialsProvider() CredentialsProvider {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	return credentialsProvider
+}
+
+// EnvCredentials reads credentials from environment variables. A scoped credential is
+// read from <SCOPE>_<NAME>, e.g. ACME_GITHUB_TOKEN, when Scoped is set.
+type EnvCredentials struct {
+	Scoped bool
+}
+
+func (e EnvCredentials) Credential(_ context.Context, scope string, name string) (string, bool, error) {
+	if e.Scoped && scope != "" {
+		name = envName(scope) + "_" + name
+	}
+	value, ok := os.LookupEnv(name)
+	return value, ok && value != "", nil
+}
+
+// envName upper cases a scope and replaces characters that are not valid in variable names
+func envName(scope string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		}
+		return '_'
+	}, scope)
+}
+
+// FileCredentials reads each credential from a file named after it, as Kubernetes and
+// Docker mount secrets. Scoped credentials are read from a directory named after the
+// scope, e.g. /run/secrets/acme/GITHUB_TOKEN.
+type FileCredentials struct {
+	Dir string
+}
+
+func (f FileCredentials) Credential(_ context.Context, scope string, name string) (string, bool, error) {
+	if strings.ContainsAny(name, `/\`) || strings.Contains(scope, "..") || strings.ContainsAny(scope, `/\`) {
+		return "", false, fmt.Errorf("invalid credential %s for scope %s", name, scope)
+	}
+	data, err := os.ReadFile(filepath.Join(f.Dir, scope, name))
+	if errors.Is(err, os.ErrNotExist) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("failed to read credential %s: %w", name, err)
+	}
+	value := strings.TrimSpace(string(data))
+	return value, value != "", nil
+}
+
+// VaultCredentials reads credentials from a HashiCorp Vault KV version 2 secret whose
+// keys are the credential names. The secret is Path, or Path/<scope> for scoped
+// credentials. Secrets are cached for CacheTTL, one minute by default.
+type VaultCredentials struct {
+	// Address of the Vault server, e.g. https://vault.example.com:8200
+	Address string
+	Token   string
+	// Mount of the KV engine, secret by default
+	Mount    string
+	Path     string
+	CacheTTL time.Duration
+
+	mu    sync.Mutex
+	cache map[string]vaultSecret
+}
+
+type vaultSecret struct {
+	data    map[string]any
+	fetched time.Time
+}
+
+func (v *VaultCredentials) Credential(ctx context.Context, scope string, name string) (string, bool, error) {
+	if strings.Contains(scope, "..") || strings.ContainsAny(scope, `/\`) {
+		return "", false, fmt.Errorf("invalid credential %s for scope %s", name, scope)
+	}
+	secretPath := strings.Trim(v.Path, "/")
+	if scope != "" {
+		secretPath += "/" + scope
+	}
+	data, err := v.secret(ctx, secretPath)
+	if err != nil {
+		return "", false, err
+	}
+	value, ok := data[name].(string)
+	return value, ok && value != "", nil
+}
+
+func (v *VaultCredentials) secret(ctx context.Context, secretPath string) (map[string]any, error) {
+	ttl := v.CacheTTL
+	if ttl <= 0 {
+		ttl = time.Minute
+	}
+	v.mu.Lock()
+	cached, ok := v.cache[secretPath]
+	v.mu.Unlock()
+	if ok && time.Since(cached.fetched) < ttl {
+		return cached.data, nil
+	}
+	mount := v.Mount
+	if mount == "" {
+		mount = "secret"
+	}
+	var resp struct {
+		Data struct {
+			Data map[string]any `json:"data"`
+		} `json:"data"`
+	}
+	url := strings.TrimRight(v.Address, "/") + "/v1/" + strings.Trim(mount, "/") + "/data/" + secretPath
+	err := requestJSON(ctx, http.MethodGet, url, "Bearer "+v.Token, nil, &resp)
+	if err != nil && strings.HasPrefix(err.Error(), "status code 404") {
+		// a scope without a secret has no credentials
+		err = nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read vault secret %s: %w", secretPath, err)
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.cache == nil {
+		v.cache = make(map[string]vaultSecret)
+	}
+	v.cache[secretPath] = vaultSecret{data: resp.Data.Data, fetched: time.Now()}
+	return resp.Data.Data, nil
+}
+
+// ChainCredentials returns the credential from the first provider that has it
+type ChainCredentials []CredentialsProvider
+
+func (c ChainCredentials) Credential(ctx context.Context, scope string, name string) (string, bool, error) {
+	for _, provider := range c {
+		value, ok, err := provider.Credential(ctx, scope, name)
+		if err != nil || ok {
+			return value, ok, err
+		}
+	}
+	return "", false, nil
+}
+
+// credentials reads the credentials of one tool call. The first error is kept so
+// callers can read several values and check once.
+type credentials struct {
+	provider CredentialsProvider
+	scope    string
+	// env is the conversation's environment, it takes precedence over the provider
+	env map[string]string
+	err error
+}
+
+// credentialsFor returns the credentials of the scope and environment in the tool
+// arguments
+func credentialsFor(args map[string]any) *credentials {
+	scope, _ := args[CredentialScopeArg].(string)
+	env, _ := args[EnvArg].(map[string]string)
+	return &credentials{provider: getCredentialsProvider(), scope: scope, env: env}
+}
+
+// get returns a credential, empty when it does not exist or the provider failed
+func (c *credentials) get(name string) string {
+	if c.err != nil {
+		return ""
+	}
+	if value := c.env[name]; value != "" {
+		return value
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	value, _, err := c.provider.Credential(ctx, c.scope, name)
+	if err != nil {
+		c.err = fmt.Errorf("failed to read credential %s: %w", name, err)
+		return ""
+	}
+	return value
+}
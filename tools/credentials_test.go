@@ -0,0 +1,41 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestVaultCredentials(t *testing.T) {
+	var paths []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"data":{"data":{"GITHUB_TOKEN":"ghp_acme"}}}`)
+	}))
+	defer srv.Close()
+	vault := &VaultCredentials{Address: srv.URL, Token: "token", Path: "genai"}
+
+	value, ok, err := vault.Credential(context.Background(), "acme", "GITHUB_TOKEN")
+	if err != nil || !ok || value != "ghp_acme" {
+		t.Fatalf("credential %q, %v, %v", value, ok, err)
+	}
+	if len(paths) != 1 || paths[0] != "/v1/secret/data/genai/acme" {
+		t.Errorf("requested %v", paths)
+	}
+	// scopes that would read the secret of another path are rejected before a request
+	for _, scope := range []string{"../admin", "acme/../admin", "acme/ops", `acme\ops`} {
+		if _, _, err := vault.Credential(context.Background(), scope, "GITHUB_TOKEN"); err == nil {
+			t.Errorf("scope %q accepted", scope)
+		}
+	}
+	if len(paths) != 1 {
+		t.Errorf("invalid scopes requested %v", paths[1:])
+	}
+}
@@ -0,0 +1,142 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// ToolDoc documents a registered tool, what an agent given the tool can do with it
+type ToolDoc struct {
+	Name        string      `json:"name"`
+	Description string      `json:"description"`
+	Parameters  []Parameter `json:"parameters"`
+	// Options are the names of the arguments set by the tool configuration instead of
+	// the model, their values are left out as they may hold credentials
+	Options   []string  `json:"options,omitempty"`
+	Paginated bool      `json:"paginated,omitempty"`
+	Examples  []Example `json:"examples,omitempty"`
+}
+
+// Docs documents the named tools, or every registered tool when no names are given,
+// sorted by name
+func Docs(toolNames ...string) ([]ToolDoc, error) {
+	if len(toolNames) == 0 {
+		toolNames = Tools()
+	}
+	docs := make([]ToolDoc, 0, len(toolNames))
+	for _, toolName := range toolNames {
+		tool, ok := lookupTool(toolName)
+		if !ok {
+			return nil, fmt.Errorf("tool %s: %w", toolName, ErrToolNotFound)
+		}
+		doc := ToolDoc{
+			Name:        tool.Name,
+			Description: tool.Description,
+			Parameters:  append([]Parameter{}, tool.Parameters...),
+			Paginated:   tool.Paginated,
+			Examples:    tool.Examples,
+		}
+		for key := range tool.Options {
+			doc.Options = append(doc.Options, key)
+		}
+		sort.Strings(doc.Options)
+		docs = append(docs, doc)
+	}
+	sort.Slice(docs, func(i, j int) bool { return docs[i].Name < docs[j].Name })
+	return docs, nil
+}
+
+// WriteDocsMarkdown writes docs as a markdown document with a section per tool
+func WriteDocsMarkdown(w io.Writer, docs []ToolDoc) error {
+	var b strings.Builder
+	b.WriteString("# Tools\n")
+	for _, doc := range docs {
+		fmt.Fprintf(&b, "\n## %s\n\n", doc.Name)
+		if doc.Description != "" {
+			fmt.Fprintf(&b, "%s\n\n", doc.Description)
+		}
+		if len(doc.Parameters) == 0 {
+			b.WriteString("No parameters.\n")
+		} else {
+			b.WriteString("| Parameter | Type | Required | Description |\n")
+			b.WriteString("| --- | --- | --- | --- |\n")
+			for _, param := range doc.Parameters {
+				required := "no"
+				if param.Required {
+					required = "yes"
+				}
+				fmt.Fprintf(&b, "| `%s` | %s | %s | %s |\n", param.Name, param.Type, required, markdownCell(param.Description))
+			}
+		}
+		if len(doc.Options) > 0 {
+			fmt.Fprintf(&b, "\nConfigured options: `%s`\n", strings.Join(doc.Options, "`, `"))
+		}
+		if doc.Paginated {
+			fmt.Fprintf(&b, "\nResults are paginated, pass the returned `%s` to get the next page.\n", CursorArg)
+		}
+		if len(doc.Examples) > 0 {
+			b.WriteString("\n### Examples\n")
+		}
+		for _, example := range doc.Examples {
+			args, err := json.MarshalIndent(example.Args, "", "  ")
+			if err != nil {
+				return fmt.Errorf("example of %s: %w", doc.Name, err)
+			}
+			b.WriteString("\n")
+			if example.Description != "" {
+				fmt.Fprintf(&b, "%s\n\n", example.Description)
+			}
+			fmt.Fprintf(&b, "```json\n%s\n```\n", args)
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// markdownCell keeps text from breaking out of a table cell
+func markdownCell(s string) string {
+	return strings.NewReplacer("|", `\|`, "\r\n", " ", "\n", " ").Replace(s)
+}
+
+// DocsHandler serves the documentation of the registered tools, as markdown or as JSON
+// when the format query parameter is json or the request accepts application/json.
+// The tool query parameter limits the docs to the given tools. The registry is read on
+// each request, so tools registered at runtime are included.
+func DocsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		docs, err := Docs(r.URL.Query()["tool"]...)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		format := r.URL.Query().Get("format")
+		if format == "" && strings.Contains(r.Header.Get("Accept"), "application/json") {
+			format = "json"
+		}
+		switch format {
+		case "json":
+			w.Header().Set("Content-Type", "application/json")
+			enc := json.NewEncoder(w)
+			enc.SetIndent("", "  ")
+			err = enc.Encode(docs)
+		case "", "markdown":
+			w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+			err = WriteDocsMarkdown(w, docs)
+		default:
+			http.Error(w, fmt.Sprintf("unknown format %q, use markdown or json", format), http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			Logger().Error(err, "Failed to write the tool docs")
+		}
+	})
+}
@@ -0,0 +1,93 @@
+package tools
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestToolDocsHandler(t *testing.T) {
+	RegisterTool(Tool{
+		Name:        "mock_weather",
+		Description: "Get the weather of a city",
+		Parameters:  []Parameter{{Name: "city", Type: "string", Description: "City", Required: true}},
+	})
+	t.Cleanup(func() { UnregisterTool("mock_weather") })
+	RegisterTool(Tool{
+		Name:        "mock_lookup",
+		Description: "Look up a record | by id",
+		Parameters:  []Parameter{{Name: "id", Type: "string", Description: "The record id", Required: true}},
+		Options:     map[string]string{"apiKey": "secret-value"},
+		Paginated:   true,
+		Examples:    []Example{{Description: "Look up the first record", Args: map[string]any{"id": "r-1"}}},
+	})
+	t.Cleanup(func() { UnregisterTool("mock_lookup") })
+	srv := httptest.NewServer(DocsHandler())
+	defer srv.Close()
+
+	get := func(query, accept string) (int, string) {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodGet, srv.URL+query, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.StatusCode, string(body)
+	}
+
+	status, markdown := get("?tool=mock_lookup&tool=mock_weather", "")
+	if status != http.StatusOK {
+		t.Fatalf("status %d: %s", status, markdown)
+	}
+	for _, want := range []string{
+		"## mock_lookup\n\nLook up a record | by id\n",
+		"| `id` | string | yes | The record id |",
+		"| `cursor` | string | no |",
+		"Configured options: `apiKey`",
+		"### Examples\n\nLook up the first record\n\n```json\n{\n  \"id\": \"r-1\"\n}\n```",
+		"## mock_weather",
+	} {
+		if !strings.Contains(markdown, want) {
+			t.Errorf("markdown is missing %q:\n%s", want, markdown)
+		}
+	}
+	if strings.Contains(markdown, "secret-value") {
+		t.Errorf("markdown shows an option value:\n%s", markdown)
+	}
+	if strings.Index(markdown, "## mock_lookup") > strings.Index(markdown, "## mock_weather") {
+		t.Errorf("tools are not sorted:\n%s", markdown)
+	}
+
+	status, body := get("?tool=mock_lookup", "application/json")
+	if status != http.StatusOK {
+		t.Fatalf("status %d: %s", status, body)
+	}
+	var docs []ToolDoc
+	if err := json.Unmarshal([]byte(body), &docs); err != nil {
+		t.Fatal(err)
+	}
+	if len(docs) != 1 || docs[0].Name != "mock_lookup" || len(docs[0].Parameters) != 2 || !docs[0].Paginated || docs[0].Examples[0].Args["id"] != "r-1" {
+		t.Errorf("json docs %+v", docs)
+	}
+
+	if status, _ := get("?tool=mock_missing", ""); status != http.StatusNotFound {
+		t.Errorf("status %d for a missing tool, want 404", status)
+	}
+	if status, _ := get("?format=yaml", ""); status != http.StatusBadRequest {
+		t.Errorf("status %d for an unknown format, want 400", status)
+	}
+}
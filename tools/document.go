@@ -0,0 +1,484 @@
+package tools
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	DataQueryToolName    = "data_query"
+	DataValidateToolName = "data_validate"
+	DataFormatToolName   = "data_format"
+
+	formatJSON = "json"
+	formatYAML = "yaml"
+)
+
+var documentTools = map[string]Tool{
+	DataQueryToolName:    dataQueryTool,
+	DataValidateToolName: dataValidateTool,
+	DataFormatToolName:   dataFormatTool,
+}
+
+// documentParameters select the document, either inline or from a file
+var documentParameters = []Parameter{
+	{
+		Name:        "document",
+		Type:        "string",
+		Description: "The JSON or YAML document, either document or path is required",
+		Required:    false,
+	},
+	{
+		Name:        "path",
+		Type:        "string",
+		Description: "The path to a JSON or YAML file, either document or path is required",
+		Required:    false,
+	},
+	{
+		Name:        "format",
+		Type:        "string",
+		Description: "The input format, json or yaml. Detected from the file extension or content when not set.",
+		Required:    false,
+	},
+}
+
+var dataQueryTool = Tool{
+	Name:        DataQueryToolName,
+	Description: "Query a JSON or YAML document with a JSONPath expression such as $.items[0].name, $.items[*].id or $..name and return the matching values",
+	Parameters: append([]Parameter{
+		{
+			Name:        "query",
+			Type:        "string",
+			Description: "The JSONPath expression, supports .key, ['key'], [index], [*], .* and ..key",
+			Required:    true,
+		},
+	}, documentParameters...),
+	Options: map[string]string{
+		"basePath": ".",
+	},
+	Run: DataQuery,
+}
+
+var dataValidateTool = Tool{
+	Name:        DataValidateToolName,
+	Description: "Check that a JSON or YAML document is well formed and report the location of the first syntax error",
+	Parameters:  documentParameters,
+	Options: map[string]string{
+		"basePath": ".",
+	},
+	Run: DataValidate,
+}
+
+var dataFormatTool = Tool{
+	Name:        DataFormatToolName,
+	Description: "Pretty-print a JSON or YAML document or convert it between JSON and YAML",
+	Parameters: append([]Parameter{
+		{
+			Name:        "output_format",
+			Type:        "string",
+			Description: "The output format, json or yaml, defaults to the input format",
+			Required:    false,
+		},
+	}, documentParameters...),
+	Options: map[string]string{
+		"basePath": ".",
+	},
+	Run: DataFormat,
+}
+
+func DataQuery(args map[string]any) (map[string]any, error) {
+	query, ok := args["query"].(string)
+	if !ok {
+		return map[string]any{
+			"success": false,
+			"error":   fmt.Sprintf("expected string: %v", args["query"]),
+		}, fmt.Errorf("expected string: %v", args["query"])
+	}
+	content, format, err := loadDocument(args)
+	if err != nil {
+		return map[string]any{
+			"success": false,
+			"error":   err.Error(),
+		}, err
+	}
+	doc, err := parseDocument(content, format)
+	if err != nil {
+		return map[string]any{
+			"success": false,
+			"error":   err.Error(),
+		}, err
+	}
+	matches, err := QueryDocument(doc, query)
+	if err != nil {
+		return map[string]any{
+			"success": false,
+			"error":   err.Error(),
+		}, err
+	}
+	return map[string]any{
+		"success": true,
+		"matches": matches,
+		"count":   len(matches),
+	}, nil
+}
+
+func DataValidate(args map[string]any) (map[string]any, error) {
+	content, format, err := loadDocument(args)
+	if err != nil {
+		return map[string]any{
+			"success": false,
+			"error":   err.Error(),
+		}, err
+	}
+	// an invalid document is a successful validation
+	if _, err := parseDocument(content, format); err != nil {
+		return map[string]any{
+			"success": true,
+			"valid":   false,
+			"format":  format,
+			"error":   err.Error(),
+		}, nil
+	}
+	return map[string]any{
+		"success": true,
+		"valid":   true,
+		"format":  format,
+	}, nil
+}
+
+func DataFormat(args map[string]any) (map[string]any, error) {
+	content, format, err := loadDocument(args)
+	if err != nil {
+		return map[string]any{
+			"success": false,
+			"error":   err.Error(),
+		}, err
+	}
+	output, _ := args["output_format"].(string)
+	if output == "" {
+		output = format
+	}
+	output = strings.ToLower(output)
+	var formatted string
+	switch output {
+	case formatJSON:
+		formatted, err = formatAsJSON(content, format)
+	case formatYAML:
+		formatted, err = formatAsYAML(content)
+	default:
+		err = fmt.Errorf("unsupported output format: %s", output)
+	}
+	if err != nil {
+		return map[string]any{
+			"success": false,
+			"error":   err.Error(),
+		}, err
+	}
+	return map[string]any{
+		"success":  true,
+		"format":   output,
+		"document": formatted,
+	}, nil
+}
+
+// loadDocument returns the document content and its format from the document or path argument
+func loadDocument(args map[string]any) ([]byte, string, error) {
+	format, _ := args["format"].(string)
+	format = strings.ToLower(format)
+	var content []byte
+	if document, ok := args["document"].(string); ok && document != "" {
+		content = []byte(document)
+	} else if path, ok := args["path"].(string); ok && path != "" {
+		basePath, _ := args[BasePathArg].(string)
+		p, err := sandboxPath(basePath, path)
+		if err != nil {
+			return nil, "", err
+		}
+		if content, err = os.ReadFile(p); err != nil {
+			return nil, "", fmt.Errorf("failed to read file: %w", err)
+		}
+		if format == "" {
+			switch strings.ToLower(filepath.Ext(path)) {
+			case ".json":
+				format = formatJSON
+			case ".yaml", ".yml":
+				format = formatYAML
+			}
+		}
+	} else {
+		return nil, "", fmt.Errorf("either document or path is required")
+	}
+	if format == "" {
+		format = detectFormat(content)
+	}
+	if format != formatJSON && format != formatYAML {
+		return nil, "", fmt.Errorf("unsupported format: %s", format)
+	}
+	return content, format, nil
+}
+
+// detectFormat treats documents starting with { or [ as JSON
+func detectFormat(content []byte) string {
+	trimmed := bytes.TrimSpace(content)
+	if len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') {
+		return formatJSON
+	}
+	return formatYAML
+}
+
+func parseDocument(content []byte, format string) (any, error) {
+	var doc any
+	if format == formatJSON {
+		decoder := json.NewDecoder(bytes.NewReader(content))
+		decoder.UseNumber()
+		if err := decoder.Decode(&doc); err != nil {
+			return nil, jsonError(content, err)
+		}
+		if decoder.More() {
+			return nil, fmt.Errorf("invalid JSON: unexpected content after the document")
+		}
+		return doc, nil
+	}
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return nil, fmt.Errorf("invalid YAML: %w", err)
+	}
+	return normalizeYAML(doc), nil
+}
+
+// jsonError adds the line and column to JSON syntax errors
+func jsonError(content []byte, err error) error {
+	var offset int64 = -1
+	switch e := err.(type) {
+	case *json.SyntaxError:
+		offset = e.Offset
+	case *json.UnmarshalTypeError:
+		offset = e.Offset
+	}
+	if offset < 0 {
+		return fmt.Errorf("invalid JSON: %w", err)
+	}
+	before := content[:min(int(offset), len(content))]
+	line := bytes.Count(before, []byte("\n")) + 1
+	column := len(before) - bytes.LastIndexByte(before, '\n')
+	return fmt.Errorf("invalid JSON at line %d, column %d: %w", line, column, err)
+}
+
+// normalizeYAML converts maps with non string keys so documents can be encoded as JSON
+func normalizeYAML(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, item := range v {
+			v[key] = normalizeYAML(item)
+		}
+		return v
+	case map[any]any:
+		converted := make(map[string]any, len(v))
+		for key, item := range v {
+			converted[fmt.Sprintf("%v", key)] = normalizeYAML(item)
+		}
+		return converted
+	case []any:
+		for i, item := range v {
+			v[i] = normalizeYAML(item)
+		}
+		return v
+	}
+	return value
+}
+
+func formatAsJSON(content []byte, format string) (string, error) {
+	if format == formatJSON {
+		// indent the original so key order is kept
+		var out bytes.Buffer
+		if err := json.Indent(&out, bytes.TrimSpace(content), "", "  "); err != nil {
+			return "", jsonError(content, err)
+		}
+		return out.String(), nil
+	}
+	doc, err := parseDocument(content, format)
+	if err != nil {
+		return "", err
+	}
+	out, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode JSON: %w", err)
+	}
+	return string(out), nil
+}
+
+// formatAsYAML goes through a yaml.Node so key order and comments are kept. JSON is
+// valid YAML so the same path converts JSON documents.
+func formatAsYAML(content []byte) (string, error) {
+	var node yaml.Node
+	if err := yaml.Unmarshal(content, &node); err != nil {
+		return "", fmt.Errorf("invalid YAML: %w", err)
+	}
+	blockStyle(&node)
+	var out bytes.Buffer
+	encoder := yaml.NewEncoder(&out)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&node); err != nil {
+		return "", fmt.Errorf("failed to encode YAML: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return "", fmt.Errorf("failed to encode YAML: %w", err)
+	}
+	return out.String(), nil
+}
+
+// blockStyle switches flow collections, which is how JSON input is parsed, to block style
+func blockStyle(node *yaml.Node) {
+	node.Style &^= yaml.FlowStyle
+	if node.Kind == yaml.ScalarNode && node.Style&yaml.DoubleQuotedStyle != 0 && node.Tag == "!!str" {
+		// keep strings quoted only where YAML needs it
+		node.Style &^= yaml.DoubleQuotedStyle
+	}
+	for _, child := range node.Content {
+		blockStyle(child)
+	}
+}
+
+// QueryDocument evaluates a JSONPath expression against a decoded document. It supports
+// the subset agents use in practice: $ root, .key, ['key'], [index] with negative
+// indexes counting from the end, [*] and .* wildcards and ..key recursive descent.
+func QueryDocument(doc any, query string) ([]any, error) {
+	query = strings.TrimSpace(query)
+	query = strings.TrimPrefix(query, "$")
+	current := []any{doc}
+	for query != "" {
+		var err error
+		var next []any
+		switch {
+		case strings.HasPrefix(query, ".."):
+			var key string
+			key, query = cutPathKey(query[2:])
+			if key == "" {
+				return nil, fmt.Errorf("invalid query: expected a key after ..")
+			}
+			for _, value := range current {
+				next = append(next, descendants(value, key)...)
+			}
+		case strings.HasPrefix(query, "."):
+			var key string
+			key, query = cutPathKey(query[1:])
+			if key == "" {
+				return nil, fmt.Errorf("invalid query: expected a key after .")
+			}
+			next = selectKey(current, key)
+		case strings.HasPrefix(query, "["):
+			end := strings.IndexByte(query, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("invalid query: missing ]")
+			}
+			selector := strings.TrimSpace(query[1:end])
+			query = query[end+1:]
+			next, err = selectBracket(current, selector)
+			if err != nil {
+				return nil, err
+			}
+		default:
+			// a leading key without a dot, e.g. items[0]
+			var key string
+			key, query = cutPathKey(query)
+			if key == "" {
+				return nil, fmt.Errorf("invalid query near %q", query)
+			}
+			next = selectKey(current, key)
+		}
+		current = next
+	}
+	if current == nil {
+		current = []any{}
+	}
+	return current, nil
+}
+
+// cutPathKey splits a dotted key from the rest of the query
+func cutPathKey(query string) (string, string) {
+	end := strings.IndexAny(query, ".[")
+	if end < 0 {
+		return query, ""
+	}
+	return query[:end], query[end:]
+}
+
+// selectKey selects key of objects, * selects every value of objects and lists. The
+// values of objects are selected in key order so a query always returns the same result.
+func selectKey(values []any, key string) []any {
+	var selected []any
+	for _, value := range values {
+		switch v := value.(type) {
+		case map[string]any:
+			if key == "*" {
+				for _, k := range slices.Sorted(maps.Keys(v)) {
+					selected = append(selected, v[k])
+				}
+			} else if item, ok := v[key]; ok {
+				selected = append(selected, item)
+			}
+		case []any:
+			if key == "*" {
+				selected = append(selected, v...)
+			}
+		}
+	}
+	return selected
+}
+
+func selectBracket(values []any, selector string) ([]any, error) {
+	if selector == "*" {
+		return selectKey(values, "*"), nil
+	}
+	if len(selector) >= 2 && (selector[0] == '\'' || selector[0] == '"') && selector[len(selector)-1] == selector[0] {
+		return selectKey(values, selector[1:len(selector)-1]), nil
+	}
+	index, err := strconv.Atoi(selector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid query: unsupported selector [%s]", selector)
+	}
+	var selected []any
+	for _, value := range values {
+		list, ok := value.([]any)
+		if !ok {
+			continue
+		}
+		i := index
+		if i < 0 {
+			i += len(list)
+		}
+		if i >= 0 && i < len(list) {
+			selected = append(selected, list[i])
+		}
+	}
+	return selected, nil
+}
+
+// descendants returns the values of key at any depth below value, objects are walked in
+// key order
+func descendants(value any, key string) []any {
+	var found []any
+	switch v := value.(type) {
+	case map[string]any:
+		for _, k := range slices.Sorted(maps.Keys(v)) {
+			item := v[k]
+			if k == key || key == "*" {
+				found = append(found, item)
+			}
+			found = append(found, descendants(item, key)...)
+		}
+	case []any:
+		for _, item := range v {
+			found = append(found, descendants(item, key)...)
+		}
+	}
+	return found
+}
@@ -0,0 +1,176 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testDocument = `{
+  "store": {
+    "name": "corner shop",
+    "items": [
+      {"id": 1, "name": "apple", "tags": {"color": "red", "size": "small"}},
+      {"id": 2, "name": "pear", "tags": {"color": "green"}},
+      {"id": 3, "name": "plum"}
+    ]
+  },
+  "owner": {"name": "sam", "zone": "b", "age": 40}
+}`
+
+func TestQueryDocument(t *testing.T) {
+	doc, err := parseDocument([]byte(testDocument), formatJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"$.store.name", `["corner shop"]`},
+		{"$.store.items[0].name", `["apple"]`},
+		{"$.store.items[-1].name", `["plum"]`},
+		{"$.store.items[5].name", `[]`},
+		{"$['store']['name']", `["corner shop"]`},
+		{"store.items[1].id", `[2]`},
+		{"$.store.items[*].id", `[1,2,3]`},
+		{"$.store.items.*.name", `["apple","pear","plum"]`},
+		// objects are walked in key order
+		{"$.owner.*", `[40,"sam","b"]`},
+		{"$.owner[*]", `[40,"sam","b"]`},
+		{"$..name", `["sam","apple","pear","plum","corner shop"]`},
+		{"$..color", `["red","green"]`},
+		{"$.store.items[0].tags..*", `["red","small"]`},
+		{"$.missing", `[]`},
+		{"$.owner.age", `[40]`},
+	}
+	for _, test := range tests {
+		matches, err := QueryDocument(doc, test.query)
+		if err != nil {
+			t.Errorf("%s: %v", test.query, err)
+			continue
+		}
+		got, _ := json.Marshal(matches)
+		if string(got) != test.want {
+			t.Errorf("%s = %s, want %s", test.query, got, test.want)
+		}
+	}
+	// map iteration must not change the result
+	first, _ := QueryDocument(doc, "$..*")
+	for i := 0; i < 20; i++ {
+		again, _ := QueryDocument(doc, "$..*")
+		if fmt.Sprint(again) != fmt.Sprint(first) {
+			t.Fatalf("results differ between runs:\n%v\n%v", first, again)
+		}
+	}
+}
+
+func TestQueryDocumentErrors(t *testing.T) {
+	for _, query := range []string{"$..", "$.", "$.items[0", "$.items[abc]"} {
+		if _, err := QueryDocument(map[string]any{}, query); err == nil {
+			t.Errorf("%s: expected an error", query)
+		}
+	}
+}
+
+func TestDataQueryYAML(t *testing.T) {
+	document := "servers:\n  - name: web\n    ports: {80: http, 443: https}\n  - name: db\n"
+	result, err := DataQuery(map[string]any{"document": document, "query": "$.servers[0].ports"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// YAML maps with non string keys are converted so they can be encoded as JSON
+	matches := result["matches"].([]any)
+	ports, ok := matches[0].(map[string]any)
+	if !ok || ports["80"] != "http" || ports["443"] != "https" {
+		t.Errorf("ports %#v", matches[0])
+	}
+	if _, err := json.Marshal(result); err != nil {
+		t.Errorf("result can not be encoded as JSON: %v", err)
+	}
+}
+
+func TestDataQueryPath(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "config.yml"), []byte("name: app\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	result, err := DataQuery(map[string]any{"path": "config.yml", "query": "$.name", BasePathArg: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(result["matches"]) != "[app]" {
+		t.Errorf("matches %v", result["matches"])
+	}
+	if _, err := DataQuery(map[string]any{"path": "../outside.json", "query": "$", BasePathArg: dir}); err == nil {
+		t.Error("a path outside the base path was read")
+	}
+}
+
+func TestDataValidate(t *testing.T) {
+	result, err := DataValidate(map[string]any{"document": "{\n  \"a\": 1,\n  \"b\": }"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result["valid"] != false || !strings.Contains(result["error"].(string), "line 3") {
+		t.Errorf("result %v, want invalid at line 3", result)
+	}
+	result, err = DataValidate(map[string]any{"document": "a: 1\nb: [1, 2]\n"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result["valid"] != true || result["format"] != formatYAML {
+		t.Errorf("result %v, want valid YAML", result)
+	}
+}
+
+func TestDataFormat(t *testing.T) {
+	tests := []struct {
+		name   string
+		args   map[string]any
+		want   string
+		format string
+	}{
+		{
+			name:   "json keeps key order",
+			args:   map[string]any{"document": `{"b":1,"a":[true,null]}`},
+			want:   "{\n  \"b\": 1,\n  \"a\": [\n    true,\n    null\n  ]\n}",
+			format: formatJSON,
+		},
+		{
+			name:   "json to yaml",
+			args:   map[string]any{"document": `{"b":"x y","a":[1,2]}`, "output_format": "yaml"},
+			want:   "b: x y\na:\n  - 1\n  - 2\n",
+			format: formatYAML,
+		},
+		{
+			name:   "yaml to json",
+			args:   map[string]any{"document": "name: app\nports:\n  - 80\n", "output_format": "JSON"},
+			want:   "{\n  \"name\": \"app\",\n  \"ports\": [\n    80\n  ]\n}",
+			format: formatJSON,
+		},
+		{
+			name:   "yaml keeps comments",
+			args:   map[string]any{"document": "# settings\nname: app # the name\n"},
+			want:   "# settings\nname: app # the name\n",
+			format: formatYAML,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := DataFormat(test.args)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result["document"] != test.want || result["format"] != test.format {
+				t.Errorf("got %s %q, want %s %q", result["format"], result["document"], test.format, test.want)
+			}
+		})
+	}
+	if _, err := DataFormat(map[string]any{"document": "{}", "output_format": "toml"}); err == nil {
+		t.Error("expected an error for an unsupported output format")
+	}
+}
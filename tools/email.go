@@ -0,0 +1,381 @@
+package tools
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	htmltemplate "html/template"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+const (
+	SendEmailToolName = "send_email"
+
+	// Credentials read when email is not configured with ConfigureEmail
+	SMTPHostEnv               = "SMTP_HOST"
+	SMTPPortEnv               = "SMTP_PORT"
+	SMTPUsernameEnv           = "SMTP_USERNAME"
+	SMTPPasswordEnv           = "SMTP_PASSWORD"
+	EmailFromEnv              = "EMAIL_FROM"
+	EmailAllowedRecipientsEnv = "EMAIL_ALLOWED_RECIPIENTS"
+	// EmailSendEnv must be true to send mail, otherwise send_email is a dry run
+	EmailSendEnv = "EMAIL_SEND"
+
+	maxEmailRecipients = 20
+)
+
+// EmailConfig configures the send_email tool
+type EmailConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+	// AllowedRecipients are addresses or domains written as @example.com, mail to
+	// anyone else is rejected. An empty list rejects every recipient.
+	AllowedRecipients []string
+	// Send delivers mail, when false the tool only renders the message
+	Send bool
+}
+
+// EmailTemplate renders the subject and body of an email from the data the model passes
+type EmailTemplate struct {
+	Subject string
+	Body    string
+	// HTML escapes the data in Body and sends it as text/html
+	HTML bool
+}
+
+var (
+	emailConfig    *EmailConfig
+	emailTemplates = map[string]EmailTemplate{}
+	emailMu        sync.RWMutex
+)
+
+// ConfigureEmail replaces the configuration read from the CredentialsProvider
+func ConfigureEmail(config EmailConfig) {
+	emailMu.Lock()
+	defer emailMu.Unlock()
+	emailConfig = &config
+}
+
+// RegisterEmailTemplate adds a template the model can select by name. The subject and
+// body are Go templates executed with the data argument.
+func RegisterEmailTemplate(name string, tmpl EmailTemplate) error {
+	if _, err := template.New(name).Parse(tmpl.Subject); err != nil {
+		return fmt.Errorf("invalid subject template: %w", err)
+	}
+	var err error
+	if tmpl.HTML {
+		_, err = htmltemplate.New(name).Parse(tmpl.Body)
+	} else {
+		_, err = template.New(name).Parse(tmpl.Body)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid body template: %w", err)
+	}
+	emailMu.Lock()
+	defer emailMu.Unlock()
+	emailTemplates[name] = tmpl
+	return nil
+}
+
+func getEmailConfig(args map[string]any) (EmailConfig, error) {
+	emailMu.RLock()
+	defer emailMu.RUnlock()
+	if emailConfig != nil {
+		return *emailConfig, nil
+	}
+	creds := credentialsFor(args)
+	port, _ := strconv.Atoi(creds.get(SMTPPortEnv))
+	config := EmailConfig{
+		Host:     creds.get(SMTPHostEnv),
+		Port:     port,
+		Username: creds.get(SMTPUsernameEnv),
+		Password: creds.get(SMTPPasswordEnv),
+		From:     creds.get(EmailFromEnv),
+		Send:     creds.get(EmailSendEnv) == "true",
+	}
+	for _, recipient := range strings.Split(creds.get(EmailAllowedRecipientsEnv), ",") {
+		if recipient = strings.TrimSpace(recipient); recipient != "" {
+			config.AllowedRecipients = append(config.AllowedRecipients, recipient)
+		}
+	}
+	return config, creds.err
+}
+
+// allowed reports whether the address matches an allowed address or @domain
+func (c EmailConfig) allowed(address string) bool {
+	address = strings.ToLower(address)
+	for _, allowed := range c.AllowedRecipients {
+		allowed = strings.ToLower(allowed)
+		if strings.HasPrefix(allowed, "@") {
+			if strings.HasSuffix(address, allowed) {
+				return true
+			}
+		} else if address == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+var emailTools = map[string]Tool{
+	SendEmailToolName: sendEmailTool,
+}
+
+var sendEmailTool = Tool{
+	Name:        SendEmailToolName,
+	Description: "Send an email to allowed recipients. Pass a subject and body, or a template name and its data.",
+	Parameters: []Parameter{
+		{
+			Name:        "to",
+			Type:        "stringArray",
+			Description: "The recipient email addresses",
+			Required:    true,
+		},
+		{
+			Name:        "subject",
+			Type:        "string",
+			Description: "The subject, not used with a template",
+			Required:    false,
+		},
+		{
+			Name:        "body",
+			Type:        "string",
+			Description: "The plain text body, not used with a template",
+			Required:    false,
+		},
+		{
+			Name:        "template",
+			Type:        "string",
+			Description: "The name of a registered email template",
+			Required:    false,
+		},
+		{
+			Name:        "data",
+			Type:        "string",
+			Description: "A JSON object with the values used by the template",
+			Required:    false,
+		},
+	},
+	Options: map[string]string{},
+	Run:     SendEmail,
+}
+
+type sendEmailArgs struct {
+	To       []string `json:"to"`
+	Subject  string   `json:"subject"`
+	Body     string   `json:"body"`
+	Template string   `json:"template"`
+	Data     string   `json:"data"`
+}
+
+func SendEmail(args map[string]any) (map[string]any, error) {
+	typed, err := DecodeArgs[sendEmailArgs](args)
+	if err != nil {
+		return map[string]any{
+			"success": false,
+			"error":   err.Error(),
+		}, err
+	}
+	config, err := getEmailConfig(args)
+	if err != nil {
+		return map[string]any{
+			"success": false,
+			"error":   err.Error(),
+		}, err
+	}
+	recipients, err := emailRecipients(config, typed.To)
+	if err != nil {
+		return map[string]any{
+			"success": false,
+			"error":   err.Error(),
+		}, err
+	}
+	subject, body, contentType, err := renderEmail(typed)
+	if err != nil {
+		return map[string]any{
+			"success": false,
+			"error":   err.Error(),
+		}, err
+	}
+	if strings.ContainsAny(subject, "\r\n") {
+		subject = strings.Join(strings.Fields(subject), " ")
+	}
+	if !config.Send {
+		return map[string]any{
+			"success": true,
+			"dry_run": true,
+			"to":      recipients,
+			"subject": subject,
+			"body":    body,
+			"message": "The email was not sent because sending is disabled, this is what would have been sent.",
+		}, nil
+	}
+	if config.Host == "" || config.From == "" {
+		err := fmt.Errorf("email is not configured, set %s and %s", SMTPHostEnv, EmailFromEnv)
+		return map[string]any{
+			"success": false,
+			"error":   err.Error(),
+		}, err
+	}
+	from, err := mail.ParseAddress(config.From)
+	if err != nil {
+		err = fmt.Errorf("invalid %s: %w", EmailFromEnv, err)
+		return map[string]any{
+			"success": false,
+			"error":   err.Error(),
+		}, err
+	}
+	if err := deliverEmail(config, from.Address, recipients, buildEmail(from, recipients, subject, body, contentType)); err != nil {
+		err = NewToolError(fmt.Errorf("failed to send email: %w", err), "", true)
+		return map[string]any{
+			"success": false,
+			"error":   err.Error(),
+		}, err
+	}
+	return map[string]any{
+		"success": true,
+		"to":      recipients,
+		"subject": subject,
+	}, nil
+}
+
+// emailRecipients parses the addresses and checks them against the allowlist
+func emailRecipients(config EmailConfig, to []string) ([]string, error) {
+	if len(to) == 0 {
+		return nil, NewToolError(fmt.Errorf("at least one recipient is required"), "", true)
+	}
+	if len(to) > maxEmailRecipients {
+		return nil, NewToolError(fmt.Errorf("too many recipients, the limit is %d", maxEmailRecipients), "", false)
+	}
+	recipients := make([]string, 0, len(to))
+	for _, address := range to {
+		parsed, err := mail.ParseAddress(address)
+		if err != nil {
+			return nil, NewToolError(fmt.Errorf("invalid address %q: %w", address, err), "Pass plain email addresses such as name@example.com.", true)
+		}
+		if !config.allowed(parsed.Address) {
+			return nil, NewToolError(fmt.Errorf("recipient %s is not allowed", parsed.Address), "Only send email to the allowed recipients.", false)
+		}
+		recipients = append(recipients, parsed.Address)
+	}
+	return recipients, nil
+}
+
+// renderEmail returns the subject, body and content type, from a template when one is named
+func renderEmail(args sendEmailArgs) (string, string, string, error) {
+	if args.Template == "" {
+		if args.Subject == "" || args.Body == "" {
+			return "", "", "", NewToolError(fmt.Errorf("subject and body are required without a template"), "", true)
+		}
+		return args.Subject, args.Body, "text/plain; charset=utf-8", nil
+	}
+	emailMu.RLock()
+	tmpl, ok := emailTemplates[args.Template]
+	emailMu.RUnlock()
+	if !ok {
+		return "", "", "", NewToolError(fmt.Errorf("unknown template: %s", args.Template), "", false)
+	}
+	data := map[string]any{}
+	if args.Data != "" {
+		if err := json.Unmarshal([]byte(args.Data), &data); err != nil {
+			return "", "", "", NewToolError(fmt.Errorf("invalid data: %w", err), "Pass data as a JSON object.", true)
+		}
+	}
+	var subject, body bytes.Buffer
+	if err := template.Must(template.New(args.Template).Parse(tmpl.Subject)).Execute(&subject, data); err != nil {
+		return "", "", "", fmt.Errorf("failed to render subject: %w", err)
+	}
+	contentType := "text/plain; charset=utf-8"
+	var err error
+	if tmpl.HTML {
+		contentType = "text/html; charset=utf-8"
+		err = htmltemplate.Must(htmltemplate.New(args.Template).Parse(tmpl.Body)).Execute(&body, data)
+	} else {
+		err = template.Must(template.New(args.Template).Parse(tmpl.Body)).Execute(&body, data)
+	}
+	if err != nil {
+		return "", "", "", fmt.Errorf("failed to render body: %w", err)
+	}
+	return subject.String(), body.String(), contentType, nil
+}
+
+func buildEmail(from *mail.Address, to []string, subject string, body string, contentType string) []byte {
+	var msg bytes.Buffer
+	id := make([]byte, 16)
+	rand.Read(id)
+	domain := from.Address[strings.LastIndex(from.Address, "@")+1:]
+	fmt.Fprintf(&msg, "From: %s\r\n", from.String())
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&msg, "Message-ID: <%s@%s>\r\n", hex.EncodeToString(id), domain)
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&msg, "Content-Type: %s\r\n", contentType)
+	msg.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+	msg.WriteString(strings.ReplaceAll(strings.ReplaceAll(body, "\r\n", "\n"), "\n", "\r\n"))
+	return msg.Bytes()
+}
+
+// deliverEmail sends with implicit TLS on port 465 and STARTTLS when offered otherwise
+func deliverEmail(config EmailConfig, from string, to []string, msg []byte) error {
+	port := config.Port
+	if port == 0 {
+		port = 587
+	}
+	addr := net.JoinHostPort(config.Host, strconv.Itoa(port))
+	var auth smtp.Auth
+	if config.Username != "" {
+		auth = smtp.PlainAuth("", config.Username, config.Password, config.Host)
+	}
+	if port != 465 {
+		return smtp.SendMail(addr, auth, from, to, msg)
+	}
+	conn, err := tls.DialWithDialer(&net.Dialer{Timeout: 30 * time.Second}, "tcp", addr, &tls.Config{ServerName: config.Host})
+	if err != nil {
+		return err
+	}
+	client, err := smtp.NewClient(conn, config.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+	if auth != nil {
+		if err := client.Auth(auth); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(from); err != nil {
+		return err
+	}
+	for _, recipient := range to {
+		if err := client.Rcpt(recipient); err != nil {
+			return err
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
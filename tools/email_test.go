@@ -0,0 +1,171 @@
+package tools
+
+import (
+	"bufio"
+	"encoding/base64"
+	"net"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// smtpMessage is a message received by the fake SMTP server
+type smtpMessage struct {
+	auth string
+	from string
+	to   []string
+	data string
+}
+
+// serveSMTP runs a plain SMTP server that accepts one message and sends it on the channel
+func serveSMTP(t *testing.T) (string, int, <-chan smtpMessage) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	messages := make(chan smtpMessage, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		text := textproto.NewConn(conn)
+		var msg smtpMessage
+		text.PrintfLine("220 localhost ESMTP")
+		for {
+			line, err := text.ReadLine()
+			if err != nil {
+				return
+			}
+			verb, arg, _ := strings.Cut(line, " ")
+			switch strings.ToUpper(verb) {
+			case "EHLO":
+				text.PrintfLine("250-localhost\r\n250 AUTH PLAIN")
+			case "AUTH":
+				_, credentials, _ := strings.Cut(arg, " ")
+				decoded, _ := base64.StdEncoding.DecodeString(credentials)
+				msg.auth = string(decoded)
+				text.PrintfLine("235 Authenticated")
+			case "MAIL":
+				msg.from = arg
+				text.PrintfLine("250 OK")
+			case "RCPT":
+				msg.to = append(msg.to, arg)
+				text.PrintfLine("250 OK")
+			case "DATA":
+				text.PrintfLine("354 Go ahead")
+				data, _ := text.ReadDotBytes()
+				msg.data = string(data)
+				text.PrintfLine("250 Queued")
+				messages <- msg
+			case "QUIT":
+				text.PrintfLine("221 Bye")
+				return
+			default:
+				text.PrintfLine("502 Not implemented")
+			}
+		}
+	}()
+	host, port, _ := net.SplitHostPort(listener.Addr().String())
+	portNumber, _ := strconv.Atoi(port)
+	return host, portNumber, messages
+}
+
+func TestSendEmail(t *testing.T) {
+	host, port, messages := serveSMTP(t)
+	env := map[string]string{
+		SMTPHostEnv:               host,
+		SMTPPortEnv:               strconv.Itoa(port),
+		SMTPUsernameEnv:           "mailer",
+		SMTPPasswordEnv:           "secret",
+		EmailFromEnv:              "Reports <reports@example.com>",
+		EmailAllowedRecipientsEnv: "@example.com, ops@partner.org",
+		EmailSendEnv:              "true",
+	}
+	result, err := SendEmail(map[string]any{
+		"to":      []any{"Ana <Ana@Example.com>", "ops@partner.org"},
+		"subject": "Weekly report\r\nBcc: everyone@example.com",
+		"body":    "All green.\nSee you.",
+		EnvArg:    env,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result["dry_run"] != nil || result["subject"] != "Weekly report Bcc: everyone@example.com" {
+		t.Errorf("result %v", result)
+	}
+	msg := <-messages
+	if msg.auth != "\x00mailer\x00secret" || msg.from != "FROM:<reports@example.com>" || len(msg.to) != 2 || msg.to[0] != "TO:<Ana@Example.com>" {
+		t.Errorf("envelope %+v", msg)
+	}
+	header, err := textproto.NewReader(bufio.NewReader(strings.NewReader(msg.data))).ReadMIMEHeader()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the newline in the subject does not start another header
+	if header.Get("Bcc") != "" || header.Get("Subject") != "Weekly report Bcc: everyone@example.com" || header.Get("To") != "Ana@Example.com, ops@partner.org" {
+		t.Errorf("header %v", header)
+	}
+	if !strings.HasSuffix(header.Get("Message-Id"), "@example.com>") || header.Get("Content-Type") != "text/plain; charset=utf-8" {
+		t.Errorf("header %v", header)
+	}
+	if !strings.HasSuffix(msg.data, "\nAll green.\nSee you.\n") {
+		t.Errorf("data %q", msg.data)
+	}
+}
+
+func TestSendEmailDryRun(t *testing.T) {
+	if err := RegisterEmailTemplate("incident", EmailTemplate{
+		Subject: "Incident: {{.title}}",
+		Body:    "<p>{{.title}} is {{.status}}</p>",
+		HTML:    true,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		emailMu.Lock()
+		defer emailMu.Unlock()
+		delete(emailTemplates, "incident")
+	})
+	env := map[string]string{EmailAllowedRecipientsEnv: "oncall@example.com"}
+	result, err := SendEmail(map[string]any{
+		"to":       []any{"oncall@example.com"},
+		"template": "incident",
+		"data":     `{"title":"<script>","status":"resolved"}`,
+		EnvArg:     env,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result["dry_run"] != true || result["subject"] != "Incident: <script>" || result["body"] != "<p>&lt;script&gt; is resolved</p>" {
+		t.Errorf("result %v", result)
+	}
+
+	tests := []struct {
+		args map[string]any
+		want string
+	}{
+		{map[string]any{"to": []any{"eve@evil.com"}, "subject": "s", "body": "b"}, "not allowed"},
+		{map[string]any{"to": []any{"oncall@example.com.evil.com"}, "subject": "s", "body": "b"}, "not allowed"},
+		{map[string]any{"to": []any{"not an address"}, "subject": "s", "body": "b"}, "invalid address"},
+		{map[string]any{"to": []any{}, "subject": "s", "body": "b"}, "at least one recipient"},
+		{map[string]any{"to": []any{"oncall@example.com"}, "subject": "s"}, "subject and body are required"},
+		{map[string]any{"to": []any{"oncall@example.com"}, "template": "missing"}, "unknown template"},
+		{map[string]any{"to": []any{"oncall@example.com"}, "template": "incident", "data": "[1]"}, "invalid data"},
+	}
+	for _, tt := range tests {
+		tt.args[EnvArg] = env
+		if _, err := SendEmail(tt.args); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("SendEmail(%v) error %v, want %q", tt.args, err, tt.want)
+		}
+	}
+	if err := RegisterEmailTemplate("broken", EmailTemplate{Subject: "{{.title"}); err == nil {
+		t.Error("registered a template that does not parse")
+	}
+	if !(EmailConfig{AllowedRecipients: []string{"@Example.com"}}).allowed("ana@example.COM") {
+		t.Error("domains are not matched case insensitively")
+	}
+}
@@ -181,7 +181,8 @@ var listFilesTool = Tool{
 	Options: map[string]string{
 		"basePath": ".",
 	},
-	Run: ListFiles,
+	Run:       ListFiles,
+	Paginated: true,
 }
 
 func ListFiles(args map[string]any) (map[string]any, error) {
@@ -212,10 +213,17 @@ func ListFiles(args map[string]any) (map[string]any, error) {
 	for i, file := range files {
 		names[i] = file.Name()
 	}
-	namesString := strings.Join(names, ", ")
-	return map[string]any{
+	page, next, err := Paginate(names, args, DefaultPageSize)
+	if err != nil {
+		return map[string]any{
+			"success": false,
+			"error":   err.Error(),
+		}, err
+	}
+	namesString := strings.Join(page, ", ")
+	return setNextCursor(map[string]any{
 		"files": namesString,
-	}, nil
+	}, next), nil
 }
 
 func ListDirectories(path string) ([]string, error) {
@@ -252,9 +260,13 @@ var treeTool = Tool{
 	Options: map[string]string{
 		"basePath": ".",
 	},
-	Run: Tree,
+	Run:       Tree,
+	Paginated: true,
 }
 
+// treePageSize is the number of lines returned per page of tree output
+const treePageSize = 500
+
 func Tree(args map[string]any) (map[string]any, error) {
 	var output string
 	path, ok := args["path"].(string)
@@ -296,9 +308,16 @@ func Tree(args map[string]any) (map[string]any, error) {
 	}
 	output += subTree
 
-	return map[string]any{
-		"path": output,
-	}, nil
+	lines, next, err := Paginate(strings.Split(strings.TrimSuffix(output, "\n"), "\n"), args, treePageSize)
+	if err != nil {
+		return map[string]any{
+			"success": false,
+			"error":   err.Error(),
+		}, err
+	}
+	return setNextCursor(map[string]any{
+		"path": strings.Join(lines, "\n") + "\n",
+	}, next), nil
 }
 
 func subTree(path string, prefix string, excludeList []string) (string, error) {
@@ -3,11 +3,11 @@ package tools
 import (
 	"fmt"
 
-	"github.com/google/generative-ai-go/genai"
+	"google.golang.org/genai"
 )
 
 func RunGeminiTool(toolName string, args map[string]any) (any, error) {
-	tool, ok := toolMap[toolName]
+	tool, ok := lookupTool(toolName)
 	if !ok {
 		return map[string]any{
 			"success": false,
@@ -22,7 +22,17 @@ func RunGeminiTool(toolName string, args map[string]any) (any, error) {
 }
 
 func GetGeminiTool(name string) (*genai.Tool, error) {
-	tool, ok := toolMap[name]
+	schema, err := CachedSchema(name, "gemini", func() (any, error) {
+		return buildGeminiTool(name)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return schema.(*genai.Tool), nil
+}
+
+func buildGeminiTool(name string) (*genai.Tool, error) {
+	tool, ok := lookupTool(name)
 	if !ok {
 		return nil, fmt.Errorf("tool not found: %s", name)
 	}
@@ -78,6 +88,16 @@ func paramToGenaiSchema(param Parameter) *genai.Schema {
 			Type:        genai.TypeBoolean,
 			Description: param.Description,
 		}
+	case "integer":
+		return &genai.Schema{
+			Type:        genai.TypeInteger,
+			Description: param.Description,
+		}
+	case "number":
+		return &genai.Schema{
+			Type:        genai.TypeNumber,
+			Description: param.Description,
+		}
 	}
 	return nil
 }
@@ -35,7 +35,7 @@ func ApplyPatch(args map[string]any) (map[string]any, error) {
 			"error":   fmt.Sprintf("expected string: %v", args["patch"]),
 		}, fmt.Errorf("expected string: %v", args["patch"])
 	}
-	path, ok := args["basePath"].(string)
+	path, ok := repoPath(args)
 	if !ok {
 		return map[string]any{
 			"success": false,
@@ -115,7 +115,7 @@ var revertFileTool = Tool{
 }
 
 func RevertFileWrapper(args map[string]any) (map[string]any, error) {
-	path, ok := args["basePath"].(string)
+	path, ok := repoPath(args)
 	if !ok {
 		return map[string]any{
 			"success": false,
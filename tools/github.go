@@ -4,7 +4,6 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"os"
 	"strconv"
 	"strings"
 
@@ -13,7 +12,7 @@ import (
 )
 
 const (
-	// Environment variable name for GitHub token
+	// Credential name for the GitHub token
 	GithubTokenEnv = "GITHUB_TOKEN"
 )
 
@@ -26,18 +25,22 @@ var githubTools = map[string]Tool{
 	"getInvolvedIssues":   getInvolvedIssuesTool,
 }
 
-// getGitHubToken gets the GitHub token from environment variable
-func getGitHubToken() (string, error) {
-	token := os.Getenv(GithubTokenEnv)
+// getGitHubToken gets the GitHub token from the credentials provider
+func getGitHubToken(args map[string]any) (string, error) {
+	creds := credentialsFor(args)
+	token := creds.get(GithubTokenEnv)
+	if creds.err != nil {
+		return "", creds.err
+	}
 	if token == "" {
-		return "", fmt.Errorf("GitHub token not found in environment variable %s", GithubTokenEnv)
+		return "", fmt.Errorf("GitHub token not found in credential %s", GithubTokenEnv)
 	}
 	return token, nil
 }
 
-// getGitHubClient creates a new GitHub client using the token from environment
-func getGitHubClient() (*github.Client, error) {
-	token, err := getGitHubToken()
+// getGitHubClient creates a new GitHub client using the token from the credentials provider
+func getGitHubClient(args map[string]any) (*github.Client, error) {
+	token, err := getGitHubToken(args)
 	if err != nil {
 		return nil, err
 	}
@@ -67,22 +70,28 @@ var getPullRequestsTool = Tool{
 			Required:    false,
 		},
 	},
-	Options: map[string]string{},
-	Run:     GetPullRequests,
+	Options:   map[string]string{},
+	Run:       GetPullRequests,
+	Paginated: true,
 }
 
 func GetPullRequests(args map[string]any) (map[string]any, error) {
 	user := args["user"].(string)
 	repo, hasRepo := args["repository"].(string)
 
-	client, err := getGitHubClient()
+	client, err := getGitHubClient(args)
+	if err != nil {
+		return nil, err
+	}
+
+	page, err := githubPage(args)
 	if err != nil {
 		return nil, err
 	}
 
 	ctx := context.Background()
 	opts := &github.SearchOptions{
-		ListOptions: github.ListOptions{PerPage: 100},
+		ListOptions: github.ListOptions{PerPage: 100, Page: page},
 	}
 
 	query := fmt.Sprintf("involves:%s is:pr", user)
@@ -90,7 +99,7 @@ func GetPullRequests(args map[string]any) (map[string]any, error) {
 		query += fmt.Sprintf(" repo:%s", repo)
 	}
 
-	result, _, err := client.Search.Issues(ctx, query, opts)
+	result, resp, err := client.Search.Issues(ctx, query, opts)
 	if err != nil {
 		return nil, fmt.Errorf("failed to search pull requests: %w", err)
 	}
@@ -114,13 +123,13 @@ func GetPullRequests(args map[string]any) (map[string]any, error) {
 	}
 
 	if DEBUG {
-		fmt.Printf("called getPullRequests with %s\nFound %d pull requests\nInfo: %s\n", user, result.GetTotal(), string(marshaled))
+		Logger().Info("Called getPullRequests", "user", user, "found", result.GetTotal(), "result", string(marshaled))
 	}
 
-	return map[string]any{
+	return setNextCursor(map[string]any{
 		"pullRequests": string(marshaled),
 		"total":        result.GetTotal(),
-	}, nil
+	}, githubNextCursor(resp)), nil
 }
 
 var getAssignedPRsTool = Tool{
@@ -140,22 +149,28 @@ var getAssignedPRsTool = Tool{
 			Required:    false,
 		},
 	},
-	Options: map[string]string{},
-	Run:     GetAssignedPRs,
+	Options:   map[string]string{},
+	Run:       GetAssignedPRs,
+	Paginated: true,
 }
 
 func GetAssignedPRs(args map[string]any) (map[string]any, error) {
 	user := args["user"].(string)
 	repo, hasRepo := args["repository"].(string)
 
-	client, err := getGitHubClient()
+	client, err := getGitHubClient(args)
+	if err != nil {
+		return nil, err
+	}
+
+	page, err := githubPage(args)
 	if err != nil {
 		return nil, err
 	}
 
 	ctx := context.Background()
 	opts := &github.SearchOptions{
-		ListOptions: github.ListOptions{PerPage: 100},
+		ListOptions: github.ListOptions{PerPage: 100, Page: page},
 	}
 
 	query := fmt.Sprintf("assignee:%s is:pr", user)
@@ -163,7 +178,7 @@ func GetAssignedPRs(args map[string]any) (map[string]any, error) {
 		query += fmt.Sprintf(" repo:%s", repo)
 	}
 
-	result, _, err := client.Search.Issues(ctx, query, opts)
+	result, resp, err := client.Search.Issues(ctx, query, opts)
 	if err != nil {
 		return nil, fmt.Errorf("failed to search assigned pull requests: %w", err)
 	}
@@ -187,13 +202,13 @@ func GetAssignedPRs(args map[string]any) (map[string]any, error) {
 	}
 
 	if DEBUG {
-		fmt.Printf("called getAssignedPRs with %s\nFound %d pull requests\nInfo: %s\n", user, result.GetTotal(), string(marshaled))
+		Logger().Info("Called getAssignedPRs", "user", user, "found", result.GetTotal(), "result", string(marshaled))
 	}
 
-	return map[string]any{
+	return setNextCursor(map[string]any{
 		"pullRequests": string(marshaled),
 		"total":        result.GetTotal(),
-	}, nil
+	}, githubNextCursor(resp)), nil
 }
 
 var getUserReposTool = Tool{
@@ -207,24 +222,30 @@ var getUserReposTool = Tool{
 			Required:    true,
 		},
 	},
-	Options: map[string]string{},
-	Run:     GetUserRepos,
+	Options:   map[string]string{},
+	Run:       GetUserRepos,
+	Paginated: true,
 }
 
 func GetUserRepos(args map[string]any) (map[string]any, error) {
 	user := args["user"].(string)
 
-	client, err := getGitHubClient()
+	client, err := getGitHubClient(args)
+	if err != nil {
+		return nil, err
+	}
+
+	page, err := githubPage(args)
 	if err != nil {
 		return nil, err
 	}
 
 	ctx := context.Background()
 	opts := &github.RepositoryListByUserOptions{
-		ListOptions: github.ListOptions{PerPage: 100},
+		ListOptions: github.ListOptions{PerPage: 100, Page: page},
 	}
 
-	repos, _, err := client.Repositories.ListByUser(ctx, user, opts)
+	repos, resp, err := client.Repositories.ListByUser(ctx, user, opts)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list user repositories: %w", err)
 	}
@@ -248,13 +269,13 @@ func GetUserRepos(args map[string]any) (map[string]any, error) {
 		return nil, fmt.Errorf("failed to marshal repository list: %w", err)
 	}
 	if DEBUG {
-		fmt.Printf("called getUserRepos with %s\nFound %d repositories\nInfo: %s\n", user, len(repoList), string(marshaled))
+		Logger().Info("Called getUserRepos", "user", user, "found", len(repoList), "result", string(marshaled))
 	}
 
-	return map[string]any{
+	return setNextCursor(map[string]any{
 		"repositories": string(marshaled),
 		"total":        len(repoList),
-	}, nil
+	}, githubNextCursor(resp)), nil
 }
 
 var getContributedReposTool = Tool{
@@ -268,25 +289,31 @@ var getContributedReposTool = Tool{
 			Required:    true,
 		},
 	},
-	Options: map[string]string{},
-	Run:     GetContributedRepos,
+	Options:   map[string]string{},
+	Run:       GetContributedRepos,
+	Paginated: true,
 }
 
 func GetContributedRepos(args map[string]any) (map[string]any, error) {
 	user := args["user"].(string)
 
-	client, err := getGitHubClient()
+	client, err := getGitHubClient(args)
+	if err != nil {
+		return nil, err
+	}
+
+	page, err := githubPage(args)
 	if err != nil {
 		return nil, err
 	}
 
 	ctx := context.Background()
 	opts := &github.SearchOptions{
-		ListOptions: github.ListOptions{PerPage: 100},
+		ListOptions: github.ListOptions{PerPage: 100, Page: page},
 	}
 
 	query := fmt.Sprintf("author:%s", user)
-	result, _, err := client.Search.Repositories(ctx, query, opts)
+	result, resp, err := client.Search.Repositories(ctx, query, opts)
 	if err != nil {
 		return nil, fmt.Errorf("failed to search contributed repositories: %w", err)
 	}
@@ -311,13 +338,13 @@ func GetContributedRepos(args map[string]any) (map[string]any, error) {
 		return nil, fmt.Errorf("failed to marshal repository list: %w", err)
 	}
 	if DEBUG {
-		fmt.Printf("called getContributedRepos with %s\nFound %d repositories\nInfo: %s\n", user, result.GetTotal(), string(marshaled))
+		Logger().Info("Called getContributedRepos", "user", user, "found", result.GetTotal(), "result", string(marshaled))
 	}
 
-	return map[string]any{
+	return setNextCursor(map[string]any{
 		"repositories": string(marshaled),
 		"total":        result.GetTotal(),
-	}, nil
+	}, githubNextCursor(resp)), nil
 }
 
 var getAssignedIssuesTool = Tool{
@@ -337,22 +364,28 @@ var getAssignedIssuesTool = Tool{
 			Required:    false,
 		},
 	},
-	Options: map[string]string{},
-	Run:     GetAssignedIssues,
+	Options:   map[string]string{},
+	Run:       GetAssignedIssues,
+	Paginated: true,
 }
 
 func GetAssignedIssues(args map[string]any) (map[string]any, error) {
 	user := args["user"].(string)
 	repo, hasRepo := args["repository"].(string)
 
-	client, err := getGitHubClient()
+	client, err := getGitHubClient(args)
+	if err != nil {
+		return nil, err
+	}
+
+	page, err := githubPage(args)
 	if err != nil {
 		return nil, err
 	}
 
 	ctx := context.Background()
 	opts := &github.SearchOptions{
-		ListOptions: github.ListOptions{PerPage: 100},
+		ListOptions: github.ListOptions{PerPage: 100, Page: page},
 	}
 
 	query := fmt.Sprintf("assignee:%s is:issue", user)
@@ -360,7 +393,7 @@ func GetAssignedIssues(args map[string]any) (map[string]any, error) {
 		query += fmt.Sprintf(" repo:%s", repo)
 	}
 
-	result, _, err := client.Search.Issues(ctx, query, opts)
+	result, resp, err := client.Search.Issues(ctx, query, opts)
 	if err != nil {
 		return nil, fmt.Errorf("failed to search assigned issues: %w", err)
 	}
@@ -384,13 +417,13 @@ func GetAssignedIssues(args map[string]any) (map[string]any, error) {
 	}
 
 	if DEBUG {
-		fmt.Printf("called getAssignedIssues with %s\nFound %d issues\nInfo: %s\n", user, result.GetTotal(), string(marshaled))
+		Logger().Info("Called getAssignedIssues", "user", user, "found", result.GetTotal(), "result", string(marshaled))
 	}
 
-	return map[string]any{
+	return setNextCursor(map[string]any{
 		"issues": string(marshaled),
 		"total":  result.GetTotal(),
-	}, nil
+	}, githubNextCursor(resp)), nil
 }
 
 var getInvolvedIssuesTool = Tool{
@@ -410,22 +443,28 @@ var getInvolvedIssuesTool = Tool{
 			Required:    false,
 		},
 	},
-	Options: map[string]string{},
-	Run:     GetInvolvedIssues,
+	Options:   map[string]string{},
+	Run:       GetInvolvedIssues,
+	Paginated: true,
 }
 
 func GetInvolvedIssues(args map[string]any) (map[string]any, error) {
 	user := args["user"].(string)
 	repo, hasRepo := args["repository"].(string)
 
-	client, err := getGitHubClient()
+	client, err := getGitHubClient(args)
+	if err != nil {
+		return nil, err
+	}
+
+	page, err := githubPage(args)
 	if err != nil {
 		return nil, err
 	}
 
 	ctx := context.Background()
 	opts := &github.SearchOptions{
-		ListOptions: github.ListOptions{PerPage: 100},
+		ListOptions: github.ListOptions{PerPage: 100, Page: page},
 	}
 
 	query := fmt.Sprintf("involves:%s is:issue", user)
@@ -433,7 +472,7 @@ func GetInvolvedIssues(args map[string]any) (map[string]any, error) {
 		query += fmt.Sprintf(" repo:%s", repo)
 	}
 
-	result, _, err := client.Search.Issues(ctx, query, opts)
+	result, resp, err := client.Search.Issues(ctx, query, opts)
 	if err != nil {
 		return nil, fmt.Errorf("failed to search involved issues: %w", err)
 	}
@@ -457,11 +496,24 @@ func GetInvolvedIssues(args map[string]any) (map[string]any, error) {
 	}
 
 	if DEBUG {
-		fmt.Printf("called getInvolvedIssues with %s\nFound %d issues\nInfo: %s\n", user, result.GetTotal(), string(marshaled))
+		Logger().Info("Called getInvolvedIssues", "user", user, "found", result.GetTotal(), "result", string(marshaled))
 	}
 
-	return map[string]any{
+	return setNextCursor(map[string]any{
 		"issues": string(marshaled),
 		"total":  result.GetTotal(),
-	}, nil
+	}, githubNextCursor(resp)), nil
+}
+
+// githubPage reads the page number from the cursor argument
+func githubPage(args map[string]any) (int, error) {
+	return cursorOffset(args)
+}
+
+// githubNextCursor returns the cursor of the next page, empty on the last page
+func githubNextCursor(resp *github.Response) string {
+	if resp == nil || resp.NextPage == 0 {
+		return ""
+	}
+	return strconv.Itoa(resp.NextPage)
 }
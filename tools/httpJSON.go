@@ -0,0 +1,59 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// httpClient is shared by the tools that call JSON APIs
+var httpClient = &http.Client{Timeout: 30 * time.Second}
+
+// postJSON posts body and decodes the response into out when it is not nil
+func postJSON(ctx context.Context, url string, authorization string, body any, out any) error {
+	return requestJSON(ctx, http.MethodPost, url, authorization, body, out)
+}
+
+// requestJSON sends body as JSON, or no body when it is nil, and decodes the response
+// into out when it is not nil
+func requestJSON(ctx context.Context, method string, url string, authorization string, body any, out any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	}
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4<<20))
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("status code %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody[:min(len(respBody), 1024)])))
+	}
+	if out != nil && len(respBody) > 0 {
+		if err := json.Unmarshal(respBody, out); err != nil {
+			return fmt.Errorf("failed to decode response: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,209 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	IngestFileToolName = "ingest_file"
+
+	defaultChunkSize    = 2000
+	defaultChunkOverlap = 200
+	defaultNamespace    = "default"
+	// ingestBatchSize is the number of chunks embedded per provider call
+	ingestBatchSize = 32
+)
+
+var ingestTools = map[string]Tool{
+	IngestFileToolName: ingestFileTool,
+}
+
+var ingestFileTool = Tool{
+	Name:        IngestFileToolName,
+	Description: "Read a file, split it into overlapping chunks and store each chunk as a memory so it can be found with memory_retrieve. Each chunk's metadata holds the namespace, file and byte offset, filter on {\"namespace\": ...} to search only ingested documents.",
+	Parameters: []Parameter{
+		{
+			Name:        "path",
+			Type:        "string",
+			Description: "The path to the file to ingest",
+			Required:    true,
+		},
+		{
+			Name:        "namespace",
+			Type:        "string",
+			Description: "The memory namespace to store the chunks in, defaults to \"default\"",
+			Required:    false,
+		},
+		{
+			Name:        "chunk_size",
+			Type:        "integer",
+			Description: "The maximum chunk size in bytes, defaults to 2000",
+			Required:    false,
+		},
+		{
+			Name:        "overlap",
+			Type:        "integer",
+			Description: "The number of bytes shared by consecutive chunks, defaults to 200",
+			Required:    false,
+		},
+	},
+	Options: map[string]string{
+		"basePath": ".",
+	},
+	Run: IngestFile,
+}
+
+// Chunk is a slice of a file and its byte offset
+type Chunk struct {
+	Offset  int
+	Content string
+}
+
+func IngestFile(args map[string]any) (map[string]any, error) {
+	if globalMemoryTool == nil {
+		return map[string]any{
+			"success": false,
+			"error":   "memory tool not initialized",
+		}, fmt.Errorf("memory tool not initialized")
+	}
+	path, ok := args["path"].(string)
+	if !ok {
+		return map[string]any{
+			"success": false,
+			"error":   fmt.Sprintf("expected string: %v", args["path"]),
+		}, fmt.Errorf("expected string: %v", args["path"])
+	}
+	namespace, ok := args["namespace"].(string)
+	if !ok || namespace == "" {
+		namespace = defaultNamespace
+	}
+	chunkSize := intArg(args["chunk_size"], defaultChunkSize)
+	overlap := intArg(args["overlap"], defaultChunkOverlap)
+	if chunkSize <= 0 || overlap < 0 || overlap >= chunkSize {
+		err := fmt.Errorf("invalid chunk_size %d and overlap %d, overlap must be smaller than chunk_size", chunkSize, overlap)
+		return map[string]any{
+			"success": false,
+			"error":   err.Error(),
+		}, err
+	}
+
+	basePath, _ := args["basePath"].(string)
+	p, err := sandboxPath(basePath, path)
+	if err != nil {
+		return map[string]any{
+			"success": false,
+			"error":   err.Error(),
+		}, err
+	}
+	content, err := os.ReadFile(p)
+	if err != nil {
+		return map[string]any{
+			"success": false,
+			"error":   fmt.Sprintf("failed to read file: %s", err.Error()),
+		}, fmt.Errorf("failed to read file: %w", err)
+	}
+	if !utf8.Valid(content) {
+		return map[string]any{
+			"success": false,
+			"error":   "file is not valid UTF-8 text",
+		}, fmt.Errorf("file %s is not valid UTF-8 text", path)
+	}
+
+	chunks := ChunkText(string(content), chunkSize, overlap)
+	var ids []string
+	for start := 0; start < len(chunks); start += ingestBatchSize {
+		batch := chunks[start:min(start+ingestBatchSize, len(chunks))]
+		contents := make([]string, len(batch))
+		metadata := make([]map[string]interface{}, len(batch))
+		for i, chunk := range batch {
+			contents[i] = chunk.Content
+			metadata[i] = map[string]interface{}{
+				"namespace": namespace,
+				"file":      path,
+				"offset":    chunk.Offset,
+				"length":    len(chunk.Content),
+				"chunk":     start + i,
+			}
+		}
+		batchIDs, err := globalMemoryTool.StoreBatch(context.Background(), contents, metadata)
+		ids = append(ids, batchIDs...)
+		if err != nil {
+			return map[string]any{
+				"success": false,
+				"error":   fmt.Sprintf("stored %d of %d chunks: %s", len(ids), len(chunks), err.Error()),
+				"ids":     ids,
+			}, fmt.Errorf("failed to store chunks: %w", err)
+		}
+	}
+	return map[string]any{
+		"success":   true,
+		"namespace": namespace,
+		"file":      path,
+		"chunks":    len(chunks),
+		"ids":       ids,
+	}, nil
+}
+
+// ChunkText splits text into chunks of at most size bytes where consecutive chunks
+// share overlap bytes. Chunks end at a line break when one is found in the second
+// half of the chunk and never split a UTF-8 character.
+func ChunkText(text string, size int, overlap int) []Chunk {
+	var chunks []Chunk
+	for offset := 0; offset < len(text); {
+		end := min(offset+size, len(text))
+		if end < len(text) {
+			for end > offset && !utf8.RuneStart(text[end]) {
+				end--
+			}
+			if nl := strings.LastIndexByte(text[offset:end], '\n'); nl >= size/2 {
+				end = offset + nl + 1
+			}
+		}
+		chunks = append(chunks, Chunk{Offset: offset, Content: text[offset:end]})
+		if end == len(text) {
+			break
+		}
+		next := max(end-overlap, offset+1)
+		for next < end && !utf8.RuneStart(text[next]) {
+			next++
+		}
+		offset = next
+	}
+	return chunks
+}
+
+// sandboxPath resolves path under basePath and rejects paths that escape it
+func sandboxPath(basePath string, path string) (string, error) {
+	p, err := handlePaths(basePath, path)
+	if err != nil {
+		return "", err
+	}
+	if basePath == "" {
+		basePath = "."
+	}
+	base, err := filepath.Abs(basePath)
+	if err != nil {
+		return "", fmt.Errorf("error resolving base path: %w", err)
+	}
+	rel, err := filepath.Rel(base, p)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("path %s is outside of %s", path, basePath)
+	}
+	return p, nil
+}
+
+// intArg reads an integer argument, JSON numbers are decoded as float64
+func intArg(value any, fallback int) int {
+	switch v := value.(type) {
+	case int:
+		return v
+	case float64:
+		return int(v)
+	}
+	return fallback
+}
@@ -0,0 +1,418 @@
+package tools
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// Credentials for Jira. With JIRA_EMAIL the token is a Jira Cloud API token,
+	// without it a Data Center personal access token.
+	JiraURLEnv   = "JIRA_URL"
+	JiraEmailEnv = "JIRA_EMAIL"
+	JiraTokenEnv = "JIRA_API_TOKEN"
+
+	jiraPageSize = 50
+)
+
+var jiraTools = map[string]Tool{
+	"searchJiraIssues": searchJiraIssuesTool,
+	"getJiraIssue":     getJiraIssueTool,
+	"createJiraIssue":  createJiraIssueTool,
+	"commentJiraIssue": commentJiraIssueTool,
+}
+
+type jiraClient struct {
+	baseURL       string
+	authorization string
+	cloud         bool
+}
+
+// getJiraClient creates a Jira client from the credentials of the scope in args
+func getJiraClient(args map[string]any) (*jiraClient, error) {
+	creds := credentialsFor(args)
+	baseURL := strings.TrimRight(creds.get(JiraURLEnv), "/")
+	token := creds.get(JiraTokenEnv)
+	email := creds.get(JiraEmailEnv)
+	if creds.err != nil {
+		return nil, creds.err
+	}
+	if baseURL == "" || token == "" {
+		return nil, fmt.Errorf("Jira is not configured, set %s and %s", JiraURLEnv, JiraTokenEnv)
+	}
+	if email != "" {
+		return &jiraClient{
+			baseURL:       baseURL,
+			authorization: "Basic " + base64.StdEncoding.EncodeToString([]byte(email+":"+token)),
+			cloud:         true,
+		}, nil
+	}
+	return &jiraClient{baseURL: baseURL, authorization: "Bearer " + token}, nil
+}
+
+// do calls the version 2 REST API, which takes plain text descriptions and comments
+func (c *jiraClient) do(method string, path string, body any, out any) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	return requestJSON(ctx, method, c.baseURL+"/rest/api/2"+path, c.authorization, body, out)
+}
+
+type jiraIssue struct {
+	Key    string `json:"key"`
+	Fields struct {
+		Summary     string `json:"summary"`
+		Description string `json:"description"`
+		Status      struct {
+			Name string `json:"name"`
+		} `json:"status"`
+		IssueType struct {
+			Name string `json:"name"`
+		} `json:"issuetype"`
+		Priority *struct {
+			Name string `json:"name"`
+		} `json:"priority"`
+		Assignee *jiraUser `json:"assignee"`
+		Reporter *jiraUser `json:"reporter"`
+		Labels   []string  `json:"labels"`
+		Created  string    `json:"created"`
+		Updated  string    `json:"updated"`
+		Comment  *struct {
+			Comments []struct {
+				Author  *jiraUser `json:"author"`
+				Body    string    `json:"body"`
+				Created string    `json:"created"`
+			} `json:"comments"`
+		} `json:"comment"`
+	} `json:"fields"`
+}
+
+type jiraUser struct {
+	DisplayName string `json:"displayName"`
+}
+
+func (u *jiraUser) name() string {
+	if u == nil {
+		return ""
+	}
+	return u.DisplayName
+}
+
+// summary returns the fields listed by searches, in the shape of the GitHub issue tools
+func (c *jiraClient) summary(issue jiraIssue) map[string]string {
+	return map[string]string{
+		"key":       issue.Key,
+		"title":     issue.Fields.Summary,
+		"state":     issue.Fields.Status.Name,
+		"type":      issue.Fields.IssueType.Name,
+		"assignee":  issue.Fields.Assignee.name(),
+		"url":       c.baseURL + "/browse/" + issue.Key,
+		"createdAt": issue.Fields.Created,
+		"updatedAt": issue.Fields.Updated,
+	}
+}
+
+var searchJiraIssuesTool = Tool{
+	Name:        "searchJiraIssues",
+	Description: "Search Jira issues with JQL, for example assignee = currentUser() AND resolution = Unresolved",
+	Parameters: []Parameter{
+		{
+			Name:        "jql",
+			Type:        "string",
+			Description: "The JQL query",
+			Required:    true,
+		},
+	},
+	Options:   map[string]string{},
+	Run:       SearchJiraIssues,
+	Paginated: true,
+}
+
+func SearchJiraIssues(args map[string]any) (map[string]any, error) {
+	jql, _ := args["jql"].(string)
+	if jql == "" {
+		err := NewToolError(fmt.Errorf("jql is required"), "", true)
+		return map[string]any{
+			"success": false,
+			"error":   err.Error(),
+		}, err
+	}
+	client, err := getJiraClient(args)
+	if err != nil {
+		return map[string]any{
+			"success": false,
+			"error":   err.Error(),
+		}, err
+	}
+	cursor, _ := args[CursorArg].(string)
+	query := url.Values{
+		"jql":        {jql},
+		"maxResults": {strconv.Itoa(jiraPageSize)},
+		"fields":     {"summary,status,issuetype,assignee,created,updated"},
+	}
+	var result struct {
+		Issues        []jiraIssue `json:"issues"`
+		Total         int         `json:"total"`
+		StartAt       int         `json:"startAt"`
+		NextPageToken string      `json:"nextPageToken"`
+	}
+	// Jira Cloud pages searches with a token, Data Center with an offset
+	path := "/search/jql?"
+	if client.cloud {
+		if cursor != "" {
+			query.Set("nextPageToken", cursor)
+		}
+	} else {
+		path = "/search?"
+		offset, err := cursorOffset(args)
+		if err != nil {
+			return map[string]any{
+				"success": false,
+				"error":   err.Error(),
+			}, err
+		}
+		query.Set("startAt", strconv.Itoa(offset))
+	}
+	if err := client.do(http.MethodGet, path+query.Encode(), nil, &result); err != nil {
+		err = fmt.Errorf("failed to search issues: %w", err)
+		return map[string]any{
+			"success": false,
+			"error":   err.Error(),
+		}, err
+	}
+	issues := make([]map[string]string, len(result.Issues))
+	for i, issue := range result.Issues {
+		issues[i] = client.summary(issue)
+	}
+	marshaled, err := json.Marshal(issues)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal issues: %w", err)
+	}
+	if DEBUG {
+		Logger().Info("Called searchJiraIssues", "jql", jql, "found", len(issues), "result", string(marshaled))
+	}
+	next := result.NextPageToken
+	if !client.cloud {
+		next = ""
+		if end := result.StartAt + len(result.Issues); len(result.Issues) > 0 && end < result.Total {
+			next = strconv.Itoa(end)
+		}
+	}
+	return setNextCursor(map[string]any{
+		"success": true,
+		"issues":  string(marshaled),
+	}, next), nil
+}
+
+var getJiraIssueTool = Tool{
+	Name:        "getJiraIssue",
+	Description: "Get a Jira issue with its description and comments",
+	Parameters: []Parameter{
+		{
+			Name:        "key",
+			Type:        "string",
+			Description: "The issue key, for example PROJ-123",
+			Required:    true,
+		},
+	},
+	Options: map[string]string{},
+	Run:     GetJiraIssue,
+}
+
+func GetJiraIssue(args map[string]any) (map[string]any, error) {
+	key, _ := args["key"].(string)
+	client, err := getJiraClient(args)
+	if err == nil && key == "" {
+		err = NewToolError(fmt.Errorf("key is required"), "", true)
+	}
+	if err != nil {
+		return map[string]any{
+			"success": false,
+			"error":   err.Error(),
+		}, err
+	}
+	var issue jiraIssue
+	fields := "summary,description,status,issuetype,priority,assignee,reporter,labels,created,updated,comment"
+	if err := client.do(http.MethodGet, "/issue/"+url.PathEscape(key)+"?fields="+fields, nil, &issue); err != nil {
+		err = fmt.Errorf("failed to get issue %s: %w", key, err)
+		return map[string]any{
+			"success": false,
+			"error":   err.Error(),
+		}, err
+	}
+	result := map[string]any{"success": true}
+	for k, v := range client.summary(issue) {
+		result[k] = v
+	}
+	result["description"] = issue.Fields.Description
+	result["reporter"] = issue.Fields.Reporter.name()
+	result["labels"] = issue.Fields.Labels
+	if issue.Fields.Priority != nil {
+		result["priority"] = issue.Fields.Priority.Name
+	}
+	comments := []map[string]string{}
+	if issue.Fields.Comment != nil {
+		for _, comment := range issue.Fields.Comment.Comments {
+			comments = append(comments, map[string]string{
+				"author":    comment.Author.name(),
+				"body":      comment.Body,
+				"createdAt": comment.Created,
+			})
+		}
+	}
+	result["comments"] = comments
+	return result, nil
+}
+
+var createJiraIssueTool = Tool{
+	Name:        "createJiraIssue",
+	Description: "Create a Jira issue",
+	Parameters: []Parameter{
+		{
+			Name:        "project",
+			Type:        "string",
+			Description: "The project key, for example PROJ",
+			Required:    true,
+		},
+		{
+			Name:        "title",
+			Type:        "string",
+			Description: "The issue summary",
+			Required:    true,
+		},
+		{
+			Name:        "description",
+			Type:        "string",
+			Description: "The issue description",
+			Required:    false,
+		},
+		{
+			Name:        "type",
+			Type:        "string",
+			Description: "The issue type, defaults to Task",
+			Required:    false,
+		},
+		{
+			Name:        "labels",
+			Type:        "stringArray",
+			Description: "Labels to add to the issue",
+			Required:    false,
+		},
+	},
+	Options: map[string]string{},
+	Run:     CreateJiraIssue,
+}
+
+type createJiraIssueArgs struct {
+	Project     string   `json:"project"`
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	Type        string   `json:"type"`
+	Labels      []string `json:"labels"`
+}
+
+func CreateJiraIssue(args map[string]any) (map[string]any, error) {
+	typed, err := DecodeArgs[createJiraIssueArgs](args)
+	if err == nil && (typed.Project == "" || typed.Title == "") {
+		err = NewToolError(fmt.Errorf("project and title are required"), "", true)
+	}
+	if err != nil {
+		return map[string]any{
+			"success": false,
+			"error":   err.Error(),
+		}, err
+	}
+	client, err := getJiraClient(args)
+	if err != nil {
+		return map[string]any{
+			"success": false,
+			"error":   err.Error(),
+		}, err
+	}
+	if typed.Type == "" {
+		typed.Type = "Task"
+	}
+	fields := map[string]any{
+		"project":   map[string]string{"key": typed.Project},
+		"summary":   typed.Title,
+		"issuetype": map[string]string{"name": typed.Type},
+	}
+	if typed.Description != "" {
+		fields["description"] = typed.Description
+	}
+	if len(typed.Labels) > 0 {
+		fields["labels"] = typed.Labels
+	}
+	var created struct {
+		Key string `json:"key"`
+	}
+	if err := client.do(http.MethodPost, "/issue", map[string]any{"fields": fields}, &created); err != nil {
+		err = NewToolError(fmt.Errorf("failed to create issue: %w", err), "Check the project key and issue type.", false)
+		return map[string]any{
+			"success": false,
+			"error":   err.Error(),
+		}, err
+	}
+	return map[string]any{
+		"success": true,
+		"key":     created.Key,
+		"url":     client.baseURL + "/browse/" + created.Key,
+	}, nil
+}
+
+var commentJiraIssueTool = Tool{
+	Name:        "commentJiraIssue",
+	Description: "Add a comment to a Jira issue",
+	Parameters: []Parameter{
+		{
+			Name:        "key",
+			Type:        "string",
+			Description: "The issue key, for example PROJ-123",
+			Required:    true,
+		},
+		{
+			Name:        "body",
+			Type:        "string",
+			Description: "The comment",
+			Required:    true,
+		},
+	},
+	Options: map[string]string{},
+	Run:     CommentJiraIssue,
+}
+
+func CommentJiraIssue(args map[string]any) (map[string]any, error) {
+	key, _ := args["key"].(string)
+	body, _ := args["body"].(string)
+	client, err := getJiraClient(args)
+	if err == nil && (key == "" || body == "") {
+		err = NewToolError(fmt.Errorf("key and body are required"), "", true)
+	}
+	if err != nil {
+		return map[string]any{
+			"success": false,
+			"error":   err.Error(),
+		}, err
+	}
+	var comment struct {
+		ID string `json:"id"`
+	}
+	if err := client.do(http.MethodPost, "/issue/"+url.PathEscape(key)+"/comment", map[string]string{"body": body}, &comment); err != nil {
+		err = fmt.Errorf("failed to comment on issue %s: %w", key, err)
+		return map[string]any{
+			"success": false,
+			"error":   err.Error(),
+		}, err
+	}
+	return map[string]any{
+		"success": true,
+		"key":     key,
+		"id":      comment.ID,
+	}, nil
+}
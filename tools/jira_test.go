@@ -0,0 +1,140 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestJiraCloud(t *testing.T) {
+	var requests []string
+	var created map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Cloud API tokens are sent as basic auth with the account's email
+		if user, token, _ := r.BasicAuth(); user != "ana@example.com" || token != "cloud-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		requests = append(requests, r.Method+" "+r.URL.RequestURI())
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/rest/api/2/search/jql":
+			if r.URL.Query().Get("nextPageToken") == "" {
+				fmt.Fprint(w, `{"issues":[{"key":"OPS-7","fields":{"summary":"Rotate keys","status":{"name":"To Do"},"issuetype":{"name":"Task"},"assignee":{"displayName":"Ana"}}}],"nextPageToken":"tok-2"}`)
+				return
+			}
+			fmt.Fprint(w, `{"issues":[]}`)
+		case "/rest/api/2/issue/OPS-7":
+			fmt.Fprint(w, `{"key":"OPS-7","fields":{"summary":"Rotate keys","description":"Every 90 days","status":{"name":"To Do"},"issuetype":{"name":"Task"},
+				"priority":{"name":"High"},"reporter":{"displayName":"Ben"},"labels":["security"],
+				"comment":{"comments":[{"author":{"displayName":"Ana"},"body":"On it","created":"2024-01-15T10:00:00.000+0000"}]}}}`)
+		case "/rest/api/2/issue":
+			json.NewDecoder(r.Body).Decode(&created)
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprint(w, `{"id":"10001","key":"OPS-8"}`)
+		case "/rest/api/2/issue/OPS-7/comment":
+			var comment map[string]string
+			json.NewDecoder(r.Body).Decode(&comment)
+			if comment["body"] != "Done" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprint(w, `{"id":"20001"}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"errorMessages":["Issue does not exist"]}`)
+		}
+	}))
+	defer srv.Close()
+	env := map[string]string{JiraURLEnv: srv.URL + "/", JiraEmailEnv: "ana@example.com", JiraTokenEnv: "cloud-token"}
+
+	result, err := SearchJiraIssues(map[string]any{"jql": "assignee = currentUser()", EnvArg: env})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var issues []map[string]string
+	json.Unmarshal([]byte(result["issues"].(string)), &issues)
+	if len(issues) != 1 || issues[0]["key"] != "OPS-7" || issues[0]["assignee"] != "Ana" || issues[0]["url"] != srv.URL+"/browse/OPS-7" {
+		t.Errorf("issues %v", issues)
+	}
+	if result[NextCursorKey] != "tok-2" {
+		t.Errorf("next cursor %v", result[NextCursorKey])
+	}
+	result, err = SearchJiraIssues(map[string]any{"jql": "assignee = currentUser()", CursorArg: "tok-2", EnvArg: env})
+	if err != nil || result[NextCursorKey] != nil {
+		t.Errorf("last page %v, %v", result, err)
+	}
+	if !strings.Contains(requests[0], "jql=assignee+%3D+currentUser%28%29") || !strings.Contains(requests[1], "nextPageToken=tok-2") {
+		t.Errorf("requests %v", requests)
+	}
+
+	result, err = GetJiraIssue(map[string]any{"key": "OPS-7", EnvArg: env})
+	if err != nil {
+		t.Fatal(err)
+	}
+	comments := result["comments"].([]map[string]string)
+	if result["priority"] != "High" || result["reporter"] != "Ben" || result["description"] != "Every 90 days" || len(comments) != 1 || comments[0]["author"] != "Ana" {
+		t.Errorf("issue %v", result)
+	}
+	if _, err := GetJiraIssue(map[string]any{"key": "OPS-404", EnvArg: env}); err == nil || !strings.Contains(err.Error(), "Issue does not exist") {
+		t.Errorf("missing issue error %v", err)
+	}
+
+	result, err = CreateJiraIssue(map[string]any{"project": "OPS", "title": "Renew certificates", "labels": []any{"security"}, EnvArg: env})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields := created["fields"].(map[string]any)
+	if fields["issuetype"].(map[string]any)["name"] != "Task" || fields["project"].(map[string]any)["key"] != "OPS" || fields["description"] != nil {
+		t.Errorf("created fields %v", fields)
+	}
+	if result["key"] != "OPS-8" || result["url"] != srv.URL+"/browse/OPS-8" {
+		t.Errorf("created %v", result)
+	}
+
+	result, err = CommentJiraIssue(map[string]any{"key": "OPS-7", "body": "Done", EnvArg: env})
+	if err != nil || result["id"] != "20001" {
+		t.Errorf("comment %v, %v", result, err)
+	}
+}
+
+func TestJiraDataCenter(t *testing.T) {
+	var offsets []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// personal access tokens are bearer tokens
+		if r.Header.Get("Authorization") != "Bearer pat" || r.URL.Path != "/jira/rest/api/2/search" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		offsets = append(offsets, r.URL.Query().Get("startAt"))
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"startAt":%s,"total":51,"issues":[{"key":"OPS-1","fields":{"summary":"First","status":{"name":"Done"}}}]}`, r.URL.Query().Get("startAt"))
+	}))
+	defer srv.Close()
+	env := map[string]string{JiraURLEnv: srv.URL + "/jira", JiraTokenEnv: "pat"}
+
+	result, err := SearchJiraIssues(map[string]any{"jql": "project = OPS", CursorArg: "49", EnvArg: env})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result[NextCursorKey] != "50" {
+		t.Errorf("next cursor %v, want the next offset", result[NextCursorKey])
+	}
+	result, err = SearchJiraIssues(map[string]any{"jql": "project = OPS", CursorArg: "50", EnvArg: env})
+	if err != nil || result[NextCursorKey] != nil {
+		t.Errorf("last page %v, %v", result, err)
+	}
+	if len(offsets) != 2 || offsets[0] != "49" {
+		t.Errorf("offsets %v", offsets)
+	}
+	if _, err := SearchJiraIssues(map[string]any{"jql": "project = OPS", CursorArg: "-1", EnvArg: env}); err == nil {
+		t.Error("searched with a negative cursor")
+	}
+	if _, err := SearchJiraIssues(map[string]any{"jql": "project = OPS", EnvArg: map[string]string{JiraURLEnv: srv.URL}}); err == nil || !strings.Contains(err.Error(), JiraTokenEnv) {
+		t.Errorf("unconfigured error %v", err)
+	}
+}
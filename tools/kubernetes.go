@@ -0,0 +1,512 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+const (
+	KubernetesListPodsToolName        = "kubernetes_list_pods"
+	KubernetesListDeploymentsToolName = "kubernetes_list_deployments"
+	KubernetesListEventsToolName      = "kubernetes_list_events"
+	KubernetesPodLogsToolName         = "kubernetes_pod_logs"
+
+	// Environment variables read when the Kubernetes tools are not configured.
+	// KUBECONFIG is read by the kubeconfig loader itself.
+	KubernetesContextEnv    = "KUBERNETES_CONTEXT"
+	KubernetesNamespacesEnv = "KUBERNETES_ALLOWED_NAMESPACES"
+
+	DefaultKubernetesLogLines = 200
+	DefaultKubernetesMaxLog   = 256 * 1024
+
+	kubernetesPageSize = 100
+)
+
+// KubernetesConfig configures the Kubernetes tools. The tools only read from the
+// cluster, give the credentials a role that can get, list and read logs and nothing else.
+type KubernetesConfig struct {
+	// Kubeconfig is the path of a kubeconfig file, the KUBECONFIG search path and then
+	// the in-cluster service account are used when it is empty
+	Kubeconfig string
+	// Context selects a kubeconfig context, the current context when empty
+	Context string
+	// AllowedNamespaces are the namespaces the tools can read, "*" allows all of them.
+	// When empty only the context's namespace is allowed.
+	AllowedNamespaces []string
+	// MaxLogBytes caps the logs returned by kubernetes_pod_logs
+	MaxLogBytes int64
+}
+
+type kubernetesClient struct {
+	clientset kubernetes.Interface
+	config    KubernetesConfig
+	// namespace is the context's namespace, used when no namespace is passed
+	namespace string
+}
+
+var (
+	kubeClient   *kubernetesClient
+	kubeClientMu sync.Mutex
+)
+
+// ConfigureKubernetes sets the cluster used by the Kubernetes tools. Without it the
+// kubeconfig is loaded from the environment on first use.
+func ConfigureKubernetes(config KubernetesConfig) error {
+	client, err := newKubernetesClient(config)
+	if err != nil {
+		return err
+	}
+	kubeClientMu.Lock()
+	defer kubeClientMu.Unlock()
+	kubeClient = client
+	return nil
+}
+
+func getKubernetesClient() (*kubernetesClient, error) {
+	kubeClientMu.Lock()
+	defer kubeClientMu.Unlock()
+	if kubeClient != nil {
+		return kubeClient, nil
+	}
+	config := KubernetesConfig{Context: os.Getenv(KubernetesContextEnv)}
+	for _, namespace := range strings.Split(os.Getenv(KubernetesNamespacesEnv), ",") {
+		if namespace = strings.TrimSpace(namespace); namespace != "" {
+			config.AllowedNamespaces = append(config.AllowedNamespaces, namespace)
+		}
+	}
+	client, err := newKubernetesClient(config)
+	if err != nil {
+		return nil, err
+	}
+	kubeClient = client
+	return client, nil
+}
+
+func newKubernetesClient(config KubernetesConfig) (*kubernetesClient, error) {
+	if config.MaxLogBytes <= 0 {
+		config.MaxLogBytes = DefaultKubernetesMaxLog
+	}
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	if config.Kubeconfig != "" {
+		rules.ExplicitPath = config.Kubeconfig
+	}
+	loader := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, &clientcmd.ConfigOverrides{CurrentContext: config.Context})
+	restConfig, err := loader.ClientConfig()
+	if err != nil {
+		// fall back to the service account when running in a pod without a kubeconfig
+		inCluster, inClusterErr := rest.InClusterConfig()
+		if inClusterErr != nil {
+			return nil, fmt.Errorf("failed to load kubeconfig: %w", err)
+		}
+		restConfig = inCluster
+	}
+	namespace, _, err := loader.Namespace()
+	if err != nil || namespace == "" {
+		namespace = metav1.NamespaceDefault
+	}
+	clientset, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create kubernetes client: %w", err)
+	}
+	return &kubernetesClient{clientset: clientset, config: config, namespace: namespace}, nil
+}
+
+// resolveNamespace returns the namespace to read, rejecting namespaces that are not allowed
+func (c *kubernetesClient) resolveNamespace(namespace string) (string, error) {
+	if namespace == "" {
+		namespace = c.namespace
+	}
+	allowed := c.config.AllowedNamespaces
+	if len(allowed) == 0 {
+		allowed = []string{c.namespace}
+	}
+	if slices.Contains(allowed, "*") || slices.Contains(allowed, namespace) {
+		return namespace, nil
+	}
+	return "", NewToolError(fmt.Errorf("namespace %s is not allowed", namespace), fmt.Sprintf("Use one of the allowed namespaces: %s.", strings.Join(allowed, ", ")), false)
+}
+
+var kubernetesTools = map[string]Tool{
+	KubernetesListPodsToolName:        kubernetesListPodsTool,
+	KubernetesListDeploymentsToolName: kubernetesListDeploymentsTool,
+	KubernetesListEventsToolName:      kubernetesListEventsTool,
+	KubernetesPodLogsToolName:         kubernetesPodLogsTool,
+}
+
+var kubernetesNamespaceParameter = Parameter{
+	Name:        "namespace",
+	Type:        "string",
+	Description: "The namespace, defaults to the configured namespace",
+	Required:    false,
+}
+
+var kubernetesSelectorParameter = Parameter{
+	Name:        "label_selector",
+	Type:        "string",
+	Description: "A label selector such as app=web (optional)",
+	Required:    false,
+}
+
+var kubernetesListPodsTool = Tool{
+	Name:        KubernetesListPodsToolName,
+	Description: "List the pods in a Kubernetes namespace with their phase, readiness and restarts",
+	Parameters: []Parameter{
+		kubernetesNamespaceParameter,
+		kubernetesSelectorParameter,
+	},
+	Options:   map[string]string{},
+	Run:       KubernetesListPods,
+	Paginated: true,
+}
+
+var kubernetesListDeploymentsTool = Tool{
+	Name:        KubernetesListDeploymentsToolName,
+	Description: "List the deployments in a Kubernetes namespace with their replica counts",
+	Parameters: []Parameter{
+		kubernetesNamespaceParameter,
+		kubernetesSelectorParameter,
+	},
+	Options:   map[string]string{},
+	Run:       KubernetesListDeployments,
+	Paginated: true,
+}
+
+var kubernetesListEventsTool = Tool{
+	Name:        KubernetesListEventsToolName,
+	Description: "List the events in a Kubernetes namespace, newest first, optionally for one object",
+	Parameters: []Parameter{
+		kubernetesNamespaceParameter,
+		{
+			Name:        "object",
+			Type:        "string",
+			Description: "Only list events of the object with this name (optional)",
+			Required:    false,
+		},
+		{
+			Name:        "warnings_only",
+			Type:        "boolean",
+			Description: "Only list Warning events",
+			Required:    false,
+		},
+	},
+	Options:   map[string]string{},
+	Run:       KubernetesListEvents,
+	Paginated: true,
+}
+
+var kubernetesPodLogsTool = Tool{
+	Name:        KubernetesPodLogsToolName,
+	Description: "Get the most recent log lines of a pod's container",
+	Parameters: []Parameter{
+		kubernetesNamespaceParameter,
+		{
+			Name:        "pod",
+			Type:        "string",
+			Description: "The pod name",
+			Required:    true,
+		},
+		{
+			Name:        "container",
+			Type:        "string",
+			Description: "The container, required for pods with several containers",
+			Required:    false,
+		},
+		{
+			Name:        "tail_lines",
+			Type:        "integer",
+			Description: fmt.Sprintf("The number of lines from the end of the log, defaults to %d", DefaultKubernetesLogLines),
+			Required:    false,
+		},
+		{
+			Name:        "previous",
+			Type:        "boolean",
+			Description: "Read the logs of the previous, crashed container",
+			Required:    false,
+		},
+	},
+	Options: map[string]string{},
+	Run:     KubernetesPodLogs,
+}
+
+type kubernetesListArgs struct {
+	Namespace     string `json:"namespace"`
+	LabelSelector string `json:"label_selector"`
+	Object        string `json:"object"`
+	WarningsOnly  bool   `json:"warnings_only"`
+	Cursor        string `json:"cursor"`
+}
+
+// kubernetesList decodes the arguments of a list tool and resolves its namespace
+func kubernetesList(args map[string]any) (*kubernetesClient, kubernetesListArgs, error) {
+	typed, err := DecodeArgs[kubernetesListArgs](args)
+	if err != nil {
+		return nil, typed, err
+	}
+	client, err := getKubernetesClient()
+	if err != nil {
+		return nil, typed, err
+	}
+	typed.Namespace, err = client.resolveNamespace(typed.Namespace)
+	return client, typed, err
+}
+
+func (a kubernetesListArgs) options() metav1.ListOptions {
+	return metav1.ListOptions{LabelSelector: a.LabelSelector, Limit: kubernetesPageSize, Continue: a.Cursor}
+}
+
+func KubernetesListPods(args map[string]any) (map[string]any, error) {
+	client, typed, err := kubernetesList(args)
+	if err != nil {
+		return map[string]any{
+			"success": false,
+			"error":   err.Error(),
+		}, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	list, err := client.clientset.CoreV1().Pods(typed.Namespace).List(ctx, typed.options())
+	if err != nil {
+		err = fmt.Errorf("failed to list pods: %w", err)
+		return map[string]any{
+			"success": false,
+			"error":   err.Error(),
+		}, err
+	}
+	pods := make([]map[string]any, len(list.Items))
+	for i, pod := range list.Items {
+		ready, restarts := 0, int32(0)
+		for _, status := range pod.Status.ContainerStatuses {
+			if status.Ready {
+				ready++
+			}
+			restarts += status.RestartCount
+		}
+		pods[i] = map[string]any{
+			"name":     pod.Name,
+			"phase":    string(pod.Status.Phase),
+			"ready":    fmt.Sprintf("%d/%d", ready, len(pod.Spec.Containers)),
+			"restarts": restarts,
+			"node":     pod.Spec.NodeName,
+			"age":      kubernetesAge(pod.CreationTimestamp),
+		}
+		if reason := podWaitingReason(pod); reason != "" {
+			pods[i]["reason"] = reason
+		}
+	}
+	return setNextCursor(map[string]any{
+		"success":   true,
+		"namespace": typed.Namespace,
+		"pods":      pods,
+	}, list.Continue), nil
+}
+
+// podWaitingReason returns why a container is not running, such as CrashLoopBackOff
+func podWaitingReason(pod corev1.Pod) string {
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.State.Waiting != nil && status.State.Waiting.Reason != "" {
+			return status.State.Waiting.Reason
+		}
+		if status.State.Terminated != nil && status.State.Terminated.Reason != "" {
+			return status.State.Terminated.Reason
+		}
+	}
+	return pod.Status.Reason
+}
+
+func KubernetesListDeployments(args map[string]any) (map[string]any, error) {
+	client, typed, err := kubernetesList(args)
+	if err != nil {
+		return map[string]any{
+			"success": false,
+			"error":   err.Error(),
+		}, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	list, err := client.clientset.AppsV1().Deployments(typed.Namespace).List(ctx, typed.options())
+	if err != nil {
+		err = fmt.Errorf("failed to list deployments: %w", err)
+		return map[string]any{
+			"success": false,
+			"error":   err.Error(),
+		}, err
+	}
+	deployments := make([]map[string]any, len(list.Items))
+	for i, deployment := range list.Items {
+		desired := int32(1)
+		if deployment.Spec.Replicas != nil {
+			desired = *deployment.Spec.Replicas
+		}
+		images := []string{}
+		for _, container := range deployment.Spec.Template.Spec.Containers {
+			images = append(images, container.Image)
+		}
+		deployments[i] = map[string]any{
+			"name":      deployment.Name,
+			"ready":     fmt.Sprintf("%d/%d", deployment.Status.ReadyReplicas, desired),
+			"updated":   deployment.Status.UpdatedReplicas,
+			"available": deployment.Status.AvailableReplicas,
+			"images":    images,
+			"age":       kubernetesAge(deployment.CreationTimestamp),
+		}
+	}
+	return setNextCursor(map[string]any{
+		"success":     true,
+		"namespace":   typed.Namespace,
+		"deployments": deployments,
+	}, list.Continue), nil
+}
+
+func KubernetesListEvents(args map[string]any) (map[string]any, error) {
+	client, typed, err := kubernetesList(args)
+	if err != nil {
+		return map[string]any{
+			"success": false,
+			"error":   err.Error(),
+		}, err
+	}
+	options := typed.options()
+	var fields []string
+	if typed.Object != "" {
+		fields = append(fields, "involvedObject.name="+typed.Object)
+	}
+	if typed.WarningsOnly {
+		fields = append(fields, "type="+corev1.EventTypeWarning)
+	}
+	options.FieldSelector = strings.Join(fields, ",")
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	list, err := client.clientset.CoreV1().Events(typed.Namespace).List(ctx, options)
+	if err != nil {
+		err = fmt.Errorf("failed to list events: %w", err)
+		return map[string]any{
+			"success": false,
+			"error":   err.Error(),
+		}, err
+	}
+	items := list.Items
+	slices.SortFunc(items, func(a, b corev1.Event) int {
+		return eventTime(b).Compare(eventTime(a))
+	})
+	events := make([]map[string]any, len(items))
+	for i, event := range items {
+		events[i] = map[string]any{
+			"type":    event.Type,
+			"reason":  event.Reason,
+			"object":  strings.ToLower(event.InvolvedObject.Kind) + "/" + event.InvolvedObject.Name,
+			"message": event.Message,
+			"count":   event.Count,
+			"last":    eventTime(event).UTC().Format(time.RFC3339),
+		}
+	}
+	return setNextCursor(map[string]any{
+		"success":   true,
+		"namespace": typed.Namespace,
+		"events":    events,
+	}, list.Continue), nil
+}
+
+// eventTime returns when an event last happened, events use different fields for it
+func eventTime(event corev1.Event) time.Time {
+	switch {
+	case !event.LastTimestamp.IsZero():
+		return event.LastTimestamp.Time
+	case !event.EventTime.IsZero():
+		return event.EventTime.Time
+	}
+	return event.CreationTimestamp.Time
+}
+
+type kubernetesPodLogsArgs struct {
+	Namespace string `json:"namespace"`
+	Pod       string `json:"pod"`
+	Container string `json:"container"`
+	TailLines int64  `json:"tail_lines"`
+	Previous  bool   `json:"previous"`
+}
+
+func KubernetesPodLogs(args map[string]any) (map[string]any, error) {
+	typed, err := DecodeArgs[kubernetesPodLogsArgs](args)
+	if err == nil && typed.Pod == "" {
+		err = NewToolError(fmt.Errorf("pod is required"), "", true)
+	}
+	var client *kubernetesClient
+	if err == nil {
+		client, err = getKubernetesClient()
+	}
+	if err == nil {
+		typed.Namespace, err = client.resolveNamespace(typed.Namespace)
+	}
+	if err != nil {
+		return map[string]any{
+			"success": false,
+			"error":   err.Error(),
+		}, err
+	}
+	if typed.TailLines <= 0 {
+		typed.TailLines = DefaultKubernetesLogLines
+	}
+	// read one byte past the limit to report truncation
+	limit := client.config.MaxLogBytes + 1
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	stream, err := client.clientset.CoreV1().Pods(typed.Namespace).GetLogs(typed.Pod, &corev1.PodLogOptions{
+		Container:  typed.Container,
+		TailLines:  &typed.TailLines,
+		Previous:   typed.Previous,
+		LimitBytes: &limit,
+	}).Stream(ctx)
+	if err != nil {
+		err = fmt.Errorf("failed to get logs of pod %s: %w", typed.Pod, err)
+		return map[string]any{
+			"success": false,
+			"error":   err.Error(),
+		}, err
+	}
+	defer stream.Close()
+	logs, err := io.ReadAll(io.LimitReader(stream, limit))
+	if err != nil {
+		err = fmt.Errorf("failed to read logs of pod %s: %w", typed.Pod, err)
+		return map[string]any{
+			"success": false,
+			"error":   err.Error(),
+		}, err
+	}
+	truncated := int64(len(logs)) > client.config.MaxLogBytes
+	if truncated {
+		logs = logs[:client.config.MaxLogBytes]
+	}
+	return map[string]any{
+		"success":   true,
+		"namespace": typed.Namespace,
+		"pod":       typed.Pod,
+		"logs":      string(logs),
+		"truncated": truncated,
+	}, nil
+}
+
+// kubernetesAge formats the time since a timestamp like kubectl, e.g. 3d or 5h
+func kubernetesAge(timestamp metav1.Time) string {
+	age := time.Since(timestamp.Time)
+	switch {
+	case age >= 48*time.Hour:
+		return fmt.Sprintf("%dd", int(age.Hours()/24))
+	case age >= time.Hour:
+		return fmt.Sprintf("%dh", int(age.Hours()))
+	case age >= time.Minute:
+		return fmt.Sprintf("%dm", int(age.Minutes()))
+	}
+	return fmt.Sprintf("%ds", int(age.Seconds()))
+}
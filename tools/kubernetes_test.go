@@ -0,0 +1,145 @@
+package tools
+
+import (
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// useKubernetes points the Kubernetes tools at the TLS server srv through a kubeconfig file
+func useKubernetes(t *testing.T, config KubernetesConfig, srv *httptest.Server) {
+	t.Helper()
+	kubeconfig := filepath.Join(t.TempDir(), "config")
+	data := fmt.Sprintf(`apiVersion: v1
+kind: Config
+clusters:
+- name: test
+  cluster:
+    server: %s
+    certificate-authority-data: %s
+users:
+- name: reader
+  user:
+    token: reader-token
+contexts:
+- name: test
+  context:
+    cluster: test
+    user: reader
+    namespace: shop
+current-context: test
+`, srv.URL, base64.StdEncoding.EncodeToString(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})))
+	if err := os.WriteFile(kubeconfig, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	config.Kubeconfig = kubeconfig
+	if err := ConfigureKubernetes(config); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		kubeClientMu.Lock()
+		defer kubeClientMu.Unlock()
+		kubeClient = nil
+	})
+}
+
+func TestKubernetesTools(t *testing.T) {
+	created := time.Now().Add(-50 * time.Hour).UTC().Format(time.RFC3339)
+	var queries []string
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer reader-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		queries = append(queries, r.URL.Path+"?"+r.URL.RawQuery)
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/v1/namespaces/shop/pods":
+			fmt.Fprintf(w, `{"kind":"PodList","apiVersion":"v1","metadata":{"continue":"next-page"},"items":[
+				{"metadata":{"name":"web-1","creationTimestamp":%q},"spec":{"nodeName":"node-a","containers":[{"name":"web"},{"name":"proxy"}]},
+				 "status":{"phase":"Running","containerStatuses":[{"name":"web","ready":true,"restartCount":4,"state":{"waiting":{"reason":"CrashLoopBackOff"}}},{"name":"proxy","ready":true,"restartCount":1}]}}]}`, created)
+		case "/apis/apps/v1/namespaces/shop/deployments":
+			fmt.Fprintf(w, `{"kind":"DeploymentList","apiVersion":"apps/v1","metadata":{},"items":[
+				{"metadata":{"name":"web","creationTimestamp":%q},"spec":{"replicas":3,"template":{"spec":{"containers":[{"name":"web","image":"shop/web:1.2"}]}}},
+				 "status":{"readyReplicas":2,"updatedReplicas":3,"availableReplicas":2}}]}`, created)
+		case "/api/v1/namespaces/shop/events":
+			fmt.Fprint(w, `{"kind":"EventList","apiVersion":"v1","metadata":{},"items":[
+				{"metadata":{"name":"a"},"type":"Warning","reason":"BackOff","message":"Back-off restarting","count":3,"lastTimestamp":"2024-01-15T10:00:00Z","involvedObject":{"kind":"Pod","name":"web-1"}},
+				{"metadata":{"name":"b"},"type":"Warning","reason":"Unhealthy","message":"Readiness probe failed","count":1,"lastTimestamp":"2024-01-15T11:00:00Z","involvedObject":{"kind":"Pod","name":"web-1"}}]}`)
+		case "/api/v1/namespaces/shop/pods/web-1/log":
+			w.Header().Set("Content-Type", "text/plain")
+			fmt.Fprint(w, "line one\nline two\n")
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+	useKubernetes(t, KubernetesConfig{MaxLogBytes: 10}, srv)
+
+	result, err := KubernetesListPods(map[string]any{"label_selector": "app=web", "cursor": "page-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pod := result["pods"].([]map[string]any)[0]
+	if pod["ready"] != "2/2" || pod["restarts"] != int32(5) || pod["reason"] != "CrashLoopBackOff" || pod["node"] != "node-a" || pod["age"] != "2d" {
+		t.Errorf("pod %v", pod)
+	}
+	if result["namespace"] != "shop" || result[NextCursorKey] != "next-page" {
+		t.Errorf("pods %v", result)
+	}
+	if query := queries[0]; !strings.Contains(query, "labelSelector=app%3Dweb") || !strings.Contains(query, "continue=page-1") || !strings.Contains(query, "limit=100") {
+		t.Errorf("pods query %s", query)
+	}
+
+	result, err = KubernetesListDeployments(map[string]any{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	deployment := result["deployments"].([]map[string]any)[0]
+	if deployment["ready"] != "2/3" || deployment["images"].([]string)[0] != "shop/web:1.2" {
+		t.Errorf("deployment %v", deployment)
+	}
+
+	result, err = KubernetesListEvents(map[string]any{"object": "web-1", "warnings_only": true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the latest event comes first
+	events := result["events"].([]map[string]any)
+	if len(events) != 2 || events[0]["reason"] != "Unhealthy" || events[0]["object"] != "pod/web-1" || events[1]["last"] != "2024-01-15T10:00:00Z" {
+		t.Errorf("events %v", events)
+	}
+	if query := queries[len(queries)-1]; !strings.Contains(query, "fieldSelector=involvedObject.name%3Dweb-1%2Ctype%3DWarning") {
+		t.Errorf("events query %s", query)
+	}
+
+	result, err = KubernetesPodLogs(map[string]any{"pod": "web-1", "tail_lines": 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result["logs"] != "line one\nl" || result["truncated"] != true {
+		t.Errorf("logs %q, truncated %v", result["logs"], result["truncated"])
+	}
+	if query := queries[len(queries)-1]; !strings.Contains(query, "tailLines=5") || !strings.Contains(query, "limitBytes=11") {
+		t.Errorf("logs query %s", query)
+	}
+
+	// only the context's namespace is allowed by default
+	requests := len(queries)
+	if _, err := KubernetesListPods(map[string]any{"namespace": "kube-system"}); err == nil {
+		t.Error("listed pods in a namespace that is not allowed")
+	}
+	if _, err := KubernetesPodLogs(map[string]any{}); err == nil {
+		t.Error("read logs without a pod")
+	}
+	if len(queries) != requests {
+		t.Errorf("rejected calls reached the cluster: %v", queries[requests:])
+	}
+}
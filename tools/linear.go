@@ -0,0 +1,460 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	// Credential name for the Linear API key
+	LinearTokenEnv = "LINEAR_API_KEY"
+
+	linearPageSize = 50
+)
+
+// linearAPIURL is a variable so it can be pointed at a test server
+var linearAPIURL = "https://api.linear.app/graphql"
+
+var linearTools = map[string]Tool{
+	"searchLinearIssues": searchLinearIssuesTool,
+	"getLinearIssue":     getLinearIssueTool,
+	"createLinearIssue":  createLinearIssueTool,
+	"commentLinearIssue": commentLinearIssueTool,
+}
+
+// linearQuery runs a GraphQL query with the key of the scope in args and decodes its
+// data into out
+func linearQuery(args map[string]any, query string, variables map[string]any, out any) error {
+	creds := credentialsFor(args)
+	token := creds.get(LinearTokenEnv)
+	if creds.err != nil {
+		return creds.err
+	}
+	if token == "" {
+		return fmt.Errorf("Linear API key not found in credential %s", LinearTokenEnv)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	var resp struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	// personal API keys are sent without a scheme
+	if err := postJSON(ctx, linearAPIURL, token, map[string]any{"query": query, "variables": variables}, &resp); err != nil {
+		return err
+	}
+	if len(resp.Errors) > 0 {
+		messages := make([]string, len(resp.Errors))
+		for i, e := range resp.Errors {
+			messages[i] = e.Message
+		}
+		return fmt.Errorf("linear error: %s", strings.Join(messages, "; "))
+	}
+	return json.Unmarshal(resp.Data, out)
+}
+
+const linearIssueFields = `identifier title url createdAt updatedAt state { name } assignee { name } team { key }`
+
+type linearIssue struct {
+	Identifier string `json:"identifier"`
+	Title      string `json:"title"`
+	URL        string `json:"url"`
+	CreatedAt  string `json:"createdAt"`
+	UpdatedAt  string `json:"updatedAt"`
+	State      struct {
+		Name string `json:"name"`
+	} `json:"state"`
+	Assignee *struct {
+		Name string `json:"name"`
+	} `json:"assignee"`
+	Team struct {
+		Key string `json:"key"`
+	} `json:"team"`
+}
+
+// summary returns the fields listed by searches, in the shape of the GitHub issue tools
+func (issue linearIssue) summary() map[string]string {
+	assignee := ""
+	if issue.Assignee != nil {
+		assignee = issue.Assignee.Name
+	}
+	return map[string]string{
+		"key":       issue.Identifier,
+		"title":     issue.Title,
+		"state":     issue.State.Name,
+		"team":      issue.Team.Key,
+		"assignee":  assignee,
+		"url":       issue.URL,
+		"createdAt": issue.CreatedAt,
+		"updatedAt": issue.UpdatedAt,
+	}
+}
+
+var searchLinearIssuesTool = Tool{
+	Name:        "searchLinearIssues",
+	Description: "Search Linear issues by title, team, assignee and state, most recently updated first",
+	Parameters: []Parameter{
+		{
+			Name:        "query",
+			Type:        "string",
+			Description: "Text the title contains (optional)",
+			Required:    false,
+		},
+		{
+			Name:        "team",
+			Type:        "string",
+			Description: "The team key, for example ENG (optional)",
+			Required:    false,
+		},
+		{
+			Name:        "assignee",
+			Type:        "string",
+			Description: "The assignee's email, or me for the API key's user (optional)",
+			Required:    false,
+		},
+		{
+			Name:        "state",
+			Type:        "string",
+			Description: "The workflow state name, for example In Progress (optional)",
+			Required:    false,
+		},
+	},
+	Options:   map[string]string{},
+	Run:       SearchLinearIssues,
+	Paginated: true,
+}
+
+type searchLinearIssuesArgs struct {
+	Query    string `json:"query"`
+	Team     string `json:"team"`
+	Assignee string `json:"assignee"`
+	State    string `json:"state"`
+	Cursor   string `json:"cursor"`
+}
+
+func SearchLinearIssues(args map[string]any) (map[string]any, error) {
+	typed, err := DecodeArgs[searchLinearIssuesArgs](args)
+	if err != nil {
+		return map[string]any{
+			"success": false,
+			"error":   err.Error(),
+		}, err
+	}
+	filter := map[string]any{}
+	if typed.Query != "" {
+		filter["title"] = map[string]string{"containsIgnoreCase": typed.Query}
+	}
+	if typed.Team != "" {
+		filter["team"] = map[string]any{"key": map[string]string{"eq": typed.Team}}
+	}
+	switch typed.Assignee {
+	case "":
+	case "me":
+		filter["assignee"] = map[string]any{"isMe": map[string]bool{"eq": true}}
+	default:
+		filter["assignee"] = map[string]any{"email": map[string]string{"eq": typed.Assignee}}
+	}
+	if typed.State != "" {
+		filter["state"] = map[string]any{"name": map[string]string{"eqIgnoreCase": typed.State}}
+	}
+	variables := map[string]any{"first": linearPageSize, "filter": filter}
+	if typed.Cursor != "" {
+		variables["after"] = typed.Cursor
+	}
+	var result struct {
+		Issues struct {
+			Nodes    []linearIssue `json:"nodes"`
+			PageInfo struct {
+				HasNextPage bool   `json:"hasNextPage"`
+				EndCursor   string `json:"endCursor"`
+			} `json:"pageInfo"`
+		} `json:"issues"`
+	}
+	query := `query($first: Int, $after: String, $filter: IssueFilter) {
+		issues(first: $first, after: $after, filter: $filter, orderBy: updatedAt) {
+			nodes { ` + linearIssueFields + ` }
+			pageInfo { hasNextPage endCursor }
+		}
+	}`
+	if err := linearQuery(args, query, variables, &result); err != nil {
+		err = fmt.Errorf("failed to search issues: %w", err)
+		return map[string]any{
+			"success": false,
+			"error":   err.Error(),
+		}, err
+	}
+	issues := make([]map[string]string, len(result.Issues.Nodes))
+	for i, issue := range result.Issues.Nodes {
+		issues[i] = issue.summary()
+	}
+	marshaled, err := json.Marshal(issues)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal issues: %w", err)
+	}
+	if DEBUG {
+		Logger().Info("Called searchLinearIssues", "filter", filter, "found", len(issues), "result", string(marshaled))
+	}
+	next := ""
+	if result.Issues.PageInfo.HasNextPage {
+		next = result.Issues.PageInfo.EndCursor
+	}
+	return setNextCursor(map[string]any{
+		"success": true,
+		"issues":  string(marshaled),
+	}, next), nil
+}
+
+var getLinearIssueTool = Tool{
+	Name:        "getLinearIssue",
+	Description: "Get a Linear issue with its description and comments",
+	Parameters: []Parameter{
+		{
+			Name:        "key",
+			Type:        "string",
+			Description: "The issue identifier, for example ENG-123",
+			Required:    true,
+		},
+	},
+	Options: map[string]string{},
+	Run:     GetLinearIssue,
+}
+
+func GetLinearIssue(args map[string]any) (map[string]any, error) {
+	key, _ := args["key"].(string)
+	if key == "" {
+		err := NewToolError(fmt.Errorf("key is required"), "", true)
+		return map[string]any{
+			"success": false,
+			"error":   err.Error(),
+		}, err
+	}
+	var result struct {
+		Issue struct {
+			linearIssue
+			Description   string `json:"description"`
+			PriorityLabel string `json:"priorityLabel"`
+			Labels        struct {
+				Nodes []struct {
+					Name string `json:"name"`
+				} `json:"nodes"`
+			} `json:"labels"`
+			Comments struct {
+				Nodes []struct {
+					Body      string `json:"body"`
+					CreatedAt string `json:"createdAt"`
+					User      *struct {
+						Name string `json:"name"`
+					} `json:"user"`
+				} `json:"nodes"`
+			} `json:"comments"`
+		} `json:"issue"`
+	}
+	query := `query($id: String!) {
+		issue(id: $id) {
+			` + linearIssueFields + ` description priorityLabel
+			labels { nodes { name } }
+			comments { nodes { body createdAt user { name } } }
+		}
+	}`
+	if err := linearQuery(args, query, map[string]any{"id": key}, &result); err != nil {
+		err = fmt.Errorf("failed to get issue %s: %w", key, err)
+		return map[string]any{
+			"success": false,
+			"error":   err.Error(),
+		}, err
+	}
+	issue := result.Issue
+	response := map[string]any{"success": true}
+	for k, v := range issue.summary() {
+		response[k] = v
+	}
+	response["description"] = issue.Description
+	response["priority"] = issue.PriorityLabel
+	labels := []string{}
+	for _, label := range issue.Labels.Nodes {
+		labels = append(labels, label.Name)
+	}
+	response["labels"] = labels
+	comments := []map[string]string{}
+	for _, comment := range issue.Comments.Nodes {
+		author := ""
+		if comment.User != nil {
+			author = comment.User.Name
+		}
+		comments = append(comments, map[string]string{
+			"author":    author,
+			"body":      comment.Body,
+			"createdAt": comment.CreatedAt,
+		})
+	}
+	response["comments"] = comments
+	return response, nil
+}
+
+var createLinearIssueTool = Tool{
+	Name:        "createLinearIssue",
+	Description: "Create a Linear issue",
+	Parameters: []Parameter{
+		{
+			Name:        "team",
+			Type:        "string",
+			Description: "The team key, for example ENG",
+			Required:    true,
+		},
+		{
+			Name:        "title",
+			Type:        "string",
+			Description: "The issue title",
+			Required:    true,
+		},
+		{
+			Name:        "description",
+			Type:        "string",
+			Description: "The issue description in markdown",
+			Required:    false,
+		},
+	},
+	Options: map[string]string{},
+	Run:     CreateLinearIssue,
+}
+
+type createLinearIssueArgs struct {
+	Team        string `json:"team"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+}
+
+func CreateLinearIssue(args map[string]any) (map[string]any, error) {
+	typed, err := DecodeArgs[createLinearIssueArgs](args)
+	if err == nil && (typed.Team == "" || typed.Title == "") {
+		err = NewToolError(fmt.Errorf("team and title are required"), "", true)
+	}
+	if err != nil {
+		return map[string]any{
+			"success": false,
+			"error":   err.Error(),
+		}, err
+	}
+	var teams struct {
+		Teams struct {
+			Nodes []struct {
+				ID string `json:"id"`
+			} `json:"nodes"`
+		} `json:"teams"`
+	}
+	err = linearQuery(args, `query($key: String!) { teams(filter: { key: { eq: $key } }) { nodes { id } } }`, map[string]any{"key": typed.Team}, &teams)
+	if err == nil && len(teams.Teams.Nodes) == 0 {
+		err = NewToolError(fmt.Errorf("team %s not found", typed.Team), "Pass the key of an existing team.", false)
+	}
+	if err != nil {
+		return map[string]any{
+			"success": false,
+			"error":   err.Error(),
+		}, err
+	}
+	input := map[string]any{
+		"teamId": teams.Teams.Nodes[0].ID,
+		"title":  typed.Title,
+	}
+	if typed.Description != "" {
+		input["description"] = typed.Description
+	}
+	var created struct {
+		IssueCreate struct {
+			Success bool `json:"success"`
+			Issue   struct {
+				Identifier string `json:"identifier"`
+				URL        string `json:"url"`
+			} `json:"issue"`
+		} `json:"issueCreate"`
+	}
+	query := `mutation($input: IssueCreateInput!) { issueCreate(input: $input) { success issue { identifier url } } }`
+	err = linearQuery(args, query, map[string]any{"input": input}, &created)
+	if err == nil && !created.IssueCreate.Success {
+		err = fmt.Errorf("issue was not created")
+	}
+	if err != nil {
+		err = fmt.Errorf("failed to create issue: %w", err)
+		return map[string]any{
+			"success": false,
+			"error":   err.Error(),
+		}, err
+	}
+	return map[string]any{
+		"success": true,
+		"key":     created.IssueCreate.Issue.Identifier,
+		"url":     created.IssueCreate.Issue.URL,
+	}, nil
+}
+
+var commentLinearIssueTool = Tool{
+	Name:        "commentLinearIssue",
+	Description: "Add a comment to a Linear issue",
+	Parameters: []Parameter{
+		{
+			Name:        "key",
+			Type:        "string",
+			Description: "The issue identifier, for example ENG-123",
+			Required:    true,
+		},
+		{
+			Name:        "body",
+			Type:        "string",
+			Description: "The comment in markdown",
+			Required:    true,
+		},
+	},
+	Options: map[string]string{},
+	Run:     CommentLinearIssue,
+}
+
+func CommentLinearIssue(args map[string]any) (map[string]any, error) {
+	key, _ := args["key"].(string)
+	body, _ := args["body"].(string)
+	if key == "" || body == "" {
+		err := NewToolError(fmt.Errorf("key and body are required"), "", true)
+		return map[string]any{
+			"success": false,
+			"error":   err.Error(),
+		}, err
+	}
+	// the mutation takes the issue's ID, look it up from the identifier
+	var issue struct {
+		Issue struct {
+			ID string `json:"id"`
+		} `json:"issue"`
+	}
+	var created struct {
+		CommentCreate struct {
+			Success bool `json:"success"`
+			Comment struct {
+				ID string `json:"id"`
+			} `json:"comment"`
+		} `json:"commentCreate"`
+	}
+	err := linearQuery(args, `query($id: String!) { issue(id: $id) { id } }`, map[string]any{"id": key}, &issue)
+	if err == nil {
+		query := `mutation($input: CommentCreateInput!) { commentCreate(input: $input) { success comment { id } } }`
+		err = linearQuery(args, query, map[string]any{"input": map[string]string{"issueId": issue.Issue.ID, "body": body}}, &created)
+	}
+	if err == nil && !created.CommentCreate.Success {
+		err = fmt.Errorf("comment was not created")
+	}
+	if err != nil {
+		err = fmt.Errorf("failed to comment on issue %s: %w", key, err)
+		return map[string]any{
+			"success": false,
+			"error":   err.Error(),
+		}, err
+	}
+	return map[string]any{
+		"success": true,
+		"key":     key,
+		"id":      created.CommentCreate.Comment.ID,
+	}, nil
+}
@@ -0,0 +1,121 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// linearRequest is a GraphQL request received by the fake Linear API
+type linearRequest struct {
+	Query     string         `json:"query"`
+	Variables map[string]any `json:"variables"`
+}
+
+// useLinear points the Linear tools at a server answering each request with respond
+func useLinear(t *testing.T, respond func(req linearRequest) string) *[]linearRequest {
+	var requests []linearRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// personal API keys are sent without a scheme
+		if r.Header.Get("Authorization") != "lin_api_key" || r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var req linearRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		requests = append(requests, req)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, respond(req))
+	}))
+	t.Cleanup(srv.Close)
+	apiURL := linearAPIURL
+	linearAPIURL = srv.URL
+	t.Cleanup(func() { linearAPIURL = apiURL })
+	return &requests
+}
+
+func TestLinearIssues(t *testing.T) {
+	requests := useLinear(t, func(req linearRequest) string {
+		switch {
+		case strings.Contains(req.Query, "issues("):
+			return `{"data":{"issues":{"nodes":[{"identifier":"ENG-12","title":"Flaky deploy","url":"https://linear.app/acme/issue/ENG-12","state":{"name":"In Progress"},"team":{"key":"ENG"},"assignee":null}],
+				"pageInfo":{"hasNextPage":true,"endCursor":"c-1"}}}}`
+		case strings.Contains(req.Query, "comments {"):
+			return `{"data":{"issue":{"identifier":"ENG-12","title":"Flaky deploy","description":"Fails on **arm64**","priorityLabel":"Urgent","state":{"name":"In Progress"},
+				"labels":{"nodes":[{"name":"ci"}]},"comments":{"nodes":[{"body":"Seen again","createdAt":"2024-01-15T10:00:00Z","user":null}]}}}}`
+		case strings.Contains(req.Query, "teams("):
+			if req.Variables["key"] != "ENG" {
+				return `{"data":{"teams":{"nodes":[]}}}`
+			}
+			return `{"data":{"teams":{"nodes":[{"id":"team-1"}]}}}`
+		case strings.Contains(req.Query, "issueCreate"):
+			return `{"data":{"issueCreate":{"success":true,"issue":{"identifier":"ENG-13","url":"https://linear.app/acme/issue/ENG-13"}}}}`
+		case strings.Contains(req.Query, "issue(id: $id) { id }"):
+			if req.Variables["id"] != "ENG-12" {
+				return `{"data":null,"errors":[{"message":"Entity not found"}]}`
+			}
+			return `{"data":{"issue":{"id":"uuid-12"}}}`
+		case strings.Contains(req.Query, "commentCreate"):
+			return `{"data":{"commentCreate":{"success":true,"comment":{"id":"comment-1"}}}}`
+		}
+		return `{"errors":[{"message":"unexpected query"}]}`
+	})
+	env := map[string]string{LinearTokenEnv: "lin_api_key"}
+
+	result, err := SearchLinearIssues(map[string]any{"team": "ENG", "assignee": "me", "state": "in progress", CursorArg: "c-0", EnvArg: env})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var issues []map[string]string
+	json.Unmarshal([]byte(result["issues"].(string)), &issues)
+	if len(issues) != 1 || issues[0]["key"] != "ENG-12" || issues[0]["state"] != "In Progress" || issues[0]["assignee"] != "" {
+		t.Errorf("issues %v", issues)
+	}
+	if result[NextCursorKey] != "c-1" {
+		t.Errorf("next cursor %v", result[NextCursorKey])
+	}
+	variables := (*requests)[0].Variables
+	filter, _ := json.Marshal(variables["filter"])
+	if variables["after"] != "c-0" || string(filter) != `{"assignee":{"isMe":{"eq":true}},"state":{"name":{"eqIgnoreCase":"in progress"}},"team":{"key":{"eq":"ENG"}}}` {
+		t.Errorf("variables %v", variables)
+	}
+
+	result, err = GetLinearIssue(map[string]any{"key": "ENG-12", EnvArg: env})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result["priority"] != "Urgent" || result["labels"].([]string)[0] != "ci" || result["comments"].([]map[string]string)[0]["body"] != "Seen again" {
+		t.Errorf("issue %v", result)
+	}
+
+	result, err = CreateLinearIssue(map[string]any{"team": "ENG", "title": "Pin the runner image", EnvArg: env})
+	if err != nil || result["key"] != "ENG-13" {
+		t.Errorf("created %v, %v", result, err)
+	}
+	if input := (*requests)[len(*requests)-1].Variables["input"].(map[string]any); input["teamId"] != "team-1" || input["description"] != nil {
+		t.Errorf("create input %v", input)
+	}
+	if _, err := CreateLinearIssue(map[string]any{"team": "OPS", "title": "Nope", EnvArg: env}); err == nil || !strings.Contains(err.Error(), "team OPS not found") {
+		t.Errorf("missing team error %v", err)
+	}
+
+	result, err = CommentLinearIssue(map[string]any{"key": "ENG-12", "body": "Fixed", EnvArg: env})
+	if err != nil || result["id"] != "comment-1" {
+		t.Errorf("comment %v, %v", result, err)
+	}
+	if input := (*requests)[len(*requests)-1].Variables["input"].(map[string]any); input["issueId"] != "uuid-12" {
+		t.Errorf("comment input %v", input)
+	}
+	if _, err := CommentLinearIssue(map[string]any{"key": "ENG-404", "body": "Fixed", EnvArg: env}); err == nil || !strings.Contains(err.Error(), "Entity not found") {
+		t.Errorf("GraphQL error %v", err)
+	}
+	if _, err := GetLinearIssue(map[string]any{"key": "ENG-12", EnvArg: map[string]string{}}); err == nil {
+		t.Error("called Linear without an API key")
+	}
+}
@@ -0,0 +1,254 @@
+package tools
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-logr/logr"
+	"github.com/go-logr/stdr"
+)
+
+// Redacted replaces the values of secret keys and the secrets found in logged text
+const Redacted = "[REDACTED]"
+
+// DefaultRedactKeys are the log keys whose values LogPolicy always redacts. Keys are
+// compared without case, dashes and underscores, and match when they end with one of
+// these, so "github_token" and "clientSecret" are redacted too.
+var DefaultRedactKeys = []string{
+	"apikey", "password", "passwd", "secret", "token", "authorization", "cookie",
+	"credentials", "databaseurl", "dsn", "connectionstring", "privatekey",
+}
+
+// defaultSecretPatterns find secrets in logged text: credentials in URLs, bearer tokens,
+// password parameters of connection strings, and the key formats of common providers
+var defaultSecretPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)\b([a-z][a-z0-9+.-]*://[^/\s:@]+:)[^@\s/]+(@)`),
+	regexp.MustCompile(`(?i)\b(bearer\s+)[a-z0-9._~+/=-]{8,}`),
+	regexp.MustCompile(`(?i)\b((?:password|passwd|pwd)\s*[=:]\s*)[^\s&;,'"]+`),
+	regexp.MustCompile(`\bsk-[A-Za-z0-9_-]{16,}`),
+	regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{30,}`),
+	regexp.MustCompile(`\b(?:ghp|gho|ghu|ghs|github_pat)_[A-Za-z0-9_]{20,}`),
+	regexp.MustCompile(`\bxox[abprs]-[A-Za-z0-9-]{10,}`),
+	regexp.MustCompile(`\bAKIA[0-9A-Z]{16}\b`),
+}
+
+var (
+	emailRegex = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+	// phone numbers are only recognized in international format, other digit runs are
+	// too often ids and timestamps
+	phoneRegex = regexp.MustCompile(`\+[1-9][0-9 ().-]{7,18}[0-9]`)
+)
+
+// LogPolicy controls what logs may contain. It redacts the values of secret keys and
+// secrets found in text, truncates long values and hashes personal data, so logs of
+// messages, tool arguments and results can be kept. Apply it to a logger with
+// NewPolicyLogger.
+type LogPolicy struct {
+	// MaxContentLength truncates logged strings to this many characters, 0 keeps them
+	MaxContentLength int
+	// HashPII replaces email addresses and international phone numbers with a short
+	// hash, so the same address can be followed through the logs without being shown
+	HashPII bool
+	// RedactKeys are redacted besides DefaultRedactKeys
+	RedactKeys []string
+	// SecretPatterns find secrets in text besides the built in patterns. The groups of a
+	// pattern are kept, the secret follows the first, e.g. the name of a parameter.
+	SecretPatterns []*regexp.Regexp
+}
+
+// DefaultLogPolicy truncates content to 500 characters and hashes personal data
+var DefaultLogPolicy = LogPolicy{MaxContentLength: 500, HashPII: true}
+
+// redactKey reports whether the values of key are secret
+func (p LogPolicy) redactKey(key string) bool {
+	normalized := strings.ToLower(strings.NewReplacer("_", "", "-", "").Replace(key))
+	for _, keys := range [][]string{DefaultRedactKeys, p.RedactKeys} {
+		for _, k := range keys {
+			if strings.HasSuffix(normalized, strings.ToLower(k)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// RedactString removes the secrets and personal data from s and truncates it
+func (p LogPolicy) RedactString(s string) string {
+	for _, patterns := range [][]*regexp.Regexp{defaultSecretPatterns, p.SecretPatterns} {
+		for _, pattern := range patterns {
+			s = pattern.ReplaceAllStringFunc(s, func(match string) string {
+				// the groups are the parts of the match to keep around the secret
+				if groups := pattern.FindStringSubmatch(match); len(groups) > 1 {
+					return groups[1] + Redacted + strings.Join(groups[2:], "")
+				}
+				return Redacted
+			})
+		}
+	}
+	if p.HashPII {
+		s = emailRegex.ReplaceAllStringFunc(s, func(email string) string { return "email:" + shortHash(strings.ToLower(email)) })
+		s = phoneRegex.ReplaceAllStringFunc(s, func(phone string) string { return "phone:" + shortHash(phone) })
+	}
+	if p.MaxContentLength > 0 && utf8.RuneCountInString(s) > p.MaxContentLength {
+		runes := []rune(s)
+		s = fmt.Sprintf("%s... (%d more characters)", string(runes[:p.MaxContentLength]), len(runes)-p.MaxContentLength)
+	}
+	return s
+}
+
+func shortHash(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:4])
+}
+
+// Redact returns value as it may be logged. Strings are redacted, maps and slices are
+// copied with their keys and elements redacted, numbers and other plain values are kept
+// and anything else is logged as its redacted text.
+func (p LogPolicy) Redact(value any) any {
+	switch v := value.(type) {
+	case nil, bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64,
+		float32, float64, time.Duration, time.Time:
+		return v
+	case string:
+		return p.RedactString(v)
+	case []byte:
+		return p.RedactString(string(v))
+	case error:
+		return p.RedactString(v.Error())
+	case map[string]any:
+		redacted := make(map[string]any, len(v))
+		for key, value := range v {
+			redacted[key] = p.redactValue(key, value)
+		}
+		return redacted
+	case map[string]string:
+		redacted := make(map[string]string, len(v))
+		for key, value := range v {
+			if p.redactKey(key) {
+				redacted[key] = Redacted
+			} else {
+				redacted[key] = p.RedactString(value)
+			}
+		}
+		return redacted
+	case []string:
+		redacted := make([]string, len(v))
+		for i, s := range v {
+			redacted[i] = p.RedactString(s)
+		}
+		return redacted
+	case []any:
+		redacted := make([]any, len(v))
+		for i, e := range v {
+			redacted[i] = p.Redact(e)
+		}
+		return redacted
+	}
+	switch reflect.ValueOf(value).Kind() {
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return value
+	}
+	return p.RedactString(fmt.Sprintf("%+v", value))
+}
+
+// redactValue redacts the value logged under key
+func (p LogPolicy) redactValue(key string, value any) any {
+	if p.redactKey(key) && value != nil && value != "" {
+		return Redacted
+	}
+	return p.Redact(value)
+}
+
+// RedactKeysAndValues redacts logr style key and value pairs
+func (p LogPolicy) RedactKeysAndValues(keysAndValues []any) []any {
+	redacted := make([]any, len(keysAndValues))
+	for i := 0; i < len(keysAndValues); i += 2 {
+		redacted[i] = keysAndValues[i]
+		if i+1 == len(keysAndValues) {
+			break
+		}
+		key, _ := keysAndValues[i].(string)
+		redacted[i+1] = p.redactValue(key, keysAndValues[i+1])
+	}
+	return redacted
+}
+
+// NewPolicyLogger returns a logger that applies policy to the values and errors logged
+// through it before logger writes them. Messages are kept as they are, they are
+// expected to be constant.
+func NewPolicyLogger(logger logr.Logger, policy LogPolicy) logr.Logger {
+	sink := logger.GetSink()
+	if sink == nil {
+		return logger
+	}
+	// the sink is called from the policy sink, one frame further from the caller
+	if withDepth, ok := sink.(logr.CallDepthLogSink); ok {
+		sink = withDepth.WithCallDepth(1)
+	}
+	return logr.New(&policySink{sink: sink, policy: policy})
+}
+
+// policySink redacts what is logged before passing it to sink
+type policySink struct {
+	sink   logr.LogSink
+	policy LogPolicy
+}
+
+// Init does nothing, the sink was initialized by its own logger
+func (s *policySink) Init(logr.RuntimeInfo) {}
+
+func (s *policySink) Enabled(level int) bool {
+	return s.sink.Enabled(level)
+}
+
+func (s *policySink) Info(level int, msg string, keysAndValues ...any) {
+	s.sink.Info(level, msg, s.policy.RedactKeysAndValues(keysAndValues)...)
+}
+
+func (s *policySink) Error(err error, msg string, keysAndValues ...any) {
+	if err != nil {
+		err = errors.New(s.policy.RedactString(err.Error()))
+	}
+	s.sink.Error(err, msg, s.policy.RedactKeysAndValues(keysAndValues)...)
+}
+
+func (s *policySink) WithValues(keysAndValues ...any) logr.LogSink {
+	return &policySink{sink: s.sink.WithValues(s.policy.RedactKeysAndValues(keysAndValues)...), policy: s.policy}
+}
+
+func (s *policySink) WithName(name string) logr.LogSink {
+	return &policySink{sink: s.sink.WithName(name), policy: s.policy}
+}
+
+var (
+	toolLogger   = NewPolicyLogger(stdr.New(log.New(os.Stderr, "", log.LstdFlags)), DefaultLogPolicy).WithName("tools")
+	toolLoggerMu sync.RWMutex
+)
+
+// SetLogger sets the logger of the tools. It logs their failures, and the calls and
+// results of tools when DEBUG is set. The default writes to stderr with
+// DefaultLogPolicy applied, apply a policy to a replacement with NewPolicyLogger.
+func SetLogger(logger logr.Logger) {
+	toolLoggerMu.Lock()
+	defer toolLoggerMu.Unlock()
+	toolLogger = logger
+}
+
+// Logger returns the logger of the tools
+func Logger() logr.Logger {
+	toolLoggerMu.RLock()
+	defer toolLoggerMu.RUnlock()
+	return toolLogger
+}
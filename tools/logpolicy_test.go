@@ -0,0 +1,66 @@
+package tools
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr/funcr"
+)
+
+func TestLogPolicy(t *testing.T) {
+	var lines []string
+	logger := funcr.New(func(prefix, args string) {
+		lines = append(lines, args)
+	}, funcr.Options{})
+	l := NewPolicyLogger(logger, LogPolicy{MaxContentLength: 40, HashPII: true}).WithValues("github_token", "ghp_0123456789abcdefghijklmnop")
+	l.Info("Running tool", "args", map[string]any{
+		"apiKey": "secret-value",
+		"dsn":    "postgres://app:hunter2@db:5432/app",
+		"query":  "email jane@example.com",
+		"count":  3,
+	})
+	l.Error(errors.New("connect postgres://app:hunter2@db:5432/app: refused"), "Failed to connect")
+	l.Info("Tool result", "result", strings.Repeat("a", 50))
+	output := strings.Join(lines, "\n")
+
+	for _, secret := range []string{"ghp_0123456789", "secret-value", "hunter2", "jane@example.com"} {
+		if strings.Contains(output, secret) {
+			t.Errorf("log contains %q:\n%s", secret, output)
+		}
+	}
+	for _, want := range []string{
+		`"github_token"="[REDACTED]"`,
+		`"apiKey"="[REDACTED]"`,
+		`"count"=3`,
+		`"error"="connect postgres://app:[REDACTED]@db`,
+		`email email:`,
+		strings.Repeat("a", 40) + "... (10 more characters)",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("log is missing %s:\n%s", want, output)
+		}
+	}
+}
+
+func TestLogPolicyPatterns(t *testing.T) {
+	policy := LogPolicy{}
+	for input, want := range map[string]string{
+		"Authorization: Bearer abcdefgh12345678": "Authorization: Bearer [REDACTED]",
+		"key sk-abcdefghijklmnop1234":            "key [REDACTED]",
+		"host=db password=hunter2 user=app":      "host=db password=[REDACTED] user=app",
+		"call +1 (555) 010-9999 now":             "call +1 (555) 010-9999 now",
+		"order 20240101 shipped":                 "order 20240101 shipped",
+	} {
+		if got := policy.RedactString(input); got != want {
+			t.Errorf("RedactString(%q) = %q, want %q", input, got, want)
+		}
+	}
+	hashed := LogPolicy{HashPII: true}
+	if a, b := hashed.RedactString("Jane@Example.com"), hashed.RedactString("jane@example.com"); a != b || !strings.HasPrefix(a, "email:") {
+		t.Errorf("emails hashed to %q and %q, want the same hash", a, b)
+	}
+	if got := hashed.RedactString("call +1 (555) 010-9999 now"); strings.Contains(got, "555") {
+		t.Errorf("phone number was not hashed: %q", got)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/google/uuid"
@@ -67,6 +68,16 @@ func NewMemoryTool(config MemoryConfig, embeddingProvider EmbeddingProvider) (*M
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
 
+	mt, err := newMemoryTool(db, config, embeddingProvider)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return mt, nil
+}
+
+// newMemoryTool creates a MemoryTool on an open connection pool
+func newMemoryTool(db *sql.DB, config MemoryConfig, embeddingProvider EmbeddingProvider) (*MemoryTool, error) {
 	// Test the connection
 	if err := db.Ping(); err != nil {
 		return nil, fmt.Errorf("failed to ping database: %w", err)
@@ -90,7 +101,7 @@ func initSchema(db *sql.DB) error {
 	_, extErr := db.Exec("CREATE EXTENSION IF NOT EXISTS vector")
 	if extErr != nil {
 		// Log the error but continue - we might be able to work without it for testing
-		fmt.Printf("Warning: Could not create vector extension: %v\n", extErr)
+		Logger().Error(extErr, "Could not create the vector extension")
 	}
 
 	// Use a fixed dimension for the vector type. In PostgreSQL, table schema definitions
@@ -128,7 +139,7 @@ func initSchema(db *sql.DB) error {
 
 	for _, query := range indexQueries {
 		if _, err := db.Exec(query); err != nil {
-			fmt.Printf("Warning: Could not create index with query '%s': %v\n", query, err)
+			Logger().Error(err, "Could not create index", "query", query)
 		}
 	}
 
@@ -143,6 +154,26 @@ func (mt *MemoryTool) generateEmbedding(ctx context.Context, text string) ([]flo
 		return nil, fmt.Errorf("failed to generate embedding: %w", err)
 	}
 
+	return fitDimensions(embedding), nil
+}
+
+// generateEmbeddings generates embeddings for several texts in a single provider call
+func (mt *MemoryTool) generateEmbeddings(ctx context.Context, texts []string) ([][]float32, error) {
+	embeddings, err := mt.embeddingProvider.GenerateEmbeddings(ctx, texts, mt.config.EmbeddingModel)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate embeddings: %w", err)
+	}
+	if len(embeddings) != len(texts) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(texts), len(embeddings))
+	}
+	for i, embedding := range embeddings {
+		embeddings[i] = fitDimensions(embedding)
+	}
+	return embeddings, nil
+}
+
+// fitDimensions pads or truncates an embedding to the dimensions of the table schema
+func fitDimensions(embedding []float32) []float32 {
 	// Ensure the embedding has the correct dimensions for our table schema
 	// Our table schema uses 1536 dimensions, so we need to pad or truncate if necessary
 	targetDims := 1536
@@ -157,7 +188,7 @@ func (mt *MemoryTool) generateEmbedding(ctx context.Context, text string) ([]flo
 		embedding = padded
 	}
 
-	return embedding, nil
+	return embedding
 }
 
 // Store saves a memory with content and metadata
@@ -170,6 +201,33 @@ func (mt *MemoryTool) Store(ctx context.Context, content string, metadata map[st
 		return "", fmt.Errorf("failed to generate embedding: %w", err)
 	}
 
+	if err := mt.insert(ctx, id, content, embedding, metadata); err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+// StoreBatch saves several memories, embedding them in a single provider call
+func (mt *MemoryTool) StoreBatch(ctx context.Context, contents []string, metadata []map[string]interface{}) ([]string, error) {
+	if len(metadata) != len(contents) {
+		return nil, fmt.Errorf("expected metadata for each of the %d contents, got %d", len(contents), len(metadata))
+	}
+	embeddings, err := mt.generateEmbeddings(ctx, contents)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, len(contents))
+	for i, content := range contents {
+		ids[i] = uuid.New().String()
+		if err := mt.insert(ctx, ids[i], content, embeddings[i], metadata[i]); err != nil {
+			return ids[:i], err
+		}
+	}
+	return ids, nil
+}
+
+// insert writes a memory with a precomputed embedding
+func (mt *MemoryTool) insert(ctx context.Context, id string, content string, embedding []float32, metadata map[string]interface{}) error {
 	// Set expiration time if TTL is configured
 	var expiresAt *time.Time
 	if mt.config.DefaultTTL > 0 {
@@ -182,7 +240,7 @@ func (mt *MemoryTool) Store(ctx context.Context, content string, metadata map[st
 	if metadata != nil {
 		jsonData, err := json.Marshal(metadata)
 		if err != nil {
-			return "", fmt.Errorf("failed to marshal metadata: %w", err)
+			return fmt.Errorf("failed to marshal metadata: %w", err)
 		}
 		rawMetadata = json.RawMessage(jsonData)
 	}
@@ -194,12 +252,12 @@ func (mt *MemoryTool) Store(ctx context.Context, content string, metadata map[st
 		RETURNING id
 	`
 
-	_, err = mt.db.ExecContext(ctx, query, id, content, pgvector.NewVector(embedding), rawMetadata, expiresAt)
+	_, err := mt.db.ExecContext(ctx, query, id, content, pgvector.NewVector(embedding), rawMetadata, expiresAt)
 	if err != nil {
-		return "", fmt.Errorf("failed to store memory: %w", err)
+		return fmt.Errorf("failed to store memory: %w", err)
 	}
 
-	return id, nil
+	return nil
 }
 
 // Retrieve performs semantic search for memories
@@ -288,6 +346,51 @@ func (mt *MemoryTool) Retrieve(ctx context.Context, queryText string, options Re
 	return results, nil
 }
 
+// List returns memories ordered by creation time, newest first, optionally filtered by metadata
+func (mt *MemoryTool) List(ctx context.Context, filters map[string]interface{}, limit int, offset int) ([]*MemoryEntry, error) {
+	query := `
+		SELECT id, content, metadata, created_at, updated_at, expires_at
+		FROM memories
+		WHERE (expires_at IS NULL OR expires_at > NOW())
+	`
+	var args []interface{}
+	argIndex := 1
+	if len(filters) > 0 {
+		filterJSON, err := json.Marshal(filters)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal filters: %w", err)
+		}
+		query += fmt.Sprintf(" AND metadata @> $%d::jsonb", argIndex)
+		args = append(args, string(filterJSON))
+		argIndex++
+	}
+	query += fmt.Sprintf(" ORDER BY created_at DESC, id LIMIT $%d OFFSET $%d", argIndex, argIndex+1)
+	args = append(args, limit, offset)
+
+	rows, err := mt.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list memories: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []*MemoryEntry
+	for rows.Next() {
+		var mem MemoryEntry
+		var metadataBytes []byte
+		if err := rows.Scan(&mem.ID, &mem.Content, &metadataBytes, &mem.CreatedAt, &mem.UpdatedAt, &mem.ExpiresAt); err != nil {
+			return nil, fmt.Errorf("failed to scan memory: %w", err)
+		}
+		mem.Metadata = make(map[string]interface{})
+		if metadataBytes != nil {
+			if err := json.Unmarshal(metadataBytes, &mem.Metadata); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal metadata: %w", err)
+			}
+		}
+		entries = append(entries, &mem)
+	}
+	return entries, rows.Err()
+}
+
 // Update modifies an existing memory entry
 func (mt *MemoryTool) Update(ctx context.Context, id string, content string, metadata map[string]interface{}) error {
 	// Generate new embedding for updated content
@@ -343,6 +446,7 @@ const (
 	MemoryRetrieveToolName = "memory_retrieve"
 	MemoryUpdateToolName   = "memory_update"
 	MemoryDeleteToolName   = "memory_delete"
+	MemoryListToolName     = "memory_list"
 )
 
 var memoryTools = map[string]Tool{
@@ -387,11 +491,21 @@ var memoryTools = map[string]Tool{
 		Options: map[string]string{},
 		Run: runMemoryDelete,
 	},
+	MemoryListToolName: {
+		Name:        MemoryListToolName,
+		Description: "List stored memories, newest first",
+		Parameters: []Parameter{
+			{Name: "filters", Type: "object", Description: "Metadata filters to apply", Required: false},
+		},
+		Options:   map[string]string{},
+		Run:       runMemoryList,
+		Paginated: true,
+	},
 	"memory_operation": {
 		Name:        "memory_operation",
-		Description: "Perform memory operations (store, retrieve, update, delete)",
+		Description: "Perform memory operations (store, retrieve, update, delete, list)",
 		Parameters: []Parameter{
-			{Name: "operation", Type: "string", Description: "The operation to perform (store, retrieve, update, delete)", Required: true},
+			{Name: "operation", Type: "string", Description: "The operation to perform (store, retrieve, update, delete, list)", Required: true},
 			{Name: "arguments", Type: "object", Description: "Operation-specific arguments", Required: true},
 		},
 		Options: map[string]string{},
@@ -437,6 +551,29 @@ func InitializeMemoryTool(config MemoryConfig, embeddingProvider EmbeddingProvid
 	return nil
 }
 
+// InitializeMemoryToolWithDB initializes the global memory tool instance on an open
+// connection pool, config.DatabaseURL is ignored. The memory tool owns db and closes it
+// in CloseMemoryTool.
+func InitializeMemoryToolWithDB(db *sql.DB, config MemoryConfig, embeddingProvider EmbeddingProvider) error {
+	mt, err := newMemoryTool(db, config, embeddingProvider)
+	if err != nil {
+		return err
+	}
+	globalMemoryTool = mt
+	return nil
+}
+
+// CloseMemoryTool closes the database connection of the global memory tool instance,
+// the memory tools fail until it is initialized again
+func CloseMemoryTool() error {
+	if globalMemoryTool == nil {
+		return nil
+	}
+	err := globalMemoryTool.Close()
+	globalMemoryTool = nil
+	return err
+}
+
 // runMemoryStore handles the memory store operation
 func runMemoryStore(args map[string]any) (map[string]any, error) {
 	if globalMemoryTool == nil {
@@ -588,12 +725,54 @@ func runMemoryDelete(args map[string]any) (map[string]any, error) {
 	}, nil
 }
 
+// runMemoryList handles the memory list operation
+func runMemoryList(args map[string]any) (map[string]any, error) {
+	if globalMemoryTool == nil {
+		return nil, fmt.Errorf("memory tool not initialized")
+	}
+
+	offset, err := cursorOffset(args)
+	if err != nil {
+		return nil, err
+	}
+
+	var filters map[string]interface{}
+	if filterMap, ok := args["filters"].(map[string]any); ok {
+		filters = filterMap
+	}
+
+	// fetch one extra entry to know whether there is another page
+	entries, err := globalMemoryTool.List(context.Background(), filters, DefaultPageSize+1, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list memories: %w", err)
+	}
+	next := ""
+	if len(entries) > DefaultPageSize {
+		entries = entries[:DefaultPageSize]
+		next = strconv.Itoa(offset + DefaultPageSize)
+	}
+
+	serializableResults := make([]map[string]any, len(entries))
+	for i, entry := range entries {
+		serializableResults[i] = map[string]any{
+			"id":         entry.ID,
+			"content":    entry.Content,
+			"metadata":   entry.Metadata,
+			"created_at": entry.CreatedAt,
+		}
+	}
+
+	return setNextCursor(map[string]any{
+		"memories": serializableResults,
+	}, next), nil
+}
+
 // Alternative approach: Single tool with operation parameter
 var memoryOperationTool = Tool{
 	Name:        "memory_operation",
-	Description: "Perform memory operations (store, retrieve, update, delete)",
+	Description: "Perform memory operations (store, retrieve, update, delete, list)",
 	Parameters: []Parameter{
-		{Name: "operation", Type: "string", Description: "The operation to perform (store, retrieve, update, delete)", Required: true},
+		{Name: "operation", Type: "string", Description: "The operation to perform (store, retrieve, update, delete, list)", Required: true},
 		{Name: "arguments", Type: "object", Description: "Operation-specific arguments", Required: true},
 	},
 	Run: runMemoryOperation,
@@ -620,6 +799,8 @@ func runMemoryOperation(args map[string]any) (map[string]any, error) {
 		return runMemoryUpdate(arguments)
 	case "delete":
 		return runMemoryDelete(arguments)
+	case "list":
+		return runMemoryList(arguments)
 	default:
 		return nil, fmt.Errorf("unknown operation: %s", operation)
 	}
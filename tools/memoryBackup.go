@@ -0,0 +1,451 @@
+package tools
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pgvector/pgvector-go"
+)
+
+const (
+	// MemoryBackupVersion is written to the header of every backup
+	MemoryBackupVersion = 1
+
+	backupPrefix     = "memories-"
+	backupExtension  = ".jsonl.gz"
+	backupTimeFormat = "20060102T150405Z"
+	backupPageSize   = 500
+)
+
+// BackupStore holds memory backups by name
+type BackupStore interface {
+	Put(ctx context.Context, name string, r io.Reader, size int64) error
+	Get(ctx context.Context, name string) (io.ReadCloser, error)
+	// List returns the names of the stored backups
+	List(ctx context.Context) ([]string, error)
+	Delete(ctx context.Context, name string) error
+}
+
+// BackupInfo describes a completed backup
+type BackupInfo struct {
+	Name      string    `json:"name"`
+	Memories  int       `json:"memories"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// RestoreOptions configures RestoreBackup
+type RestoreOptions struct {
+	// Replace deletes all memories before restoring, otherwise memories with the
+	// same id are overwritten and the rest are kept
+	Replace bool
+}
+
+// BackupSchedule configures ScheduleBackups
+type BackupSchedule struct {
+	Interval time.Duration
+	// Keep is the number of backups to retain, all are kept when zero
+	Keep int
+	// OnBackup is called after every run, errors are printed when it is nil
+	OnBackup func(BackupInfo, error)
+}
+
+// backupHeader is the first line of a backup
+type backupHeader struct {
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// backupRecord is a row of the memories table, the embedding is kept so a restore
+// does not need the embedding provider
+type backupRecord struct {
+	ID        string                 `json:"id"`
+	Content   string                 `json:"content"`
+	Embedding []float32              `json:"embedding,omitempty"`
+	Metadata  map[string]interface{} `json:"metadata,omitempty"`
+	CreatedAt time.Time              `json:"created_at"`
+	UpdatedAt time.Time              `json:"updated_at"`
+	ExpiresAt *time.Time             `json:"expires_at,omitempty"`
+}
+
+// Backup writes every memory, including expired ones, to the store as gzipped JSONL
+func (mt *MemoryTool) Backup(ctx context.Context, store BackupStore) (BackupInfo, error) {
+	info := BackupInfo{CreatedAt: time.Now().UTC()}
+	info.Name = backupPrefix + info.CreatedAt.Format(backupTimeFormat) + backupExtension
+
+	// the backup is staged on disk so the store receives its size up front
+	tmp, err := os.CreateTemp("", "genai-backup-*")
+	if err != nil {
+		return info, fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	count, err := mt.Export(ctx, tmp)
+	if err != nil {
+		return info, err
+	}
+	info.Memories = count
+	if info.Size, err = tmp.Seek(0, io.SeekCurrent); err != nil {
+		return info, err
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return info, err
+	}
+	if err := store.Put(ctx, info.Name, tmp, info.Size); err != nil {
+		return info, fmt.Errorf("failed to store backup: %w", err)
+	}
+	return info, nil
+}
+
+// Export writes a gzipped backup of all memories to w and returns the number written
+func (mt *MemoryTool) Export(ctx context.Context, w io.Writer) (int, error) {
+	gz := gzip.NewWriter(w)
+	encoder := json.NewEncoder(gz)
+	if err := encoder.Encode(backupHeader{Version: MemoryBackupVersion, CreatedAt: time.Now().UTC()}); err != nil {
+		return 0, err
+	}
+	count := 0
+	lastID := ""
+	for {
+		records, err := mt.exportPage(ctx, lastID)
+		if err != nil {
+			return count, err
+		}
+		for _, record := range records {
+			if err := encoder.Encode(record); err != nil {
+				return count, fmt.Errorf("failed to write backup: %w", err)
+			}
+		}
+		count += len(records)
+		if len(records) < backupPageSize {
+			break
+		}
+		lastID = records[len(records)-1].ID
+	}
+	if err := gz.Close(); err != nil {
+		return count, fmt.Errorf("failed to write backup: %w", err)
+	}
+	return count, nil
+}
+
+// exportPage reads memories ordered by id after lastID, paging by key keeps the
+// export consistent while memories are added
+func (mt *MemoryTool) exportPage(ctx context.Context, lastID string) ([]backupRecord, error) {
+	query := `
+		SELECT id, content, embedding::text, metadata, created_at, updated_at, expires_at
+		FROM memories
+	`
+	args := []interface{}{}
+	if lastID != "" {
+		query += " WHERE id > $1"
+		args = append(args, lastID)
+	}
+	query += fmt.Sprintf(" ORDER BY id LIMIT %d", backupPageSize)
+
+	rows, err := mt.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read memories: %w", err)
+	}
+	defer rows.Close()
+
+	var records []backupRecord
+	for rows.Next() {
+		var record backupRecord
+		var embedding sql.NullString
+		var metadataBytes []byte
+		if err := rows.Scan(&record.ID, &record.Content, &embedding, &metadataBytes, &record.CreatedAt, &record.UpdatedAt, &record.ExpiresAt); err != nil {
+			return nil, fmt.Errorf("failed to scan memory: %w", err)
+		}
+		if embedding.Valid {
+			var vector pgvector.Vector
+			if err := vector.Parse(embedding.String); err != nil {
+				return nil, fmt.Errorf("failed to parse embedding of %s: %w", record.ID, err)
+			}
+			record.Embedding = vector.Slice()
+		}
+		if metadataBytes != nil {
+			if err := json.Unmarshal(metadataBytes, &record.Metadata); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal metadata: %w", err)
+			}
+		}
+		records = append(records, record)
+	}
+	return records, rows.Err()
+}
+
+// RestoreBackup loads the named backup from the store
+func (mt *MemoryTool) RestoreBackup(ctx context.Context, store BackupStore, name string, options RestoreOptions) (int, error) {
+	r, err := store.Get(ctx, name)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open backup: %w", err)
+	}
+	defer r.Close()
+	return mt.Restore(ctx, r, options)
+}
+
+// Restore loads a backup written by Export in a single transaction and returns the
+// number of memories restored
+func (mt *MemoryTool) Restore(ctx context.Context, r io.Reader, options RestoreOptions) (int, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return 0, fmt.Errorf("invalid backup: %w", err)
+	}
+	defer gz.Close()
+	scanner := bufio.NewScanner(gz)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+
+	var header backupHeader
+	if !scanner.Scan() {
+		return 0, fmt.Errorf("invalid backup: missing header")
+	}
+	if err := json.Unmarshal(scanner.Bytes(), &header); err != nil || header.Version == 0 {
+		return 0, fmt.Errorf("invalid backup: bad header")
+	}
+	if header.Version > MemoryBackupVersion {
+		return 0, fmt.Errorf("unsupported backup version %d", header.Version)
+	}
+
+	tx, err := mt.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+	if options.Replace {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM memories"); err != nil {
+			return 0, fmt.Errorf("failed to clear memories: %w", err)
+		}
+	}
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO memories (id, content, embedding, metadata, created_at, updated_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (id) DO UPDATE SET
+			content = EXCLUDED.content,
+			embedding = EXCLUDED.embedding,
+			metadata = EXCLUDED.metadata,
+			created_at = EXCLUDED.created_at,
+			updated_at = EXCLUDED.updated_at,
+			expires_at = EXCLUDED.expires_at
+	`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prepare restore: %w", err)
+	}
+	defer stmt.Close()
+
+	count := 0
+	for line := 2; scanner.Scan(); line++ {
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+		var record backupRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return 0, fmt.Errorf("invalid backup record on line %d: %w", line, err)
+		}
+		var embedding interface{}
+		if len(record.Embedding) > 0 {
+			embedding = pgvector.NewVector(record.Embedding)
+		}
+		var metadata interface{}
+		if record.Metadata != nil {
+			data, err := json.Marshal(record.Metadata)
+			if err != nil {
+				return 0, fmt.Errorf("failed to marshal metadata: %w", err)
+			}
+			metadata = json.RawMessage(data)
+		}
+		if _, err := stmt.ExecContext(ctx, record.ID, record.Content, embedding, metadata, record.CreatedAt, record.UpdatedAt, record.ExpiresAt); err != nil {
+			return 0, fmt.Errorf("failed to restore memory %s: %w", record.ID, err)
+		}
+		count++
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, fmt.Errorf("failed to read backup: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit restore: %w", err)
+	}
+	return count, nil
+}
+
+// LatestBackup returns the name of the newest backup in the store
+func LatestBackup(ctx context.Context, store BackupStore) (string, error) {
+	names, err := listBackups(ctx, store)
+	if err != nil {
+		return "", err
+	}
+	if len(names) == 0 {
+		return "", fmt.Errorf("no backups found")
+	}
+	return names[len(names)-1], nil
+}
+
+// PruneBackups deletes all but the newest keep backups
+func PruneBackups(ctx context.Context, store BackupStore, keep int) error {
+	names, err := listBackups(ctx, store)
+	if err != nil {
+		return err
+	}
+	for len(names) > keep {
+		if err := store.Delete(ctx, names[0]); err != nil {
+			return fmt.Errorf("failed to delete backup %s: %w", names[0], err)
+		}
+		names = names[1:]
+	}
+	return nil
+}
+
+// listBackups returns the backup names oldest first, the timestamp in the name sorts
+func listBackups(ctx context.Context, store BackupStore) ([]string, error) {
+	all, err := store.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list backups: %w", err)
+	}
+	var names []string
+	for _, name := range all {
+		if strings.HasPrefix(name, backupPrefix) && strings.HasSuffix(name, backupExtension) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// ScheduleBackups backs up the memories every Interval, starting immediately, until
+// ctx is done. Old backups are pruned after each successful run.
+func (mt *MemoryTool) ScheduleBackups(ctx context.Context, store BackupStore, schedule BackupSchedule) error {
+	if schedule.Interval <= 0 {
+		return fmt.Errorf("backup interval must be positive")
+	}
+	report := schedule.OnBackup
+	if report == nil {
+		report = func(info BackupInfo, err error) {
+			if err != nil {
+				Logger().Error(err, "Memory backup failed")
+			}
+		}
+	}
+	run := func() {
+		info, err := mt.Backup(ctx, store)
+		if err == nil && schedule.Keep > 0 {
+			err = PruneBackups(ctx, store, schedule.Keep)
+		}
+		report(info, err)
+	}
+	go func() {
+		ticker := time.NewTicker(schedule.Interval)
+		defer ticker.Stop()
+		run()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				run()
+			}
+		}
+	}()
+	return nil
+}
+
+// LocalBackupStore keeps backups in a directory
+type LocalBackupStore struct {
+	Dir string
+}
+
+func (s LocalBackupStore) Put(ctx context.Context, name string, r io.Reader, size int64) error {
+	if err := os.MkdirAll(s.Dir, 0o755); err != nil {
+		return err
+	}
+	// write to a temporary name so a partial backup is never listed
+	tmp, err := os.CreateTemp(s.Dir, ".tmp-"+name)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filepath.Join(s.Dir, filepath.Base(name)))
+}
+
+func (s LocalBackupStore) Get(ctx context.Context, name string) (io.ReadCloser, error) {
+	return os.Open(filepath.Join(s.Dir, filepath.Base(name)))
+}
+
+func (s LocalBackupStore) List(ctx context.Context) ([]string, error) {
+	entries, err := os.ReadDir(s.Dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			names = append(names, entry.Name())
+		}
+	}
+	return names, nil
+}
+
+func (s LocalBackupStore) Delete(ctx context.Context, name string) error {
+	return os.Remove(filepath.Join(s.Dir, filepath.Base(name)))
+}
+
+// S3BackupStore keeps backups in an S3 compatible or GCS bucket under Prefix
+type S3BackupStore struct {
+	Client *S3Client
+	Prefix string
+}
+
+func (s S3BackupStore) Put(ctx context.Context, name string, r io.Reader, size int64) error {
+	return s.Client.Put(ctx, path.Join(s.Prefix, name), r, size, "application/gzip")
+}
+
+func (s S3BackupStore) Get(ctx context.Context, name string) (io.ReadCloser, error) {
+	r, _, err := s.Client.Get(ctx, path.Join(s.Prefix, name))
+	return r, err
+}
+
+func (s S3BackupStore) List(ctx context.Context) ([]string, error) {
+	prefix := s.Prefix
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	var names []string
+	continuation := ""
+	for {
+		page, err := s.Client.List(ctx, prefix, "/", continuation, 1000)
+		if err != nil {
+			return nil, err
+		}
+		for _, object := range page.Objects {
+			names = append(names, strings.TrimPrefix(object.Key, prefix))
+		}
+		if page.NextContinuation == "" {
+			return names, nil
+		}
+		continuation = page.NextContinuation
+	}
+}
+
+func (s S3BackupStore) Delete(ctx context.Context, name string) error {
+	return s.Client.Delete(ctx, path.Join(s.Prefix, name))
+}
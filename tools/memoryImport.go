@@ -0,0 +1,207 @@
+package tools
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Memory export formats that can be imported
+const (
+	ImportMem0      = "mem0"
+	ImportLangChain = "langchain"
+)
+
+// ImportedMemory is a memory read from another framework's export
+type ImportedMemory struct {
+	Content  string
+	Metadata map[string]interface{}
+}
+
+// ImportResult summarizes an import
+type ImportResult struct {
+	Imported int      `json:"imported"`
+	Skipped  int      `json:"skipped"`
+	IDs      []string `json:"ids"`
+}
+
+// ParseMemoryExport reads an export in the given format, an empty format is
+// detected from the data
+func ParseMemoryExport(r io.Reader, format string) ([]ImportedMemory, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read export: %w", err)
+	}
+	if format == "" {
+		format = DetectMemoryExportFormat(data)
+	}
+	switch format {
+	case ImportMem0:
+		return parseMem0(data)
+	case ImportLangChain:
+		return parseLangChain(data)
+	}
+	return nil, fmt.Errorf("unknown export format %q, expected %s or %s", format, ImportMem0, ImportLangChain)
+}
+
+// DetectMemoryExportFormat guesses the format of an export. LangChain documents have
+// page_content fields, everything else is read as mem0.
+func DetectMemoryExportFormat(data []byte) string {
+	if bytes.Contains(data, []byte(`"page_content"`)) {
+		return ImportLangChain
+	}
+	return ImportMem0
+}
+
+// mem0Memory is an entry of mem0's get_all and export output
+type mem0Memory struct {
+	ID         string                 `json:"id"`
+	Memory     string                 `json:"memory"`
+	Text       string                 `json:"text"`
+	Hash       string                 `json:"hash"`
+	Metadata   map[string]interface{} `json:"metadata"`
+	Categories []string               `json:"categories"`
+	UserID     string                 `json:"user_id"`
+	AgentID    string                 `json:"agent_id"`
+	RunID      string                 `json:"run_id"`
+	CreatedAt  string                 `json:"created_at"`
+	UpdatedAt  string                 `json:"updated_at"`
+}
+
+// parseMem0 accepts a list of memories or an object holding them in results or memories
+func parseMem0(data []byte) ([]ImportedMemory, error) {
+	var entries []mem0Memory
+	if err := json.Unmarshal(data, &entries); err != nil {
+		var wrapped struct {
+			Results  []mem0Memory `json:"results"`
+			Memories []mem0Memory `json:"memories"`
+		}
+		if wrappedErr := json.Unmarshal(data, &wrapped); wrappedErr != nil {
+			return nil, fmt.Errorf("invalid mem0 export: %w", err)
+		}
+		entries = append(wrapped.Results, wrapped.Memories...)
+	}
+	memories := make([]ImportedMemory, 0, len(entries))
+	for _, entry := range entries {
+		content := entry.Memory
+		if content == "" {
+			content = entry.Text
+		}
+		metadata := copyMetadata(entry.Metadata)
+		setIfNotEmpty(metadata, "source_id", entry.ID)
+		setIfNotEmpty(metadata, "hash", entry.Hash)
+		setIfNotEmpty(metadata, "user_id", entry.UserID)
+		setIfNotEmpty(metadata, "agent_id", entry.AgentID)
+		setIfNotEmpty(metadata, "run_id", entry.RunID)
+		setIfNotEmpty(metadata, "original_created_at", entry.CreatedAt)
+		setIfNotEmpty(metadata, "original_updated_at", entry.UpdatedAt)
+		if len(entry.Categories) > 0 {
+			metadata["categories"] = entry.Categories
+		}
+		memories = append(memories, ImportedMemory{Content: content, Metadata: metadata})
+	}
+	return memories, nil
+}
+
+// langChainDocument is a Document as written by Document.dict() or dumpd(), which
+// wraps the fields in kwargs and uses id for the class path
+type langChainDocument struct {
+	ID          any                    `json:"id"`
+	PageContent string                 `json:"page_content"`
+	Metadata    map[string]interface{} `json:"metadata"`
+	Kwargs      *langChainDocument     `json:"kwargs"`
+}
+
+// parseLangChain accepts JSONL with one document per line or a JSON list of documents
+func parseLangChain(data []byte) ([]ImportedMemory, error) {
+	var documents []langChainDocument
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		if err := json.Unmarshal(trimmed, &documents); err != nil {
+			return nil, fmt.Errorf("invalid LangChain documents: %w", err)
+		}
+	} else {
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+		for line := 1; scanner.Scan(); line++ {
+			text := strings.TrimSpace(scanner.Text())
+			if text == "" {
+				continue
+			}
+			var document langChainDocument
+			if err := json.Unmarshal([]byte(text), &document); err != nil {
+				return nil, fmt.Errorf("invalid LangChain document on line %d: %w", line, err)
+			}
+			documents = append(documents, document)
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("failed to read LangChain documents: %w", err)
+		}
+	}
+	memories := make([]ImportedMemory, 0, len(documents))
+	for _, document := range documents {
+		if document.Kwargs != nil {
+			document = *document.Kwargs
+		}
+		metadata := copyMetadata(document.Metadata)
+		if id, ok := document.ID.(string); ok {
+			setIfNotEmpty(metadata, "source_id", id)
+		}
+		memories = append(memories, ImportedMemory{Content: document.PageContent, Metadata: metadata})
+	}
+	return memories, nil
+}
+
+// Import stores memories from another framework. Each memory's metadata records the
+// format it came from and its namespace, empty memories are skipped.
+func (mt *MemoryTool) Import(ctx context.Context, memories []ImportedMemory, format string, namespace string) (ImportResult, error) {
+	if namespace == "" {
+		namespace = defaultNamespace
+	}
+	importedAt := time.Now().UTC().Format(time.RFC3339)
+	var contents []string
+	var metadata []map[string]interface{}
+	result := ImportResult{IDs: []string{}}
+	for _, memory := range memories {
+		if strings.TrimSpace(memory.Content) == "" {
+			result.Skipped++
+			continue
+		}
+		m := copyMetadata(memory.Metadata)
+		if _, ok := m["namespace"]; !ok {
+			m["namespace"] = namespace
+		}
+		m["imported_from"] = format
+		m["imported_at"] = importedAt
+		contents = append(contents, memory.Content)
+		metadata = append(metadata, m)
+	}
+	for start := 0; start < len(contents); start += ingestBatchSize {
+		end := min(start+ingestBatchSize, len(contents))
+		ids, err := mt.StoreBatch(ctx, contents[start:end], metadata[start:end])
+		result.IDs = append(result.IDs, ids...)
+		result.Imported = len(result.IDs)
+		if err != nil {
+			return result, fmt.Errorf("imported %d of %d memories: %w", result.Imported, len(contents), err)
+		}
+	}
+	return result, nil
+}
+
+func copyMetadata(metadata map[string]interface{}) map[string]interface{} {
+	copied := make(map[string]interface{}, len(metadata))
+	for k, v := range metadata {
+		copied[k] = v
+	}
+	return copied
+}
+
+func setIfNotEmpty(metadata map[string]interface{}, key string, value string) {
+	if value != "" {
+		metadata[key] = value
+	}
+}
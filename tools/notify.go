@@ -0,0 +1,374 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	SlackToolName   = "slack_post_message"
+	DiscordToolName = "discord_post_message"
+
+	// Credentials read when notifications are not configured with ConfigureNotifications
+	SlackWebhookEnv   = "SLACK_WEBHOOK_URL"
+	SlackTokenEnv     = "SLACK_BOT_TOKEN"
+	SlackChannelEnv   = "SLACK_CHANNEL"
+	DiscordWebhookEnv = "DISCORD_WEBHOOK_URL"
+	DiscordTokenEnv   = "DISCORD_BOT_TOKEN"
+	DiscordChannelEnv = "DISCORD_CHANNEL_ID"
+
+	// longer messages are split into several posts
+	slackMessageLimit   = 4000
+	discordMessageLimit = 2000
+	maxMessageParts     = 10
+)
+
+// API base URLs, variables so they can be pointed at a test server
+var (
+	slackAPIURL   = "https://slack.com/api"
+	discordAPIURL = "https://discord.com/api/v10"
+)
+
+// NotificationConfig configures the Slack and Discord tools. A webhook posts to the
+// channel it was created for, a bot token posts to the channel argument or the
+// default channel.
+type NotificationConfig struct {
+	SlackWebhookURL   string
+	SlackBotToken     string
+	SlackChannel      string
+	DiscordWebhookURL string
+	DiscordBotToken   string
+	DiscordChannelID  string
+}
+
+var (
+	notificationConfig   *NotificationConfig
+	notificationConfigMu sync.RWMutex
+)
+
+// ConfigureNotifications replaces the configuration read from the CredentialsProvider
+func ConfigureNotifications(config NotificationConfig) {
+	notificationConfigMu.Lock()
+	defer notificationConfigMu.Unlock()
+	notificationConfig = &config
+}
+
+func getNotificationConfig(args map[string]any) (NotificationConfig, error) {
+	notificationConfigMu.RLock()
+	defer notificationConfigMu.RUnlock()
+	if notificationConfig != nil {
+		return *notificationConfig, nil
+	}
+	creds := credentialsFor(args)
+	config := NotificationConfig{
+		SlackWebhookURL:   creds.get(SlackWebhookEnv),
+		SlackBotToken:     creds.get(SlackTokenEnv),
+		SlackChannel:      creds.get(SlackChannelEnv),
+		DiscordWebhookURL: creds.get(DiscordWebhookEnv),
+		DiscordBotToken:   creds.get(DiscordTokenEnv),
+		DiscordChannelID:  creds.get(DiscordChannelEnv),
+	}
+	return config, creds.err
+}
+
+var notifyTools = map[string]Tool{
+	SlackToolName:   slackTool,
+	DiscordToolName: discordTool,
+}
+
+var slackTool = Tool{
+	Name:        SlackToolName,
+	Description: "Post a message to a Slack channel. Markdown is converted to Slack formatting and long messages are split.",
+	Parameters: []Parameter{
+		{
+			Name:        "text",
+			Type:        "string",
+			Description: "The message in markdown",
+			Required:    true,
+		},
+		{
+			Name:        "channel",
+			Type:        "string",
+			Description: "The channel name or ID, defaults to the configured channel",
+			Required:    false,
+		},
+	},
+	Options: map[string]string{},
+	Run:     PostSlackMessage,
+}
+
+var discordTool = Tool{
+	Name:        DiscordToolName,
+	Description: "Post a message to a Discord channel. Long messages are split.",
+	Parameters: []Parameter{
+		{
+			Name:        "text",
+			Type:        "string",
+			Description: "The message in markdown",
+			Required:    true,
+		},
+		{
+			Name:        "channel_id",
+			Type:        "string",
+			Description: "The channel ID, defaults to the configured channel",
+			Required:    false,
+		},
+	},
+	Options: map[string]string{},
+	Run:     PostDiscordMessage,
+}
+
+type slackArgs struct {
+	Text    string `json:"text"`
+	Channel string `json:"channel"`
+}
+
+type discordArgs struct {
+	Text      string `json:"text"`
+	ChannelID string `json:"channel_id"`
+}
+
+func PostSlackMessage(args map[string]any) (map[string]any, error) {
+	typed, err := DecodeArgs[slackArgs](args)
+	if err == nil && strings.TrimSpace(typed.Text) == "" {
+		err = NewToolError(fmt.Errorf("text is required"), "", true)
+	}
+	if err != nil {
+		return map[string]any{
+			"success": false,
+			"error":   err.Error(),
+		}, err
+	}
+	config, err := getNotificationConfig(args)
+	if err != nil {
+		return map[string]any{
+			"success": false,
+			"error":   err.Error(),
+		}, err
+	}
+	channel := typed.Channel
+	if channel == "" {
+		channel = config.SlackChannel
+	}
+	var post func(ctx context.Context, text string) error
+	switch {
+	case config.SlackBotToken != "":
+		if channel == "" {
+			err = NewToolError(fmt.Errorf("channel is required"), "Pass the channel to post to.", true)
+			break
+		}
+		post = func(ctx context.Context, text string) error {
+			return postSlackAPI(ctx, config.SlackBotToken, channel, text)
+		}
+	case config.SlackWebhookURL != "":
+		post = func(ctx context.Context, text string) error {
+			return postJSON(ctx, config.SlackWebhookURL, "", map[string]any{"text": text}, nil)
+		}
+	default:
+		err = fmt.Errorf("slack is not configured, set %s or %s", SlackWebhookEnv, SlackTokenEnv)
+	}
+	if err != nil {
+		return map[string]any{
+			"success": false,
+			"error":   err.Error(),
+		}, err
+	}
+	parts, err := postParts(splitMessage(SlackMarkdown(typed.Text), slackMessageLimit), post)
+	if err != nil {
+		return map[string]any{
+			"success": false,
+			"error":   err.Error(),
+			"posted":  parts,
+		}, err
+	}
+	return map[string]any{
+		"success": true,
+		"posted":  parts,
+	}, nil
+}
+
+func PostDiscordMessage(args map[string]any) (map[string]any, error) {
+	typed, err := DecodeArgs[discordArgs](args)
+	if err == nil && strings.TrimSpace(typed.Text) == "" {
+		err = NewToolError(fmt.Errorf("text is required"), "", true)
+	}
+	if err != nil {
+		return map[string]any{
+			"success": false,
+			"error":   err.Error(),
+		}, err
+	}
+	config, err := getNotificationConfig(args)
+	if err != nil {
+		return map[string]any{
+			"success": false,
+			"error":   err.Error(),
+		}, err
+	}
+	channel := typed.ChannelID
+	if channel == "" {
+		channel = config.DiscordChannelID
+	}
+	var post func(ctx context.Context, text string) error
+	switch {
+	case config.DiscordBotToken != "":
+		if channel == "" {
+			err = NewToolError(fmt.Errorf("channel_id is required"), "Pass the ID of the channel to post to.", true)
+			break
+		}
+		post = func(ctx context.Context, text string) error {
+			return postJSON(ctx, discordAPIURL+"/channels/"+channel+"/messages", "Bot "+config.DiscordBotToken, map[string]any{"content": text}, nil)
+		}
+	case config.DiscordWebhookURL != "":
+		post = func(ctx context.Context, text string) error {
+			return postJSON(ctx, config.DiscordWebhookURL, "", map[string]any{"content": text}, nil)
+		}
+	default:
+		err = fmt.Errorf("discord is not configured, set %s or %s", DiscordWebhookEnv, DiscordTokenEnv)
+	}
+	if err != nil {
+		return map[string]any{
+			"success": false,
+			"error":   err.Error(),
+		}, err
+	}
+	parts, err := postParts(splitMessage(typed.Text, discordMessageLimit), post)
+	if err != nil {
+		return map[string]any{
+			"success": false,
+			"error":   err.Error(),
+			"posted":  parts,
+		}, err
+	}
+	return map[string]any{
+		"success": true,
+		"posted":  parts,
+	}, nil
+}
+
+// postParts posts the parts in order and returns how many were posted
+func postParts(parts []string, post func(ctx context.Context, text string) error) (int, error) {
+	if len(parts) > maxMessageParts {
+		return 0, NewToolError(fmt.Errorf("message is too long, it would take %d posts", len(parts)), fmt.Sprintf("Shorten the message to fit in %d posts.", maxMessageParts), true)
+	}
+	for i, part := range parts {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		err := post(ctx, part)
+		cancel()
+		if err != nil {
+			return i, fmt.Errorf("failed to post message: %w", err)
+		}
+	}
+	return len(parts), nil
+}
+
+func postSlackAPI(ctx context.Context, token string, channel string, text string) error {
+	var resp struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+	}
+	if err := postJSON(ctx, slackAPIURL+"/chat.postMessage", "Bearer "+token, map[string]any{"channel": channel, "text": text}, &resp); err != nil {
+		return err
+	}
+	if !resp.OK {
+		return fmt.Errorf("slack error: %s", resp.Error)
+	}
+	return nil
+}
+
+// splitMessage splits text into parts of at most limit bytes, preferring line breaks.
+// A code block open at a split is closed and reopened in the next part.
+func splitMessage(text string, limit int) []string {
+	if len(text) <= limit {
+		return []string{text}
+	}
+	// leave room to close and reopen a code block
+	limit -= 8
+	var parts []string
+	var current strings.Builder
+	inCode := false
+	flush := func() {
+		part := current.String()
+		if inCode {
+			part += "\n```"
+		}
+		parts = append(parts, strings.TrimRight(part, "\n"))
+		current.Reset()
+		if inCode {
+			current.WriteString("```\n")
+		}
+	}
+	for _, line := range strings.SplitAfter(text, "\n") {
+		for len(line) > limit {
+			// a single line longer than the limit is cut at the last space that fits
+			cut := strings.LastIndex(line[:limit-current.Len()], " ")
+			if cut <= 0 {
+				cut = limit - current.Len()
+			}
+			current.WriteString(line[:cut])
+			line = strings.TrimLeft(line[cut:], " ")
+			flush()
+		}
+		if current.Len()+len(line) > limit {
+			flush()
+		}
+		current.WriteString(line)
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inCode = !inCode
+		}
+	}
+	if strings.TrimSpace(current.String()) != "" {
+		inCode = false
+		flush()
+	}
+	return parts
+}
+
+var (
+	markdownBold    = regexp.MustCompile(`\*\*(.+?)\*\*|__(.+?)__`)
+	markdownItalic  = regexp.MustCompile(`(^|[^*\w])\*([^*\s][^*]*?)\*([^*\w]|$)`)
+	markdownStrike  = regexp.MustCompile(`~~(.+?)~~`)
+	markdownLink    = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)\)`)
+	markdownHeading = regexp.MustCompile(`^#{1,6}\s+(.+)$`)
+	markdownBullet  = regexp.MustCompile(`^(\s*)[-*+]\s+`)
+)
+
+// SlackMarkdown converts common markdown to Slack mrkdwn, code is left unchanged
+func SlackMarkdown(text string) string {
+	lines := strings.Split(text, "\n")
+	inCode := false
+	for i, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inCode = !inCode
+			continue
+		}
+		if inCode {
+			continue
+		}
+		// split out inline code so it is not converted
+		segments := strings.Split(line, "`")
+		for j := 0; j < len(segments); j += 2 {
+			segments[j] = slackInline(segments[j], j == 0)
+		}
+		lines[i] = strings.Join(segments, "`")
+	}
+	return strings.Join(lines, "\n")
+}
+
+func slackInline(text string, lineStart bool) string {
+	if lineStart {
+		if m := markdownHeading.FindStringSubmatch(text); m != nil {
+			text = "**" + strings.Trim(m[1], "*") + "**"
+		}
+		text = markdownBullet.ReplaceAllString(text, "$1• ")
+	}
+	text = markdownItalic.ReplaceAllString(text, "${1}_${2}_${3}")
+	text = markdownBold.ReplaceAllString(text, "*$1$2*")
+	text = markdownStrike.ReplaceAllString(text, "~$1~")
+	text = markdownLink.ReplaceAllString(text, "<$2|$1>")
+	return text
+}
@@ -0,0 +1,156 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// notifyServer is a fake Slack and Discord API that records the posted messages
+type notifyServer struct {
+	mu    sync.Mutex
+	posts []map[string]string
+}
+
+func (s *notifyServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var body map[string]string
+	json.NewDecoder(r.Body).Decode(&body)
+	body["path"] = r.URL.Path
+	body["authorization"] = r.Header.Get("Authorization")
+	s.mu.Lock()
+	s.posts = append(s.posts, body)
+	s.mu.Unlock()
+	switch {
+	case r.URL.Path == "/slack/chat.postMessage" && body["channel"] == "missing":
+		fmt.Fprint(w, `{"ok":false,"error":"channel_not_found"}`)
+	case r.URL.Path == "/slack/chat.postMessage":
+		fmt.Fprint(w, `{"ok":true}`)
+	case r.URL.Path == "/discord/channels/broken/messages":
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprint(w, `{"message":"Missing Access"}`)
+	default:
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// useNotifyServer points the Slack and Discord API URLs at a fake server
+func useNotifyServer(t *testing.T) (*notifyServer, *httptest.Server) {
+	fake := &notifyServer{}
+	srv := httptest.NewServer(fake)
+	slackURL, discordURL := slackAPIURL, discordAPIURL
+	slackAPIURL, discordAPIURL = srv.URL+"/slack", srv.URL+"/discord"
+	t.Cleanup(func() {
+		slackAPIURL, discordAPIURL = slackURL, discordURL
+		srv.Close()
+	})
+	return fake, srv
+}
+
+func TestPostSlackMessage(t *testing.T) {
+	fake, srv := useNotifyServer(t)
+	env := map[string]string{SlackTokenEnv: "xoxb-token", SlackChannelEnv: "#alerts"}
+
+	result, err := PostSlackMessage(map[string]any{"text": "# Deploy\n- **web** is [live](https://example.com)", EnvArg: env})
+	if err != nil || result["posted"] != 1 {
+		t.Fatalf("post %v, %v", result, err)
+	}
+	post := fake.posts[0]
+	if post["authorization"] != "Bearer xoxb-token" || post["channel"] != "#alerts" || post["text"] != "*Deploy*\n• *web* is <https://example.com|live>" {
+		t.Errorf("post %v", post)
+	}
+	if _, err := PostSlackMessage(map[string]any{"text": "hi", "channel": "missing", EnvArg: env}); err == nil || !strings.Contains(err.Error(), "channel_not_found") {
+		t.Errorf("Slack error %v", err)
+	}
+
+	// a configured webhook replaces the credentials
+	ConfigureNotifications(NotificationConfig{SlackWebhookURL: srv.URL + "/hooks/slack"})
+	t.Cleanup(func() {
+		notificationConfigMu.Lock()
+		defer notificationConfigMu.Unlock()
+		notificationConfig = nil
+	})
+	result, err = PostSlackMessage(map[string]any{"text": "hi", EnvArg: env})
+	if err != nil || result["posted"] != 1 {
+		t.Fatalf("webhook post %v, %v", result, err)
+	}
+	if post := fake.posts[len(fake.posts)-1]; post["path"] != "/hooks/slack" || post["authorization"] != "" || post["text"] != "hi" {
+		t.Errorf("webhook post %v", post)
+	}
+}
+
+func TestPostDiscordMessage(t *testing.T) {
+	fake, _ := useNotifyServer(t)
+	env := map[string]string{DiscordTokenEnv: "bot-token"}
+
+	if _, err := PostDiscordMessage(map[string]any{"text": "hi", EnvArg: env}); err == nil || !strings.Contains(err.Error(), "channel_id is required") {
+		t.Errorf("missing channel error %v", err)
+	}
+	text := strings.Repeat("word ", 500)
+	result, err := PostDiscordMessage(map[string]any{"text": text, "channel_id": "123", EnvArg: env})
+	if err != nil || result["posted"] != 2 {
+		t.Fatalf("post %v, %v", result, err)
+	}
+	for _, post := range fake.posts {
+		if post["path"] != "/discord/channels/123/messages" || post["authorization"] != "Bot bot-token" || len(post["content"]) > discordMessageLimit {
+			t.Errorf("post to %s with %q and %d bytes", post["path"], post["authorization"], len(post["content"]))
+		}
+	}
+
+	result, err = PostDiscordMessage(map[string]any{"text": "hi", "channel_id": "broken", EnvArg: env})
+	if err == nil || !strings.Contains(err.Error(), "Missing Access") || result["posted"] != 0 {
+		t.Errorf("forbidden post %v, %v", result, err)
+	}
+	posts := len(fake.posts)
+	if _, err := PostDiscordMessage(map[string]any{"text": strings.Repeat("x", 11*discordMessageLimit), "channel_id": "123", EnvArg: env}); err == nil {
+		t.Error("posted a message longer than the part limit")
+	}
+	if len(fake.posts) != posts {
+		t.Error("posted part of a message that is too long")
+	}
+	if _, err := PostDiscordMessage(map[string]any{"text": "hi", EnvArg: map[string]string{}}); err == nil || !strings.Contains(err.Error(), "not configured") {
+		t.Errorf("unconfigured error %v", err)
+	}
+}
+
+func TestSplitMessage(t *testing.T) {
+	text := "intro\n```\n" + strings.Repeat("line of code\n", 10) + "```\noutro"
+	parts := splitMessage(text, 60)
+	if len(parts) < 2 {
+		t.Fatalf("parts %q", parts)
+	}
+	for _, part := range parts {
+		if len(part) > 60 {
+			t.Errorf("part of %d bytes: %q", len(part), part)
+		}
+		// code blocks are closed at a split and reopened in the next part
+		if strings.Count(part, "```")%2 != 0 {
+			t.Errorf("unbalanced code block in %q", part)
+		}
+	}
+	if got := splitMessage("short", 60); len(got) != 1 || got[0] != "short" {
+		t.Errorf("short message %q", got)
+	}
+}
+
+func TestSlackMarkdown(t *testing.T) {
+	tests := []struct {
+		markdown string
+		want     string
+	}{
+		{"**bold** and *italic*", "*bold* and _italic_"},
+		{"~~old~~ [docs](https://example.com/a_b)", "~old~ <https://example.com/a_b|docs>"},
+		{"## Results", "*Results*"},
+		{"  * nested", "  • nested"},
+		{"keep `**code**` as is", "keep `**code**` as is"},
+		{"```\n**code block**\n```", "```\n**code block**\n```"},
+	}
+	for _, tt := range tests {
+		if got := SlackMarkdown(tt.markdown); got != tt.want {
+			t.Errorf("SlackMarkdown(%q) = %q, want %q", tt.markdown, got, tt.want)
+		}
+	}
+}
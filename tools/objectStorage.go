@@ -0,0 +1,371 @@
+package tools
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	ObjectListToolName  = "object_list"
+	ObjectReadToolName  = "object_read"
+	ObjectWriteToolName = "object_write"
+
+	// Credentials read when the object storage tools are not configured
+	ObjectStorageBucketEnv = "OBJECT_STORAGE_BUCKET"
+	ObjectStoragePrefixEnv = "OBJECT_STORAGE_PREFIX"
+
+	DefaultObjectMaxRead  = 1 << 20
+	DefaultObjectMaxWrite = 1 << 20
+
+	objectPageSize = 100
+)
+
+// ObjectStorageConfig configures the object storage tools
+type ObjectStorageConfig struct {
+	S3Config
+	// Prefix sandboxes the tools, keys are relative to it and cannot leave it
+	Prefix string
+	// MaxReadBytes and MaxWriteBytes cap object sizes, the defaults are 1MB
+	MaxReadBytes  int64
+	MaxWriteBytes int64
+}
+
+type objectStorage struct {
+	client *S3Client
+	config ObjectStorageConfig
+}
+
+var (
+	objectStore *objectStorage
+	// scopedObjectStores are created from the credentials provider, one per credential scope
+	scopedObjectStores = map[string]*objectStorage{}
+	objectStoreMu      sync.Mutex
+)
+
+// ConfigureObjectStorage sets the bucket used by the object storage tools. Without
+// it the bucket is read from the OBJECT_STORAGE_BUCKET credential and the keys from AWS_*.
+func ConfigureObjectStorage(config ObjectStorageConfig) error {
+	store, err := newObjectStorage(config)
+	if err != nil {
+		return err
+	}
+	objectStoreMu.Lock()
+	defer objectStoreMu.Unlock()
+	objectStore = store
+	return nil
+}
+
+func newObjectStorage(config ObjectStorageConfig) (*objectStorage, error) {
+	if config.MaxReadBytes <= 0 {
+		config.MaxReadBytes = DefaultObjectMaxRead
+	}
+	if config.MaxWriteBytes <= 0 {
+		config.MaxWriteBytes = DefaultObjectMaxWrite
+	}
+	config.Prefix = strings.Trim(config.Prefix, "/")
+	client, err := NewS3Client(config.S3Config)
+	if err != nil {
+		return nil, err
+	}
+	return &objectStorage{client: client, config: config}, nil
+}
+
+func getObjectStorage(args map[string]any) (*objectStorage, error) {
+	scope, _ := args[CredentialScopeArg].(string)
+	objectStoreMu.Lock()
+	defer objectStoreMu.Unlock()
+	if objectStore != nil {
+		return objectStore, nil
+	}
+	if store, ok := scopedObjectStores[scope]; ok {
+		return store, nil
+	}
+	creds := credentialsFor(args)
+	config := ObjectStorageConfig{S3Config: s3Config(creds.get), Prefix: creds.get(ObjectStoragePrefixEnv)}
+	config.Bucket = creds.get(ObjectStorageBucketEnv)
+	if creds.err != nil {
+		return nil, creds.err
+	}
+	if config.Bucket == "" {
+		return nil, fmt.Errorf("object storage is not configured, set %s", ObjectStorageBucketEnv)
+	}
+	store, err := newObjectStorage(config)
+	if err != nil {
+		return nil, err
+	}
+	scopedObjectStores[scope] = store
+	return store, nil
+}
+
+// key maps a key relative to the sandbox prefix to the bucket key
+func (s *objectStorage) key(relative string) (string, error) {
+	if relative == "" {
+		return "", NewToolError(fmt.Errorf("key is required"), "", false)
+	}
+	for _, segment := range strings.Split(relative, "/") {
+		if segment == ".." {
+			return "", NewToolError(fmt.Errorf("invalid key: %s", relative), "Keys cannot contain .. segments.", false)
+		}
+	}
+	cleaned := strings.TrimPrefix(path.Clean("/"+relative), "/")
+	if s.config.Prefix == "" {
+		return cleaned, nil
+	}
+	return s.config.Prefix + "/" + cleaned, nil
+}
+
+// relative strips the sandbox prefix from a bucket key
+func (s *objectStorage) relative(key string) string {
+	if s.config.Prefix == "" {
+		return key
+	}
+	return strings.TrimPrefix(key, s.config.Prefix+"/")
+}
+
+var objectStorageTools = map[string]Tool{
+	ObjectListToolName:  objectListTool,
+	ObjectReadToolName:  objectReadTool,
+	ObjectWriteToolName: objectWriteTool,
+}
+
+var objectListTool = Tool{
+	Name:        ObjectListToolName,
+	Description: "List objects in the object storage bucket. Keys ending in / are folders that can be listed with the prefix argument.",
+	Parameters: []Parameter{
+		{
+			Name:        "prefix",
+			Type:        "string",
+			Description: "Only list keys starting with this prefix, e.g. reports/",
+			Required:    false,
+		},
+	},
+	Options:   map[string]string{},
+	Run:       ListObjects,
+	Paginated: true,
+}
+
+var objectReadTool = Tool{
+	Name:        ObjectReadToolName,
+	Description: "Read an object from the object storage bucket. Binary objects are returned base64 encoded.",
+	Parameters: []Parameter{
+		{
+			Name:        "key",
+			Type:        "string",
+			Description: "The key of the object",
+			Required:    true,
+		},
+	},
+	Options: map[string]string{},
+	Run:     ReadObject,
+}
+
+var objectWriteTool = Tool{
+	Name:        ObjectWriteToolName,
+	Description: "Write text to an object in the object storage bucket, replacing it if it exists",
+	Parameters: []Parameter{
+		{
+			Name:        "key",
+			Type:        "string",
+			Description: "The key of the object",
+			Required:    true,
+		},
+		{
+			Name:        "content",
+			Type:        "string",
+			Description: "The content to write",
+			Required:    true,
+		},
+		{
+			Name:        "content_type",
+			Type:        "string",
+			Description: "The MIME type of the content, defaults to text/plain",
+			Required:    false,
+		},
+	},
+	Options: map[string]string{},
+	Run:     WriteObject,
+}
+
+type objectListArgs struct {
+	Prefix string `json:"prefix"`
+	Cursor string `json:"cursor"`
+}
+
+type objectReadArgs struct {
+	Key string `json:"key"`
+}
+
+type objectWriteArgs struct {
+	Key         string `json:"key"`
+	Content     string `json:"content"`
+	ContentType string `json:"content_type"`
+}
+
+func ListObjects(args map[string]any) (map[string]any, error) {
+	typed, err := DecodeArgs[objectListArgs](args)
+	if err != nil {
+		return map[string]any{
+			"success": false,
+			"error":   err.Error(),
+		}, err
+	}
+	store, err := getObjectStorage(args)
+	if err != nil {
+		return map[string]any{
+			"success": false,
+			"error":   err.Error(),
+		}, err
+	}
+	prefix := store.config.Prefix
+	if prefix != "" {
+		prefix += "/"
+	}
+	if typed.Prefix != "" {
+		if strings.Contains("/"+typed.Prefix+"/", "/../") {
+			err := NewToolError(fmt.Errorf("invalid prefix: %s", typed.Prefix), "Prefixes cannot contain .. segments.", false)
+			return map[string]any{
+				"success": false,
+				"error":   err.Error(),
+			}, err
+		}
+		prefix += strings.TrimPrefix(typed.Prefix, "/")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	page, err := store.client.List(ctx, prefix, "/", typed.Cursor, objectPageSize)
+	if err != nil {
+		return map[string]any{
+			"success": false,
+			"error":   err.Error(),
+		}, err
+	}
+	objects := make([]S3Object, 0, len(page.Objects))
+	for _, object := range page.Objects {
+		object.Key = store.relative(object.Key)
+		objects = append(objects, object)
+	}
+	folders := make([]string, 0, len(page.Prefixes))
+	for _, folder := range page.Prefixes {
+		folders = append(folders, store.relative(folder))
+	}
+	return setNextCursor(map[string]any{
+		"success": true,
+		"objects": objects,
+		"folders": folders,
+	}, page.NextContinuation), nil
+}
+
+func ReadObject(args map[string]any) (map[string]any, error) {
+	typed, err := DecodeArgs[objectReadArgs](args)
+	if err != nil {
+		return map[string]any{
+			"success": false,
+			"error":   err.Error(),
+		}, err
+	}
+	store, err := getObjectStorage(args)
+	if err != nil {
+		return map[string]any{
+			"success": false,
+			"error":   err.Error(),
+		}, err
+	}
+	key, err := store.key(typed.Key)
+	if err != nil {
+		return map[string]any{
+			"success": false,
+			"error":   err.Error(),
+		}, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+	body, size, err := store.client.Get(ctx, key)
+	if err != nil {
+		return map[string]any{
+			"success": false,
+			"error":   err.Error(),
+		}, err
+	}
+	defer body.Close()
+	maxBytes := store.config.MaxReadBytes
+	if size > maxBytes {
+		err := NewToolError(fmt.Errorf("object is %d bytes, the limit is %d", size, maxBytes), "", false)
+		return map[string]any{
+			"success": false,
+			"error":   err.Error(),
+		}, err
+	}
+	data, err := io.ReadAll(io.LimitReader(body, maxBytes+1))
+	if err == nil && int64(len(data)) > maxBytes {
+		err = NewToolError(fmt.Errorf("object is larger than the limit of %d bytes", maxBytes), "", false)
+	}
+	if err != nil {
+		return map[string]any{
+			"success": false,
+			"error":   err.Error(),
+		}, err
+	}
+	result := map[string]any{
+		"success": true,
+		"key":     typed.Key,
+		"size":    len(data),
+	}
+	if utf8.Valid(data) {
+		result["content"] = string(data)
+	} else {
+		result["content"] = base64.StdEncoding.EncodeToString(data)
+		result["encoding"] = "base64"
+	}
+	return result, nil
+}
+
+func WriteObject(args map[string]any) (map[string]any, error) {
+	typed, err := DecodeArgs[objectWriteArgs](args)
+	if err != nil {
+		return map[string]any{
+			"success": false,
+			"error":   err.Error(),
+		}, err
+	}
+	store, err := getObjectStorage(args)
+	if err != nil {
+		return map[string]any{
+			"success": false,
+			"error":   err.Error(),
+		}, err
+	}
+	key, err := store.key(typed.Key)
+	if err == nil && int64(len(typed.Content)) > store.config.MaxWriteBytes {
+		err = NewToolError(fmt.Errorf("content is %d bytes, the limit is %d", len(typed.Content), store.config.MaxWriteBytes), "Split the content across several objects.", true)
+	}
+	if err != nil {
+		return map[string]any{
+			"success": false,
+			"error":   err.Error(),
+		}, err
+	}
+	contentType := typed.ContentType
+	if contentType == "" {
+		contentType = "text/plain; charset=utf-8"
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+	if err := store.client.Put(ctx, key, strings.NewReader(typed.Content), int64(len(typed.Content)), contentType); err != nil {
+		return map[string]any{
+			"success": false,
+			"error":   err.Error(),
+		}, err
+	}
+	return map[string]any{
+		"success": true,
+		"key":     typed.Key,
+		"size":    len(typed.Content),
+	}, nil
+}
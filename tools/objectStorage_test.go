@@ -0,0 +1,350 @@
+package tools
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeBucket is an S3 compatible server that keeps objects in memory and rejects
+// requests whose SigV4 signature does not match secretKey
+type fakeBucket struct {
+	bucket    string
+	secretKey string
+
+	mu      sync.Mutex
+	objects map[string]string
+}
+
+func newFakeBucket(t *testing.T, bucket string, secretKey string) *httptest.Server {
+	b := &fakeBucket{bucket: bucket, secretKey: secretKey, objects: map[string]string{}}
+	srv := httptest.NewServer(b)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func (b *fakeBucket) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := b.verify(r); err != nil {
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprintf(w, "<Error><Code>SignatureDoesNotMatch</Code><Message>%s</Message></Error>", err)
+		return
+	}
+	key, ok := strings.CutPrefix(r.URL.Path, "/"+b.bucket+"/")
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, "<Error><Code>NoSuchBucket</Code><Message>The bucket does not exist</Message></Error>")
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch {
+	case r.Method == http.MethodPut:
+		data, _ := io.ReadAll(r.Body)
+		b.objects[key] = string(data)
+	case r.Method == http.MethodGet && key == "":
+		b.list(w, r)
+	case r.Method == http.MethodGet:
+		data, ok := b.objects[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+		io.WriteString(w, data)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// list answers a ListObjectsV2 request, the continuation token is the last key returned
+func (b *fakeBucket) list(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	prefix, delimiter, after := query.Get("prefix"), query.Get("delimiter"), query.Get("continuation-token")
+	maxKeys, _ := strconv.Atoi(query.Get("max-keys"))
+	keys := make([]string, 0, len(b.objects))
+	for key := range b.objects {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	type content struct {
+		Key  string `xml:"Key"`
+		Size int    `xml:"Size"`
+	}
+	type commonPrefix struct {
+		Prefix string `xml:"Prefix"`
+	}
+	var result struct {
+		XMLName               xml.Name       `xml:"ListBucketResult"`
+		Contents              []content      `xml:"Contents"`
+		CommonPrefixes        []commonPrefix `xml:"CommonPrefixes"`
+		IsTruncated           bool           `xml:"IsTruncated"`
+		NextContinuationToken string         `xml:"NextContinuationToken,omitempty"`
+	}
+	seen := map[string]bool{}
+	for _, key := range keys {
+		if !strings.HasPrefix(key, prefix) || key <= after {
+			continue
+		}
+		if maxKeys > 0 && len(result.Contents)+len(result.CommonPrefixes) == maxKeys {
+			result.IsTruncated = true
+			break
+		}
+		result.NextContinuationToken = key
+		if i := strings.Index(key[len(prefix):], delimiter); delimiter != "" && i >= 0 {
+			folder := key[:len(prefix)+i+1]
+			if !seen[folder] {
+				seen[folder] = true
+				result.CommonPrefixes = append(result.CommonPrefixes, commonPrefix{folder})
+			}
+			continue
+		}
+		result.Contents = append(result.Contents, content{key, len(b.objects[key])})
+	}
+	if !result.IsTruncated {
+		result.NextContinuationToken = ""
+	}
+	xml.NewEncoder(w).Encode(result)
+}
+
+// verify recomputes the SigV4 signature of a request from what the server received
+func (b *fakeBucket) verify(r *http.Request) error {
+	credential, signedHeaders, signature, ok := parseSigV4(r.Header.Get("Authorization"))
+	if !ok {
+		return fmt.Errorf("missing or malformed Authorization header")
+	}
+	scope := strings.SplitN(credential, "/", 2)[1]
+	fields := strings.Split(scope, "/")
+	var canonicalHeaders strings.Builder
+	for _, name := range strings.Split(signedHeaders, ";") {
+		value := r.Header.Get(name)
+		if name == "host" {
+			value = r.Host
+		}
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(value) + "\n")
+	}
+	canonicalRequest := strings.Join([]string{
+		r.Method,
+		r.URL.EscapedPath(),
+		r.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		r.Header.Get("X-Amz-Content-Sha256"),
+	}, "\n")
+	hashed := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + r.Header.Get("X-Amz-Date") + "\n" + scope + "\n" + hex.EncodeToString(hashed[:])
+	key := []byte("AWS4" + b.secretKey)
+	for _, part := range fields {
+		key = hmacSHA256(key, part)
+	}
+	if want := hex.EncodeToString(hmacSHA256(key, stringToSign)); signature != want {
+		return fmt.Errorf("signature %s, want %s for\n%s", signature, want, canonicalRequest)
+	}
+	return nil
+}
+
+// parseSigV4 splits an AWS4-HMAC-SHA256 Authorization header into its fields
+func parseSigV4(authorization string) (credential, signedHeaders, signature string, ok bool) {
+	rest, ok := strings.CutPrefix(authorization, "AWS4-HMAC-SHA256 ")
+	if !ok {
+		return "", "", "", false
+	}
+	for _, field := range strings.Split(rest, ", ") {
+		name, value, _ := strings.Cut(field, "=")
+		switch name {
+		case "Credential":
+			credential = value
+		case "SignedHeaders":
+			signedHeaders = value
+		case "Signature":
+			signature = value
+		}
+	}
+	return credential, signedHeaders, signature, credential != "" && signedHeaders != "" && signature != ""
+}
+
+// resetObjectStorage clears the configured and cached object stores after a test
+func resetObjectStorage(t *testing.T) {
+	t.Cleanup(func() {
+		objectStoreMu.Lock()
+		defer objectStoreMu.Unlock()
+		objectStore = nil
+		scopedObjectStores = map[string]*objectStorage{}
+	})
+}
+
+func TestS3Sign(t *testing.T) {
+	client, err := NewS3Client(S3Config{Region: "eu-west-1", Bucket: "reports", AccessKey: "AKIDEXAMPLE", SecretKey: "secret", SessionToken: "session"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, err := http.NewRequest(http.MethodGet, "https://reports.s3.eu-west-1.amazonaws.com/q1%20summary.txt", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now, _ := http.ParseTime("Mon, 15 Jan 2024 10:00:00 GMT")
+	client.sign(req, now.UTC())
+	credential, signedHeaders, _, ok := parseSigV4(req.Header.Get("Authorization"))
+	if !ok || credential != "AKIDEXAMPLE/20240115/eu-west-1/s3/aws4_request" {
+		t.Errorf("authorization %q", req.Header.Get("Authorization"))
+	}
+	if signedHeaders != "host;x-amz-content-sha256;x-amz-date;x-amz-security-token" {
+		t.Errorf("signed headers %s", signedHeaders)
+	}
+	if req.Header.Get("X-Amz-Date") != "20240115T100000Z" || req.Header.Get("X-Amz-Security-Token") != "session" {
+		t.Errorf("headers %v", req.Header)
+	}
+	req.Host = req.URL.Host
+	if err := (&fakeBucket{secretKey: "secret"}).verify(req); err != nil {
+		t.Error(err)
+	}
+	if err := (&fakeBucket{secretKey: "other"}).verify(req); err == nil {
+		t.Error("a signature with another secret verified")
+	}
+}
+
+func TestObjectStorageS3(t *testing.T) {
+	resetObjectStorage(t)
+	srv := newFakeBucket(t, "reports", "secret")
+	err := ConfigureObjectStorage(ObjectStorageConfig{
+		S3Config:     S3Config{Endpoint: srv.URL, Bucket: "reports", AccessKey: "AKIDEXAMPLE", SecretKey: "secret"},
+		Prefix:       "/acme/",
+		MaxReadBytes: 16,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for key, content := range map[string]string{"q1 summary.txt": "revenue up", "2024/jan.csv": "a,b\n1,2", "2024/feb.csv": "a,b\n3,4"} {
+		result, err := WriteObject(map[string]any{"key": key, "content": content})
+		if err != nil || result["size"] != len(content) {
+			t.Fatalf("write %s: %v, %v", key, result, err)
+		}
+	}
+	if _, err := WriteObject(map[string]any{"key": "../globex/notes.txt", "content": "x"}); err == nil {
+		t.Error("wrote outside the prefix")
+	}
+
+	result, err := ReadObject(map[string]any{"key": "q1 summary.txt"})
+	if err != nil || result["content"] != "revenue up" {
+		t.Errorf("read %v, %v", result, err)
+	}
+	if _, err := ReadObject(map[string]any{"key": "missing.txt"}); err == nil || !strings.Contains(err.Error(), ErrObjectNotFound.Error()) {
+		t.Errorf("read of a missing key: %v", err)
+	}
+	WriteObject(map[string]any{"key": "large.txt", "content": strings.Repeat("x", 17)})
+	if _, err := ReadObject(map[string]any{"key": "large.txt"}); err == nil || !strings.Contains(err.Error(), "limit") {
+		t.Errorf("read of a large object: %v", err)
+	}
+
+	result, err = ListObjects(map[string]any{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	objects := result["objects"].([]S3Object)
+	if len(objects) != 2 || objects[0].Key != "large.txt" || objects[1].Key != "q1 summary.txt" || objects[1].Size != 10 {
+		t.Errorf("objects %+v", objects)
+	}
+	if folders := result["folders"].([]string); len(folders) != 1 || folders[0] != "2024/" {
+		t.Errorf("folders %v", folders)
+	}
+	result, err = ListObjects(map[string]any{"prefix": "2024/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if objects := result["objects"].([]S3Object); len(objects) != 2 || objects[0].Key != "2024/feb.csv" {
+		t.Errorf("objects %+v", objects)
+	}
+	if _, err := ListObjects(map[string]any{"prefix": "../"}); err == nil {
+		t.Error("listed outside the prefix")
+	}
+}
+
+func TestS3ClientList(t *testing.T) {
+	srv := newFakeBucket(t, "reports", "secret")
+	client, err := NewS3Client(S3Config{Endpoint: srv.URL, Bucket: "reports", AccessKey: "AKIDEXAMPLE", SecretKey: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"a.txt", "b.txt", "c.txt"} {
+		if err := client.Put(context.Background(), key, strings.NewReader(key), int64(len(key)), "text/plain"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var keys []string
+	continuation := ""
+	for pages := 0; pages < 5; pages++ {
+		page, err := client.List(context.Background(), "", "", continuation, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, object := range page.Objects {
+			keys = append(keys, object.Key)
+		}
+		if continuation = page.NextContinuation; continuation == "" {
+			break
+		}
+	}
+	if strings.Join(keys, ",") != "a.txt,b.txt,c.txt" {
+		t.Errorf("listed %v", keys)
+	}
+
+	wrongKey, err := NewS3Client(S3Config{Endpoint: srv.URL, Bucket: "reports", AccessKey: "AKIDEXAMPLE", SecretKey: "wrong"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wrongKey.List(context.Background(), "", "", "", 0); err == nil || !strings.Contains(err.Error(), "403: SignatureDoesNotMatch") {
+		t.Errorf("list with a wrong key: %v", err)
+	}
+}
+
+func TestObjectStorageGCS(t *testing.T) {
+	resetObjectStorage(t)
+	// GCS serves the S3 listing format from its XML API, with its own extra elements
+	var paths []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path+"?"+r.URL.RawQuery)
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=GOOGHMAC/") {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		fmt.Fprint(w, `<?xml version="1.0" encoding="UTF-8"?>
+<ListBucketResult xmlns="http://doc.s3.amazonaws.com/2006-03-01">
+  <Name>assets</Name><Prefix>tenant/</Prefix><KeyCount>1</KeyCount>
+  <Contents><Key>tenant/logo.png</Key><Generation>1705312800000000</Generation>
+    <LastModified>2024-01-15T10:00:00.000Z</LastModified><Size>2048</Size></Contents>
+  <IsTruncated>true</IsTruncated><NextContinuationToken>CgpsbG9nby5wbmc=</NextContinuationToken>
+</ListBucketResult>`)
+	}))
+	defer srv.Close()
+	env := map[string]string{
+		ObjectStorageBucketEnv:  "assets",
+		ObjectStoragePrefixEnv:  "tenant",
+		"AWS_ENDPOINT_URL_S3":   srv.URL,
+		"AWS_ACCESS_KEY_ID":     "GOOGHMAC",
+		"AWS_SECRET_ACCESS_KEY": "secret",
+	}
+	result, err := ListObjects(map[string]any{EnvArg: env})
+	if err != nil {
+		t.Fatal(err)
+	}
+	objects := result["objects"].([]S3Object)
+	if len(objects) != 1 || objects[0].Key != "logo.png" || objects[0].Size != 2048 || objects[0].LastModified.Year() != 2024 {
+		t.Errorf("objects %+v", objects)
+	}
+	if result[NextCursorKey] != "CgpsbG9nby5wbmc=" {
+		t.Errorf("cursor %v", result[NextCursorKey])
+	}
+	if len(paths) != 1 || paths[0] != "/assets/?delimiter=%2F&list-type=2&max-keys=100&prefix=tenant%2F" {
+		t.Errorf("requested %v", paths)
+	}
+}
@@ -14,7 +14,7 @@ type OllamaFunctionProperties struct {
 }
 
 func RunOllamaTool(toolName string, args map[string]any) (any, error) {
-	tool, ok := toolMap[toolName]
+	tool, ok := lookupTool(toolName)
 	if !ok {
 		return map[string]any{
 			"success": false,
@@ -33,7 +33,17 @@ func RunOllamaTool(toolName string, args map[string]any) (any, error) {
 }
 
 func GetOllamaTool(name string) (*ollama.Tool, error) {
-	tool, ok := toolMap[name]
+	schema, err := CachedSchema(name, "ollama", func() (any, error) {
+		return buildOllamaTool(name)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return schema.(*ollama.Tool), nil
+}
+
+func buildOllamaTool(name string) (*ollama.Tool, error) {
+	tool, ok := lookupTool(name)
 	if !ok {
 		return nil, fmt.Errorf("tool not found: %s", name)
 	}
@@ -78,6 +88,11 @@ func paramToOllamaFunctionProperties(param Parameter) OllamaFunctionProperties {
 			Type:        "string[]",
 			Description: param.Description,
 		}
+	case "boolean", "integer", "number":
+		return OllamaFunctionProperties{
+			Type:        param.Type,
+			Description: param.Description,
+		}
 	}
 	return OllamaFunctionProperties{}
 }
@@ -0,0 +1,526 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// OpenAPIBaseURLArg overrides the server of an OpenAPI tool, set it in the tool Options
+// to point a registered API at another environment at runtime
+const OpenAPIBaseURLArg = "baseURL"
+
+const (
+	// openAPIMaxResponse is the number of response bytes returned to the model
+	openAPIMaxResponse = 32 << 10
+	// openAPIMaxDescription keeps long operation descriptions from filling the prompt
+	openAPIMaxDescription = 1024
+)
+
+// OpenAPIOptions configures the tools generated from an OpenAPI specification
+type OpenAPIOptions struct {
+	// BaseURL is the server requests are sent to, the first server of the spec when empty
+	BaseURL string
+	// Prefix is added to the tool names, e.g. "petstore_"
+	Prefix string
+	// Credential names the credential sent with each request, read from the
+	// CredentialsProvider in the scope of the conversation. It is sent as the spec's
+	// security scheme requires, a bearer token when the spec has none. For HTTP basic
+	// authentication the credential is "user:password".
+	Credential string
+	// Operations limits the tools to these operation ids, all operations when empty
+	Operations []string
+}
+
+// openAPIOperation is an operation of the spec with its parameters resolved
+type openAPIOperation struct {
+	method     string
+	path       string
+	parameters []openAPIParameter
+	// body is set when the request body is JSON, bodyParams holds the names of its
+	// properties or "body" when it is sent as a single parameter
+	body       bool
+	bodyParams map[string]bool
+	// jsonParams are parameters passed as JSON encoded strings
+	jsonParams map[string]bool
+}
+
+type openAPIParameter struct {
+	name string
+	in   string
+}
+
+// openAPIAuth is how the credential is sent
+type openAPIAuth struct {
+	in     string
+	name   string
+	scheme string
+}
+
+var openAPINameChars = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+
+// RegisterOpenAPI registers a tool for each operation of an OpenAPI 3 specification, in
+// JSON or YAML, and returns the tool names
+func RegisterOpenAPI(spec []byte, options OpenAPIOptions) ([]string, error) {
+	tools, err := OpenAPITools(spec, options)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(tools))
+	for _, tool := range tools {
+		RegisterTool(tool)
+		names = append(names, tool.Name)
+	}
+	return names, nil
+}
+
+// OpenAPITools returns a tool for each operation of an OpenAPI 3 specification. Operations
+// with request bodies other than JSON are skipped.
+func OpenAPITools(spec []byte, options OpenAPIOptions) ([]Tool, error) {
+	var doc map[string]any
+	if err := yaml.Unmarshal(spec, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse OpenAPI spec: %w", err)
+	}
+	if version, _ := doc["openapi"].(string); !strings.HasPrefix(version, "3.") {
+		return nil, fmt.Errorf("unsupported OpenAPI version %q, only 3.x is supported", version)
+	}
+	baseURL := options.BaseURL
+	if baseURL == "" {
+		if servers, _ := doc["servers"].([]any); len(servers) > 0 {
+			server, _ := servers[0].(map[string]any)
+			baseURL, _ = server["url"].(string)
+		}
+	}
+	auth := openAPISecurity(doc)
+	only := make(map[string]bool, len(options.Operations))
+	for _, id := range options.Operations {
+		only[id] = true
+	}
+
+	paths, _ := doc["paths"].(map[string]any)
+	pathNames := make([]string, 0, len(paths))
+	for path := range paths {
+		pathNames = append(pathNames, path)
+	}
+	sort.Strings(pathNames)
+	var tools []Tool
+	seen := make(map[string]bool)
+	for _, path := range pathNames {
+		item, _ := resolveRef(doc, paths[path]).(map[string]any)
+		for _, method := range []string{"get", "put", "post", "delete", "patch", "head", "options"} {
+			op, ok := item[method].(map[string]any)
+			if !ok {
+				continue
+			}
+			id, _ := op["operationId"].(string)
+			if len(only) > 0 && !only[id] {
+				continue
+			}
+			if id == "" {
+				id = method + path
+			}
+			name := options.Prefix + strings.Trim(openAPINameChars.ReplaceAllString(id, "_"), "_")
+			if len(name) > 64 {
+				name = name[:64]
+			}
+			if seen[name] {
+				return nil, fmt.Errorf("duplicate tool name %s for %s %s", name, strings.ToUpper(method), path)
+			}
+			seen[name] = true
+			tool, ok := openAPITool(doc, method, path, item, op)
+			if !ok {
+				continue
+			}
+			operation := tool.operation
+			tools = append(tools, Tool{
+				Name:        name,
+				Description: tool.description,
+				Parameters:  tool.parameters,
+				Options:     map[string]string{},
+				Run: func(args map[string]any) (map[string]any, error) {
+					return runOpenAPIOperation(operation, baseURL, auth, options.Credential, args)
+				},
+			})
+		}
+	}
+	if len(tools) == 0 {
+		return nil, errors.New("OpenAPI spec has no operations")
+	}
+	return tools, nil
+}
+
+type openAPIToolSpec struct {
+	description string
+	parameters  []Parameter
+	operation   openAPIOperation
+}
+
+// openAPITool describes an operation as a tool, it returns false for operations that
+// cannot be called as one
+func openAPITool(doc map[string]any, method string, path string, item map[string]any, op map[string]any) (openAPIToolSpec, bool) {
+	spec := openAPIToolSpec{operation: openAPIOperation{
+		method:     strings.ToUpper(method),
+		path:       path,
+		bodyParams: make(map[string]bool),
+		jsonParams: make(map[string]bool),
+	}}
+	summary, _ := op["summary"].(string)
+	description, _ := op["description"].(string)
+	spec.description = strings.TrimSpace(summary + "\n" + description)
+	if spec.description == "" {
+		spec.description = fmt.Sprintf("%s %s", spec.operation.method, path)
+	}
+	if len(spec.description) > openAPIMaxDescription {
+		spec.description = spec.description[:openAPIMaxDescription]
+	}
+
+	// parameters of the path item apply to every operation unless the operation
+	// overrides them
+	params := make(map[string]map[string]any)
+	var order []string
+	for _, list := range []any{item["parameters"], op["parameters"]} {
+		entries, _ := list.([]any)
+		for _, entry := range entries {
+			param, _ := resolveRef(doc, entry).(map[string]any)
+			paramName, _ := param["name"].(string)
+			in, _ := param["in"].(string)
+			if paramName == "" || in == "cookie" {
+				continue
+			}
+			key := in + ":" + paramName
+			if _, ok := params[key]; !ok {
+				order = append(order, key)
+			}
+			params[key] = param
+		}
+	}
+	used := make(map[string]bool)
+	for _, key := range order {
+		param := params[key]
+		paramName := param["name"].(string)
+		in := param["in"].(string)
+		required, _ := param["required"].(bool)
+		paramDescription, _ := param["description"].(string)
+		schema, _ := resolveRef(doc, param["schema"]).(map[string]any)
+		paramType, isJSON := openAPIType(schema)
+		if isJSON {
+			spec.operation.jsonParams[paramName] = true
+			paramDescription = strings.TrimSpace(paramDescription + " (JSON encoded)")
+		}
+		spec.parameters = append(spec.parameters, Parameter{Name: paramName, Type: paramType, Description: paramDescription, Required: required || in == "path"})
+		spec.operation.parameters = append(spec.operation.parameters, openAPIParameter{name: paramName, in: in})
+		used[paramName] = true
+	}
+
+	body, _ := resolveRef(doc, op["requestBody"]).(map[string]any)
+	content, _ := body["content"].(map[string]any)
+	media, ok := content["application/json"].(map[string]any)
+	if !ok {
+		if len(content) > 0 {
+			// only JSON request bodies are supported
+			return spec, false
+		}
+		return spec, true
+	}
+	spec.operation.body = true
+	bodyRequired, _ := body["required"].(bool)
+	schema, _ := resolveRef(doc, media["schema"]).(map[string]any)
+	properties, _ := schema["properties"].(map[string]any)
+	if len(properties) == 0 {
+		bodyDescription, _ := body["description"].(string)
+		spec.parameters = append(spec.parameters, Parameter{Name: "body", Type: "string", Description: strings.TrimSpace(bodyDescription + " (JSON encoded request body)"), Required: bodyRequired})
+		spec.operation.bodyParams["body"] = true
+		spec.operation.jsonParams["body"] = true
+		return spec, true
+	}
+	requiredProps := make(map[string]bool)
+	if list, ok := schema["required"].([]any); ok {
+		for _, r := range list {
+			if s, ok := r.(string); ok {
+				requiredProps[s] = true
+			}
+		}
+	}
+	propNames := make([]string, 0, len(properties))
+	for prop := range properties {
+		propNames = append(propNames, prop)
+	}
+	sort.Strings(propNames)
+	for _, prop := range propNames {
+		if used[prop] {
+			// a body property with the name of a parameter cannot be told apart
+			return spec, false
+		}
+		propSchema, _ := resolveRef(doc, properties[prop]).(map[string]any)
+		if readOnly, _ := propSchema["readOnly"].(bool); readOnly {
+			continue
+		}
+		propType, isJSON := openAPIType(propSchema)
+		propDescription, _ := propSchema["description"].(string)
+		if isJSON {
+			spec.operation.jsonParams[prop] = true
+			propDescription = strings.TrimSpace(propDescription + " (JSON encoded)")
+		}
+		if values, ok := propSchema["enum"].([]any); ok {
+			propDescription = strings.TrimSpace(fmt.Sprintf("%s One of %v.", propDescription, values))
+		}
+		spec.parameters = append(spec.parameters, Parameter{Name: prop, Type: propType, Description: propDescription, Required: bodyRequired && requiredProps[prop]})
+		spec.operation.bodyParams[prop] = true
+	}
+	return spec, true
+}
+
+// openAPIType maps a schema to a parameter type. Objects and arrays of other than
+// strings are passed as JSON encoded strings since not every provider accepts them.
+func openAPIType(schema map[string]any) (string, bool) {
+	switch schema["type"] {
+	case "integer":
+		return "integer", false
+	case "number":
+		return "number", false
+	case "boolean":
+		return "boolean", false
+	case "string":
+		return "string", false
+	case "array":
+		items, _ := schema["items"].(map[string]any)
+		if items["type"] == "string" {
+			return "stringArray", false
+		}
+		return "string", true
+	case "object":
+		return "string", true
+	}
+	if _, ok := schema["properties"]; ok {
+		return "string", true
+	}
+	return "string", false
+}
+
+// resolveRef follows local $ref pointers such as #/components/schemas/Pet
+func resolveRef(doc map[string]any, node any) any {
+	for depth := 0; depth < 16; depth++ {
+		m, ok := node.(map[string]any)
+		if !ok {
+			return node
+		}
+		ref, ok := m["$ref"].(string)
+		if !ok || !strings.HasPrefix(ref, "#/") {
+			return node
+		}
+		var current any = doc
+		for _, key := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+			key = strings.ReplaceAll(strings.ReplaceAll(key, "~1", "/"), "~0", "~")
+			parent, _ := current.(map[string]any)
+			current = parent[key]
+		}
+		node = current
+	}
+	return node
+}
+
+// openAPISecurity returns how the spec expects to be authenticated, the first scheme of
+// its global security requirements or of its components
+func openAPISecurity(doc map[string]any) openAPIAuth {
+	auth := openAPIAuth{in: "header", name: "Authorization", scheme: "Bearer"}
+	components, _ := doc["components"].(map[string]any)
+	schemes, _ := components["securitySchemes"].(map[string]any)
+	var schemeName string
+	if requirements, _ := doc["security"].([]any); len(requirements) > 0 {
+		if requirement, ok := requirements[0].(map[string]any); ok {
+			for name := range requirement {
+				schemeName = name
+			}
+		}
+	}
+	if schemeName == "" {
+		names := make([]string, 0, len(schemes))
+		for name := range schemes {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		if len(names) > 0 {
+			schemeName = names[0]
+		}
+	}
+	scheme, _ := resolveRef(doc, schemes[schemeName]).(map[string]any)
+	switch scheme["type"] {
+	case "apiKey":
+		in, _ := scheme["in"].(string)
+		name, _ := scheme["name"].(string)
+		if (in == "header" || in == "query") && name != "" {
+			return openAPIAuth{in: in, name: name}
+		}
+	case "http":
+		if s, _ := scheme["scheme"].(string); strings.EqualFold(s, "basic") {
+			auth.scheme = "Basic"
+		}
+	}
+	return auth
+}
+
+func runOpenAPIOperation(operation openAPIOperation, baseURL string, auth openAPIAuth, credential string, args map[string]any) (map[string]any, error) {
+	if override, ok := args[OpenAPIBaseURLArg].(string); ok && override != "" {
+		baseURL = override
+	}
+	if baseURL == "" {
+		err := errors.New("no server configured, set the baseURL option of the tool")
+		return map[string]any{"success": false, "error": err.Error()}, err
+	}
+	path := operation.path
+	query := url.Values{}
+	header := http.Header{}
+	for _, param := range operation.parameters {
+		value, ok := args[param.name]
+		if !ok || value == nil {
+			continue
+		}
+		values := openAPIValues(value)
+		switch param.in {
+		case "path":
+			path = strings.ReplaceAll(path, "{"+param.name+"}", url.PathEscape(strings.Join(values, ",")))
+		case "query":
+			query[param.name] = values
+		case "header":
+			header.Set(param.name, strings.Join(values, ","))
+		}
+	}
+	if strings.Contains(path, "{") {
+		err := NewToolError(fmt.Errorf("missing path parameters in %s", path), "Pass every path parameter.", true)
+		return map[string]any{"success": false, "error": err.Error()}, err
+	}
+
+	var body io.Reader
+	if operation.body {
+		payload := make(map[string]any)
+		var raw any
+		for name := range operation.bodyParams {
+			value, ok := args[name]
+			if !ok || value == nil {
+				continue
+			}
+			if s, isString := value.(string); isString && operation.jsonParams[name] {
+				if err := json.Unmarshal([]byte(s), &value); err != nil {
+					err = NewToolError(fmt.Errorf("%s is not valid JSON: %w", name, err), "Pass a JSON encoded value.", true)
+					return map[string]any{"success": false, "error": err.Error()}, err
+				}
+			}
+			if name == "body" && len(operation.bodyParams) == 1 {
+				raw = value
+			} else {
+				payload[name] = value
+			}
+		}
+		var data []byte
+		var err error
+		if raw != nil {
+			data, err = json.Marshal(raw)
+		} else if len(payload) > 0 {
+			data, err = json.Marshal(payload)
+		}
+		if err != nil {
+			return map[string]any{"success": false, "error": err.Error()}, err
+		}
+		if data != nil {
+			body = bytes.NewReader(data)
+			header.Set("Content-Type", "application/json")
+		}
+	}
+
+	creds := credentialsFor(args)
+	if credential != "" {
+		secret := creds.get(credential)
+		if creds.err != nil {
+			return map[string]any{"success": false, "error": creds.err.Error()}, creds.err
+		}
+		if secret != "" {
+			switch {
+			case auth.in == "query":
+				query.Set(auth.name, secret)
+			case auth.scheme == "Basic":
+				header.Set(auth.name, "Basic "+base64.StdEncoding.EncodeToString([]byte(secret)))
+			case auth.scheme != "":
+				header.Set(auth.name, auth.scheme+" "+secret)
+			default:
+				header.Set(auth.name, secret)
+			}
+		}
+	}
+
+	target := strings.TrimRight(baseURL, "/") + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), httpClient.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, operation.method, target, body)
+	if err != nil {
+		return map[string]any{"success": false, "error": err.Error()}, err
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	req.Header.Set("Accept", "application/json")
+	if requestID, ok := args[RequestIDArg].(string); ok && requestID != "" {
+		req.Header.Set("X-Request-Id", requestID)
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return map[string]any{"success": false, "error": err.Error()}, err
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, openAPIMaxResponse+1))
+	truncated := len(data) > openAPIMaxResponse
+	if truncated {
+		data = data[:openAPIMaxResponse]
+	}
+	if resp.StatusCode/100 != 2 {
+		err := fmt.Errorf("status code %d: %s", resp.StatusCode, strings.TrimSpace(string(data[:min(len(data), 1024)])))
+		if resp.StatusCode/100 == 4 && resp.StatusCode != http.StatusUnauthorized && resp.StatusCode != http.StatusForbidden {
+			err = NewToolError(err, "Check the arguments against the API's error message.", true)
+		}
+		return map[string]any{"success": false, "status": resp.StatusCode, "error": err.Error()}, err
+	}
+	result := map[string]any{"success": true, "status": resp.StatusCode}
+	var decoded any
+	if !truncated && json.Unmarshal(data, &decoded) == nil {
+		result["result"] = decoded
+	} else if len(data) > 0 {
+		result["result"] = string(data)
+	}
+	if truncated {
+		result["truncated"] = true
+	}
+	return result, nil
+}
+
+// openAPIValues converts an argument to the strings of a path, query or header value
+func openAPIValues(value any) []string {
+	switch v := value.(type) {
+	case []string:
+		return v
+	case []any:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			values = append(values, fmt.Sprint(item))
+		}
+		return values
+	case float64:
+		// JSON numbers decode as float64, integers are sent without a fraction
+		if v == float64(int64(v)) {
+			return []string{fmt.Sprint(int64(v))}
+		}
+	}
+	return []string{fmt.Sprint(value)}
+}
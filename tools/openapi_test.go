@@ -0,0 +1,247 @@
+package tools
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const testPetstoreSpec = `
+openapi: 3.0.3
+info:
+  title: Petstore
+  version: "1"
+servers:
+  - url: https://petstore.invalid/v1
+security:
+  - apiKey: []
+components:
+  securitySchemes:
+    apiKey:
+      type: apiKey
+      in: header
+      name: X-API-Key
+  parameters:
+    PetID:
+      name: petId
+      in: path
+      schema:
+        type: integer
+  schemas:
+    NewPet:
+      type: object
+      required: [name]
+      properties:
+        id:
+          type: integer
+          readOnly: true
+        name:
+          type: string
+          description: The pet's name
+        status:
+          type: string
+          enum: [available, sold]
+        tags:
+          type: array
+          items:
+            type: string
+        owner:
+          type: object
+          properties:
+            email:
+              type: string
+paths:
+  /pets:
+    get:
+      operationId: listPets
+      summary: List pets
+      parameters:
+        - name: status
+          in: query
+          schema:
+            type: array
+            items:
+              type: string
+        - name: limit
+          in: query
+          schema:
+            type: integer
+    post:
+      operationId: createPet
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema:
+              $ref: '#/components/schemas/NewPet'
+  /pets/{petId}:
+    parameters:
+      - $ref: '#/components/parameters/PetID'
+    get:
+      operationId: getPet
+    delete:
+      operationId: deletePet
+  /pets/{petId}/photo:
+    put:
+      operationId: uploadPhoto
+      requestBody:
+        content:
+          image/png:
+            schema:
+              type: string
+              format: binary
+`
+
+func TestOpenAPITools(t *testing.T) {
+	tools, err := OpenAPITools([]byte(testPetstoreSpec), OpenAPIOptions{Prefix: "pets_"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	byName := make(map[string]Tool)
+	for _, tool := range tools {
+		byName[tool.Name] = tool
+	}
+	// the photo upload is skipped, its body is not JSON
+	if len(tools) != 4 || byName["pets_uploadPhoto"].Name != "" {
+		t.Fatalf("tools %v", byName)
+	}
+	parameters := make(map[string]Parameter)
+	for _, param := range byName["pets_createPet"].Parameters {
+		parameters[param.Name] = param
+	}
+	if _, ok := parameters["id"]; ok || len(parameters) != 4 {
+		t.Errorf("createPet parameters %+v, want the read only id left out", parameters)
+	}
+	if !parameters["name"].Required || parameters["status"].Required || !strings.Contains(parameters["status"].Description, "One of [available sold]") {
+		t.Errorf("createPet parameters %+v", parameters)
+	}
+	if parameters["tags"].Type != "stringArray" || parameters["owner"].Type != "string" || !strings.Contains(parameters["owner"].Description, "JSON encoded") {
+		t.Errorf("createPet parameters %+v", parameters)
+	}
+	if params := byName["pets_getPet"].Parameters; len(params) != 1 || params[0].Name != "petId" || params[0].Type != "integer" || !params[0].Required {
+		t.Errorf("getPet parameters %+v", params)
+	}
+
+	if _, err := OpenAPITools([]byte("swagger: \"2.0\"\npaths: {}"), OpenAPIOptions{}); err == nil {
+		t.Error("accepted a Swagger 2 spec")
+	}
+	if tools, err := OpenAPITools([]byte(testPetstoreSpec), OpenAPIOptions{Operations: []string{"getPet"}}); err != nil || len(tools) != 1 || tools[0].Name != "getPet" {
+		t.Errorf("limited tools %v, %v", tools, err)
+	}
+}
+
+func TestOpenAPIRequests(t *testing.T) {
+	type request struct {
+		method string
+		uri    string
+		header http.Header
+		body   string
+	}
+	var requests []request
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests = append(requests, request{r.Method, r.URL.RequestURI(), r.Header, string(body)})
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/v1/pets/404":
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"message":"pet not found"}`)
+		case r.URL.Path == "/v1/pets/big":
+			w.Write([]byte(strings.Repeat("x", openAPIMaxResponse+10)))
+		case r.Method == http.MethodPost:
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprint(w, `{"id":7}`)
+		default:
+			fmt.Fprint(w, `[{"id":1,"name":"Rex"}]`)
+		}
+	}))
+	defer srv.Close()
+	tools, err := OpenAPITools([]byte(testPetstoreSpec), OpenAPIOptions{BaseURL: srv.URL + "/v1/", Credential: "PETSTORE_KEY"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	run := make(map[string]func(map[string]any) (map[string]any, error))
+	for _, tool := range tools {
+		run[tool.Name] = tool.Run
+	}
+	env := map[string]string{"PETSTORE_KEY": "key-1"}
+
+	result, err := run["listPets"](map[string]any{"status": []any{"available", "sold"}, "limit": 10.0, EnvArg: env, RequestIDArg: "req-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pets := result["result"].([]any); len(pets) != 1 || result["status"] != 200 {
+		t.Errorf("result %v", result)
+	}
+	// integers decoded from JSON are sent without a fraction
+	got := requests[0]
+	if got.uri != "/v1/pets?limit=10&status=available&status=sold" || got.header.Get("X-API-Key") != "key-1" || got.header.Get("X-Request-Id") != "req-1" {
+		t.Errorf("request %s %v", got.uri, got.header)
+	}
+
+	result, err = run["createPet"](map[string]any{"name": "Rex", "tags": []any{"good"}, "owner": `{"email":"ana@example.com"}`, EnvArg: env})
+	if err != nil || result["status"] != 201 {
+		t.Fatalf("create %v, %v", result, err)
+	}
+	var body map[string]any
+	json.Unmarshal([]byte(requests[1].body), &body)
+	if owner, ok := body["owner"].(map[string]any); !ok || owner["email"] != "ana@example.com" || body["name"] != "Rex" || len(body) != 3 {
+		t.Errorf("body %s", requests[1].body)
+	}
+	if requests[1].header.Get("Content-Type") != "application/json" {
+		t.Errorf("content type %s", requests[1].header.Get("Content-Type"))
+	}
+	if _, err := run["createPet"](map[string]any{"name": "Rex", "owner": "{", EnvArg: env}); err == nil || !strings.Contains(err.Error(), "owner is not valid JSON") {
+		t.Errorf("invalid JSON error %v", err)
+	}
+
+	_, err = run["getPet"](map[string]any{"petId": "404", EnvArg: env})
+	var toolErr *ToolError
+	if !errors.As(err, &toolErr) || !toolErr.Retryable || !strings.Contains(err.Error(), "pet not found") {
+		t.Errorf("not found error %v", err)
+	}
+	result, err = run["getPet"](map[string]any{"petId": "big", EnvArg: env})
+	if err != nil || result["truncated"] != true || len(result["result"].(string)) != openAPIMaxResponse {
+		t.Errorf("large response truncated %v, %v", result["truncated"], err)
+	}
+	requestCount := len(requests)
+	if _, err := run["deletePet"](map[string]any{EnvArg: env}); err == nil || !strings.Contains(err.Error(), "missing path parameters") {
+		t.Errorf("missing path parameter error %v", err)
+	}
+	if _, err := run["getPet"](map[string]any{"petId": "a/b", OpenAPIBaseURLArg: srv.URL + "/v1"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := requests[len(requests)-1]; got.uri != "/v1/pets/a%2Fb" || got.header.Get("X-API-Key") != "" {
+		t.Errorf("request %s %v", got.uri, got.header)
+	}
+	if len(requests) != requestCount+1 {
+		t.Errorf("the call without its path parameter was sent")
+	}
+}
+
+func TestOpenAPISecurity(t *testing.T) {
+	tests := []struct {
+		spec string
+		want openAPIAuth
+	}{
+		{`{"openapi":"3.0.0"}`, openAPIAuth{in: "header", name: "Authorization", scheme: "Bearer"}},
+		{`{"components":{"securitySchemes":{"basic":{"type":"http","scheme":"basic"}}}}`, openAPIAuth{in: "header", name: "Authorization", scheme: "Basic"}},
+		{`{"components":{"securitySchemes":{"key":{"type":"apiKey","in":"query","name":"api_key"}}}}`, openAPIAuth{in: "query", name: "api_key"}},
+		{`{"security":[{"token":[]}],"components":{"securitySchemes":{"a":{"type":"apiKey","in":"query","name":"key"},"token":{"type":"http","scheme":"bearer"}}}}`,
+			openAPIAuth{in: "header", name: "Authorization", scheme: "Bearer"}},
+	}
+	for _, tt := range tests {
+		var doc map[string]any
+		if err := json.Unmarshal([]byte(tt.spec), &doc); err != nil {
+			t.Fatal(err)
+		}
+		if got := openAPISecurity(doc); got != tt.want {
+			t.Errorf("openAPISecurity(%s) = %+v, want %+v", tt.spec, got, tt.want)
+		}
+	}
+}
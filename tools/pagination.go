@@ -0,0 +1,78 @@
+package tools
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Paginated tools return at most one page of results. When more results exist the
+// result holds NextCursorKey, the model passes it back as the CursorArg argument to
+// continue. Tools set Paginated and the cursor parameter is added on registration.
+const (
+	CursorArg     = "cursor"
+	NextCursorKey = "next_cursor"
+
+	DefaultPageSize = 100
+)
+
+var cursorParameter = Parameter{
+	Name:        CursorArg,
+	Type:        "string",
+	Description: "The next_cursor returned by a previous call, used to fetch the next page of results",
+	Required:    false,
+}
+
+// withPagination adds the cursor parameter to paginated tools
+func withPagination(tool Tool) Tool {
+	if !tool.Paginated {
+		return tool
+	}
+	for _, param := range tool.Parameters {
+		if param.Name == CursorArg {
+			return tool
+		}
+	}
+	params := make([]Parameter, 0, len(tool.Parameters)+1)
+	params = append(params, tool.Parameters...)
+	tool.Parameters = append(params, cursorParameter)
+	return tool
+}
+
+// cursorOffset decodes the cursor argument of an offset based tool
+func cursorOffset(args map[string]any) (int, error) {
+	cursor, ok := args[CursorArg].(string)
+	if !ok || cursor == "" {
+		return 0, nil
+	}
+	offset, err := strconv.Atoi(cursor)
+	if err != nil || offset < 0 {
+		return 0, fmt.Errorf("invalid cursor: %s", cursor)
+	}
+	return offset, nil
+}
+
+// Paginate returns the page of items selected by the cursor argument and the
+// cursor of the next page, which is empty on the last page
+func Paginate[T any](items []T, args map[string]any, pageSize int) ([]T, string, error) {
+	offset, err := cursorOffset(args)
+	if err != nil {
+		return nil, "", err
+	}
+	if offset > len(items) {
+		offset = len(items)
+	}
+	end := min(offset+pageSize, len(items))
+	next := ""
+	if end < len(items) {
+		next = strconv.Itoa(end)
+	}
+	return items[offset:end], next, nil
+}
+
+// setNextCursor adds the next cursor to a tool result when there are more results
+func setNextCursor(result map[string]any, next string) map[string]any {
+	if next != "" {
+		result[NextCursorKey] = next
+	}
+	return result
+}
@@ -0,0 +1,258 @@
+package plugin
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jbutlerdev/genai/tools"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+const (
+	// DefaultTimeout is the deadline of a tool call when Client.Timeout is not set
+	DefaultTimeout = 30 * time.Second
+	// handshakeTimeout is how long a started plugin has to print its address
+	handshakeTimeout = 10 * time.Second
+)
+
+// Client is a connection to a plugin
+type Client struct {
+	// Timeout is the deadline of each tool call, DefaultTimeout when zero
+	Timeout time.Duration
+
+	conn *grpc.ClientConn
+	cmd  *exec.Cmd
+
+	mu         sync.Mutex
+	registered []string
+}
+
+// Start runs a plugin program and connects to it. The plugin's stderr and any output
+// after the handshake are written to the host's stderr. The plugin only gets PATH, HOME
+// and TMPDIR from the host's environment, use StartWithEnv to pass more variables.
+func Start(ctx context.Context, path string, args ...string) (*Client, error) {
+	return StartWithEnv(ctx, path, nil, args...)
+}
+
+// StartWithEnv is Start passing the variables of the host's environment listed in env
+// to the plugin as well
+func StartWithEnv(ctx context.Context, path string, env []string, args ...string) (*Client, error) {
+	cmd := exec.Command(path, args...)
+	cmd.Env = pluginEnv(env)
+	cmd.Stderr = os.Stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start plugin %s: %w", path, err)
+	}
+	output := bufio.NewReader(stdout)
+	addr, err := handshake(ctx, output)
+	if err != nil {
+		cmd.Process.Kill()
+		cmd.Wait()
+		return nil, fmt.Errorf("plugin %s: %w", path, err)
+	}
+	go io.Copy(os.Stderr, output)
+	client, err := Dial(ctx, addr)
+	if err != nil {
+		cmd.Process.Kill()
+		cmd.Wait()
+		return nil, err
+	}
+	client.cmd = cmd
+	return client, nil
+}
+
+// pluginEnv returns the environment of a plugin, PATH, HOME, TMPDIR and the listed
+// variables of the host's environment
+func pluginEnv(names []string) []string {
+	env := make([]string, 0, len(names)+4)
+	for _, name := range append([]string{"PATH", "HOME", "TMPDIR"}, names...) {
+		if value, ok := os.LookupEnv(name); ok {
+			env = append(env, name+"="+value)
+		}
+	}
+	return append(env, MagicCookieEnv+"="+MagicCookieValue)
+}
+
+// handshake reads the "version|network|address|grpc" line a plugin prints when it is
+// ready and returns the address to dial
+func handshake(ctx context.Context, stdout *bufio.Reader) (string, error) {
+	lines := make(chan string, 1)
+	errs := make(chan error, 1)
+	go func() {
+		line, err := stdout.ReadString('\n')
+		if err != nil {
+			errs <- fmt.Errorf("exited before the handshake: %w", err)
+			return
+		}
+		lines <- strings.TrimSpace(line)
+	}()
+	var line string
+	select {
+	case line = <-lines:
+	case err := <-errs:
+		return "", err
+	case <-time.After(handshakeTimeout):
+		return "", errors.New("timed out waiting for the handshake")
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+	fields := strings.Split(line, "|")
+	if len(fields) != 4 || fields[3] != "grpc" {
+		return "", fmt.Errorf("invalid handshake %q", line)
+	}
+	if version, err := strconv.Atoi(fields[0]); err != nil || version != ProtocolVersion {
+		return "", fmt.Errorf("unsupported protocol version %s", fields[0])
+	}
+	switch fields[1] {
+	case "unix":
+		return "unix://" + fields[2], nil
+	case "tcp":
+		return fields[2], nil
+	}
+	return "", fmt.Errorf("unsupported network %s", fields[1])
+}
+
+// Dial connects to a plugin that is already running, e.g. a tool service shared by
+// several hosts, at a gRPC target such as localhost:9000 or unix:///run/tools.sock
+func Dial(_ context.Context, target string) (*Client, error) {
+	conn, err := grpc.NewClient(target, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to plugin: %w", err)
+	}
+	return &Client{conn: conn}, nil
+}
+
+// Tools returns the tools the plugin serves, their Run functions call the plugin
+func (c *Client) Tools(ctx context.Context) ([]tools.Tool, error) {
+	out := new(structpb.Struct)
+	if err := c.conn.Invoke(ctx, "/"+serviceName+"/ListTools", &emptypb.Empty{}, out); err != nil {
+		return nil, fmt.Errorf("failed to list plugin tools: %w", err)
+	}
+	var list struct {
+		Tools []toolSchema `json:"tools"`
+	}
+	if err := fromStruct(out, &list); err != nil {
+		return nil, err
+	}
+	result := make([]tools.Tool, 0, len(list.Tools))
+	for _, schema := range list.Tools {
+		tool := tools.Tool{Name: schema.Name, Description: schema.Description}
+		for _, p := range schema.Parameters {
+			tool.Parameters = append(tool.Parameters, tools.Parameter{Name: p.Name, Type: p.Type, Description: p.Description, Required: p.Required})
+		}
+		name := schema.Name
+		tool.Run = func(args map[string]any) (map[string]any, error) {
+			return c.call(name, args)
+		}
+		result = append(result, tool)
+	}
+	return result, nil
+}
+
+// Register adds the plugin's tools to the tool registry and returns their names
+func (c *Client) Register(ctx context.Context) ([]string, error) {
+	pluginTools, err := c.Tools(ctx)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(pluginTools))
+	for _, tool := range pluginTools {
+		tools.RegisterTool(tool)
+		names = append(names, tool.Name)
+	}
+	c.mu.Lock()
+	c.registered = append(c.registered, names...)
+	c.mu.Unlock()
+	return names, nil
+}
+
+// call runs a tool in the plugin with the client's timeout as deadline. The session
+// arguments, such as the conversation's environment and credential scope, stay in the
+// host.
+func (c *Client) call(name string, args map[string]any) (map[string]any, error) {
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	args = maps.Clone(args)
+	tools.StripSessionArgs(args)
+	in, err := toStruct(map[string]any{"name": name, "arguments": args})
+	if err != nil {
+		return map[string]any{"success": false, "error": err.Error()}, err
+	}
+	out := new(structpb.Struct)
+	if err := c.conn.Invoke(ctx, "/"+serviceName+"/CallTool", in, out); err != nil {
+		switch status.Code(err) {
+		case codes.NotFound:
+			err = fmt.Errorf("tool %s: %w", name, tools.ErrToolNotFound)
+		case codes.DeadlineExceeded:
+			err = fmt.Errorf("plugin tool %s: %w", name, context.DeadlineExceeded)
+		default:
+			err = fmt.Errorf("plugin tool %s: %w", name, err)
+		}
+		return map[string]any{"success": false, "error": err.Error()}, err
+	}
+	var result callResult
+	if err := fromStruct(out, &result); err != nil {
+		return map[string]any{"success": false, "error": err.Error()}, err
+	}
+	if result.Error == "" {
+		return result.Result, nil
+	}
+	err = errors.New(result.Error)
+	if result.ToolError {
+		err = tools.NewToolError(err, result.Hint, result.Retryable)
+	}
+	if result.Result == nil {
+		result.Result = map[string]any{"success": false, "error": result.Error}
+	}
+	return result.Result, err
+}
+
+// Close unregisters the plugin's tools, closes the connection and stops the plugin if
+// it was started with Start
+func (c *Client) Close() error {
+	c.mu.Lock()
+	for _, name := range c.registered {
+		tools.UnregisterTool(name)
+	}
+	c.registered = nil
+	c.mu.Unlock()
+	err := c.conn.Close()
+	if c.cmd != nil {
+		c.cmd.Process.Signal(os.Interrupt)
+		done := make(chan struct{})
+		go func() {
+			c.cmd.Wait()
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			c.cmd.Process.Kill()
+			<-done
+		}
+	}
+	return err
+}
@@ -0,0 +1,133 @@
+// Package plugin serves tools from external processes over gRPC. A plugin is a program
+// that calls Serve with its tools, the host starts it with Start and registers the tools
+// it reports. Plugins that run as long lived services can be reached with Dial instead.
+// See plugin.proto for the protocol.
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/jbutlerdev/genai/tools"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+const (
+	// ProtocolVersion is the first field of the handshake line
+	ProtocolVersion = 1
+	// MagicCookieEnv is set by the host so a plugin started by hand explains itself
+	// instead of waiting for a host
+	MagicCookieEnv   = "GENAI_PLUGIN_MAGIC_COOKIE"
+	MagicCookieValue = "7c1d6f0c8a2b4e5f9d3a"
+
+	serviceName = "genai.plugin.v1.ToolPlugin"
+)
+
+// toolPlugin is the service implemented by plugins
+type toolPlugin interface {
+	ListTools(ctx context.Context, in *emptypb.Empty) (*structpb.Struct, error)
+	CallTool(ctx context.Context, in *structpb.Struct) (*structpb.Struct, error)
+}
+
+// serviceDesc describes the service without generated code since its messages are
+// well known types
+var serviceDesc = grpc.ServiceDesc{
+	ServiceName: serviceName,
+	HandlerType: (*toolPlugin)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListTools",
+			Handler: func(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+				in := new(emptypb.Empty)
+				if err := dec(in); err != nil {
+					return nil, err
+				}
+				handler := func(ctx context.Context, req any) (any, error) {
+					return srv.(toolPlugin).ListTools(ctx, req.(*emptypb.Empty))
+				}
+				if interceptor == nil {
+					return handler(ctx, in)
+				}
+				return interceptor(ctx, in, &grpc.UnaryServerInfo{Server: srv, FullMethod: "/" + serviceName + "/ListTools"}, handler)
+			},
+		},
+		{
+			MethodName: "CallTool",
+			Handler: func(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+				in := new(structpb.Struct)
+				if err := dec(in); err != nil {
+					return nil, err
+				}
+				handler := func(ctx context.Context, req any) (any, error) {
+					return srv.(toolPlugin).CallTool(ctx, req.(*structpb.Struct))
+				}
+				if interceptor == nil {
+					return handler(ctx, in)
+				}
+				return interceptor(ctx, in, &grpc.UnaryServerInfo{Server: srv, FullMethod: "/" + serviceName + "/CallTool"}, handler)
+			},
+		},
+	},
+	Metadata: "plugin.proto",
+}
+
+// toolSchema is a tool as reported by ListTools
+type toolSchema struct {
+	Name        string            `json:"name"`
+	Description string            `json:"description"`
+	Parameters  []parameterSchema `json:"parameters"`
+}
+
+type parameterSchema struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Description string `json:"description"`
+	Required    bool   `json:"required"`
+}
+
+// callResult is the response of CallTool
+type callResult struct {
+	Result map[string]any `json:"result"`
+	Error  string         `json:"error,omitempty"`
+	// ToolError is set when the error is a tools.ToolError with a hint for the model
+	ToolError bool   `json:"toolError,omitempty"`
+	Hint      string `json:"hint,omitempty"`
+	Retryable bool   `json:"retryable,omitempty"`
+}
+
+// toStruct converts a value to a Struct through JSON, so tool results with slices,
+// numbers of any type and structs are accepted
+func toStruct(v any) (*structpb.Struct, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode message: %w", err)
+	}
+	var m map[string]any
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("failed to encode message: %w", err)
+	}
+	return structpb.NewStruct(m)
+}
+
+// fromStruct decodes a Struct into out through JSON
+func fromStruct(s *structpb.Struct, out any) error {
+	data, err := s.MarshalJSON()
+	if err != nil {
+		return fmt.Errorf("failed to decode message: %w", err)
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("failed to decode message: %w", err)
+	}
+	return nil
+}
+
+func schemaOf(tool tools.Tool) toolSchema {
+	schema := toolSchema{Name: tool.Name, Description: tool.Description}
+	for _, p := range tool.Parameters {
+		schema.Parameters = append(schema.Parameters, parameterSchema{Name: p.Name, Type: p.Type, Description: p.Description, Required: p.Required})
+	}
+	return schema
+}
@@ -0,0 +1,164 @@
+package plugin
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jbutlerdev/genai/tools"
+)
+
+// testTools are served by the test binary when the host starts it as a plugin
+var testTools = []tools.Tool{
+	{
+		Name:        "echo",
+		Description: "Return the arguments and the plugin's environment",
+		Parameters:  []tools.Parameter{{Name: "text", Type: "string", Description: "Text to echo", Required: true}},
+		Run: func(args map[string]any) (map[string]any, error) {
+			return map[string]any{"args": args, "env": os.Environ()}, nil
+		},
+	},
+	{
+		Name:        "fail",
+		Description: "Fail with a hint",
+		Run: func(args map[string]any) (map[string]any, error) {
+			return nil, tools.NewToolError(errors.New("always fails"), "Do not retry.", false)
+		},
+	},
+}
+
+func TestMain(m *testing.M) {
+	// Start runs the test binary again as the plugin
+	if os.Getenv(MagicCookieEnv) == MagicCookieValue {
+		if err := Serve(testTools...); err != nil {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+func TestStart(t *testing.T) {
+	t.Setenv("GENAI_PLUGIN_SECRET", "hunter2")
+	t.Setenv("GENAI_PLUGIN_REGION", "eu-west-1")
+	ctx := context.Background()
+	client, err := StartWithEnv(ctx, os.Args[0], []string{"GENAI_PLUGIN_REGION"}, "-test.run=^$")
+	if err != nil {
+		t.Fatalf("start failed: %v", err)
+	}
+	defer client.Close()
+	names, err := client.Register(ctx)
+	if err != nil {
+		t.Fatalf("register failed: %v", err)
+	}
+	if len(names) != 2 {
+		t.Fatalf("registered %v", names)
+	}
+	echo, err := tools.GetTool("echo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(echo.Parameters) != 1 || echo.Parameters[0].Name != "text" || !echo.Parameters[0].Required {
+		t.Errorf("parameters %+v", echo.Parameters)
+	}
+
+	args := map[string]any{
+		"text":                   "hello",
+		tools.EnvArg:             map[string]string{"GITHUB_TOKEN": "ghp_secret"},
+		tools.CredentialScopeArg: "acme",
+	}
+	result, err := echo.Run(args)
+	if err != nil {
+		t.Fatalf("echo failed: %v", err)
+	}
+	forwarded := result["args"].(map[string]any)
+	if len(forwarded) != 1 || forwarded["text"] != "hello" {
+		t.Errorf("forwarded %v, want only the tool's arguments", forwarded)
+	}
+	if _, ok := args[tools.EnvArg]; !ok {
+		t.Error("the caller's arguments were modified")
+	}
+	env := make(map[string]bool)
+	for _, variable := range result["env"].([]any) {
+		name, _, _ := strings.Cut(variable.(string), "=")
+		env[name] = true
+	}
+	if env["GENAI_PLUGIN_SECRET"] || !env["GENAI_PLUGIN_REGION"] || !env["PATH"] {
+		t.Errorf("plugin environment %v", env)
+	}
+
+	_, err = client.call("fail", nil)
+	var toolErr *tools.ToolError
+	if !errors.As(err, &toolErr) || toolErr.Hint != "Do not retry." {
+		t.Errorf("error %v, want a tool error with the hint", err)
+	}
+	if _, err := client.call("missing", nil); !errors.Is(err, tools.ErrToolNotFound) {
+		t.Errorf("error %v, want %v", err, tools.ErrToolNotFound)
+	}
+
+	if err := client.Close(); err != nil {
+		t.Errorf("close failed: %v", err)
+	}
+	if _, err := tools.GetTool("echo"); err == nil {
+		t.Error("echo is still registered after Close")
+	}
+}
+
+func TestDial(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "tools.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := NewServer(testTools...)
+	go server.Serve(listener)
+	defer server.Stop()
+	client, err := Dial(context.Background(), "unix://"+socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	pluginTools, err := client.Tools(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pluginTools) != 2 {
+		t.Fatalf("tools %+v", pluginTools)
+	}
+	result, err := client.call("echo", map[string]any{"text": "hi"})
+	if err != nil || result["args"].(map[string]any)["text"] != "hi" {
+		t.Errorf("echo %v, %v", result, err)
+	}
+}
+
+func TestHandshake(t *testing.T) {
+	tests := []struct {
+		line string
+		addr string
+	}{
+		{"1|unix|/tmp/plugin.sock|grpc\n", "unix:///tmp/plugin.sock"},
+		{"1|tcp|127.0.0.1:4000|grpc\n", "127.0.0.1:4000"},
+		{"2|unix|/tmp/plugin.sock|grpc\n", ""},
+		{"1|udp|127.0.0.1:4000|grpc\n", ""},
+		{"1|unix|/tmp/plugin.sock|netrpc\n", ""},
+		{"listening on :4000\n", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		addr, err := handshake(context.Background(), bufio.NewReader(strings.NewReader(tt.line)))
+		if tt.addr == "" {
+			if err == nil {
+				t.Errorf("handshake %q accepted", tt.line)
+			}
+			continue
+		}
+		if err != nil || addr != tt.addr {
+			t.Errorf("handshake %q: %q, %v, want %s", tt.line, addr, err, tt.addr)
+		}
+	}
+}
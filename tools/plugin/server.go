@@ -0,0 +1,115 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	"github.com/jbutlerdev/genai/tools"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// server runs the tools of a plugin
+type server struct {
+	tools map[string]tools.Tool
+}
+
+// NewServer returns a gRPC server serving toolsToServe, for plugins that run as a service
+// and are reached with Dial. Plugins started by the host use Serve.
+func NewServer(toolsToServe ...tools.Tool) *grpc.Server {
+	s := &server{tools: make(map[string]tools.Tool, len(toolsToServe))}
+	for _, tool := range toolsToServe {
+		s.tools[tool.Name] = tool
+	}
+	grpcServer := grpc.NewServer()
+	grpcServer.RegisterService(&serviceDesc, s)
+	return grpcServer
+}
+
+// Serve serves tools to the host that started the process and returns when the host
+// stops it
+func Serve(toolsToServe ...tools.Tool) error {
+	if os.Getenv(MagicCookieEnv) != MagicCookieValue {
+		return errors.New("this program is a genai tool plugin and is started by the host application")
+	}
+	dir, err := os.MkdirTemp("", "genai-plugin")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "plugin.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		return fmt.Errorf("failed to listen: %w", err)
+	}
+	grpcServer := NewServer(toolsToServe...)
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		grpcServer.GracefulStop()
+	}()
+	fmt.Printf("%d|unix|%s|grpc\n", ProtocolVersion, socket)
+	return grpcServer.Serve(listener)
+}
+
+func (s *server) ListTools(_ context.Context, _ *emptypb.Empty) (*structpb.Struct, error) {
+	schemas := make([]toolSchema, 0, len(s.tools))
+	for _, tool := range s.tools {
+		schemas = append(schemas, schemaOf(tool))
+	}
+	return toStruct(map[string]any{"tools": schemas})
+}
+
+func (s *server) CallTool(ctx context.Context, in *structpb.Struct) (*structpb.Struct, error) {
+	var call struct {
+		Name      string         `json:"name"`
+		Arguments map[string]any `json:"arguments"`
+	}
+	if err := fromStruct(in, &call); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	tool, ok := s.tools[call.Name]
+	if !ok || tool.Run == nil {
+		return nil, status.Errorf(codes.NotFound, "tool %s does not exist", call.Name)
+	}
+	if call.Arguments == nil {
+		call.Arguments = make(map[string]any)
+	}
+	// tools do not take a context, the result of a call past its deadline is dropped
+	type outcome struct {
+		result map[string]any
+		err    error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		result, err := tool.Run(call.Arguments)
+		done <- outcome{result, err}
+	}()
+	var out outcome
+	select {
+	case out = <-done:
+	case <-ctx.Done():
+		return nil, status.FromContextError(ctx.Err()).Err()
+	}
+	response := callResult{Result: out.result}
+	if out.err != nil {
+		response.Error = out.err.Error()
+		var toolErr *tools.ToolError
+		if errors.As(out.err, &toolErr) {
+			response.ToolError = true
+			response.Hint = toolErr.Hint
+			response.Retryable = toolErr.Retryable
+		}
+	}
+	return toStruct(response)
+}
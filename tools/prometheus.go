@@ -0,0 +1,463 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	PrometheusQueryToolName      = "prometheus_query"
+	PrometheusQueryRangeToolName = "prometheus_query_range"
+
+	// Credentials read when Prometheus is not configured with ConfigurePrometheus
+	PrometheusURLEnv   = "PROMETHEUS_URL"
+	PrometheusTokenEnv = "PROMETHEUS_TOKEN"
+
+	// DefaultPrometheusPoints is the number of points a range series is down-sampled to
+	DefaultPrometheusPoints = 30
+
+	maxPrometheusSeries = 20
+	maxPrometheusPoints = 200
+	// rangeSteps is the number of steps used when no step is given
+	rangeSteps = 250
+)
+
+// PrometheusConfig configures the Prometheus tools
+type PrometheusConfig struct {
+	// URL is the Prometheus server, e.g. http://prometheus:9090
+	URL string
+	// Token is sent as a bearer token, for servers behind an authenticating proxy
+	Token string
+}
+
+var (
+	prometheusConfig   *PrometheusConfig
+	prometheusConfigMu sync.RWMutex
+)
+
+// ConfigurePrometheus replaces the configuration read from the CredentialsProvider
+func ConfigurePrometheus(config PrometheusConfig) {
+	prometheusConfigMu.Lock()
+	defer prometheusConfigMu.Unlock()
+	prometheusConfig = &config
+}
+
+func getPrometheusConfig(args map[string]any) (PrometheusConfig, error) {
+	prometheusConfigMu.RLock()
+	config := prometheusConfig
+	prometheusConfigMu.RUnlock()
+	if config == nil {
+		creds := credentialsFor(args)
+		config = &PrometheusConfig{URL: creds.get(PrometheusURLEnv), Token: creds.get(PrometheusTokenEnv)}
+		if creds.err != nil {
+			return PrometheusConfig{}, creds.err
+		}
+	}
+	if config.URL == "" {
+		return PrometheusConfig{}, fmt.Errorf("prometheus is not configured, set %s", PrometheusURLEnv)
+	}
+	return *config, nil
+}
+
+var prometheusTools = map[string]Tool{
+	PrometheusQueryToolName:      prometheusQueryTool,
+	PrometheusQueryRangeToolName: prometheusQueryRangeTool,
+}
+
+var prometheusQueryTool = Tool{
+	Name:        PrometheusQueryToolName,
+	Description: "Run an instant PromQL query and return the value of each series",
+	Parameters: []Parameter{
+		{
+			Name:        "query",
+			Type:        "string",
+			Description: "The PromQL expression",
+			Required:    true,
+		},
+		{
+			Name:        "time",
+			Type:        "string",
+			Description: "Evaluation time as RFC3339, a unix timestamp or a duration ago such as 1h, defaults to now",
+			Required:    false,
+		},
+	},
+	Options: map[string]string{},
+	Run:     PrometheusQuery,
+}
+
+var prometheusQueryRangeTool = Tool{
+	Name:        PrometheusQueryRangeToolName,
+	Description: "Run a PromQL range query and return a summary of each series with down-sampled values",
+	Parameters: []Parameter{
+		{
+			Name:        "query",
+			Type:        "string",
+			Description: "The PromQL expression",
+			Required:    true,
+		},
+		{
+			Name:        "start",
+			Type:        "string",
+			Description: "Start as RFC3339, a unix timestamp or a duration ago such as 6h",
+			Required:    true,
+		},
+		{
+			Name:        "end",
+			Type:        "string",
+			Description: "End in the same formats as start, defaults to now",
+			Required:    false,
+		},
+		{
+			Name:        "step",
+			Type:        "string",
+			Description: "Resolution such as 1m, chosen from the range when empty",
+			Required:    false,
+		},
+		{
+			Name:        "points",
+			Type:        "integer",
+			Description: fmt.Sprintf("The number of values returned per series, defaults to %d", DefaultPrometheusPoints),
+			Required:    false,
+		},
+	},
+	Options: map[string]string{},
+	Run:     PrometheusQueryRange,
+}
+
+type prometheusQueryArgs struct {
+	Query  string `json:"query"`
+	Time   string `json:"time"`
+	Start  string `json:"start"`
+	End    string `json:"end"`
+	Step   string `json:"step"`
+	Points int    `json:"points"`
+}
+
+type prometheusResponse struct {
+	Status    string   `json:"status"`
+	Error     string   `json:"error"`
+	ErrorType string   `json:"errorType"`
+	Warnings  []string `json:"warnings"`
+	Data      struct {
+		ResultType string          `json:"resultType"`
+		Result     json.RawMessage `json:"result"`
+	} `json:"data"`
+}
+
+type prometheusSeries struct {
+	Metric map[string]string `json:"metric"`
+	Value  prometheusSample  `json:"value"`
+	Values []prometheusSample
+}
+
+// prometheusSample is a [unix time, "value"] pair
+type prometheusSample struct {
+	Time  float64
+	Value float64
+}
+
+func (s *prometheusSample) UnmarshalJSON(data []byte) error {
+	var pair [2]any
+	if err := json.Unmarshal(data, &pair); err != nil {
+		return err
+	}
+	timestamp, ok := pair[0].(float64)
+	if !ok {
+		return fmt.Errorf("invalid sample time: %v", pair[0])
+	}
+	value, ok := pair[1].(string)
+	if !ok {
+		return fmt.Errorf("invalid sample value: %v", pair[1])
+	}
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return err
+	}
+	s.Time, s.Value = timestamp, parsed
+	return nil
+}
+
+func PrometheusQuery(args map[string]any) (map[string]any, error) {
+	typed, err := DecodeArgs[prometheusQueryArgs](args)
+	if err == nil && typed.Query == "" {
+		err = NewToolError(fmt.Errorf("query is required"), "", true)
+	}
+	params := url.Values{"query": {typed.Query}}
+	if err == nil && typed.Time != "" {
+		var at time.Time
+		at, err = parsePrometheusTime(typed.Time, time.Now())
+		params.Set("time", formatPrometheusTime(at))
+	}
+	var resp *prometheusResponse
+	if err == nil {
+		resp, err = prometheusRequest(args, "/api/v1/query", params)
+	}
+	if err != nil {
+		return map[string]any{
+			"success": false,
+			"error":   err.Error(),
+		}, err
+	}
+	result := map[string]any{
+		"success":    true,
+		"resultType": resp.Data.ResultType,
+	}
+	if len(resp.Warnings) > 0 {
+		result["warnings"] = resp.Warnings
+	}
+	switch resp.Data.ResultType {
+	case "vector":
+		var series []prometheusSeries
+		if err := json.Unmarshal(resp.Data.Result, &series); err != nil {
+			return nil, fmt.Errorf("failed to decode result: %w", err)
+		}
+		sortSeries(series, func(s prometheusSeries) float64 { return s.Value.Value })
+		result["total"] = len(series)
+		if len(series) > maxPrometheusSeries {
+			series = series[:maxPrometheusSeries]
+			result["truncated"] = true
+		}
+		values := make([]map[string]any, len(series))
+		for i, s := range series {
+			values[i] = map[string]any{
+				"metric": formatMetric(s.Metric),
+				"value":  s.Value.Value,
+			}
+		}
+		result["series"] = values
+	case "scalar", "string":
+		var sample [2]any
+		if err := json.Unmarshal(resp.Data.Result, &sample); err != nil {
+			return nil, fmt.Errorf("failed to decode result: %w", err)
+		}
+		result["value"] = sample[1]
+	default:
+		result["result"] = string(resp.Data.Result)
+	}
+	return result, nil
+}
+
+func PrometheusQueryRange(args map[string]any) (map[string]any, error) {
+	typed, err := DecodeArgs[prometheusQueryArgs](args)
+	if err == nil && (typed.Query == "" || typed.Start == "") {
+		err = NewToolError(fmt.Errorf("query and start are required"), "", true)
+	}
+	now := time.Now()
+	var start, end time.Time
+	var step time.Duration
+	if err == nil {
+		start, err = parsePrometheusTime(typed.Start, now)
+	}
+	if err == nil {
+		end = now
+		if typed.End != "" {
+			end, err = parsePrometheusTime(typed.End, now)
+		}
+	}
+	if err == nil && !end.After(start) {
+		err = NewToolError(fmt.Errorf("end must be after start"), "", true)
+	}
+	if err == nil {
+		step = end.Sub(start) / rangeSteps
+		if typed.Step != "" {
+			step, err = parsePrometheusDuration(typed.Step)
+		}
+		step = max(step, time.Second)
+	}
+	var resp *prometheusResponse
+	if err == nil {
+		resp, err = prometheusRequest(args, "/api/v1/query_range", url.Values{
+			"query": {typed.Query},
+			"start": {formatPrometheusTime(start)},
+			"end":   {formatPrometheusTime(end)},
+			"step":  {strconv.FormatFloat(step.Seconds(), 'f', -1, 64)},
+		})
+	}
+	if err != nil {
+		return map[string]any{
+			"success": false,
+			"error":   err.Error(),
+		}, err
+	}
+	points := typed.Points
+	if points <= 0 {
+		points = DefaultPrometheusPoints
+	}
+	points = min(points, maxPrometheusPoints)
+	var series []prometheusSeries
+	if err := json.Unmarshal(resp.Data.Result, &series); err != nil {
+		return nil, fmt.Errorf("failed to decode result: %w", err)
+	}
+	// the largest series first, they are usually what the question is about
+	sortSeries(series, func(s prometheusSeries) float64 { return seriesMax(s.Values) })
+	result := map[string]any{
+		"success": true,
+		"start":   start.UTC().Format(time.RFC3339),
+		"end":     end.UTC().Format(time.RFC3339),
+		"step":    step.String(),
+		"total":   len(series),
+	}
+	if len(resp.Warnings) > 0 {
+		result["warnings"] = resp.Warnings
+	}
+	if len(series) > maxPrometheusSeries {
+		series = series[:maxPrometheusSeries]
+		result["truncated"] = true
+	}
+	summaries := make([]map[string]any, len(series))
+	for i, s := range series {
+		summaries[i] = summarizeSeries(s, points)
+	}
+	result["series"] = summaries
+	return result, nil
+}
+
+// prometheusRequest calls the HTTP API and checks the response status
+func prometheusRequest(args map[string]any, path string, params url.Values) (*prometheusResponse, error) {
+	config, err := getPrometheusConfig(args)
+	if err != nil {
+		return nil, err
+	}
+	authorization := ""
+	if config.Token != "" {
+		authorization = "Bearer " + config.Token
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+	var resp prometheusResponse
+	err = requestJSON(ctx, http.MethodGet, strings.TrimRight(config.URL, "/")+path+"?"+params.Encode(), authorization, nil, &resp)
+	if err != nil {
+		// bad queries are rejected with 400, the model can fix those
+		if strings.Contains(err.Error(), "status code 400") || strings.Contains(err.Error(), "status code 422") {
+			return nil, NewToolError(fmt.Errorf("query failed: %w", err), "Check the PromQL syntax and metric names.", true)
+		}
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+	if resp.Status != "success" {
+		return nil, NewToolError(fmt.Errorf("query failed: %s: %s", resp.ErrorType, resp.Error), "", true)
+	}
+	return &resp, nil
+}
+
+// summarizeSeries returns the statistics of a series and its values down-sampled by
+// averaging into at most points buckets
+func summarizeSeries(s prometheusSeries, points int) map[string]any {
+	summary := map[string]any{
+		"metric":  formatMetric(s.Metric),
+		"samples": len(s.Values),
+	}
+	if len(s.Values) == 0 {
+		return summary
+	}
+	minimum, maximum, sum := math.Inf(1), math.Inf(-1), 0.0
+	for _, sample := range s.Values {
+		minimum = math.Min(minimum, sample.Value)
+		maximum = math.Max(maximum, sample.Value)
+		sum += sample.Value
+	}
+	summary["min"] = roundValue(minimum)
+	summary["max"] = roundValue(maximum)
+	summary["avg"] = roundValue(sum / float64(len(s.Values)))
+	summary["first"] = roundValue(s.Values[0].Value)
+	summary["last"] = roundValue(s.Values[len(s.Values)-1].Value)
+	buckets := min(points, len(s.Values))
+	values := make([][2]any, 0, buckets)
+	for b := range buckets {
+		from := b * len(s.Values) / buckets
+		to := (b + 1) * len(s.Values) / buckets
+		bucketSum := 0.0
+		for _, sample := range s.Values[from:to] {
+			bucketSum += sample.Value
+		}
+		at := time.Unix(int64(s.Values[from].Time), 0).UTC().Format(time.RFC3339)
+		values = append(values, [2]any{at, roundValue(bucketSum / float64(to-from))})
+	}
+	summary["values"] = values
+	return summary
+}
+
+// roundValue keeps four significant digits, enough to reason about trends
+func roundValue(v float64) float64 {
+	if v == 0 || math.IsNaN(v) || math.IsInf(v, 0) {
+		return v
+	}
+	rounded, _ := strconv.ParseFloat(strconv.FormatFloat(v, 'g', 4, 64), 64)
+	return rounded
+}
+
+func seriesMax(values []prometheusSample) float64 {
+	maximum := math.Inf(-1)
+	for _, sample := range values {
+		maximum = math.Max(maximum, sample.Value)
+	}
+	return maximum
+}
+
+func sortSeries(series []prometheusSeries, key func(prometheusSeries) float64) {
+	sort.SliceStable(series, func(i, j int) bool {
+		return key(series[i]) > key(series[j])
+	})
+}
+
+// formatMetric writes labels like PromQL, e.g. up{instance="a:9100",job="node"}
+func formatMetric(metric map[string]string) string {
+	name := metric["__name__"]
+	labels := make([]string, 0, len(metric))
+	for label, value := range metric {
+		if label != "__name__" {
+			labels = append(labels, label+"="+strconv.Quote(value))
+		}
+	}
+	sort.Strings(labels)
+	return name + "{" + strings.Join(labels, ",") + "}"
+}
+
+// parsePrometheusTime accepts RFC3339, unix seconds, "now" or a duration ago such as 1h
+func parsePrometheusTime(s string, now time.Time) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || s == "now" {
+		return now, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	if seconds, err := strconv.ParseFloat(s, 64); err == nil {
+		return time.Unix(0, int64(seconds*float64(time.Second))), nil
+	}
+	ago, err := parsePrometheusDuration(strings.TrimPrefix(strings.TrimPrefix(s, "now-"), "-"))
+	if err != nil {
+		return time.Time{}, NewToolError(fmt.Errorf("invalid time %q", s), "Use RFC3339, a unix timestamp or a duration ago such as 1h.", true)
+	}
+	return now.Add(-ago), nil
+}
+
+// parsePrometheusDuration parses Go durations and the d and w units of PromQL
+func parsePrometheusDuration(s string) (time.Duration, error) {
+	for unit, length := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if n, ok := strings.CutSuffix(s, unit); ok {
+			count, err := strconv.ParseFloat(n, 64)
+			if err != nil {
+				return 0, NewToolError(fmt.Errorf("invalid duration %q", s), "", true)
+			}
+			return time.Duration(count * float64(length)), nil
+		}
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, NewToolError(fmt.Errorf("invalid duration %q", s), "Use a duration such as 30s, 5m, 1h or 1d.", true)
+	}
+	return d, nil
+}
+
+func formatPrometheusTime(t time.Time) string {
+	return strconv.FormatFloat(float64(t.UnixNano())/float64(time.Second), 'f', 3, 64)
+}
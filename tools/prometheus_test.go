@@ -0,0 +1,144 @@
+package tools
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPrometheusQuery(t *testing.T) {
+	var params url.Values
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer prom-token" || r.URL.Path != "/prom/api/v1/query" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		params = r.URL.Query()
+		w.Header().Set("Content-Type", "application/json")
+		switch params.Get("query") {
+		case "up":
+			fmt.Fprint(w, `{"status":"success","warnings":["partial data"],"data":{"resultType":"vector","result":[
+				{"metric":{"__name__":"up","job":"node","instance":"a:9100"},"value":[1705312800,"0"]},
+				{"metric":{"__name__":"up","job":"node","instance":"b:9100"},"value":[1705312800,"1"]}]}}`)
+		case "scalar(1)":
+			fmt.Fprint(w, `{"status":"success","data":{"resultType":"scalar","result":[1705312800,"1"]}}`)
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"status":"error","errorType":"bad_data","error":"parse error"}`)
+		}
+	}))
+	defer srv.Close()
+	env := map[string]string{PrometheusURLEnv: srv.URL + "/prom/", PrometheusTokenEnv: "prom-token"}
+
+	result, err := PrometheusQuery(map[string]any{"query": "up", "time": "2024-01-15T10:00:00Z", EnvArg: env})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if params.Get("time") != "1705312800.000" {
+		t.Errorf("time %s", params.Get("time"))
+	}
+	// the largest value comes first
+	series := result["series"].([]map[string]any)
+	if len(series) != 2 || series[0]["metric"] != `up{instance="b:9100",job="node"}` || series[0]["value"] != 1.0 {
+		t.Errorf("series %v", series)
+	}
+	if warnings := result["warnings"].([]string); len(warnings) != 1 {
+		t.Errorf("warnings %v", warnings)
+	}
+
+	result, err = PrometheusQuery(map[string]any{"query": "scalar(1)", EnvArg: env})
+	if err != nil || result["value"] != "1" || result["resultType"] != "scalar" {
+		t.Errorf("scalar %v, %v", result, err)
+	}
+	_, err = PrometheusQuery(map[string]any{"query": "up{", EnvArg: env})
+	var toolErr *ToolError
+	if !errors.As(err, &toolErr) || !toolErr.Retryable || !strings.Contains(err.Error(), "parse error") {
+		t.Errorf("bad query error %v", err)
+	}
+	if _, err := PrometheusQuery(map[string]any{"query": "up", EnvArg: map[string]string{}}); err == nil || !strings.Contains(err.Error(), PrometheusURLEnv) {
+		t.Errorf("unconfigured error %v", err)
+	}
+}
+
+func TestPrometheusQueryRange(t *testing.T) {
+	var params url.Values
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		params = r.URL.Query()
+		w.Header().Set("Content-Type", "application/json")
+		var values []string
+		for i := range 10 {
+			values = append(values, fmt.Sprintf(`[%d,"%d"]`, 1705312800+60*i, i))
+		}
+		fmt.Fprintf(w, `{"status":"success","data":{"resultType":"matrix","result":[
+			{"metric":{"job":"api"},"values":[[1705312800,"0.5"]]},
+			{"metric":{"job":"web"},"values":[%s]}]}}`, strings.Join(values, ","))
+	}))
+	defer srv.Close()
+	ConfigurePrometheus(PrometheusConfig{URL: srv.URL})
+	t.Cleanup(func() {
+		prometheusConfigMu.Lock()
+		defer prometheusConfigMu.Unlock()
+		prometheusConfig = nil
+	})
+
+	result, err := PrometheusQueryRange(map[string]any{"query": "rate(requests[5m])", "start": "2024-01-15T10:00:00Z", "end": "2024-01-15T10:10:00Z", "step": "1m", "points": 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if params.Get("start") != "1705312800.000" || params.Get("end") != "1705313400.000" || params.Get("step") != "60" {
+		t.Errorf("params %v", params)
+	}
+	series := result["series"].([]map[string]any)
+	web := series[0]
+	if web["metric"] != `{job="web"}` || web["samples"] != 10 || web["min"] != 0.0 || web["max"] != 9.0 || web["avg"] != 4.5 || web["last"] != 9.0 {
+		t.Errorf("web %v", web)
+	}
+	// ten samples are averaged into five buckets of two
+	values := web["values"].([][2]any)
+	if len(values) != 5 || values[0] != [2]any{"2024-01-15T10:00:00Z", 0.5} || values[4] != [2]any{"2024-01-15T10:08:00Z", 8.5} {
+		t.Errorf("values %v", values)
+	}
+	if result["step"] != "1m0s" || result["total"] != 2 {
+		t.Errorf("result %v", result)
+	}
+
+	// without a step the range is divided into steps
+	if _, err := PrometheusQueryRange(map[string]any{"query": "up", "start": "2024-01-15T10:00:00Z", "end": "2024-01-15T10:00:50Z"}); err != nil {
+		t.Fatal(err)
+	}
+	if params.Get("step") != "1" {
+		t.Errorf("step %s, want the one second minimum", params.Get("step"))
+	}
+	if _, err := PrometheusQueryRange(map[string]any{"query": "up", "start": "1h", "end": "2h"}); err == nil {
+		t.Error("queried a range that ends before it starts")
+	}
+}
+
+func TestParsePrometheusTime(t *testing.T) {
+	now := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		input string
+		want  time.Time
+	}{
+		{"now", now},
+		{"2024-01-15T10:00:00Z", time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)},
+		{"1705312800.5", time.Unix(1705312800, 500000000)},
+		{"6h", now.Add(-6 * time.Hour)},
+		{"now-2d", now.Add(-48 * time.Hour)},
+		{"-1w", now.Add(-7 * 24 * time.Hour)},
+	}
+	for _, tt := range tests {
+		got, err := parsePrometheusTime(tt.input, now)
+		if err != nil || !got.Equal(tt.want) {
+			t.Errorf("parsePrometheusTime(%q) = %v, %v, want %v", tt.input, got, err, tt.want)
+		}
+	}
+	if _, err := parsePrometheusTime("yesterday", now); err == nil {
+		t.Error("parsed yesterday")
+	}
+}
@@ -0,0 +1,336 @@
+package tools
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrObjectNotFound is returned when a key does not exist in the bucket
+var ErrObjectNotFound = errors.New("object not found")
+
+// S3Config configures an S3Client. GCS buckets are reached through the XML API
+// with an HMAC key and the endpoint https://storage.googleapis.com.
+type S3Config struct {
+	// Endpoint such as http://localhost:9000, AWS when empty
+	Endpoint     string
+	Region       string
+	Bucket       string
+	AccessKey    string
+	SecretKey    string
+	SessionToken string
+	// PathStyle puts the bucket in the path instead of the host name, always used
+	// with a custom Endpoint
+	PathStyle bool
+}
+
+// S3ConfigFromEnv reads the standard AWS_* variables, the bucket is left to the caller
+func S3ConfigFromEnv() S3Config {
+	return s3Config(os.Getenv)
+}
+
+// s3Config reads the standard AWS_* names with get
+func s3Config(get func(string) string) S3Config {
+	config := S3Config{
+		Endpoint:     get("AWS_ENDPOINT_URL_S3"),
+		Region:       get("AWS_REGION"),
+		AccessKey:    get("AWS_ACCESS_KEY_ID"),
+		SecretKey:    get("AWS_SECRET_ACCESS_KEY"),
+		SessionToken: get("AWS_SESSION_TOKEN"),
+	}
+	if config.Endpoint == "" {
+		config.Endpoint = get("AWS_ENDPOINT_URL")
+	}
+	if config.Region == "" {
+		config.Region = get("AWS_DEFAULT_REGION")
+	}
+	return config
+}
+
+// S3Client is a minimal client for S3 compatible object storage signed with SigV4
+type S3Client struct {
+	config   S3Config
+	endpoint *url.URL
+	client   *http.Client
+}
+
+// S3Object describes an object in a listing
+type S3Object struct {
+	Key          string    `json:"key"`
+	Size         int64     `json:"size"`
+	LastModified time.Time `json:"last_modified"`
+}
+
+// S3ListResult is a page of a bucket listing
+type S3ListResult struct {
+	Objects []S3Object `json:"objects"`
+	// Prefixes are the common prefixes when listing with a delimiter
+	Prefixes         []string `json:"prefixes,omitempty"`
+	NextContinuation string   `json:"next_continuation,omitempty"`
+}
+
+// NewS3Client creates a client for the configured bucket
+func NewS3Client(config S3Config) (*S3Client, error) {
+	if config.Bucket == "" {
+		return nil, fmt.Errorf("bucket is required")
+	}
+	if config.AccessKey == "" || config.SecretKey == "" {
+		return nil, fmt.Errorf("access key and secret key are required")
+	}
+	if config.Region == "" {
+		config.Region = "us-east-1"
+	}
+	endpoint := config.Endpoint
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", config.Region)
+	} else {
+		config.PathStyle = true
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid endpoint %q", endpoint)
+	}
+	return &S3Client{config: config, endpoint: u, client: &http.Client{Timeout: 5 * time.Minute}}, nil
+}
+
+// Bucket returns the bucket the client reads and writes
+func (c *S3Client) Bucket() string {
+	return c.config.Bucket
+}
+
+// Put uploads size bytes from body to key
+func (c *S3Client) Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error {
+	header := http.Header{}
+	if contentType != "" {
+		header.Set("Content-Type", contentType)
+	}
+	resp, err := c.do(ctx, http.MethodPut, key, nil, header, body, size)
+	if err != nil {
+		return fmt.Errorf("failed to put %s: %w", key, err)
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// Get opens the object at key, the caller closes the reader
+func (c *S3Client) Get(ctx context.Context, key string) (io.ReadCloser, int64, error) {
+	resp, err := c.do(ctx, http.MethodGet, key, nil, nil, nil, 0)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get %s: %w", key, err)
+	}
+	return resp.Body, resp.ContentLength, nil
+}
+
+// Delete removes the object at key
+func (c *S3Client) Delete(ctx context.Context, key string) error {
+	resp, err := c.do(ctx, http.MethodDelete, key, nil, nil, nil, 0)
+	if err != nil {
+		return fmt.Errorf("failed to delete %s: %w", key, err)
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// List returns up to maxKeys objects under prefix. A delimiter such as / groups keys
+// below it into Prefixes, continuation is NextContinuation from the previous page.
+func (c *S3Client) List(ctx context.Context, prefix string, delimiter string, continuation string, maxKeys int) (*S3ListResult, error) {
+	query := url.Values{"list-type": {"2"}}
+	if prefix != "" {
+		query.Set("prefix", prefix)
+	}
+	if delimiter != "" {
+		query.Set("delimiter", delimiter)
+	}
+	if continuation != "" {
+		query.Set("continuation-token", continuation)
+	}
+	if maxKeys > 0 {
+		query.Set("max-keys", fmt.Sprint(maxKeys))
+	}
+	resp, err := c.do(ctx, http.MethodGet, "", query, nil, nil, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list objects: %w", err)
+	}
+	defer resp.Body.Close()
+	var listing struct {
+		Contents []struct {
+			Key          string    `xml:"Key"`
+			Size         int64     `xml:"Size"`
+			LastModified time.Time `xml:"LastModified"`
+		} `xml:"Contents"`
+		CommonPrefixes []struct {
+			Prefix string `xml:"Prefix"`
+		} `xml:"CommonPrefixes"`
+		IsTruncated           bool   `xml:"IsTruncated"`
+		NextContinuationToken string `xml:"NextContinuationToken"`
+	}
+	if err := xml.NewDecoder(resp.Body).Decode(&listing); err != nil {
+		return nil, fmt.Errorf("failed to decode listing: %w", err)
+	}
+	result := &S3ListResult{Objects: []S3Object{}}
+	for _, object := range listing.Contents {
+		result.Objects = append(result.Objects, S3Object{Key: object.Key, Size: object.Size, LastModified: object.LastModified})
+	}
+	for _, prefix := range listing.CommonPrefixes {
+		result.Prefixes = append(result.Prefixes, prefix.Prefix)
+	}
+	if listing.IsTruncated {
+		result.NextContinuation = listing.NextContinuationToken
+	}
+	return result, nil
+}
+
+// do signs and sends a request, responses other than 2xx are returned as errors
+func (c *S3Client) do(ctx context.Context, method string, key string, query url.Values, header http.Header, body io.Reader, size int64) (*http.Response, error) {
+	u := *c.endpoint
+	path := strings.TrimSuffix(u.Path, "/")
+	if c.config.PathStyle {
+		path += "/" + c.config.Bucket
+	} else {
+		u.Host = c.config.Bucket + "." + u.Host
+	}
+	path += "/" + key
+	u.Path = path
+	u.RawPath = s3EscapePath(path)
+	u.RawQuery = s3CanonicalQuery(query)
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	if body != nil {
+		req.ContentLength = size
+	}
+	c.sign(req, time.Now().UTC())
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 == 2 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound && key != "" {
+		return nil, ErrObjectNotFound
+	}
+	var s3Err struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if xml.Unmarshal(data, &s3Err) == nil && s3Err.Code != "" {
+		return nil, fmt.Errorf("status code %d: %s: %s", resp.StatusCode, s3Err.Code, s3Err.Message)
+	}
+	return nil, fmt.Errorf("status code %d", resp.StatusCode)
+}
+
+// sign adds an AWS Signature Version 4 Authorization header. The payload is not
+// hashed so bodies can be streamed.
+func (c *S3Client) sign(req *http.Request, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", "UNSIGNED-PAYLOAD")
+	if c.config.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", c.config.SessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		lower := strings.ToLower(name)
+		if lower == "content-type" || strings.HasPrefix(lower, "x-amz-") {
+			headers[lower] = strings.TrimSpace(strings.Join(values, ","))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		"UNSIGNED-PAYLOAD",
+	}, "\n")
+	scope := date + "/" + c.config.Region + "/s3/aws4_request"
+	hashed := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(hashed[:])
+
+	key := hmacSHA256([]byte("AWS4"+c.config.SecretKey), date)
+	key = hmacSHA256(key, c.config.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		c.config.AccessKey, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// s3Escape percent encodes everything except the unreserved characters, as SigV4 requires
+func s3Escape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		if ('A' <= ch && ch <= 'Z') || ('a' <= ch && ch <= 'z') || ('0' <= ch && ch <= '9') ||
+			ch == '-' || ch == '_' || ch == '.' || ch == '~' {
+			b.WriteByte(ch)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", ch)
+		}
+	}
+	return b.String()
+}
+
+func s3EscapePath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		segments[i] = s3Escape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// s3CanonicalQuery encodes the query sorted by key, which is also a valid query string
+func s3CanonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var parts []string
+	for _, key := range keys {
+		for _, value := range query[key] {
+			parts = append(parts, s3Escape(key)+"="+s3Escape(value))
+		}
+	}
+	return strings.Join(parts, "&")
+}
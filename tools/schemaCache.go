@@ -0,0 +1,46 @@
+package tools
+
+import "sync"
+
+type schemaKey struct {
+	tool     string
+	provider string
+}
+
+// schemaCache holds provider specific schemas converted from registered tools so
+// they are not rebuilt on every turn. Entries are dropped when a tool is registered
+// or unregistered.
+var schemaCache = struct {
+	sync.RWMutex
+	entries map[schemaKey]any
+}{entries: make(map[schemaKey]any)}
+
+// CachedSchema returns the cached schema of a tool for a provider, building and
+// caching it with build on a miss
+func CachedSchema(toolName string, provider string, build func() (any, error)) (any, error) {
+	key := schemaKey{tool: toolName, provider: provider}
+	schemaCache.RLock()
+	schema, ok := schemaCache.entries[key]
+	schemaCache.RUnlock()
+	if ok {
+		return schema, nil
+	}
+	schema, err := build()
+	if err != nil {
+		return nil, err
+	}
+	schemaCache.Lock()
+	schemaCache.entries[key] = schema
+	schemaCache.Unlock()
+	return schema, nil
+}
+
+func invalidateSchemas(toolName string) {
+	schemaCache.Lock()
+	defer schemaCache.Unlock()
+	for key := range schemaCache.entries {
+		if key.tool == toolName {
+			delete(schemaCache.entries, key)
+		}
+	}
+}
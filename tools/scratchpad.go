@@ -0,0 +1,137 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	ScratchpadToolName = "scratchpad"
+
+	// scratchpadPrefix namespaces scratchpad entries in the conversation store
+	scratchpadPrefix = "scratchpad/"
+)
+
+var scratchpadTools = map[string]Tool{
+	ScratchpadToolName: scratchpadTool,
+}
+
+var scratchpadTool = Tool{
+	Name:        ScratchpadToolName,
+	Description: "A key/value scratchpad for working notes that lasts for the whole conversation, e.g. a plan, findings or intermediate results. Use memory tools for knowledge shared across conversations.",
+	Parameters: []Parameter{
+		{
+			Name:        "operation",
+			Type:        "string",
+			Description: "The operation to perform: set, get, append, delete or list",
+			Required:    true,
+		},
+		{
+			Name:        "key",
+			Type:        "string",
+			Description: "The entry key, required for every operation except list",
+			Required:    false,
+		},
+		{
+			Name:        "value",
+			Type:        "string",
+			Description: "The value to set or append, append adds a new line to the existing value",
+			Required:    false,
+		},
+	},
+	Options: map[string]string{},
+	Run:     Scratchpad,
+}
+
+func Scratchpad(args map[string]any) (map[string]any, error) {
+	conversationID, ok := args[ConversationIDArg].(string)
+	if !ok || conversationID == "" {
+		return map[string]any{
+			"success": false,
+			"error":   "scratchpad requires a conversation",
+		}, fmt.Errorf("scratchpad requires the %s argument", ConversationIDArg)
+	}
+	operation, ok := args["operation"].(string)
+	if !ok {
+		return map[string]any{
+			"success": false,
+			"error":   fmt.Sprintf("expected string: %v", args["operation"]),
+		}, fmt.Errorf("expected string: %v", args["operation"])
+	}
+	ctx := context.Background()
+	store := getConversationStore()
+
+	if operation == "list" {
+		entries, err := store.List(ctx, conversationID, scratchpadPrefix)
+		if err != nil {
+			return map[string]any{
+				"success": false,
+				"error":   err.Error(),
+			}, err
+		}
+		keys := make([]string, 0, len(entries))
+		values := make(map[string]any, len(entries))
+		for key, value := range entries {
+			key = strings.TrimPrefix(key, scratchpadPrefix)
+			keys = append(keys, key)
+			values[key] = value
+		}
+		sort.Strings(keys)
+		return map[string]any{
+			"success": true,
+			"keys":    keys,
+			"entries": values,
+		}, nil
+	}
+
+	key, ok := args["key"].(string)
+	if !ok || key == "" {
+		return map[string]any{
+			"success": false,
+			"error":   fmt.Sprintf("%s requires a key", operation),
+		}, fmt.Errorf("%s requires a key", operation)
+	}
+	value, _ := args["value"].(string)
+	storeKey := scratchpadPrefix + key
+
+	var err error
+	result := map[string]any{
+		"success": true,
+		"key":     key,
+	}
+	switch operation {
+	case "set":
+		err = store.Set(ctx, conversationID, storeKey, value)
+		result["value"] = value
+	case "get":
+		var found bool
+		value, found, err = store.Get(ctx, conversationID, storeKey)
+		result["found"] = found
+		result["value"] = value
+	case "append":
+		var current string
+		var found bool
+		current, found, err = store.Get(ctx, conversationID, storeKey)
+		if err != nil {
+			break
+		}
+		if found && current != "" {
+			value = current + "\n" + value
+		}
+		err = store.Set(ctx, conversationID, storeKey, value)
+		result["value"] = value
+	case "delete":
+		err = store.Delete(ctx, conversationID, storeKey)
+	default:
+		err = fmt.Errorf("unknown operation: %s", operation)
+	}
+	if err != nil {
+		return map[string]any{
+			"success": false,
+			"error":   err.Error(),
+		}, err
+	}
+	return result, nil
+}
@@ -6,7 +6,6 @@ import (
 	"io"
 	"net/http"
 	"net/url"
-	"os"
 
 	"golang.org/x/net/html"
 )
@@ -39,8 +38,15 @@ func SearchWeb(args map[string]any) (map[string]any, error) {
 		}, fmt.Errorf("query is not a string")
 	}
 
-	// get searxngURL from environment variable
-	searxngURL := os.Getenv("SEARXNG_URL")
+	// get searxngURL from the credentials provider
+	creds := credentialsFor(args)
+	searxngURL := creds.get("SEARXNG_URL")
+	if creds.err != nil {
+		return map[string]any{
+			"success": false,
+			"error":   creds.err.Error(),
+		}, creds.err
+	}
 	if searxngURL == "" {
 		return map[string]any{
 			"success": false,
@@ -0,0 +1,65 @@
+package tools
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Arguments injected by the framework from the conversation's session context.
+// They take precedence over the static Options of a tool.
+const (
+	// BasePathArg is the directory file tools resolve paths against
+	BasePathArg = "basePath"
+	// RepoRootArg is the git repository git tools operate on, defaults to BasePathArg
+	RepoRootArg = "repoRoot"
+	// SessionFactsArg holds free form facts about the environment as a map[string]string
+	SessionFactsArg = "sessionFacts"
+	// ConversationIDArg identifies the conversation for tools that keep state in the ConversationStore
+	ConversationIDArg = "conversationID"
+	// CredentialScopeArg selects whose credentials the CredentialsProvider returns
+	CredentialScopeArg = "credentialScope"
+	// EnvArg holds the conversation's environment variables as a map[string]string.
+	// Credentials, such as JIRA_URL or SLACK_CHANNEL, and the variables of command tools
+	// are read from it before the process environment or CredentialsProvider.
+	EnvArg = "env"
+	// RequestIDArg is the ID of the chat turn the tool runs in, OpenAPI tools send it in
+	// the X-Request-Id header
+	RequestIDArg = "requestID"
+)
+
+// sessionArgs are the arguments only the framework may set
+var sessionArgs = []string{BasePathArg, RepoRootArg, SessionFactsArg, ConversationIDArg, CredentialScopeArg, EnvArg, RequestIDArg}
+
+// StripSessionArgs deletes the session arguments from the arguments of a tool call, so a
+// model can not choose another directory, repository, environment or credential scope
+func StripSessionArgs(args map[string]any) {
+	for _, name := range sessionArgs {
+		delete(args, name)
+	}
+}
+
+// repoPath returns the repository git tools should open
+func repoPath(args map[string]any) (string, bool) {
+	if root, ok := args[RepoRootArg].(string); ok && root != "" {
+		return root, true
+	}
+	path, ok := args[BasePathArg].(string)
+	return path, ok
+}
+
+// envReference matches ${NAME} references to environment variables in tool options
+var envReference = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// ResolveOption replaces the ${NAME} references in a tool option with the variables of
+// the conversation's environment, references to variables it does not set are kept
+func ResolveOption(value string, env map[string]string) string {
+	if len(env) == 0 || !strings.Contains(value, "${") {
+		return value
+	}
+	return envReference.ReplaceAllStringFunc(value, func(reference string) string {
+		if resolved, ok := env[reference[2:len(reference)-1]]; ok {
+			return resolved
+		}
+		return reference
+	})
+}
@@ -0,0 +1,676 @@
+package tools
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"math/rand/v2"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	AnalyzeSpreadsheetToolName = "analyze_spreadsheet"
+
+	// DefaultSpreadsheetRows is the number of rows returned by head, sample and filter
+	DefaultSpreadsheetRows = 10
+
+	maxSpreadsheetRows  = 100
+	maxSpreadsheetBytes = 64 << 20
+	maxCellLength       = 200
+	topValueCount       = 5
+)
+
+var spreadsheetTools = map[string]Tool{
+	AnalyzeSpreadsheetToolName: analyzeSpreadsheetTool,
+}
+
+var analyzeSpreadsheetTool = Tool{
+	Name:        AnalyzeSpreadsheetToolName,
+	Description: "Inspect a CSV, TSV or XLSX file without reading all of it: info lists the columns and their types, head and sample return rows, stats summarizes columns and filter returns the rows matching conditions",
+	Parameters: []Parameter{
+		{
+			Name:        "path",
+			Type:        "string",
+			Description: "The spreadsheet file, the first row holds the column names",
+			Required:    true,
+		},
+		{
+			Name:        "operation",
+			Type:        "string",
+			Description: "The operation to perform: info, head, sample, stats or filter",
+			Required:    true,
+		},
+		{
+			Name:        "sheet",
+			Type:        "string",
+			Description: "The XLSX sheet to read, defaults to the first sheet",
+			Required:    false,
+		},
+		{
+			Name:        "columns",
+			Type:        "stringArray",
+			Description: "Only return or summarize these columns",
+			Required:    false,
+		},
+		{
+			Name:        "where",
+			Type:        "stringArray",
+			Description: "Conditions for filter that must all match, e.g. status = open, age >= 30 or name contains smith. Operators are =, !=, >, >=, <, <= and contains.",
+			Required:    false,
+		},
+		{
+			Name:        "rows",
+			Type:        "integer",
+			Description: fmt.Sprintf("The number of rows returned by head, sample and filter, defaults to %d", DefaultSpreadsheetRows),
+			Required:    false,
+		},
+	},
+	Options: map[string]string{
+		"basePath": ".",
+	},
+	Run:       AnalyzeSpreadsheet,
+	Paginated: true,
+}
+
+type analyzeSpreadsheetArgs struct {
+	Path      string   `json:"path"`
+	Operation string   `json:"operation"`
+	Sheet     string   `json:"sheet"`
+	Columns   []string `json:"columns"`
+	Where     []string `json:"where"`
+	Rows      int      `json:"rows"`
+	BasePath  string   `json:"basePath"`
+}
+
+// spreadsheet is a table whose first row was the header
+type spreadsheet struct {
+	columns []string
+	rows    [][]string
+	sheets  []string
+	sheet   string
+}
+
+func AnalyzeSpreadsheet(args map[string]any) (map[string]any, error) {
+	typed, err := DecodeArgs[analyzeSpreadsheetArgs](args)
+	if err == nil && typed.Path == "" {
+		err = NewToolError(fmt.Errorf("path is required"), "", true)
+	}
+	var sheet *spreadsheet
+	if err == nil {
+		sheet, err = loadSpreadsheet(typed.BasePath, typed.Path, typed.Sheet)
+	}
+	var columns []int
+	if err == nil {
+		columns, err = sheet.selectColumns(typed.Columns)
+	}
+	rows := typed.Rows
+	if rows <= 0 {
+		rows = DefaultSpreadsheetRows
+	}
+	rows = min(rows, maxSpreadsheetRows)
+	var result map[string]any
+	if err == nil {
+		result = map[string]any{
+			"success":   true,
+			"operation": typed.Operation,
+			"row_count": len(sheet.rows),
+		}
+		if len(sheet.sheets) > 0 {
+			result["sheet"] = sheet.sheet
+			result["sheets"] = sheet.sheets
+		}
+		switch typed.Operation {
+		case "info":
+			types := make(map[string]string, len(columns))
+			for _, column := range columns {
+				types[sheet.columns[column]] = sheet.columnType(column)
+			}
+			result["columns"] = sheet.names(columns)
+			result["types"] = types
+		case "head":
+			var page [][]string
+			var next string
+			page, next, err = Paginate(sheet.rows, args, rows)
+			result["columns"] = sheet.names(columns)
+			result["rows"] = project(page, columns)
+			setNextCursor(result, next)
+		case "sample":
+			result["columns"] = sheet.names(columns)
+			result["rows"] = project(sampleRows(sheet.rows, rows), columns)
+		case "stats":
+			stats := make([]map[string]any, len(columns))
+			for i, column := range columns {
+				stats[i] = sheet.columnStats(column)
+			}
+			result["stats"] = stats
+		case "filter":
+			var conditions []condition
+			conditions, err = sheet.parseConditions(typed.Where)
+			var matches [][]string
+			for _, row := range sheet.rows {
+				if err == nil && matchesAll(row, conditions) {
+					matches = append(matches, row)
+				}
+			}
+			var page [][]string
+			var next string
+			if err == nil {
+				page, next, err = Paginate(matches, args, rows)
+			}
+			result["matches"] = len(matches)
+			result["columns"] = sheet.names(columns)
+			result["rows"] = project(page, columns)
+			setNextCursor(result, next)
+		default:
+			err = NewToolError(fmt.Errorf("unknown operation: %s", typed.Operation), "Use info, head, sample, stats or filter.", true)
+		}
+	}
+	if err != nil {
+		return map[string]any{
+			"success": false,
+			"error":   err.Error(),
+		}, err
+	}
+	return result, nil
+}
+
+// loadSpreadsheet reads a CSV, TSV or XLSX file in the sandbox
+func loadSpreadsheet(basePath string, name string, sheetName string) (*spreadsheet, error) {
+	p, err := sandboxPath(basePath, name)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(p)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	if info.Size() > maxSpreadsheetBytes {
+		return nil, NewToolError(fmt.Errorf("%s is %d bytes, the limit is %d", name, info.Size(), maxSpreadsheetBytes), "", false)
+	}
+	var records [][]string
+	sheet := &spreadsheet{}
+	switch strings.ToLower(filepath.Ext(p)) {
+	case ".xlsx", ".xlsm":
+		records, sheet.sheets, sheet.sheet, err = readXLSX(p, sheetName)
+	case ".tsv", ".tab":
+		records, err = readDelimited(p, '\t')
+	default:
+		records, err = readDelimited(p, ',')
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, NewToolError(fmt.Errorf("%s is empty", name), "", false)
+	}
+	sheet.columns = headerNames(records[0])
+	for _, record := range records[1:] {
+		row := make([]string, len(sheet.columns))
+		copy(row, record)
+		sheet.rows = append(sheet.rows, row)
+	}
+	return sheet, nil
+}
+
+func readDelimited(p string, delimiter rune) ([][]string, error) {
+	file, err := os.Open(p)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	defer file.Close()
+	reader := csv.NewReader(file)
+	reader.Comma = delimiter
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, NewToolError(fmt.Errorf("failed to parse %s: %w", filepath.Base(p), err), "", false)
+	}
+	// a byte order mark would become part of the first column name
+	if len(records) > 0 && len(records[0]) > 0 {
+		records[0][0] = strings.TrimPrefix(records[0][0], "\ufeff")
+	}
+	return records, nil
+}
+
+// headerNames names empty columns by position and makes duplicate names unique
+func headerNames(header []string) []string {
+	names := make([]string, len(header))
+	seen := map[string]int{}
+	for i, name := range header {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			name = fmt.Sprintf("column_%d", i+1)
+		}
+		if seen[name]++; seen[name] > 1 {
+			name = fmt.Sprintf("%s_%d", name, seen[name])
+		}
+		names[i] = name
+	}
+	return names
+}
+
+func (s *spreadsheet) selectColumns(names []string) ([]int, error) {
+	if len(names) == 0 {
+		columns := make([]int, len(s.columns))
+		for i := range columns {
+			columns[i] = i
+		}
+		return columns, nil
+	}
+	columns := make([]int, 0, len(names))
+	for _, name := range names {
+		column, err := s.column(name)
+		if err != nil {
+			return nil, err
+		}
+		columns = append(columns, column)
+	}
+	return columns, nil
+}
+
+func (s *spreadsheet) column(name string) (int, error) {
+	for i, column := range s.columns {
+		if column == name {
+			return i, nil
+		}
+	}
+	for i, column := range s.columns {
+		if strings.EqualFold(column, name) {
+			return i, nil
+		}
+	}
+	return 0, NewToolError(fmt.Errorf("unknown column: %s", name), fmt.Sprintf("The columns are %s.", strings.Join(s.columns, ", ")), true)
+}
+
+func (s *spreadsheet) names(columns []int) []string {
+	names := make([]string, len(columns))
+	for i, column := range columns {
+		names[i] = s.columns[column]
+	}
+	return names
+}
+
+// columnType is number when every non-empty value is a number, empty when there are no values
+func (s *spreadsheet) columnType(column int) string {
+	kind := "empty"
+	for _, row := range s.rows {
+		value := strings.TrimSpace(row[column])
+		if value == "" {
+			continue
+		}
+		if _, ok := parseNumber(value); !ok {
+			return "text"
+		}
+		kind = "number"
+	}
+	return kind
+}
+
+func (s *spreadsheet) columnStats(column int) map[string]any {
+	kind := s.columnType(column)
+	stats := map[string]any{
+		"column": s.columns[column],
+		"type":   kind,
+	}
+	counts := map[string]int{}
+	var numbers []float64
+	empty := 0
+	for _, row := range s.rows {
+		value := strings.TrimSpace(row[column])
+		if value == "" {
+			empty++
+			continue
+		}
+		counts[value]++
+		if kind == "number" {
+			n, _ := parseNumber(value)
+			numbers = append(numbers, n)
+		}
+	}
+	stats["count"] = len(s.rows) - empty
+	stats["empty"] = empty
+	stats["unique"] = len(counts)
+	if len(numbers) > 0 {
+		sort.Float64s(numbers)
+		sum := 0.0
+		for _, n := range numbers {
+			sum += n
+		}
+		mean := sum / float64(len(numbers))
+		variance := 0.0
+		for _, n := range numbers {
+			variance += (n - mean) * (n - mean)
+		}
+		stats["min"] = numbers[0]
+		stats["max"] = numbers[len(numbers)-1]
+		stats["sum"] = sum
+		stats["mean"] = mean
+		stats["median"] = percentile(numbers, 0.5)
+		stats["p90"] = percentile(numbers, 0.9)
+		stats["stddev"] = math.Sqrt(variance / float64(len(numbers)))
+		return stats
+	}
+	if kind == "text" {
+		values := make([]string, 0, len(counts))
+		for value := range counts {
+			values = append(values, value)
+		}
+		sort.Slice(values, func(i, j int) bool {
+			if counts[values[i]] != counts[values[j]] {
+				return counts[values[i]] > counts[values[j]]
+			}
+			return values[i] < values[j]
+		})
+		top := make([]map[string]any, 0, topValueCount)
+		for _, value := range values[:min(len(values), topValueCount)] {
+			top = append(top, map[string]any{"value": truncateCell(value), "count": counts[value]})
+		}
+		stats["top_values"] = top
+	}
+	return stats
+}
+
+// percentile interpolates between the closest ranks of sorted values
+func percentile(sorted []float64, p float64) float64 {
+	rank := p * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+	return sorted[lower] + (sorted[upper]-sorted[lower])*(rank-float64(lower))
+}
+
+// parseNumber accepts numbers written with thousands separators, currency symbols or a percent sign
+func parseNumber(value string) (float64, bool) {
+	cleaned := strings.NewReplacer(",", "", "$", "", "€", "", "£", "", "%", "").Replace(value)
+	n, err := strconv.ParseFloat(strings.TrimSpace(cleaned), 64)
+	return n, err == nil && !math.IsNaN(n) && !math.IsInf(n, 0)
+}
+
+type condition struct {
+	column   int
+	operator string
+	value    string
+}
+
+// conditionOperators are ordered so that >= is found before =
+var conditionOperators = []string{" contains ", "!=", ">=", "<=", "==", "=", ">", "<"}
+
+func (s *spreadsheet) parseConditions(where []string) ([]condition, error) {
+	conditions := make([]condition, 0, len(where))
+	for _, expression := range where {
+		parsed := false
+		for _, operator := range conditionOperators {
+			name, value, ok := strings.Cut(expression, operator)
+			if !ok {
+				continue
+			}
+			column, err := s.column(strings.TrimSpace(name))
+			if err != nil {
+				return nil, err
+			}
+			value = strings.Trim(strings.TrimSpace(value), `"'`)
+			conditions = append(conditions, condition{column: column, operator: strings.TrimSpace(operator), value: value})
+			parsed = true
+			break
+		}
+		if !parsed {
+			return nil, NewToolError(fmt.Errorf("invalid condition: %s", expression), "Write conditions as column operator value, e.g. age >= 30.", true)
+		}
+	}
+	return conditions, nil
+}
+
+func matchesAll(row []string, conditions []condition) bool {
+	for _, c := range conditions {
+		if !c.matches(row[c.column]) {
+			return false
+		}
+	}
+	return true
+}
+
+// matches compares numerically when both sides are numbers, as case insensitive text otherwise
+func (c condition) matches(cell string) bool {
+	cell = strings.TrimSpace(cell)
+	if c.operator == "contains" {
+		return strings.Contains(strings.ToLower(cell), strings.ToLower(c.value))
+	}
+	compared := 0
+	a, aNumber := parseNumber(cell)
+	b, bNumber := parseNumber(c.value)
+	if aNumber && bNumber {
+		compared = cmpFloat(a, b)
+	} else {
+		compared = strings.Compare(strings.ToLower(cell), strings.ToLower(c.value))
+	}
+	switch c.operator {
+	case "=", "==":
+		return compared == 0
+	case "!=":
+		return compared != 0
+	case ">":
+		return compared > 0
+	case ">=":
+		return compared >= 0
+	case "<":
+		return compared < 0
+	case "<=":
+		return compared <= 0
+	}
+	return false
+}
+
+func cmpFloat(a float64, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// sampleRows picks n rows at random and returns them in file order
+func sampleRows(rows [][]string, n int) [][]string {
+	if n >= len(rows) {
+		return rows
+	}
+	picked := rand.Perm(len(rows))[:n]
+	sort.Ints(picked)
+	sample := make([][]string, n)
+	for i, index := range picked {
+		sample[i] = rows[index]
+	}
+	return sample
+}
+
+// project returns the selected columns of rows with long cells truncated
+func project(rows [][]string, columns []int) [][]string {
+	projected := make([][]string, len(rows))
+	for i, row := range rows {
+		projected[i] = make([]string, len(columns))
+		for j, column := range columns {
+			projected[i][j] = truncateCell(row[column])
+		}
+	}
+	return projected
+}
+
+func truncateCell(value string) string {
+	if len(value) <= maxCellLength {
+		return value
+	}
+	cut := maxCellLength
+	for cut > 0 && value[cut]&0xC0 == 0x80 {
+		cut--
+	}
+	return value[:cut] + "…"
+}
+
+// readXLSX reads the cell values of a worksheet, selected by name or the first sheet.
+// Formulas are read as their cached values and dates as serial numbers.
+func readXLSX(p string, sheetName string) ([][]string, []string, string, error) {
+	archive, err := zip.OpenReader(p)
+	if err != nil {
+		return nil, nil, "", NewToolError(fmt.Errorf("failed to open %s: %w", filepath.Base(p), err), "", false)
+	}
+	defer archive.Close()
+	files := make(map[string]*zip.File, len(archive.File))
+	for _, file := range archive.File {
+		files[file.Name] = file
+	}
+
+	var workbook struct {
+		Sheets []struct {
+			Name string `xml:"name,attr"`
+			ID   string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	if err := decodeZipXML(files, "xl/workbook.xml", &workbook); err != nil {
+		return nil, nil, "", err
+	}
+	var relationships struct {
+		Relationships []struct {
+			ID     string `xml:"Id,attr"`
+			Target string `xml:"Target,attr"`
+		} `xml:"Relationship"`
+	}
+	if err := decodeZipXML(files, "xl/_rels/workbook.xml.rels", &relationships); err != nil {
+		return nil, nil, "", err
+	}
+	if len(workbook.Sheets) == 0 {
+		return nil, nil, "", NewToolError(fmt.Errorf("%s has no sheets", filepath.Base(p)), "", false)
+	}
+	sheets := make([]string, len(workbook.Sheets))
+	selected := -1
+	for i, sheet := range workbook.Sheets {
+		sheets[i] = sheet.Name
+		if selected < 0 && (sheetName == "" || strings.EqualFold(sheet.Name, sheetName)) {
+			selected = i
+		}
+	}
+	if selected < 0 {
+		return nil, nil, "", NewToolError(fmt.Errorf("unknown sheet: %s", sheetName), fmt.Sprintf("The sheets are %s.", strings.Join(sheets, ", ")), true)
+	}
+	target := ""
+	for _, relationship := range relationships.Relationships {
+		if relationship.ID == workbook.Sheets[selected].ID {
+			target = relationship.Target
+		}
+	}
+	if strings.HasPrefix(target, "/") {
+		target = strings.TrimPrefix(target, "/")
+	} else {
+		target = path.Join("xl", target)
+	}
+
+	var sharedStrings struct {
+		Items []xlsxText `xml:"si"`
+	}
+	if _, ok := files["xl/sharedStrings.xml"]; ok {
+		if err := decodeZipXML(files, "xl/sharedStrings.xml", &sharedStrings); err != nil {
+			return nil, nil, "", err
+		}
+	}
+	var worksheet struct {
+		Rows []struct {
+			Index int `xml:"r,attr"`
+			Cells []struct {
+				Ref    string   `xml:"r,attr"`
+				Type   string   `xml:"t,attr"`
+				Value  string   `xml:"v"`
+				Inline xlsxText `xml:"is"`
+			} `xml:"c"`
+		} `xml:"sheetData>row"`
+	}
+	if err := decodeZipXML(files, target, &worksheet); err != nil {
+		return nil, nil, "", err
+	}
+	var records [][]string
+	for _, row := range worksheet.Rows {
+		// rows without values are left out of the file, keep the blank lines
+		for row.Index > len(records)+1 {
+			records = append(records, nil)
+		}
+		var record []string
+		for i, cell := range row.Cells {
+			column := i
+			if cell.Ref != "" {
+				column = xlsxColumn(cell.Ref)
+			}
+			for len(record) <= column {
+				record = append(record, "")
+			}
+			switch cell.Type {
+			case "s":
+				index, err := strconv.Atoi(cell.Value)
+				if err == nil && index >= 0 && index < len(sharedStrings.Items) {
+					record[column] = sharedStrings.Items[index].String()
+				}
+			case "inlineStr":
+				record[column] = cell.Inline.String()
+			case "b":
+				record[column] = map[string]string{"1": "TRUE", "0": "FALSE"}[cell.Value]
+			default:
+				record[column] = cell.Value
+			}
+		}
+		records = append(records, record)
+	}
+	return records, sheets, sheets[selected], nil
+}
+
+// xlsxText is a string that is either plain or made of formatted runs
+type xlsxText struct {
+	Text string `xml:"t"`
+	Runs []struct {
+		Text string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (t xlsxText) String() string {
+	if len(t.Runs) == 0 {
+		return t.Text
+	}
+	var sb strings.Builder
+	for _, run := range t.Runs {
+		sb.WriteString(run.Text)
+	}
+	return sb.String()
+}
+
+// xlsxColumn converts the letters of a cell reference such as AB12 to a zero based column
+func xlsxColumn(ref string) int {
+	column := 0
+	for _, r := range ref {
+		if r < 'A' || r > 'Z' {
+			break
+		}
+		column = column*26 + int(r-'A') + 1
+	}
+	return column - 1
+}
+
+func decodeZipXML(files map[string]*zip.File, name string, out any) error {
+	file, ok := files[name]
+	if !ok {
+		return NewToolError(fmt.Errorf("invalid xlsx file, %s is missing", name), "", false)
+	}
+	reader, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	if err := xml.NewDecoder(io.LimitReader(reader, 4*maxSpreadsheetBytes)).Decode(out); err != nil {
+		return NewToolError(fmt.Errorf("invalid xlsx file, failed to parse %s: %w", name, err), "", false)
+	}
+	return nil
+}
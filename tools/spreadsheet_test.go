@@ -0,0 +1,150 @@
+package tools
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// writeXLSX writes a workbook with the given parts to dir
+func writeXLSX(t *testing.T, dir string, name string, parts map[string]string) {
+	t.Helper()
+	file, err := os.Create(filepath.Join(dir, name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	archive := zip.NewWriter(file)
+	for name, content := range parts {
+		w, err := archive.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(content))
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+const testWorkbook = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+  <sheets>
+    <sheet name="Summary" sheetId="1" r:id="rId2"/>
+    <sheet name="Orders" sheetId="2" r:id="rId1"/>
+  </sheets>
+</workbook>`
+
+const testWorkbookRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+  <Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="/xl/worksheets/orders.xml"/>
+  <Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>
+</Relationships>`
+
+const testSharedStrings = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" count="6" uniqueCount="6">
+  <si><t>customer</t></si>
+  <si><t>total</t></si>
+  <si><t>paid</t></si>
+  <si><r><t>Acme </t></r><r><rPr><b/></rPr><t>Corp</t></r></si>
+  <si><t>Globex</t></si>
+  <si><t>region</t></si>
+</sst>`
+
+// the third row is left out, the note of Initech is in a column without a header
+const testOrdersSheet = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+  <sheetData>
+    <row r="1"><c r="A1" t="s"><v>0</v></c><c r="B1" t="s"><v>1</v></c><c r="C1" t="s"><v>2</v></c><c r="D1" t="s"><v>5</v></c></row>
+    <row r="2"><c r="A2" t="s"><v>3</v></c><c r="B2"><v>1250.5</v></c><c r="C2" t="b"><v>1</v></c><c r="D2" t="inlineStr"><is><t>EMEA</t></is></c></row>
+    <row r="4"><c r="A4" t="s"><v>4</v></c><c r="B4"><f>SUM(1,2)</f><v>3</v></c><c r="C4" t="b"><v>0</v></c></row>
+    <row r="5"><c r="A5" t="inlineStr"><is><t>Initech</t></is></c><c r="B5"><v>80</v></c><c r="D5" t="inlineStr"><is><t>AMER</t></is></c><c r="F5" t="inlineStr"><is><t>late</t></is></c></row>
+  </sheetData>
+</worksheet>`
+
+const testSummarySheet = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+  <sheetData><row r="1"><c r="A1" t="inlineStr"><is><t>quarter</t></is></c></row><row r="2"><c r="A2"><v>1</v></c></row></sheetData>
+</worksheet>`
+
+func TestAnalyzeSpreadsheetXLSX(t *testing.T) {
+	dir := t.TempDir()
+	writeXLSX(t, dir, "orders.xlsx", map[string]string{
+		"xl/workbook.xml":            testWorkbook,
+		"xl/_rels/workbook.xml.rels": testWorkbookRels,
+		"xl/sharedStrings.xml":       testSharedStrings,
+		"xl/worksheets/orders.xml":   testOrdersSheet,
+		"xl/worksheets/sheet1.xml":   testSummarySheet,
+	})
+	run := func(args map[string]any) map[string]any {
+		t.Helper()
+		args["basePath"] = dir
+		args["path"] = "orders.xlsx"
+		result, err := AnalyzeSpreadsheet(args)
+		if err != nil {
+			t.Fatalf("%v: %v", args, err)
+		}
+		return result
+	}
+
+	result := run(map[string]any{"operation": "info"})
+	if result["sheet"] != "Summary" || !reflect.DeepEqual(result["sheets"], []string{"Summary", "Orders"}) || result["row_count"] != 1 {
+		t.Errorf("first sheet %v", result)
+	}
+
+	result = run(map[string]any{"operation": "head", "sheet": "orders"})
+	want := [][]string{
+		{"Acme Corp", "1250.5", "TRUE", "EMEA"},
+		{"", "", "", ""},
+		{"Globex", "3", "FALSE", ""},
+		{"Initech", "80", "", "AMER"},
+	}
+	if !reflect.DeepEqual(result["rows"], want) {
+		t.Errorf("rows %v, want %v", result["rows"], want)
+	}
+	if !reflect.DeepEqual(result["columns"], []string{"customer", "total", "paid", "region"}) {
+		t.Errorf("columns %v", result["columns"])
+	}
+
+	result = run(map[string]any{"operation": "filter", "sheet": "Orders", "where": []any{"total > 50", "region != AMER"}, "columns": []any{"customer"}})
+	if result["matches"] != 1 || !reflect.DeepEqual(result["rows"], [][]string{{"Acme Corp"}}) {
+		t.Errorf("filter %v", result)
+	}
+
+	result = run(map[string]any{"operation": "stats", "sheet": "Orders", "columns": []any{"total"}})
+	stats := result["stats"].([]map[string]any)[0]
+	if stats["type"] != "number" || stats["count"] != 3 || stats["empty"] != 1 || stats["min"] != 3.0 || stats["max"] != 1250.5 || stats["median"] != 80.0 {
+		t.Errorf("stats %v", stats)
+	}
+
+	if _, err := AnalyzeSpreadsheet(map[string]any{"basePath": dir, "path": "orders.xlsx", "operation": "info", "sheet": "Missing"}); err == nil {
+		t.Error("read a missing sheet")
+	}
+}
+
+func TestAnalyzeSpreadsheetCSV(t *testing.T) {
+	dir := t.TempDir()
+	data := "\ufeffname,score,,score\nana,\"1,200\",x,3\nben,95%,,4\n"
+	if err := os.WriteFile(filepath.Join(dir, "scores.csv"), []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	result, err := AnalyzeSpreadsheet(map[string]any{"basePath": dir, "path": "scores.csv", "operation": "info"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(result["columns"], []string{"name", "score", "column_3", "score_2"}) {
+		t.Errorf("columns %v", result["columns"])
+	}
+	if types := result["types"].(map[string]string); types["score"] != "number" || types["name"] != "text" {
+		t.Errorf("types %v", types)
+	}
+	result, err = AnalyzeSpreadsheet(map[string]any{"basePath": dir, "path": "scores.csv", "operation": "filter", "where": []any{"score >= 1000"}})
+	if err != nil || result["matches"] != 1 {
+		t.Errorf("filter %v, %v", result, err)
+	}
+	if _, err := AnalyzeSpreadsheet(map[string]any{"basePath": dir, "path": "../scores.csv", "operation": "info"}); err == nil {
+		t.Error("read outside the base path")
+	}
+}
@@ -0,0 +1,344 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	TaskCreateToolName   = "task_create"
+	TaskUpdateToolName   = "task_update"
+	TaskCompleteToolName = "task_complete"
+	TaskListToolName     = "task_list"
+
+	// tasksKey holds the JSON encoded task list in the conversation store
+	tasksKey = "tasks"
+)
+
+// TaskStatus is the progress of a task
+type TaskStatus string
+
+const (
+	TaskPending    TaskStatus = "pending"
+	TaskInProgress TaskStatus = "in_progress"
+	TaskDone       TaskStatus = "done"
+	TaskCancelled  TaskStatus = "cancelled"
+)
+
+// Task is an item on a conversation's task list
+type Task struct {
+	ID          string     `json:"id"`
+	Title       string     `json:"title"`
+	Description string     `json:"description,omitempty"`
+	Status      TaskStatus `json:"status"`
+	Notes       string     `json:"notes,omitempty"`
+	Created     time.Time  `json:"created"`
+	Updated     time.Time  `json:"updated"`
+}
+
+// tasksMu serializes read-modify-write cycles on task lists
+var tasksMu sync.Mutex
+
+var taskTools = map[string]Tool{
+	TaskCreateToolName:   taskCreateTool,
+	TaskUpdateToolName:   taskUpdateTool,
+	TaskCompleteToolName: taskCompleteTool,
+	TaskListToolName:     taskListTool,
+}
+
+var taskCreateTool = Tool{
+	Name:        TaskCreateToolName,
+	Description: "Add a task to the conversation's task list. Plan multi-step work as tasks and keep them updated, the list is kept when the conversation is compacted.",
+	Parameters: []Parameter{
+		{
+			Name:        "title",
+			Type:        "string",
+			Description: "A short description of the task",
+			Required:    true,
+		},
+		{
+			Name:        "description",
+			Type:        "string",
+			Description: "Details needed to complete the task",
+			Required:    false,
+		},
+	},
+	Options: map[string]string{},
+	Run:     TaskCreate,
+}
+
+var taskUpdateTool = Tool{
+	Name:        TaskUpdateToolName,
+	Description: "Update the status, title or notes of a task",
+	Parameters: []Parameter{
+		{
+			Name:        "id",
+			Type:        "string",
+			Description: "The id of the task",
+			Required:    true,
+		},
+		{
+			Name:        "status",
+			Type:        "string",
+			Description: "The new status: pending, in_progress, done or cancelled",
+			Required:    false,
+		},
+		{
+			Name:        "title",
+			Type:        "string",
+			Description: "The new title",
+			Required:    false,
+		},
+		{
+			Name:        "notes",
+			Type:        "string",
+			Description: "Notes about progress, replaces the existing notes",
+			Required:    false,
+		},
+	},
+	Options: map[string]string{},
+	Run:     TaskUpdate,
+}
+
+var taskCompleteTool = Tool{
+	Name:        TaskCompleteToolName,
+	Description: "Mark a task as done",
+	Parameters: []Parameter{
+		{
+			Name:        "id",
+			Type:        "string",
+			Description: "The id of the task",
+			Required:    true,
+		},
+		{
+			Name:        "notes",
+			Type:        "string",
+			Description: "The outcome of the task",
+			Required:    false,
+		},
+	},
+	Options: map[string]string{},
+	Run:     TaskComplete,
+}
+
+var taskListTool = Tool{
+	Name:        TaskListToolName,
+	Description: "List the tasks of the conversation and their status",
+	Parameters: []Parameter{
+		{
+			Name:        "status",
+			Type:        "string",
+			Description: "Only list tasks with this status",
+			Required:    false,
+		},
+	},
+	Options: map[string]string{},
+	Run:     TaskList,
+}
+
+// ListTasks returns the task list of a conversation
+func ListTasks(ctx context.Context, conversationID string) ([]Task, error) {
+	value, ok, err := getConversationStore().Get(ctx, conversationID, tasksKey)
+	if err != nil || !ok {
+		return nil, err
+	}
+	var tasks []Task
+	if err := json.Unmarshal([]byte(value), &tasks); err != nil {
+		return nil, fmt.Errorf("failed to decode tasks: %w", err)
+	}
+	return tasks, nil
+}
+
+func saveTasks(ctx context.Context, conversationID string, tasks []Task) error {
+	data, err := json.Marshal(tasks)
+	if err != nil {
+		return fmt.Errorf("failed to encode tasks: %w", err)
+	}
+	return getConversationStore().Set(ctx, conversationID, tasksKey, string(data))
+}
+
+// updateTasks loads the task list, applies update and saves the result
+func updateTasks(conversationID string, update func([]Task) ([]Task, error)) ([]Task, error) {
+	tasksMu.Lock()
+	defer tasksMu.Unlock()
+	ctx := context.Background()
+	tasks, err := ListTasks(ctx, conversationID)
+	if err != nil {
+		return nil, err
+	}
+	if tasks, err = update(tasks); err != nil {
+		return nil, err
+	}
+	return tasks, saveTasks(ctx, conversationID, tasks)
+}
+
+func conversationArg(args map[string]any) (string, error) {
+	conversationID, ok := args[ConversationIDArg].(string)
+	if !ok || conversationID == "" {
+		return "", fmt.Errorf("tool requires the %s argument", ConversationIDArg)
+	}
+	return conversationID, nil
+}
+
+func TaskCreate(args map[string]any) (map[string]any, error) {
+	conversationID, err := conversationArg(args)
+	if err != nil {
+		return map[string]any{
+			"success": false,
+			"error":   err.Error(),
+		}, err
+	}
+	title, ok := args["title"].(string)
+	if !ok || title == "" {
+		return map[string]any{
+			"success": false,
+			"error":   fmt.Sprintf("expected string: %v", args["title"]),
+		}, fmt.Errorf("expected string: %v", args["title"])
+	}
+	description, _ := args["description"].(string)
+	var task Task
+	_, err = updateTasks(conversationID, func(tasks []Task) ([]Task, error) {
+		now := time.Now().UTC()
+		task = Task{
+			ID:          strconv.Itoa(nextTaskID(tasks)),
+			Title:       title,
+			Description: description,
+			Status:      TaskPending,
+			Created:     now,
+			Updated:     now,
+		}
+		return append(tasks, task), nil
+	})
+	if err != nil {
+		return map[string]any{
+			"success": false,
+			"error":   err.Error(),
+		}, err
+	}
+	return map[string]any{
+		"success": true,
+		"task":    task,
+	}, nil
+}
+
+func TaskUpdate(args map[string]any) (map[string]any, error) {
+	status, _ := args["status"].(string)
+	if status != "" && !validTaskStatus(TaskStatus(status)) {
+		err := fmt.Errorf("invalid status %q, expected pending, in_progress, done or cancelled", status)
+		return map[string]any{
+			"success": false,
+			"error":   err.Error(),
+		}, err
+	}
+	return modifyTask(args, func(task *Task) {
+		if status != "" {
+			task.Status = TaskStatus(status)
+		}
+		if title, ok := args["title"].(string); ok && title != "" {
+			task.Title = title
+		}
+		if notes, ok := args["notes"].(string); ok {
+			task.Notes = notes
+		}
+	})
+}
+
+func TaskComplete(args map[string]any) (map[string]any, error) {
+	return modifyTask(args, func(task *Task) {
+		task.Status = TaskDone
+		if notes, ok := args["notes"].(string); ok && notes != "" {
+			task.Notes = notes
+		}
+	})
+}
+
+func TaskList(args map[string]any) (map[string]any, error) {
+	conversationID, err := conversationArg(args)
+	if err != nil {
+		return map[string]any{
+			"success": false,
+			"error":   err.Error(),
+		}, err
+	}
+	tasks, err := ListTasks(context.Background(), conversationID)
+	if err != nil {
+		return map[string]any{
+			"success": false,
+			"error":   err.Error(),
+		}, err
+	}
+	status, _ := args["status"].(string)
+	listed := []Task{}
+	counts := map[TaskStatus]int{}
+	for _, task := range tasks {
+		counts[task.Status]++
+		if status == "" || task.Status == TaskStatus(status) {
+			listed = append(listed, task)
+		}
+	}
+	return map[string]any{
+		"success": true,
+		"tasks":   listed,
+		"counts":  counts,
+	}, nil
+}
+
+// modifyTask applies change to the task selected by the id argument
+func modifyTask(args map[string]any, change func(*Task)) (map[string]any, error) {
+	conversationID, err := conversationArg(args)
+	if err != nil {
+		return map[string]any{
+			"success": false,
+			"error":   err.Error(),
+		}, err
+	}
+	id := fmt.Sprintf("%v", args["id"])
+	if f, ok := args["id"].(float64); ok {
+		// models sometimes send numeric ids as numbers
+		id = strconv.Itoa(int(f))
+	}
+	var task Task
+	_, err = updateTasks(conversationID, func(tasks []Task) ([]Task, error) {
+		for i := range tasks {
+			if tasks[i].ID == id {
+				change(&tasks[i])
+				tasks[i].Updated = time.Now().UTC()
+				task = tasks[i]
+				return tasks, nil
+			}
+		}
+		return nil, fmt.Errorf("task not found: %s", id)
+	})
+	if err != nil {
+		return map[string]any{
+			"success": false,
+			"error":   err.Error(),
+		}, err
+	}
+	return map[string]any{
+		"success": true,
+		"task":    task,
+	}, nil
+}
+
+func nextTaskID(tasks []Task) int {
+	next := 1
+	for _, task := range tasks {
+		if id, err := strconv.Atoi(task.ID); err == nil && id >= next {
+			next = id + 1
+		}
+	}
+	return next
+}
+
+func validTaskStatus(status TaskStatus) bool {
+	switch status {
+	case TaskPending, TaskInProgress, TaskDone, TaskCancelled:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,38 @@
+// probe is the WASM tool of the tests. It echoes its text argument or, depending on the
+// mode argument, tries to leave the sandbox or exceed its limits.
+package main
+
+import (
+	"encoding/json"
+	"os"
+)
+
+func main() {
+	var args map[string]any
+	if err := json.NewDecoder(os.Stdin).Decode(&args); err != nil {
+		os.Stderr.WriteString(err.Error())
+		os.Exit(1)
+	}
+	result := map[string]any{}
+	switch args["mode"] {
+	case "echo":
+		result["text"] = args["text"]
+		result["host"] = args["basePath"]
+	case "file":
+		_, err := os.ReadFile("/etc/hostname")
+		result["readable"] = err == nil
+	case "env":
+		result["home"] = os.Getenv("HOME")
+	case "alloc":
+		buf := make([]byte, 256<<20)
+		buf[len(buf)-1] = 1
+		result["allocated"] = len(buf)
+	case "spin":
+		for {
+		}
+	case "fail":
+		os.Stderr.WriteString("bad input")
+		os.Exit(2)
+	}
+	json.NewEncoder(os.Stdout).Encode(result)
+}
@@ -0,0 +1,553 @@
+package tools
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	CurrentTimeToolName = "current_time"
+	DateAddToolName     = "date_add"
+	DateDiffToolName    = "date_diff"
+	CronExplainToolName = "cron_explain"
+
+	defaultCronRuns = 5
+	maxCronRuns     = 50
+)
+
+var timeTools = map[string]Tool{
+	CurrentTimeToolName: currentTimeTool,
+	DateAddToolName:     dateAddTool,
+	DateDiffToolName:    dateDiffTool,
+	CronExplainToolName: cronExplainTool,
+}
+
+var timezoneParameter = Parameter{
+	Name:        "timezone",
+	Type:        "string",
+	Description: "An IANA timezone such as \"America/New_York\", defaults to UTC",
+	Required:    false,
+}
+
+var currentTimeTool = Tool{
+	Name:        CurrentTimeToolName,
+	Description: "Get the current date and time. Use this instead of guessing today's date.",
+	Parameters:  []Parameter{timezoneParameter},
+	Options:     map[string]string{},
+	Run:         CurrentTime,
+}
+
+var dateAddTool = Tool{
+	Name:        DateAddToolName,
+	Description: "Add or subtract years, months, days and a duration from a date. Use negative values to subtract.",
+	Parameters: []Parameter{
+		{
+			Name:        "date",
+			Type:        "string",
+			Description: "The start date as RFC 3339 (2006-01-02T15:04:05Z) or YYYY-MM-DD, defaults to now",
+			Required:    false,
+		},
+		{
+			Name:        "years",
+			Type:        "integer",
+			Description: "The number of years to add",
+			Required:    false,
+		},
+		{
+			Name:        "months",
+			Type:        "integer",
+			Description: "The number of months to add",
+			Required:    false,
+		},
+		{
+			Name:        "days",
+			Type:        "integer",
+			Description: "The number of days to add",
+			Required:    false,
+		},
+		{
+			Name:        "duration",
+			Type:        "string",
+			Description: "A duration to add such as \"1h30m\" or \"-45m\"",
+			Required:    false,
+		},
+		timezoneParameter,
+	},
+	Options: map[string]string{},
+	Run:     DateAdd,
+	Examples: []Example{
+		{Description: "The date 90 days after the start of the year", Args: map[string]any{"date": "2025-01-01", "days": 90}},
+		{Description: "The time two and a half hours ago in Tokyo", Args: map[string]any{"duration": "-2h30m", "timezone": "Asia/Tokyo"}},
+	},
+}
+
+var dateDiffTool = Tool{
+	Name:        DateDiffToolName,
+	Description: "Calculate the time between two dates",
+	Parameters: []Parameter{
+		{
+			Name:        "from",
+			Type:        "string",
+			Description: "The first date as RFC 3339 or YYYY-MM-DD, defaults to now",
+			Required:    false,
+		},
+		{
+			Name:        "to",
+			Type:        "string",
+			Description: "The second date as RFC 3339 or YYYY-MM-DD",
+			Required:    true,
+		},
+		timezoneParameter,
+	},
+	Options: map[string]string{},
+	Run:     DateDiff,
+	Examples: []Example{
+		{Description: "The days until the end of the year", Args: map[string]any{"to": "2025-12-31"}},
+	},
+}
+
+var cronExplainTool = Tool{
+	Name:        CronExplainToolName,
+	Description: "Explain a five field cron expression (minute hour day-of-month month day-of-week) or a descriptor such as @daily and list its next run times",
+	Parameters: []Parameter{
+		{
+			Name:        "expression",
+			Type:        "string",
+			Description: "The cron expression",
+			Required:    true,
+		},
+		{
+			Name:        "count",
+			Type:        "integer",
+			Description: "The number of upcoming run times to list, defaults to 5",
+			Required:    false,
+		},
+		timezoneParameter,
+	},
+	Options: map[string]string{},
+	Run:     CronExplain,
+	Examples: []Example{
+		{Description: "The next three runs of a weekday morning job", Args: map[string]any{"expression": "30 9 * * 1-5", "count": 3}},
+	},
+}
+
+func CurrentTime(args map[string]any) (map[string]any, error) {
+	loc, err := timezoneArg(args)
+	if err != nil {
+		return map[string]any{
+			"success": false,
+			"error":   err.Error(),
+		}, err
+	}
+	now := time.Now().In(loc)
+	return map[string]any{
+		"success":  true,
+		"time":     now.Format(time.RFC3339),
+		"date":     now.Format(time.DateOnly),
+		"weekday":  now.Weekday().String(),
+		"timezone": loc.String(),
+		"unix":     now.Unix(),
+	}, nil
+}
+
+func DateAdd(args map[string]any) (map[string]any, error) {
+	loc, err := timezoneArg(args)
+	if err != nil {
+		return map[string]any{
+			"success": false,
+			"error":   err.Error(),
+		}, err
+	}
+	date, err := dateArg(args, "date", loc)
+	if err != nil {
+		return map[string]any{
+			"success": false,
+			"error":   err.Error(),
+		}, err
+	}
+	result := date.AddDate(intArg(args["years"], 0), intArg(args["months"], 0), intArg(args["days"], 0))
+	if d, ok := args["duration"].(string); ok && d != "" {
+		duration, err := time.ParseDuration(d)
+		if err != nil {
+			return map[string]any{
+				"success": false,
+				"error":   fmt.Sprintf("invalid duration: %s", d),
+			}, fmt.Errorf("invalid duration %s: %w", d, err)
+		}
+		result = result.Add(duration)
+	}
+	return map[string]any{
+		"success": true,
+		"date":    result.Format(time.RFC3339),
+		"weekday": result.Weekday().String(),
+	}, nil
+}
+
+func DateDiff(args map[string]any) (map[string]any, error) {
+	loc, err := timezoneArg(args)
+	if err != nil {
+		return map[string]any{
+			"success": false,
+			"error":   err.Error(),
+		}, err
+	}
+	from, err := dateArg(args, "from", loc)
+	if err != nil {
+		return map[string]any{
+			"success": false,
+			"error":   err.Error(),
+		}, err
+	}
+	if _, ok := args["to"].(string); !ok {
+		return map[string]any{
+			"success": false,
+			"error":   fmt.Sprintf("expected string: %v", args["to"]),
+		}, fmt.Errorf("expected string: %v", args["to"])
+	}
+	to, err := dateArg(args, "to", loc)
+	if err != nil {
+		return map[string]any{
+			"success": false,
+			"error":   err.Error(),
+		}, err
+	}
+	diff := to.Sub(from)
+	return map[string]any{
+		"success":  true,
+		"duration": diff.String(),
+		"seconds":  int64(diff.Seconds()),
+		"hours":    diff.Hours(),
+		"days":     diff.Hours() / 24,
+		// calendar days ignore the time of day and daylight saving changes
+		"calendar_days": calendarDays(from, to),
+	}, nil
+}
+
+func CronExplain(args map[string]any) (map[string]any, error) {
+	expression, ok := args["expression"].(string)
+	if !ok {
+		return map[string]any{
+			"success": false,
+			"error":   fmt.Sprintf("expected string: %v", args["expression"]),
+		}, fmt.Errorf("expected string: %v", args["expression"])
+	}
+	loc, err := timezoneArg(args)
+	if err != nil {
+		return map[string]any{
+			"success": false,
+			"error":   err.Error(),
+		}, err
+	}
+	schedule, err := ParseCron(expression)
+	if err != nil {
+		return map[string]any{
+			"success": false,
+			"error":   err.Error(),
+		}, err
+	}
+	count := min(max(intArg(args["count"], defaultCronRuns), 0), maxCronRuns)
+	var runs []string
+	next := time.Now().In(loc)
+	for range count {
+		next, ok = schedule.Next(next)
+		if !ok {
+			break
+		}
+		runs = append(runs, next.Format(time.RFC3339))
+	}
+	return map[string]any{
+		"success":     true,
+		"description": schedule.Describe(),
+		"next_runs":   runs,
+		"timezone":    loc.String(),
+	}, nil
+}
+
+// timezoneArg loads the timezone argument, UTC when it is not set
+func timezoneArg(args map[string]any) (*time.Location, error) {
+	name, _ := args["timezone"].(string)
+	if name == "" {
+		return time.UTC, nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown timezone %s: %w", name, err)
+	}
+	return loc, nil
+}
+
+// dateArg parses a date argument, the current time when it is not set.
+// Dates without an offset are in loc.
+func dateArg(args map[string]any, key string, loc *time.Location) (time.Time, error) {
+	value, _ := args[key].(string)
+	if value == "" {
+		return time.Now().In(loc), nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.In(loc), nil
+	}
+	for _, layout := range []string{time.DateTime, "2006-01-02T15:04:05", time.DateOnly} {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid %s %q, expected RFC 3339 or YYYY-MM-DD", key, value)
+}
+
+func calendarDays(from time.Time, to time.Time) int {
+	start := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	end := time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, time.UTC)
+	return int(end.Sub(start).Hours() / 24)
+}
+
+// CronSchedule is a parsed five field cron expression
+type CronSchedule struct {
+	fields [5]cronField
+}
+
+type cronField struct {
+	expr string
+	// values holds the matching values, indexed by value
+	values []bool
+	// any is true for *, used for the day of month and day of week rule
+	any bool
+}
+
+type cronFieldSpec struct {
+	name  string
+	unit  string
+	min   int
+	max   int
+	names []string
+}
+
+var cronSpecs = [5]cronFieldSpec{
+	{name: "minute", unit: "minute", min: 0, max: 59},
+	{name: "hour", unit: "hour", min: 0, max: 23},
+	{name: "day of month", unit: "day", min: 1, max: 31},
+	{name: "month", unit: "month", min: 1, max: 12, names: []string{"", "JAN", "FEB", "MAR", "APR", "MAY", "JUN", "JUL", "AUG", "SEP", "OCT", "NOV", "DEC"}},
+	{name: "day of week", unit: "day", min: 0, max: 7, names: []string{"SUN", "MON", "TUE", "WED", "THU", "FRI", "SAT", "SUN"}},
+}
+
+var cronDescriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// ParseCron parses a five field cron expression or one of the @ descriptors
+func ParseCron(expression string) (*CronSchedule, error) {
+	expr := strings.TrimSpace(expression)
+	if descriptor, ok := cronDescriptors[strings.ToLower(expr)]; ok {
+		expr = descriptor
+	}
+	parts := strings.Fields(expr)
+	if len(parts) != 5 {
+		return nil, fmt.Errorf("invalid cron expression %q: expected 5 fields, got %d", expression, len(parts))
+	}
+	schedule := &CronSchedule{}
+	for i, part := range parts {
+		field, err := parseCronField(part, cronSpecs[i])
+		if err != nil {
+			return nil, fmt.Errorf("invalid cron expression %q: %w", expression, err)
+		}
+		schedule.fields[i] = field
+	}
+	// 7 is an alias for sunday
+	if schedule.fields[4].values[7] {
+		schedule.fields[4].values[0] = true
+	}
+	return schedule, nil
+}
+
+func parseCronField(expr string, spec cronFieldSpec) (cronField, error) {
+	field := cronField{expr: expr, values: make([]bool, spec.max+1), any: expr == "*" || expr == "?"}
+	for _, item := range strings.Split(expr, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(item, "/")
+		step := 1
+		if hasStep {
+			s, err := strconv.Atoi(stepPart)
+			if err != nil || s <= 0 {
+				return field, fmt.Errorf("invalid step %q in %s field", stepPart, spec.name)
+			}
+			step = s
+		}
+		start, end := spec.min, spec.max
+		if rangePart != "*" && rangePart != "?" {
+			low, high, isRange := strings.Cut(rangePart, "-")
+			var err error
+			if start, err = cronValue(low, spec); err != nil {
+				return field, err
+			}
+			end = start
+			if isRange {
+				if end, err = cronValue(high, spec); err != nil {
+					return field, err
+				}
+			} else if hasStep {
+				end = spec.max
+			}
+			if end < start {
+				return field, fmt.Errorf("invalid range %q in %s field", rangePart, spec.name)
+			}
+		}
+		for v := start; v <= end; v += step {
+			field.values[v] = true
+		}
+	}
+	return field, nil
+}
+
+func cronValue(value string, spec cronFieldSpec) (int, error) {
+	for i, name := range spec.names {
+		if name != "" && strings.EqualFold(value, name) {
+			return i, nil
+		}
+	}
+	v, err := strconv.Atoi(value)
+	if err != nil || v < spec.min || v > spec.max {
+		return 0, fmt.Errorf("invalid value %q in %s field, expected %d-%d", value, spec.name, spec.min, spec.max)
+	}
+	return v, nil
+}
+
+// matchesDay applies the cron rule that a day matches either the day of month or
+// the day of week when both are restricted
+func (s *CronSchedule) matchesDay(t time.Time) bool {
+	dom := s.fields[2].values[t.Day()]
+	dow := s.fields[4].values[int(t.Weekday())]
+	if s.fields[2].any || s.fields[4].any {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+// Next returns the first run time after t in the location of t. It reports false when
+// the expression never matches, e.g. 0 0 30 2 *. Run times that do not exist on the day
+// clocks are set forward are skipped, and schedules with fixed hours run once in the
+// hour repeated when clocks are set back.
+func (s *CronSchedule) Next(t time.Time) (time.Time, bool) {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	// every schedule repeats within a leap year cycle
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		if !s.fields[3].values[int(t.Month())] {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.matchesDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.fields[1].values[t.Hour()] || (!s.fields[1].any && repeatedHour(t)) {
+			next := time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			if !next.After(t) {
+				// the next hour on the clock is the repeated one
+				next = t.Truncate(time.Hour).Add(time.Hour)
+			}
+			t = next
+			continue
+		}
+		if !s.fields[0].values[t.Minute()] {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t, true
+	}
+	return time.Time{}, false
+}
+
+// repeatedHour reports whether t is in the second pass of an hour repeated when clocks
+// are set back
+func repeatedHour(t time.Time) bool {
+	earlier := t.Add(-time.Hour)
+	return earlier.Hour() == t.Hour() && earlier.Day() == t.Day()
+}
+
+// Describe returns an English description of the schedule
+func (s *CronSchedule) Describe() string {
+	minute, hour := s.fields[0], s.fields[1]
+	var sb strings.Builder
+	if m, ok := single(minute); ok {
+		if h, ok := single(hour); ok {
+			fmt.Fprintf(&sb, "At %02d:%02d", h, m)
+		} else {
+			fmt.Fprintf(&sb, "At minute %d past %s", m, describeCronField(hour, cronSpecs[1]))
+		}
+	} else {
+		sb.WriteString("At " + describeCronField(minute, cronSpecs[0]))
+		if !hour.any {
+			sb.WriteString(" past " + describeCronField(hour, cronSpecs[1]))
+		}
+	}
+	if !s.fields[2].any {
+		sb.WriteString(" on " + describeCronField(s.fields[2], cronSpecs[2]) + " of the month")
+	}
+	if !s.fields[4].any {
+		if !s.fields[2].any {
+			sb.WriteString(" and")
+		}
+		sb.WriteString(" on " + describeCronField(s.fields[4], cronSpecs[4]))
+	}
+	if !s.fields[3].any {
+		sb.WriteString(" in " + describeCronField(s.fields[3], cronSpecs[3]))
+	}
+	return sb.String()
+}
+
+// single returns the value of a field that matches exactly one value
+func single(field cronField) (int, bool) {
+	value, count := 0, 0
+	for v, ok := range field.values {
+		if ok {
+			value = v
+			count++
+		}
+	}
+	return value, count == 1
+}
+
+func describeCronField(field cronField, spec cronFieldSpec) string {
+	if field.any {
+		return "every " + spec.unit
+	}
+	if rest, ok := strings.CutPrefix(field.expr, "*/"); ok {
+		return fmt.Sprintf("every %s %ss", rest, spec.unit)
+	}
+	var items []string
+	for _, item := range strings.Split(field.expr, ",") {
+		rangePart, step, hasStep := strings.Cut(item, "/")
+		low, high, isRange := strings.Cut(rangePart, "-")
+		text := cronName(low, spec)
+		if isRange {
+			text = cronName(low, spec) + " through " + cronName(high, spec)
+		}
+		if hasStep {
+			text = fmt.Sprintf("every %s %ss from %s", step, spec.unit, text)
+		}
+		items = append(items, text)
+	}
+	prefix := spec.unit + " "
+	if len(spec.names) > 0 {
+		prefix = ""
+	}
+	return prefix + strings.Join(items, ", ")
+}
+
+// cronName returns the display name of a month or weekday value
+func cronName(value string, spec cronFieldSpec) string {
+	v, err := cronValue(value, spec)
+	if err != nil || len(spec.names) == 0 {
+		return value
+	}
+	if spec.name == "month" {
+		return time.Month(v).String()
+	}
+	return time.Weekday(v % 7).String()
+}
@@ -0,0 +1,76 @@
+package tools
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCronSchedule(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("time zone data is not available: %v", err)
+	}
+	// 2024-01-15 is a Monday
+	monday := time.Date(2024, 1, 15, 10, 7, 0, 0, time.UTC)
+	tests := []struct {
+		expression string
+		from       time.Time
+		next       string
+		describe   string
+	}{
+		{"0 0 30 2 *", monday, "", "At 00:00 on day 30 of the month in February"},
+		{"*/15 9-17 * * MON-FRI", monday, "2024-01-15T10:15:00Z", "At every 15 minutes past hour 9 through 17 on Monday through Friday"},
+		{"*/15 9-17 * * MON-FRI", time.Date(2024, 1, 19, 17, 50, 0, 0, time.UTC), "2024-01-22T09:00:00Z", ""},
+		// a day matches the day of month or the day of week when both are restricted
+		{"0 12 1,15 * 7", monday, "2024-01-15T12:00:00Z", "At 12:00 on day 1, 15 of the month and on Sunday"},
+		{"0 12 1,15 * 7", time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC), "2024-01-21T12:00:00Z", ""},
+		{"0 0 13 * FRI", monday, "2024-01-19T00:00:00Z", "At 00:00 on day 13 of the month and on Friday"},
+		{"5 4 * * sun", monday, "2024-01-21T04:05:00Z", "At 04:05 on Sunday"},
+		{"0 */6 * jan-mar *", monday, "2024-01-15T12:00:00Z", "At minute 0 past every 6 hours in January through March"},
+		{"0 0 29 2 *", time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), "2028-02-29T00:00:00Z", ""},
+		{"@weekly", monday, "2024-01-21T00:00:00Z", "At 00:00 on Sunday"},
+		{"@hourly", monday, "2024-01-15T11:00:00Z", "At minute 0 past every hour"},
+		{"@yearly", time.Date(2024, 12, 31, 23, 59, 0, 0, time.UTC), "2025-01-01T00:00:00Z", ""},
+		// 02:30 does not exist on 2024-03-10 in New York
+		{"30 2 * * *", time.Date(2024, 3, 10, 0, 0, 0, 0, newYork), "2024-03-11T02:30:00-04:00", "At 02:30"},
+		// 01:30 happens twice on 2024-11-03, a fixed time runs once
+		{"30 1 * * *", time.Date(2024, 11, 3, 1, 30, 0, 0, newYork), "2024-11-04T01:30:00-05:00", ""},
+		{"*/30 * * * *", time.Date(2024, 11, 3, 1, 45, 0, 0, newYork), "2024-11-03T01:00:00-05:00", ""},
+	}
+	for _, test := range tests {
+		schedule, err := ParseCron(test.expression)
+		if err != nil {
+			t.Errorf("%s: %v", test.expression, err)
+			continue
+		}
+		next, ok := schedule.Next(test.from)
+		switch {
+		case test.next == "" && ok:
+			t.Errorf("%s: next %s, want no match", test.expression, next)
+		case test.next != "" && !ok:
+			t.Errorf("%s: no match, want %s", test.expression, test.next)
+		case ok && next.Format(time.RFC3339) != test.next:
+			t.Errorf("%s after %s: next %s, want %s", test.expression, test.from, next.Format(time.RFC3339), test.next)
+		}
+		if test.describe != "" && schedule.Describe() != test.describe {
+			t.Errorf("%s: described as %q, want %q", test.expression, schedule.Describe(), test.describe)
+		}
+	}
+}
+
+func TestParseCronErrors(t *testing.T) {
+	for _, expression := range []string{
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"* * * * MON-XYZ",
+		"@often",
+	} {
+		if _, err := ParseCron(expression); err == nil {
+			t.Errorf("%s: expected an error", expression)
+		}
+	}
+}
@@ -1,14 +1,40 @@
 package tools
 
 import (
+	"errors"
 	"fmt"
+	"sync"
 
-	"github.com/google/generative-ai-go/genai"
 	ollama "github.com/ollama/ollama/api"
+	"google.golang.org/genai"
 )
 
 const DEBUG = false
 
+// ErrToolNotFound is returned for tools that are not registered
+var ErrToolNotFound = errors.New("tool does not exist")
+
+// ToolError is returned by tools to tell the model how to recover from a failure
+type ToolError struct {
+	Err error
+	// Hint is a suggestion for the model, e.g. which argument to fix
+	Hint string
+	// Retryable is false when calling the tool again will fail the same way
+	Retryable bool
+}
+
+func NewToolError(err error, hint string, retryable bool) *ToolError {
+	return &ToolError{Err: err, Hint: hint, Retryable: retryable}
+}
+
+func (e *ToolError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ToolError) Unwrap() error {
+	return e.Err
+}
+
 type Tool struct {
 	Name        string
 	Description string
@@ -16,6 +42,16 @@ type Tool struct {
 	Options     map[string]string
 	Run         func(map[string]any) (map[string]any, error)
 	Summarize   bool
+	// Paginated tools accept a cursor argument, see pagination.go
+	Paginated bool
+	// Examples are shown in the tool documentation, see Docs
+	Examples []Example
+}
+
+// Example is a documented call of a tool
+type Example struct {
+	Description string         `json:"description"`
+	Args        map[string]any `json:"args"`
 }
 
 type RunnableTool struct {
@@ -24,13 +60,16 @@ type RunnableTool struct {
 }
 
 type Parameter struct {
-	Name        string
-	Type        string
-	Description string
-	Required    bool
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Description string `json:"description"`
+	Required    bool   `json:"required"`
 }
 
-var toolMap = mergeTools(fileTools, githubTools, gitTools, searchTools, memoryTools)
+var toolMap = mergeTools(fileTools, githubTools, gitTools, searchTools, memoryTools, ingestTools, timeTools, calculatorTools, documentTools, scratchpadTools, taskTools, weatherTools, objectStorageTools, emailTools, notifyTools, jiraTools, linearTools, kubernetesTools, prometheusTools, calendarTools, spreadsheetTools)
+
+// registryMu guards toolMap once tools can be registered at runtime
+var registryMu sync.RWMutex
 
 func mergeTools(tools ...map[string]Tool) map[string]Tool {
 	keys := make(map[string]bool)
@@ -41,16 +80,18 @@ func mergeTools(tools ...map[string]Tool) map[string]Tool {
 				panic(fmt.Sprintf("duplicate tool name: %s", key))
 			}
 			keys[key] = true
-			merged[key] = value
+			merged[key] = withPagination(value)
 		}
 	}
 	return merged
 }
 
 func GetTool(toolName string) (*Tool, error) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
 	tool, ok := toolMap[toolName]
 	if !ok {
-		return nil, fmt.Errorf("tool %s does not exist", toolName)
+		return nil, fmt.Errorf("tool %s: %w", toolName, ErrToolNotFound)
 	}
 	return &tool, nil
 }
@@ -68,9 +109,37 @@ func GetTools(toolNames []string) ([]*Tool, error) {
 }
 
 func Tools() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
 	tools := make([]string, 0, len(toolMap))
 	for toolName := range toolMap {
 		tools = append(tools, toolName)
 	}
 	return tools
 }
+
+// RegisterTool adds a tool to the registry, replacing any tool with the same name
+func RegisterTool(tool Tool) {
+	registryMu.Lock()
+	toolMap[tool.Name] = withPagination(tool)
+	registryMu.Unlock()
+	invalidateSchemas(tool.Name)
+	notifyToolChange(ToolChange{Tool: tool.Name, Kind: ToolRegistered, Options: tool.Options})
+}
+
+// UnregisterTool removes a tool from the registry
+func UnregisterTool(toolName string) {
+	registryMu.Lock()
+	delete(toolMap, toolName)
+	registryMu.Unlock()
+	invalidateSchemas(toolName)
+	notifyToolChange(ToolChange{Tool: toolName, Kind: ToolUnregistered})
+}
+
+// lookupTool returns a registered tool by name
+func lookupTool(toolName string) (Tool, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	tool, ok := toolMap[toolName]
+	return tool, ok
+}
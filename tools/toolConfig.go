@@ -0,0 +1,176 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"sync"
+	"time"
+)
+
+// ToolChangeKind is the kind of change made to the tool registry
+type ToolChangeKind string
+
+const (
+	ToolRegistered     ToolChangeKind = "registered"
+	ToolUnregistered   ToolChangeKind = "unregistered"
+	ToolOptionsChanged ToolChangeKind = "options"
+)
+
+// ToolChange is passed to the listeners added with OnToolChange
+type ToolChange struct {
+	Tool string
+	Kind ToolChangeKind
+	// Options of the tool after the change, nil when it was unregistered
+	Options map[string]string
+}
+
+var toolListeners = struct {
+	sync.Mutex
+	next  int
+	funcs map[int]func(ToolChange)
+}{funcs: make(map[int]func(ToolChange))}
+
+// OnToolChange calls fn after a tool is registered, unregistered or its options change.
+// The returned function removes the listener.
+func OnToolChange(fn func(ToolChange)) (remove func()) {
+	toolListeners.Lock()
+	defer toolListeners.Unlock()
+	id := toolListeners.next
+	toolListeners.next++
+	toolListeners.funcs[id] = fn
+	return func() {
+		toolListeners.Lock()
+		defer toolListeners.Unlock()
+		delete(toolListeners.funcs, id)
+	}
+}
+
+func notifyToolChange(change ToolChange) {
+	toolListeners.Lock()
+	funcs := make([]func(ToolChange), 0, len(toolListeners.funcs))
+	for _, fn := range toolListeners.funcs {
+		funcs = append(funcs, fn)
+	}
+	toolListeners.Unlock()
+	for _, fn := range funcs {
+		fn(change)
+	}
+}
+
+// SetToolOptions replaces the options of a registered tool. Options are read when the
+// tool runs, so running chats use the new values from their next tool call.
+func SetToolOptions(toolName string, options map[string]string) error {
+	return ApplyToolOptions(map[string]map[string]string{toolName: options})
+}
+
+// UpdateToolOptions merges options into the options of a registered tool, an empty
+// value removes the option
+func UpdateToolOptions(toolName string, options map[string]string) error {
+	registryMu.Lock()
+	tool, ok := toolMap[toolName]
+	if !ok {
+		registryMu.Unlock()
+		return fmt.Errorf("tool %s: %w", toolName, ErrToolNotFound)
+	}
+	merged := maps.Clone(tool.Options)
+	if merged == nil {
+		merged = make(map[string]string)
+	}
+	for key, value := range options {
+		if value == "" {
+			delete(merged, key)
+		} else {
+			merged[key] = value
+		}
+	}
+	tool.Options = merged
+	toolMap[toolName] = tool
+	registryMu.Unlock()
+	notifyToolChange(ToolChange{Tool: toolName, Kind: ToolOptionsChanged, Options: maps.Clone(merged)})
+	return nil
+}
+
+// ApplyToolOptions replaces the options of several tools at once, keyed by tool name.
+// No tool is changed when one of them is not registered.
+func ApplyToolOptions(config map[string]map[string]string) error {
+	registryMu.Lock()
+	for toolName := range config {
+		if _, ok := toolMap[toolName]; !ok {
+			registryMu.Unlock()
+			return fmt.Errorf("tool %s: %w", toolName, ErrToolNotFound)
+		}
+	}
+	var changes []ToolChange
+	for toolName, options := range config {
+		tool := toolMap[toolName]
+		if maps.Equal(tool.Options, options) {
+			continue
+		}
+		// tools returned by GetTool share the old map, so it is replaced rather than modified
+		tool.Options = maps.Clone(options)
+		toolMap[toolName] = tool
+		changes = append(changes, ToolChange{Tool: toolName, Kind: ToolOptionsChanged, Options: maps.Clone(options)})
+	}
+	registryMu.Unlock()
+	for _, change := range changes {
+		notifyToolChange(change)
+	}
+	return nil
+}
+
+// LoadToolOptions applies a JSON file mapping tool names to their options, e.g.
+// {"readFile": {"basePath": "/srv/data"}}
+func LoadToolOptions(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read tool options: %w", err)
+	}
+	var config map[string]map[string]string
+	if err := json.Unmarshal(data, &config); err != nil {
+		return fmt.Errorf("failed to parse tool options %s: %w", path, err)
+	}
+	return ApplyToolOptions(config)
+}
+
+// WatchToolOptions loads the tool options file and reloads it whenever it is modified,
+// checking every interval until ctx is canceled. Reload errors are passed to onError
+// and the previous options are kept.
+func WatchToolOptions(ctx context.Context, path string, interval time.Duration, onError func(error)) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("failed to read tool options: %w", err)
+	}
+	if err := LoadToolOptions(path); err != nil {
+		return err
+	}
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	modified, size := info.ModTime(), info.Size()
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			info, err := os.Stat(path)
+			if err == nil && info.ModTime().Equal(modified) && info.Size() == size {
+				continue
+			}
+			if err == nil {
+				modified, size = info.ModTime(), info.Size()
+				err = LoadToolOptions(path)
+			}
+			if err != nil && onError != nil {
+				onError(err)
+			}
+		}
+	}()
+	return nil
+}
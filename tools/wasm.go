@@ -0,0 +1,130 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// WASMManifest describes a tool implemented by a WASI module. The module is run once per
+// call with the tool arguments as a JSON object on stdin and writes its result, a JSON
+// object, to stdout. It has no access to the filesystem, network or environment.
+type WASMManifest struct {
+	Name        string      `json:"name"`
+	Description string      `json:"description"`
+	Parameters  []Parameter `json:"parameters"`
+	// Module is the path of the .wasm file, relative to the manifest
+	Module string `json:"module"`
+	// TimeoutSeconds limits each call, 30 seconds by default
+	TimeoutSeconds int `json:"timeoutSeconds"`
+	// MemoryLimitMB limits the module's memory, 64MB by default
+	MemoryLimitMB int `json:"memoryLimitMB"`
+}
+
+const (
+	defaultWASMTimeout     = 30 * time.Second
+	defaultWASMMemoryLimit = 64
+	// wasmOutputLimit bounds what a module can write to stdout and stderr
+	wasmOutputLimit = 1 << 20
+)
+
+// wasmModule is a compiled module ready to be run
+type wasmModule interface {
+	// run executes the module with input on stdin and returns its stdout
+	run(ctx context.Context, input []byte) ([]byte, error)
+}
+
+// LoadWASMTool reads a manifest and compiles its module into a tool
+func LoadWASMTool(manifestPath string) (Tool, error) {
+	data, err := os.ReadFile(manifestPath)
+	if err != nil {
+		return Tool{}, fmt.Errorf("failed to read manifest: %w", err)
+	}
+	var manifest WASMManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return Tool{}, fmt.Errorf("failed to parse manifest %s: %w", manifestPath, err)
+	}
+	if manifest.Name == "" || manifest.Module == "" {
+		return Tool{}, fmt.Errorf("manifest %s must have a name and a module", manifestPath)
+	}
+	modulePath := manifest.Module
+	if !filepath.IsAbs(modulePath) {
+		modulePath = filepath.Join(filepath.Dir(manifestPath), modulePath)
+	}
+	wasm, err := os.ReadFile(modulePath)
+	if err != nil {
+		return Tool{}, fmt.Errorf("failed to read module of %s: %w", manifest.Name, err)
+	}
+	memoryLimit := manifest.MemoryLimitMB
+	if memoryLimit <= 0 {
+		memoryLimit = defaultWASMMemoryLimit
+	}
+	module, err := compileWASM(wasm, memoryLimit)
+	if err != nil {
+		return Tool{}, fmt.Errorf("failed to compile module of %s: %w", manifest.Name, err)
+	}
+	timeout := defaultWASMTimeout
+	if manifest.TimeoutSeconds > 0 {
+		timeout = time.Duration(manifest.TimeoutSeconds) * time.Second
+	}
+	return Tool{
+		Name:        manifest.Name,
+		Description: manifest.Description,
+		Parameters:  manifest.Parameters,
+		Run: func(args map[string]any) (map[string]any, error) {
+			return runWASMTool(module, manifest.Parameters, timeout, args)
+		},
+	}, nil
+}
+
+// RegisterWASMTools registers a tool for each *.json manifest in dir and returns their names
+func RegisterWASMTools(dir string) ([]string, error) {
+	manifests, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, manifest := range manifests {
+		tool, err := LoadWASMTool(manifest)
+		if err != nil {
+			return names, err
+		}
+		RegisterTool(tool)
+		names = append(names, tool.Name)
+	}
+	return names, nil
+}
+
+func runWASMTool(module wasmModule, parameters []Parameter, timeout time.Duration, args map[string]any) (map[string]any, error) {
+	// only declared parameters are passed, framework arguments such as the base path and
+	// credential scope stay on the host
+	input := make(map[string]any, len(parameters))
+	for _, parameter := range parameters {
+		if value, ok := args[parameter.Name]; ok {
+			input[parameter.Name] = value
+		}
+	}
+	data, err := json.Marshal(input)
+	if err != nil {
+		return map[string]any{"success": false, "error": err.Error()}, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	output, err := module.run(ctx, data)
+	if ctx.Err() != nil {
+		err = NewToolError(fmt.Errorf("tool timed out after %s", timeout), "", false)
+	}
+	if err != nil {
+		return map[string]any{"success": false, "error": err.Error()}, err
+	}
+	var result map[string]any
+	if err := json.Unmarshal(output, &result); err != nil {
+		// modules that print text rather than JSON
+		return map[string]any{"success": true, "output": strings.TrimSpace(string(output))}, nil
+	}
+	return result, nil
+}
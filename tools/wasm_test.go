@@ -0,0 +1,133 @@
+package tools
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// buildProbe compiles testdata/wasm/probe into dir
+func buildProbe(t *testing.T, dir string) {
+	t.Helper()
+	goTool, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("the go tool is needed to build the test module")
+	}
+	cmd := exec.Command(goTool, "build", "-o", filepath.Join(dir, "probe.wasm"), "./testdata/wasm/probe")
+	cmd.Env = append(os.Environ(), "GOOS=wasip1", "GOARCH=wasm")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("failed to build the test module: %v\n%s", err, output)
+	}
+}
+
+func writeManifest(t *testing.T, dir string, manifest WASMManifest) string {
+	t.Helper()
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, manifest.Name+".json")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestWASMTool(t *testing.T) {
+	dir := t.TempDir()
+	buildProbe(t, dir)
+	parameters := []Parameter{
+		{Name: "mode", Type: "string", Description: "What to do", Required: true},
+		{Name: "text", Type: "string", Description: "Text to echo"},
+	}
+	writeManifest(t, dir, WASMManifest{Name: "probe", Description: "Test module", Module: "probe.wasm", Parameters: parameters})
+	writeManifest(t, dir, WASMManifest{Name: "probe_limited", Description: "Test module with tight limits", Module: "probe.wasm",
+		Parameters: parameters, TimeoutSeconds: 1, MemoryLimitMB: 32})
+
+	names, err := RegisterWASMTools(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		for _, name := range names {
+			UnregisterTool(name)
+		}
+	})
+	if strings.Join(names, ",") != "probe,probe_limited" {
+		t.Fatalf("registered %v", names)
+	}
+	probe, err := GetTool("probe")
+	if err != nil {
+		t.Fatal(err)
+	}
+	limited, err := GetTool("probe_limited")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		tool    *Tool
+		args    map[string]any
+		want    map[string]any
+		wantErr string
+	}{
+		// undeclared arguments such as the base path stay on the host
+		{name: "echo", tool: probe, args: map[string]any{"mode": "echo", "text": "hello", "basePath": "/srv"}, want: map[string]any{"text": "hello", "host": nil}},
+		{name: "no filesystem", tool: probe, args: map[string]any{"mode": "file"}, want: map[string]any{"readable": false}},
+		{name: "no environment", tool: probe, args: map[string]any{"mode": "env"}, want: map[string]any{"home": ""}},
+		{name: "exit code", tool: probe, args: map[string]any{"mode": "fail"}, wantErr: "bad input"},
+		{name: "memory limit", tool: limited, args: map[string]any{"mode": "alloc"}, wantErr: "exit_code(2)"},
+		{name: "timeout", tool: limited, args: map[string]any{"mode": "spin"}, wantErr: "timed out after 1s"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := tt.tool.Run(tt.args)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error %v, want %q", err, tt.wantErr)
+				}
+				if result["success"] != false {
+					t.Errorf("result %v, want success false", result)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			for key, want := range tt.want {
+				if got := result[key]; got != want {
+					t.Errorf("%s = %v, want %v", key, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadWASMToolErrors(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name     string
+		manifest string
+		wantErr  string
+	}{
+		{name: "invalid json", manifest: "{", wantErr: "failed to parse manifest"},
+		{name: "no module", manifest: `{"name":"broken"}`, wantErr: "must have a name and a module"},
+		{name: "missing module", manifest: `{"name":"broken","module":"missing.wasm"}`, wantErr: "failed to read module"},
+		{name: "not wasm", manifest: `{"name":"broken","module":"broken.json"}`, wantErr: "failed to compile module"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, "broken.json")
+			if err := os.WriteFile(path, []byte(tt.manifest), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := LoadWASMTool(path); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
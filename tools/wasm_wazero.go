@@ -0,0 +1,65 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+	"github.com/tetratelabs/wazero/sys"
+)
+
+type wazeroModule struct {
+	runtime  wazero.Runtime
+	compiled wazero.CompiledModule
+}
+
+// compileWASM compiles a module with a memory limit in MB
+func compileWASM(wasm []byte, memoryLimitMB int) (wasmModule, error) {
+	ctx := context.Background()
+	config := wazero.NewRuntimeConfig().
+		// a page is 64KiB
+		WithMemoryLimitPages(uint32(memoryLimitMB * 16)).
+		WithCloseOnContextDone(true)
+	runtime := wazero.NewRuntimeWithConfig(ctx, config)
+	if _, err := wasi_snapshot_preview1.Instantiate(ctx, runtime); err != nil {
+		runtime.Close(ctx)
+		return nil, err
+	}
+	compiled, err := runtime.CompileModule(ctx, wasm)
+	if err != nil {
+		runtime.Close(ctx)
+		return nil, err
+	}
+	return &wazeroModule{runtime: runtime, compiled: compiled}, nil
+}
+
+func (w *wazeroModule) run(ctx context.Context, input []byte) ([]byte, error) {
+	stdout := &limitedBuffer{limit: wasmOutputLimit}
+	stderr := &limitedBuffer{limit: wasmOutputLimit}
+	// no filesystem, environment or network is configured, anonymous instances let
+	// calls run concurrently
+	config := wazero.NewModuleConfig().
+		WithName("").
+		WithStdin(bytes.NewReader(input)).
+		WithStdout(stdout).
+		WithStderr(stderr)
+	module, err := w.runtime.InstantiateModule(ctx, w.compiled, config)
+	if module != nil {
+		defer module.Close(ctx)
+	}
+	var exitErr *sys.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 0 {
+		err = nil
+	}
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%w: %s", err, msg)
+		}
+		return nil, err
+	}
+	return stdout.Bytes(), nil
+}
@@ -0,0 +1,423 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	GeocodeToolName = "geocode"
+	WeatherToolName = "weather_forecast"
+
+	geocodePageSize = 5
+	weatherPageSize = 7
+	maxForecastDays = 16
+)
+
+// Place is a geocoding result
+type Place struct {
+	Name      string  `json:"name"`
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	Type      string  `json:"type,omitempty"`
+}
+
+// Forecast is the current weather and daily forecast at a location
+type Forecast struct {
+	Timezone string          `json:"timezone"`
+	Units    string          `json:"units"`
+	Current  CurrentWeather  `json:"current"`
+	Daily    []DailyForecast `json:"daily"`
+}
+
+type CurrentWeather struct {
+	Time        string  `json:"time"`
+	Temperature float64 `json:"temperature"`
+	Humidity    float64 `json:"humidity"`
+	WindSpeed   float64 `json:"wind_speed"`
+	Conditions  string  `json:"conditions"`
+}
+
+type DailyForecast struct {
+	Date          string  `json:"date"`
+	TemperatureHi float64 `json:"temperature_max"`
+	TemperatureLo float64 `json:"temperature_min"`
+	Precipitation float64 `json:"precipitation"`
+	WindSpeedMax  float64 `json:"wind_speed_max"`
+	Conditions    string  `json:"conditions"`
+}
+
+// WeatherBackend looks up places and forecasts, the default uses Nominatim and
+// Open-Meteo which do not require an API key
+type WeatherBackend interface {
+	Geocode(ctx context.Context, query string, limit int) ([]Place, error)
+	Forecast(ctx context.Context, latitude float64, longitude float64, days int, imperial bool) (*Forecast, error)
+}
+
+var (
+	weatherBackend   WeatherBackend = NewOpenMeteoBackend()
+	weatherBackendMu sync.RWMutex
+)
+
+// SetWeatherBackend replaces the backend used by the weather and geocode tools
+func SetWeatherBackend(backend WeatherBackend) {
+	weatherBackendMu.Lock()
+	defer weatherBackendMu.Unlock()
+	weatherBackend = backend
+}
+
+func getWeatherBackend() WeatherBackend {
+	weatherBackendMu.RLock()
+	defer weatherBackendMu.RUnlock()
+	return weatherBackend
+}
+
+var weatherTools = map[string]Tool{
+	GeocodeToolName: geocodeTool,
+	WeatherToolName: weatherTool,
+}
+
+var geocodeTool = Tool{
+	Name:        GeocodeToolName,
+	Description: "Find the coordinates of a place, e.g. a city, address or landmark",
+	Parameters: []Parameter{
+		{
+			Name:        "query",
+			Type:        "string",
+			Description: "The place to search for",
+			Required:    true,
+		},
+	},
+	Options:   map[string]string{},
+	Run:       Geocode,
+	Paginated: true,
+}
+
+var weatherTool = Tool{
+	Name:        WeatherToolName,
+	Description: "Get the current weather and the daily forecast at a location. Pass a place name or coordinates.",
+	Parameters: []Parameter{
+		{
+			Name:        "location",
+			Type:        "string",
+			Description: "A place name, used when latitude and longitude are not given",
+			Required:    false,
+		},
+		{
+			Name:        "latitude",
+			Type:        "number",
+			Description: "The latitude of the location",
+			Required:    false,
+		},
+		{
+			Name:        "longitude",
+			Type:        "number",
+			Description: "The longitude of the location",
+			Required:    false,
+		},
+		{
+			Name:        "days",
+			Type:        "integer",
+			Description: "The number of forecast days, 1 to 16, defaults to 7",
+			Required:    false,
+		},
+		{
+			Name:        "units",
+			Type:        "string",
+			Description: "metric or imperial, defaults to metric",
+			Required:    false,
+		},
+	},
+	Options:   map[string]string{},
+	Run:       Weather,
+	Paginated: true,
+}
+
+type geocodeArgs struct {
+	Query string `json:"query"`
+}
+
+type weatherArgs struct {
+	Location  string   `json:"location"`
+	Latitude  *float64 `json:"latitude"`
+	Longitude *float64 `json:"longitude"`
+	Days      int      `json:"days"`
+	Units     string   `json:"units"`
+}
+
+func Geocode(args map[string]any) (map[string]any, error) {
+	typed, err := DecodeArgs[geocodeArgs](args)
+	if err == nil && typed.Query == "" {
+		err = fmt.Errorf("query is required")
+	}
+	if err != nil {
+		return map[string]any{
+			"success": false,
+			"error":   err.Error(),
+		}, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	places, err := getWeatherBackend().Geocode(ctx, typed.Query, 20)
+	if err != nil {
+		return map[string]any{
+			"success": false,
+			"error":   err.Error(),
+		}, err
+	}
+	page, next, err := Paginate(places, args, geocodePageSize)
+	if err != nil {
+		return map[string]any{
+			"success": false,
+			"error":   err.Error(),
+		}, err
+	}
+	return setNextCursor(map[string]any{
+		"success": true,
+		"places":  page,
+	}, next), nil
+}
+
+func Weather(args map[string]any) (map[string]any, error) {
+	typed, err := DecodeArgs[weatherArgs](args)
+	if err == nil && typed.Units != "" && typed.Units != "metric" && typed.Units != "imperial" {
+		err = NewToolError(fmt.Errorf("invalid units: %s", typed.Units), "Use metric or imperial.", true)
+	}
+	if err != nil {
+		return map[string]any{
+			"success": false,
+			"error":   err.Error(),
+		}, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	backend := getWeatherBackend()
+
+	result := map[string]any{"success": true}
+	var latitude, longitude float64
+	switch {
+	case typed.Latitude != nil && typed.Longitude != nil:
+		latitude, longitude = *typed.Latitude, *typed.Longitude
+	case typed.Location != "":
+		places, err := backend.Geocode(ctx, typed.Location, 1)
+		if err == nil && len(places) == 0 {
+			err = NewToolError(fmt.Errorf("location not found: %s", typed.Location), "Try a more general place name or pass coordinates.", false)
+		}
+		if err != nil {
+			return map[string]any{
+				"success": false,
+				"error":   err.Error(),
+			}, err
+		}
+		latitude, longitude = places[0].Latitude, places[0].Longitude
+		result["location"] = places[0]
+	default:
+		err := NewToolError(fmt.Errorf("location or latitude and longitude are required"), "Pass a place name as location.", true)
+		return map[string]any{
+			"success": false,
+			"error":   err.Error(),
+		}, err
+	}
+	if latitude < -90 || latitude > 90 || longitude < -180 || longitude > 180 {
+		err := NewToolError(fmt.Errorf("invalid coordinates: %f, %f", latitude, longitude), "Latitude is between -90 and 90, longitude between -180 and 180.", true)
+		return map[string]any{
+			"success": false,
+			"error":   err.Error(),
+		}, err
+	}
+
+	days := typed.Days
+	if days <= 0 {
+		days = 7
+	}
+	forecast, err := backend.Forecast(ctx, latitude, longitude, min(days, maxForecastDays), typed.Units == "imperial")
+	if err != nil {
+		return map[string]any{
+			"success": false,
+			"error":   err.Error(),
+		}, err
+	}
+	daily, next, err := Paginate(forecast.Daily, args, weatherPageSize)
+	if err != nil {
+		return map[string]any{
+			"success": false,
+			"error":   err.Error(),
+		}, err
+	}
+	result["timezone"] = forecast.Timezone
+	result["units"] = forecast.Units
+	result["current"] = forecast.Current
+	result["daily"] = daily
+	return setNextCursor(result, next), nil
+}
+
+// OpenMeteoBackend geocodes with Nominatim and forecasts with Open-Meteo
+type OpenMeteoBackend struct {
+	Client      *http.Client
+	GeocodeURL  string
+	ForecastURL string
+	// UserAgent identifies the application, Nominatim rejects requests without one
+	UserAgent string
+}
+
+func NewOpenMeteoBackend() *OpenMeteoBackend {
+	return &OpenMeteoBackend{
+		Client:      &http.Client{Timeout: 30 * time.Second},
+		GeocodeURL:  "https://nominatim.openstreetmap.org/search",
+		ForecastURL: "https://api.open-meteo.com/v1/forecast",
+		UserAgent:   "github.com/jbutlerdev/genai",
+	}
+}
+
+func (b *OpenMeteoBackend) get(ctx context.Context, endpoint string, query url.Values, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint+"?"+query.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", b.UserAgent)
+	req.Header.Set("Accept", "application/json")
+	resp, err := b.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("status code: %d", resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+func (b *OpenMeteoBackend) Geocode(ctx context.Context, query string, limit int) ([]Place, error) {
+	var results []struct {
+		DisplayName string `json:"display_name"`
+		Lat         string `json:"lat"`
+		Lon         string `json:"lon"`
+		Type        string `json:"addresstype"`
+	}
+	err := b.get(ctx, b.GeocodeURL, url.Values{
+		"q":      {query},
+		"format": {"jsonv2"},
+		"limit":  {strconv.Itoa(limit)},
+	}, &results)
+	if err != nil {
+		return nil, fmt.Errorf("failed to geocode %s: %w", query, err)
+	}
+	places := make([]Place, 0, len(results))
+	for _, r := range results {
+		lat, latErr := strconv.ParseFloat(r.Lat, 64)
+		lon, lonErr := strconv.ParseFloat(r.Lon, 64)
+		if latErr != nil || lonErr != nil {
+			continue
+		}
+		places = append(places, Place{Name: r.DisplayName, Latitude: lat, Longitude: lon, Type: r.Type})
+	}
+	return places, nil
+}
+
+func (b *OpenMeteoBackend) Forecast(ctx context.Context, latitude float64, longitude float64, days int, imperial bool) (*Forecast, error) {
+	query := url.Values{
+		"latitude":      {strconv.FormatFloat(latitude, 'f', -1, 64)},
+		"longitude":     {strconv.FormatFloat(longitude, 'f', -1, 64)},
+		"current":       {"temperature_2m,relative_humidity_2m,wind_speed_10m,weather_code"},
+		"daily":         {"weather_code,temperature_2m_max,temperature_2m_min,precipitation_sum,wind_speed_10m_max"},
+		"timezone":      {"auto"},
+		"forecast_days": {strconv.Itoa(days)},
+	}
+	units := "metric (°C, km/h, mm)"
+	if imperial {
+		query.Set("temperature_unit", "fahrenheit")
+		query.Set("wind_speed_unit", "mph")
+		query.Set("precipitation_unit", "inch")
+		units = "imperial (°F, mph, inch)"
+	}
+	var resp struct {
+		Timezone string `json:"timezone"`
+		Current  struct {
+			Time        string  `json:"time"`
+			Temperature float64 `json:"temperature_2m"`
+			Humidity    float64 `json:"relative_humidity_2m"`
+			WindSpeed   float64 `json:"wind_speed_10m"`
+			WeatherCode int     `json:"weather_code"`
+		} `json:"current"`
+		Daily struct {
+			Time          []string  `json:"time"`
+			WeatherCode   []int     `json:"weather_code"`
+			TemperatureHi []float64 `json:"temperature_2m_max"`
+			TemperatureLo []float64 `json:"temperature_2m_min"`
+			Precipitation []float64 `json:"precipitation_sum"`
+			WindSpeedMax  []float64 `json:"wind_speed_10m_max"`
+		} `json:"daily"`
+	}
+	if err := b.get(ctx, b.ForecastURL, query, &resp); err != nil {
+		return nil, fmt.Errorf("failed to get forecast: %w", err)
+	}
+	forecast := &Forecast{
+		Timezone: resp.Timezone,
+		Units:    units,
+		Current: CurrentWeather{
+			Time:        resp.Current.Time,
+			Temperature: resp.Current.Temperature,
+			Humidity:    resp.Current.Humidity,
+			WindSpeed:   resp.Current.WindSpeed,
+			Conditions:  weatherConditions(resp.Current.WeatherCode),
+		},
+	}
+	d := resp.Daily
+	for i, date := range d.Time {
+		if i >= len(d.WeatherCode) || i >= len(d.TemperatureHi) || i >= len(d.TemperatureLo) || i >= len(d.Precipitation) || i >= len(d.WindSpeedMax) {
+			break
+		}
+		forecast.Daily = append(forecast.Daily, DailyForecast{
+			Date:          date,
+			TemperatureHi: d.TemperatureHi[i],
+			TemperatureLo: d.TemperatureLo[i],
+			Precipitation: d.Precipitation[i],
+			WindSpeedMax:  d.WindSpeedMax[i],
+			Conditions:    weatherConditions(d.WeatherCode[i]),
+		})
+	}
+	return forecast, nil
+}
+
+// weatherConditions describes a WMO weather interpretation code
+func weatherConditions(code int) string {
+	switch code {
+	case 0:
+		return "clear sky"
+	case 1:
+		return "mainly clear"
+	case 2:
+		return "partly cloudy"
+	case 3:
+		return "overcast"
+	case 45, 48:
+		return "fog"
+	case 51, 53, 55:
+		return "drizzle"
+	case 56, 57:
+		return "freezing drizzle"
+	case 61, 63, 65:
+		return "rain"
+	case 66, 67:
+		return "freezing rain"
+	case 71, 73, 75:
+		return "snow"
+	case 77:
+		return "snow grains"
+	case 80, 81, 82:
+		return "rain showers"
+	case 85, 86:
+		return "snow showers"
+	case 95:
+		return "thunderstorm"
+	case 96, 99:
+		return "thunderstorm with hail"
+	}
+	return fmt.Sprintf("unknown (code %d)", code)
+}
@@ -0,0 +1,189 @@
+package genai
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/jbutlerdev/genai/tools"
+	gemini "google.golang.org/genai"
+)
+
+// DefaultToolSelectionTopK is the number of tools ToolSelection sends when TopK is zero
+const DefaultToolSelectionTopK = 8
+
+// ToolSelection sends each request of a turn only the tools whose descriptions are
+// closest to the user's message, found by the similarity of their embeddings. It keeps
+// chats with many tools from spending tokens on schemas the turn does not need. Chats
+// with at most TopK tools send all of them, as do turns without text and turns whose
+// message could not be embedded. The embeddings of the tools are kept, a ToolSelection
+// can be shared by several chats.
+type ToolSelection struct {
+	// TopK is the number of tools picked, DefaultToolSelectionTopK when zero
+	TopK int
+	// Always lists tools sent with every turn besides the picked ones, e.g. tools the
+	// system prompt refers to. The allowed functions of FunctionCalling are always sent.
+	Always []string
+	// Embedder embeds the tools and messages, the provider itself when nil
+	Embedder tools.EmbeddingProvider
+	// EmbeddingModel is passed to the embedder, which uses its default when empty
+	EmbeddingModel string
+
+	mu sync.Mutex
+	// embeddings are the embeddings of the tools by their description, so a tool that
+	// is reconfigured gets a new one
+	embeddings map[string][]float32
+}
+
+// toolText is the text a tool is embedded with
+func toolText(tool *tools.Tool) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: %s", tool.Name, tool.Description)
+	for _, p := range tool.Parameters {
+		fmt.Fprintf(&b, "\n%s: %s", p.Name, p.Description)
+	}
+	return b.String()
+}
+
+// toolEmbeddings returns the embeddings of texts, embedding those it does not have yet
+// in a single request
+func (s *ToolSelection) toolEmbeddings(ctx context.Context, embedder tools.EmbeddingProvider, texts []string) ([][]float32, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.embeddings == nil {
+		s.embeddings = make(map[string][]float32)
+	}
+	var missing []string
+	for _, text := range texts {
+		if _, ok := s.embeddings[text]; !ok && !slices.Contains(missing, text) {
+			missing = append(missing, text)
+		}
+	}
+	if len(missing) > 0 {
+		embedded, err := embedder.GenerateEmbeddings(ctx, missing, s.EmbeddingModel)
+		if err != nil {
+			return nil, err
+		}
+		if len(embedded) != len(missing) {
+			return nil, fmt.Errorf("got %d embeddings for %d tools", len(embedded), len(missing))
+		}
+		for i, text := range missing {
+			s.embeddings[text] = embedded[i]
+		}
+	}
+	embeddings := make([][]float32, len(texts))
+	for i, text := range texts {
+		embeddings[i] = s.embeddings[text]
+	}
+	return embeddings, nil
+}
+
+// selectTools picks the tools of the turn answering msg, all tools are sent when the
+// selection is off or fails
+func (m *Model) selectTools(ctx context.Context, msg Message) {
+	m.turnTools = nil
+	s := m.ToolSelection
+	if s == nil {
+		return
+	}
+	topK := s.TopK
+	if topK <= 0 {
+		topK = DefaultToolSelectionTopK
+	}
+	keep := make(map[string]bool)
+	for _, name := range s.Always {
+		keep[name] = true
+	}
+	if m.FunctionCalling != nil {
+		for _, name := range m.FunctionCalling.AllowedFunctions {
+			keep[name] = true
+		}
+	}
+	var candidates []*tools.Tool
+	for _, tool := range m.Tools {
+		if _, local := m.localTools[tool.Name]; !local && !keep[tool.Name] {
+			candidates = append(candidates, tool)
+		}
+	}
+	text := msg.Text()
+	if len(candidates) <= topK || text == "" {
+		return
+	}
+	var embedder tools.EmbeddingProvider = m.Provider
+	if s.Embedder != nil {
+		embedder = s.Embedder
+	}
+	query, err := embedder.GenerateEmbedding(ctx, text, s.EmbeddingModel)
+	if err != nil {
+		m.Logger.Error(err, "Failed to embed the message for tool selection")
+		return
+	}
+	texts := make([]string, len(candidates))
+	for i, tool := range candidates {
+		texts[i] = toolText(tool)
+	}
+	embeddings, err := s.toolEmbeddings(ctx, embedder, texts)
+	if err != nil {
+		m.Logger.Error(err, "Failed to embed the tools for tool selection")
+		return
+	}
+	scores := make(map[string]float64, len(candidates))
+	for i, tool := range candidates {
+		scores[tool.Name] = cosineSimilarity(query, embeddings[i])
+	}
+	// the sort is stable so ties keep the order of the toolset
+	slices.SortStableFunc(candidates, func(a, b *tools.Tool) int {
+		switch {
+		case scores[a.Name] > scores[b.Name]:
+			return -1
+		case scores[a.Name] < scores[b.Name]:
+			return 1
+		}
+		return 0
+	})
+	selected := make(map[string]bool, topK+len(keep)+len(m.localTools))
+	for name := range keep {
+		selected[name] = true
+	}
+	for name := range m.localTools {
+		selected[name] = true
+	}
+	var picked []string
+	for _, tool := range candidates[:topK] {
+		selected[tool.Name] = true
+		picked = append(picked, tool.Name)
+	}
+	m.Logger.Info("Selected tools", "tools", picked, "of", len(m.Tools))
+	m.turnTools = selected
+}
+
+// toolSelected reports whether the tool is sent in the current turn
+func (m *Model) toolSelected(name string) bool {
+	return m.turnTools == nil || m.turnTools[name]
+}
+
+// selectedGeminiTools leaves the function declarations out of geminiTools that are not
+// sent in the current turn, tools without declarations such as Google Search are kept
+func (m *Model) selectedGeminiTools(geminiTools []*gemini.Tool) []*gemini.Tool {
+	var selected []*gemini.Tool
+	for _, tool := range geminiTools {
+		if len(tool.FunctionDeclarations) == 0 {
+			selected = append(selected, tool)
+			continue
+		}
+		var declarations []*gemini.FunctionDeclaration
+		for _, declaration := range tool.FunctionDeclarations {
+			if m.toolSelected(declaration.Name) {
+				declarations = append(declarations, declaration)
+			}
+		}
+		if len(declarations) > 0 {
+			filtered := *tool
+			filtered.FunctionDeclarations = declarations
+			selected = append(selected, &filtered)
+		}
+	}
+	return selected
+}
@@ -0,0 +1,143 @@
+package genai
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/jbutlerdev/genai/tools"
+)
+
+// registerTools registers tools that return their name and unregisters them after the test
+func registerTools(t *testing.T, descriptions map[string]string) []*tools.Tool {
+	t.Helper()
+	var toolset []*tools.Tool
+	for _, name := range slices.Sorted(maps.Keys(descriptions)) {
+		tools.RegisterTool(tools.Tool{Name: name, Description: descriptions[name], Run: func(map[string]any) (map[string]any, error) {
+			return map[string]any{"tool": name}, nil
+		}})
+		t.Cleanup(func() { tools.UnregisterTool(name) })
+		tool, err := tools.GetTool(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		toolset = append(toolset, tool)
+	}
+	return toolset
+}
+
+func TestToolSelection(t *testing.T) {
+	weather, _ := registerWeatherTool(t)
+	toolset := append([]*tools.Tool{weather}, registerTools(t, map[string]string{
+		"mock_stock":     "Look up the share price of a company on the stock market",
+		"mock_translate": "Translate text into another language",
+		"mock_reminder":  "Set a reminder for a meeting at a given time",
+	})...)
+	p, err := NewMockProvider(
+		weatherCall("Paris"),
+		MockResponse{Text: "Sunny"},
+		MockResponse{Text: "Done"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the provider embeds the tools and messages by the topics they mention
+	embedder := topicEmbedder{topics: []string{"weather", "stock", "translate", "reminder"}}
+	p.Embed = func(text string) []float32 {
+		embedding, _ := embedder.GenerateEmbedding(context.Background(), text, "")
+		return embedding
+	}
+	selection := &ToolSelection{TopK: 1, Always: []string{"mock_reminder"}}
+	chat := p.ChatEvents(ModelOptions{ModelName: "mock", ToolSelection: selection}, toolset)
+	defer close(chat.Done)
+	if _, err := mockTurn(t, chat, "What is the weather in Paris?"); err != nil {
+		t.Fatalf("turn failed: %v", err)
+	}
+	if _, err := mockTurn(t, chat, "Translate this text into French"); err != nil {
+		t.Fatalf("turn failed: %v", err)
+	}
+
+	requests := p.Requests()
+	if len(requests) != 3 {
+		t.Fatalf("%d requests, want 3", len(requests))
+	}
+	want := [][]string{
+		// the tool results of a turn are sent with the same tools
+		{"mock_weather", "mock_reminder"},
+		{"mock_weather", "mock_reminder"},
+		{"mock_reminder", "mock_translate"},
+	}
+	for i, req := range requests {
+		if !slices.Equal(req.Tools, want[i]) {
+			t.Errorf("request %d tools %v, want %v", i+1, req.Tools, want[i])
+		}
+	}
+	if len(selection.embeddings) != 3 {
+		t.Errorf("%d tool embeddings, want 3", len(selection.embeddings))
+	}
+}
+
+func TestToolSelectionFewTools(t *testing.T) {
+	weather, _ := registerWeatherTool(t)
+	p, err := NewMockProvider(MockResponse{Text: "Hi"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	chat := p.ChatEvents(ModelOptions{ModelName: "mock", ToolSelection: &ToolSelection{}}, []*tools.Tool{weather})
+	defer close(chat.Done)
+	if _, err := mockTurn(t, chat, "Hello"); err != nil {
+		t.Fatalf("turn failed: %v", err)
+	}
+	if tools := p.Requests()[0].Tools; !slices.Equal(tools, []string{"mock_weather"}) {
+		t.Errorf("tools %v, want all of them", tools)
+	}
+}
+
+// requestTools returns the names of the tools sent in an OpenAI or Ollama request body
+func requestTools(body map[string]any) []string {
+	var names []string
+	toolList, _ := body["tools"].([]any)
+	for _, tool := range toolList {
+		function, _ := tool.(map[string]any)["function"].(map[string]any)
+		name, _ := function["name"].(string)
+		names = append(names, name)
+	}
+	return names
+}
+
+func TestToolSelectionOllama(t *testing.T) {
+	weather, _ := registerWeatherTool(t)
+	toolset := append([]*tools.Tool{weather}, registerTools(t, map[string]string{
+		"mock_stock":     "Look up the share price of a company on the stock market",
+		"mock_translate": "Translate text into another language",
+	})...)
+	srv := newTestServer(t, ollamaChatBody)
+	p, err := NewProvider(OLLAMA, ProviderOptions{BaseURL: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Log = logr.Discard()
+	p.Retry = RetryPolicy{MaxAttempts: 1}
+	selection := &ToolSelection{TopK: 1, Embedder: topicEmbedder{topics: []string{"weather", "stock", "translate"}}}
+	chat := p.ChatEvents(ModelOptions{ModelName: "test", ToolSelection: selection}, toolset)
+	defer close(chat.Done)
+	if _, err := mockTurn(t, chat, "What is the weather in Paris?"); err != nil {
+		t.Fatalf("turn failed: %v", err)
+	}
+	if _, err := mockTurn(t, chat, "Translate this text into French"); err != nil {
+		t.Fatalf("turn failed: %v", err)
+	}
+
+	requests := srv.bodies()
+	if len(requests) != 2 {
+		t.Fatalf("%d requests, want 2", len(requests))
+	}
+	want := [][]string{{"mock_weather"}, {"mock_translate"}}
+	for i, req := range requests {
+		if got := requestTools(req); !slices.Equal(got, want[i]) {
+			t.Errorf("request %d tools %v, want %v", i+1, got, want[i])
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package genai
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/openai/openai-go"
+	gemini "google.golang.org/genai"
+)
+
+// default transcription models
+const (
+	DefaultOpenAITranscriptionModel = "whisper-1"
+	DefaultGeminiTranscriptionModel = "gemini-2.5-flash"
+)
+
+// TranscribeOptions configures Provider.Transcribe
+type TranscribeOptions struct {
+	// Model defaults to DefaultOpenAITranscriptionModel or DefaultGeminiTranscriptionModel
+	Model string
+	// MIMEType of the audio, e.g. audio/wav, detected from the data when empty
+	MIMEType string
+	// Language is the ISO-639-1 code of the spoken language, improves accuracy
+	Language string
+	// Prompt guides the style or spelling of the transcript, e.g. names and jargon
+	Prompt string
+}
+
+// audioExtensions are the file names OpenAI uses to detect the audio format
+var audioExtensions = map[string]string{
+	"audio/wav":  "wav",
+	"audio/mpeg": "mp3",
+	"audio/mp3":  "mp3",
+	"audio/ogg":  "ogg",
+	"audio/flac": "flac",
+	"audio/webm": "webm",
+	"audio/mp4":  "m4a",
+	"audio/m4a":  "m4a",
+}
+
+// Transcribe converts speech to text with OpenAI transcription models or Gemini
+// audio understanding
+func (p *Provider) Transcribe(ctx context.Context, audio []byte, opts TranscribeOptions) (string, error) {
+	if len(audio) == 0 {
+		return "", fmt.Errorf("no audio to transcribe")
+	}
+	if opts.MIMEType == "" {
+		opts.MIMEType = detectAudioType(audio)
+	}
+	ctx, cancel := context.WithTimeout(ctx, p.Timeouts.merge(DefaultTimeouts).Generate)
+	defer cancel()
+	switch p.Provider {
+	case OPENAI, VLLM:
+		if opts.Model == "" {
+			opts.Model = DefaultOpenAITranscriptionModel
+		}
+		return p.Client.OpenAI.Transcribe(ctx, audio, opts)
+	case GEMINI:
+		if opts.Model == "" {
+			opts.Model = DefaultGeminiTranscriptionModel
+		}
+		return geminiTranscribe(ctx, p, audio, opts)
+	default:
+		return "", fmt.Errorf("unsupported provider for transcription: %s", p.Provider)
+	}
+}
+
+// Transcribe sends audio to the transcriptions endpoint
+func (c *OpenAIClient) Transcribe(ctx context.Context, audio []byte, opts TranscribeOptions) (string, error) {
+	extension, ok := audioExtensions[opts.MIMEType]
+	if !ok {
+		return "", fmt.Errorf("unsupported audio type: %s", opts.MIMEType)
+	}
+	resp, err := retry(ctx, c.retry, c.log, c.provider, c.metrics, func() (*openai.Transcription, error) {
+		return openAIBalanced(c, func(client *OpenAIClient) (*openai.Transcription, error) {
+			params := openai.AudioTranscriptionNewParams{
+				// the reader is consumed by each attempt
+				File:  openai.File(bytes.NewReader(audio), "audio."+extension, opts.MIMEType),
+				Model: openai.AudioModel(opts.Model),
+			}
+			if opts.Language != "" {
+				params.Language = openai.String(opts.Language)
+			}
+			if opts.Prompt != "" {
+				params.Prompt = openai.String(opts.Prompt)
+			}
+			return client.client.Audio.Transcriptions.New(ctx, params)
+		})
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to transcribe audio: %w", err)
+	}
+	return resp.Text, nil
+}
+
+func geminiTranscribe(ctx context.Context, p *Provider, audio []byte, opts TranscribeOptions) (string, error) {
+	instruction := "Transcribe this audio verbatim. Return only the transcript, without timestamps or commentary."
+	if opts.Language != "" {
+		instruction += fmt.Sprintf(" The audio is in the language with ISO-639-1 code %s.", opts.Language)
+	}
+	if opts.Prompt != "" {
+		instruction += " Context: " + opts.Prompt
+	}
+	contents := []*gemini.Content{
+		gemini.NewContentFromParts([]*gemini.Part{
+			gemini.NewPartFromText(instruction),
+			gemini.NewPartFromBytes(audio, opts.MIMEType),
+		}, gemini.RoleUser),
+	}
+	resp, err := retry(ctx, p.Retry, p.Log, GEMINI, p.observer(), func() (*gemini.GenerateContentResponse, error) {
+		return balanced(p, func(client *Client) (*gemini.GenerateContentResponse, error) {
+			return client.Gemini.Models.GenerateContent(ctx, opts.Model, contents, nil)
+		})
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to transcribe audio: %w", err)
+	}
+	if err := geminiBlocked(resp); err != nil {
+		return "", err
+	}
+	text, _ := geminiText(resp)
+	return strings.TrimSpace(text), nil
+}
+
+// detectAudioType sniffs the audio format, falling back to wav
+func detectAudioType(audio []byte) string {
+	switch {
+	case len(audio) >= 4 && string(audio[:4]) == "fLaC":
+		return "audio/flac"
+	case len(audio) >= 4 && bytes.Equal(audio[:4], []byte{0x1A, 0x45, 0xDF, 0xA3}):
+		return "audio/webm"
+	case len(audio) >= 8 && string(audio[4:8]) == "ftyp":
+		return "audio/mp4"
+	case len(audio) >= 2 && audio[0] == 0xFF && audio[1]&0xE0 == 0xE0:
+		// mp3 frame sync without an ID3 tag
+		return "audio/mpeg"
+	}
+	switch detected := http.DetectContentType(audio); detected {
+	case "audio/wave":
+		return "audio/wav"
+	case "application/ogg":
+		return "audio/ogg"
+	case "audio/mpeg":
+		return detected
+	}
+	return "audio/wav"
+}
@@ -0,0 +1,172 @@
+package genai
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// TranscriptVersion is the format version written by Chat.Export
+const TranscriptVersion = 1
+
+// Transcript is the serialized state of a conversation
+type Transcript struct {
+	Version        int       `json:"version"`
+	ConversationID string    `json:"conversationId,omitempty"`
+	Provider       string    `json:"provider,omitempty"`
+	Model          string    `json:"model,omitempty"`
+	Turns          int       `json:"turns"`
+	Exported       time.Time `json:"exported"`
+	Messages       []Message `json:"messages"`
+	Usage          *Usage    `json:"usage,omitempty"`
+}
+
+// Export serializes the conversation, including tool calls and results, to JSON
+func (c *Chat) Export() ([]byte, error) {
+	transcript := c.Transcript()
+	data, err := json.MarshalIndent(transcript, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode transcript: %w", err)
+	}
+	return data, nil
+}
+
+// Transcript returns the current state of the conversation
+func (c *Chat) Transcript() Transcript {
+	usage := c.Usage()
+	return Transcript{
+		Version:        TranscriptVersion,
+		ConversationID: c.ID(),
+		Provider:       c.model.Provider.Provider,
+		Model:          c.model.ModelName,
+		Turns:          c.Turns,
+		Exported:       time.Now().UTC(),
+		Messages:       c.History(),
+		Usage:          &usage,
+	}
+}
+
+// Import replaces the conversation with an exported transcript. It should be called
+// before sending the next message, not while a response is being generated.
+func (c *Chat) Import(data []byte) error {
+	transcript, err := ParseTranscript(data)
+	if err != nil {
+		return err
+	}
+	if transcript.ConversationID != "" {
+		c.SetConversationID(transcript.ConversationID)
+	}
+	c.Turns = transcript.Turns
+	c.model.setHistory(c.model.seedHistory(transcript.Messages))
+	if transcript.Usage != nil {
+		c.model.setUsage(*transcript.Usage)
+	}
+	return nil
+}
+
+// ParseTranscript decodes and validates a transcript written by Chat.Export
+func ParseTranscript(data []byte) (Transcript, error) {
+	var transcript Transcript
+	if err := json.Unmarshal(data, &transcript); err != nil {
+		return Transcript{}, fmt.Errorf("failed to decode transcript: %w", err)
+	}
+	if transcript.Version > TranscriptVersion {
+		return Transcript{}, fmt.Errorf("unsupported transcript version %d", transcript.Version)
+	}
+	if len(transcript.Messages) == 0 {
+		return Transcript{}, fmt.Errorf("transcript has no messages")
+	}
+	if err := ValidateHistory(transcript.Messages); err != nil {
+		return Transcript{}, fmt.Errorf("invalid transcript: %w", err)
+	}
+	return transcript, nil
+}
+
+// conversationStart returns the index of the first user message of the conversation.
+// The messages before it are the system prompt and the few-shot examples, which are
+// the leading user turns the usage has no record of.
+func (t Transcript) conversationStart() int {
+	var starts []int
+	for i, msg := range t.Messages {
+		if msg.Role == RoleUser && len(msg.ToolResults()) == 0 {
+			starts = append(starts, i)
+		}
+	}
+	if len(starts) == 0 {
+		return len(t.Messages)
+	}
+	skip := 0
+	if t.Usage != nil && len(t.Usage.Turns) > 0 && len(starts) > len(t.Usage.Turns) {
+		skip = len(starts) - len(t.Usage.Turns)
+	}
+	return starts[skip]
+}
+
+// Markdown renders the transcript for humans, e.g. for audit logs
+func (t Transcript) Markdown() string {
+	var sb strings.Builder
+	sb.WriteString("# Conversation")
+	if t.ConversationID != "" {
+		fmt.Fprintf(&sb, " %s", t.ConversationID)
+	}
+	sb.WriteString("\n\n")
+	if t.Model != "" {
+		fmt.Fprintf(&sb, "- Model: %s/%s\n", t.Provider, t.Model)
+	}
+	fmt.Fprintf(&sb, "- Turns: %d\n", t.Turns)
+	if !t.Exported.IsZero() {
+		fmt.Fprintf(&sb, "- Exported: %s\n", t.Exported.Format(time.RFC3339))
+	}
+	sb.WriteString(RenderMarkdown(t.Messages))
+	return sb.String()
+}
+
+// RenderMarkdown renders messages as markdown with one section per message
+func RenderMarkdown(messages []Message) string {
+	var sb strings.Builder
+	for _, msg := range messages {
+		fmt.Fprintf(&sb, "\n## %s\n", markdownRole(msg.Role))
+		for _, part := range msg.Parts {
+			sb.WriteString("\n")
+			switch part.Type {
+			case TextPart:
+				sb.WriteString(part.Text)
+				sb.WriteString("\n")
+			case ImagePart, FilePart:
+				if part.URL != "" {
+					fmt.Fprintf(&sb, "[%s: %s](%s)\n", part.Type, part.MIMEType, part.URL)
+				} else {
+					fmt.Fprintf(&sb, "[%s: %s, %d bytes]\n", part.Type, part.MIMEType, len(part.Data))
+				}
+			case ToolCallPart:
+				fmt.Fprintf(&sb, "Tool call `%s`", part.ToolCall.Name)
+				if part.ToolCall.ID != "" {
+					fmt.Fprintf(&sb, " (%s)", part.ToolCall.ID)
+				}
+				fmt.Fprintf(&sb, "\n\n```json\n%s\n```\n", part.ToolCall.argumentsJSON())
+			case ToolResultPart:
+				fmt.Fprintf(&sb, "Result of `%s`", part.ToolResult.Name)
+				if part.ToolResult.IsError {
+					sb.WriteString(" (error)")
+				}
+				fmt.Fprintf(&sb, "\n\n```\n%s\n```\n", part.ToolResult.Content)
+			}
+		}
+	}
+	return sb.String()
+}
+
+func markdownRole(role Role) string {
+	switch role {
+	case RoleSystem:
+		return "System"
+	case RoleUser:
+		return "User"
+	case RoleAssistant:
+		return "Assistant"
+	case RoleTool:
+		return "Tool"
+	}
+	return string(role)
+}
@@ -0,0 +1,80 @@
+package genai
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// headerTransport adds fixed headers to every request
+type headerTransport struct {
+	base    http.RoundTripper
+	headers map[string]string
+}
+
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	for key, value := range t.headers {
+		req.Header.Set(key, value)
+	}
+	return t.base.RoundTrip(req)
+}
+
+// CloseIdleConnections closes the idle connections of the base transport
+func (t *headerTransport) CloseIdleConnections() {
+	closeIdle(t.base)
+}
+
+// customTransport reports whether any of the transport options are set or request IDs
+// are sent, so the SDK needs the client built by httpClient
+func (p *Provider) customTransport() bool {
+	return p.HTTPClient != nil || p.ProxyURL != "" || p.TLSConfig != nil || len(p.Headers) > 0 || p.requestIDHeader() != ""
+}
+
+// httpClient builds the http.Client passed to the provider SDK from HTTPClient,
+// ProxyURL, TLSConfig and Headers. extraHeaders are added after Headers, the request
+// ID of each request's context is sent in the provider's request ID header.
+func (p *Provider) httpClient(extraHeaders map[string]string) (*http.Client, error) {
+	client := &http.Client{}
+	if p.HTTPClient != nil {
+		// copy so the caller's client is not modified
+		*client = *p.HTTPClient
+	}
+	transport := client.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	if p.ProxyURL != "" || p.TLSConfig != nil {
+		base, ok := transport.(*http.Transport)
+		if !ok {
+			return nil, fmt.Errorf("ProxyURL and TLSConfig require an *http.Transport, got %T", transport)
+		}
+		base = base.Clone()
+		if p.ProxyURL != "" {
+			proxy, err := url.Parse(p.ProxyURL)
+			if err != nil {
+				return nil, fmt.Errorf("invalid proxy URL: %w", err)
+			}
+			base.Proxy = http.ProxyURL(proxy)
+		}
+		if p.TLSConfig != nil {
+			base.TLSClientConfig = p.TLSConfig.Clone()
+		}
+		transport = base
+	}
+	headers := make(map[string]string, len(p.Headers)+len(extraHeaders))
+	for key, value := range p.Headers {
+		headers[key] = value
+	}
+	for key, value := range extraHeaders {
+		headers[key] = value
+	}
+	if len(headers) > 0 {
+		transport = &headerTransport{base: transport, headers: headers}
+	}
+	if header := p.requestIDHeader(); header != "" {
+		transport = &requestIDTransport{base: transport, header: header}
+	}
+	client.Transport = transport
+	return client, nil
+}
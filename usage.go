@@ -0,0 +1,164 @@
+package genai
+
+import (
+	ollama "github.com/ollama/ollama/api"
+	"github.com/openai/openai-go"
+	gemini "google.golang.org/genai"
+)
+
+// Usage counts the tokens used by one or more requests
+type Usage struct {
+	PromptTokens int `json:"promptTokens"`
+	// CompletionTokens include ReasoningTokens
+	CompletionTokens int `json:"completionTokens"`
+	// CachedTokens are the prompt tokens served from the provider's prompt cache
+	CachedTokens    int `json:"cachedTokens,omitempty"`
+	ReasoningTokens int `json:"reasoningTokens,omitempty"`
+	TotalTokens     int `json:"totalTokens"`
+	Requests        int `json:"requests"`
+	// CachedResponses are responses served from the ProviderOptions.Cache, they are not
+	// counted as requests
+	CachedResponses int `json:"cachedResponses,omitempty"`
+	// Turns breaks a chat's usage down by user message, each turn includes the tool
+	// call round trips and compaction it caused
+	Turns []TurnUsage `json:"turns,omitempty"`
+}
+
+// TurnUsage is the usage of one chat turn
+type TurnUsage struct {
+	Turn             int    `json:"turn"`
+	Model            string `json:"model,omitempty"`
+	PromptTokens     int    `json:"promptTokens"`
+	CompletionTokens int    `json:"completionTokens"`
+	CachedTokens     int    `json:"cachedTokens,omitempty"`
+	ReasoningTokens  int    `json:"reasoningTokens,omitempty"`
+	TotalTokens      int    `json:"totalTokens"`
+	Requests         int    `json:"requests"`
+	CachedResponses  int    `json:"cachedResponses,omitempty"`
+	// RequestID is the ID of the turn, sent to the provider and passed to tools
+	RequestID string `json:"requestID,omitempty"`
+}
+
+// Add adds the totals of other to u, the turn breakdown is not merged
+func (u *Usage) Add(other Usage) {
+	u.PromptTokens += other.PromptTokens
+	u.CompletionTokens += other.CompletionTokens
+	u.CachedTokens += other.CachedTokens
+	u.ReasoningTokens += other.ReasoningTokens
+	u.TotalTokens += other.TotalTokens
+	u.Requests += other.Requests
+	u.CachedResponses += other.CachedResponses
+}
+
+// responses reports that u includes a response, sent or cached
+func (u Usage) responses() bool {
+	return u.Requests > 0 || u.CachedResponses > 0
+}
+
+func (t *TurnUsage) add(model string, u Usage) {
+	t.Model = model
+	t.PromptTokens += u.PromptTokens
+	t.CompletionTokens += u.CompletionTokens
+	t.CachedTokens += u.CachedTokens
+	t.ReasoningTokens += u.ReasoningTokens
+	t.TotalTokens += u.TotalTokens
+	t.Requests += u.Requests
+	t.CachedResponses += u.CachedResponses
+}
+
+// Usage returns the tokens used by the model so far
+func (m *Model) Usage() Usage {
+	m.usageMu.Lock()
+	defer m.usageMu.Unlock()
+	usage := m.usage
+	usage.Turns = append([]TurnUsage(nil), m.usage.Turns...)
+	return usage
+}
+
+func (m *Model) setUsage(usage Usage) {
+	m.usageMu.Lock()
+	defer m.usageMu.Unlock()
+	m.usage = usage
+}
+
+// startUsageTurn begins the usage breakdown of a new chat turn
+func (m *Model) startUsageTurn(requestID string) {
+	m.usageMu.Lock()
+	defer m.usageMu.Unlock()
+	m.usage.Turns = append(m.usage.Turns, TurnUsage{Turn: len(m.usage.Turns) + 1, RequestID: requestID})
+}
+
+// recordUsage adds the usage of a request to the totals and the current turn, and
+// reports it to the provider's metrics and OnUsage
+func (m *Model) recordUsage(model string, u Usage) {
+	m.Provider.observer().Response(m.Provider.Provider, model, u)
+	if onUsage := m.Provider.hooks.onUsage; onUsage != nil {
+		onUsage(UsageInfo{Provider: m.Provider.Provider, Model: model, RequestID: m.sessionContext().RequestID, Usage: u})
+	}
+	m.addUsage(model, u)
+}
+
+// addUsage is recordUsage for usage another model already reported, e.g. a drafter's
+func (m *Model) addUsage(model string, u Usage) {
+	m.usageMu.Lock()
+	defer m.usageMu.Unlock()
+	m.usage.Add(u)
+	if n := len(m.usage.Turns); n > 0 {
+		m.usage.Turns[n-1].add(model, u)
+	}
+}
+
+// recordHelperUsage adds the usage of a request made on the model's behalf, e.g. by a
+// critic, keeping the model of the current turn
+func (m *Model) recordHelperUsage(u Usage) {
+	m.usageMu.Lock()
+	defer m.usageMu.Unlock()
+	m.usage.Add(u)
+	if n := len(m.usage.Turns); n > 0 {
+		m.usage.Turns[n-1].add(m.usage.Turns[n-1].Model, u)
+	}
+}
+
+// Usage returns the tokens used by the conversation so far, broken down by turn
+func (c *Chat) Usage() Usage {
+	return c.model.Usage()
+}
+
+// cachedUsage is the usage of a response from the response cache
+func cachedUsage() Usage {
+	return Usage{CachedResponses: 1}
+}
+
+func openAIUsage(u openai.CompletionUsage) Usage {
+	return Usage{
+		PromptTokens:     int(u.PromptTokens),
+		CompletionTokens: int(u.CompletionTokens),
+		CachedTokens:     int(u.PromptTokensDetails.CachedTokens),
+		ReasoningTokens:  int(u.CompletionTokensDetails.ReasoningTokens),
+		TotalTokens:      int(u.TotalTokens),
+		Requests:         1,
+	}
+}
+
+func ollamaUsage(metrics ollama.Metrics) Usage {
+	return Usage{
+		PromptTokens:     metrics.PromptEvalCount,
+		CompletionTokens: metrics.EvalCount,
+		TotalTokens:      metrics.PromptEvalCount + metrics.EvalCount,
+		Requests:         1,
+	}
+}
+
+func geminiUsage(metadata *gemini.GenerateContentResponseUsageMetadata) Usage {
+	if metadata == nil {
+		return Usage{Requests: 1}
+	}
+	return Usage{
+		PromptTokens:     int(metadata.PromptTokenCount + metadata.ToolUsePromptTokenCount),
+		CompletionTokens: int(metadata.CandidatesTokenCount + metadata.ThoughtsTokenCount),
+		CachedTokens:     int(metadata.CachedContentTokenCount),
+		ReasoningTokens:  int(metadata.ThoughtsTokenCount),
+		TotalTokens:      int(metadata.TotalTokenCount),
+		Requests:         1,
+	}
+}
@@ -0,0 +1,257 @@
+package genai
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// RecorderMode selects whether a Recorder sends requests or replays them
+type RecorderMode string
+
+const (
+	// RecordMode sends every request and records the interactions
+	RecordMode RecorderMode = "record"
+	// ReplayMode answers from the cassette and fails requests that were not recorded
+	ReplayMode RecorderMode = "replay"
+	// AutoMode replays when the cassette exists and records it otherwise
+	AutoMode RecorderMode = "auto"
+)
+
+// ErrNoRecording is returned in ReplayMode for requests missing from the cassette
+var ErrNoRecording = errors.New("no recorded interaction matches the request")
+
+// redactedQuery are query parameters that carry credentials, they are not recorded
+var redactedQuery = []string{"key", "api_key", "access_token"}
+
+// Cassette holds recorded HTTP interactions
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Interaction is a request with the response it received. Request headers are not
+// recorded, they carry the API keys.
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+type RecordedRequest struct {
+	Method string `json:"method"`
+	// Path is the URL path with the query, without host so the cassette replays against
+	// any base URL
+	Path string `json:"path"`
+	Body string `json:"body,omitempty"`
+}
+
+type RecordedResponse struct {
+	Status  int               `json:"status"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    string            `json:"body"`
+}
+
+// Recorder is an http.RoundTripper that records provider interactions to a cassette
+// file and replays them, so tests run offline and without API keys:
+//
+//	rec, err := genai.NewRecorder("testdata/chat.json", genai.AutoMode)
+//	defer rec.Save()
+//	p, err := genai.NewProvider(genai.OPENAI, genai.ProviderOptions{HTTPClient: rec.Client()})
+//
+// Requests are matched by method, path and JSON body, identical requests replay their
+// recordings in order.
+type Recorder struct {
+	path string
+	mode RecorderMode
+	// Transport sends the requests in RecordMode, http.DefaultTransport when nil
+	Transport http.RoundTripper
+
+	mu       sync.Mutex
+	cassette Cassette
+	used     []bool
+	changed  bool
+}
+
+// NewRecorder creates a recorder for the cassette at path, which is read in ReplayMode
+// and AutoMode
+func NewRecorder(path string, mode RecorderMode) (*Recorder, error) {
+	r := &Recorder{path: path, mode: mode}
+	switch mode {
+	case RecordMode:
+	case ReplayMode, AutoMode:
+		data, err := os.ReadFile(path)
+		if errors.Is(err, os.ErrNotExist) && mode == AutoMode {
+			r.mode = RecordMode
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read cassette: %w", err)
+		}
+		if err := json.Unmarshal(data, &r.cassette); err != nil {
+			return nil, fmt.Errorf("failed to parse cassette %s: %w", path, err)
+		}
+		r.mode = ReplayMode
+		r.used = make([]bool, len(r.cassette.Interactions))
+	default:
+		return nil, fmt.Errorf("unknown recorder mode: %s", mode)
+	}
+	return r, nil
+}
+
+// Mode returns RecordMode or ReplayMode, AutoMode is resolved by NewRecorder
+func (r *Recorder) Mode() RecorderMode {
+	return r.mode
+}
+
+// Client returns an http.Client using the recorder, for ProviderOptions.HTTPClient
+func (r *Recorder) Client() *http.Client {
+	return &http.Client{Transport: r}
+}
+
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	recorded := RecordedRequest{Method: req.Method, Path: recordedPath(req), Body: normalizeJSON(body)}
+	if r.mode == ReplayMode {
+		return r.replay(req, recorded)
+	}
+
+	transport := r.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	out := req.Clone(req.Context())
+	out.Body = io.NopCloser(bytes.NewReader(body))
+	out.ContentLength = int64(len(body))
+	resp, err := transport.RoundTrip(out)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	interaction := Interaction{
+		Request: recorded,
+		Response: RecordedResponse{
+			Status:  resp.StatusCode,
+			Headers: map[string]string{"Content-Type": resp.Header.Get("Content-Type")},
+			Body:    string(respBody),
+		},
+	}
+	r.mu.Lock()
+	r.cassette.Interactions = append(r.cassette.Interactions, interaction)
+	r.changed = true
+	r.mu.Unlock()
+	return interaction.Response.httpResponse(req), nil
+}
+
+// replay answers with the first unused interaction matching the request
+func (r *Recorder) replay(req *http.Request, recorded RecordedRequest) (*http.Response, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, interaction := range r.cassette.Interactions {
+		if r.used[i] || interaction.Request != recorded {
+			continue
+		}
+		r.used[i] = true
+		return interaction.Response.httpResponse(req), nil
+	}
+	return nil, fmt.Errorf("%w: %s %s", ErrNoRecording, recorded.Method, recorded.Path)
+}
+
+// Unused returns the recorded interactions that were not replayed
+func (r *Recorder) Unused() []Interaction {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var unused []Interaction
+	for i, used := range r.used {
+		if !used {
+			unused = append(unused, r.cassette.Interactions[i])
+		}
+	}
+	return unused
+}
+
+// Save writes the recorded interactions to the cassette, it does nothing when
+// replaying
+func (r *Recorder) Save() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.mode != RecordMode || !r.changed {
+		return nil
+	}
+	data, err := json.MarshalIndent(r.cassette, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(r.path), 0o755); err != nil {
+		return fmt.Errorf("failed to create cassette directory: %w", err)
+	}
+	if err := os.WriteFile(r.path, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write cassette: %w", err)
+	}
+	r.changed = false
+	return nil
+}
+
+func (resp RecordedResponse) httpResponse(req *http.Request) *http.Response {
+	header := make(http.Header, len(resp.Headers))
+	for key, value := range resp.Headers {
+		if value != "" {
+			header.Set(key, value)
+		}
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", resp.Status, http.StatusText(resp.Status)),
+		StatusCode:    resp.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(strings.NewReader(resp.Body)),
+		ContentLength: int64(len(resp.Body)),
+		Request:       req,
+	}
+}
+
+// recordedPath is the path and query of the request without credentials
+func recordedPath(req *http.Request) string {
+	query := req.URL.Query()
+	for _, key := range redactedQuery {
+		query.Del(key)
+	}
+	path := req.URL.Path
+	if encoded := query.Encode(); encoded != "" {
+		path += "?" + encoded
+	}
+	return path
+}
+
+// normalizeJSON re-encodes JSON bodies so requests match regardless of key order and
+// whitespace, other bodies are kept as they are
+func normalizeJSON(body []byte) string {
+	var value any
+	if err := json.Unmarshal(body, &value); err != nil {
+		return string(body)
+	}
+	normalized, err := json.Marshal(value)
+	if err != nil {
+		return string(body)
+	}
+	return string(normalized)
+}
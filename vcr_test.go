@@ -0,0 +1,80 @@
+package genai
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr"
+)
+
+func TestRecorderReplays(t *testing.T) {
+	srv := newTestServer(t, openAICompletionBody)
+	cassette := filepath.Join(t.TempDir(), "cassettes", "generate.json")
+	generate := func(rec *Recorder, prompt string) (string, error) {
+		t.Helper()
+		p, err := NewProvider(OPENAI, ProviderOptions{APIKey: "secret-key", BaseURL: srv.URL + "/v1", HTTPClient: rec.Client()})
+		if err != nil {
+			t.Fatal(err)
+		}
+		p.Log = logr.Discard()
+		p.Retry = RetryPolicy{MaxAttempts: 1}
+		text, _, err := p.generateWithUsage(context.Background(), ModelOptions{ModelName: "test"}, prompt)
+		return text, err
+	}
+
+	rec, err := NewRecorder(cassette, AutoMode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec.Mode() != RecordMode {
+		t.Fatalf("mode %s without a cassette, want record", rec.Mode())
+	}
+	if _, err := generate(rec, "hi"); err != nil {
+		t.Fatal(err)
+	}
+	if err := rec.Save(); err != nil {
+		t.Fatal(err)
+	}
+	srv.Close()
+	data, err := os.ReadFile(cassette)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "secret-key") {
+		t.Error("cassette contains the API key")
+	}
+
+	rec, err = NewRecorder(cassette, AutoMode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec.Mode() != ReplayMode {
+		t.Fatalf("mode %s with a cassette, want replay", rec.Mode())
+	}
+	text, err := generate(rec, "hi")
+	if err != nil || text != "hello" {
+		t.Fatalf("replayed %q, %v, want hello", text, err)
+	}
+	if unused := rec.Unused(); len(unused) != 0 {
+		t.Errorf("%d interactions were not replayed", len(unused))
+	}
+	// the recording is used once and other prompts were not recorded
+	for _, prompt := range []string{"hi", "bye"} {
+		if _, err := generate(rec, prompt); !errors.Is(err, ErrNoRecording) {
+			t.Errorf("prompt %q replayed with %v, want ErrNoRecording", prompt, err)
+		}
+	}
+}
+
+func TestNormalizeJSON(t *testing.T) {
+	if a, b := normalizeJSON([]byte(`{"b": 1, "a": [1, 2]}`)), normalizeJSON([]byte(`{"a":[1,2],"b":1}`)); a != b {
+		t.Errorf("%s != %s", a, b)
+	}
+	if got := normalizeJSON([]byte("plain")); got != "plain" {
+		t.Errorf("normalizeJSON(plain) = %q", got)
+	}
+}